@@ -24,6 +24,7 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 
+	"github.com/orcfax/oracle-suite/pkg/secrets"
 	"github.com/orcfax/oracle-suite/pkg/supervisor"
 	"github.com/orcfax/oracle-suite/pkg/util/hcl"
 )
@@ -62,6 +63,9 @@ func NewRunCmd(cfg supervisor.Config, cf *ConfigFlags, lf *LoggerFlags) *cobra.C
 			if err = s.Start(ctx); err != nil {
 				return err
 			}
+			if r, ok := s.(supervisor.Reloadable); ok {
+				go reloadConfig(ctx, cfg, cf, r, lf.Logger().WithField("tag", ReloadLoggerTag))
+			}
 			return <-s.Wait()
 		},
 	}
@@ -76,6 +80,7 @@ func NewRenderConfigCmd(cfg supervisor.Config, cf *ConfigFlags) *cobra.Command {
 		Use:   "config",
 		Args:  cobra.NoArgs,
 		Short: "Render the config file",
+		Long:  "Render the config file. Use subcommands to validate the config or compare two config files.",
 		RunE: func(cmd *cobra.Command, _ []string) error {
 			if err := cf.Load(cfg); err != nil {
 				return err
@@ -88,12 +93,16 @@ func NewRenderConfigCmd(cfg supervisor.Config, cf *ConfigFlags) *cobra.Command {
 			if diags.HasErrors() {
 				return diags
 			}
-			fmt.Println(string(content))
+			fmt.Println(secrets.Redact(string(content)))
 			return nil
 		},
 	}
 	flags := cmd.Flags()
 	flags.AddFlagSet(cf.FlagSet())
+	cmd.AddCommand(
+		NewValidateConfigCmd(cfg, cf),
+		NewDiffConfigCmd(cfg),
+	)
 	return cmd
 }
 
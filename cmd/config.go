@@ -0,0 +1,175 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/spf13/cobra"
+
+	"github.com/orcfax/oracle-suite/pkg/config"
+	"github.com/orcfax/oracle-suite/pkg/secrets"
+	"github.com/orcfax/oracle-suite/pkg/supervisor"
+)
+
+// NewValidateConfigCmd returns a command that loads the config and reports
+// semantic errors and warnings found in it, with their source ranges.
+// Only configs that implement the config.Validator interface are checked
+// beyond decoding.
+func NewValidateConfigCmd(cfg supervisor.Config, cf *ConfigFlags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "validate",
+		Args:  cobra.NoArgs,
+		Short: "Validate the config file",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			var diags hcl.Diagnostics
+			if err := cf.Load(cfg); err != nil {
+				if !errors.As(err, &diags) {
+					return err
+				}
+			} else if v, ok := cfg.(config.Validator); ok {
+				diags = v.Validate()
+			}
+			w := hcl.NewDiagnosticTextWriter(cmd.OutOrStdout(), diagnosticFiles(diags), 0, false)
+			if err := w.WriteDiagnostics(diags); err != nil {
+				return err
+			}
+			var errs, warns int
+			for _, diag := range diags {
+				switch diag.Severity {
+				case hcl.DiagError:
+					errs++
+				case hcl.DiagWarning:
+					warns++
+				}
+			}
+			if errs > 0 {
+				return fmt.Errorf("config is invalid: %d error(s), %d warning(s)", errs, warns)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Config is valid: %d warning(s)\n", warns)
+			return nil
+		},
+	}
+}
+
+// NewDiffConfigCmd returns a command that compares entities resolved from
+// two configs, like data models and contracts. Each argument is a path, or
+// a comma-separated list of paths, to config files. Configs that do not
+// implement the config.Resolvable interface are compared as a whole.
+func NewDiffConfigCmd(cfg supervisor.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "diff OLD NEW",
+		Args:  cobra.ExactArgs(2),
+		Short: "Compare data models and contracts of two config files",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			oldSnapshot, err := resolveConfig(cfg, args[0])
+			if err != nil {
+				return fmt.Errorf("unable to load %s: %w", args[0], err)
+			}
+			newSnapshot, err := resolveConfig(cfg, args[1])
+			if err != nil {
+				return fmt.Errorf("unable to load %s: %w", args[1], err)
+			}
+			return diffSnapshots(cmd.OutOrStdout(), oldSnapshot, newSnapshot)
+		},
+	}
+}
+
+// resolveConfig loads config files into a new instance of the given config
+// and returns a snapshot of its resolved entities.
+func resolveConfig(cfg supervisor.Config, paths string) (config.Snapshot, error) {
+	newCfg := reflect.New(reflect.TypeOf(cfg).Elem()).Interface()
+	cf := ConfigFlags{paths: strings.Split(paths, ",")}
+	if err := cf.Load(newCfg); err != nil {
+		return nil, err
+	}
+	if r, ok := newCfg.(config.Resolvable); ok {
+		return r.Resolved()
+	}
+	return config.NewSnapshot(map[string]any{"config": newCfg})
+}
+
+// diffSnapshots writes a unified diff of the HCL representation of every
+// entity that differs between the snapshots. Added and removed entities are
+// compared with /dev/null.
+func diffSnapshots(w io.Writer, oldSnapshot, newSnapshot config.Snapshot) error {
+	changed := oldSnapshot.Changed(newSnapshot)
+	if len(changed) == 0 {
+		_, err := fmt.Fprintln(w, "No differences")
+		return err
+	}
+	for _, name := range changed {
+		oldBlock, inOld := oldSnapshot[name]
+		newBlock, inNew := newSnapshot[name]
+		fromFile, toFile := name, name
+		if !inOld {
+			fromFile = "/dev/null"
+		}
+		if !inNew {
+			toFile = "/dev/null"
+		}
+		err := difflib.WriteUnifiedDiff(w, difflib.UnifiedDiff{
+			A:        blockLines(oldBlock),
+			B:        blockLines(newBlock),
+			FromFile: fromFile,
+			ToFile:   toFile,
+			Context:  3,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// blockLines splits the HCL representation of an entity into lines for
+// the diff, ignoring trailing new lines. Secrets are redacted.
+func blockLines(b []byte) []string {
+	s := strings.TrimRight(secrets.Redact(string(b)), "\n")
+	if s == "" {
+		return nil
+	}
+	return difflib.SplitLines(s)
+}
+
+// diagnosticFiles reads files referenced by diagnostics, so that source
+// snippets can be printed along with them. Files that cannot be read, like
+// embedded configs, are skipped.
+func diagnosticFiles(diags hcl.Diagnostics) map[string]*hcl.File {
+	files := map[string]*hcl.File{}
+	for _, diag := range diags {
+		if diag.Subject == nil {
+			continue
+		}
+		name := diag.Subject.Filename
+		if _, ok := files[name]; ok {
+			continue
+		}
+		b, err := os.ReadFile(name)
+		if err != nil {
+			continue
+		}
+		files[name] = &hcl.File{Bytes: b}
+	}
+	return files
+}
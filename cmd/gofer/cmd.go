@@ -21,13 +21,7 @@ import (
 	"strings"
 
 	"github.com/orcfax/oracle-suite/pkg/datapoint"
-)
-
-const (
-	formatPlain  = "plain"
-	formatTrace  = "trace"
-	formatJSON   = "json"
-	formatOrcfax = "orcfax"
+	pkgGofer "github.com/orcfax/oracle-suite/pkg/gofer"
 )
 
 type formatTypeValue struct {
@@ -36,21 +30,21 @@ type formatTypeValue struct {
 
 func (v *formatTypeValue) String() string {
 	if v.format == "" {
-		return formatPlain
+		return pkgGofer.FormatPlain
 	}
 	return v.format
 }
 
 func (v *formatTypeValue) Set(s string) error {
 	switch strings.ToLower(s) {
-	case formatPlain:
-		v.format = formatPlain
-	case formatTrace:
-		v.format = formatTrace
-	case formatJSON:
-		v.format = formatJSON
-	case formatOrcfax:
-		v.format = formatOrcfax
+	case pkgGofer.FormatPlain:
+		v.format = pkgGofer.FormatPlain
+	case pkgGofer.FormatTrace:
+		v.format = pkgGofer.FormatTrace
+	case pkgGofer.FormatJSON:
+		v.format = pkgGofer.FormatJSON
+	case pkgGofer.FormatOrcfax:
+		v.format = pkgGofer.FormatOrcfax
 	default:
 		return fmt.Errorf("unsupported format: %s", s)
 	}
@@ -0,0 +1,110 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/orcfax/oracle-suite/cmd"
+	gofer "github.com/orcfax/oracle-suite/pkg/config/gofernext"
+	pkgGofer "github.com/orcfax/oracle-suite/pkg/gofer"
+	"github.com/orcfax/oracle-suite/pkg/supervisor"
+)
+
+func NewAgentCmd(cfg supervisor.Config, cf *cmd.ConfigFlags, lf *cmd.LoggerFlags) *cobra.Command {
+	var (
+		listen         string
+		streamInterval time.Duration
+		maxStreams     int
+		corsOrigin     string
+	)
+	cc := &cobra.Command{
+		Use:     "agent",
+		Aliases: []string{"serve"},
+		Args:    cobra.NoArgs,
+		Short:   "Start an HTTP API server that exposes data points and models",
+		RunE: func(cc *cobra.Command, _ []string) (err error) {
+			if err := cf.Load(cfg); err != nil {
+				return err
+			}
+			services, err := cfg.Services(lf.Logger(), cc.Root().Use, cc.Root().Version)
+			if err != nil {
+				return err
+			}
+			s, ok := services.(*gofer.Services)
+			if !ok {
+				return fmt.Errorf("services are not gofer.Services")
+			}
+			agent, err := pkgGofer.NewAgent(pkgGofer.AgentConfig{
+				DataProvider:   s.DataProvider,
+				Address:        listen,
+				StreamInterval: streamInterval,
+				MaxStreams:     maxStreams,
+				CORSOrigin:     corsOrigin,
+				Logger:         s.Logger,
+			})
+			if err != nil {
+				return err
+			}
+			ctx, ctxCancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer ctxCancel()
+			if err = services.Start(ctx); err != nil {
+				return err
+			}
+			if err = agent.Start(ctx); err != nil {
+				return err
+			}
+			s.Logger.WithField("address", agent.Addr().String()).Info("Gofer agent started")
+			<-ctx.Done()
+			if err := <-agent.Wait(); err != nil {
+				return err
+			}
+			return <-services.Wait()
+		},
+	}
+	cc.Flags().StringVar(
+		&listen,
+		"listen",
+		"127.0.0.1:8080",
+		"address on which the HTTP API server listens",
+	)
+	cc.Flags().DurationVar(
+		&streamInterval,
+		"stream-interval",
+		5*time.Second,
+		"interval at which data points are checked for changes by the stream endpoint",
+	)
+	cc.Flags().IntVar(
+		&maxStreams,
+		"max-streams",
+		100,
+		"maximum number of concurrent stream connections",
+	)
+	cc.Flags().StringVar(
+		&corsOrigin,
+		"cors-origin",
+		"*",
+		"value of the Access-Control-Allow-Origin header",
+	)
+	return cc
+}
@@ -16,22 +16,17 @@
 package main
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
 	"os"
 	"os/signal"
-	"sort"
 
 	"github.com/spf13/cobra"
 
 	"github.com/orcfax/oracle-suite/cmd"
 	gofer "github.com/orcfax/oracle-suite/pkg/config/gofernext"
-	"github.com/orcfax/oracle-suite/pkg/datapoint"
-	"github.com/orcfax/oracle-suite/pkg/datapoint/value"
+	pkgGofer "github.com/orcfax/oracle-suite/pkg/gofer"
 	"github.com/orcfax/oracle-suite/pkg/supervisor"
-	"github.com/orcfax/oracle-suite/pkg/util/maputil"
 	"github.com/orcfax/oracle-suite/pkg/util/treerender"
 )
 
@@ -63,7 +58,7 @@ func NewDataCmd(cfg supervisor.Config, cf *cmd.ConfigFlags, lf *cmd.LoggerFlags)
 			if err != nil {
 				return err
 			}
-			marshaled, err := marshalDataPoints(points, format.String())
+			marshaled, err := pkgGofer.MarshalDataPoints(points, format.String())
 			if err != nil {
 				return err
 			}
@@ -85,60 +80,3 @@ func NewDataCmd(cfg supervisor.Config, cf *cmd.ConfigFlags, lf *cmd.LoggerFlags)
 	)
 	return cmd
 }
-
-func marshalDataPoints(points map[string]datapoint.Point, format string) ([]byte, error) {
-	switch format {
-	case formatPlain:
-		return marshalDataPointsPlain(points)
-	case formatTrace:
-		return marshalDataPointsTrace(points)
-	case formatJSON:
-		return marshalDataPointsJSON(points)
-	case formatOrcfax:
-		return marshallDataPointsOrcfax(points)
-	default:
-		return nil, fmt.Errorf("unsupported format: %s", format)
-	}
-}
-
-func marshalDataPointsPlain(points map[string]datapoint.Point) ([]byte, error) {
-	var buf bytes.Buffer
-	for i, name := range maputil.SortKeys(points, sort.Strings) {
-		if i > 0 {
-			buf.WriteString("\n")
-		}
-		if err := points[name].Validate(); err != nil {
-			buf.WriteString(fmt.Sprintf("%s: %s", name, err))
-		} else {
-			buf.WriteString(fmt.Sprintf("%s: %s", name, points[name].Value.Print()))
-		}
-	}
-	return buf.Bytes(), nil
-}
-
-func marshalDataPointsTrace(points map[string]datapoint.Point) ([]byte, error) {
-	var buf bytes.Buffer
-	for _, name := range maputil.SortKeys(points, sort.Strings) {
-		bts, err := points[name].MarshalTrace()
-		if err != nil {
-			return nil, err
-		}
-		buf.WriteString(fmt.Sprintf("Data point for %s:\n", name))
-		buf.Write(bts)
-	}
-	return buf.Bytes(), nil
-}
-
-func marshalDataPointsJSON(points map[string]datapoint.Point) ([]byte, error) {
-	return json.Marshal(points)
-}
-
-func marshallDataPointsOrcfax(points map[string]datapoint.Point) ([]byte, error) {
-	ret := make(map[string]value.OrcfaxMessage)
-	for _, name := range maputil.SortKeys(points, sort.Strings) {
-		bts, _ := points[name].MarshalOrcfax()
-		ret[name] = bts
-	}
-	orcfaxData, _ := json.Marshal(ret)
-	return orcfaxData, nil
-}
@@ -16,21 +16,17 @@
 package main
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
 	"os"
 	"os/signal"
-	"sort"
 
 	"github.com/spf13/cobra"
 
 	"github.com/orcfax/oracle-suite/cmd"
 	gofer "github.com/orcfax/oracle-suite/pkg/config/gofernext"
-	"github.com/orcfax/oracle-suite/pkg/datapoint"
+	pkgGofer "github.com/orcfax/oracle-suite/pkg/gofer"
 	"github.com/orcfax/oracle-suite/pkg/supervisor"
-	"github.com/orcfax/oracle-suite/pkg/util/maputil"
 	"github.com/orcfax/oracle-suite/pkg/util/treerender"
 )
 
@@ -62,7 +58,7 @@ func NewModelsCmd(cfg supervisor.Config, cf *cmd.ConfigFlags, lf *cmd.LoggerFlag
 			if err != nil {
 				return err
 			}
-			marshaled, err := marshalModels(models, format.String())
+			marshaled, err := pkgGofer.MarshalModels(models, format.String())
 			if err != nil {
 				return err
 			}
@@ -84,44 +80,3 @@ func NewModelsCmd(cfg supervisor.Config, cf *cmd.ConfigFlags, lf *cmd.LoggerFlag
 	)
 	return cc
 }
-
-func marshalModels(models map[string]datapoint.Model, format string) ([]byte, error) {
-	switch format {
-	case formatPlain:
-		return marshalModelsPlain(models)
-	case formatTrace:
-		return marshalModelsTrace(models)
-	case formatJSON:
-		return marshalModelsJSON(models)
-	default:
-		return nil, fmt.Errorf("unsupported format: %s", format)
-	}
-}
-
-func marshalModelsPlain(models map[string]datapoint.Model) ([]byte, error) {
-	var buf bytes.Buffer
-	for i, name := range maputil.SortKeys(models, sort.Strings) {
-		if i > 0 {
-			buf.WriteString("\n")
-		}
-		buf.WriteString(name)
-	}
-	return buf.Bytes(), nil
-}
-
-func marshalModelsTrace(models map[string]datapoint.Model) ([]byte, error) {
-	var buf bytes.Buffer
-	for _, name := range maputil.SortKeys(models, sort.Strings) {
-		bts, err := models[name].MarshalTrace()
-		if err != nil {
-			return nil, err
-		}
-		buf.WriteString(fmt.Sprintf("Model for %s:\n", name))
-		buf.Write(bts)
-	}
-	return buf.Bytes(), nil
-}
-
-func marshalModelsJSON(models map[string]datapoint.Model) ([]byte, error) {
-	return json.Marshal(models)
-}
@@ -61,6 +61,7 @@ func main() {
 		cmd.NewRenderConfigCmd(&config, &cf),
 		NewModelsCmd(&config, &cf, &lf),
 		NewDataCmd(&config, &cf, &lf),
+		NewAgentCmd(&config, &cf, &lf),
 		versionFunc(),
 	)
 
@@ -25,6 +25,7 @@ import (
 	"github.com/orcfax/oracle-suite/pkg/log"
 	logrus2 "github.com/orcfax/oracle-suite/pkg/log/logrus"
 	"github.com/orcfax/oracle-suite/pkg/log/logrus/formatter"
+	"github.com/orcfax/oracle-suite/pkg/secrets"
 )
 
 // LoggerFlags is a set of flags for configuring a logger.
@@ -156,10 +157,15 @@ func (f *formatterFlag) Type() string {
 	return "text|json"
 }
 
-// Formatter returns the logrus.Formatter for selected type.
+// Formatter returns the logrus.Formatter for selected type. Secrets resolved
+// from the config are redacted from the formatted logs.
 func (f *formatterFlag) Formatter() logrus.Formatter {
-	if f.format == "" {
-		return formattersMap[defaultFormatter]()
+	format := f.format
+	if format == "" {
+		format = defaultFormatter
+	}
+	return &formatter.RedactFormatter{
+		Formatter: formattersMap[format](),
+		Redact:    secrets.Redact,
 	}
-	return formattersMap[f.format]()
 }
@@ -0,0 +1,95 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"reflect"
+	"syscall"
+	"time"
+
+	"github.com/orcfax/oracle-suite/pkg/log"
+	"github.com/orcfax/oracle-suite/pkg/supervisor"
+)
+
+const ReloadLoggerTag = "CONFIG_RELOAD"
+
+// configWatchInterval is the interval at which config files are checked
+// for changes.
+const configWatchInterval = 5 * time.Second
+
+// fileStat is used to detect changes in config files.
+type fileStat struct {
+	modTime time.Time
+	size    int64
+}
+
+// reloadConfig loads the config files and applies them to the running
+// services every time the SIGHUP signal is received or any of the config
+// files is modified. If the config cannot be loaded or applied, the error
+// is logged and the services keep running with the previous config.
+func reloadConfig(
+	ctx context.Context,
+	cfg supervisor.Config,
+	cf *ConfigFlags,
+	srv supervisor.Reloadable,
+	logger log.Logger,
+) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGHUP)
+	defer signal.Stop(sigCh)
+	ticker := time.NewTicker(configWatchInterval)
+	defer ticker.Stop()
+	stats := cf.stats()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sigCh:
+			logger.Info("Received SIGHUP, reloading config")
+		case <-ticker.C:
+			curr := cf.stats()
+			if reflect.DeepEqual(curr, stats) {
+				continue
+			}
+			stats = curr
+			logger.Info("Config files changed, reloading config")
+		}
+		newCfg := reflect.New(reflect.TypeOf(cfg).Elem()).Interface().(supervisor.Config)
+		if err := cf.Load(newCfg); err != nil {
+			logger.WithError(err).Error("Unable to load config")
+			continue
+		}
+		if err := srv.Reload(newCfg); err != nil {
+			logger.WithError(err).Error("Unable to reload config")
+		}
+	}
+}
+
+// stats returns the modification time and size of the config files.
+func (cf *ConfigFlags) stats() map[string]fileStat {
+	stats := make(map[string]fileStat, len(cf.paths))
+	for _, path := range cf.paths {
+		fi, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		stats[path] = fileStat{modTime: fi.ModTime(), size: fi.Size()}
+	}
+	return stats
+}
@@ -0,0 +1,130 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/orcfax/oracle-suite/cmd"
+	relayConfig "github.com/orcfax/oracle-suite/pkg/config/relay"
+	"github.com/orcfax/oracle-suite/pkg/config/spectre"
+)
+
+const (
+	formatText = "text"
+	formatJSON = "json"
+)
+
+type formatTypeValue struct {
+	format string
+}
+
+func (v *formatTypeValue) String() string {
+	if v.format == "" {
+		return formatText
+	}
+	return v.format
+}
+
+func (v *formatTypeValue) Set(s string) error {
+	switch strings.ToLower(s) {
+	case formatText:
+		v.format = formatText
+	case formatJSON:
+		v.format = formatJSON
+	default:
+		return fmt.Errorf("unsupported format: %s", s)
+	}
+	return nil
+}
+
+func (v *formatTypeValue) Type() string {
+	return "text|json"
+}
+
+func NewContractsCmd(cfg *spectre.Config, cf *cmd.ConfigFlags, lf *cmd.LoggerFlags) *cobra.Command {
+	var format formatTypeValue
+	cc := &cobra.Command{
+		Use:     "contracts",
+		Aliases: []string{"contract"},
+		Args:    cobra.NoArgs,
+		Short:   "List contracts handled by the relay, including ones resolved from on-chain registries",
+		RunE: func(cc *cobra.Command, _ []string) error {
+			if err := cf.Load(cfg); err != nil {
+				return err
+			}
+			ctx, ctxCancel := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer ctxCancel()
+			contracts, err := cfg.Contracts(ctx, lf.Logger())
+			if err != nil {
+				return err
+			}
+			switch format.String() {
+			case formatJSON:
+				bts, err := json.Marshal(contracts)
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(bts))
+			default:
+				printContracts(contracts)
+			}
+			return nil
+		},
+	}
+	cc.Flags().AddFlagSet(cf.FlagSet())
+	cc.Flags().VarP(
+		&format,
+		"format",
+		"o",
+		"output format",
+	)
+	return cc
+}
+
+func printContracts(contracts []relayConfig.Contract) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TYPE\tSOURCE\tCLIENT\tADDRESS\tDATA MODEL\tSPREAD\tEXPIRATION\tOPTIMISTIC SPREAD\tOPTIMISTIC EXPIRATION")
+	for _, c := range contracts {
+		var opSpread, opExpiration string
+		if c.OptimisticExpiration > 0 {
+			opSpread = fmt.Sprintf("%g", c.OptimisticSpread)
+			opExpiration = fmt.Sprintf("%d", c.OptimisticExpiration)
+		}
+		fmt.Fprintf(
+			w,
+			"%s\t%s\t%s\t%s\t%s\t%g\t%d\t%s\t%s\n",
+			c.Type,
+			c.Source,
+			c.EthereumClient,
+			c.ContractAddr,
+			c.DataModel,
+			c.Spread,
+			c.Expiration,
+			opSpread,
+			opExpiration,
+		)
+	}
+	_ = w.Flush()
+}
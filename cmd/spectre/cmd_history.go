@@ -0,0 +1,353 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/defiweb/go-eth/rpc"
+	"github.com/defiweb/go-eth/types"
+	"github.com/spf13/cobra"
+
+	"github.com/orcfax/oracle-suite/cmd"
+	ethereumConfig "github.com/orcfax/oracle-suite/pkg/config/ethereum"
+	relayConfig "github.com/orcfax/oracle-suite/pkg/config/relay"
+	"github.com/orcfax/oracle-suite/pkg/config/spectre"
+	"github.com/orcfax/oracle-suite/pkg/contract/chronicle"
+	"github.com/orcfax/oracle-suite/pkg/relay"
+)
+
+const (
+	formatCSV = "csv"
+
+	historyTypePoke   = "poke"
+	historyTypeOpPoke = "opPoke"
+
+	defaultHistoryBlockRange = 5000
+)
+
+type historyFormatValue struct {
+	format string
+}
+
+func (v *historyFormatValue) String() string {
+	if v.format == "" {
+		return formatCSV
+	}
+	return v.format
+}
+
+func (v *historyFormatValue) Set(s string) error {
+	switch strings.ToLower(s) {
+	case formatCSV:
+		v.format = formatCSV
+	case formatJSON:
+		v.format = formatJSON
+	default:
+		return fmt.Errorf("unsupported format: %s", s)
+	}
+	return nil
+}
+
+func (v *historyFormatValue) Type() string {
+	return "csv|json"
+}
+
+// historyRecord is a single poke found in the contract history.
+type historyRecord struct {
+	Type        string         `json:"type"`
+	BlockNumber uint64         `json:"block_number"`
+	TxHash      types.Hash     `json:"tx_hash"`
+	Caller      types.Address  `json:"caller"`
+	OpFeed      *types.Address `json:"op_feed,omitempty"`
+	DataModel   string         `json:"data_model"`
+	Value       string         `json:"value"`
+	Age         time.Time      `json:"age"`
+	Signers     []string       `json:"signers"`
+}
+
+func NewHistoryCmd(cfg *spectre.Config, cf *cmd.ConfigFlags, lf *cmd.LoggerFlags) *cobra.Command {
+	var (
+		format         historyFormatValue
+		fromBlock      uint64
+		toBlock        uint64
+		blockRange     uint64
+		ethereumClient string
+	)
+	cc := &cobra.Command{
+		Use:   "history CONTRACT",
+		Args:  cobra.ExactArgs(1),
+		Short: "Export pokes of a Scribe or optimistic Scribe contract",
+		Long: `Export pokes of a Scribe or optimistic Scribe contract.
+
+The command scans Poked and OpPoked events emitted by the contract between the given blocks
+and decodes the poked values and signers. Signers of regular pokes are decoded from the input
+of the poke transactions. Signers are resolved using the current list of feeds, IDs of feeds
+that are no longer lifted are printed as hex numbers.`,
+		RunE: func(cc *cobra.Command, args []string) error {
+			address, err := types.AddressFromHex(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid contract address: %w", err)
+			}
+			if blockRange == 0 {
+				return errors.New("block range must be greater than 0")
+			}
+			if err := cf.Load(cfg); err != nil {
+				return err
+			}
+			ctx, ctxCancel := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer ctxCancel()
+			contract, client, err := historyContract(ctx, cfg, lf, address, ethereumClient)
+			if err != nil {
+				return err
+			}
+			if toBlock == 0 {
+				latest, err := client.BlockNumber(ctx)
+				if err != nil {
+					return fmt.Errorf("unable to fetch the latest block number: %w", err)
+				}
+				toBlock = latest.Uint64()
+			}
+			if fromBlock > toBlock {
+				return errors.New("from block must not be greater than to block")
+			}
+			records, err := history(ctx, contract, client, fromBlock, toBlock, blockRange)
+			if err != nil {
+				return err
+			}
+			switch format.String() {
+			case formatJSON:
+				return writeHistoryJSON(os.Stdout, records)
+			default:
+				return writeHistoryCSV(os.Stdout, records)
+			}
+		},
+	}
+	cc.Flags().AddFlagSet(cf.FlagSet())
+	cc.Flags().VarP(
+		&format,
+		"format",
+		"o",
+		"output format",
+	)
+	cc.Flags().Uint64Var(
+		&fromBlock,
+		"from",
+		0,
+		"first block to scan",
+	)
+	cc.Flags().Uint64Var(
+		&toBlock,
+		"to",
+		0,
+		"last block to scan (default latest)",
+	)
+	cc.Flags().Uint64Var(
+		&blockRange,
+		"block-range",
+		defaultHistoryBlockRange,
+		"maximum number of blocks scanned in a single query",
+	)
+	cc.Flags().StringVar(
+		&ethereumClient,
+		"ethereum-client",
+		"",
+		"name of the Ethereum client, required if the contract is configured for multiple clients",
+	)
+	_ = cc.MarkFlagRequired("from")
+	return cc
+}
+
+// historyContract finds the contract handled by the relay and returns it
+// together with its RPC client.
+func historyContract(
+	ctx context.Context,
+	cfg *spectre.Config,
+	lf *cmd.LoggerFlags,
+	address types.Address,
+	ethereumClient string,
+) (relayConfig.Contract, rpc.RPC, error) {
+	contracts, err := cfg.Contracts(ctx, lf.Logger())
+	if err != nil {
+		return relayConfig.Contract{}, nil, err
+	}
+	var found []relayConfig.Contract
+	for _, c := range contracts {
+		if c.ContractAddr == address && (ethereumClient == "" || c.EthereumClient == ethereumClient) {
+			found = append(found, c)
+		}
+	}
+	switch {
+	case len(found) == 0:
+		return relayConfig.Contract{}, nil, fmt.Errorf("contract %s is not handled by the relay", address)
+	case len(found) > 1:
+		return relayConfig.Contract{}, nil, fmt.Errorf(
+			"contract %s is configured for multiple Ethereum clients, use the --ethereum-client flag",
+			address,
+		)
+	}
+	contract := found[0]
+	if contract.Type != relay.ContractTypeScribe && contract.Type != relay.ContractTypeOptimisticScribe {
+		return relayConfig.Contract{}, nil, fmt.Errorf("history is not supported for %s contracts", contract.Type)
+	}
+	clients, err := cfg.Ethereum.ClientRegistry(ethereumConfig.Dependencies{Logger: lf.Logger()})
+	if err != nil {
+		return relayConfig.Contract{}, nil, err
+	}
+	client, ok := clients[contract.EthereumClient]
+	if !ok {
+		return relayConfig.Contract{}, nil, fmt.Errorf("ethereum client %q is not configured", contract.EthereumClient)
+	}
+	return contract, client, nil
+}
+
+// history returns the pokes of the contract between the given blocks,
+// inclusive, sorted by block number.
+func history(
+	ctx context.Context,
+	contract relayConfig.Contract,
+	client rpc.RPC,
+	fromBlock, toBlock, blockRange uint64,
+) ([]historyRecord, error) {
+	// OpScribe extends the Scribe, so it can be used for both contract types.
+	scribe := chronicle.NewOpScribe(client, contract.ContractAddr)
+	feeds, err := scribe.Feeds().Call(ctx, types.LatestBlockNumber)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch feeds: %w", err)
+	}
+	var records []historyRecord
+	for from := fromBlock; from <= toBlock; from += blockRange {
+		to := from + blockRange - 1
+		if to > toBlock {
+			to = toBlock
+		}
+		pokes, err := scribe.Pokes(ctx, types.BlockNumberFromUint64(from), types.BlockNumberFromUint64(to))
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range pokes {
+			r := historyRecord{
+				Type:        historyTypePoke,
+				BlockNumber: p.BlockNumber,
+				TxHash:      p.TxHash,
+				Caller:      p.Caller,
+				DataModel:   contract.DataModel,
+				Value:       p.PokeData.Val.String(),
+				Age:         p.PokeData.Age.UTC(),
+			}
+			if p.SchnorrData != nil {
+				r.Signers = historySigners(p.SchnorrData.FeedIDs, feeds)
+			}
+			records = append(records, r)
+		}
+		if contract.Type != relay.ContractTypeOptimisticScribe {
+			continue
+		}
+		opPokes, err := scribe.OpPokes(ctx, types.BlockNumberFromUint64(from), types.BlockNumberFromUint64(to))
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range opPokes {
+			opFeed := p.OpFeed
+			records = append(records, historyRecord{
+				Type:        historyTypeOpPoke,
+				BlockNumber: p.BlockNumber,
+				TxHash:      p.TxHash,
+				Caller:      p.Caller,
+				OpFeed:      &opFeed,
+				DataModel:   contract.DataModel,
+				Value:       p.PokeData.Val.String(),
+				Age:         p.PokeData.Age.UTC(),
+				Signers:     historySigners(p.SchnorrData.FeedIDs, feeds),
+			})
+		}
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].BlockNumber < records[j].BlockNumber
+	})
+	return records, nil
+}
+
+// historySigners returns addresses of feeds with the given IDs. IDs that do
+// not match any of the current feeds are returned as hex numbers.
+func historySigners(feedIDs chronicle.FeedIDs, feeds []types.Address) []string {
+	addresses := make(map[byte]types.Address, len(feeds))
+	for _, f := range feeds {
+		addresses[f[0]] = f
+	}
+	signers := []string{}
+	for _, id := range feedIDs.FeedIDs() {
+		if addr, ok := addresses[id]; ok {
+			signers = append(signers, addr.String())
+			continue
+		}
+		signers = append(signers, fmt.Sprintf("0x%02x", id))
+	}
+	return signers
+}
+
+func writeHistoryJSON(w io.Writer, records []historyRecord) error {
+	if records == nil {
+		records = []historyRecord{}
+	}
+	bts, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(bts))
+	return err
+}
+
+func writeHistoryCSV(w io.Writer, records []historyRecord) error {
+	cw := csv.NewWriter(w)
+	err := cw.Write([]string{"type", "block_number", "tx_hash", "caller", "op_feed", "data_model", "value", "age", "signers"})
+	if err != nil {
+		return err
+	}
+	for _, r := range records {
+		var opFeed string
+		if r.OpFeed != nil {
+			opFeed = r.OpFeed.String()
+		}
+		err := cw.Write([]string{
+			r.Type,
+			strconv.FormatUint(r.BlockNumber, 10),
+			r.TxHash.String(),
+			r.Caller.String(),
+			opFeed,
+			r.DataModel,
+			r.Value,
+			r.Age.Format(time.RFC3339),
+			strings.Join(r.Signers, " "),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
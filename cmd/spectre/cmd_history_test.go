@@ -0,0 +1,96 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/orcfax/oracle-suite/pkg/contract/chronicle"
+)
+
+func TestHistorySigners(t *testing.T) {
+	feed1 := types.MustAddressFromHex("0x0100000000000000000000000000000000000001")
+	feed2 := types.MustAddressFromHex("0x0200000000000000000000000000000000000002")
+	signers := historySigners(chronicle.FeedIDsFromIDs([]byte{1, 2, 3}), []types.Address{feed1, feed2})
+	assert.Equal(t, []string{feed1.String(), feed2.String(), "0x03"}, signers)
+}
+
+func TestWriteHistory(t *testing.T) {
+	opFeed := types.MustAddressFromHex("0x0200000000000000000000000000000000000002")
+	records := []historyRecord{
+		{
+			Type:        historyTypePoke,
+			BlockNumber: 10,
+			TxHash:      types.MustHashFromHex("0x0000000000000000000000000000000000000000000000000000000000000001", types.PadNone),
+			Caller:      types.MustAddressFromHex("0x0300000000000000000000000000000000000003"),
+			DataModel:   "ETH/USD",
+			Value:       "1650.5",
+			Age:         time.Unix(1694119620, 0).UTC(),
+			Signers:     []string{"0x0100000000000000000000000000000000000001", "0x03"},
+		},
+		{
+			Type:        historyTypeOpPoke,
+			BlockNumber: 11,
+			TxHash:      types.MustHashFromHex("0x0000000000000000000000000000000000000000000000000000000000000002", types.PadNone),
+			Caller:      types.MustAddressFromHex("0x0300000000000000000000000000000000000003"),
+			OpFeed:      &opFeed,
+			DataModel:   "ETH/USD",
+			Value:       "1651",
+			Age:         time.Unix(1694119680, 0).UTC(),
+			Signers:     []string{},
+		},
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, writeHistoryCSV(&buf, records))
+		assert.Equal(t,
+			"type,block_number,tx_hash,caller,op_feed,data_model,value,age,signers\n"+
+				"poke,10,0x0000000000000000000000000000000000000000000000000000000000000001,0x0300000000000000000000000000000000000003,,ETH/USD,1650.5,2023-09-07T20:47:00Z,0x0100000000000000000000000000000000000001 0x03\n"+
+				"opPoke,11,0x0000000000000000000000000000000000000000000000000000000000000002,0x0300000000000000000000000000000000000003,0x0200000000000000000000000000000000000002,ETH/USD,1651,2023-09-07T20:48:00Z,\n",
+			buf.String(),
+		)
+	})
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, writeHistoryJSON(&buf, records[1:]))
+		assert.JSONEq(t,
+			`[{
+				"type": "opPoke",
+				"block_number": 11,
+				"tx_hash": "0x0000000000000000000000000000000000000000000000000000000000000002",
+				"caller": "0x0300000000000000000000000000000000000003",
+				"op_feed": "0x0200000000000000000000000000000000000002",
+				"data_model": "ETH/USD",
+				"value": "1651",
+				"age": "2023-09-07T20:48:00Z",
+				"signers": []
+			}]`,
+			buf.String(),
+		)
+	})
+	t.Run("empty json", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, writeHistoryJSON(&buf, nil))
+		assert.Equal(t, "[]\n", buf.String())
+	})
+}
@@ -33,6 +33,8 @@ func main() {
 	c.AddCommand(
 		cmd.NewRunCmd(&config, &cf, &lf),
 		cmd.NewRenderConfigCmd(&config, &cf),
+		NewContractsCmd(&config, &cf, &lf),
+		NewHistoryCmd(&config, &cf, &lf),
 	)
 
 	if err := c.Execute(); err != nil {
@@ -0,0 +1,12 @@
+variables {
+  rpc_splitter_clients = explode(var.item_separator, env("CFG_RPC_SPLITTER_CLIENTS", ""))
+}
+
+rpc_splitter {
+  # Address to listen for RPC requests.
+  listen_addr = env("CFG_RPC_SPLITTER_LISTEN_ADDR", "127.0.0.1:8545")
+
+  # Names of Ethereum clients to serve, each under a virtual host with the same
+  # name. If empty, all configured clients are served.
+  clients = var.rpc_splitter_clients
+}
@@ -100,4 +100,74 @@ spectre {
       optimistic_expiration = contract.value.poke_optimistic.expiration
     }
   }
+
+  dynamic "registry" {
+    for_each = env("CFG_SPECTRE_CHAINLOG_ADDR", "") == "" ? [] : [1]
+    content {
+      # Ethereum client to use for reading the on-chain registries.
+      ethereum_client = "default"
+
+      # Address of the Chainlog contract used to find the WatRegistry and FeedRegistry contracts.
+      chainlog_addr = env("CFG_SPECTRE_CHAINLOG_ADDR", "")
+
+      # Ethereum clients used to update discovered contracts, matched by chain ID.
+      relay_ethereum_clients = ["default"]
+
+      # List of data models to relay. If empty, all data models from the registry are relayed.
+      data_models = var.spectre_pairs
+
+      # Time in seconds between registry refreshes.
+      interval = tonumber(env("CFG_SPECTRE_REGISTRY_INTERVAL", "600"))
+
+      median {
+        spread     = 1
+        expiration = 86400
+      }
+
+      scribe {
+        spread     = 3
+        expiration = 86400
+      }
+
+      optimistic_scribe {
+        spread                = 3
+        expiration            = 86400
+        optimistic_spread     = 1
+        optimistic_expiration = 43200
+      }
+    }
+  }
+
+  dynamic "watcher" {
+    for_each = env("CFG_SPECTRE_WATCHER", "0") == "1" ? [1] : []
+    content {
+      # Time in seconds between verifications of contract values.
+      interval = tonumber(env("CFG_SPECTRE_WATCHER_INTERVAL", "60"))
+
+      # Time in seconds for which a discrepancy must persist before an alarm is raised.
+      grace_period = tonumber(env("CFG_SPECTRE_WATCHER_GRACE_PERIOD", "600"))
+    }
+  }
+
+  dynamic "challenger" {
+    for_each = env("CFG_SPECTRE_CHALLENGER", "0") == "1" ? [1] : []
+    content {
+      # Time in seconds between checks for new optimistic pokes.
+      interval = tonumber(env("CFG_SPECTRE_CHALLENGER_INTERVAL", "30"))
+
+      # Maximum number of blocks scanned for optimistic pokes in a single query.
+      block_range = tonumber(env("CFG_SPECTRE_CHALLENGER_BLOCK_RANGE", "1000"))
+    }
+  }
+
+  dynamic "reputation" {
+    for_each = env("CFG_SPECTRE_REPUTATION", "0") == "1" ? [1] : []
+    content {
+      # Maximum deviation from the median, in percent, above which a data point is considered an outlier.
+      max_deviation = tonumber(env("CFG_SPECTRE_REPUTATION_MAX_DEVIATION", "5"))
+
+      # Score above which a feed is excluded from the quorum.
+      exclusion_threshold = tonumber(env("CFG_SPECTRE_REPUTATION_EXCLUSION_THRESHOLD", "0.5"))
+    }
+  }
 }
@@ -5,12 +5,23 @@ variables {
     try(var.libp2p_bootstraps[var.environment], [])
   )))
 
-  webapi_enable              = tobool(env("CFG_WEBAPI_ENABLE", "0"))
-  webapi_eth_address_book    = env("CFG_WEBAPI_ETH_ADDR_BOOK", try(var.contract_map["${var.environment}-${var.chain_name}-TorAddressRegister"], ""))
-  webapi_static_address_book = explode(var.item_separator, env("CFG_WEBAPI_STATIC_ADDR_BOOK", join(
+  webapi_enable                         = tobool(env("CFG_WEBAPI_ENABLE", "0"))
+  webapi_eth_address_book               = env("CFG_WEBAPI_ETH_ADDR_BOOK", try(var.contract_map["${var.environment}-${var.chain_name}-TorAddressRegister"], ""))
+  webapi_static_address_book            = explode(var.item_separator, env("CFG_WEBAPI_STATIC_ADDR_BOOK", join(
     var.item_separator,
     try(var.static_address_books[var.environment], [])
   )))
+  webapi_dns_address_book               = explode(var.item_separator, env("CFG_WEBAPI_DNS_ADDR_BOOK", ""))
+  webapi_dns_address_book_signer        = env("CFG_WEBAPI_DNS_ADDR_BOOK_SIGNER", "")
+  webapi_well_known_address_book        = explode(var.item_separator, env("CFG_WEBAPI_WELL_KNOWN_ADDR_BOOK", ""))
+  webapi_well_known_address_book_signer = env("CFG_WEBAPI_WELL_KNOWN_ADDR_BOOK_SIGNER", "")
+
+  mq_enable = tobool(env("CFG_MQ_ENABLE", "0"))
+
+  mqtt_broker_addr  = env("CFG_MQTT_BROKER_ADDR", "")
+  mqtt_topic_prefix = env("CFG_MQTT_TOPIC_PREFIX", "oracle")
+
+  private_topics = explode(var.item_separator, env("CFG_PRIVATE_TOPICS", ""))
 }
 
 transport {
@@ -39,6 +50,7 @@ transport {
       listen_addr       = env("CFG_WEBAPI_LISTEN_ADDR", "")
       socks5_proxy_addr = env("CFG_WEBAPI_SOCKS5_PROXY_ADDR", "")
       ethereum_key      = "default"
+      streaming         = tobool(env("CFG_WEBAPI_STREAMING", "0"))
 
       # Ethereum based address book. Enabled if CFG_WEBAPI_ETH_ADDR_BOOK is set to a contract address.
       dynamic "ethereum_address_book" {
@@ -56,6 +68,84 @@ transport {
           addresses = var.webapi_static_address_book
         }
       }
+
+      # DNS address book. Enabled if CFG_WEBAPI_DNS_ADDR_BOOK is set to a list of domains.
+      dynamic "dns_address_book" {
+        for_each = length(var.webapi_dns_address_book) == 0 ? [] : [1]
+        content {
+          domains = var.webapi_dns_address_book
+          signer  = var.webapi_dns_address_book_signer
+        }
+      }
+
+      # Well-known address book. Enabled if CFG_WEBAPI_WELL_KNOWN_ADDR_BOOK is set to a list of URLs.
+      dynamic "well_known_address_book" {
+        for_each = length(var.webapi_well_known_address_book) == 0 ? [] : [1]
+        content {
+          urls   = var.webapi_well_known_address_book
+          signer = var.webapi_well_known_address_book_signer
+        }
+      }
+    }
+  }
+
+  # MQ transport configuration. Enabled if CFG_MQ_ENABLE is set to anything evaluated to `true`.
+  dynamic "mq" {
+    for_each = var.mq_enable ? [1] : []
+    content {
+      feeds             = var.feeds
+      listen_addr       = env("CFG_MQ_LISTEN_ADDR", "")
+      consumers         = explode(var.item_separator, env("CFG_MQ_CONSUMERS", ""))
+      journal_path      = env("CFG_MQ_JOURNAL_PATH", "./journal")
+      socks5_proxy_addr = env("CFG_MQ_SOCKS5_PROXY_ADDR", "")
+      ethereum_key      = "default"
+    }
+  }
+
+  # MQTT bridge configuration. Enabled if CFG_MQTT_BROKER_ADDR is set to a broker address. Data points received by
+  # other transports are published to the broker.
+  dynamic "mqtt" {
+    for_each = var.mqtt_broker_addr == "" ? [] : [1]
+    content {
+      broker_addr = var.mqtt_broker_addr
+      client_id   = env("CFG_MQTT_CLIENT_ID", "")
+      username    = env("CFG_MQTT_USERNAME", "")
+      password    = env("CFG_MQTT_PASSWORD", "")
+      tls         = tobool(env("CFG_MQTT_TLS", "0"))
+      qos         = tonumber(env("CFG_MQTT_QOS", "1"))
+      format      = env("CFG_MQTT_FORMAT", "protobuf")
+
+      topic "data_point/v1" {
+        publish = "${var.mqtt_topic_prefix}/data_point/v1"
+      }
+    }
+  }
+
+  # Encrypted private topics. Enabled if CFG_PRIVATE_TOPICS is set to a list of topics. Messages on these topics can
+  # be read only by members listed in CFG_PRIVATE_MEMBERS, other nodes only relay them.
+  dynamic "private" {
+    for_each = length(var.private_topics) == 0 ? [] : [1]
+    content {
+      members               = explode(var.item_separator, env("CFG_PRIVATE_MEMBERS", join(var.item_separator, var.feeds)))
+      topics                = var.private_topics
+      ethereum_key          = "default"
+      key_rotation_interval = tonumber(env("CFG_PRIVATE_KEY_ROTATION_INTERVAL", "3600"))
+    }
+  }
+
+  dynamic "metrics" {
+    for_each = env("CFG_METRICS_LISTEN_ADDR", "") == "" ? [] : [1]
+    content {
+      listen_addr = env("CFG_METRICS_LISTEN_ADDR", "")
+    }
+  }
+
+  # Deduplication of messages received from multiple transports. Enabled if CFG_DEDUP_ENABLE is set to anything
+  # evaluated to `true`.
+  dynamic "dedup" {
+    for_each = tobool(env("CFG_DEDUP_ENABLE", "0")) ? [1] : []
+    content {
+      monotonic = tobool(env("CFG_DEDUP_MONOTONIC", "0"))
     }
   }
 }
@@ -34,6 +34,9 @@ var Ghost []byte
 //go:embed config-gofer.hcl
 var Gofer []byte
 
+//go:embed config-rpc-splitter.hcl
+var RPCSplitter []byte
+
 //go:embed config-spectre.hcl
 var Spectre []byte
 
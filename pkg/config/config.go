@@ -16,10 +16,12 @@
 package config
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/ext/tryfunc"
@@ -29,12 +31,18 @@ import (
 
 	"github.com/hashicorp/hcl/v2/ext/dynblock"
 
+	"github.com/orcfax/oracle-suite/pkg/secrets"
 	utilHCL "github.com/orcfax/oracle-suite/pkg/util/hcl"
 	"github.com/orcfax/oracle-suite/pkg/util/hcl/ext/include"
 	"github.com/orcfax/oracle-suite/pkg/util/hcl/ext/variables"
 	"github.com/orcfax/oracle-suite/pkg/util/hcl/funcs"
 )
 
+// secretTimeout is the maximum time it may take to resolve a secret using
+// the secret function. Resolvers may use shorter timeouts, e.g. the Vault
+// resolver uses the VAULT_CLIENT_TIMEOUT environment variable.
+const secretTimeout = time.Minute
+
 var hclContext = &hcl.EvalContext{
 	Variables: map[string]cty.Value{
 		"env": getEnvVars(),
@@ -71,6 +79,7 @@ var hclContext = &hcl.EvalContext{
 		// Customer (like in more custom) functions:
 		"env":     envFunc,
 		"explode": explodeFunc,
+		"secret":  secretFunc,
 	},
 }
 
@@ -106,6 +115,23 @@ var envFunc = function.New(&function.Spec{
 	},
 })
 
+var secretFunc = function.New(&function.Spec{
+	Description: `Returns the secret referenced by the given URI, e.g. "file:///run/secrets/key", "env:KEY" or "vault:secret/path#key". Secrets are redacted from logs and rendered configuration.`, //nolint:lll
+	Params: []function.Parameter{
+		{Name: "uri", Type: cty.String},
+	},
+	Type: function.StaticReturnType(cty.String),
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		ctx, ctxCancel := context.WithTimeout(context.Background(), secretTimeout)
+		defer ctxCancel()
+		value, err := secrets.Resolve(ctx, args[0].AsString())
+		if err != nil {
+			return cty.NilVal, err
+		}
+		return cty.StringVal(value), nil
+	},
+})
+
 var explodeFunc = function.New(&function.Spec{
 	Description: "Produces a list of one or more strings by splitting the given string at all instances of a given separator substring. Empty string becomes an empty List", //nolint:lll
 	Params: []function.Parameter{
@@ -190,3 +216,31 @@ func LoadEmbeds(config any, embeds [][]byte) (err error) {
 type HasDefaults interface {
 	DefaultEmbeds() [][]byte
 }
+
+// Validator is implemented by configurations that can be checked for
+// semantic errors that are not caught while decoding, like references to
+// undefined names.
+type Validator interface {
+	// Validate returns errors and warnings found in the configuration.
+	Validate() hcl.Diagnostics
+}
+
+// Resolvable is implemented by configurations that define entities, like
+// data models or contracts, that can be compared between configurations.
+type Resolvable interface {
+	// Resolved returns a snapshot of the entities defined in the
+	// configuration, keyed by names that are unique within it.
+	Resolved() (Snapshot, error)
+}
+
+// DecodeRemain decodes the remaining body of a loaded configuration into
+// the given config. It may be used to read blocks that are not a part of
+// the configuration struct, like blocks used by other applications. It
+// must be called after the configuration is loaded, so that variables
+// defined in the loaded files are available.
+func DecodeRemain(body hcl.Body, config any) error {
+	if diags := utilHCL.Decode(hclContext, body, config); diags.HasErrors() {
+		return diags
+	}
+	return nil
+}
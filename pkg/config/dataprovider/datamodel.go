@@ -29,6 +29,7 @@ type configDataModel struct {
 // can be used in a price model.
 type configDynamicNode interface {
 	buildGraph(origins map[string]origin.Origin, roots map[string]graph.Node) ([]graph.Node, error)
+	validate(origins map[string]origin.Origin, roots map[string]graph.Node) hcl.Diagnostics
 	hclRange() hcl.Range
 }
 
@@ -0,0 +1,214 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dataprovider
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+
+	"github.com/orcfax/oracle-suite/pkg/datapoint/graph"
+	"github.com/orcfax/oracle-suite/pkg/datapoint/origin"
+	"github.com/orcfax/oracle-suite/pkg/util/sliceutil"
+)
+
+// DataModelNames returns the names of configured data models.
+func (c *Config) DataModelNames() []string {
+	names := make([]string, len(c.DataModels))
+	for i, pm := range c.DataModels {
+		names[i] = pm.Name
+	}
+	return names
+}
+
+// Entities returns the data model blocks keyed by their names. It is used
+// to compare data models between configurations.
+func (c *Config) Entities() map[string]any {
+	entities := make(map[string]any, len(c.DataModels))
+	for i := range c.DataModels {
+		entities[fmt.Sprintf("data_model %q", c.DataModels[i].Name)] = &c.DataModels[i].configNode
+	}
+	return entities
+}
+
+// Validate checks the data models for references to undefined origins and
+// data models, invalid median parameters and cycles. Clients is a list of
+// configured Ethereum client names that origins may use.
+//
+// Unlike ConfigureDataProvider, Validate does not create origins and reports
+// all problems found instead of the first one.
+func (c *Config) Validate(clients []string) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+
+	// Origins are not created, only their names are needed to build
+	// the graph.
+	origins := map[string]origin.Origin{}
+	for _, o := range c.Origins {
+		origins[o.Name] = nil
+		if client, ok := o.ethereumClient(); ok && !sliceutil.Contains(clients, client) {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Validation error",
+				Detail:   fmt.Sprintf("Origin %s uses Ethereum client %q which is not configured", o.Name, client),
+				Subject:  o.Range.Ptr(),
+			})
+		}
+	}
+
+	models := map[string]graph.Node{}
+	for _, pm := range c.DataModels {
+		models[pm.Name] = graph.NewReferenceNode()
+	}
+	for _, pm := range c.DataModels {
+		modelDiags := pm.validate(origins, models)
+		if len(pm.Nodes) != 1 {
+			modelDiags = append(modelDiags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Validation error",
+				Detail:   "Data model must have exactly one root node",
+				Subject:  pm.Range.Ptr(),
+			})
+		}
+		diags = append(diags, modelDiags...)
+		if modelDiags.HasErrors() {
+			continue
+		}
+
+		// Build the graph to find errors reported by nodes and to detect
+		// cycles once all data models are built.
+		node, err := pm.configureDataModel(origins, models)
+		if err == nil {
+			err = models[pm.Name].AddNodes(node)
+		}
+		if err != nil {
+			diags = append(diags, errorDiagnostics(err, pm.Range)...)
+		}
+	}
+	for _, pm := range c.DataModels {
+		if nodes := graph.DetectCycle(models[pm.Name]); len(nodes) > 0 {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Validation error",
+				Detail: fmt.Sprintf(
+					"Cycle detected in the data model %s: %s",
+					pm.Name,
+					strings.Join(sliceutil.Map(nodes, func(n graph.Node) string {
+						return n.Meta()["type"].(string)
+					}), " -> "),
+				),
+				Subject: pm.Range.Ptr(),
+			})
+		}
+	}
+	return diags
+}
+
+// validate recursively checks references to origins and data models, and
+// median parameters of the node and its children.
+func (c *configNode) validate(origins map[string]origin.Origin, roots map[string]graph.Node) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	for _, node := range c.Nodes {
+		switch node := node.(type) {
+		case *configNodeOrigin:
+			if _, ok := origins[node.Origin]; !ok {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Validation error",
+					Detail:   fmt.Sprintf("Unknown origin: %s", node.Origin),
+					Subject:  node.hclRange().Ptr(),
+				})
+			}
+		case *configNodeReference:
+			if _, ok := roots[node.DataModel]; !ok {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Validation error",
+					Detail:   fmt.Sprintf("Unknown data model: %s", node.DataModel),
+					Subject:  node.hclRange().Ptr(),
+				})
+			}
+		case *configNodeMedian:
+			if node.MinValues > len(node.Nodes) {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Validation error",
+					Detail: fmt.Sprintf(
+						"Median requires at least %d values but has only %d sources",
+						node.MinValues,
+						len(node.Nodes),
+					),
+					Subject: node.hclRange().Ptr(),
+				})
+			}
+		}
+		diags = append(diags, node.validate(origins, roots)...)
+	}
+	return diags
+}
+
+// ethereumClient returns the name of the Ethereum client used by the origin.
+// If the origin does not use an Ethereum client, false is returned.
+func (c *configOrigin) ethereumClient() (string, bool) {
+	switch o := c.OriginConfig.(type) {
+	case *configOriginBalancerV2:
+		return o.Contracts.EthereumClient, true
+	case *configOriginComposableBalancerV2:
+		return o.Contracts.EthereumClient, true
+	case *configOriginWeightedBalancerV2:
+		return o.Contracts.EthereumClient, true
+	case *configOriginCurve:
+		return o.Contracts.EthereumClient, true
+	case *configOriginDSR:
+		return o.Contracts.EthereumClient, true
+	case *configOriginLidoLST:
+		return o.Contracts.EthereumClient, true
+	case *configOriginRocketPool:
+		return o.Contracts.EthereumClient, true
+	case *configOriginSDAI:
+		return o.Contracts.EthereumClient, true
+	case *configOriginSushiswap:
+		return o.Contracts.EthereumClient, true
+	case *configOriginUniswapV2:
+		return o.Contracts.EthereumClient, true
+	case *configOriginUniswapV3:
+		return o.Contracts.EthereumClient, true
+	case *configOriginWrappedStakedETH:
+		return o.Contracts.EthereumClient, true
+	}
+	return "", false
+}
+
+// errorDiagnostics converts an error returned while building the graph into
+// diagnostics. Errors that are not diagnostics are reported at the given
+// range.
+func errorDiagnostics(err error, subject hcl.Range) hcl.Diagnostics {
+	var diag *hcl.Diagnostic
+	if errors.As(err, &diag) {
+		return hcl.Diagnostics{diag}
+	}
+	var diags hcl.Diagnostics
+	if errors.As(err, &diags) {
+		return diags
+	}
+	return hcl.Diagnostics{{
+		Severity: hcl.DiagError,
+		Summary:  "Validation error",
+		Detail:   err.Error(),
+		Subject:  subject.Ptr(),
+	}}
+}
@@ -42,12 +42,15 @@ const (
 	splitterVirtualHost       = "rpc-splitter"
 	defaultTotalTimeout       = 10
 	defaultGracefulTimeout    = 1
+	defaultCacheSize          = 1000
+	defaultCacheConfirmations = 64
 	defaultGasLimitMultiplier = 1.25
 )
 
 type (
 	KeyRegistry    map[string]wallet.Key
 	ClientRegistry map[string]rpc.RPC
+	RelayRegistry  map[string]ConfigRelay
 )
 
 type Dependencies struct {
@@ -91,6 +94,11 @@ type ConfigKey struct {
 	// key. If empty, then the passphrase is not provided.
 	PassphraseFile string `hcl:"passphrase_file,optional"`
 
+	// Passphrase is the passphrase for the key. It is meant to be used with
+	// the secret function, e.g. secret("vault:secret/oracle#passphrase").
+	// It cannot be used together with PassphraseFile.
+	Passphrase string `hcl:"passphrase,optional"`
+
 	// HCL fields:
 	Content hcl.BodyContent `hcl:",content"`
 
@@ -124,6 +132,20 @@ type ConfigClient struct {
 	// block number.
 	MaxBlocksBehind uint64 `hcl:"max_blocks_behind,optional"`
 
+	// CacheSize is the maximum number of immutable responses, such as mined
+	// transactions or calls at a specific block, cached by RPC-Splitter.
+	CacheSize uint32 `hcl:"cache_size,optional"`
+
+	// CacheConfirmations is the number of blocks after which results that
+	// depend on a block are cached. Results for more recent blocks are not
+	// cached, because they may change after a chain reorganization.
+	CacheConfirmations uint64 `hcl:"cache_confirmations,optional"`
+
+	// MaxEndpoints is the maximum number of healthy RPC endpoints queried per
+	// request. If there are more, the more reliable endpoints are queried more
+	// often. If zero, all healthy endpoints are queried.
+	MaxEndpoints uint32 `hcl:"max_endpoints,optional"`
+
 	// Key configuration:
 
 	// EthereumKey is the name of the Ethereum key to use for signing
@@ -141,6 +163,10 @@ type ConfigClient struct {
 	MaxGasPriorityFee        *big.Int `hcl:"max_gas_priority_fee,optional"`
 	MaxGasLimit              *big.Int `hcl:"max_gas_limit,optional"`
 
+	// Relay configures the relay worker that sends transactions using this
+	// client. Used only by Spectre.
+	Relay *ConfigRelay `hcl:"relay,block,optional"`
+
 	// HCL fields:
 	Range   hcl.Range       `hcl:",range"`
 	Content hcl.BodyContent `hcl:",content"`
@@ -149,6 +175,25 @@ type ConfigClient struct {
 	client rpc.RPC
 }
 
+// ConfigRelay contains the configuration for the relay worker of an Ethereum
+// client.
+type ConfigRelay struct {
+	// Interval is a time in seconds between checks if contracts need to be
+	// updated. If zero, the global relay interval is used.
+	Interval uint32 `hcl:"interval,optional"`
+
+	// MaxConcurrency is the maximum number of contracts checked in parallel.
+	MaxConcurrency uint32 `hcl:"max_concurrency,optional"`
+
+	// GasUsageCap is the soft cap for gas usage of a single relay
+	// transaction.
+	GasUsageCap uint64 `hcl:"gas_usage_cap,optional"`
+
+	// HCL fields:
+	Range   hcl.Range       `hcl:",range"`
+	Content hcl.BodyContent `hcl:",content"`
+}
+
 // KeyRegistry returns the list of configured Ethereum keys.
 func (c *Config) KeyRegistry(d Dependencies) (KeyRegistry, error) {
 	if c == nil {
@@ -171,6 +216,34 @@ func (c *Config) ClientRegistry(d Dependencies) (ClientRegistry, error) {
 	return c.clients, nil
 }
 
+// RelayRegistry returns the relay configuration of Ethereum clients. Only
+// clients with the relay block are included.
+func (c *Config) RelayRegistry() RelayRegistry {
+	if c == nil {
+		return nil
+	}
+	relays := make(RelayRegistry)
+	for _, clientCfg := range c.Clients {
+		if clientCfg.Relay != nil {
+			relays[clientCfg.Name] = *clientCfg.Relay
+		}
+	}
+	return relays
+}
+
+// ClientNames returns the names of configured Ethereum clients. It does
+// not create the clients, so it may be used to validate references to them.
+func (c *Config) ClientNames() []string {
+	if c == nil {
+		return nil
+	}
+	names := make([]string, len(c.Clients))
+	for i, clientCfg := range c.Clients {
+		names[i] = clientCfg.Name
+	}
+	return names
+}
+
 func (c *Config) prepare(d Dependencies) error {
 	if c.prepared {
 		return nil
@@ -276,13 +349,25 @@ func (c *ConfigKey) Key(logger log.Logger) (wallet.Key, error) {
 	}
 
 	// Get passphrase.
-	passphrase, err := readAccountPassphrase(c.PassphraseFile)
-	if err != nil {
-		return nil, &hcl.Diagnostic{
-			Severity: hcl.DiagError,
-			Summary:  "Validation error",
-			Detail:   fmt.Sprintf("Failed to read Ethereum key passphrase: %v", err),
-			Subject:  c.Content.Attributes["passphrase_file"].Range.Ptr(),
+	passphrase := c.Passphrase
+	if len(c.PassphraseFile) > 0 {
+		if len(c.Passphrase) > 0 {
+			return nil, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Validation error",
+				Detail:   "Only one of passphrase and passphrase_file can be set",
+				Subject:  c.Content.Attributes["passphrase"].Range.Ptr(),
+			}
+		}
+		var err error
+		passphrase, err = readAccountPassphrase(c.PassphraseFile)
+		if err != nil {
+			return nil, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Validation error",
+				Detail:   fmt.Sprintf("Failed to read Ethereum key passphrase: %v", err),
+				Subject:  c.Content.Attributes["passphrase_file"].Range.Ptr(),
+			}
 		}
 	}
 
@@ -315,54 +400,9 @@ func (c *ConfigClient) Client(logger log.Logger, keys KeyRegistry) (rpc.RPC, err
 		return c.client, nil
 	}
 
-	// Validate the client configuration.
-	if len(c.Name) == 0 {
-		return nil, &hcl.Diagnostic{
-			Severity: hcl.DiagError,
-			Summary:  "Validation error",
-			Detail:   "Ethereum client name is required",
-			Subject:  c.Content.Attributes["name"].Range.Ptr(),
-		}
-	}
-	if !nameRegexp.MatchString(c.Name) {
-		return nil, &hcl.Diagnostic{
-			Severity: hcl.DiagError,
-			Summary:  "Validation error",
-			Detail:   "Ethereum client name must contain only alphanumeric characters and underscores",
-			Subject:  c.Content.Attributes["name"].Range.Ptr(),
-		}
-	}
-	if len(c.RPCURLs) == 0 {
-		return nil, &hcl.Diagnostic{
-			Severity: hcl.DiagError,
-			Summary:  "Validation error",
-			Detail:   "At least one RPC URL is required",
-			Subject:  c.Content.Attributes["rpc_urls"].Range.Ptr(),
-		}
-	}
-	if c.Timeout == 0 {
-		c.Timeout = defaultTotalTimeout
-	}
-	if c.GracefulTimeout == 0 {
-		c.GracefulTimeout = defaultGracefulTimeout
-	}
-	if c.Timeout < 1 {
-		return nil, &hcl.Diagnostic{
-			Severity: hcl.DiagError,
-			Summary:  "Validation error",
-			Detail:   "Timeout cannot be less than one second",
-			Subject:  c.Content.Attributes["timeout"].Range.Ptr(),
-		}
-	}
-	if c.GracefulTimeout < 1 {
-		return nil, &hcl.Diagnostic{
-			Severity: hcl.DiagError,
-			Summary:  "Validation error",
-			Detail:   "Graceful timeout cannot be less than one second",
-			Subject:  c.Content.Attributes["graceful_timeout"].Range.Ptr(),
-		}
+	if err := c.validate(); err != nil {
+		return nil, err
 	}
-
 	// Create the RPC client.
 	rpcTransport, err := c.transport(logger)
 	if err != nil {
@@ -477,23 +517,104 @@ func (c *ConfigClient) Client(logger log.Logger, keys KeyRegistry) (rpc.RPC, err
 	return client, nil
 }
 
-func (c *ConfigClient) transport(logger log.Logger) (transport.Transport, error) {
-	var err error
+// Splitter returns an RPC-Splitter HTTP handler for the client's RPC URLs.
+func (c *ConfigClient) Splitter(logger log.Logger) (http.Handler, error) {
+	if c == nil {
+		return nil, fmt.Errorf("ethereum config: client is not configured")
+	}
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	splitter, err := rpcsplitter.NewServer(c.splitterOptions(logger)...)
+	if err != nil {
+		return nil, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Runtime error",
+			Detail:   fmt.Sprintf("Failed to create RPC-Splitter: %v", err),
+			Subject:  c.Range.Ptr(),
+		}
+	}
+	return splitter, nil
+}
+
+// validate validates the client name, RPC URLs and RPC-Splitter settings
+// and sets the default values.
+func (c *ConfigClient) validate() error {
+	if len(c.Name) == 0 {
+		return &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Validation error",
+			Detail:   "Ethereum client name is required",
+			Subject:  c.Content.Attributes["name"].Range.Ptr(),
+		}
+	}
+	if !nameRegexp.MatchString(c.Name) {
+		return &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Validation error",
+			Detail:   "Ethereum client name must contain only alphanumeric characters and underscores",
+			Subject:  c.Content.Attributes["name"].Range.Ptr(),
+		}
+	}
+	if len(c.RPCURLs) == 0 {
+		return &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Validation error",
+			Detail:   "At least one RPC URL is required",
+			Subject:  c.Content.Attributes["rpc_urls"].Range.Ptr(),
+		}
+	}
+	if c.Timeout == 0 {
+		c.Timeout = defaultTotalTimeout
+	}
+	if c.GracefulTimeout == 0 {
+		c.GracefulTimeout = defaultGracefulTimeout
+	}
+	if c.CacheSize == 0 {
+		c.CacheSize = defaultCacheSize
+	}
+	if c.CacheConfirmations == 0 {
+		c.CacheConfirmations = defaultCacheConfirmations
+	}
+	if c.Timeout < 1 {
+		return &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Validation error",
+			Detail:   "Timeout cannot be less than one second",
+			Subject:  c.Content.Attributes["timeout"].Range.Ptr(),
+		}
+	}
+	if c.GracefulTimeout < 1 {
+		return &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Validation error",
+			Detail:   "Graceful timeout cannot be less than one second",
+			Subject:  c.Content.Attributes["graceful_timeout"].Range.Ptr(),
+		}
+	}
+	return nil
+}
+
+func (c *ConfigClient) splitterOptions(logger log.Logger) []rpcsplitter.Option {
 	rpcURLs := make([]string, len(c.RPCURLs))
 	for i, u := range c.RPCURLs {
 		rpcURLs[i] = u.String()
 	}
-	// In theory, we don't need to use RPC-Splitter for a single endpoint, but
-	// to make the application behavior consistent we use it.
-	splitter, err := rpcsplitter.NewTransport(
-		splitterVirtualHost,
-		nil,
+	return []rpcsplitter.Option{
 		rpcsplitter.WithEndpoints(rpcURLs),
-		rpcsplitter.WithTotalTimeout(time.Second*time.Duration(c.Timeout)),
-		rpcsplitter.WithGracefulTimeout(time.Second*time.Duration(c.GracefulTimeout)),
+		rpcsplitter.WithTotalTimeout(time.Second * time.Duration(c.Timeout)),
+		rpcsplitter.WithGracefulTimeout(time.Second * time.Duration(c.GracefulTimeout)),
 		rpcsplitter.WithRequirements(minimumRequiredResponses(len(c.RPCURLs)), int(c.MaxBlocksBehind)),
+		rpcsplitter.WithCache(int(c.CacheSize), c.CacheConfirmations),
+		rpcsplitter.WithHealth(rpcsplitter.HealthConfig{MaxEndpoints: int(c.MaxEndpoints)}),
 		rpcsplitter.WithLogger(logger),
-	)
+	}
+}
+
+func (c *ConfigClient) transport(logger log.Logger) (transport.Transport, error) {
+	// In theory, we don't need to use RPC-Splitter for a single endpoint, but
+	// to make the application behavior consistent we use it.
+	splitter, err := rpcsplitter.NewTransport(splitterVirtualHost, nil, c.splitterOptions(logger)...)
 	if err != nil {
 		return nil, &hcl.Diagnostic{
 			Severity: hcl.DiagError,
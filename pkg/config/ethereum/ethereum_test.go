@@ -9,6 +9,7 @@ import (
 
 	"github.com/orcfax/oracle-suite/pkg/config"
 	"github.com/orcfax/oracle-suite/pkg/log/null"
+	"github.com/orcfax/oracle-suite/pkg/secrets"
 )
 
 func TestConfig(t *testing.T) {
@@ -41,6 +42,9 @@ func TestConfig(t *testing.T) {
 				assert.Equal(t, uint32(10), cfg.Clients[1].Timeout)
 				assert.Equal(t, uint32(5), cfg.Clients[1].GracefulTimeout)
 				assert.Equal(t, uint64(100), cfg.Clients[1].MaxBlocksBehind)
+				assert.Equal(t, uint32(500), cfg.Clients[1].CacheSize)
+				assert.Equal(t, uint64(12), cfg.Clients[1].CacheConfirmations)
+				assert.Equal(t, uint32(3), cfg.Clients[1].MaxEndpoints)
 				assert.Equal(t, "key2", cfg.Clients[1].EthereumKey)
 				assert.Equal(t, uint64(1), cfg.Clients[1].ChainID)
 				assert.Equal(t, "eip1559", cfg.Clients[1].TransactionType)
@@ -49,6 +53,10 @@ func TestConfig(t *testing.T) {
 				assert.Equal(t, big.NewInt(1000000000000), cfg.Clients[1].MaxGasFee)
 				assert.Equal(t, big.NewInt(1000000000000), cfg.Clients[1].MaxGasPriorityFee)
 				assert.Equal(t, big.NewInt(10000000), cfg.Clients[1].MaxGasLimit)
+				require.NotNil(t, cfg.Clients[1].Relay)
+				assert.Equal(t, uint32(30), cfg.Clients[1].Relay.Interval)
+				assert.Equal(t, uint32(4), cfg.Clients[1].Relay.MaxConcurrency)
+				assert.Equal(t, uint64(1000000), cfg.Clients[1].Relay.GasUsageCap)
 			},
 		},
 		{
@@ -76,6 +84,36 @@ func TestConfig(t *testing.T) {
 				assert.NotNil(t, clients["client2"])
 			},
 		},
+		{
+			name: "passphrase secret",
+			path: "secret.hcl",
+			test: func(t *testing.T, cfg *Config) {
+				assert.NotEmpty(t, cfg.Keys[0].Passphrase)
+				assert.Equal(t, secrets.Redacted, secrets.Redact(cfg.Keys[0].Passphrase))
+
+				keys, err := cfg.KeyRegistry(Dependencies{Logger: null.New()})
+				require.NoError(t, err)
+				assert.Equal(t, "0x2d800d93b065ce011af83f316cef9f0d005b0aa4", keys["key2"].Address().String())
+			},
+		},
+		{
+			name: "passphrase conflict",
+			path: "secret-conflict.hcl",
+			test: func(t *testing.T, cfg *Config) {
+				_, err := cfg.KeyRegistry(Dependencies{Logger: null.New()})
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "Only one of passphrase and passphrase_file can be set")
+			},
+		},
+		{
+			name: "relay registry",
+			path: "config.hcl",
+			test: func(t *testing.T, cfg *Config) {
+				relays := cfg.RelayRegistry()
+				require.Len(t, relays, 1)
+				assert.Equal(t, uint32(30), relays["client2"].Interval)
+			},
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -26,6 +26,9 @@ client "client2" {
   timeout                     = 10
   graceful_timeout            = 5
   max_blocks_behind           = 100
+  cache_size                  = 500
+  cache_confirmations         = 12
+  max_endpoints               = 3
   ethereum_key                = "key2"
   chain_id                    = 1
   tx_type                     = "eip1559"
@@ -34,4 +37,10 @@ client "client2" {
   max_gas_fee                 = 1000000000000
   max_gas_priority_fee        = 1000000000000
   max_gas_limit               = 10000000
+
+  relay {
+    interval        = 30
+    max_concurrency = 4
+    gas_usage_cap   = 1000000
+  }
 }
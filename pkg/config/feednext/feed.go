@@ -29,6 +29,7 @@ import (
 
 	"github.com/orcfax/oracle-suite/pkg/log"
 	"github.com/orcfax/oracle-suite/pkg/transport"
+	"github.com/orcfax/oracle-suite/pkg/util/sliceutil"
 	"github.com/orcfax/oracle-suite/pkg/util/timeutil"
 )
 
@@ -107,3 +108,23 @@ func (c *Config) ConfigureFeed(d Dependencies) (*feed.Feed, error) {
 	c.feed = feedService
 	return feedService, nil
 }
+
+// Validate checks that data models published by the feed are defined.
+// DataModels is a list of data models provided by the data provider.
+//
+// Undefined data models are reported as warnings, because the feed still
+// publishes the other data models.
+func (c *Config) Validate(dataModels []string) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	for _, model := range c.DataModels {
+		if !sliceutil.Contains(dataModels, model) {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagWarning,
+				Summary:  "Validation warning",
+				Detail:   fmt.Sprintf("Data model %q is not defined and will not be published", model),
+				Subject:  c.Content.Attributes["data_models"].Range.Ptr(),
+			})
+		}
+	}
+	return diags
+}
@@ -17,23 +17,31 @@ package ghostnext
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/hashicorp/hcl/v2"
 
 	"github.com/orcfax/oracle-suite/config"
+	pkgConfig "github.com/orcfax/oracle-suite/pkg/config"
 	configGoferNext "github.com/orcfax/oracle-suite/pkg/config/dataprovider"
 	ethereumConfig "github.com/orcfax/oracle-suite/pkg/config/ethereum"
 	feedConfig "github.com/orcfax/oracle-suite/pkg/config/feednext"
 	loggerConfig "github.com/orcfax/oracle-suite/pkg/config/logger"
+	tracingConfig "github.com/orcfax/oracle-suite/pkg/config/tracing"
 	transportConfig "github.com/orcfax/oracle-suite/pkg/config/transport"
 	"github.com/orcfax/oracle-suite/pkg/feed"
 	"github.com/orcfax/oracle-suite/pkg/log"
 	pkgSupervisor "github.com/orcfax/oracle-suite/pkg/supervisor"
+	pkgTracing "github.com/orcfax/oracle-suite/pkg/tracing"
 	pkgTransport "github.com/orcfax/oracle-suite/pkg/transport"
 	"github.com/orcfax/oracle-suite/pkg/transport/messages"
+	"github.com/orcfax/oracle-suite/pkg/transport/scoped"
 )
 
+const LoggerTag = "CONFIG_GHOST"
+
 // Config is the configuration for Ghost.
 type Config struct {
 	Ghost     feedConfig.Config      `hcl:"ghost,block"`
@@ -41,6 +49,7 @@ type Config struct {
 	Ethereum  ethereumConfig.Config  `hcl:"ethereum,block"`
 	Transport transportConfig.Config `hcl:"transport,block"`
 	Logger    *loggerConfig.Config   `hcl:"logger,block,optional"`
+	Tracing   *tracingConfig.Config  `hcl:"tracing,block,optional"`
 
 	// HCL fields:
 	Remain  hcl.Body        `hcl:",remain"` // To ignore unknown blocks.
@@ -58,8 +67,27 @@ func (Config) DefaultEmbeds() [][]byte {
 	}
 }
 
+// Validate implements the config.Validator interface.
+func (c *Config) Validate() hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	clients := c.Ethereum.ClientNames()
+	diags = append(diags, c.Gofer.Validate(clients)...)
+	diags = append(diags, c.Ghost.Validate(c.Gofer.DataModelNames())...)
+	diags = append(diags, c.Transport.Validate(clients)...)
+	return diags
+}
+
+// Resolved implements the config.Resolvable interface.
+func (c *Config) Resolved() (pkgConfig.Snapshot, error) {
+	return pkgConfig.NewSnapshot(c.Gofer.Entities())
+}
+
 // Services returns the services configured for Lair.
 func (c *Config) Services(baseLogger log.Logger, appName string, appVersion string) (pkgSupervisor.Service, error) {
+	snapshot, err := c.snapshot()
+	if err != nil {
+		return nil, err
+	}
 	logger, err := c.Logger.Logger(loggerConfig.Dependencies{
 		AppName:    appName,
 		AppVersion: appVersion,
@@ -68,6 +96,15 @@ func (c *Config) Services(baseLogger log.Logger, appName string, appVersion stri
 	if err != nil {
 		return nil, err
 	}
+	tracer, err := c.Tracing.Tracer(tracingConfig.Dependencies{
+		AppName:    appName,
+		AppVersion: appVersion,
+		Logger:     logger,
+	})
+	if err != nil {
+		return nil, err
+	}
+	pkgTracing.SetDefault(tracer)
 	keys, err := c.Ethereum.KeyRegistry(ethereumConfig.Dependencies{Logger: logger})
 	if err != nil {
 		return nil, err
@@ -93,28 +130,77 @@ func (c *Config) Services(baseLogger log.Logger, appName string, appVersion stri
 	if err != nil {
 		return nil, err
 	}
+	subs := scoped.NewSubscriptions(transport)
+	config := c.copy()
+	feedGroup, feedService, err := c.feedServices(keys, clients, subs, logger)
+	if err != nil {
+		return nil, err
+	}
+	return &Services{
+		Feed:            feedService,
+		Transport:       transport,
+		Logger:          logger,
+		Tracer:          tracer,
+		config:          config,
+		snapshot:        snapshot,
+		transportConfig: &c.Transport,
+		keys:            keys,
+		clients:         clients,
+		subs:            subs,
+		feedGroup:       feedGroup,
+	}, nil
+}
+
+// snapshot returns a snapshot of the configuration used to detect changes
+// on reload.
+func (c *Config) snapshot() (pkgConfig.Snapshot, error) {
+	return pkgConfig.NewSnapshot(map[string]any{
+		"ghost":     &c.Ghost,
+		"gofer":     &c.Gofer,
+		"ethereum":  &c.Ethereum,
+		"transport": &c.Transport,
+		"logger":    c.Logger,
+		"tracing":   c.Tracing,
+	})
+}
+
+// feedServices returns the feed service together with a service that
+// manages it. The feed uses a scoped transport, so it can be restarted
+// without stopping the transport.
+func (c *Config) feedServices(
+	keys ethereumConfig.KeyRegistry,
+	clients ethereumConfig.ClientRegistry,
+	subs *scoped.Subscriptions,
+	logger log.Logger,
+) (pkgSupervisor.Service, *feed.Feed, error) {
+	scopedTransport := scoped.New(subs)
 	dataProvider, err := c.Gofer.ConfigureDataProvider(configGoferNext.Dependencies{
 		Clients: clients,
 		Logger:  logger,
 	})
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	feedService, err := c.Ghost.ConfigureFeed(feedConfig.Dependencies{
 		KeysRegistry: keys,
 		DataProvider: dataProvider,
-		Transport:    transport,
+		Transport:    scopedTransport,
 		Logger:       logger,
 	})
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	group := pkgSupervisor.New(logger)
+	group.Watch(scopedTransport, feedService)
+	return group, feedService, nil
+}
 
-	return &Services{
-		Feed:      feedService,
-		Transport: transport,
-		Logger:    logger,
-	}, nil
+// copy returns a shallow copy of the config. Services created from the
+// config are cached, so the copy must be made before they are created to
+// be able to create new instances of services from it later.
+func (c *Config) copy() *Config {
+	cp := *c
+	return &cp
 }
 
 // Services returns the services that are configured from the Config struct.
@@ -122,8 +208,20 @@ type Services struct {
 	Feed      *feed.Feed
 	Transport pkgTransport.Service
 	Logger    log.Logger
+	Tracer    *pkgTracing.Tracer
 
-	supervisor *pkgSupervisor.Supervisor
+	mu              sync.Mutex
+	ctx             context.Context
+	config          *Config // Config used to create the running feed.
+	prevConfig      *Config // Config used before the last reload, used to roll back.
+	snapshot        pkgConfig.Snapshot
+	transportConfig *transportConfig.Config // Config used to create the running transport.
+	keys            ethereumConfig.KeyRegistry
+	clients         ethereumConfig.ClientRegistry
+	subs            *scoped.Subscriptions
+	feedGroup       pkgSupervisor.Service
+	reloadCh        chan pkgSupervisor.Service
+	supervisor      *pkgSupervisor.Supervisor
 }
 
 // Start implements the supervisor.Service interface.
@@ -131,11 +229,22 @@ func (s *Services) Start(ctx context.Context) error {
 	if s.supervisor != nil {
 		return fmt.Errorf("services already started")
 	}
+	s.mu.Lock()
+	s.ctx = ctx
+	s.reloadCh = make(chan pkgSupervisor.Service)
+	s.mu.Unlock()
 	s.supervisor = pkgSupervisor.New(s.Logger)
-	s.supervisor.Watch(s.Transport, s.Feed)
+	s.supervisor.Watch(s.Transport, pkgSupervisor.NewReloader(pkgSupervisor.ReloaderConfig{
+		Factory:  s.feedFactory,
+		Rollback: s.feedRollback,
+		Logger:   s.Logger,
+	}))
 	if l, ok := s.Logger.(pkgSupervisor.Service); ok {
 		s.supervisor.Watch(l)
 	}
+	if s.Tracer != nil {
+		s.supervisor.Watch(s.Tracer)
+	}
 	return s.supervisor.Start(ctx)
 }
 
@@ -143,3 +252,120 @@ func (s *Services) Start(ctx context.Context) error {
 func (s *Services) Wait() <-chan error {
 	return s.supervisor.Wait()
 }
+
+// Reload implements the supervisor.Reloadable interface.
+//
+// The ghost and gofer blocks are reloaded by restarting the feed service.
+// The transport keeps running. If the new feed fails to start, the previous
+// one is restored. Changes to the WebAPI address book are applied to the
+// running transport. Changes to other blocks are logged and ignored until
+// the next restart.
+func (s *Services) Reload(cfg pkgSupervisor.Config) error {
+	c, ok := cfg.(*Config)
+	if !ok {
+		return fmt.Errorf("unexpected config type %T", cfg)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.reloadCh == nil {
+		return fmt.Errorf("services are not started")
+	}
+	snapshot, err := c.snapshot()
+	if err != nil {
+		return err
+	}
+	logger := s.Logger.WithField("tag", LoggerTag)
+	reload := false
+	for _, name := range s.snapshot.Changed(snapshot) {
+		switch name {
+		case "ghost", "gofer":
+			reload = true
+		case "transport":
+			err := c.Transport.ReloadAddressBook(s.transportConfig, transportConfig.Dependencies{
+				Clients: s.clients,
+				Logger:  s.Logger,
+			})
+			switch {
+			case errors.Is(err, transportConfig.ErrRestartRequired):
+				logger.WithField("block", name).Warn("Configuration change requires a restart")
+			case err != nil:
+				return err
+			default:
+				s.snapshot["transport"] = snapshot["transport"]
+				logger.Info("Address book reloaded")
+			}
+		default:
+			logger.WithField("block", name).Warn("Configuration change requires a restart")
+		}
+	}
+	if !reload {
+		logger.Info("No feed configuration changes to reload")
+		return nil
+	}
+	config := c.copy()
+	feedGroup, feedService, err := c.feedServices(s.keys, s.clients, s.subs, s.Logger)
+	if err != nil {
+		return err
+	}
+	select {
+	case s.reloadCh <- feedGroup:
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	}
+	s.prevConfig = s.config
+	s.applyFeedConfig(config, snapshot, feedService)
+	logger.Info("Configuration reloaded")
+	return nil
+}
+
+// feedRollback creates the feed from the config used before the last
+// reload. It is used by the reloader if the reloaded feed fails to start.
+func (s *Services) feedRollback() (pkgSupervisor.Service, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.prevConfig == nil {
+		return nil, fmt.Errorf("no previous configuration")
+	}
+	snapshot, err := s.prevConfig.snapshot()
+	if err != nil {
+		return nil, err
+	}
+	config := s.prevConfig
+	feedGroup, feedService, err := s.prevConfig.copy().feedServices(s.keys, s.clients, s.subs, s.Logger)
+	if err != nil {
+		return nil, err
+	}
+	s.prevConfig = nil
+	s.applyFeedConfig(config, snapshot, feedService)
+	s.Logger.WithField("tag", LoggerTag).Warn("Configuration rolled back")
+	return feedGroup, nil
+}
+
+// applyFeedConfig updates the services after the feed is recreated from
+// the given config.
+//
+// Must be called with s.mu locked.
+func (s *Services) applyFeedConfig(config *Config, snapshot pkgConfig.Snapshot, feedService *feed.Feed) {
+	s.config = config
+	s.snapshot["ghost"] = snapshot["ghost"]
+	s.snapshot["gofer"] = snapshot["gofer"]
+	s.Feed = feedService
+}
+
+// feedFactory is the factory for the feed reloader. It starts the initial
+// feed and then every feed sent by Reload.
+func (s *Services) feedFactory(ctx context.Context, ch chan pkgSupervisor.Service) error {
+	next := s.feedGroup
+	for {
+		select {
+		case ch <- next:
+		case <-ctx.Done():
+			return nil
+		}
+		select {
+		case next = <-s.reloadCh:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
@@ -16,10 +16,12 @@
 package ghostnext
 
 import (
+	"context"
 	"os"
 	"strings"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/orcfax/oracle-suite/pkg/config"
@@ -51,6 +53,88 @@ func TestConfig(t *testing.T) {
 	}
 }
 
+func TestReload(t *testing.T) {
+	ctx, ctxCancel := context.WithCancel(context.Background())
+	defer ctxCancel()
+
+	load := func(path string) *Config {
+		var cfg Config
+		require.NoError(t, config.LoadFiles(&cfg, []string{"./testdata/" + path}))
+		return &cfg
+	}
+
+	cfg := load("reload.hcl")
+	srv, err := cfg.Services(null.New(), "", "")
+	require.NoError(t, err)
+	services := srv.(*Services)
+	require.NoError(t, services.Start(ctx))
+	transport := services.Transport
+	feed := services.Feed
+
+	// Unchanged configuration does not restart the feed.
+	require.NoError(t, services.Reload(load("reload.hcl")))
+	assert.Same(t, feed, services.Feed)
+
+	// Invalid configuration is rejected and the feed keeps running.
+	require.Error(t, services.Reload(load("reload-invalid.hcl")))
+	assert.Same(t, feed, services.Feed)
+
+	// Changed configuration restarts the feed, but not the transport.
+	require.NoError(t, services.Reload(load("reload-changed.hcl")))
+	assert.NotSame(t, feed, services.Feed)
+	assert.Same(t, transport, services.Transport)
+
+	// Rollback restores the feed from the previous configuration.
+	reloaded := services.Feed
+	_, err = services.feedRollback()
+	require.NoError(t, err)
+	assert.NotSame(t, reloaded, services.Feed)
+	_, err = services.feedRollback()
+	require.Error(t, err)
+
+	ctxCancel()
+	<-services.Wait()
+}
+
+func TestValidate(t *testing.T) {
+	var cfg Config
+	require.NoError(t, config.LoadFiles(&cfg, []string{"./testdata/validate.hcl"}))
+
+	diags := cfg.Validate()
+	var details []string
+	for _, diag := range diags {
+		details = append(details, diag.Detail)
+	}
+	assert.Equal(t, []string{
+		`Origin uniswapV3 uses Ethereum client "client2" which is not configured`,
+		`Median requires at least 3 values but has only 2 sources`,
+		`Unknown origin: kraken`,
+		`Unknown data model: D/C`,
+		`Cycle detected in the data model A/B: reference -> reference -> invert`,
+		`Cycle detected in the data model B/A: reference -> invert -> reference`,
+		`Data model "XTZ/USD" is not defined and will not be published`,
+	}, details)
+	assert.Len(t, diags.Errs(), 6)
+	assert.Equal(t, 28, diags[1].Subject.Start.Line)
+	assert.Equal(t, 31, diags[2].Subject.Start.Line)
+}
+
+func TestResolved(t *testing.T) {
+	var cfg Config
+	require.NoError(t, config.LoadFiles(&cfg, []string{"./testdata/validate.hcl"}))
+
+	snapshot, err := cfg.Resolved()
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		`data_model "A/B"`,
+		`data_model "B/A"`,
+		`data_model "BTC/USD"`,
+		`data_model "C/D"`,
+		`data_model "ETH/USD"`,
+	}, snapshot.Changed(nil))
+	assert.Contains(t, string(snapshot[`data_model "BTC/USD"`]), "min_values = 3")
+}
+
 func TestDefaults(t *testing.T) {
 	cfg := &Config{}
 	require.NoError(t, config.LoadEmbeds(cfg, cfg.DefaultEmbeds()))
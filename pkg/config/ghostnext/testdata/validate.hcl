@@ -0,0 +1,71 @@
+ghost {
+  ethereum_key = "key1"
+  interval     = 60
+
+  data_models = [
+    "BTC/USD",
+    "XTZ/USD",
+  ]
+}
+
+gofer {
+  origin "coinbase" {
+    type = "tick_generic_jq"
+    url  = "https://api.pro.coinbase.com/products/$${ucbase}-$${ucquote}/ticker"
+    jq   = "{price: .price, time: .time, volume: .volume}"
+  }
+
+  origin "uniswapV3" {
+    type = "uniswapV3"
+    contracts "client2" {
+      addresses = {
+        "ETH/USD" = "0x1234567890123456789012345678901234567890"
+      }
+    }
+  }
+
+  data_model "BTC/USD" {
+    median {
+      min_values = 3
+      origin "coinbase" { query = "BTC/USD" }
+      origin "kraken" { query = "BTC/USD" }
+    }
+  }
+
+  data_model "ETH/USD" {
+    origin "uniswapV3" { query = "ETH/USD" }
+  }
+
+  data_model "A/B" {
+    reference { data_model = "B/A" }
+  }
+
+  data_model "B/A" {
+    invert {
+      reference { data_model = "A/B" }
+    }
+  }
+
+  data_model "C/D" {
+    reference { data_model = "D/C" }
+  }
+}
+
+ethereum {
+  rand_keys = ["key1"]
+
+  client "client1" {
+    rpc_urls     = ["https://rpc1.example"]
+    chain_id     = 1
+    ethereum_key = "key1"
+  }
+}
+
+transport {
+  libp2p {
+    feeds             = ["0x1234567890123456789012345678901234567890"]
+    listen_addrs      = ["/ip4/127.0.0.1/tcp/0"]
+    disable_discovery = true
+    ethereum_key      = "key1"
+  }
+}
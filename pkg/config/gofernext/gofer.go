@@ -23,12 +23,15 @@ import (
 	"github.com/hashicorp/hcl/v2"
 
 	"github.com/orcfax/oracle-suite/config"
+	pkgConfig "github.com/orcfax/oracle-suite/pkg/config"
 	dataproviderConfig "github.com/orcfax/oracle-suite/pkg/config/dataprovider"
 	ethereumConfig "github.com/orcfax/oracle-suite/pkg/config/ethereum"
 	loggerConfig "github.com/orcfax/oracle-suite/pkg/config/logger"
+	tracingConfig "github.com/orcfax/oracle-suite/pkg/config/tracing"
 	"github.com/orcfax/oracle-suite/pkg/datapoint"
 	"github.com/orcfax/oracle-suite/pkg/log"
 	pkgSupervisor "github.com/orcfax/oracle-suite/pkg/supervisor"
+	pkgTracing "github.com/orcfax/oracle-suite/pkg/tracing"
 )
 
 // Config is the configuration for Gofer.
@@ -36,6 +39,7 @@ type Config struct {
 	Gofer    dataproviderConfig.Config `hcl:"gofer,block"`
 	Ethereum *ethereumConfig.Config    `hcl:"ethereum,block,optional"`
 	Logger   *loggerConfig.Config      `hcl:"logger,block,optional"`
+	Tracing  *tracingConfig.Config     `hcl:"tracing,block,optional"`
 
 	// HCL fields:
 	Remain  hcl.Body        `hcl:",remain"` // To ignore unknown blocks.
@@ -51,10 +55,21 @@ func (Config) DefaultEmbeds() [][]byte {
 	}
 }
 
+// Validate implements the config.Validator interface.
+func (c *Config) Validate() hcl.Diagnostics {
+	return c.Gofer.Validate(c.Ethereum.ClientNames())
+}
+
+// Resolved implements the config.Resolvable interface.
+func (c *Config) Resolved() (pkgConfig.Snapshot, error) {
+	return pkgConfig.NewSnapshot(c.Gofer.Entities())
+}
+
 // Services returns the services that are configured from the Config struct.
 type Services struct {
 	DataProvider datapoint.Provider
 	Logger       log.Logger
+	Tracer       *pkgTracing.Tracer
 
 	supervisor *pkgSupervisor.Supervisor
 }
@@ -71,6 +86,9 @@ func (s *Services) Start(ctx context.Context) error {
 	if l, ok := s.Logger.(pkgSupervisor.Service); ok {
 		s.supervisor.Watch(l)
 	}
+	if s.Tracer != nil {
+		s.supervisor.Watch(s.Tracer)
+	}
 	return s.supervisor.Start(ctx)
 }
 
@@ -89,6 +107,15 @@ func (c *Config) Services(baseLogger log.Logger, appName, appVersion string) (pk
 	if err != nil {
 		return nil, err
 	}
+	tracer, err := c.Tracing.Tracer(tracingConfig.Dependencies{
+		AppName:    appName,
+		AppVersion: appVersion,
+		Logger:     logger,
+	})
+	if err != nil {
+		return nil, err
+	}
+	pkgTracing.SetDefault(tracer)
 
 	/* Ethereum client is disabled for Orcfax's purposes. If it is needed
 	   in the future then more granular config might be useful here. E.g.
@@ -111,5 +138,6 @@ func (c *Config) Services(baseLogger log.Logger, appName, appVersion string) (pk
 	return &Services{
 		DataProvider: priceProvider,
 		Logger:       logger,
+		Tracer:       tracer,
 	}, nil
 }
@@ -22,13 +22,23 @@ import (
 	"regexp"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/hcl/v2"
 
 	"github.com/orcfax/oracle-suite/pkg/config"
+	"github.com/orcfax/oracle-suite/pkg/httpserver"
+	"github.com/orcfax/oracle-suite/pkg/httpserver/middleware"
 	"github.com/orcfax/oracle-suite/pkg/log"
 	"github.com/orcfax/oracle-suite/pkg/log/chain"
 	"github.com/orcfax/oracle-suite/pkg/log/grafana"
+	"github.com/orcfax/oracle-suite/pkg/log/prometheus"
+	"github.com/orcfax/oracle-suite/pkg/metrics"
+)
+
+const (
+	defaultMetricsPath    = "/metrics"
+	defaultMetricsTimeout = 10 * time.Second
 )
 
 type Dependencies struct {
@@ -41,6 +51,9 @@ type Config struct {
 	// Grafana is a configuration for a Grafana logger.
 	Grafana *grafanaLogger `hcl:"grafana,block,optional"`
 
+	// Prometheus is a configuration for a Prometheus logger.
+	Prometheus *prometheusLogger `hcl:"prometheus,block,optional"`
+
 	// HCL fields:
 	Range   hcl.Range       `hcl:",range"`
 	Content hcl.BodyContent `hcl:",content"`
@@ -104,6 +117,58 @@ type grafanaMetric struct {
 	Content hcl.BodyContent `hcl:",content"`
 }
 
+type prometheusLogger struct {
+	// ListenAddr is the address on which metrics are exposed over HTTP.
+	// The address must be in the format `host:port`.
+	ListenAddr string `hcl:"listen_addr"`
+
+	// Path is the HTTP path under which metrics are exposed.
+	Path string `hcl:"path,optional"`
+
+	// Metrics is a list of metrics to expose.
+	Metrics []prometheusMetric `hcl:"metric,block"`
+
+	// HCL fields:
+	Range   hcl.Range       `hcl:",range"`
+	Content hcl.BodyContent `hcl:",content"`
+}
+
+type prometheusMetric struct {
+	// MatchMessage is a regular expression to match a log message.
+	MatchMessage string `hcl:"match_message"`
+
+	// MatchFields is a map of regular expressions to match log fields.
+	MatchFields map[string]string `hcl:"match_fields,optional"`
+
+	// Value is a dot-separated path of the field with the metric value.
+	// If empty, the value 1 will be used as the metric value.
+	Value string `hcl:"value,optional"`
+
+	// ScaleFactor Scales the value by the specified number. If it is zero,
+	// scaling is not applied.
+	ScaleFactor float64 `hcl:"scale_factor,optional"`
+
+	// Name of metric. It can contain references to log fields in the format
+	// `%{path}`, where path is the dot-separated path to the field.
+	// Characters not allowed in Prometheus metric names are replaced with
+	// underscores.
+	Name string `hcl:"name"`
+
+	// Labels is a map of metric labels. Values can contain references to
+	// log fields in the format `%{path}`, where path is the dot-separated
+	// path to the field.
+	Labels map[string]string `hcl:"labels,optional"`
+
+	// Type is a type of the metric. Possible values are:
+	// - "gauge" - set the metric to the last value
+	// - "counter" - add the value to the metric
+	Type string `hcl:"type,optional"`
+
+	// HCL fields:
+	Range   hcl.Range       `hcl:",range"`
+	Content hcl.BodyContent `hcl:",content"`
+}
+
 func (c *Config) Logger(d Dependencies) (log.Logger, error) {
 	if c == nil {
 		return d.BaseLogger, nil
@@ -119,6 +184,13 @@ func (c *Config) Logger(d Dependencies) (log.Logger, error) {
 		}
 		loggers = append(loggers, logger)
 	}
+	if c.Prometheus != nil {
+		logger, err := c.prometheusLogger(d)
+		if err != nil {
+			return nil, err
+		}
+		loggers = append(loggers, logger)
+	}
 	logger := chain.New(loggers...)
 	if len(loggers) == 1 {
 		logger = loggers[0]
@@ -147,30 +219,13 @@ func (c *Config) grafanaLogger(d Dependencies) (log.Logger, error) {
 			TransformFunc: scalingFunc(cfg.ScaleFactor),
 		}
 
-		// Compile the regular expression for a message:
-		metric.MatchMessage, err = regexp.Compile(cfg.MatchMessage)
+		metric.MatchMessage, metric.MatchFields, err = compileMatchRules(
+			cfg.MatchMessage,
+			cfg.MatchFields,
+			cfg.Content,
+		)
 		if err != nil {
-			return nil, &hcl.Diagnostic{
-				Severity: hcl.DiagError,
-				Summary:  "Validation error",
-				Detail:   fmt.Sprintf("Invalid regular expression: %s", cfg.MatchMessage),
-				Subject:  cfg.Content.Attributes["match_message"].NameRange.Ptr(),
-			}
-		}
-
-		// Compile regular expressions for log fields:
-		metric.MatchFields = map[string]*regexp.Regexp{}
-		for f, p := range cfg.MatchFields {
-			rx, err := regexp.Compile(p)
-			if err != nil {
-				return nil, &hcl.Diagnostic{
-					Severity: hcl.DiagError,
-					Summary:  "Validation error",
-					Detail:   fmt.Sprintf("Invalid regular expression: %s", p),
-					Subject:  cfg.Content.Attributes["match_fields"].NameRange.Ptr(),
-				}
-			}
-			metric.MatchFields[f] = rx
+			return nil, err
 		}
 
 		// On duplicate:
@@ -220,6 +275,109 @@ func (c *Config) grafanaLogger(d Dependencies) (log.Logger, error) {
 	return logger, nil
 }
 
+func (c *Config) prometheusLogger(d Dependencies) (log.Logger, error) {
+	var err error
+	var promMetrics []prometheus.Metric
+	for _, cfg := range c.Prometheus.Metrics {
+		metric := prometheus.Metric{
+			Value:         cfg.Value,
+			Name:          cfg.Name,
+			Labels:        cfg.Labels,
+			TransformFunc: scalingFunc(cfg.ScaleFactor),
+		}
+
+		metric.MatchMessage, metric.MatchFields, err = compileMatchRules(
+			cfg.MatchMessage,
+			cfg.MatchFields,
+			cfg.Content,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		// Metric type:
+		switch strings.ToLower(cfg.Type) {
+		case "gauge", "":
+			metric.Type = prometheus.Gauge
+		case "counter":
+			metric.Type = prometheus.Counter
+		default:
+			return nil, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Validation error",
+				Detail:   fmt.Sprintf("Invalid value for type: %s. Possible values are: gauge, counter", cfg.Type),
+				Subject:  cfg.Content.Attributes["type"].NameRange.Ptr(),
+			}
+		}
+
+		promMetrics = append(promMetrics, metric)
+	}
+	path := c.Prometheus.Path
+	if path == "" {
+		path = defaultMetricsPath
+	}
+	registry := metrics.NewMemory(nil)
+	srv := httpserver.New(&http.Server{
+		Addr:              c.Prometheus.ListenAddr,
+		Handler:           http.NotFoundHandler(),
+		ReadTimeout:       defaultMetricsTimeout,
+		ReadHeaderTimeout: defaultMetricsTimeout,
+		WriteTimeout:      defaultMetricsTimeout,
+		IdleTimeout:       defaultMetricsTimeout,
+	})
+	srv.Use(&middleware.Metrics{Path: path, Handler: registry})
+	logger, err := prometheus.New(d.BaseLogger.Level(), prometheus.Config{
+		Metrics:  promMetrics,
+		Registry: registry,
+		Server:   srv,
+		Logger:   d.BaseLogger,
+	})
+	if err != nil {
+		return nil, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Runtime error",
+			Detail:   fmt.Sprintf("Failed to create the Prometheus logger: %s", err),
+			Subject:  c.Range.Ptr(),
+		}
+	}
+	return logger, nil
+}
+
+// compileMatchRules compiles the match_message and match_fields regular
+// expressions of a metric block.
+func compileMatchRules(
+	matchMessage string,
+	matchFields map[string]string,
+	content hcl.BodyContent,
+) (*regexp.Regexp, map[string]*regexp.Regexp, error) {
+	// Compile the regular expression for a message:
+	msgRx, err := regexp.Compile(matchMessage)
+	if err != nil {
+		return nil, nil, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Validation error",
+			Detail:   fmt.Sprintf("Invalid regular expression: %s", matchMessage),
+			Subject:  content.Attributes["match_message"].NameRange.Ptr(),
+		}
+	}
+
+	// Compile regular expressions for log fields:
+	fieldsRx := map[string]*regexp.Regexp{}
+	for f, p := range matchFields {
+		rx, err := regexp.Compile(p)
+		if err != nil {
+			return nil, nil, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Validation error",
+				Detail:   fmt.Sprintf("Invalid regular expression: %s", p),
+				Subject:  content.Attributes["match_fields"].NameRange.Ptr(),
+			}
+		}
+		fieldsRx[f] = rx
+	}
+	return msgRx, fieldsRx, nil
+}
+
 func scalingFunc(sf float64) func(v float64) float64 {
 	if sf == 0 || sf == 1 {
 		return nil
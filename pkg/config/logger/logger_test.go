@@ -65,6 +65,47 @@ func TestConfig(t *testing.T) {
 				assert.NotNil(t, service)
 			},
 		},
+		{
+			name: "prometheus",
+			path: "prometheus.hcl",
+			test: func(t *testing.T, cfg *Config) {
+				assert.NotNil(t, cfg.Prometheus)
+				assert.Equal(t, "127.0.0.1:0", cfg.Prometheus.ListenAddr)
+				assert.Equal(t, "/custom-metrics", cfg.Prometheus.Path)
+
+				require.Len(t, cfg.Prometheus.Metrics, 2)
+				metric := cfg.Prometheus.Metrics[0]
+				assert.Equal(t, "Price updated", metric.MatchMessage)
+				assert.Equal(t, map[string]string{"origin": "binance"}, metric.MatchFields)
+				assert.Equal(t, "price", metric.Value)
+				assert.Equal(t, 0.5, metric.ScaleFactor)
+				assert.Equal(t, "gofer_price", metric.Name)
+				assert.Equal(t, map[string]string{"pair": "%{pair}"}, metric.Labels)
+				assert.Equal(t, "", metric.Type)
+				assert.Equal(t, "counter", cfg.Prometheus.Metrics[1].Type)
+
+				service, err := cfg.Logger(Dependencies{
+					AppName:    "app",
+					AppVersion: suite.Version,
+					BaseLogger: null.New(),
+				})
+				require.NoError(t, err)
+				assert.NotNil(t, service)
+			},
+		},
+		{
+			name: "prometheus-invalid-type",
+			path: "prometheus-invalid-type.hcl",
+			test: func(t *testing.T, cfg *Config) {
+				_, err := cfg.Logger(Dependencies{
+					AppName:    "app",
+					AppVersion: suite.Version,
+					BaseLogger: null.New(),
+				})
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "Invalid value for type: histogram")
+			},
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
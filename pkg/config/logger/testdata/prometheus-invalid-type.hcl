@@ -0,0 +1,9 @@
+prometheus {
+  listen_addr = "127.0.0.1:0"
+
+  metric {
+    match_message = "Price updated"
+    name          = "gofer_price"
+    type          = "histogram"
+  }
+}
@@ -0,0 +1,23 @@
+prometheus {
+  listen_addr = "127.0.0.1:0"
+  path        = "/custom-metrics"
+
+  metric {
+    match_message = "Price updated"
+    match_fields  = {
+      origin = "binance"
+    }
+    value        = "price"
+    scale_factor = 0.5
+    name         = "gofer_price"
+    labels       = {
+      pair = "%%{pair}"
+    }
+  }
+
+  metric {
+    match_message = "Invalid price"
+    name          = "gofer_invalid_prices_total"
+    type          = "counter"
+  }
+}
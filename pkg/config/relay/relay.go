@@ -16,34 +16,61 @@
 package relay
 
 import (
+	"context"
 	"fmt"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/defiweb/go-eth/crypto"
+	"github.com/defiweb/go-eth/rpc"
 	"github.com/defiweb/go-eth/types"
 	"github.com/hashicorp/hcl/v2"
 
 	ethereumConfig "github.com/orcfax/oracle-suite/pkg/config/ethereum"
+	"github.com/orcfax/oracle-suite/pkg/contract/chronicle"
 	"github.com/orcfax/oracle-suite/pkg/datapoint"
+	"github.com/orcfax/oracle-suite/pkg/datapoint/reputation"
 	"github.com/orcfax/oracle-suite/pkg/datapoint/signer"
 	datapointStore "github.com/orcfax/oracle-suite/pkg/datapoint/store"
 	"github.com/orcfax/oracle-suite/pkg/log"
+	"github.com/orcfax/oracle-suite/pkg/metrics"
 	musigStore "github.com/orcfax/oracle-suite/pkg/musig/store"
 	"github.com/orcfax/oracle-suite/pkg/relay"
 	"github.com/orcfax/oracle-suite/pkg/transport"
+	"github.com/orcfax/oracle-suite/pkg/util/maputil"
 	"github.com/orcfax/oracle-suite/pkg/util/timeutil"
 )
 
 type Services struct {
 	Relay      *relay.Relay
+	Registry   *relay.Registry   // Registry is nil if the registry block is not configured.
+	Watcher    *relay.Watcher    // Watcher is nil if the watcher block is not configured.
+	Challenger *relay.Challenger // Challenger is nil if the challenger block is not configured.
 	PriceStore *datapointStore.Store
 	MuSigStore *musigStore.Store
 }
 
 type Dependencies struct {
 	Clients   ethereumConfig.ClientRegistry
+	Relays    ethereumConfig.RelayRegistry
 	Transport transport.Service
 	Logger    log.Logger
+
+	// Scorer is an optional reputation scorer. If nil, the scorer is
+	// created from the reputation block.
+	Scorer *reputation.Scorer
+
+	// Metrics is an optional metrics registry to which the health of relay
+	// workers and watcher alarms are reported.
+	Metrics metrics.Registry
+
+	// PriceStore and MuSigStore are optional stores used instead of creating
+	// new ones. Lists of collected data models are replaced with the ones
+	// required by configured contracts. They are used to keep collected
+	// data points and signatures when relay services are recreated.
+	PriceStore *datapointStore.Store
+	MuSigStore *musigStore.Store
 }
 
 type Config struct {
@@ -56,12 +83,26 @@ type Config struct {
 	// OptimisticScribe is a list of OptimisticScribe contracts to watch.
 	OptimisticScribe []configOptimisticScribe `hcl:"optimistic_scribe,block"`
 
+	// Registry configures discovery of contracts from on-chain registries.
+	Registry *configRegistry `hcl:"registry,block,optional"`
+
+	// Watcher configures verification of values stored in contracts.
+	Watcher *configWatcher `hcl:"watcher,block,optional"`
+
+	// Challenger configures challenging of invalid optimistic pokes.
+	Challenger *configChallenger `hcl:"challenger,block,optional"`
+
+	// Reputation configures scoring of feeds based on the quality of
+	// published data points.
+	Reputation *configReputation `hcl:"reputation,block,optional"`
+
 	// HCL fields:
 	Range   hcl.Range       `hcl:",range"`
 	Content hcl.BodyContent `hcl:",content"`
 
 	// Configured services:
 	services *Services
+	scorer   *reputation.Scorer
 }
 
 type configCommon struct {
@@ -115,6 +156,167 @@ type configOptimisticScribe struct {
 	OptimisticExpiration uint32 `hcl:"optimistic_expiration"`
 }
 
+type configRegistry struct {
+	// EthereumClient is a name of an Ethereum client used to read
+	// the registries.
+	EthereumClient string `hcl:"ethereum_client"`
+
+	// ChainlogAddr is an address of a Chainlog contract from which addresses
+	// of registries are read. Ignored if both WatRegistryAddr and
+	// FeedRegistryAddr are set.
+	ChainlogAddr *types.Address `hcl:"chainlog_addr,optional"`
+
+	// WatRegistryAddr is an address of a WatRegistry contract.
+	WatRegistryAddr *types.Address `hcl:"wat_registry_addr,optional"`
+
+	// FeedRegistryAddr is an address of a FeedRegistry contract.
+	FeedRegistryAddr *types.Address `hcl:"feed_registry_addr,optional"`
+
+	// RelayEthereumClients is a list of Ethereum clients used to relay data
+	// to discovered contracts. Clients are matched with deployments by their
+	// chain IDs.
+	RelayEthereumClients []string `hcl:"relay_ethereum_clients"`
+
+	// DataModels is an optional list of data models to relay. If empty,
+	// all data models found in the registry are relayed.
+	DataModels []string `hcl:"data_models,optional"`
+
+	// Interval is a time in seconds between registry refreshes.
+	Interval uint32 `hcl:"interval,optional"`
+
+	// Median defines a poke policy for discovered Median contracts. If not
+	// set, Median contracts are ignored.
+	Median *configRegistryPolicy `hcl:"median,block,optional"`
+
+	// Scribe defines a poke policy for discovered Scribe contracts. If not
+	// set, Scribe contracts are ignored.
+	Scribe *configRegistryPolicy `hcl:"scribe,block,optional"`
+
+	// OptimisticScribe defines a poke policy for discovered
+	// OptimisticScribe contracts. If not set, OptimisticScribe contracts
+	// are ignored.
+	OptimisticScribe *configRegistryPolicy `hcl:"optimistic_scribe,block,optional"`
+
+	// HCL fields:
+	Range   hcl.Range       `hcl:",range"`
+	Content hcl.BodyContent `hcl:",content"`
+}
+
+type configRegistryPolicy struct {
+	// Spread is a minimum spread between the current price to trigger an
+	// update. A spread is represented as a percentage point, e.g. 1 means
+	// 1%.
+	Spread float64 `hcl:"spread"`
+
+	// Expiration is a time in seconds after which the price is considered
+	// expired which triggers an update.
+	Expiration uint32 `hcl:"expiration"`
+
+	// OptimisticSpread is a minimum spread to trigger an optimistic update.
+	// Used only for OptimisticScribe contracts.
+	OptimisticSpread float64 `hcl:"optimistic_spread,optional"`
+
+	// OptimisticExpiration is a time in seconds after which the price is
+	// considered expired which triggers an optimistic update. Used only for
+	// OptimisticScribe contracts.
+	OptimisticExpiration uint32 `hcl:"optimistic_expiration,optional"`
+
+	// HCL fields:
+	Range   hcl.Range       `hcl:",range"`
+	Content hcl.BodyContent `hcl:",content"`
+}
+
+type configWatcher struct {
+	// Interval is a time in seconds between contract verifications.
+	Interval uint32 `hcl:"interval,optional"`
+
+	// GracePeriod is a time in seconds for which a discrepancy between
+	// a contract and data points must persist before an alarm is raised.
+	GracePeriod *uint32 `hcl:"grace_period,optional"`
+
+	// HCL fields:
+	Range   hcl.Range       `hcl:",range"`
+	Content hcl.BodyContent `hcl:",content"`
+}
+
+type configChallenger struct {
+	// Interval is a time in seconds between checks for new optimistic pokes.
+	Interval uint32 `hcl:"interval,optional"`
+
+	// BlockRange is a maximum number of blocks scanned for optimistic pokes
+	// in a single query.
+	BlockRange uint64 `hcl:"block_range,optional"`
+
+	// HCL fields:
+	Range   hcl.Range       `hcl:",range"`
+	Content hcl.BodyContent `hcl:",content"`
+}
+
+type configReputation struct {
+	// MaxDeviation is the maximum deviation from the median of other feeds,
+	// in percent, above which a data point is considered an outlier.
+	MaxDeviation float64 `hcl:"max_deviation,optional"`
+
+	// Window is a time in seconds within which data points are compared.
+	Window uint32 `hcl:"window,optional"`
+
+	// Smoothing is the weight of the latest observation in the score.
+	Smoothing float64 `hcl:"smoothing,optional"`
+
+	// ExclusionThreshold is the score above which a feed is excluded from
+	// quorum selection.
+	ExclusionThreshold float64 `hcl:"exclusion_threshold,optional"`
+
+	// MinFeeds is the minimum number of other feeds required to calculate
+	// the median.
+	MinFeeds int `hcl:"min_feeds,optional"`
+
+	// MinObservations is the minimum number of observations before a feed
+	// can be penalized.
+	MinObservations int `hcl:"min_observations,optional"`
+
+	// HCL fields:
+	Range   hcl.Range       `hcl:",range"`
+	Content hcl.BodyContent `hcl:",content"`
+}
+
+func (c *configRegistryPolicy) pokePolicy() relay.PokePolicy {
+	return relay.PokePolicy{
+		Spread:               c.Spread,
+		Expiration:           time.Second * time.Duration(c.Expiration),
+		OptimisticSpread:     c.OptimisticSpread,
+		OptimisticExpiration: time.Second * time.Duration(c.OptimisticExpiration),
+	}
+}
+
+const (
+	defaultRegistryInterval   = 10 * 60 // 10 minutes
+	defaultWatcherInterval    = 60      // 1 minute
+	defaultWatcherGracePeriod = 10 * 60 // 10 minutes
+	defaultChallengerInterval = 30      // 30 seconds
+)
+
+// chainlogRegistry is a relay.DeploymentProvider that resolves addresses of
+// registries from the Chainlog on first use.
+type chainlogRegistry struct {
+	mu       sync.Mutex
+	chainlog *chronicle.Chainlog
+	registry *chronicle.Registry
+}
+
+func (c *chainlogRegistry) Deployments(ctx context.Context) ([]chronicle.Deployment, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.registry == nil {
+		registry, err := chronicle.NewRegistryFromChainlog(ctx, c.chainlog)
+		if err != nil {
+			return nil, err
+		}
+		c.registry = registry
+	}
+	return c.registry.Deployments(ctx)
+}
+
 func configCommonFields(c configCommon) log.Fields {
 	return log.Fields{
 		"ethereumClient": c.EthereumClient,
@@ -127,6 +329,36 @@ func configCommonFields(c configCommon) log.Fields {
 
 const LoggerTag = "CONFIG_" + relay.LoggerTag
 
+// Scorer returns the feed reputation scorer. If the reputation block is
+// not configured, nil is returned.
+func (c *Config) Scorer(logger log.Logger) (*reputation.Scorer, error) {
+	if c.Reputation == nil {
+		return nil, nil
+	}
+	if c.scorer != nil {
+		return c.scorer, nil
+	}
+	scorer, err := reputation.New(reputation.Config{
+		Window:             time.Second * time.Duration(c.Reputation.Window),
+		MaxDeviation:       c.Reputation.MaxDeviation,
+		Smoothing:          c.Reputation.Smoothing,
+		ExclusionThreshold: c.Reputation.ExclusionThreshold,
+		MinFeeds:           c.Reputation.MinFeeds,
+		MinObservations:    c.Reputation.MinObservations,
+		Logger:             logger,
+	})
+	if err != nil {
+		return nil, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Validation error",
+			Detail:   fmt.Sprintf("Failed to create the reputation scorer: %v", err),
+			Subject:  &c.Reputation.Range,
+		}
+	}
+	c.scorer = scorer
+	return c.scorer, nil
+}
+
 func (c *Config) Relay(d Dependencies) (*Services, error) {
 	logger := d.Logger.
 		WithField("tag", LoggerTag)
@@ -157,29 +389,58 @@ func (c *Config) Relay(d Dependencies) (*Services, error) {
 		}).
 		Debug("Data models")
 
+	// Data points are scored before they are stored if the reputation
+	// block is configured.
+	scorer := d.Scorer
+	if scorer == nil {
+		var err error
+		if scorer, err = c.Scorer(d.Logger); err != nil {
+			return nil, err
+		}
+	}
+	var feedReputation relay.Reputation
+	if scorer != nil {
+		feedReputation = scorer
+	}
+
 	// Create a data point store service for all median contracts.
-	priceStoreSrv, err := datapointStore.New(datapointStore.Config{
-		Storage:    datapointStore.NewMemoryStorage(),
-		Transport:  d.Transport,
-		Models:     dataModels,
-		Recoverers: []datapoint.Recoverer{signer.NewTickRecoverer(crypto.ECRecoverer)},
-		Logger:     d.Logger,
-	})
-	if err != nil {
-		return nil, &hcl.Diagnostic{
-			Severity: hcl.DiagError,
-			Summary:  "Store error",
-			Detail:   fmt.Sprintf("Failed to create the data point store service: %v", err),
-			Subject:  &c.Range,
+	priceStoreSrv := d.PriceStore
+	if priceStoreSrv != nil {
+		priceStoreSrv.SetModels(dataModels)
+	} else {
+		var storage datapointStore.Storage = datapointStore.NewMemoryStorage()
+		if scorer != nil {
+			storage = reputation.NewStorage(storage, scorer)
+		}
+		var err error
+		priceStoreSrv, err = datapointStore.New(datapointStore.Config{
+			Storage:    storage,
+			Transport:  d.Transport,
+			Models:     dataModels,
+			Recoverers: []datapoint.Recoverer{signer.NewTickRecoverer(crypto.ECRecoverer)},
+			Logger:     d.Logger,
+		})
+		if err != nil {
+			return nil, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Store error",
+				Detail:   fmt.Sprintf("Failed to create the data point store service: %v", err),
+				Subject:  &c.Range,
+			}
 		}
 	}
 
 	// Create Store service.
-	musigStoreSrv := musigStore.New(musigStore.Config{
-		Transport:  d.Transport,
-		DataModels: scribeDataModels,
-		Logger:     d.Logger,
-	})
+	musigStoreSrv := d.MuSigStore
+	if musigStoreSrv != nil {
+		musigStoreSrv.SetDataModels(scribeDataModels)
+	} else {
+		musigStoreSrv = musigStore.New(musigStore.Config{
+			Transport:  d.Transport,
+			DataModels: scribeDataModels,
+			Logger:     d.Logger,
+		})
+	}
 
 	var (
 		medianCfgs   []relay.ConfigMedian
@@ -211,6 +472,7 @@ func (c *Config) Relay(d Dependencies) (*Services, error) {
 			DataPointStore:  priceStoreSrv,
 			Spread:          cfg.Spread,
 			Expiration:      time.Second * time.Duration(cfg.Expiration),
+			Reputation:      feedReputation,
 		})
 	}
 	for _, cfg := range c.Scribe {
@@ -234,6 +496,7 @@ func (c *Config) Relay(d Dependencies) (*Services, error) {
 			ContractAddress: cfg.ContractAddr,
 			Client:          client,
 			MuSigStore:      musigStoreSrv,
+			DataPointStore:  priceStoreSrv,
 			Spread:          cfg.Spread,
 			Expiration:      time.Second * time.Duration(cfg.Expiration),
 		})
@@ -259,6 +522,7 @@ func (c *Config) Relay(d Dependencies) (*Services, error) {
 			ContractAddress:      cfg.ContractAddr,
 			Client:               client,
 			MuSigStore:           musigStoreSrv,
+			DataPointStore:       priceStoreSrv,
 			Spread:               cfg.Spread,
 			Expiration:           time.Second * time.Duration(cfg.Expiration),
 			OptimisticSpread:     cfg.OptimisticSpread,
@@ -270,6 +534,8 @@ func (c *Config) Relay(d Dependencies) (*Services, error) {
 		Medians:           medianCfgs,
 		Scribes:           scribeCfgs,
 		OptimisticScribes: opScribeCfgs,
+		Workers:           workerConfigs(d),
+		Metrics:           d.Metrics,
 		Logger:            d.Logger,
 		Ticker:            timeutil.NewTicker(time.Minute * 2),
 	})
@@ -282,10 +548,318 @@ func (c *Config) Relay(d Dependencies) (*Services, error) {
 		}
 	}
 
+	var registrySrv *relay.Registry
+	if c.Registry != nil {
+		registrySrv, err = c.Registry.registry(d, relaySrv, priceStoreSrv, musigStoreSrv, feedReputation)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var watcherSrv *relay.Watcher
+	if c.Watcher != nil {
+		watcherSrv, err = c.Watcher.watcher(d, relaySrv, priceStoreSrv)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var challengerSrv *relay.Challenger
+	if c.Challenger != nil {
+		challengerSrv, err = c.Challenger.challenger(d, relaySrv, priceStoreSrv)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	c.services = &Services{
 		Relay:      relaySrv,
+		Registry:   registrySrv,
+		Watcher:    watcherSrv,
+		Challenger: challengerSrv,
 		PriceStore: priceStoreSrv,
 		MuSigStore: musigStoreSrv,
 	}
 	return c.services, nil
 }
+
+// workerConfigs returns the configuration of relay workers, one for each
+// Ethereum client.
+func workerConfigs(d Dependencies) []relay.ConfigWorker {
+	names := maputil.SortKeys(d.Clients, sort.Strings)
+	workers := make([]relay.ConfigWorker, 0, len(names))
+	for _, name := range names {
+		worker := relay.ConfigWorker{
+			Name:   name,
+			Client: d.Clients[name],
+		}
+		if cfg, ok := d.Relays[name]; ok {
+			if cfg.Interval > 0 {
+				worker.Ticker = timeutil.NewTicker(time.Second * time.Duration(cfg.Interval))
+			}
+			worker.MaxConcurrency = int(cfg.MaxConcurrency)
+			worker.GasUsageSoftCap = cfg.GasUsageCap
+		}
+		workers = append(workers, worker)
+	}
+	return workers
+}
+
+func (c *configRegistry) registry(
+	d Dependencies,
+	relaySrv *relay.Relay,
+	priceStoreSrv *datapointStore.Store,
+	musigStoreSrv *musigStore.Store,
+	feedReputation relay.Reputation,
+) (*relay.Registry, error) {
+	client, ok := d.Clients[c.EthereumClient]
+	if !ok {
+		return nil, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Validation error",
+			Detail:   fmt.Sprintf("Ethereum client %q is not configured", c.EthereumClient),
+			Subject:  c.Content.Attributes["ethereum_client"].Range.Ptr(),
+		}
+	}
+	var deployments relay.DeploymentProvider
+	switch {
+	case c.WatRegistryAddr != nil && c.FeedRegistryAddr != nil:
+		registry, err := chronicle.NewRegistry(
+			chronicle.NewFeedRegistry(client, *c.FeedRegistryAddr),
+			chronicle.NewWatRegistry(client, *c.WatRegistryAddr),
+		)
+		if err != nil {
+			return nil, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Registry error",
+				Detail:   fmt.Sprintf("Failed to create the registry: %v", err),
+				Subject:  &c.Range,
+			}
+		}
+		deployments = registry
+	case c.ChainlogAddr != nil:
+		deployments = &chainlogRegistry{chainlog: chronicle.NewChainlog(client, *c.ChainlogAddr)}
+	default:
+		return nil, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Validation error",
+			Detail:   "Either chainlog_addr or both wat_registry_addr and feed_registry_addr must be set",
+			Subject:  &c.Range,
+		}
+	}
+	var relayClients []rpc.RPC
+	for _, name := range c.RelayEthereumClients {
+		relayClient, ok := d.Clients[name]
+		if !ok {
+			return nil, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Validation error",
+				Detail:   fmt.Sprintf("Ethereum client %q is not configured", name),
+				Subject:  c.Content.Attributes["relay_ethereum_clients"].Range.Ptr(),
+			}
+		}
+		relayClients = append(relayClients, relayClient)
+	}
+	policies := make(map[relay.ContractType]relay.PokePolicy)
+	if c.Median != nil {
+		policies[relay.ContractTypeMedian] = c.Median.pokePolicy()
+	}
+	if c.Scribe != nil {
+		policies[relay.ContractTypeScribe] = c.Scribe.pokePolicy()
+	}
+	if c.OptimisticScribe != nil {
+		policies[relay.ContractTypeOptimisticScribe] = c.OptimisticScribe.pokePolicy()
+	}
+	interval := c.Interval
+	if interval == 0 {
+		interval = defaultRegistryInterval
+	}
+	registrySrv, err := relay.NewRegistry(relay.RegistryConfig{
+		Registry:        deployments,
+		Clients:         relayClients,
+		DataModels:      c.DataModels,
+		Policies:        policies,
+		StaticContracts: relaySrv.Contracts(),
+		DataPointStore:  priceStoreSrv,
+		MuSigStore:      musigStoreSrv,
+		Reputation:      feedReputation,
+		Relay:           relaySrv,
+		Ticker:          timeutil.NewTicker(time.Second * time.Duration(interval)),
+		Logger:          d.Logger,
+	})
+	if err != nil {
+		return nil, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Registry error",
+			Detail:   fmt.Sprintf("Failed to create the registry service: %v", err),
+			Subject:  &c.Range,
+		}
+	}
+	return registrySrv, nil
+}
+
+func (c *configWatcher) watcher(
+	d Dependencies,
+	relaySrv *relay.Relay,
+	priceStoreSrv *datapointStore.Store,
+) (*relay.Watcher, error) {
+	interval := c.Interval
+	if interval == 0 {
+		interval = defaultWatcherInterval
+	}
+	gracePeriod := uint32(defaultWatcherGracePeriod)
+	if c.GracePeriod != nil {
+		gracePeriod = *c.GracePeriod
+	}
+	var alarmHook relay.AlarmHook
+	if d.Metrics != nil {
+		alarmHook = relay.MetricsAlarmHook(d.Metrics)
+	}
+	watcherSrv, err := relay.NewWatcher(relay.WatcherConfig{
+		Relay:          relaySrv,
+		DataPointStore: priceStoreSrv,
+		GracePeriod:    time.Second * time.Duration(gracePeriod),
+		Ticker:         timeutil.NewTicker(time.Second * time.Duration(interval)),
+		AlarmHook:      alarmHook,
+		Logger:         d.Logger,
+	})
+	if err != nil {
+		return nil, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Watcher error",
+			Detail:   fmt.Sprintf("Failed to create the watcher service: %v", err),
+			Subject:  &c.Range,
+		}
+	}
+	return watcherSrv, nil
+}
+
+func (c *configChallenger) challenger(
+	d Dependencies,
+	relaySrv *relay.Relay,
+	priceStoreSrv *datapointStore.Store,
+) (*relay.Challenger, error) {
+	interval := c.Interval
+	if interval == 0 {
+		interval = defaultChallengerInterval
+	}
+	challengerSrv, err := relay.NewChallenger(relay.ChallengerConfig{
+		Relay:          relaySrv,
+		DataPointStore: priceStoreSrv,
+		BlockRange:     c.BlockRange,
+		Ticker:         timeutil.NewTicker(time.Second * time.Duration(interval)),
+		Logger:         d.Logger,
+	})
+	if err != nil {
+		return nil, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Challenger error",
+			Detail:   fmt.Sprintf("Failed to create the challenger service: %v", err),
+			Subject:  &c.Range,
+		}
+	}
+	return challengerSrv, nil
+}
+
+// Contract describes a single contract handled by the relay.
+type Contract struct {
+	Type                 relay.ContractType `json:"type"`
+	Source               string             `json:"source"`
+	EthereumClient       string             `json:"ethereum_client"`
+	ContractAddr         types.Address      `json:"contract_addr"`
+	DataModel            string             `json:"data_model"`
+	Feeds                []types.Address    `json:"feeds,omitempty"`
+	Spread               float64            `json:"spread"`
+	Expiration           uint32             `json:"expiration"`
+	OptimisticSpread     float64            `json:"optimistic_spread,omitempty"`
+	OptimisticExpiration uint32             `json:"optimistic_expiration,omitempty"`
+}
+
+const (
+	ContractSourceConfig   = "config"
+	ContractSourceRegistry = "registry"
+)
+
+// Contracts returns the list of contracts handled by the relay. If the
+// registry block is configured, contracts are also resolved from on-chain
+// registries.
+func (c *Config) Contracts(ctx context.Context, d Dependencies) ([]Contract, error) {
+	srvs, err := c.Relay(d)
+	if err != nil {
+		return nil, err
+	}
+	clientNames := make(map[rpc.RPC]string)
+	for name, client := range d.Clients {
+		clientNames[client] = name
+	}
+	var contracts []Contract
+	add := func(source string, r relay.Contracts) {
+		for _, m := range r.Medians {
+			contracts = append(contracts, Contract{
+				Type:           relay.ContractTypeMedian,
+				Source:         source,
+				EthereumClient: clientNames[m.Client],
+				ContractAddr:   m.ContractAddress,
+				DataModel:      m.DataModel,
+				Feeds:          m.FeedAddresses,
+				Spread:         m.Spread,
+				Expiration:     uint32(m.Expiration.Seconds()),
+			})
+		}
+		for _, s := range r.Scribes {
+			contracts = append(contracts, Contract{
+				Type:           relay.ContractTypeScribe,
+				Source:         source,
+				EthereumClient: clientNames[s.Client],
+				ContractAddr:   s.ContractAddress,
+				DataModel:      s.DataModel,
+				Spread:         s.Spread,
+				Expiration:     uint32(s.Expiration.Seconds()),
+			})
+		}
+		for _, s := range r.OptimisticScribes {
+			contracts = append(contracts, Contract{
+				Type:                 relay.ContractTypeOptimisticScribe,
+				Source:               source,
+				EthereumClient:       clientNames[s.Client],
+				ContractAddr:         s.ContractAddress,
+				DataModel:            s.DataModel,
+				Spread:               s.Spread,
+				Expiration:           uint32(s.Expiration.Seconds()),
+				OptimisticSpread:     s.OptimisticSpread,
+				OptimisticExpiration: uint32(s.OptimisticExpiration.Seconds()),
+			})
+		}
+	}
+	static := srvs.Relay.Contracts()
+	add(ContractSourceConfig, static)
+	if srvs.Registry != nil {
+		discovered, err := srvs.Registry.Contracts(ctx)
+		if err != nil {
+			return nil, err
+		}
+		// Statically configured contracts take precedence over discovered ones.
+		staticAddrs := make(map[types.Address]bool)
+		for _, c := range contracts {
+			staticAddrs[c.ContractAddr] = true
+		}
+		var r relay.Contracts
+		for _, m := range discovered.Medians {
+			if !staticAddrs[m.ContractAddress] {
+				r.Medians = append(r.Medians, m)
+			}
+		}
+		for _, s := range discovered.Scribes {
+			if !staticAddrs[s.ContractAddress] {
+				r.Scribes = append(r.Scribes, s)
+			}
+		}
+		for _, s := range discovered.OptimisticScribes {
+			if !staticAddrs[s.ContractAddress] {
+				r.OptimisticScribes = append(r.OptimisticScribes, s)
+			}
+		}
+		add(ContractSourceRegistry, r)
+	}
+	return contracts, nil
+}
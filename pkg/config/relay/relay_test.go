@@ -51,6 +51,44 @@ func TestConfig(t *testing.T) {
 					types.MustAddressFromHex("0x4455667788990011223344556677889900112233"),
 					types.MustAddressFromHex("0x5566778899001122334455667788990011223344"),
 				}, cfg.OptimisticScribe[0].Feeds)
+
+				require.NotNil(t, cfg.Watcher)
+				assert.Equal(t, uint32(30), cfg.Watcher.Interval)
+				require.NotNil(t, cfg.Watcher.GracePeriod)
+				assert.Equal(t, uint32(0), *cfg.Watcher.GracePeriod)
+
+				require.NotNil(t, cfg.Challenger)
+				assert.Equal(t, uint32(15), cfg.Challenger.Interval)
+				assert.Equal(t, uint64(500), cfg.Challenger.BlockRange)
+
+				require.NotNil(t, cfg.Reputation)
+				assert.Equal(t, float64(2), cfg.Reputation.MaxDeviation)
+				assert.Equal(t, uint32(300), cfg.Reputation.Window)
+				assert.Equal(t, float64(0), cfg.Reputation.Smoothing)
+				assert.Equal(t, 0.4, cfg.Reputation.ExclusionThreshold)
+				assert.Equal(t, 4, cfg.Reputation.MinFeeds)
+				assert.Equal(t, 20, cfg.Reputation.MinObservations)
+			},
+		},
+		{
+			name: "registry",
+			path: "registry.hcl",
+			test: func(t *testing.T, cfg *Config) {
+				require.NotNil(t, cfg.Registry)
+				assert.Equal(t, "client1", cfg.Registry.EthereumClient)
+				assert.Equal(t, "0x1234567890123456789012345678901234567890", cfg.Registry.ChainlogAddr.String())
+				assert.Nil(t, cfg.Registry.WatRegistryAddr)
+				assert.Nil(t, cfg.Registry.FeedRegistryAddr)
+				assert.Equal(t, []string{"client1", "client2"}, cfg.Registry.RelayEthereumClients)
+				assert.Equal(t, []string{"ETH/USD"}, cfg.Registry.DataModels)
+				assert.Equal(t, uint32(300), cfg.Registry.Interval)
+				require.NotNil(t, cfg.Registry.Median)
+				assert.Equal(t, float64(1), cfg.Registry.Median.Spread)
+				assert.Equal(t, uint32(3600), cfg.Registry.Median.Expiration)
+				assert.Nil(t, cfg.Registry.Scribe)
+				require.NotNil(t, cfg.Registry.OptimisticScribe)
+				assert.Equal(t, float64(0.5), cfg.Registry.OptimisticScribe.OptimisticSpread)
+				assert.Equal(t, uint32(600), cfg.Registry.OptimisticScribe.OptimisticExpiration)
 			},
 		},
 	}
@@ -35,3 +35,21 @@ optimistic_scribe {
     "0x5566778899001122334455667788990011223344",
   ]
 }
+
+watcher {
+  interval     = 30
+  grace_period = 0
+}
+
+challenger {
+  interval    = 15
+  block_range = 500
+}
+
+reputation {
+  max_deviation       = 2
+  window              = 300
+  exclusion_threshold = 0.4
+  min_feeds           = 4
+  min_observations    = 20
+}
@@ -0,0 +1,19 @@
+registry {
+  ethereum_client        = "client1"
+  chainlog_addr          = "0x1234567890123456789012345678901234567890"
+  relay_ethereum_clients = ["client1", "client2"]
+  data_models            = ["ETH/USD"]
+  interval               = 300
+
+  median {
+    spread     = 1
+    expiration = 3600
+  }
+
+  optimistic_scribe {
+    spread                = 2
+    expiration            = 7200
+    optimistic_spread     = 0.5
+    optimistic_expiration = 600
+  }
+}
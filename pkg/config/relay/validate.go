@@ -0,0 +1,122 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package relay
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+
+	"github.com/orcfax/oracle-suite/pkg/util/sliceutil"
+)
+
+// Entities returns the contract blocks keyed by their type, Ethereum client
+// and address. It is used to compare contracts between configurations.
+func (c *Config) Entities() map[string]any {
+	entities := make(map[string]any)
+	for i, m := range c.Median {
+		entities[contractEntityName("median", m.configCommon)] = &c.Median[i]
+	}
+	for i, s := range c.Scribe {
+		entities[contractEntityName("scribe", s.configCommon)] = &c.Scribe[i]
+	}
+	for i, s := range c.OptimisticScribe {
+		entities[contractEntityName("optimistic_scribe", s.configCommon)] = &c.OptimisticScribe[i]
+	}
+	return entities
+}
+
+// Validate checks that Ethereum clients used by the relay are configured
+// and that relayed data models are published by feeds. Clients is a list of
+// configured Ethereum client names. DataModels is a list of data models
+// published by feeds; if it is nil, data models are not checked.
+//
+// Data models that are not published are reported as warnings, because
+// feeds may be configured outside the given configuration.
+func (c *Config) Validate(clients []string, dataModels []string) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	var contracts []configCommon
+	for _, m := range c.Median {
+		contracts = append(contracts, m.configCommon)
+	}
+	for _, s := range c.Scribe {
+		contracts = append(contracts, s.configCommon)
+	}
+	for _, s := range c.OptimisticScribe {
+		contracts = append(contracts, s.configCommon)
+	}
+	for _, contract := range contracts {
+		if !sliceutil.Contains(clients, contract.EthereumClient) {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Validation error",
+				Detail:   fmt.Sprintf("Ethereum client %q is not configured", contract.EthereumClient),
+				Subject:  contract.Content.Attributes["ethereum_client"].Range.Ptr(),
+			})
+		}
+		if dataModels != nil && !sliceutil.Contains(dataModels, contract.DataModel) {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagWarning,
+				Summary:  "Validation warning",
+				Detail:   fmt.Sprintf("Data model %q is not published by any feed", contract.DataModel),
+				Subject:  contract.Content.Attributes["data_model"].Range.Ptr(),
+			})
+		}
+	}
+	if c.Registry != nil {
+		diags = append(diags, c.Registry.validate(clients, dataModels)...)
+	}
+	return diags
+}
+
+func (c *configRegistry) validate(clients []string, dataModels []string) hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	if !sliceutil.Contains(clients, c.EthereumClient) {
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Validation error",
+			Detail:   fmt.Sprintf("Ethereum client %q is not configured", c.EthereumClient),
+			Subject:  c.Content.Attributes["ethereum_client"].Range.Ptr(),
+		})
+	}
+	for _, name := range c.RelayEthereumClients {
+		if !sliceutil.Contains(clients, name) {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Validation error",
+				Detail:   fmt.Sprintf("Ethereum client %q is not configured", name),
+				Subject:  c.Content.Attributes["relay_ethereum_clients"].Range.Ptr(),
+			})
+		}
+	}
+	if dataModels != nil {
+		for _, model := range c.DataModels {
+			if !sliceutil.Contains(dataModels, model) {
+				diags = append(diags, &hcl.Diagnostic{
+					Severity: hcl.DiagWarning,
+					Summary:  "Validation warning",
+					Detail:   fmt.Sprintf("Data model %q is not published by any feed", model),
+					Subject:  c.Content.Attributes["data_models"].Range.Ptr(),
+				})
+			}
+		}
+	}
+	return diags
+}
+
+func contractEntityName(typ string, c configCommon) string {
+	return fmt.Sprintf("%s %q %q", typ, c.EthereumClient, c.ContractAddr.String())
+}
@@ -0,0 +1,206 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package rpcsplitter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/hcl/v2"
+
+	"github.com/orcfax/oracle-suite/config"
+	ethereumConfig "github.com/orcfax/oracle-suite/pkg/config/ethereum"
+	loggerConfig "github.com/orcfax/oracle-suite/pkg/config/logger"
+	"github.com/orcfax/oracle-suite/pkg/httpserver"
+	"github.com/orcfax/oracle-suite/pkg/httpserver/middleware"
+	"github.com/orcfax/oracle-suite/pkg/log"
+	"github.com/orcfax/oracle-suite/pkg/rpcsplitter"
+	"github.com/orcfax/oracle-suite/pkg/supervisor"
+	"github.com/orcfax/oracle-suite/pkg/util/sliceutil"
+)
+
+const LoggerTag = "RPC_SPLITTER_SERVER"
+
+// HealthCheckPath is the path of the health check endpoint.
+const HealthCheckPath = "/healthcheck"
+
+// defaultHTTPTimeout is the default timeout for the HTTP server. It must
+// be longer than the RPC-Splitter total timeout.
+const defaultHTTPTimeout = 60 * time.Second
+
+// Config is the configuration for the RPC-Splitter server.
+type Config struct {
+	RPCSplitter ConfigRPCSplitter     `hcl:"rpc_splitter,block"`
+	Ethereum    ethereumConfig.Config `hcl:"ethereum,block"`
+	Logger      *loggerConfig.Config  `hcl:"logger,block,optional"`
+
+	// HCL fields:
+	Remain  hcl.Body        `hcl:",remain"` // To ignore unknown blocks.
+	Content hcl.BodyContent `hcl:",content"`
+}
+
+func (Config) DefaultEmbeds() [][]byte {
+	return [][]byte{
+		config.Defaults,
+		config.RPCSplitter,
+		config.Ethereum,
+	}
+}
+
+// Validate implements the config.Validator interface.
+func (c *Config) Validate() hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	clients := c.Ethereum.ClientNames()
+	for _, name := range c.RPCSplitter.Clients {
+		if !sliceutil.Contains(clients, name) {
+			diags = append(diags, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Validation error",
+				Detail:   fmt.Sprintf("Ethereum client %q is not configured", name),
+				Subject:  c.RPCSplitter.Content.Attributes["clients"].Range.Ptr(),
+			})
+		}
+	}
+	return diags
+}
+
+// ConfigRPCSplitter contains the configuration for the RPC-Splitter server.
+type ConfigRPCSplitter struct {
+	// ListenAddr is an address to listen for RPC requests.
+	ListenAddr string `hcl:"listen_addr"`
+
+	// Clients is a list of Ethereum client names to serve. Every client is
+	// served under a virtual host with the same name. If empty, all
+	// configured clients are served.
+	Clients []string `hcl:"clients,optional"`
+
+	// HCL fields:
+	Range   hcl.Range       `hcl:",range"`
+	Content hcl.BodyContent `hcl:",content"`
+}
+
+// Services returns the services that are configured from the Config struct.
+type Services struct {
+	HTTPServer *httpserver.HTTPServer
+	Logger     log.Logger
+
+	supervisor *supervisor.Supervisor
+}
+
+// Start implements the supervisor.Service interface.
+func (s *Services) Start(ctx context.Context) error {
+	if s.supervisor != nil {
+		return fmt.Errorf("services already started")
+	}
+	s.supervisor = supervisor.New(s.Logger)
+	s.supervisor.Watch(s.HTTPServer)
+	if l, ok := s.Logger.(supervisor.Service); ok {
+		s.supervisor.Watch(l)
+	}
+	return s.supervisor.Start(ctx)
+}
+
+// Wait implements the supervisor.Service interface.
+func (s *Services) Wait() <-chan error {
+	return s.supervisor.Wait()
+}
+
+// Services returns the services configured for the RPC-Splitter server.
+func (c *Config) Services(baseLogger log.Logger, appName string, appVersion string) (supervisor.Service, error) {
+	logger, err := c.Logger.Logger(loggerConfig.Dependencies{
+		AppName:    appName,
+		AppVersion: appVersion,
+		BaseLogger: baseLogger,
+	})
+	if err != nil {
+		return nil, err
+	}
+	handler, err := c.handler(logger)
+	if err != nil {
+		return nil, err
+	}
+	logger = logger.WithField("tag", LoggerTag)
+	srv := httpserver.New(&http.Server{
+		Addr:              c.RPCSplitter.ListenAddr,
+		Handler:           handler,
+		IdleTimeout:       defaultHTTPTimeout,
+		ReadTimeout:       defaultHTTPTimeout,
+		ReadHeaderTimeout: defaultHTTPTimeout,
+		// WriteTimeout is not set, because it would terminate
+		// websocket subscriptions.
+	})
+	srv.Use(
+		&middleware.HealthCheck{Path: HealthCheckPath},
+		&middleware.Logger{Log: logger},
+		&middleware.Recover{Recover: func(err any) {
+			logger.WithField("panic", err).Error("Panic while handling HTTP request")
+		}},
+	)
+	return &Services{HTTPServer: srv, Logger: logger}, nil
+}
+
+func (c *Config) handler(logger log.Logger) (rpcsplitter.VirtualHosts, error) {
+	if c.RPCSplitter.ListenAddr == "" {
+		return nil, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Validation error",
+			Detail:   "Listen address is required",
+			Subject:  c.RPCSplitter.Range.Ptr(),
+		}
+	}
+	clients := make(map[string]*ethereumConfig.ConfigClient, len(c.Ethereum.Clients))
+	for i := range c.Ethereum.Clients {
+		clients[c.Ethereum.Clients[i].Name] = &c.Ethereum.Clients[i]
+	}
+	names := c.RPCSplitter.Clients
+	if len(names) == 0 {
+		for _, client := range c.Ethereum.Clients {
+			names = append(names, client.Name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Validation error",
+			Detail:   "At least one Ethereum client must be configured",
+			Subject:  c.RPCSplitter.Range.Ptr(),
+		}
+	}
+	vhosts := make(rpcsplitter.VirtualHosts, len(names))
+	for _, name := range names {
+		client, ok := clients[name]
+		if !ok {
+			return nil, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Validation error",
+				Detail:   fmt.Sprintf("Ethereum client %q is not configured", name),
+				Subject:  c.RPCSplitter.Content.Attributes["clients"].Range.Ptr(),
+			}
+		}
+		h, err := client.Splitter(logger)
+		if err != nil {
+			return nil, err
+		}
+		logger.
+			WithField("name", name).
+			WithField("addr", c.RPCSplitter.ListenAddr).
+			Info("Serving Ethereum client")
+		vhosts[name] = h
+	}
+	return vhosts, nil
+}
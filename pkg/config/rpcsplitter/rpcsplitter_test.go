@@ -0,0 +1,138 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package rpcsplitter
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/orcfax/oracle-suite/pkg/config"
+	"github.com/orcfax/oracle-suite/pkg/log/null"
+	"github.com/orcfax/oracle-suite/pkg/rpcsplitter"
+	"github.com/orcfax/oracle-suite/pkg/util/hcl"
+)
+
+func TestConfig(t *testing.T) {
+	// Upstream RPC endpoint that responds to eth_chainId calls.
+	upstream := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID json.RawMessage `json:"id"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		rw.Header().Set("Content-Type", "application/json")
+		_, _ = rw.Write([]byte(`{"jsonrpc":"2.0","id":` + string(req.ID) + `,"result":"0x1"}`))
+	}))
+	defer upstream.Close()
+	t.Setenv("TEST_RPC_URL", upstream.URL)
+
+	tests := []struct {
+		name    string
+		path    string
+		test    func(*testing.T, *Config)
+		wantErr string
+	}{
+		{
+			name: "valid",
+			path: "config.hcl",
+			test: func(t *testing.T, cfg *Config) {
+				services, err := cfg.Services(null.New(), "", "")
+				require.NoError(t, err)
+				srv := services.(*Services).HTTPServer
+
+				for _, target := range []string{"http://ethereum/", "http://localhost/arbitrum"} {
+					req := httptest.NewRequest(
+						http.MethodPost,
+						target,
+						strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"eth_chainId","params":[]}`),
+					)
+					req.Header.Set("Content-Type", "application/json")
+					rw := httptest.NewRecorder()
+					srv.ServeHTTP(rw, req)
+					body, _ := io.ReadAll(rw.Body)
+					assert.JSONEq(t, `{"jsonrpc":"2.0","id":1,"result":"0x1"}`, string(body))
+				}
+
+				rw := httptest.NewRecorder()
+				srv.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "http://ethereum"+rpcsplitter.StatusPath, nil))
+				assert.Equal(t, http.StatusOK, rw.Code)
+				var status []rpcsplitter.EndpointStatus
+				require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &status))
+				require.Len(t, status, 1)
+				assert.Equal(t, uint64(1), status[0].Requests)
+			},
+		},
+		{
+			name: "clients",
+			path: "clients.hcl",
+			test: func(t *testing.T, cfg *Config) {
+				handler, err := cfg.handler(null.New())
+				require.NoError(t, err)
+				assert.Len(t, handler, 1)
+				assert.Contains(t, handler, "arbitrum")
+				assert.Empty(t, cfg.Validate())
+			},
+		},
+		{
+			name: "unknown-client",
+			path: "unknown-client.hcl",
+			test: func(t *testing.T, cfg *Config) {
+				_, err := cfg.Services(null.New(), "", "")
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), `Ethereum client "optimism" is not configured`)
+
+				diags := cfg.Validate()
+				require.Len(t, diags, 1)
+				assert.Equal(t, `Ethereum client "optimism" is not configured`, diags[0].Detail)
+				assert.Equal(t, 3, diags[0].Subject.Start.Line)
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var cfg Config
+			err := config.LoadFiles(&cfg, []string{"./testdata/" + test.path})
+			require.NoError(t, err)
+			test.test(t, &cfg)
+		})
+	}
+}
+
+func TestDefaults(t *testing.T) {
+	cfg := &Config{}
+	require.NoError(t, config.LoadEmbeds(cfg, cfg.DefaultEmbeds()))
+
+	block := &hcl.Block{}
+	hcl.Encode(cfg, block)
+
+	expectedConfig, err := os.ReadFile("./testdata/default.hcl")
+	require.NoError(t, err)
+
+	loadedConfig, diags := block.Bytes()
+	require.False(t, diags.HasErrors())
+
+	require.Equal(t,
+		strings.Trim(string(expectedConfig), "\n"),
+		strings.Trim(string(loadedConfig), "\n"),
+	)
+}
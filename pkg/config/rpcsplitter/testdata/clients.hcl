@@ -0,0 +1,14 @@
+rpc_splitter {
+  listen_addr = "127.0.0.1:0"
+  clients     = ["arbitrum"]
+}
+
+ethereum {
+  client "ethereum" {
+    rpc_urls = ["http://127.0.0.1:8545"]
+  }
+
+  client "arbitrum" {
+    rpc_urls = ["http://127.0.0.1:8545"]
+  }
+}
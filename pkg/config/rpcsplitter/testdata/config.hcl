@@ -0,0 +1,13 @@
+rpc_splitter {
+  listen_addr = "127.0.0.1:0"
+}
+
+ethereum {
+  client "ethereum" {
+    rpc_urls = [env("TEST_RPC_URL", "http://127.0.0.1:8545")]
+  }
+
+  client "arbitrum" {
+    rpc_urls = [env("TEST_RPC_URL", "http://127.0.0.1:8545")]
+  }
+}
@@ -0,0 +1,13 @@
+rpc_splitter {
+  listen_addr = "127.0.0.1:8545"
+  clients     = []
+}
+ethereum {
+  rand_keys = ["default"]
+
+  client "ethereum" {
+    rpc_urls     = ["https://eth.public-rpc.com"]
+    ethereum_key = "default"
+    chain_id     = 1
+  }
+}
@@ -0,0 +1,10 @@
+rpc_splitter {
+  listen_addr = "127.0.0.1:0"
+  clients     = ["optimism"]
+}
+
+ethereum {
+  client "ethereum" {
+    rpc_urls = ["http://127.0.0.1:8545"]
+  }
+}
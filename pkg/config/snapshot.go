@@ -0,0 +1,73 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package config
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+
+	"github.com/orcfax/oracle-suite/pkg/util/hcl"
+)
+
+// Snapshot contains the HCL representation of configuration blocks. It is
+// used to detect which blocks have changed between two configurations.
+//
+// Services often set default values in the configuration structs they are
+// created from, so a snapshot should be taken before creating services.
+type Snapshot map[string][]byte
+
+// NewSnapshot returns a snapshot of the given configuration blocks. Blocks
+// must be pointers to structs. Nil pointers are allowed for optional blocks.
+// Source ranges and unexported fields are not a part of the snapshot.
+func NewSnapshot(blocks map[string]any) (Snapshot, error) {
+	s := make(Snapshot, len(blocks))
+	for name, block := range blocks {
+		rv := reflect.ValueOf(block)
+		if !rv.IsValid() || (rv.Kind() == reflect.Ptr && rv.IsNil()) {
+			s[name] = nil
+			continue
+		}
+		body := &hcl.Block{}
+		if diags := hcl.Encode(block, body); diags.HasErrors() {
+			return nil, diags
+		}
+		b, diags := body.Bytes()
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		s[name] = b
+	}
+	return s, nil
+}
+
+// Changed returns the sorted names of blocks that differ between the
+// snapshots.
+func (s Snapshot) Changed(other Snapshot) []string {
+	var changed []string
+	for name, b := range s {
+		if ob, ok := other[name]; !ok || !bytes.Equal(b, ob) {
+			changed = append(changed, name)
+		}
+	}
+	for name := range other {
+		if _, ok := s[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
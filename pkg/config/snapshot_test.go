@@ -0,0 +1,100 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type snapshotBlock struct {
+	Name  string    `hcl:"name,label"`
+	Value int       `hcl:"value"`
+	Range hcl.Range `hcl:",range"`
+
+	service any
+}
+
+type snapshotConfig struct {
+	Blocks []snapshotBlock `hcl:"block,block"`
+	Attr   string          `hcl:"attr,optional"`
+}
+
+func TestSnapshot(t *testing.T) {
+	a := &snapshotConfig{Blocks: []snapshotBlock{{Name: "a", Value: 1}}, Attr: "x"}
+	tests := []struct {
+		name string
+		a, b any
+		want []string
+	}{
+		{
+			name: "equal",
+			a:    a,
+			b:    &snapshotConfig{Blocks: []snapshotBlock{{Name: "a", Value: 1}}, Attr: "x"},
+		},
+		{
+			name: "ranges and unexported fields are ignored",
+			a:    a,
+			b: &snapshotConfig{
+				Blocks: []snapshotBlock{{Name: "a", Value: 1, Range: hcl.Range{Filename: "b.hcl"}, service: 1}},
+				Attr:   "x",
+			},
+		},
+		{
+			name: "different attribute",
+			a:    a,
+			b:    &snapshotConfig{Blocks: []snapshotBlock{{Name: "a", Value: 1}}, Attr: "y"},
+			want: []string{"cfg"},
+		},
+		{
+			name: "different block",
+			a:    a,
+			b:    &snapshotConfig{Blocks: []snapshotBlock{{Name: "a", Value: 2}}, Attr: "x"},
+			want: []string{"cfg"},
+		},
+		{
+			name: "nil pointers",
+			a:    (*snapshotConfig)(nil),
+			b:    (*snapshotConfig)(nil),
+		},
+		{
+			name: "nil pointer and struct",
+			a:    (*snapshotConfig)(nil),
+			b:    a,
+			want: []string{"cfg"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sa, err := NewSnapshot(map[string]any{"cfg": tt.a, "other": a})
+			require.NoError(t, err)
+			sb, err := NewSnapshot(map[string]any{"cfg": tt.b, "other": a})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, sa.Changed(sb))
+		})
+	}
+}
+
+func TestSnapshot_MissingBlocks(t *testing.T) {
+	sa, err := NewSnapshot(map[string]any{"a": &snapshotConfig{}, "b": &snapshotConfig{}})
+	require.NoError(t, err)
+	sb, err := NewSnapshot(map[string]any{"b": &snapshotConfig{}, "c": &snapshotConfig{}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "c"}, sa.Changed(sb))
+}
@@ -18,30 +18,41 @@ package spectre
 import (
 	"context"
 	"fmt"
+	"sync"
 
 	"github.com/hashicorp/hcl/v2"
 
 	"github.com/orcfax/oracle-suite/config"
+	pkgConfig "github.com/orcfax/oracle-suite/pkg/config"
 	ethereumConfig "github.com/orcfax/oracle-suite/pkg/config/ethereum"
+	feedConfig "github.com/orcfax/oracle-suite/pkg/config/feednext"
 	loggerConfig "github.com/orcfax/oracle-suite/pkg/config/logger"
 	relayConfig "github.com/orcfax/oracle-suite/pkg/config/relay"
+	tracingConfig "github.com/orcfax/oracle-suite/pkg/config/tracing"
 	transportConfig "github.com/orcfax/oracle-suite/pkg/config/transport"
+	"github.com/orcfax/oracle-suite/pkg/datapoint/reputation"
 	datapointStore "github.com/orcfax/oracle-suite/pkg/datapoint/store"
 	"github.com/orcfax/oracle-suite/pkg/log"
+	"github.com/orcfax/oracle-suite/pkg/metrics"
 	musigStore "github.com/orcfax/oracle-suite/pkg/musig/store"
 	"github.com/orcfax/oracle-suite/pkg/relay"
+	"github.com/orcfax/oracle-suite/pkg/tracing"
 
 	"github.com/orcfax/oracle-suite/pkg/supervisor"
 	"github.com/orcfax/oracle-suite/pkg/transport"
+	"github.com/orcfax/oracle-suite/pkg/transport/local"
 	"github.com/orcfax/oracle-suite/pkg/transport/messages"
 )
 
+const LoggerTag = "CONFIG_SPECTRE"
+
 // Config is the configuration for Spectre.
 type Config struct {
 	Spectre   relayConfig.Config     `hcl:"spectre,block"`
 	Transport transportConfig.Config `hcl:"transport,block"`
 	Ethereum  ethereumConfig.Config  `hcl:"ethereum,block"`
 	Logger    *loggerConfig.Config   `hcl:"logger,block,optional"`
+	Tracing   *tracingConfig.Config  `hcl:"tracing,block,optional"`
 
 	// HCL fields:
 	Remain  hcl.Body        `hcl:",remain"` // To ignore unknown blocks.
@@ -58,14 +69,60 @@ func (Config) DefaultEmbeds() [][]byte {
 	}
 }
 
+// configFeeds contains the ghost block, if it is present in the same
+// configuration. Spectre does not use it to configure services, but it is
+// used to check that relayed data models are published by feeds.
+type configFeeds struct {
+	Ghost *feedConfig.Config `hcl:"ghost,block,optional"`
+
+	// HCL fields:
+	Remain hcl.Body `hcl:",remain"`
+}
+
+// Validate implements the config.Validator interface.
+func (c *Config) Validate() hcl.Diagnostics {
+	var diags hcl.Diagnostics
+	var dataModels []string
+	if c.Remain != nil {
+		// Errors are ignored, the ghost block is validated by Ghost.
+		var feeds configFeeds
+		if err := pkgConfig.DecodeRemain(c.Remain, &feeds); err == nil && feeds.Ghost != nil {
+			dataModels = feeds.Ghost.DataModels
+		}
+	}
+	clients := c.Ethereum.ClientNames()
+	diags = append(diags, c.Spectre.Validate(clients, dataModels)...)
+	diags = append(diags, c.Transport.Validate(clients)...)
+	return diags
+}
+
+// Resolved implements the config.Resolvable interface.
+func (c *Config) Resolved() (pkgConfig.Snapshot, error) {
+	return pkgConfig.NewSnapshot(c.Spectre.Entities())
+}
+
 // Services returns the services that are configured from the Config struct.
 type Services struct {
 	Relay      *relay.Relay
+	Registry   *relay.Registry
+	Watcher    *relay.Watcher
+	Challenger *relay.Challenger
 	PriceStore *datapointStore.Store
 	MuSigStore *musigStore.Store
 	Transport  transport.Service
 	Logger     log.Logger
+	Tracer     *tracing.Tracer
 
+	mu         sync.Mutex
+	ctx        context.Context
+	config     *Config // Config used to create the running relay services.
+	prevConfig *Config // Config used before the last reload, used to roll back.
+	snapshot   pkgConfig.Snapshot
+	clients    ethereumConfig.ClientRegistry
+	scorer     *reputation.Scorer
+	metrics    metrics.Registry
+	relayGroup supervisor.Service
+	reloadCh   chan supervisor.Service
 	supervisor *supervisor.Supervisor
 }
 
@@ -74,16 +131,22 @@ func (s *Services) Start(ctx context.Context) error {
 	if s.supervisor != nil {
 		return fmt.Errorf("services already started")
 	}
+	s.mu.Lock()
+	s.ctx = ctx
+	s.reloadCh = make(chan supervisor.Service)
+	s.mu.Unlock()
 	s.supervisor = supervisor.New(s.Logger)
-	s.supervisor.Watch(
-		s.Transport,
-		s.PriceStore,
-		s.MuSigStore,
-		s.Relay,
-	)
+	s.supervisor.Watch(s.Transport, s.PriceStore, s.MuSigStore, supervisor.NewReloader(supervisor.ReloaderConfig{
+		Factory:  s.relayFactory,
+		Rollback: s.relayRollback,
+		Logger:   s.Logger,
+	}))
 	if l, ok := s.Logger.(supervisor.Service); ok {
 		s.supervisor.Watch(l)
 	}
+	if s.Tracer != nil {
+		s.supervisor.Watch(s.Tracer)
+	}
 	return s.supervisor.Start(ctx)
 }
 
@@ -94,6 +157,10 @@ func (s *Services) Wait() <-chan error {
 
 // Services returns the services configured for Spectre.
 func (c *Config) Services(baseLogger log.Logger, appName string, appVersion string) (supervisor.Service, error) {
+	snapshot, err := c.snapshot()
+	if err != nil {
+		return nil, err
+	}
 	logger, err := c.Logger.Logger(loggerConfig.Dependencies{
 		AppName:    appName,
 		AppVersion: appVersion,
@@ -102,6 +169,15 @@ func (c *Config) Services(baseLogger log.Logger, appName string, appVersion stri
 	if err != nil {
 		return nil, err
 	}
+	tracer, err := c.Tracing.Tracer(tracingConfig.Dependencies{
+		AppName:    appName,
+		AppVersion: appVersion,
+		Logger:     logger,
+	})
+	if err != nil {
+		return nil, err
+	}
+	tracing.SetDefault(tracer)
 	keys, err := c.Ethereum.KeyRegistry(ethereumConfig.Dependencies{Logger: logger})
 	if err != nil {
 		return nil, err
@@ -122,30 +198,274 @@ func (c *Config) Services(baseLogger log.Logger, appName string, appVersion stri
 	if err != nil {
 		return nil, err
 	}
-	transportSrv, err := c.Transport.Transport(transportConfig.Dependencies{
+	scorer, err := c.Spectre.Scorer(logger)
+	if err != nil {
+		return nil, err
+	}
+	transportDeps := transportConfig.Dependencies{
 		Keys:       keys,
 		Clients:    clients,
 		Messages:   messageMap,
 		Logger:     logger,
 		AppName:    appName,
 		AppVersion: appVersion,
-	})
+	}
+	if scorer != nil {
+		transportDeps.Reputation = scorer
+	}
+	// Relay worker health is reported along with the transport metrics.
+	var registry metrics.Registry
+	if c.Transport.Metrics != nil {
+		registry = metrics.NewMemory(nil)
+		transportDeps.Metrics = registry
+	}
+	transportSrv, err := c.Transport.Transport(transportDeps)
 	if err != nil {
 		return nil, err
 	}
-	srvs, err := c.Spectre.Relay(relayConfig.Dependencies{
+	config := c.copy()
+	srvs, relayGroup, err := c.relayServices(relayConfig.Dependencies{
 		Clients:   clients,
 		Transport: transportSrv,
 		Logger:    logger,
+		Scorer:    scorer,
+		Metrics:   registry,
 	})
 	if err != nil {
 		return nil, err
 	}
 	return &Services{
 		Relay:      srvs.Relay,
+		Registry:   srvs.Registry,
+		Watcher:    srvs.Watcher,
+		Challenger: srvs.Challenger,
 		PriceStore: srvs.PriceStore,
 		MuSigStore: srvs.MuSigStore,
 		Transport:  transportSrv,
 		Logger:     logger,
+		Tracer:     tracer,
+		config:     config,
+		snapshot:   snapshot,
+		clients:    clients,
+		scorer:     scorer,
+		metrics:    registry,
+		relayGroup: relayGroup,
 	}, nil
 }
+
+// relayServices returns the relay services together with a service that
+// manages the relay, registry, watcher and challenger. The data point and
+// signature stores are not managed by the returned service, because they
+// must keep running, together with their transport subscriptions, when the
+// relay services are restarted. Relay blocks of Ethereum clients are taken
+// from the config, other dependencies must be provided.
+func (c *Config) relayServices(d relayConfig.Dependencies) (*relayConfig.Services, supervisor.Service, error) {
+	d.Relays = c.Ethereum.RelayRegistry()
+	srvs, err := c.Spectre.Relay(d)
+	if err != nil {
+		return nil, nil, err
+	}
+	group := supervisor.New(d.Logger)
+	group.Watch(srvs.Relay)
+	if srvs.Registry != nil {
+		group.Watch(srvs.Registry)
+	}
+	if srvs.Watcher != nil {
+		group.Watch(srvs.Watcher)
+	}
+	if srvs.Challenger != nil {
+		group.Watch(srvs.Challenger)
+	}
+	return srvs, group, nil
+}
+
+// copy returns a shallow copy of the config. Services created from the
+// config are cached, so the copy must be made before they are created to
+// be able to create new instances of services from it later.
+func (c *Config) copy() *Config {
+	cp := *c
+	return &cp
+}
+
+// Reload implements the supervisor.Reloadable interface.
+//
+// The spectre block and the relay blocks of Ethereum clients are reloaded
+// by restarting the relay services. The transport and stores keep running,
+// so already collected data points and signatures are not lost. If the new
+// relay services fail to start, the previous ones are restored. Changes to
+// other blocks are logged and ignored until the next restart.
+func (s *Services) Reload(cfg supervisor.Config) error {
+	c, ok := cfg.(*Config)
+	if !ok {
+		return fmt.Errorf("unexpected config type %T", cfg)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.reloadCh == nil {
+		return fmt.Errorf("services are not started")
+	}
+	snapshot, err := c.snapshot()
+	if err != nil {
+		return err
+	}
+	logger := s.Logger.WithField("tag", LoggerTag)
+	reload := false
+	for _, name := range s.snapshot.Changed(snapshot) {
+		switch name {
+		case "spectre", "ethereum.relays":
+			reload = true
+		default:
+			logger.WithField("block", name).Warn("Configuration change requires a restart")
+		}
+	}
+	if !reload {
+		logger.Info("No configuration changes to reload")
+		return nil
+	}
+	// The reputation scorer is shared with the transport, so it cannot be
+	// reloaded.
+	if s.scorer == nil {
+		c.Spectre.Reputation = nil
+	}
+	config := c.copy()
+	srvs, relayGroup, err := c.relayServices(s.relayDependencies())
+	if err != nil {
+		return err
+	}
+	select {
+	case s.reloadCh <- relayGroup:
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	}
+	s.prevConfig = s.config
+	s.applyRelayConfig(config, snapshot, srvs)
+	logger.Info("Configuration reloaded")
+	return nil
+}
+
+// relayRollback creates the relay services from the config used before
+// the last reload. It is used by the reloader if the reloaded services
+// fail to start.
+func (s *Services) relayRollback() (supervisor.Service, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.prevConfig == nil {
+		return nil, fmt.Errorf("no previous configuration")
+	}
+	snapshot, err := s.prevConfig.snapshot()
+	if err != nil {
+		return nil, err
+	}
+	config := s.prevConfig
+	srvs, relayGroup, err := s.prevConfig.copy().relayServices(s.relayDependencies())
+	if err != nil {
+		return nil, err
+	}
+	s.prevConfig = nil
+	s.applyRelayConfig(config, snapshot, srvs)
+	s.Logger.WithField("tag", LoggerTag).Warn("Configuration rolled back")
+	return relayGroup, nil
+}
+
+// relayDependencies returns dependencies used to recreate relay services.
+// Existing stores are reused.
+//
+// Must be called with s.mu locked.
+func (s *Services) relayDependencies() relayConfig.Dependencies {
+	return relayConfig.Dependencies{
+		Clients:    s.clients,
+		Transport:  s.Transport,
+		Logger:     s.Logger,
+		Scorer:     s.scorer,
+		Metrics:    s.metrics,
+		PriceStore: s.PriceStore,
+		MuSigStore: s.MuSigStore,
+	}
+}
+
+// applyRelayConfig updates the services after relay services are
+// recreated from the given config.
+//
+// Must be called with s.mu locked.
+func (s *Services) applyRelayConfig(config *Config, snapshot pkgConfig.Snapshot, srvs *relayConfig.Services) {
+	s.config = config
+	s.snapshot["spectre"] = snapshot["spectre"]
+	s.snapshot["ethereum.relays"] = snapshot["ethereum.relays"]
+	s.Relay = srvs.Relay
+	s.Registry = srvs.Registry
+	s.Watcher = srvs.Watcher
+	s.Challenger = srvs.Challenger
+}
+
+// relayFactory is the factory for the relay reloader. It starts the
+// initial relay services and then every relay services sent by Reload.
+func (s *Services) relayFactory(ctx context.Context, ch chan supervisor.Service) error {
+	next := s.relayGroup
+	for {
+		select {
+		case ch <- next:
+		case <-ctx.Done():
+			return nil
+		}
+		select {
+		case next = <-s.reloadCh:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// snapshot returns a snapshot of the configuration used to detect changes
+// on reload. The reputation block and relay blocks of Ethereum clients are
+// stored separately, because they are handled differently than the blocks
+// they belong to.
+func (c *Config) snapshot() (pkgConfig.Snapshot, error) {
+	spectre := c.Spectre
+	spectre.Reputation = nil
+	ethereum := c.Ethereum
+	ethereum.Clients = make([]ethereumConfig.ConfigClient, len(c.Ethereum.Clients))
+	relays := &configRelays{}
+	for i, client := range c.Ethereum.Clients {
+		if client.Relay != nil {
+			relays.Relays = append(relays.Relays, configClientRelay{Name: client.Name, Relay: *client.Relay})
+		}
+		client.Relay = nil
+		ethereum.Clients[i] = client
+	}
+	return pkgConfig.NewSnapshot(map[string]any{
+		"spectre":            &spectre,
+		"spectre.reputation": c.Spectre.Reputation,
+		"ethereum":           &ethereum,
+		"ethereum.relays":    relays,
+		"transport":          &c.Transport,
+		"logger":             c.Logger,
+		"tracing":            c.Tracing,
+	})
+}
+
+// configRelays contains relay blocks of Ethereum clients. It is used only
+// to take a snapshot of them.
+type configRelays struct {
+	Relays []configClientRelay `hcl:"client,block"`
+}
+
+type configClientRelay struct {
+	Name  string                     `hcl:"name,label"`
+	Relay ethereumConfig.ConfigRelay `hcl:"relay,block"`
+}
+
+// Contracts returns the list of contracts handled by the relay, including
+// contracts resolved from on-chain registries.
+func (c *Config) Contracts(ctx context.Context, baseLogger log.Logger) ([]relayConfig.Contract, error) {
+	clients, err := c.Ethereum.ClientRegistry(ethereumConfig.Dependencies{Logger: baseLogger})
+	if err != nil {
+		return nil, err
+	}
+	// The transport is required by the data point stores, but it is never
+	// started, because the contracts are only resolved.
+	return c.Spectre.Contracts(ctx, relayConfig.Dependencies{
+		Clients:   clients,
+		Transport: local.New(nil, 0, nil),
+		Logger:    baseLogger,
+	})
+}
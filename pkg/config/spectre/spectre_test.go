@@ -16,10 +16,12 @@
 package spectre
 
 import (
+	"context"
 	"os"
 	"strings"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/orcfax/oracle-suite/pkg/config"
@@ -40,6 +42,13 @@ func TestConfig(t *testing.T) {
 				services, err := cfg.Services(null.New(), "", "")
 				require.NoError(t, err)
 				require.NotNil(t, services)
+				require.NotNil(t, services.(*Services).Registry)
+				require.NotNil(t, services.(*Services).Watcher)
+				require.NotNil(t, services.(*Services).Challenger)
+
+				health := services.(*Services).Relay.Health()
+				require.Len(t, health, 1)
+				assert.Equal(t, "client1", health[0].Name)
 			},
 		},
 	}
@@ -53,6 +62,98 @@ func TestConfig(t *testing.T) {
 	}
 }
 
+func TestReload(t *testing.T) {
+	ctx, ctxCancel := context.WithCancel(context.Background())
+	defer ctxCancel()
+
+	load := func(path string) *Config {
+		var cfg Config
+		require.NoError(t, config.LoadFiles(&cfg, []string{"./testdata/" + path}))
+		return &cfg
+	}
+
+	cfg := load("reload.hcl")
+	srv, err := cfg.Services(null.New(), "", "")
+	require.NoError(t, err)
+	services := srv.(*Services)
+	require.NoError(t, services.Start(ctx))
+	transport := services.Transport
+	relay := services.Relay
+	priceStore := services.PriceStore
+	muSigStore := services.MuSigStore
+
+	// Unchanged configuration does not restart the relay.
+	require.NoError(t, services.Reload(load("reload.hcl")))
+	assert.Same(t, relay, services.Relay)
+
+	// Invalid configuration is rejected and the relay keeps running.
+	require.Error(t, services.Reload(load("reload-invalid.hcl")))
+	assert.Same(t, relay, services.Relay)
+
+	// Changed contract configuration restarts the relay, but not the
+	// transport and stores.
+	require.NoError(t, services.Reload(load("reload-changed.hcl")))
+	assert.NotSame(t, relay, services.Relay)
+	assert.Same(t, transport, services.Transport)
+	assert.Same(t, priceStore, services.PriceStore)
+	assert.Same(t, muSigStore, services.MuSigStore)
+	assert.Equal(t, []string{"BTC/USD"}, services.PriceStore.Models())
+	relay = services.Relay
+
+	// Rolling back restores the previous contract configuration.
+	_, err = services.relayRollback()
+	require.NoError(t, err)
+	assert.NotSame(t, relay, services.Relay)
+	assert.Equal(t, []string{"ETH/USD"}, services.PriceStore.Models())
+	_, err = services.relayRollback()
+	require.Error(t, err)
+	relay = services.Relay
+
+	// Changed relay block of an Ethereum client restarts the relay.
+	require.NoError(t, services.Reload(load("reload-relay.hcl")))
+	assert.NotSame(t, relay, services.Relay)
+
+	ctxCancel()
+	<-services.Wait()
+}
+
+func TestValidate(t *testing.T) {
+	var cfg Config
+	require.NoError(t, config.LoadFiles(&cfg, []string{"./testdata/validate.hcl"}))
+
+	diags := cfg.Validate()
+	require.Len(t, diags, 2)
+	require.Len(t, diags.Errs(), 1)
+	assert.Equal(t, `Ethereum client "client2" is not configured`, diags[0].Detail)
+	assert.Equal(t, 18, diags[0].Subject.Start.Line)
+	assert.Equal(t, `Data model "BTC/USD" is not published by any feed`, diags[1].Detail)
+	assert.Equal(t, 20, diags[1].Subject.Start.Line)
+
+	// Without the ghost block, published data models are not checked.
+	cfg = Config{}
+	require.NoError(t, config.LoadFiles(&cfg, []string{"./testdata/reload.hcl"}))
+	assert.Empty(t, cfg.Validate())
+}
+
+func TestResolved(t *testing.T) {
+	resolve := func(path string) config.Snapshot {
+		var cfg Config
+		require.NoError(t, config.LoadFiles(&cfg, []string{"./testdata/" + path}))
+		snapshot, err := cfg.Resolved()
+		require.NoError(t, err)
+		return snapshot
+	}
+
+	snapshot := resolve("reload.hcl")
+	assert.Equal(t, []string{
+		`median "client1" "0x1234567890123456789012345678901234567890"`,
+	}, snapshot.Changed(nil))
+	assert.Empty(t, snapshot.Changed(resolve("reload-relay.hcl")))
+	assert.Equal(t, []string{
+		`median "client1" "0x1234567890123456789012345678901234567890"`,
+	}, snapshot.Changed(resolve("reload-changed.hcl")))
+}
+
 func TestDefaults(t *testing.T) {
 	cfg := &Config{}
 	require.NoError(t, config.LoadEmbeds(cfg, cfg.DefaultEmbeds()))
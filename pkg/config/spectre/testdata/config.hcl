@@ -36,6 +36,27 @@ spectre {
       "0x5566778899001122334455667788990011223344",
     ]
   }
+
+  registry {
+    ethereum_client        = "client1"
+    chainlog_addr          = "0x4567890123456789012345678901234567890123"
+    relay_ethereum_clients = ["client1"]
+
+    scribe {
+      spread     = 1
+      expiration = 3600
+    }
+  }
+
+  watcher {
+    interval     = 60
+    grace_period = 300
+  }
+
+  challenger {
+    interval    = 30
+    block_range = 500
+  }
 }
 
 ethereum {
@@ -45,6 +66,11 @@ ethereum {
     rpc_urls     = ["https://rpc1.example"]
     chain_id     = 1
     ethereum_key = "key1"
+
+    relay {
+      interval        = 30
+      max_concurrency = 4
+    }
   }
 }
 
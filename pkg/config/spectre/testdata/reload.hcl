@@ -0,0 +1,36 @@
+spectre {
+  median {
+    ethereum_client = "client1"
+    contract_addr   = "0x1234567890123456789012345678901234567890"
+    data_model      = "ETH/USD"
+    spread          = 1
+    expiration      = 300
+    feeds           = [
+      "0x0011223344556677889900112233445566778899",
+      "0x1122334455667788990011223344556677889900",
+    ]
+  }
+}
+
+ethereum {
+  rand_keys = ["key1"]
+
+  client "client1" {
+    rpc_urls     = ["http://127.0.0.1:1"]
+    chain_id     = 1
+    ethereum_key = "key1"
+
+    relay {
+      interval = 30
+    }
+  }
+}
+
+transport {
+  libp2p {
+    feeds             = ["0x1234567890123456789012345678901234567890"]
+    listen_addrs      = ["/ip4/127.0.0.1/tcp/0"]
+    disable_discovery = true
+    ethereum_key      = "key1"
+  }
+}
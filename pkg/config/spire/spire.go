@@ -29,11 +29,13 @@ import (
 
 	ethereumConfig "github.com/orcfax/oracle-suite/pkg/config/ethereum"
 	loggerConfig "github.com/orcfax/oracle-suite/pkg/config/logger"
+	tracingConfig "github.com/orcfax/oracle-suite/pkg/config/tracing"
 	transportConfig "github.com/orcfax/oracle-suite/pkg/config/transport"
 	"github.com/orcfax/oracle-suite/pkg/datapoint/store"
 	"github.com/orcfax/oracle-suite/pkg/log"
 	"github.com/orcfax/oracle-suite/pkg/spire"
 	pkgSupervisor "github.com/orcfax/oracle-suite/pkg/supervisor"
+	pkgTracing "github.com/orcfax/oracle-suite/pkg/tracing"
 	pkgTransport "github.com/orcfax/oracle-suite/pkg/transport"
 	"github.com/orcfax/oracle-suite/pkg/transport/messages"
 )
@@ -44,6 +46,7 @@ type Config struct {
 	Transport transportConfig.Config `hcl:"transport,block"`
 	Ethereum  ethereumConfig.Config  `hcl:"ethereum,block"`
 	Logger    *loggerConfig.Config   `hcl:"logger,block,optional"`
+	Tracing   *tracingConfig.Config  `hcl:"tracing,block,optional"`
 
 	// HCL fields:
 	Remain  hcl.Body        `hcl:",remain"` // To ignore unknown blocks.
@@ -60,6 +63,11 @@ func (Config) DefaultEmbeds() [][]byte {
 	}
 }
 
+// Validate implements the config.Validator interface.
+func (c *Config) Validate() hcl.Diagnostics {
+	return c.Transport.Validate(c.Ethereum.ClientNames())
+}
+
 type ConfigSpire struct {
 	// RPCListenAddr is an address to listen for RPC requests.
 	RPCListenAddr string `hcl:"rpc_listen_addr"`
@@ -101,6 +109,7 @@ type AgentServices struct {
 	Transport  pkgTransport.Service
 	PriceStore *store.Store
 	Logger     log.Logger
+	Tracer     *pkgTracing.Tracer
 
 	supervisor *pkgSupervisor.Supervisor
 }
@@ -141,6 +150,9 @@ func (s *AgentServices) Start(ctx context.Context) error {
 	if l, ok := s.Logger.(pkgSupervisor.Service); ok {
 		s.supervisor.Watch(l)
 	}
+	if s.Tracer != nil {
+		s.supervisor.Watch(s.Tracer)
+	}
 	return s.supervisor.Start(ctx)
 }
 
@@ -201,6 +213,15 @@ func (c *Config) Services(baseLogger log.Logger, appName string, appVersion stri
 	if err != nil {
 		return nil, err
 	}
+	tracer, err := c.Tracing.Tracer(tracingConfig.Dependencies{
+		AppName:    appName,
+		AppVersion: appVersion,
+		Logger:     logger,
+	})
+	if err != nil {
+		return nil, err
+	}
+	pkgTracing.SetDefault(tracer)
 	keys, err := c.Ethereum.KeyRegistry(ethereumConfig.Dependencies{Logger: logger})
 	if err != nil {
 		return nil, err
@@ -239,6 +260,7 @@ func (c *Config) Services(baseLogger log.Logger, appName string, appVersion stri
 		Transport:  transport,
 		PriceStore: priceStore,
 		Logger:     logger,
+		Tracer:     tracer,
 	}, nil
 }
 
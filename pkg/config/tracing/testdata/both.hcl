@@ -0,0 +1,4 @@
+tracing {
+  file     = "/tmp/traces.json"
+  endpoint = "http://localhost:4318/v1/traces"
+}
@@ -0,0 +1,6 @@
+tracing {
+  endpoint = "http://localhost:4318/v1/traces"
+  headers  = {
+    Authorization = "Bearer token"
+  }
+}
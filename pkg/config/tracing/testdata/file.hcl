@@ -0,0 +1,5 @@
+tracing {
+  file           = "/tmp/traces.json"
+  interval       = 10
+  max_queue_size = 100
+}
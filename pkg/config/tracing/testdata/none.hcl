@@ -0,0 +1,3 @@
+tracing {
+  interval = 10
+}
@@ -0,0 +1,117 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package tracing
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/hcl/v2"
+
+	"github.com/orcfax/oracle-suite/pkg/config"
+	"github.com/orcfax/oracle-suite/pkg/log"
+	"github.com/orcfax/oracle-suite/pkg/tracing"
+)
+
+const defaultExportTimeout = 10 * time.Second
+
+type Dependencies struct {
+	AppName    string
+	AppVersion string
+	Logger     log.Logger
+}
+
+type Config struct {
+	// File is a path to a file to which spans are appended in the OTLP/JSON
+	// format, one export request per line.
+	File string `hcl:"file,optional"`
+
+	// Endpoint is an OTLP/HTTP collector endpoint to which spans are sent
+	// in the OTLP/JSON format, e.g. http://localhost:4318/v1/traces.
+	Endpoint *config.URL `hcl:"endpoint,optional"`
+
+	// Headers is a map of HTTP headers added to requests sent to the
+	// collector.
+	Headers map[string]string `hcl:"headers,optional"`
+
+	// Interval is a time interval in seconds between exports.
+	Interval uint32 `hcl:"interval,optional"`
+
+	// MaxQueueSize is the maximum number of spans waiting to be exported.
+	MaxQueueSize int `hcl:"max_queue_size,optional"`
+
+	// HCL fields:
+	Range   hcl.Range       `hcl:",range"`
+	Content hcl.BodyContent `hcl:",content"`
+
+	// Configured service:
+	tracer *tracing.Tracer
+}
+
+// Tracer returns the tracer configured by the tracing block. If the block
+// is not present, nil is returned.
+func (c *Config) Tracer(d Dependencies) (*tracing.Tracer, error) {
+	if c == nil {
+		return nil, nil
+	}
+	if c.tracer != nil {
+		return c.tracer, nil
+	}
+	var exporter tracing.Exporter
+	switch {
+	case c.File != "" && c.Endpoint != nil:
+		return nil, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Validation error",
+			Detail:   "Only one of file and endpoint can be set.",
+			Subject:  c.Content.Attributes["endpoint"].Range.Ptr(),
+		}
+	case c.File != "":
+		exporter = tracing.NewFileExporter(c.File)
+	case c.Endpoint != nil:
+		exporter = tracing.NewHTTPExporter(
+			c.Endpoint.String(),
+			c.Headers,
+			&http.Client{Timeout: defaultExportTimeout},
+		)
+	default:
+		return nil, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Validation error",
+			Detail:   "Either file or endpoint must be set.",
+			Subject:  c.Range.Ptr(),
+		}
+	}
+	tracer, err := tracing.New(tracing.Config{
+		Exporter:       exporter,
+		ServiceName:    d.AppName,
+		ServiceVersion: d.AppVersion,
+		Interval:       time.Duration(c.Interval) * time.Second,
+		MaxQueueSize:   c.MaxQueueSize,
+		Logger:         d.Logger,
+	})
+	if err != nil {
+		return nil, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Runtime error",
+			Detail:   fmt.Sprintf("Failed to create the tracer: %s", err),
+			Subject:  c.Range.Ptr(),
+		}
+	}
+	c.tracer = tracer
+	return tracer, nil
+}
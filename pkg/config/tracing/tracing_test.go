@@ -0,0 +1,102 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package tracing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/orcfax/oracle-suite/pkg/config"
+	"github.com/orcfax/oracle-suite/pkg/log/null"
+)
+
+type testConfig struct {
+	Tracing *Config `hcl:"tracing,block,optional"`
+}
+
+func TestConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		test func(*testing.T, *Config)
+	}{
+		{
+			name: "file",
+			path: "file.hcl",
+			test: func(t *testing.T, cfg *Config) {
+				assert.Equal(t, "/tmp/traces.json", cfg.File)
+				assert.Nil(t, cfg.Endpoint)
+				assert.Equal(t, uint32(10), cfg.Interval)
+				assert.Equal(t, 100, cfg.MaxQueueSize)
+
+				tracer, err := cfg.Tracer(Dependencies{AppName: "ghost", Logger: null.New()})
+				require.NoError(t, err)
+				assert.NotNil(t, tracer)
+
+				// Tracer is created only once.
+				tracer2, err := cfg.Tracer(Dependencies{AppName: "ghost", Logger: null.New()})
+				require.NoError(t, err)
+				assert.Same(t, tracer, tracer2)
+			},
+		},
+		{
+			name: "endpoint",
+			path: "endpoint.hcl",
+			test: func(t *testing.T, cfg *Config) {
+				assert.Equal(t, "http://localhost:4318/v1/traces", cfg.Endpoint.String())
+				assert.Equal(t, map[string]string{"Authorization": "Bearer token"}, cfg.Headers)
+
+				tracer, err := cfg.Tracer(Dependencies{AppName: "ghost", Logger: null.New()})
+				require.NoError(t, err)
+				assert.NotNil(t, tracer)
+			},
+		},
+		{
+			name: "both",
+			path: "both.hcl",
+			test: func(t *testing.T, cfg *Config) {
+				_, err := cfg.Tracer(Dependencies{Logger: null.New()})
+				assert.ErrorContains(t, err, "Only one of file and endpoint can be set")
+			},
+		},
+		{
+			name: "none",
+			path: "none.hcl",
+			test: func(t *testing.T, cfg *Config) {
+				_, err := cfg.Tracer(Dependencies{Logger: null.New()})
+				assert.ErrorContains(t, err, "Either file or endpoint must be set")
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var cfg testConfig
+			err := config.LoadFiles(&cfg, []string{"./testdata/" + test.path})
+			require.NoError(t, err)
+			require.NotNil(t, cfg.Tracing)
+			test.test(t, cfg.Tracing)
+		})
+	}
+}
+
+func TestConfig_Nil(t *testing.T) {
+	var cfg *Config
+	tracer, err := cfg.Tracer(Dependencies{Logger: null.New()})
+	require.NoError(t, err)
+	assert.Nil(t, tracer)
+}
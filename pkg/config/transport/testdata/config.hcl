@@ -15,6 +15,7 @@ webapi {
   listen_addr       = "localhost:8080"
   socks5_proxy_addr = "localhost:9050"
   ethereum_key      = "key"
+  streaming         = true
 
   ethereum_address_book {
     contract_addr   = "0x5678901234567890123456789012345678901234"
@@ -24,4 +25,69 @@ webapi {
   static_address_book {
     addresses = ["https://example.com/api/v1/endpoint"]
   }
+
+  dns_address_book {
+    domains   = ["consumers.example.com"]
+    signer    = "0x6789012345678901234567890123456789012345"
+    cache_ttl = 600
+  }
+
+  well_known_address_book {
+    urls      = ["https://example.com"]
+    signer    = "0x7890123456789012345678901234567890123456"
+    cache_ttl = 300
+  }
+}
+
+mq {
+  feeds               = ["0x3456789012345678901234567890123456789012"]
+  listen_addr         = "localhost:8100"
+  consumers           = ["localhost:8200"]
+  journal_path        = "./journal"
+  max_journal_records = 1000
+  socks5_proxy_addr   = "localhost:9050"
+  ethereum_key        = "key"
+}
+
+mqtt {
+  broker_addr  = "localhost:1883"
+  client_id    = "spire"
+  username     = "user"
+  password     = "pass"
+  tls          = true
+  qos          = 1
+  format       = "json"
+  feeds        = ["0x2222222222222222222222222222222222222222"]
+  ethereum_key = "key"
+
+  topic "data_point/v1" {
+    publish   = "oracle/data_point/v1"
+    subscribe = "oracle/in/data_point/v1"
+    format    = "protobuf"
+  }
+
+  topic "greet/v1" {
+    publish = "oracle/greet/v1"
+  }
+}
+
+private {
+  members               = ["0x1234567890123456789012345678901234567890", "0x2345678901234567890123456789012345678901"]
+  topics                = ["musig_partial_signature/v1.1"]
+  ethereum_key          = "key"
+  key_rotation_interval = 600
+}
+
+metrics {
+  listen_addr = "localhost:9100"
+  path        = "/metrics"
+}
+
+dedup {
+  window    = 300
+  monotonic = true
+}
+
+versioned {
+  topics = ["data_point/v1"]
 }
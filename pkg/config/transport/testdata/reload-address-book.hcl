@@ -0,0 +1,9 @@
+webapi {
+  feeds        = ["0x3456789012345678901234567890123456789012"]
+  listen_addr  = "localhost:0"
+  ethereum_key = "key"
+
+  static_address_book {
+    addresses = ["consumer2.example"]
+  }
+}
@@ -20,6 +20,7 @@ import (
 	"context"
 	"crypto/ed25519"
 	"crypto/rand"
+	"crypto/tls"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -33,28 +34,64 @@ import (
 	"github.com/multiformats/go-multiaddr"
 	"golang.org/x/net/proxy"
 
+	"github.com/orcfax/oracle-suite/pkg/config"
 	"github.com/orcfax/oracle-suite/pkg/config/ethereum"
 	"github.com/orcfax/oracle-suite/pkg/transport/logger"
 	"github.com/orcfax/oracle-suite/pkg/util/sliceutil"
 
+	"github.com/orcfax/oracle-suite/pkg/httpserver"
+	"github.com/orcfax/oracle-suite/pkg/httpserver/middleware"
 	"github.com/orcfax/oracle-suite/pkg/log"
+	"github.com/orcfax/oracle-suite/pkg/metrics"
 	"github.com/orcfax/oracle-suite/pkg/transport"
 	"github.com/orcfax/oracle-suite/pkg/transport/chain"
+	"github.com/orcfax/oracle-suite/pkg/transport/dedup"
 	"github.com/orcfax/oracle-suite/pkg/transport/libp2p"
 	"github.com/orcfax/oracle-suite/pkg/transport/libp2p/crypto/ethkey"
+	"github.com/orcfax/oracle-suite/pkg/transport/messages"
+	"github.com/orcfax/oracle-suite/pkg/transport/monitor"
+	"github.com/orcfax/oracle-suite/pkg/transport/mq"
+	"github.com/orcfax/oracle-suite/pkg/transport/mqtt"
+	"github.com/orcfax/oracle-suite/pkg/transport/private"
 	"github.com/orcfax/oracle-suite/pkg/transport/recoverer"
+	"github.com/orcfax/oracle-suite/pkg/transport/versioned"
 	"github.com/orcfax/oracle-suite/pkg/transport/webapi"
+	"github.com/orcfax/oracle-suite/pkg/util/maputil"
 	"github.com/orcfax/oracle-suite/pkg/util/timeutil"
 )
 
 const LoggerTag = "CONFIG_LIBP2P"
 
+// ErrRestartRequired is returned when a configuration change cannot be
+// applied without restarting the transport.
+var ErrRestartRequired = errors.New("configuration change requires a restart")
+
+const (
+	defaultMetricsPath    = "/metrics"
+	defaultMetricsTimeout = 10 * time.Second
+	defaultDedupWindow    = 10 * time.Minute
+)
+
 type Dependencies struct {
 	Keys     ethereum.KeyRegistry
 	Clients  ethereum.ClientRegistry
 	Messages map[string]transport.Message
 	Logger   log.Logger
 
+	// Schemas is an optional list of versioned message schemas. Messages on
+	// listed topics are published in versioned envelopes. Schemas override
+	// the ones created for topics listed in the versioned block.
+	Schemas map[string]versioned.Schema
+
+	// Metrics is an optional metrics registry. If set, transport metrics
+	// are collected even if the metrics block is not configured. If nil and
+	// the metrics block is configured, an in-memory registry is used.
+	Metrics metrics.Registry
+
+	// Reputation is an optional source of feed reputation used to penalize
+	// peers publishing poor quality data.
+	Reputation libp2p.Reputation
+
 	// Application info:
 	AppName    string
 	AppVersion string
@@ -71,13 +108,30 @@ type BootstrapDependencies struct {
 type Config struct {
 	LibP2P *libP2PConfig `hcl:"libp2p,block,optional"`
 	WebAPI *webAPIConfig `hcl:"webapi,block,optional"`
+	MQ     *mqConfig     `hcl:"mq,block,optional"`
+	MQTT   *mqttConfig   `hcl:"mqtt,block,optional"`
+
+	// Private configures end-to-end encrypted topics shared between
+	// a group of feeds.
+	Private *privateConfig `hcl:"private,block,optional"`
+
+	// Metrics configures collection of transport metrics.
+	Metrics *metricsConfig `hcl:"metrics,block,optional"`
+
+	// Dedup configures dropping of duplicated and stale messages.
+	Dedup *dedupConfig `hcl:"dedup,block,optional"`
+
+	// Versioned configures topics published in versioned envelopes.
+	Versioned *versionedConfig `hcl:"versioned,block,optional"`
 
 	// HCL fields:
 	Range   hcl.Range       `hcl:",range"`
 	Content hcl.BodyContent `hcl:",content"`
 
 	// Configured transport:
-	transport transport.Service
+	transport         transport.Service
+	webAPIClient      *http.Client
+	webAPIAddressBook *webapi.SwappableAddressBook
 }
 
 type libP2PConfig struct {
@@ -146,6 +200,10 @@ type webAPIConfig struct {
 	// Required if the transport is used for sending messages.
 	EthereumKey string `hcl:"ethereum_key"`
 
+	// Streaming enables pushing messages to consumers over long-lived
+	// websocket connections instead of sending them in batches.
+	Streaming bool `hcl:"streaming,optional"`
+
 	// AddressBook configuration. Address book provides a list of addresses
 	// to which messages will be sent.
 
@@ -155,6 +213,13 @@ type webAPIConfig struct {
 	// StaticAddressBook is the configuration for the static address book.
 	StaticAddressBook *webAPIStaticAddressBook `hcl:"static_address_book,block,optional"`
 
+	// DNSAddressBook is the configuration for the DNS address book.
+	DNSAddressBook *webAPIDNSAddressBook `hcl:"dns_address_book,block,optional"`
+
+	// WellKnownAddressBook is the configuration for the well-known address
+	// book.
+	WellKnownAddressBook *webAPIWellKnownAddressBook `hcl:"well_known_address_book,block,optional"`
+
 	// HCL fields:
 	Range   hcl.Range       `hcl:",range"`
 	Content hcl.BodyContent `hcl:",content"`
@@ -183,10 +248,222 @@ type webAPIStaticAddressBook struct {
 	Content hcl.BodyContent `hcl:",content"`
 }
 
+type webAPIDNSAddressBook struct {
+	// Domains is the list of domain names with TXT records containing
+	// consumer addresses.
+	Domains []string `hcl:"domains"`
+
+	// Signer is the Ethereum address of the key used to sign the records.
+	Signer types.Address `hcl:"signer"`
+
+	// CacheTTL is the time in seconds for which the addresses are cached.
+	CacheTTL uint32 `hcl:"cache_ttl,optional"`
+
+	// HCL fields:
+	Range   hcl.Range       `hcl:",range"`
+	Content hcl.BodyContent `hcl:",content"`
+}
+
+type webAPIWellKnownAddressBook struct {
+	// URLs is the list of server URLs serving the well-known consumers
+	// document.
+	URLs []string `hcl:"urls"`
+
+	// Signer is the Ethereum address of the key used to sign the document.
+	Signer types.Address `hcl:"signer"`
+
+	// CacheTTL is the time in seconds for which the addresses are cached.
+	CacheTTL uint32 `hcl:"cache_ttl,optional"`
+
+	// HCL fields:
+	Range   hcl.Range       `hcl:",range"`
+	Content hcl.BodyContent `hcl:",content"`
+}
+
+type mqConfig struct {
+	// Feeds is a list of Ethereum addresses that are allowed to send messages
+	// to the node.
+	Feeds []types.Address `hcl:"feeds"`
+
+	// ListenAddr is the address on which the node will listen for
+	// connections from producers. The address must be in the format
+	// `host:port`. If empty, the node does not receive messages.
+	ListenAddr string `hcl:"listen_addr,optional"`
+
+	// Consumers is the list of consumer addresses to which messages will be
+	// sent. The addresses must be in the format `host:port`.
+	Consumers []string `hcl:"consumers,optional"`
+
+	// JournalPath is the directory in which journals of sent and received
+	// messages are stored.
+	JournalPath string `hcl:"journal_path"`
+
+	// MaxJournalRecords is the number of the most recent messages kept in
+	// each journal.
+	MaxJournalRecords uint32 `hcl:"max_journal_records,optional"`
+
+	// Socks5ProxyAddr is the address of the SOCKS5 proxy server. The address
+	// must be in the format `host:port`.
+	Socks5ProxyAddr string `hcl:"socks5_proxy_addr,optional"`
+
+	// EthereumKey is the name of the Ethereum key to use for signing messages.
+	// Required if the transport is used for sending messages.
+	EthereumKey string `hcl:"ethereum_key,optional"`
+
+	// HCL fields:
+	Range   hcl.Range       `hcl:",range"`
+	Content hcl.BodyContent `hcl:",content"`
+}
+
+type mqttConfig struct {
+	// BrokerAddr is the address of the MQTT broker. The address must be in
+	// the format `host:port`.
+	BrokerAddr string `hcl:"broker_addr"`
+
+	// ClientID is the client identifier sent to the broker.
+	ClientID string `hcl:"client_id,optional"`
+
+	// Username and Password are optional credentials for the broker.
+	Username string `hcl:"username,optional"`
+	Password string `hcl:"password,optional"`
+
+	// TLS enables TLS connections to the broker.
+	TLS bool `hcl:"tls,optional"`
+
+	// QoS is the MQTT quality of service level, 0 or 1.
+	QoS uint8 `hcl:"qos,optional"`
+
+	// Format is the default payload format, `protobuf` or `json`.
+	Format string `hcl:"format,optional"`
+
+	// Feeds is a list of Ethereum addresses that are allowed to publish
+	// messages to subscribed topics. Required if any topic subscribes to
+	// the broker.
+	Feeds []types.Address `hcl:"feeds,optional"`
+
+	// EthereumKey is the name of the Ethereum key used to sign published
+	// messages authored by this node. Messages authored by other feeds are
+	// published with their original signatures. If empty, messages are
+	// published without signatures and cannot be received by other nodes.
+	EthereumKey string `hcl:"ethereum_key,optional"`
+
+	// Topics is the list of bridged topics.
+	Topics []mqttTopicConfig `hcl:"topic,block"`
+
+	// HCL fields:
+	Range   hcl.Range       `hcl:",range"`
+	Content hcl.BodyContent `hcl:",content"`
+}
+
+type privateConfig struct {
+	// Members is a list of Ethereum addresses of the group members. Only
+	// members can read messages on private topics.
+	Members []types.Address `hcl:"members"`
+
+	// Topics is a list of private topics.
+	Topics []string `hcl:"topics"`
+
+	// EthereumKey is the name of the Ethereum key used to sign group key
+	// announcements. If empty, or the key is not on the members list,
+	// the node only relays encrypted messages.
+	EthereumKey string `hcl:"ethereum_key,optional"`
+
+	// KeyRotationInterval is the interval in seconds between group key
+	// rotations.
+	KeyRotationInterval uint32 `hcl:"key_rotation_interval,optional"`
+
+	// HCL fields:
+	Range   hcl.Range       `hcl:",range"`
+	Content hcl.BodyContent `hcl:",content"`
+}
+
+type metricsConfig struct {
+	// ListenAddr is the address on which metrics are exposed over HTTP.
+	// The address must be in the format `host:port`. If empty, metrics are
+	// not exposed.
+	ListenAddr string `hcl:"listen_addr,optional"`
+
+	// Path is the HTTP path under which metrics are exposed.
+	Path string `hcl:"path,optional"`
+
+	// HCL fields:
+	Range   hcl.Range       `hcl:",range"`
+	Content hcl.BodyContent `hcl:",content"`
+}
+
+type versionedConfig struct {
+	// Topics is a list of topics on which messages are published in
+	// versioned envelopes. Messages are published in version 1, which is
+	// the current message format of the topic. Topics that are not used by
+	// the application are ignored.
+	Topics []string `hcl:"topics"`
+
+	// HCL fields:
+	Range   hcl.Range       `hcl:",range"`
+	Content hcl.BodyContent `hcl:",content"`
+}
+
+type dedupConfig struct {
+	// Window is the time in seconds during which duplicates of a message
+	// are dropped.
+	Window uint32 `hcl:"window,optional"`
+
+	// Monotonic enables dropping data points that are not newer than the
+	// last data point received from the same feed for the same model.
+	Monotonic bool `hcl:"monotonic,optional"`
+
+	// HCL fields:
+	Range   hcl.Range       `hcl:",range"`
+	Content hcl.BodyContent `hcl:",content"`
+}
+
+type mqttTopicConfig struct {
+	// Name is the name of the transport topic.
+	Name string `hcl:"name,label"`
+
+	// Publish is the MQTT topic to which messages are published.
+	Publish string `hcl:"publish,optional"`
+
+	// Subscribe is the MQTT topic filter from which messages are received.
+	Subscribe string `hcl:"subscribe,optional"`
+
+	// Format is the payload format. If empty, the default format is used.
+	Format string `hcl:"format,optional"`
+
+	// HCL fields:
+	Range   hcl.Range       `hcl:",range"`
+	Content hcl.BodyContent `hcl:",content"`
+}
+
 func (c *Config) Transport(d Dependencies) (transport.Service, error) {
 	if c.transport != nil {
 		return c.transport, nil
 	}
+	schemas, err := c.versionedSchemas(d)
+	if err != nil {
+		return nil, err
+	}
+	d.Schemas = schemas
+	if len(d.Schemas) > 0 {
+		d.Messages = versioned.Topics(d.Messages, d.Schemas)
+	}
+	var privateTopics map[string]transport.Message
+	if c.Private != nil {
+		var err error
+		privateTopics, err = maputil.Select(d.Messages, c.Private.Topics)
+		if err != nil {
+			return nil, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Validation error",
+				Detail:   fmt.Sprintf("Invalid private topic: %v", err),
+				Subject:  c.Private.Content.Attributes["topics"].Range.Ptr(),
+			}
+		}
+		d.Messages = private.Topics(d.Messages, c.Private.Topics)
+	}
+	if c.Metrics != nil && d.Metrics == nil {
+		d.Metrics = metrics.NewMemory(nil)
+	}
 	var transports []transport.Service
 	if c.LibP2P != nil {
 		t, err := c.configureLibP2P(d)
@@ -202,6 +479,13 @@ func (c *Config) Transport(d Dependencies) (transport.Service, error) {
 		}
 		transports = append(transports, t)
 	}
+	if c.MQ != nil {
+		t, err := c.configureMQ(d)
+		if err != nil {
+			return nil, err
+		}
+		transports = append(transports, t)
+	}
 	switch {
 	case len(transports) == 0:
 		return nil, &hcl.Diagnostic{
@@ -215,9 +499,118 @@ func (c *Config) Transport(d Dependencies) (transport.Service, error) {
 	default:
 		c.transport = chain.New(transports...)
 	}
+	if c.MQTT != nil {
+		t, err := c.configureMQTT(d, c.transport)
+		if err != nil {
+			return nil, err
+		}
+		c.transport = t
+	}
+	if c.Private != nil {
+		t, err := c.configurePrivate(d, c.transport, privateTopics)
+		if err != nil {
+			return nil, err
+		}
+		c.transport = t
+	}
+	if len(d.Schemas) > 0 {
+		t, err := versioned.New(versioned.Config{
+			Transport: c.transport,
+			Schemas:   d.Schemas,
+			Logger:    d.Logger,
+		})
+		if err != nil {
+			return nil, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Runtime error",
+				Detail:   fmt.Sprintf("Cannot create versioned transport: %v", err),
+				Subject:  &c.Range,
+			}
+		}
+		c.transport = t
+	}
+	if c.Dedup != nil {
+		t, err := c.configureDedup(d, c.transport)
+		if err != nil {
+			return nil, err
+		}
+		c.transport = t
+	}
+	if d.Metrics != nil {
+		t, err := c.configureMonitor(d, c.transport)
+		if err != nil {
+			return nil, err
+		}
+		c.transport = t
+	}
 	return logger.New(c.transport, d.Logger), nil
 }
 
+// Validate checks that Ethereum clients used by the transport are
+// configured. Clients is a list of configured Ethereum client names.
+// ReloadAddressBook replaces the consumer address book of the WebAPI
+// transport created from the running config with the address book
+// configured in c. The transport keeps running.
+//
+// Only the address book can be reloaded. If the configs differ in any
+// other way, ErrRestartRequired is returned.
+func (c *Config) ReloadAddressBook(running *Config, d Dependencies) error {
+	if running.webAPIAddressBook == nil || c.WebAPI == nil {
+		return ErrRestartRequired
+	}
+	cs, err := c.withoutAddressBook().snapshot()
+	if err != nil {
+		return err
+	}
+	rs, err := running.withoutAddressBook().snapshot()
+	if err != nil {
+		return err
+	}
+	if len(cs.Changed(rs)) > 0 {
+		return ErrRestartRequired
+	}
+	addressBook, err := c.configureWebAPIAddressBook(d, running.webAPIClient)
+	if err != nil {
+		return err
+	}
+	running.webAPIAddressBook.Swap(addressBook)
+	return nil
+}
+
+// withoutAddressBook returns a copy of the config without the WebAPI
+// address book blocks.
+func (c *Config) withoutAddressBook() *Config {
+	cp := *c
+	if c.WebAPI != nil {
+		webAPI := *c.WebAPI
+		webAPI.EthereumAddressBook = nil
+		webAPI.StaticAddressBook = nil
+		webAPI.DNSAddressBook = nil
+		webAPI.WellKnownAddressBook = nil
+		cp.WebAPI = &webAPI
+	}
+	return &cp
+}
+
+func (c *Config) snapshot() (config.Snapshot, error) {
+	return config.NewSnapshot(map[string]any{"transport": c})
+}
+
+func (c *Config) Validate(clients []string) hcl.Diagnostics {
+	if c.WebAPI == nil || c.WebAPI.EthereumAddressBook == nil {
+		return nil
+	}
+	if !sliceutil.Contains(clients, c.WebAPI.EthereumAddressBook.EthereumClient) {
+		return hcl.Diagnostics{{
+			Severity: hcl.DiagError,
+			Summary:  "Validation error",
+			Detail:   fmt.Sprintf("Ethereum client %q is not configured", c.WebAPI.EthereumAddressBook.EthereumClient),
+			Subject:  c.WebAPI.EthereumAddressBook.Content.Attributes["ethereum_client"].Range.Ptr(),
+		}}
+	}
+	return nil
+}
+
 func (c *Config) LibP2PBootstrap(d BootstrapDependencies) (transport.Service, error) {
 	if c.LibP2P == nil {
 		return nil, &hcl.Diagnostic{
@@ -292,6 +685,82 @@ func (c *Config) configureWebAPI(d Dependencies) (transport.Service, error) {
 	}
 
 	// Configure address book:
+	addressBook, err := c.configureWebAPIAddressBook(d, httpClient)
+	if err != nil {
+		return nil, err
+	}
+	c.webAPIClient = httpClient
+	c.webAPIAddressBook = webapi.NewSwappableAddressBook(addressBook)
+
+	// Configure signer:
+	key := d.Keys[c.WebAPI.EthereumKey]
+	if c.WebAPI.EthereumKey != "" && key == nil {
+		return nil, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Validation error",
+			Detail:   fmt.Sprintf("Ethereum key %q is not configured", c.WebAPI.EthereumKey),
+			Subject:  c.WebAPI.Content.Attributes["ethereum_key"].Range.Ptr(),
+		}
+	}
+
+	// Configure transport:
+	webapiTransport, err := webapi.New(webapi.Config{
+		ListenAddr:      c.WebAPI.ListenAddr,
+		AddressBook:     c.webAPIAddressBook,
+		Topics:          d.Messages,
+		AuthorAllowlist: c.WebAPI.Feeds,
+		FlushTicker:     timeutil.NewTicker(time.Minute),
+		Signer:          key,
+		Client:          httpClient,
+		Streaming:       c.WebAPI.Streaming,
+		Logger:          d.Logger,
+		AppName:         d.AppName,
+		AppVersion:      d.AppVersion,
+	})
+	if err != nil {
+		return nil, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Runtime error",
+			Detail:   fmt.Sprintf("Failed to create the WebAPI transport: %v", err),
+			Subject:  &c.WebAPI.Range,
+		}
+	}
+	return recoverer.New(webapiTransport, d.Logger), nil
+}
+
+// versionedSchemas returns the message schemas of versioned topics. The
+// schemas are created for topics listed in the versioned block and merged
+// with the schemas from the dependencies.
+func (c *Config) versionedSchemas(d Dependencies) (map[string]versioned.Schema, error) {
+	if c.Versioned == nil {
+		return d.Schemas, nil
+	}
+	schemas := make(map[string]versioned.Schema, len(c.Versioned.Topics)+len(d.Schemas))
+	for _, topic := range c.Versioned.Topics {
+		if _, ok := messages.AllMessagesMap[topic]; !ok {
+			return nil, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Validation error",
+				Detail:   fmt.Sprintf("Invalid versioned topic: %s", topic),
+				Subject:  c.Versioned.Content.Attributes["topics"].Range.Ptr(),
+			}
+		}
+		typ, ok := d.Messages[topic]
+		if !ok {
+			continue
+		}
+		schemas[topic] = versioned.Schema{Versions: map[uint32]transport.Message{1: typ}}
+	}
+	for topic, schema := range d.Schemas {
+		schemas[topic] = schema
+	}
+	return schemas, nil
+}
+
+// configureWebAPIAddressBook returns the address book providing WebAPI
+// message consumers.
+func (c *Config) configureWebAPIAddressBook(d Dependencies, httpClient *http.Client) (webapi.AddressBook, error) {
+	l := d.Logger.WithField("tag", "CONFIG_"+webapi.LoggerTag)
 	var addressBooks []webapi.AddressBook
 	if c.WebAPI.EthereumAddressBook != nil {
 		l.WithField("address", c.WebAPI.EthereumAddressBook.ContractAddr).
@@ -318,6 +787,40 @@ func (c *Config) configureWebAPI(d Dependencies) (transport.Service, error) {
 			webapi.NewStaticAddressBook(c.WebAPI.StaticAddressBook.Addresses),
 		)
 	}
+	if c.WebAPI.DNSAddressBook != nil {
+		cacheTTL := time.Hour
+		if c.WebAPI.DNSAddressBook.CacheTTL > 0 {
+			cacheTTL = time.Duration(c.WebAPI.DNSAddressBook.CacheTTL) * time.Second
+		}
+		for _, domain := range c.WebAPI.DNSAddressBook.Domains {
+			l.WithField("domain", domain).
+				Info("DNS address book")
+
+			addressBooks = append(addressBooks, webapi.NewDNSAddressBook(
+				net.DefaultResolver,
+				domain,
+				c.WebAPI.DNSAddressBook.Signer,
+				cacheTTL,
+			))
+		}
+	}
+	if c.WebAPI.WellKnownAddressBook != nil {
+		cacheTTL := time.Hour
+		if c.WebAPI.WellKnownAddressBook.CacheTTL > 0 {
+			cacheTTL = time.Duration(c.WebAPI.WellKnownAddressBook.CacheTTL) * time.Second
+		}
+		for _, u := range c.WebAPI.WellKnownAddressBook.URLs {
+			l.WithField("url", u).
+				Info("Well-known address book")
+
+			addressBooks = append(addressBooks, webapi.NewWellKnownAddressBook(
+				httpClient,
+				u,
+				c.WebAPI.WellKnownAddressBook.Signer,
+				cacheTTL,
+			))
+		}
+	}
 
 	var addressBook webapi.AddressBook
 	switch {
@@ -339,39 +842,281 @@ func (c *Config) configureWebAPI(d Dependencies) (transport.Service, error) {
 			Info("Consumer")
 	}
 
+	return addressBook, nil
+}
+
+func (c *Config) configurePrivate(d Dependencies, t transport.Service, topics map[string]transport.Message) (transport.Service, error) {
+	key := d.Keys[c.Private.EthereumKey]
+	if c.Private.EthereumKey != "" && key == nil {
+		return nil, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Validation error",
+			Detail:   fmt.Sprintf("Ethereum key %q is not configured", c.Private.EthereumKey),
+			Subject:  c.Private.Content.Attributes["ethereum_key"].Range.Ptr(),
+		}
+	}
+	p, err := private.New(private.Config{
+		Transport:        t,
+		Topics:           topics,
+		Members:          c.Private.Members,
+		Signer:           key,
+		RotationInterval: time.Duration(c.Private.KeyRotationInterval) * time.Second,
+		Logger:           d.Logger,
+	})
+	if err != nil {
+		return nil, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Runtime error",
+			Detail:   fmt.Sprintf("Cannot create private transport: %v", err),
+			Subject:  c.Private.Range.Ptr(),
+		}
+	}
+	if !p.IsMember() {
+		d.Logger.
+			WithField("tag", "CONFIG_"+private.LoggerTag).
+			WithAdvice("Configure the ethereum_key of one of the members to read private topics").
+			Warn("Node is not a member of the private group, encrypted messages are only relayed")
+	}
+	return p, nil
+}
+
+func (c *Config) configureDedup(d Dependencies, t transport.Service) (transport.Service, error) {
+	window := defaultDedupWindow
+	if c.Dedup.Window > 0 {
+		window = time.Duration(c.Dedup.Window) * time.Second
+	}
+	dd, err := dedup.New(dedup.Config{
+		Transport: t,
+		Window:    window,
+		Monotonic: c.Dedup.Monotonic,
+		Logger:    d.Logger,
+	})
+	if err != nil {
+		return nil, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Runtime error",
+			Detail:   fmt.Sprintf("Cannot create dedup transport: %v", err),
+			Subject:  &c.Dedup.Range,
+		}
+	}
+	return dd, nil
+}
+
+func (c *Config) configureMonitor(d Dependencies, t transport.Service) (transport.Service, error) {
+	var srv httpserver.Service
+	if c.Metrics != nil && c.Metrics.ListenAddr != "" {
+		handler, ok := d.Metrics.(http.Handler)
+		if !ok {
+			return nil, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Validation error",
+				Detail:   "The metrics registry cannot be exposed over HTTP.",
+				Subject:  c.Metrics.Content.Attributes["listen_addr"].Range.Ptr(),
+			}
+		}
+		path := c.Metrics.Path
+		if path == "" {
+			path = defaultMetricsPath
+		}
+		httpSrv := httpserver.New(&http.Server{
+			Addr:              c.Metrics.ListenAddr,
+			Handler:           http.NotFoundHandler(),
+			ReadTimeout:       defaultMetricsTimeout,
+			ReadHeaderTimeout: defaultMetricsTimeout,
+			WriteTimeout:      defaultMetricsTimeout,
+			IdleTimeout:       defaultMetricsTimeout,
+		})
+		httpSrv.Use(&middleware.Metrics{Path: path, Handler: handler})
+		srv = httpSrv
+	}
+	m, err := monitor.New(monitor.Config{
+		Transport: t,
+		Registry:  d.Metrics,
+		Server:    srv,
+		Logger:    d.Logger,
+	})
+	if err != nil {
+		return nil, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Runtime error",
+			Detail:   fmt.Sprintf("Cannot create transport monitor: %v", err),
+			Subject:  &c.Range,
+		}
+	}
+	return m, nil
+}
+
+func (c *Config) configureMQ(d Dependencies) (transport.Service, error) {
+	l := d.Logger.WithField("tag", "CONFIG_"+mq.LoggerTag)
+
+	// Configure dialer:
+	var dialer mq.Dialer
+	if len(c.MQ.Socks5ProxyAddr) != 0 {
+		socks5, err := proxy.SOCKS5("tcp", c.MQ.Socks5ProxyAddr, nil, proxy.Direct)
+		if err != nil {
+			return nil, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Runtime error",
+				Detail:   fmt.Sprintf("Cannot create SOCKS5 proxy: %v", err),
+				Subject:  &c.MQ.Content.Attributes["socks5_proxy_addr"].Range,
+			}
+		}
+		dialer = socks5.(proxy.ContextDialer)
+		l.WithField("address", c.MQ.Socks5ProxyAddr).
+			Info("SOCKS5 proxy")
+	}
+
+	// Configure signer:
+	key := d.Keys[c.MQ.EthereumKey]
+	if c.MQ.EthereumKey != "" && key == nil {
+		return nil, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Validation error",
+			Detail:   fmt.Sprintf("Ethereum key %q is not configured", c.MQ.EthereumKey),
+			Subject:  c.MQ.Content.Attributes["ethereum_key"].Range.Ptr(),
+		}
+	}
+	if len(c.MQ.Consumers) > 0 && key == nil {
+		return nil, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Validation error",
+			Detail:   "Ethereum key must be configured to send messages to consumers",
+			Subject:  c.MQ.Content.Attributes["consumers"].Range.Ptr(),
+		}
+	}
+
+	for _, addr := range c.MQ.Consumers {
+		l.WithField("address", addr).
+			Info("Consumer")
+	}
+
+	// Configure transport:
+	mqTransport, err := mq.New(mq.Config{
+		ListenAddr:        c.MQ.ListenAddr,
+		Consumers:         c.MQ.Consumers,
+		JournalPath:       c.MQ.JournalPath,
+		MaxJournalRecords: int(c.MQ.MaxJournalRecords),
+		Topics:            d.Messages,
+		AuthorAllowlist:   c.MQ.Feeds,
+		Signer:            key,
+		Dialer:            dialer,
+		Logger:            d.Logger,
+		AppName:           d.AppName,
+		AppVersion:        d.AppVersion,
+	})
+	if err != nil {
+		return nil, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Runtime error",
+			Detail:   fmt.Sprintf("Failed to create the MQ transport: %v", err),
+			Subject:  &c.MQ.Range,
+		}
+	}
+	return recoverer.New(mqTransport, d.Logger), nil
+}
+
+func (c *Config) configureMQTT(d Dependencies, t transport.Service) (transport.Service, error) {
+	l := d.Logger.WithField("tag", "CONFIG_"+mqtt.LoggerTag)
+
+	// Configure topics:
+	format := mqtt.FormatProtobuf
+	if c.MQTT.Format != "" {
+		var err error
+		format, err = mqtt.ParseFormat(c.MQTT.Format)
+		if err != nil {
+			return nil, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Validation error",
+				Detail:   fmt.Sprintf("Invalid format: %v", err),
+				Subject:  c.MQTT.Content.Attributes["format"].Range.Ptr(),
+			}
+		}
+	}
+	topics := make(map[string]mqtt.Topic)
+	for _, topic := range c.MQTT.Topics {
+		if _, ok := topics[topic.Name]; ok {
+			return nil, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Validation error",
+				Detail:   fmt.Sprintf("Topic %q is already configured", topic.Name),
+				Subject:  topic.Range.Ptr(),
+			}
+		}
+		if topic.Subscribe != "" && len(c.MQTT.Feeds) == 0 {
+			return nil, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Validation error",
+				Detail:   "Feeds must be configured to subscribe to MQTT topics",
+				Subject:  topic.Content.Attributes["subscribe"].Range.Ptr(),
+			}
+		}
+		topicFormat := format
+		if topic.Format != "" {
+			var err error
+			topicFormat, err = mqtt.ParseFormat(topic.Format)
+			if err != nil {
+				return nil, &hcl.Diagnostic{
+					Severity: hcl.DiagError,
+					Summary:  "Validation error",
+					Detail:   fmt.Sprintf("Invalid format: %v", err),
+					Subject:  topic.Content.Attributes["format"].Range.Ptr(),
+				}
+			}
+		}
+		topics[topic.Name] = mqtt.Topic{
+			Publish:   topic.Publish,
+			Subscribe: topic.Subscribe,
+			Format:    topicFormat,
+		}
+		l.WithFields(log.Fields{
+			"topic":     topic.Name,
+			"publish":   topic.Publish,
+			"subscribe": topic.Subscribe,
+			"format":    topicFormat,
+		}).Info("Bridged topic")
+	}
+
 	// Configure signer:
-	key := d.Keys[c.WebAPI.EthereumKey]
-	if c.WebAPI.EthereumKey != "" && key == nil {
+	key := d.Keys[c.MQTT.EthereumKey]
+	if c.MQTT.EthereumKey != "" && key == nil {
 		return nil, &hcl.Diagnostic{
 			Severity: hcl.DiagError,
 			Summary:  "Validation error",
-			Detail:   fmt.Sprintf("Ethereum key %q is not configured", c.WebAPI.EthereumKey),
-			Subject:  c.WebAPI.Content.Attributes["ethereum_key"].Range.Ptr(),
+			Detail:   fmt.Sprintf("Ethereum key %q is not configured", c.MQTT.EthereumKey),
+			Subject:  c.MQTT.Content.Attributes["ethereum_key"].Range.Ptr(),
 		}
 	}
 
+	// Configure dialer:
+	var dialer mqtt.Dialer
+	if c.MQTT.TLS {
+		dialer = &tls.Dialer{Config: &tls.Config{MinVersion: tls.VersionTLS12}}
+	}
+
 	// Configure transport:
-	webapiTransport, err := webapi.New(webapi.Config{
-		ListenAddr:      c.WebAPI.ListenAddr,
-		AddressBook:     addressBook,
-		Topics:          d.Messages,
-		AuthorAllowlist: c.WebAPI.Feeds,
-		FlushTicker:     timeutil.NewTicker(time.Minute),
+	bridge, err := mqtt.New(mqtt.Config{
+		Transport:       t,
+		BrokerAddr:      c.MQTT.BrokerAddr,
+		ClientID:        c.MQTT.ClientID,
+		Username:        c.MQTT.Username,
+		Password:        c.MQTT.Password,
+		Topics:          topics,
+		Messages:        d.Messages,
+		AuthorAllowlist: c.MQTT.Feeds,
 		Signer:          key,
-		Client:          httpClient,
+		QoS:             c.MQTT.QoS,
+		Dialer:          dialer,
 		Logger:          d.Logger,
-		AppName:         d.AppName,
-		AppVersion:      d.AppVersion,
 	})
 	if err != nil {
 		return nil, &hcl.Diagnostic{
 			Severity: hcl.DiagError,
 			Summary:  "Runtime error",
-			Detail:   fmt.Sprintf("Failed to create the WebAPI transport: %v", err),
-			Subject:  &c.WebAPI.Range,
+			Detail:   fmt.Sprintf("Failed to create the MQTT bridge: %v", err),
+			Subject:  &c.MQTT.Range,
 		}
 	}
-	return recoverer.New(webapiTransport, d.Logger), nil
+	return bridge, nil
 }
 
 func (c *Config) configureLibP2P(d Dependencies) (transport.Service, error) {
@@ -457,6 +1202,8 @@ func (c *Config) configureLibP2P(d Dependencies) (transport.Service, error) {
 		AuthorAllowlist:  c.LibP2P.Feeds,
 		Discovery:        !c.LibP2P.DisableDiscovery,
 		Signer:           key,
+		Reputation:       d.Reputation,
+		Metrics:          d.Metrics,
 		Logger:           d.Logger,
 		AppName:          d.AppName,
 		AppVersion:       d.AppVersion,
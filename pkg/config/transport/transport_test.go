@@ -21,6 +21,7 @@ import (
 
 	"github.com/defiweb/go-eth/types"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/hashicorp/hcl/v2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -28,6 +29,9 @@ import (
 	"github.com/orcfax/oracle-suite/pkg/config/ethereum"
 	"github.com/orcfax/oracle-suite/pkg/ethereum/mocks"
 	"github.com/orcfax/oracle-suite/pkg/log/null"
+	"github.com/orcfax/oracle-suite/pkg/transport"
+	"github.com/orcfax/oracle-suite/pkg/transport/messages"
+	"github.com/orcfax/oracle-suite/pkg/transport/versioned"
 )
 
 func TestConfig(t *testing.T) {
@@ -42,6 +46,7 @@ func TestConfig(t *testing.T) {
 			test: func(t *testing.T, cfg *Config) {
 				assert.NotNil(t, cfg.LibP2P)
 				assert.NotNil(t, cfg.WebAPI)
+				assert.NotNil(t, cfg.MQ)
 
 				// LibP2P
 				assert.Equal(t, "0x1234567890123456789012345678901234567890", cfg.LibP2P.Feeds[0].String())
@@ -60,8 +65,11 @@ func TestConfig(t *testing.T) {
 				assert.Equal(t, "localhost:8080", cfg.WebAPI.ListenAddr)
 				assert.Equal(t, "localhost:9050", cfg.WebAPI.Socks5ProxyAddr)
 				assert.Equal(t, "key", cfg.WebAPI.EthereumKey)
+				assert.True(t, cfg.WebAPI.Streaming)
 				assert.NotNil(t, cfg.WebAPI.EthereumAddressBook)
 				assert.NotNil(t, cfg.WebAPI.StaticAddressBook)
+				assert.NotNil(t, cfg.WebAPI.DNSAddressBook)
+				assert.NotNil(t, cfg.WebAPI.WellKnownAddressBook)
 
 				// EthereumAddressBook
 				assert.Equal(t, "0x5678901234567890123456789012345678901234", cfg.WebAPI.EthereumAddressBook.ContractAddr.String())
@@ -69,6 +77,63 @@ func TestConfig(t *testing.T) {
 
 				// StaticAddressBook
 				assert.Equal(t, []string{"https://example.com/api/v1/endpoint"}, cfg.WebAPI.StaticAddressBook.Addresses)
+
+				// DNSAddressBook
+				assert.Equal(t, []string{"consumers.example.com"}, cfg.WebAPI.DNSAddressBook.Domains)
+				assert.Equal(t, "0x6789012345678901234567890123456789012345", cfg.WebAPI.DNSAddressBook.Signer.String())
+				assert.Equal(t, uint32(600), cfg.WebAPI.DNSAddressBook.CacheTTL)
+
+				// WellKnownAddressBook
+				assert.Equal(t, []string{"https://example.com"}, cfg.WebAPI.WellKnownAddressBook.URLs)
+				assert.Equal(t, "0x7890123456789012345678901234567890123456", cfg.WebAPI.WellKnownAddressBook.Signer.String())
+				assert.Equal(t, uint32(300), cfg.WebAPI.WellKnownAddressBook.CacheTTL)
+
+				// MQ
+				assert.Equal(t, "0x3456789012345678901234567890123456789012", cfg.MQ.Feeds[0].String())
+				assert.Equal(t, "localhost:8100", cfg.MQ.ListenAddr)
+				assert.Equal(t, []string{"localhost:8200"}, cfg.MQ.Consumers)
+				assert.Equal(t, "./journal", cfg.MQ.JournalPath)
+				assert.Equal(t, uint32(1000), cfg.MQ.MaxJournalRecords)
+				assert.Equal(t, "localhost:9050", cfg.MQ.Socks5ProxyAddr)
+				assert.Equal(t, "key", cfg.MQ.EthereumKey)
+
+				// MQTT
+				assert.Equal(t, "localhost:1883", cfg.MQTT.BrokerAddr)
+				assert.Equal(t, "spire", cfg.MQTT.ClientID)
+				assert.Equal(t, "user", cfg.MQTT.Username)
+				assert.Equal(t, "pass", cfg.MQTT.Password)
+				assert.True(t, cfg.MQTT.TLS)
+				assert.Equal(t, uint8(1), cfg.MQTT.QoS)
+				assert.Equal(t, "json", cfg.MQTT.Format)
+				assert.Equal(t, []types.Address{types.MustAddressFromHex("0x2222222222222222222222222222222222222222")}, cfg.MQTT.Feeds)
+				assert.Equal(t, "key", cfg.MQTT.EthereumKey)
+				require.Len(t, cfg.MQTT.Topics, 2)
+				assert.Equal(t, "data_point/v1", cfg.MQTT.Topics[0].Name)
+				assert.Equal(t, "oracle/data_point/v1", cfg.MQTT.Topics[0].Publish)
+				assert.Equal(t, "oracle/in/data_point/v1", cfg.MQTT.Topics[0].Subscribe)
+				assert.Equal(t, "protobuf", cfg.MQTT.Topics[0].Format)
+				assert.Equal(t, "greet/v1", cfg.MQTT.Topics[1].Name)
+				assert.Equal(t, "oracle/greet/v1", cfg.MQTT.Topics[1].Publish)
+
+				// Private
+				assert.Equal(t, []types.Address{
+					types.MustAddressFromHex("0x1234567890123456789012345678901234567890"),
+					types.MustAddressFromHex("0x2345678901234567890123456789012345678901"),
+				}, cfg.Private.Members)
+				assert.Equal(t, []string{"musig_partial_signature/v1.1"}, cfg.Private.Topics)
+				assert.Equal(t, "key", cfg.Private.EthereumKey)
+				assert.Equal(t, uint32(600), cfg.Private.KeyRotationInterval)
+
+				// Metrics
+				assert.Equal(t, "localhost:9100", cfg.Metrics.ListenAddr)
+				assert.Equal(t, "/metrics", cfg.Metrics.Path)
+
+				// Dedup
+				assert.Equal(t, uint32(300), cfg.Dedup.Window)
+				assert.True(t, cfg.Dedup.Monotonic)
+
+				// Versioned
+				assert.Equal(t, []string{"data_point/v1"}, cfg.Versioned.Topics)
 			},
 		},
 		{
@@ -95,7 +160,7 @@ func TestConfig(t *testing.T) {
 				transport, err := cfg.Transport(Dependencies{
 					Keys:     keyRegistry,
 					Clients:  clientRegistry,
-					Messages: nil,
+					Messages: messages.AllMessagesMap,
 					Logger:   null.New(),
 				})
 				require.NoError(t, err)
@@ -112,3 +177,62 @@ func TestConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestConfig_ReloadAddressBook(t *testing.T) {
+	load := func(path string) *Config {
+		var cfg Config
+		require.NoError(t, config.LoadFiles(&cfg, []string{"./testdata/" + path}))
+		return &cfg
+	}
+	key := &mocks.Key{}
+	key.On("Address").Return(types.AddressFromHex("0x1234567890123456789012345678901234567890"))
+	deps := Dependencies{
+		Keys:     ethereum.KeyRegistry{"key": key},
+		Messages: messages.AllMessagesMap,
+		Logger:   null.New(),
+	}
+
+	running := load("reload.hcl")
+	_, err := running.Transport(deps)
+	require.NoError(t, err)
+
+	// Address book change is applied to the running transport.
+	require.NoError(t, load("reload-address-book.hcl").ReloadAddressBook(running, deps))
+	consumers, err := running.webAPIAddressBook.Consumers(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"consumer2.example"}, consumers)
+
+	// Other changes require a restart.
+	err = load("reload-restart.hcl").ReloadAddressBook(running, deps)
+	require.ErrorIs(t, err, ErrRestartRequired)
+	consumers, err = running.webAPIAddressBook.Consumers(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"consumer2.example"}, consumers)
+}
+
+func TestConfig_versionedSchemas(t *testing.T) {
+	dataPoint := messages.AllMessagesMap[messages.DataPointV1MessageName]
+	greet := messages.AllMessagesMap[messages.GreetV1MessageName]
+	cfg := &Config{Versioned: &versionedConfig{
+		Topics: []string{messages.DataPointV1MessageName, messages.PriceV1MessageName},
+	}}
+
+	// Topics not used by the application are ignored, schemas from
+	// dependencies are merged.
+	schemas, err := cfg.versionedSchemas(Dependencies{
+		Messages: messages.MessageMap{messages.DataPointV1MessageName: dataPoint},
+		Schemas: map[string]versioned.Schema{
+			messages.GreetV1MessageName: {Versions: map[uint32]transport.Message{1: greet}},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, schemas, 2)
+	assert.Equal(t, dataPoint, schemas[messages.DataPointV1MessageName].Versions[1])
+	assert.Equal(t, greet, schemas[messages.GreetV1MessageName].Versions[1])
+
+	// Unknown topics are rejected.
+	cfg.Versioned.Topics = []string{"unknown"}
+	cfg.Versioned.Content.Attributes = map[string]*hcl.Attribute{"topics": {}}
+	_, err = cfg.versionedSchemas(Dependencies{Messages: messages.AllMessagesMap})
+	require.Error(t, err)
+}
@@ -39,6 +39,9 @@ var (
 	abiWatRegistry  *goethABI.Contract
 	abiFeedRegistry *goethABI.Contract
 	abiChainlog     *goethABI.Contract
+
+	// scribePokeMethods contains all variants of the Scribe's poke method.
+	scribePokeMethods []*goethABI.Method
 )
 
 func init() {
@@ -76,9 +79,13 @@ func init() {
 		`error SignersNotOrdered()`,
 		`error SchnorrSignatureInvalid()`,
 
+		`event Poked(address indexed caller, uint128 val, uint32 age)`,
+
 		`wat()(bytes32_string wat)`,
 		`bar()(uint8 bar)`,
 		`feeds()(address[] feeds)`,
+		`constructPokeMessage(PokeData pokeData)(bytes32 message)`,
+		`isAcceptableSchnorrSignatureNow(bytes32 message, SchnorrData schnorrData)(bool ok)`,
 		`poke(PokeData pokeData, SchnorrData schnorrData)`,
 		`poke_optimized_7136211(PokeData pokeData, SchnorrData schnorrData)`,
 	)
@@ -94,10 +101,18 @@ func init() {
 		`error NoOpPokeToChallenge()`,
 		`error SchnorrDataMismatch(uint160 gotHash, uint160 wantHash)`,
 
+		`event Poked(address indexed caller, uint128 val, uint32 age)`,
+		`event OpPoked(address indexed caller, address indexed opFeed, SchnorrData schnorrData, PokeData pokeData)`,
+		`event OpPokeChallengedSuccessfully(address indexed caller, bytes schnorrErr)`,
+		`event OpPokeChallengedUnsuccessfully(address indexed caller)`,
+
 		`wat()(bytes32_string wat)`,
 		`bar()(uint8 bar)`,
 		`opChallengePeriod()(uint16 opChallengePeriod)`,
 		`feeds()(address[] feeds)`,
+		`constructPokeMessage(PokeData pokeData)(bytes32 message)`,
+		`isAcceptableSchnorrSignatureNow(bytes32 message, SchnorrData schnorrData)(bool ok)`,
+		`opChallenge(SchnorrData schnorrData)(bool ok)`,
 		`opPoke(PokeData pokeData, SchnorrData schnorrData, ECDSAData ecdsaData)`,
 		`opPoke_optimized_397084999(PokeData pokeData, SchnorrData schnorrData, ECDSAData ecdsaData)`,
 	)
@@ -140,6 +155,11 @@ func init() {
 		`tryGet(bytes32_string key)(bool ok, address address)`,
 	)
 
+	scribePokeMethods = []*goethABI.Method{
+		abiScribe.Methods["poke"],
+		abiScribe.Methods["poke_optimized_7136211"],
+	}
+
 	abiScribe.Methods["poke"] = abiScribe.Methods["poke_optimized_7136211"]
 	abiOpScribe.Methods["opPoke"] = abiOpScribe.Methods["opPoke_optimized_397084999"]
 	abiFeedRegistry.Methods["feeds(address)"] = abiFeedRegistry.Methods["feeds2"]
@@ -176,6 +196,21 @@ type ECDSADataStruct struct {
 	S *big.Int `abi:"s"`
 }
 
+func fromPokeDataStruct(p PokeDataStruct) PokeData {
+	return PokeData{
+		Val: bn.DecFixedPointFromRawBigInt(p.Val, ScribePricePrecision),
+		Age: time.Unix(int64(p.Age), 0),
+	}
+}
+
+func fromSchnorrDataStruct(s SchnorrDataStruct) SchnorrData {
+	return SchnorrData{
+		Signature:  s.Signature,
+		Commitment: s.Commitment,
+		FeedIDs:    FeedIDsFromIDs(s.FeedIDs),
+	}
+}
+
 func toPokeDataStruct(p PokeData) PokeDataStruct {
 	return PokeDataStruct{
 		Val: p.Val.SetPrec(ScribePricePrecision).RawBigInt(),
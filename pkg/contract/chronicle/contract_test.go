@@ -33,6 +33,8 @@ type mockRPC struct {
 	getStorageAtFn    func(ctx context.Context, account types.Address, key types.Hash, block types.BlockNumber) (*types.Hash, error)
 	callFn            func(ctx context.Context, call types.Call, blockNumber types.BlockNumber) ([]byte, *types.Call, error)
 	sendTransactionFn func(ctx context.Context, tx types.Transaction) (*types.Hash, *types.Transaction, error)
+	getLogsFn         func(ctx context.Context, query types.FilterLogsQuery) ([]types.Log, error)
+	getTxByHashFn     func(ctx context.Context, hash types.Hash) (*types.OnChainTransaction, error)
 }
 
 func newMockRPC(t *testing.T) *mockRPC {
@@ -58,6 +60,14 @@ func (m *mockRPC) reset(t *testing.T) {
 		assert.FailNow(t, "unexpected call to SendTransaction")
 		return nil, nil, nil
 	}
+	m.getLogsFn = func(ctx context.Context, query types.FilterLogsQuery) ([]types.Log, error) {
+		assert.FailNow(t, "unexpected call to GetLogs")
+		return nil, nil
+	}
+	m.getTxByHashFn = func(ctx context.Context, hash types.Hash) (*types.OnChainTransaction, error) {
+		assert.FailNow(t, "unexpected call to GetTransactionByHash")
+		return nil, nil
+	}
 }
 
 func (m *mockRPC) BlockNumber(ctx context.Context) (*big.Int, error) {
@@ -76,6 +86,14 @@ func (m *mockRPC) SendTransaction(ctx context.Context, tx types.Transaction) (*t
 	return m.sendTransactionFn(ctx, tx)
 }
 
+func (m *mockRPC) GetLogs(ctx context.Context, query types.FilterLogsQuery) ([]types.Log, error) {
+	return m.getLogsFn(ctx, query)
+}
+
+func (m *mockRPC) GetTransactionByHash(ctx context.Context, hash types.Hash) (*types.OnChainTransaction, error) {
+	return m.getTxByHashFn(ctx, hash)
+}
+
 func TestBytesToString(t *testing.T) {
 	tests := []struct {
 		name     string
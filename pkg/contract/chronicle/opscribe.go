@@ -29,6 +29,27 @@ import (
 	"github.com/orcfax/oracle-suite/pkg/util/errutil"
 )
 
+// OpPokeEvent represents the OpPoked event emitted by the OpScribe contract.
+type OpPokeEvent struct {
+	// BlockNumber is the number of the block in which the event was emitted.
+	BlockNumber uint64
+
+	// TxHash is the hash of the opPoke transaction.
+	TxHash types.Hash
+
+	// Caller is the address that sent the opPoke transaction.
+	Caller types.Address
+
+	// OpFeed is the address of the feed that signed the opPoke.
+	OpFeed types.Address
+
+	// PokeData is the optimistically poked data.
+	PokeData PokeData
+
+	// SchnorrData is the Schnorr signature of the poke data.
+	SchnorrData SchnorrData
+}
+
 // OpScribe allows interacting with the OpScribe contract.
 type OpScribe struct {
 	Scribe
@@ -183,6 +204,62 @@ func (s *OpScribe) OpPoke(pokeData PokeData, schnorrData SchnorrData, ecdsaData
 	)
 }
 
+// OpChallenge challenges the latest optimistic poke. The schnorrData must
+// be the same as the one used in the opPoke call. The challenge succeeds
+// only if the Schnorr signature is invalid.
+func (s *OpScribe) OpChallenge(schnorrData SchnorrData) contract.SelfTransactableCaller {
+	return contract.NewTransactableCall(
+		contract.CallOpts{
+			Client:  s.client,
+			Address: s.address,
+			Encoder: contract.NewCallEncoder(
+				abiOpScribe.Methods["opChallenge"],
+				toSchnorrDataStruct(schnorrData),
+			),
+			ErrorDecoder: contract.NewContractErrorDecoder(abiOpScribe),
+		},
+	)
+}
+
+// OpPokes returns OpPoked events emitted by the contract between the given
+// blocks, inclusive.
+func (s *OpScribe) OpPokes(ctx context.Context, fromBlock, toBlock types.BlockNumber) ([]OpPokeEvent, error) {
+	event := abiOpScribe.Events["OpPoked"]
+	query := types.NewFilterLogsQuery().
+		SetAddresses(s.address).
+		SetFromBlock(&fromBlock).
+		SetToBlock(&toBlock).
+		SetTopics([]types.Hash{event.Topic0()})
+	logs, err := s.client.GetLogs(ctx, *query)
+	if err != nil {
+		return nil, fmt.Errorf("opScribe: opPokes query failed: %w", err)
+	}
+	events := make([]OpPokeEvent, 0, len(logs))
+	for _, l := range logs {
+		if l.Removed {
+			continue
+		}
+		var (
+			ev          OpPokeEvent
+			schnorrData SchnorrDataStruct
+			pokeData    PokeDataStruct
+		)
+		if err := event.DecodeValues(l.Topics, l.Data, &ev.Caller, &ev.OpFeed, &schnorrData, &pokeData); err != nil {
+			return nil, fmt.Errorf("opScribe: unable to decode OpPoked event: %w", err)
+		}
+		if l.BlockNumber != nil {
+			ev.BlockNumber = l.BlockNumber.Uint64()
+		}
+		if l.TransactionHash != nil {
+			ev.TxHash = *l.TransactionHash
+		}
+		ev.PokeData = fromPokeDataStruct(pokeData)
+		ev.SchnorrData = fromSchnorrDataStruct(schnorrData)
+		events = append(events, ev)
+	}
+	return events, nil
+}
+
 func (s *OpScribe) opChallengePeriod(ctx context.Context, block types.BlockNumber) (time.Duration, error) {
 	res, _, err := s.client.Call(
 		ctx,
@@ -202,3 +202,108 @@ func Test_ConstructOpPokeMessage(t *testing.T) {
 	message := ConstructScribeOpPokeMessage(wat, pokeData, schnorrData, FeedIDsFromIDs([]byte{1, 2, 3, 4}))
 	assert.Equal(t, "0xc0b793eee861973d6486620f324e9c53a08992d5931218d10302f1f174f411ed", toEIP191(message).String())
 }
+
+func TestOpScribe_OpChallenge(t *testing.T) {
+	ctx := context.Background()
+	mockClient := newMockRPC(t)
+	scribe := NewOpScribe(mockClient, types.MustAddressFromHex("0x1122344556677889900112233445566778899002"))
+
+	schnorrData := SchnorrData{
+		Signature:  new(big.Int).SetBytes(hexutil.MustHexToBytes("0x1234567890123456789012345678901234567890123456789012345678901234")),
+		Commitment: types.MustAddressFromHex("0x1234567890123456789012345678901234567890"),
+		FeedIDs:    FeedIDsFromIDs([]byte{1, 2, 3, 4}),
+	}
+
+	calldata := hexutil.MustHexToBytes(
+		"0x" +
+			"8928a1f8" +
+			"0000000000000000000000000000000000000000000000000000000000000020" +
+			"1234567890123456789012345678901234567890123456789012345678901234" +
+			"0000000000000000000000001234567890123456789012345678901234567890" +
+			"0000000000000000000000000000000000000000000000000000000000000060" +
+			"0000000000000000000000000000000000000000000000000000000000000004" +
+			"0102030400000000000000000000000000000000000000000000000000000000",
+	)
+
+	mockClient.callFn = func(ctx context.Context, call types.Call, blockNumber types.BlockNumber) ([]byte, *types.Call, error) {
+		assert.Equal(t, types.LatestBlockNumber, blockNumber)
+		assert.Equal(t, &scribe.address, call.To)
+		assert.Equal(t, calldata, call.Input)
+		return []byte{}, &types.Call{}, nil
+	}
+
+	mockClient.sendTransactionFn = func(ctx context.Context, tx types.Transaction) (*types.Hash, *types.Transaction, error) {
+		assert.Equal(t, types.Call{
+			To:    &scribe.address,
+			Input: calldata,
+		}, tx.Call)
+		return &types.Hash{}, &types.Transaction{}, nil
+	}
+
+	_, _, err := scribe.OpChallenge(schnorrData).SendTransaction(ctx)
+	require.NoError(t, err)
+}
+
+func TestOpScribe_OpPokes(t *testing.T) {
+	ctx := context.Background()
+	mockClient := newMockRPC(t)
+	scribe := NewOpScribe(mockClient, types.MustAddressFromHex("0x1122344556677889900112233445566778899002"))
+
+	caller := types.MustAddressFromHex("0x2233445566778899001122334455667788990011")
+	opFeed := types.MustAddressFromHex("0x3344556677889900112233445566778899001122")
+	txHash := types.MustHashFromHex("0x00112233445566778899aabbccddeeff00112233445566778899aabbccddeeff", types.PadNone)
+	data, err := abi.EncodeValues(
+		abi.MustParseType("(SchnorrData, PokeData)"),
+		SchnorrDataStruct{
+			Signature:  big.NewInt(42),
+			Commitment: types.MustAddressFromHex("0x1234567890123456789012345678901234567890"),
+			FeedIDs:    []byte{1, 2},
+		},
+		PokeDataStruct{
+			Val: big.NewInt(1e18),
+			Age: 1694119620,
+		},
+	)
+	require.NoError(t, err)
+
+	mockClient.getLogsFn = func(ctx context.Context, query types.FilterLogsQuery) ([]types.Log, error) {
+		assert.Equal(t, []types.Address{scribe.address}, query.Address)
+		assert.Equal(t, types.BlockNumberFromUint64(10), *query.FromBlock)
+		assert.Equal(t, types.BlockNumberFromUint64(20), *query.ToBlock)
+		assert.Equal(t, [][]types.Hash{{abiOpScribe.Events["OpPoked"].Topic0()}}, query.Topics)
+		return []types.Log{
+			{
+				Topics: []types.Hash{
+					abiOpScribe.Events["OpPoked"].Topic0(),
+					types.MustHashFromBytes(caller.Bytes(), types.PadLeft),
+					types.MustHashFromBytes(opFeed.Bytes(), types.PadLeft),
+				},
+				Data:            data,
+				BlockNumber:     big.NewInt(15),
+				TransactionHash: &txHash,
+			},
+			{
+				Topics: []types.Hash{
+					abiOpScribe.Events["OpPoked"].Topic0(),
+					types.MustHashFromBytes(caller.Bytes(), types.PadLeft),
+					types.MustHashFromBytes(opFeed.Bytes(), types.PadLeft),
+				},
+				Data:        data,
+				BlockNumber: big.NewInt(16),
+				Removed:     true,
+			},
+		}, nil
+	}
+
+	events, err := scribe.OpPokes(ctx, types.BlockNumberFromUint64(10), types.BlockNumberFromUint64(20))
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, uint64(15), events[0].BlockNumber)
+	assert.Equal(t, txHash, events[0].TxHash)
+	assert.Equal(t, caller, events[0].Caller)
+	assert.Equal(t, opFeed, events[0].OpFeed)
+	assert.Equal(t, "1", events[0].PokeData.Val.String())
+	assert.Equal(t, int64(1694119620), events[0].PokeData.Age.Unix())
+	assert.Equal(t, big.NewInt(42), events[0].SchnorrData.Signature)
+	assert.Equal(t, []byte{1, 2}, events[0].SchnorrData.FeedIDs.FeedIDs())
+}
@@ -56,6 +56,35 @@ func NewRegistry(feedRegistry *FeedRegistry, watRegistry *WatRegistry) (*Registr
 	}, nil
 }
 
+// Chainlog keys under which the registry contracts are stored.
+const (
+	ChainlogFeedRegistryKey = "FeedRegistry"
+	ChainlogWatRegistryKey  = "WatRegistry"
+)
+
+// NewRegistryFromChainlog creates a new Registry instance using addresses of
+// the FeedRegistry and WatRegistry contracts stored in the Chainlog.
+func NewRegistryFromChainlog(ctx context.Context, chainlog *Chainlog) (*Registry, error) {
+	feedRegistry, err := chainlog.TryGet(ChainlogFeedRegistryKey).Call(ctx, types.LatestBlockNumber)
+	if err != nil {
+		return nil, fmt.Errorf("on-chain registry: %w", err)
+	}
+	if !feedRegistry.Ok {
+		return nil, fmt.Errorf("on-chain registry: %s not found in the chainlog", ChainlogFeedRegistryKey)
+	}
+	watRegistry, err := chainlog.TryGet(ChainlogWatRegistryKey).Call(ctx, types.LatestBlockNumber)
+	if err != nil {
+		return nil, fmt.Errorf("on-chain registry: %w", err)
+	}
+	if !watRegistry.Ok {
+		return nil, fmt.Errorf("on-chain registry: %s not found in the chainlog", ChainlogWatRegistryKey)
+	}
+	return NewRegistry(
+		NewFeedRegistry(chainlog.Client(), feedRegistry.Address),
+		NewWatRegistry(chainlog.Client(), watRegistry.Address),
+	)
+}
+
 // Deployments returns a list of all deployed contracts from the WatRegistry.
 func (r *Registry) Deployments(ctx context.Context) ([]Deployment, error) {
 	blockNumber, err := r.feedRegistry.Client().BlockNumber(ctx)
@@ -157,3 +157,56 @@ func TestRegistry_Deployments(t *testing.T) {
 	assert.Equal(t, uint64(3), deployments[2].ChainID)
 	assert.Equal(t, []types.Address{types.MustAddressFromHex("0x3456789012345678901234567890123456789012")}, deployments[2].Feeds)
 }
+
+func TestNewRegistryFromChainlog(t *testing.T) {
+	ctx := context.Background()
+	mockClient := newMockRPC(t)
+	chainlog := NewChainlog(mockClient, types.MustAddressFromHex("0x1122344556677889900112233445566778899002"))
+
+	callIdx := 0
+	mockClient.callFn = func(ctx context.Context, call types.Call, blockNumber types.BlockNumber) ([]byte, *types.Call, error) {
+		var data []byte
+		switch callIdx {
+		case 0: // tryGet FeedRegistry
+			assert.Equal(t, hexutil.MustHexToBytes("0xdc09a8a7"+"4665656452656769737472790000000000000000000000000000000000000000"), call.Input)
+			data = hexutil.MustHexToBytes(
+				"0x" +
+					"0000000000000000000000000000000000000000000000000000000000000001" +
+					"0000000000000000000000001234567890123456789012345678901234567890",
+			)
+		case 1: // tryGet WatRegistry
+			assert.Equal(t, hexutil.MustHexToBytes("0xdc09a8a7"+"5761745265676973747279000000000000000000000000000000000000000000"), call.Input)
+			data = hexutil.MustHexToBytes(
+				"0x" +
+					"0000000000000000000000000000000000000000000000000000000000000001" +
+					"0000000000000000000000003456789012345678901234567890123456789012",
+			)
+		default:
+			t.Fatalf("unexpected call: %d", callIdx)
+		}
+		callIdx++
+		return data, &types.Call{}, nil
+	}
+
+	registry, err := NewRegistryFromChainlog(ctx, chainlog)
+	require.NoError(t, err)
+	assert.Equal(t, types.MustAddressFromHex("0x1234567890123456789012345678901234567890"), registry.feedRegistry.Address())
+	assert.Equal(t, types.MustAddressFromHex("0x3456789012345678901234567890123456789012"), registry.watRegistry.Address())
+}
+
+func TestNewRegistryFromChainlog_NotFound(t *testing.T) {
+	ctx := context.Background()
+	mockClient := newMockRPC(t)
+	chainlog := NewChainlog(mockClient, types.MustAddressFromHex("0x1122344556677889900112233445566778899002"))
+
+	mockClient.callFn = func(ctx context.Context, call types.Call, blockNumber types.BlockNumber) ([]byte, *types.Call, error) {
+		return hexutil.MustHexToBytes(
+			"0x" +
+				"0000000000000000000000000000000000000000000000000000000000000000" +
+				"0000000000000000000000000000000000000000000000000000000000000000",
+		), &types.Call{}, nil
+	}
+
+	_, err := NewRegistryFromChainlog(ctx, chainlog)
+	require.Error(t, err)
+}
@@ -18,6 +18,7 @@ package chronicle
 import (
 	"context"
 	"encoding/binary"
+	"fmt"
 	"math/big"
 	"time"
 
@@ -26,6 +27,7 @@ import (
 	"github.com/defiweb/go-eth/types"
 
 	"github.com/orcfax/oracle-suite/pkg/contract"
+	"github.com/orcfax/oracle-suite/pkg/contract/multicall"
 	"github.com/orcfax/oracle-suite/pkg/util/bn"
 )
 
@@ -33,6 +35,27 @@ import (
 // as a number of decimal places after the decimal point.
 const ScribePricePrecision = 18
 
+// PokeEvent represents the Poked event emitted by the Scribe and OpScribe
+// contracts.
+type PokeEvent struct {
+	// BlockNumber is the number of the block in which the event was emitted.
+	BlockNumber uint64
+
+	// TxHash is the hash of the poke transaction.
+	TxHash types.Hash
+
+	// Caller is the address that sent the poke transaction.
+	Caller types.Address
+
+	// PokeData is the poked data.
+	PokeData PokeData
+
+	// SchnorrData is the Schnorr signature of the poke data, decoded from
+	// the input of the poke transaction. It is nil if the input could not be
+	// decoded, e.g. if the contract was poked by another contract.
+	SchnorrData *SchnorrData
+}
+
 // Scribe allows interacting with the Scribe contract.
 type Scribe struct {
 	client  rpc.RPC
@@ -104,6 +127,37 @@ func (s *Scribe) Feeds() contract.TypedSelfCaller[[]types.Address] {
 	)
 }
 
+// ConstructPokeMessage returns the message that must be signed by feeds
+// in order to update the contract with the given poke data.
+func (s *Scribe) ConstructPokeMessage(pokeData PokeData) contract.TypedSelfCaller[types.Hash] {
+	method := abiScribe.Methods["constructPokeMessage"]
+	return contract.NewTypedCall[types.Hash](
+		contract.CallOpts{
+			Client:       s.client,
+			Address:      s.address,
+			Encoder:      contract.NewCallEncoder(method, toPokeDataStruct(pokeData)),
+			Decoder:      contract.NewCallDecoder(method),
+			ErrorDecoder: contract.NewContractErrorDecoder(abiScribe),
+		},
+	)
+}
+
+// IsAcceptableSchnorrSignatureNow returns true if the given Schnorr data
+// is a valid signature of the message, created by a set of currently
+// lifted feeds that satisfies the bar.
+func (s *Scribe) IsAcceptableSchnorrSignatureNow(message types.Hash, schnorrData SchnorrData) contract.TypedSelfCaller[bool] {
+	method := abiScribe.Methods["isAcceptableSchnorrSignatureNow"]
+	return contract.NewTypedCall[bool](
+		contract.CallOpts{
+			Client:       s.client,
+			Address:      s.address,
+			Encoder:      contract.NewCallEncoder(method, message, toSchnorrDataStruct(schnorrData)),
+			Decoder:      contract.NewCallDecoder(method),
+			ErrorDecoder: contract.NewContractErrorDecoder(abiScribe),
+		},
+	)
+}
+
 // Poke updates the poke data in the contract.
 func (s *Scribe) Poke(pokeData PokeData, schnorrData SchnorrData) contract.SelfTransactableCaller {
 	return contract.NewTransactableCall(
@@ -120,6 +174,102 @@ func (s *Scribe) Poke(pokeData PokeData, schnorrData SchnorrData) contract.SelfT
 	)
 }
 
+// Pokes returns Poked events emitted by the contract between the given
+// blocks, inclusive.
+//
+// The Schnorr data of every poke is decoded from the input of the poke
+// transaction, which requires fetching every transaction. Both direct poke
+// calls and pokes aggregated using the Multicall contract are supported.
+func (s *Scribe) Pokes(ctx context.Context, fromBlock, toBlock types.BlockNumber) ([]PokeEvent, error) {
+	event := abiScribe.Events["Poked"]
+	query := types.NewFilterLogsQuery().
+		SetAddresses(s.address).
+		SetFromBlock(&fromBlock).
+		SetToBlock(&toBlock).
+		SetTopics([]types.Hash{event.Topic0()})
+	logs, err := s.client.GetLogs(ctx, *query)
+	if err != nil {
+		return nil, fmt.Errorf("scribe: pokes query failed: %w", err)
+	}
+	events := make([]PokeEvent, 0, len(logs))
+	for _, l := range logs {
+		if l.Removed {
+			continue
+		}
+		var (
+			ev  PokeEvent
+			val *big.Int
+			age uint32
+		)
+		if err := event.DecodeValues(l.Topics, l.Data, &ev.Caller, &val, &age); err != nil {
+			return nil, fmt.Errorf("scribe: unable to decode Poked event: %w", err)
+		}
+		if l.BlockNumber != nil {
+			ev.BlockNumber = l.BlockNumber.Uint64()
+		}
+		ev.PokeData = fromPokeDataStruct(PokeDataStruct{Val: val, Age: age})
+		if l.TransactionHash != nil {
+			ev.TxHash = *l.TransactionHash
+			tx, err := s.client.GetTransactionByHash(ctx, ev.TxHash)
+			if err != nil {
+				return nil, fmt.Errorf("scribe: unable to fetch poke transaction: %w", err)
+			}
+			ev.SchnorrData = s.decodePokeSchnorrData(tx.Input, ev.PokeData)
+		}
+		events = append(events, ev)
+	}
+	return events, nil
+}
+
+// decodePokeSchnorrData decodes the Schnorr data of the given poke from
+// the poke transaction input. It returns nil if the input does not contain
+// a matching poke call.
+func (s *Scribe) decodePokeSchnorrData(input []byte, pokeData PokeData) *SchnorrData {
+	if schnorrData := decodePokeCall(input, pokeData); schnorrData != nil {
+		return schnorrData
+	}
+	calls, err := multicall.DecodeAggregate3(input)
+	if err != nil {
+		return nil
+	}
+	for _, c := range calls {
+		if c.Target != s.address {
+			continue
+		}
+		if schnorrData := decodePokeCall(c.CallData, pokeData); schnorrData != nil {
+			return schnorrData
+		}
+	}
+	return nil
+}
+
+// decodePokeCall decodes the Schnorr data from the poke call data if the
+// call pokes the given data.
+func decodePokeCall(callData []byte, pokeData PokeData) *SchnorrData {
+	for _, method := range scribePokeMethods {
+		if !method.FourBytes().Match(callData) {
+			continue
+		}
+		var (
+			pokeDataArg    PokeDataStruct
+			schnorrDataArg SchnorrDataStruct
+		)
+		if err := method.DecodeArgs(callData, &pokeDataArg, &schnorrDataArg); err != nil {
+			return nil
+		}
+		if !pokeDataEqual(fromPokeDataStruct(pokeDataArg), pokeData) {
+			return nil
+		}
+		schnorrData := fromSchnorrDataStruct(schnorrDataArg)
+		return &schnorrData
+	}
+	return nil
+}
+
+func pokeDataEqual(a, b PokeData) bool {
+	return a.Age.Equal(b.Age) && a.Val.Cmp(b.Val) == 0
+}
+
 func (s *Scribe) readPokeData(ctx context.Context, storageSlot int, block types.BlockNumber) (PokeData, error) {
 	const (
 		ageOffset = 0
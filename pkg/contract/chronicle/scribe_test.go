@@ -26,6 +26,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/orcfax/oracle-suite/pkg/contract/multicall"
 	"github.com/orcfax/oracle-suite/pkg/util/bn"
 )
 
@@ -112,6 +113,67 @@ func TestScribe_Feeds(t *testing.T) {
 	assert.Equal(t, expectedFeeds, feeds)
 }
 
+func TestScribe_ConstructPokeMessage(t *testing.T) {
+	ctx := context.Background()
+	mockClient := newMockRPC(t)
+	scribe := NewScribe(mockClient, types.MustAddressFromHex("0x1122344556677889900112233445566778899002"))
+
+	pokeData := PokeData{
+		Val: bn.DecFixedPointFromRawBigInt(bn.Int("26064535000000000000000").BigInt(), ScribePricePrecision),
+		Age: time.Unix(1692913991, 0),
+	}
+
+	mockClient.callFn = func(ctx context.Context, call types.Call, blockNumber types.BlockNumber) ([]byte, *types.Call, error) {
+		assert.Equal(t, types.LatestBlockNumber, blockNumber)
+		assert.Equal(t, &scribe.address, call.To)
+		assert.Equal(t, hexutil.MustHexToBytes(
+			"0x"+
+				"acf40b6f"+
+				"000000000000000000000000000000000000000000000584f61606acd0158000"+
+				"0000000000000000000000000000000000000000000000000000000064e7d147",
+		), call.Input)
+		return hexutil.MustHexToBytes("0x00112233445566778899aabbccddeeff00112233445566778899aabbccddeeff"), &types.Call{}, nil
+	}
+
+	message, err := scribe.ConstructPokeMessage(pokeData).Call(ctx, types.LatestBlockNumber)
+	require.NoError(t, err)
+	assert.Equal(t, "0x00112233445566778899aabbccddeeff00112233445566778899aabbccddeeff", message.String())
+}
+
+func TestScribe_IsAcceptableSchnorrSignatureNow(t *testing.T) {
+	ctx := context.Background()
+	mockClient := newMockRPC(t)
+	scribe := NewScribe(mockClient, types.MustAddressFromHex("0x1122344556677889900112233445566778899002"))
+
+	message := types.MustHashFromHex("0x00112233445566778899aabbccddeeff00112233445566778899aabbccddeeff", types.PadNone)
+	schnorrData := SchnorrData{
+		Signature:  new(big.Int).SetBytes(hexutil.MustHexToBytes("0x1234567890123456789012345678901234567890123456789012345678901234")),
+		Commitment: types.MustAddressFromHex("0x1234567890123456789012345678901234567890"),
+		FeedIDs:    FeedIDsFromIDs([]byte{1, 2, 3, 4}),
+	}
+
+	mockClient.callFn = func(ctx context.Context, call types.Call, blockNumber types.BlockNumber) ([]byte, *types.Call, error) {
+		assert.Equal(t, types.LatestBlockNumber, blockNumber)
+		assert.Equal(t, &scribe.address, call.To)
+		assert.Equal(t, hexutil.MustHexToBytes(
+			"0x"+
+				"dac42ad8"+
+				"00112233445566778899aabbccddeeff00112233445566778899aabbccddeeff"+
+				"0000000000000000000000000000000000000000000000000000000000000040"+
+				"1234567890123456789012345678901234567890123456789012345678901234"+
+				"0000000000000000000000001234567890123456789012345678901234567890"+
+				"0000000000000000000000000000000000000000000000000000000000000060"+
+				"0000000000000000000000000000000000000000000000000000000000000004"+
+				"0102030400000000000000000000000000000000000000000000000000000000",
+		), call.Input)
+		return hexutil.MustHexToBytes("0x0000000000000000000000000000000000000000000000000000000000000001"), &types.Call{}, nil
+	}
+
+	ok, err := scribe.IsAcceptableSchnorrSignatureNow(message, schnorrData).Call(ctx, types.LatestBlockNumber)
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
 func TestScribe_Poke(t *testing.T) {
 	ctx := context.Background()
 	mockClient := newMockRPC(t)
@@ -161,6 +223,83 @@ func TestScribe_Poke(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestScribe_Pokes(t *testing.T) {
+	ctx := context.Background()
+	mockClient := newMockRPC(t)
+	scribe := NewScribe(mockClient, types.MustAddressFromHex("0x1122344556677889900112233445566778899002"))
+	other := types.MustAddressFromHex("0x3344556677889900112233445566778899001122")
+
+	caller := types.MustAddressFromHex("0x2233445566778899001122334455667788990011")
+	pokeData := PokeData{
+		Val: bn.DecFixedPoint(1, ScribePricePrecision),
+		Age: time.Unix(1694119620, 0),
+	}
+	schnorrData := SchnorrData{
+		Signature:  big.NewInt(42),
+		Commitment: types.MustAddressFromHex("0x1234567890123456789012345678901234567890"),
+		FeedIDs:    FeedIDsFromIDs([]byte{1, 2}),
+	}
+	pokeInput, err := scribe.Poke(pokeData, schnorrData).CallData()
+	require.NoError(t, err)
+	multicallInput, err := multicall.AggregateCallables(
+		mockClient,
+		NewScribe(mockClient, other).Poke(pokeData, schnorrData),
+		scribe.Poke(pokeData, schnorrData),
+	).CallData()
+	require.NoError(t, err)
+	eventData, err := abi.EncodeValues(abi.MustParseType("(uint128, uint32)"), big.NewInt(1e18), uint32(1694119620))
+	require.NoError(t, err)
+
+	txHashes := []types.Hash{
+		types.MustHashFromHex("0x0000000000000000000000000000000000000000000000000000000000000001", types.PadNone),
+		types.MustHashFromHex("0x0000000000000000000000000000000000000000000000000000000000000002", types.PadNone),
+		types.MustHashFromHex("0x0000000000000000000000000000000000000000000000000000000000000003", types.PadNone),
+	}
+	inputs := map[types.Hash][]byte{
+		txHashes[0]: pokeInput,
+		txHashes[1]: multicallInput,
+		txHashes[2]: {0x01, 0x02, 0x03},
+	}
+	mockClient.getLogsFn = func(ctx context.Context, query types.FilterLogsQuery) ([]types.Log, error) {
+		assert.Equal(t, []types.Address{scribe.address}, query.Address)
+		assert.Equal(t, types.BlockNumberFromUint64(10), *query.FromBlock)
+		assert.Equal(t, types.BlockNumberFromUint64(20), *query.ToBlock)
+		assert.Equal(t, [][]types.Hash{{abiScribe.Events["Poked"].Topic0()}}, query.Topics)
+		var logs []types.Log
+		for i := range txHashes {
+			logs = append(logs, types.Log{
+				Topics: []types.Hash{
+					abiScribe.Events["Poked"].Topic0(),
+					types.MustHashFromBytes(caller.Bytes(), types.PadLeft),
+				},
+				Data:            eventData,
+				BlockNumber:     big.NewInt(int64(11 + i)),
+				TransactionHash: &txHashes[i],
+			})
+		}
+		return logs, nil
+	}
+	mockClient.getTxByHashFn = func(ctx context.Context, hash types.Hash) (*types.OnChainTransaction, error) {
+		return &types.OnChainTransaction{Transaction: types.Transaction{Call: types.Call{Input: inputs[hash]}}}, nil
+	}
+
+	events, err := scribe.Pokes(ctx, types.BlockNumberFromUint64(10), types.BlockNumberFromUint64(20))
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	for i, ev := range events {
+		assert.Equal(t, uint64(11+i), ev.BlockNumber)
+		assert.Equal(t, txHashes[i], ev.TxHash)
+		assert.Equal(t, caller, ev.Caller)
+		assert.Equal(t, "1", ev.PokeData.Val.String())
+		assert.Equal(t, int64(1694119620), ev.PokeData.Age.Unix())
+	}
+	require.NotNil(t, events[0].SchnorrData)
+	assert.Equal(t, schnorrData, *events[0].SchnorrData)
+	require.NotNil(t, events[1].SchnorrData)
+	assert.Equal(t, schnorrData, *events[1].SchnorrData)
+	assert.Nil(t, events[2].SchnorrData)
+}
+
 func Test_ConstructPokeMessage(t *testing.T) {
 	pokeData := PokeData{
 		Val: bn.DecFixedPointFromRawBigInt(bn.Int("1649381927392550000000").BigInt(), ScribePricePrecision),
@@ -16,6 +16,8 @@
 package multicall
 
 import (
+	"fmt"
+
 	"github.com/defiweb/go-eth/abi"
 	"github.com/defiweb/go-eth/rpc"
 	"github.com/defiweb/go-eth/types"
@@ -43,6 +45,34 @@ type Result struct {
 	Data    []byte `abi:"returnData"`
 }
 
+// DecodeAggregate3 decodes the list of calls from the aggregate3 call data.
+func DecodeAggregate3(data []byte) ([]Call, error) {
+	var calls []Call
+	method := multicallAbi.Methods["aggregate3"]
+	if !method.FourBytes().Match(data) {
+		return nil, fmt.Errorf("multicall: data is not an aggregate3 call")
+	}
+	if err := method.DecodeArgs(data, &calls); err != nil {
+		return nil, fmt.Errorf("multicall: unable to decode aggregate3 call: %w", err)
+	}
+	return calls, nil
+}
+
+// EncodeAggregate3Results encodes the list of results as returned by the
+// aggregate3 call.
+func EncodeAggregate3Results(results []Result) ([]byte, error) {
+	data, err := abi.EncodeValues(multicallAbi.Methods["aggregate3"].Outputs(), results)
+	if err != nil {
+		return nil, fmt.Errorf("multicall: unable to encode aggregate3 results: %w", err)
+	}
+	return data, nil
+}
+
+// Address returns the address of the Multicall3 contract.
+func Address() types.Address {
+	return multicallAddress
+}
+
 type Multicall struct {
 	client rpc.RPC
 }
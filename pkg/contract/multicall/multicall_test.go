@@ -0,0 +1,61 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package multicall
+
+import (
+	"testing"
+
+	"github.com/defiweb/go-eth/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/orcfax/oracle-suite/pkg/contract/mock"
+)
+
+func TestDecodeAggregate3(t *testing.T) {
+	call1 := mock.NewCaller(t).MockAllowAllCalls()
+	call2 := mock.NewCaller(t).MockAllowAllCalls()
+	call1.AddressFn = func() types.Address { return types.MustAddressFromHex("0x1122334455667788990011223344556677889900") }
+	call2.AddressFn = func() types.Address { return types.MustAddressFromHex("0x2233445566778899001122334455667788990011") }
+	call1.CallDataFn = func() ([]byte, error) { return []byte{0x01, 0x02, 0x03}, nil }
+	call2.CallDataFn = func() ([]byte, error) { return []byte{0x04, 0x05, 0x06}, nil }
+
+	data, err := AggregateCallables(&mockClient{}, call1, call2).AllowFail().CallData()
+	require.NoError(t, err)
+
+	calls, err := DecodeAggregate3(data)
+	require.NoError(t, err)
+	assert.Equal(t, []Call{
+		{Target: call1.Address(), CallData: []byte{0x01, 0x02, 0x03}, AllowFail: true},
+		{Target: call2.Address(), CallData: []byte{0x04, 0x05, 0x06}, AllowFail: true},
+	}, calls)
+
+	_, err = DecodeAggregate3([]byte{0x01, 0x02, 0x03})
+	assert.Error(t, err)
+}
+
+func TestEncodeAggregate3Results(t *testing.T) {
+	results := []Result{
+		{Success: true, Data: []byte{0x01, 0x02, 0x03}},
+		{Success: false, Data: []byte{}},
+	}
+	data, err := EncodeAggregate3Results(results)
+	require.NoError(t, err)
+
+	var decoded []Result
+	require.NoError(t, multicallAbi.Methods["aggregate3"].DecodeValues(data, &decoded))
+	assert.Equal(t, results, decoded)
+}
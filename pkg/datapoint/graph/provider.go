@@ -6,6 +6,7 @@ import (
 	"sort"
 
 	"github.com/orcfax/oracle-suite/pkg/datapoint"
+	"github.com/orcfax/oracle-suite/pkg/tracing"
 	"github.com/orcfax/oracle-suite/pkg/util/maputil"
 )
 
@@ -51,7 +52,7 @@ func (p Provider) DataPoint(ctx context.Context, model string) (datapoint.Point,
 	if p.updater != nil {
 		p.updater.Update(ctx, []Node{node})
 	}
-	return node.DataPoint(), nil
+	return evaluate(ctx, model, node), nil
 }
 
 // DataPoints implements the data.Provider interface.
@@ -69,7 +70,7 @@ func (p Provider) DataPoints(ctx context.Context, models ...string) (map[string]
 	}
 	points := make(map[string]datapoint.Point, len(models))
 	for i, model := range models {
-		points[model] = nodes[i].DataPoint()
+		points[model] = evaluate(ctx, model, nodes[i])
 	}
 	return points, nil
 }
@@ -100,6 +101,16 @@ func (p Provider) Models(_ context.Context, models ...string) (map[string]datapo
 	return modelsMap, nil
 }
 
+// evaluate calculates the data point of the model graph.
+func evaluate(ctx context.Context, model string, node Node) datapoint.Point {
+	_, span := tracing.StartSpan(ctx, "graph.evaluate")
+	defer span.End()
+	span.SetAttribute("model", model)
+	point := node.DataPoint()
+	span.SetError(point.Validate())
+	return point
+}
+
 func nodeToModel(n Node) datapoint.Model {
 	m := datapoint.Model{}
 	m.Meta = n.Meta()
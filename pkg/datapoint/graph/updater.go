@@ -9,6 +9,7 @@ import (
 	"github.com/orcfax/oracle-suite/pkg/datapoint/origin"
 	"github.com/orcfax/oracle-suite/pkg/log"
 	"github.com/orcfax/oracle-suite/pkg/log/null"
+	"github.com/orcfax/oracle-suite/pkg/tracing"
 )
 
 const UpdaterLoggerTag = "GRAPH_UPDATER"
@@ -98,7 +99,12 @@ func (u *Updater) fetchDataPoints(ctx context.Context, queries queryMap) dataPoi
 			defer func() { <-u.limiter }()
 
 			// Fetch data points from the origin and store them in the map.
+			ctx, span := tracing.StartSpan(ctx, "updater.fetchDataPoints", tracing.WithKind(tracing.SpanKindClient))
+			span.SetAttribute("origin", originName)
+			span.SetAttribute("queries", len(queries))
 			points, err := origin.FetchDataPoints(ctx, queries)
+			span.SetError(err)
+			span.End()
 			mu.Lock()
 			if err != nil {
 				for _, query := range queries {
@@ -0,0 +1,290 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package reputation
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
+
+	"github.com/orcfax/oracle-suite/pkg/datapoint"
+	"github.com/orcfax/oracle-suite/pkg/datapoint/store"
+	"github.com/orcfax/oracle-suite/pkg/datapoint/value"
+	"github.com/orcfax/oracle-suite/pkg/log"
+	"github.com/orcfax/oracle-suite/pkg/log/null"
+	"github.com/orcfax/oracle-suite/pkg/util/bn"
+)
+
+const LoggerTag = "REPUTATION"
+
+const (
+	defaultWindow             = 10 * time.Minute
+	defaultMaxDeviation       = 5
+	defaultSmoothing          = 0.1
+	defaultExclusionThreshold = 0.5
+	defaultMinFeeds           = 3
+	defaultMinObservations    = 10
+)
+
+// Scorer scores feeds based on the quality of data points they publish.
+//
+// Every data point is compared with the median of the latest data points
+// published by other feeds for the same model within a time window. If the
+// deviation from the median is larger than the configured maximum, the data
+// point is considered an outlier. The score of a feed is an exponential
+// moving average of outliers, from 0 for feeds that always agree with the
+// consensus to 1 for feeds that never do.
+//
+// Feeds with a score above the exclusion threshold are reported and should
+// be excluded from quorum selection.
+type Scorer struct {
+	mu     sync.RWMutex
+	points map[string]map[types.Address]observation // Latest data points by model and feed.
+	feeds  map[types.Address]*Score
+
+	window             time.Duration
+	maxDeviation       float64
+	smoothing          float64
+	exclusionThreshold float64
+	minFeeds           int
+	minObservations    int
+	log                log.Logger
+}
+
+// Config is the configuration for the Scorer.
+type Config struct {
+	// Window is the maximum time difference between compared data points.
+	// If zero, default value will be used (10 minutes).
+	Window time.Duration
+
+	// MaxDeviation is the maximum deviation from the median, in percent,
+	// above which a data point is considered an outlier. If zero, default
+	// value will be used (5%).
+	MaxDeviation float64
+
+	// Smoothing is the weight of the latest observation in the score, in
+	// the range (0, 1]. If zero, default value will be used (0.1).
+	Smoothing float64
+
+	// ExclusionThreshold is the score above which a feed is excluded. If
+	// zero, default value will be used (0.5).
+	ExclusionThreshold float64
+
+	// MinFeeds is the minimum number of other feeds required to calculate
+	// the median. If zero, default value will be used (3).
+	MinFeeds int
+
+	// MinObservations is the minimum number of observations before a feed
+	// can be penalized. If zero, default value will be used (10).
+	MinObservations int
+
+	// Logger is a current logger interface used by the Scorer.
+	// If nil, null logger will be used.
+	Logger log.Logger
+}
+
+// Score describes the data quality of a feed.
+type Score struct {
+	// Score is the moving average of outliers in the range [0, 1].
+	Score float64
+
+	// Observations is the number of data points compared with the median.
+	Observations int
+
+	// Excluded is true if the feed should be excluded from quorum selection.
+	Excluded bool
+}
+
+type observation struct {
+	price *bn.DecFloatPointNumber
+	time  time.Time
+}
+
+// New creates a new Scorer.
+func New(cfg Config) (*Scorer, error) {
+	if cfg.Window == 0 {
+		cfg.Window = defaultWindow
+	}
+	if cfg.MaxDeviation == 0 {
+		cfg.MaxDeviation = defaultMaxDeviation
+	}
+	if cfg.Smoothing == 0 {
+		cfg.Smoothing = defaultSmoothing
+	}
+	if cfg.ExclusionThreshold == 0 {
+		cfg.ExclusionThreshold = defaultExclusionThreshold
+	}
+	if cfg.MinFeeds == 0 {
+		cfg.MinFeeds = defaultMinFeeds
+	}
+	if cfg.MinObservations == 0 {
+		cfg.MinObservations = defaultMinObservations
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = null.New()
+	}
+	if cfg.Window < 0 || cfg.MaxDeviation < 0 || cfg.MinFeeds < 0 || cfg.MinObservations < 0 {
+		return nil, errors.New("parameters must not be negative")
+	}
+	if cfg.Smoothing < 0 || cfg.Smoothing > 1 {
+		return nil, errors.New("smoothing must be in the range (0, 1]")
+	}
+	if cfg.ExclusionThreshold < 0 || cfg.ExclusionThreshold > 1 {
+		return nil, errors.New("exclusion threshold must be in the range (0, 1]")
+	}
+	return &Scorer{
+		points:             make(map[string]map[types.Address]observation),
+		feeds:              make(map[types.Address]*Score),
+		window:             cfg.Window,
+		maxDeviation:       cfg.MaxDeviation,
+		smoothing:          cfg.Smoothing,
+		exclusionThreshold: cfg.ExclusionThreshold,
+		minFeeds:           cfg.MinFeeds,
+		minObservations:    cfg.MinObservations,
+		log:                cfg.Logger.WithField("tag", LoggerTag),
+	}, nil
+}
+
+// Observe compares the data point published by a feed with the median of
+// data points published by other feeds and updates the feed score. Only
+// tick data points are supported, other data points are ignored.
+func (s *Scorer) Observe(from types.Address, model string, point datapoint.Point) {
+	tick, ok := point.Value.(value.Tick)
+	if !ok || tick.Price == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	points, ok := s.points[model]
+	if !ok {
+		points = make(map[types.Address]observation)
+		s.points[model] = points
+	}
+	if prev, ok := points[from]; ok && !point.Time.After(prev.time) {
+		return
+	}
+	points[from] = observation{price: tick.Price, time: point.Time}
+	var prices []*bn.DecFloatPointNumber
+	for addr, o := range points {
+		if addr == from || absDuration(point.Time.Sub(o.time)) > s.window {
+			continue
+		}
+		prices = append(prices, o.price)
+	}
+	if len(prices) < s.minFeeds {
+		return
+	}
+	median := bn.Median(prices)
+	deviation := bn.Deviation(tick.Price, median)
+	outlier := 0.0
+	if deviation > s.maxDeviation {
+		outlier = 1
+	}
+	f, ok := s.feeds[from]
+	if !ok {
+		f = &Score{}
+		s.feeds[from] = f
+	}
+	f.Observations++
+	f.Score = f.Score*(1-s.smoothing) + outlier*s.smoothing
+	excluded := f.Observations >= s.minObservations && f.Score >= s.exclusionThreshold
+	if excluded == f.Excluded {
+		f.Excluded = excluded
+		return
+	}
+	f.Excluded = excluded
+	fields := log.Fields{
+		"feed":      from.String(),
+		"model":     model,
+		"score":     f.Score,
+		"deviation": deviation,
+		"price":     tick.Price.String(),
+		"median":    median.String(),
+	}
+	if excluded {
+		s.log.
+			WithFields(fields).
+			WithAdvice("The feed publishes prices that consistently deviate from other feeds, contact the feed operator").
+			Warn("Feed excluded due to poor data quality")
+	} else {
+		s.log.
+			WithFields(fields).
+			Info("Feed is no longer excluded")
+	}
+}
+
+// Penalty returns the score of a feed if it has been observed enough times,
+// otherwise zero.
+func (s *Scorer) Penalty(feed types.Address) float64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	f, ok := s.feeds[feed]
+	if !ok || f.Observations < s.minObservations {
+		return 0
+	}
+	return f.Score
+}
+
+// Excluded returns true if the feed should be excluded from quorum
+// selection.
+func (s *Scorer) Excluded(feed types.Address) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	f, ok := s.feeds[feed]
+	return ok && f.Excluded
+}
+
+// Scores returns scores of all observed feeds.
+func (s *Scorer) Scores() map[types.Address]Score {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	r := make(map[types.Address]Score, len(s.feeds))
+	for addr, f := range s.feeds {
+		r[addr] = *f
+	}
+	return r
+}
+
+// Storage is a store.Storage decorator that passes every data point added
+// to the storage to the Scorer.
+type Storage struct {
+	store.Storage
+	scorer *Scorer
+}
+
+// NewStorage returns a new Storage that decorates the given storage.
+func NewStorage(storage store.Storage, scorer *Scorer) *Storage {
+	return &Storage{Storage: storage, scorer: scorer}
+}
+
+// Add implements the store.Storage interface.
+func (s *Storage) Add(ctx context.Context, point store.StoredDataPoint) error {
+	if err := s.Storage.Add(ctx, point); err != nil {
+		return err
+	}
+	s.scorer.Observe(point.From, point.Model, point.DataPoint)
+	return nil
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
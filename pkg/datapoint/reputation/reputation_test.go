@@ -0,0 +1,153 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package reputation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/orcfax/oracle-suite/pkg/datapoint"
+	"github.com/orcfax/oracle-suite/pkg/datapoint/store"
+	"github.com/orcfax/oracle-suite/pkg/datapoint/value"
+	"github.com/orcfax/oracle-suite/pkg/util/bn"
+)
+
+var (
+	feed1 = types.MustAddressFromHex("0x1111111111111111111111111111111111111111")
+	feed2 = types.MustAddressFromHex("0x2222222222222222222222222222222222222222")
+	feed3 = types.MustAddressFromHex("0x3333333333333333333333333333333333333333")
+	feed4 = types.MustAddressFromHex("0x4444444444444444444444444444444444444444")
+)
+
+func testPoint(price float64, t time.Time) datapoint.Point {
+	return datapoint.Point{
+		Value: value.Tick{
+			Pair:  value.Pair{Base: "ETH", Quote: "USD"},
+			Price: bn.DecFloatPoint(price),
+		},
+		Time: t,
+	}
+}
+
+func TestScorer(t *testing.T) {
+	s, err := New(Config{MinFeeds: 3, MinObservations: 5, Smoothing: 0.5, ExclusionThreshold: 0.5})
+	require.NoError(t, err)
+
+	now := time.Now()
+	for i := 0; i < 10; i++ {
+		ts := now.Add(time.Duration(i) * time.Minute)
+		s.Observe(feed1, "ETH/USD", testPoint(100, ts))
+		s.Observe(feed2, "ETH/USD", testPoint(101, ts))
+		s.Observe(feed3, "ETH/USD", testPoint(99, ts))
+		s.Observe(feed4, "ETH/USD", testPoint(150, ts))
+	}
+
+	scores := s.Scores()
+	assert.Equal(t, 0.0, scores[feed1].Score)
+	assert.False(t, scores[feed1].Excluded)
+	assert.Greater(t, scores[feed4].Score, 0.9)
+	assert.True(t, scores[feed4].Excluded)
+
+	assert.False(t, s.Excluded(feed1))
+	assert.True(t, s.Excluded(feed4))
+	assert.Equal(t, 0.0, s.Penalty(feed1))
+	assert.Greater(t, s.Penalty(feed4), 0.9)
+
+	// Feed recovers after publishing correct prices:
+	for i := 10; i < 20; i++ {
+		ts := now.Add(time.Duration(i) * time.Minute)
+		s.Observe(feed1, "ETH/USD", testPoint(100, ts))
+		s.Observe(feed2, "ETH/USD", testPoint(101, ts))
+		s.Observe(feed3, "ETH/USD", testPoint(99, ts))
+		s.Observe(feed4, "ETH/USD", testPoint(100, ts))
+	}
+	assert.False(t, s.Excluded(feed4))
+	assert.Less(t, s.Penalty(feed4), 0.01)
+}
+
+func TestScorer_NotEnoughFeeds(t *testing.T) {
+	s, err := New(Config{MinFeeds: 3, MinObservations: 1})
+	require.NoError(t, err)
+
+	now := time.Now()
+	for i := 0; i < 10; i++ {
+		ts := now.Add(time.Duration(i) * time.Minute)
+		s.Observe(feed1, "ETH/USD", testPoint(100, ts))
+		s.Observe(feed2, "ETH/USD", testPoint(150, ts))
+	}
+	assert.Empty(t, s.Scores())
+	assert.False(t, s.Excluded(feed2))
+}
+
+func TestScorer_Window(t *testing.T) {
+	s, err := New(Config{MinFeeds: 2, MinObservations: 1, Window: time.Minute})
+	require.NoError(t, err)
+
+	now := time.Now()
+	s.Observe(feed1, "ETH/USD", testPoint(100, now.Add(-time.Hour)))
+	s.Observe(feed2, "ETH/USD", testPoint(100, now.Add(-time.Hour)))
+
+	// Data points from other feeds are too old to be compared:
+	s.Observe(feed3, "ETH/USD", testPoint(150, now))
+	assert.Empty(t, s.Scores())
+}
+
+func TestScorer_Models(t *testing.T) {
+	s, err := New(Config{MinFeeds: 2, MinObservations: 1, Smoothing: 1})
+	require.NoError(t, err)
+
+	now := time.Now()
+	s.Observe(feed1, "ETH/USD", testPoint(100, now))
+	s.Observe(feed2, "ETH/USD", testPoint(100, now))
+	s.Observe(feed1, "BTC/USD", testPoint(30000, now))
+	s.Observe(feed2, "BTC/USD", testPoint(30000, now))
+
+	// Prices are compared only within the same model:
+	s.Observe(feed3, "ETH/USD", testPoint(100, now))
+	assert.False(t, s.Excluded(feed3))
+	s.Observe(feed3, "BTC/USD", testPoint(100, now))
+	assert.True(t, s.Excluded(feed3))
+}
+
+func TestStorage(t *testing.T) {
+	s, err := New(Config{MinFeeds: 1, MinObservations: 1, Smoothing: 1})
+	require.NoError(t, err)
+	st := NewStorage(store.NewMemoryStorage(), s)
+
+	ctx := context.Background()
+	now := time.Now()
+	require.NoError(t, st.Add(ctx, store.StoredDataPoint{Model: "ETH/USD", From: feed1, DataPoint: testPoint(100, now)}))
+	require.NoError(t, st.Add(ctx, store.StoredDataPoint{Model: "ETH/USD", From: feed2, DataPoint: testPoint(200, now)}))
+
+	_, ok, err := st.LatestFrom(ctx, feed2, "ETH/USD")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, s.Excluded(feed2))
+}
+
+func TestNew_Validation(t *testing.T) {
+	_, err := New(Config{Smoothing: 2})
+	assert.Error(t, err)
+	_, err = New(Config{ExclusionThreshold: -1})
+	assert.Error(t, err)
+	_, err = New(Config{MinFeeds: -1})
+	assert.Error(t, err)
+}
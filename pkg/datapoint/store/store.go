@@ -20,6 +20,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/defiweb/go-eth/types"
@@ -29,9 +30,12 @@ import (
 	"github.com/orcfax/oracle-suite/pkg/datapoint/value"
 	"github.com/orcfax/oracle-suite/pkg/log"
 	"github.com/orcfax/oracle-suite/pkg/log/null"
+	"github.com/orcfax/oracle-suite/pkg/tracing"
 	"github.com/orcfax/oracle-suite/pkg/transport"
 	"github.com/orcfax/oracle-suite/pkg/transport/messages"
 	"github.com/orcfax/oracle-suite/pkg/util/bn"
+	"github.com/orcfax/oracle-suite/pkg/util/maputil"
+	"github.com/orcfax/oracle-suite/pkg/util/sliceutil"
 )
 
 const LoggerTag = "DATA_POINT_STORE"
@@ -86,6 +90,7 @@ func StoredDataPointLogFields(o StoredDataPoint) log.Fields {
 
 // Store stores latest data points from feeds.
 type Store struct {
+	mu     sync.RWMutex
 	ctx    context.Context
 	waitCh chan error
 	log    log.Logger
@@ -132,7 +137,7 @@ func New(cfg Config) (*Store, error) {
 		log:        cfg.Logger.WithField("tag", LoggerTag),
 		storage:    cfg.Storage,
 		transport:  cfg.Transport,
-		models:     cfg.Models,
+		models:     sliceutil.Copy(cfg.Models),
 		recoverers: cfg.Recoverers,
 	}
 	return s, nil
@@ -169,11 +174,41 @@ func (p *Store) Latest(ctx context.Context, model string) (map[types.Address]Sto
 	return p.storage.Latest(ctx, model)
 }
 
-func (p *Store) collectDataPoint(point *messages.DataPoint) {
+// Models returns a copy of the list of models which are collected by
+// the store.
+func (p *Store) Models() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return sliceutil.Copy(p.models)
+}
+
+// SetModels replaces the list of models which are collected by the store.
+// Data points already stored for models that are no longer on the list
+// remain in the storage.
+func (p *Store) SetModels(models []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.models = sliceutil.Copy(models)
+}
+
+func (p *Store) collectDataPoint(ctx context.Context, point *messages.DataPoint) {
+	ctx, span := tracing.StartSpan(ctx, "store.collectDataPoint")
+	defer span.End()
+	span.SetAttribute("model", point.Model)
+
+	// Services that use stored data points, like relays, link their spans
+	// to the trace context in the metadata. The trace context added by
+	// the feed is preserved, this span is referred to only if the data
+	// point does not carry one.
+	if _, ok := tracing.Extract(point.Point.Meta); !ok && span != nil {
+		point.Point.Meta = maputil.Copy(point.Point.Meta)
+		tracing.Inject(point.Point.Meta, span.SpanContext())
+	}
 	for _, recoverer := range p.recoverers {
-		if recoverer.Supports(p.ctx, point.Point) {
-			from, err := recoverer.Recover(p.ctx, point.Model, point.Point, point.ECDSASignature)
+		if recoverer.Supports(ctx, point.Point) {
+			from, err := recoverer.Recover(ctx, point.Model, point.Point, point.ECDSASignature)
 			if err != nil {
+				span.SetError(err)
 				p.log.
 					WithError(err).
 					WithFields(log.Fields{
@@ -191,7 +226,8 @@ func (p *Store) collectDataPoint(point *messages.DataPoint) {
 				From:      *from,
 				Signature: point.ECDSASignature,
 			}
-			if err := p.storage.Add(p.ctx, sdp); err != nil {
+			if err := p.storage.Add(ctx, sdp); err != nil {
+				span.SetError(err)
 				p.log.
 					WithError(err).
 					WithFields(StoredDataPointLogFields(sdp)).
@@ -204,6 +240,7 @@ func (p *Store) collectDataPoint(point *messages.DataPoint) {
 			return
 		}
 	}
+	span.SetError(errors.New("no recoverer found for the data point"))
 	p.log.
 		WithField("model", point.Model).
 		WithFields(datapoint.PointLogFields(point.Point)).
@@ -212,6 +249,8 @@ func (p *Store) collectDataPoint(point *messages.DataPoint) {
 }
 
 func (p *Store) shouldCollect(model string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
 	for _, a := range p.models {
 		if a == model {
 			return true
@@ -244,7 +283,19 @@ func (p *Store) handlePointMessage(msg transport.ReceivedMessage) {
 			Debug("Data point rejected, model is not supported")
 		return
 	}
-	p.collectDataPoint(point)
+
+	// Continue the trace started by the feed, if the data point carries
+	// the trace context.
+	ctx := p.ctx
+	if sc, ok := tracing.Extract(point.Point.Meta); ok {
+		ctx = tracing.ContextWithRemoteSpanContext(ctx, sc)
+	}
+	ctx, span := tracing.StartSpan(ctx, "transport.receive", tracing.WithKind(tracing.SpanKindConsumer))
+	span.SetAttribute("topic", messages.DataPointV1MessageName)
+	span.SetAttribute("transport", msg.Meta.Transport)
+	defer span.End()
+
+	p.collectDataPoint(ctx, point)
 }
 
 // handleLegacyPriceMessage handles legacy price messages and converts them to
@@ -294,14 +345,14 @@ func (p *Store) handleLegacyPriceMessage(msg transport.ReceivedMessage) {
 			Debug("Data point rejected, model is not supported")
 		return
 	}
-	p.collectDataPoint(point)
+	p.collectDataPoint(p.ctx, point)
 }
 
 // logDataPointsSince logs a short summary of data points collected since the
 // given time.
 func (p *Store) logDataPointsSince(since time.Time) {
 	dataPointsLog := make(map[string]any)
-	for _, model := range p.models {
+	for _, model := range p.Models() {
 		dataPoints, err := p.storage.Latest(p.ctx, model)
 		if err != nil {
 			p.log.
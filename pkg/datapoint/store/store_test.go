@@ -3,6 +3,7 @@ package store
 import (
 	"bytes"
 	"context"
+	"strings"
 	"testing"
 	"time"
 
@@ -12,6 +13,7 @@ import (
 
 	"github.com/orcfax/oracle-suite/pkg/datapoint"
 	"github.com/orcfax/oracle-suite/pkg/datapoint/value"
+	"github.com/orcfax/oracle-suite/pkg/tracing"
 	"github.com/orcfax/oracle-suite/pkg/transport"
 	"github.com/orcfax/oracle-suite/pkg/transport/local"
 	"github.com/orcfax/oracle-suite/pkg/transport/messages"
@@ -121,3 +123,110 @@ func TestStore(t *testing.T) {
 	assert.Equal(t, "3", b[types.MustAddressFromHex("0x1111111111111111111111111111111111111111")].DataPoint.Value.Print())
 	assert.Equal(t, "4", b[types.MustAddressFromHex("0x2222222222222222222222222222222222222222")].DataPoint.Value.Print())
 }
+
+type exporterMock struct {
+	data [][]byte
+}
+
+func (e *exporterMock) Export(_ context.Context, data []byte) error {
+	e.data = append(e.data, data)
+	return nil
+}
+
+func TestStore_Models(t *testing.T) {
+	models := []string{"AAABBB", "XXXYYY"}
+	store, err := New(Config{
+		Storage:   NewMemoryStorage(),
+		Transport: local.New([]byte("test"), 0, nil),
+		Models:    models,
+	})
+	require.NoError(t, err)
+
+	// Modifying slices passed to or returned by the store must not affect it.
+	models[0] = "CCCDDD"
+	store.Models()[1] = "CCCDDD"
+	assert.Equal(t, []string{"AAABBB", "XXXYYY"}, store.Models())
+
+	models = []string{"EEEFFF"}
+	store.SetModels(models)
+	models[0] = "CCCDDD"
+	assert.Equal(t, []string{"EEEFFF"}, store.Models())
+}
+
+func TestStore_Trace(t *testing.T) {
+	ctx, ctxCancel := context.WithCancel(context.Background())
+	defer ctxCancel()
+
+	exporter := &exporterMock{}
+	tracer, err := tracing.New(tracing.Config{Exporter: exporter})
+	require.NoError(t, err)
+	tracing.SetDefault(tracer)
+	defer tracing.SetDefault(nil)
+
+	transport := local.New(
+		[]byte("test"),
+		0,
+		map[string]transport.Message{messages.DataPointV1MessageName: (*messages.DataPoint)(nil)},
+	)
+	require.NoError(t, transport.Start(ctx))
+
+	// Wait to be sure that the transport is ready.
+	time.Sleep(100 * time.Millisecond)
+
+	store, err := New(Config{
+		Storage:    NewMemoryStorage(),
+		Transport:  transport,
+		Models:     []string{"AAABBB"},
+		Recoverers: []datapoint.Recoverer{&mockRecoverer{}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, store.Start(ctx))
+
+	// Wait to be sure that the store is ready.
+	time.Sleep(100 * time.Millisecond)
+
+	// Data point with a trace context added by the feed.
+	sent := tracing.SpanContext{TraceID: tracing.TraceID{1}, SpanID: tracing.SpanID{2}}
+	msg := *aaabbb1
+	msg.Point.Meta = map[string]any{"addr": aaabbb1.Point.Meta["addr"]}
+	tracing.Inject(msg.Point.Meta, sent)
+	assert.NoError(t, transport.Broadcast(messages.DataPointV1MessageName, &msg))
+
+	assert.Eventually(t, func() bool {
+		a, _ := store.Latest(context.Background(), "AAABBB")
+		return len(a) == 1
+	}, 1*time.Second, 100*time.Millisecond)
+
+	// The stored data point preserves the trace context added by the feed.
+	a, _ := store.Latest(context.Background(), "AAABBB")
+	stored, ok := tracing.Extract(a[types.MustAddressFromHex("0x1111111111111111111111111111111111111111")].DataPoint.Meta)
+	require.True(t, ok)
+	assert.Equal(t, sent, stored)
+
+	// The transport.receive span ends after the data point is stored.
+	var spans string
+	assert.Eventually(t, func() bool {
+		require.NoError(t, tracer.Flush(ctx))
+		for _, data := range exporter.data {
+			spans += string(data)
+		}
+		exporter.data = nil
+		return strings.Contains(spans, `"name":"transport.receive"`)
+	}, 1*time.Second, 10*time.Millisecond)
+	assert.Contains(t, spans, `"parentSpanId":"`+sent.SpanID.String()+`","name":"transport.receive"`)
+	assert.Contains(t, spans, `"name":"store.collectDataPoint"`)
+
+	// Data point without a trace context refers to the
+	// store.collectDataPoint span.
+	msg = *aaabbb2
+	msg.Point.Meta = map[string]any{"addr": aaabbb2.Point.Meta["addr"]}
+	assert.NoError(t, transport.Broadcast(messages.DataPointV1MessageName, &msg))
+	assert.Eventually(t, func() bool {
+		a, _ := store.Latest(context.Background(), "AAABBB")
+		return len(a) == 2
+	}, 1*time.Second, 100*time.Millisecond)
+	a, _ = store.Latest(context.Background(), "AAABBB")
+	stored, ok = tracing.Extract(a[types.MustAddressFromHex("0x2222222222222222222222222222222222222222")].DataPoint.Meta)
+	require.True(t, ok)
+	assert.NotEqual(t, sent.TraceID, stored.TraceID)
+}
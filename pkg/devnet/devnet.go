@@ -0,0 +1,532 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package devnet provides an in-process network of oracle nodes that can be
+// used to test the whole pipeline, from price origins to contract updates,
+// without any external dependencies.
+//
+// The network consists of a set of feeds, a MuSig signer, a spire agent and
+// a relay that share a local transport. The relay sends transactions to an
+// in-memory EVM stand-in on which Median, Scribe and OpScribe contracts are
+// deployed.
+package devnet
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/defiweb/go-eth/crypto"
+	"github.com/defiweb/go-eth/rpc"
+	"github.com/defiweb/go-eth/rpc/transport"
+	"github.com/defiweb/go-eth/txmodifier"
+	"github.com/defiweb/go-eth/types"
+	"github.com/defiweb/go-eth/wallet"
+
+	"github.com/orcfax/oracle-suite/pkg/datapoint"
+	"github.com/orcfax/oracle-suite/pkg/datapoint/graph"
+	"github.com/orcfax/oracle-suite/pkg/datapoint/origin"
+	"github.com/orcfax/oracle-suite/pkg/datapoint/signer"
+	"github.com/orcfax/oracle-suite/pkg/datapoint/store"
+	"github.com/orcfax/oracle-suite/pkg/datapoint/value"
+	"github.com/orcfax/oracle-suite/pkg/feed"
+	"github.com/orcfax/oracle-suite/pkg/httpserver"
+	"github.com/orcfax/oracle-suite/pkg/log"
+	"github.com/orcfax/oracle-suite/pkg/log/null"
+	musigStore "github.com/orcfax/oracle-suite/pkg/musig/store"
+	"github.com/orcfax/oracle-suite/pkg/relay"
+	"github.com/orcfax/oracle-suite/pkg/spire"
+	"github.com/orcfax/oracle-suite/pkg/supervisor"
+	pkgTransport "github.com/orcfax/oracle-suite/pkg/transport"
+	"github.com/orcfax/oracle-suite/pkg/transport/local"
+	"github.com/orcfax/oracle-suite/pkg/transport/messages"
+	"github.com/orcfax/oracle-suite/pkg/util/timeutil"
+)
+
+const LoggerTag = "DEVNET"
+
+const (
+	// ChainID is the chain ID of the in-memory EVM.
+	ChainID = 1337
+
+	// OriginName is the name under which the price origin is registered.
+	OriginName = "devnet"
+
+	defaultFeeds      = 3
+	defaultSpread     = 1
+	defaultExpiration = time.Hour
+	defaultInterval   = time.Second
+
+	defaultOpChallengePeriod = time.Minute
+
+	// localQueue is the size of the local transport queue.
+	localQueue = 1024
+)
+
+// Config is the configuration for the Devnet.
+type Config struct {
+	// Feeds is the number of feeds to start. Every feed uses a distinct
+	// randomly generated key. If zero, three feeds are started.
+	Feeds int
+
+	// Models is a map of data models to initial prices. Model names must be
+	// formatted as "BASE/QUOTE". For every model, a Median, a Scribe and
+	// an OpScribe contract are deployed on the EVM.
+	Models map[string]float64
+
+	// Origin is an optional origin used by feeds to fetch prices. Models are
+	// queried using value.Pair. If nil, the Origin returned by the Origin
+	// method is used, and prices can be changed using the SetPrice method.
+	Origin origin.Origin
+
+	// Bar is the number of signatures required to poke the contracts.
+	// If zero, it is equal to the number of feeds.
+	Bar int
+
+	// Spread is the minimum spread, in percent, between the current and the
+	// new price required to poke the contracts. If zero, 1% is used.
+	Spread float64
+
+	// Expiration is the time after which the contracts are poked regardless
+	// of the spread. If zero, one hour is used.
+	Expiration time.Duration
+
+	// OpChallengePeriod is the challenge period of the OpScribe contracts,
+	// rounded down to seconds. If zero, one minute is used.
+	OpChallengePeriod time.Duration
+
+	// Interval is the interval at which feeds broadcast prices and the relay
+	// checks the contracts. If zero, one second is used.
+	Interval time.Duration
+
+	// Logger is used by all nodes in the network. If nil, null logger is
+	// used.
+	Logger log.Logger
+}
+
+// Devnet is a network of in-process oracle nodes.
+type Devnet struct {
+	ctx    context.Context
+	waitCh chan error
+
+	interval   time.Duration
+	spread     float64
+	expiration time.Duration
+	models     []string
+	bar        int
+	feedKeys   []*wallet.PrivateKey
+	relayKey   wallet.Key
+	origin     origin.Origin
+	devOrigin  *Origin
+	evm        *EVM
+	medians    map[string]*Median
+	scribes    map[string]*Scribe
+	opScribes  map[string]*OpScribe
+	supervisor *supervisor.Supervisor
+	evmSrv     *httpserver.HTTPServer
+	agent      *spire.Agent
+	log        log.Logger
+	baseLog    log.Logger
+}
+
+// New creates a new Devnet instance.
+func New(cfg Config) (*Devnet, error) {
+	if len(cfg.Models) == 0 {
+		return nil, errors.New("at least one model must be provided")
+	}
+	if cfg.Feeds == 0 {
+		cfg.Feeds = defaultFeeds
+	}
+	if cfg.Bar == 0 {
+		cfg.Bar = cfg.Feeds
+	}
+	if cfg.Feeds < 0 || cfg.Bar < 0 || cfg.Bar > cfg.Feeds {
+		return nil, fmt.Errorf("invalid number of feeds (%d) or bar (%d)", cfg.Feeds, cfg.Bar)
+	}
+	if cfg.Spread == 0 {
+		cfg.Spread = defaultSpread
+	}
+	if cfg.Expiration == 0 {
+		cfg.Expiration = defaultExpiration
+	}
+	if cfg.Interval == 0 {
+		cfg.Interval = defaultInterval
+	}
+	if cfg.OpChallengePeriod == 0 {
+		cfg.OpChallengePeriod = defaultOpChallengePeriod
+	}
+	if cfg.Feeds > 256 || cfg.OpChallengePeriod < time.Second {
+		return nil, fmt.Errorf("invalid number of feeds (%d) or challenge period (%s)", cfg.Feeds, cfg.OpChallengePeriod)
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = null.New()
+	}
+	evm, err := NewEVM(ChainID)
+	if err != nil {
+		return nil, err
+	}
+	d := &Devnet{
+		waitCh:     make(chan error),
+		bar:        cfg.Bar,
+		interval:   cfg.Interval,
+		spread:     cfg.Spread,
+		expiration: cfg.Expiration,
+		origin:     cfg.Origin,
+		devOrigin:  NewOrigin(),
+		evm:        evm,
+		medians:    make(map[string]*Median),
+		scribes:    make(map[string]*Scribe),
+		opScribes:  make(map[string]*OpScribe),
+		supervisor: supervisor.New(cfg.Logger),
+		log:        cfg.Logger.WithField("tag", LoggerTag),
+		baseLog:    cfg.Logger,
+	}
+	if d.origin == nil {
+		d.origin = d.devOrigin
+	}
+	d.feedKeys = newFeedKeys(cfg.Feeds)
+	pubKeys := make([]*ecdsa.PublicKey, len(d.feedKeys))
+	for i, key := range d.feedKeys {
+		pubKeys[i] = key.PublicKey()
+	}
+	d.relayKey = wallet.NewRandomKey()
+	for model, price := range cfg.Models {
+		pair, err := value.PairFromString(model)
+		if err != nil {
+			return nil, fmt.Errorf("invalid model %q: %w", model, err)
+		}
+		d.models = append(d.models, model)
+		d.devOrigin.SetPrice(pair, price)
+		d.medians[model] = NewMedian(model, cfg.Bar, d.Feeds()...)
+		d.scribes[model] = NewScribe(model, cfg.Bar, pubKeys...)
+		d.opScribes[model] = NewOpScribe(model, cfg.Bar, cfg.OpChallengePeriod, pubKeys...)
+		d.evm.Deploy(MedianAddress(model), d.medians[model])
+		d.evm.Deploy(ScribeAddress(model), d.scribes[model])
+		d.evm.Deploy(OpScribeAddress(model), d.opScribes[model])
+	}
+	sort.Strings(d.models)
+	return d, nil
+}
+
+// Start starts all nodes in the network.
+func (d *Devnet) Start(ctx context.Context) error {
+	if d.ctx != nil {
+		return errors.New("service can be started only once")
+	}
+	if ctx == nil {
+		return errors.New("context must not be nil")
+	}
+	d.log.Debug("Starting")
+	d.ctx = ctx
+
+	// The EVM must be started first, because the RPC client needs to know
+	// its address.
+	d.evmSrv = httpserver.New(&http.Server{
+		Addr:              "127.0.0.1:0",
+		Handler:           d.evm,
+		ReadHeaderTimeout: time.Second,
+	})
+	if err := d.evmSrv.Start(ctx); err != nil {
+		return fmt.Errorf("unable to start the EVM: %w", err)
+	}
+	if err := d.buildNodes(); err != nil {
+		return err
+	}
+	if err := d.supervisor.Start(ctx); err != nil {
+		return err
+	}
+	go d.contextCancelHandler()
+	return nil
+}
+
+// Wait waits until the context is canceled or until an error occurs.
+func (d *Devnet) Wait() <-chan error {
+	return d.waitCh
+}
+
+// SetPrice sets the price for the given model. It has no effect if a custom
+// origin was provided in the configuration.
+func (d *Devnet) SetPrice(model string, price float64) error {
+	pair, err := value.PairFromString(model)
+	if err != nil {
+		return err
+	}
+	d.devOrigin.SetPrice(pair, price)
+	return nil
+}
+
+// Origin returns the origin used to set prices.
+func (d *Devnet) Origin() *Origin {
+	return d.devOrigin
+}
+
+// EVM returns the in-memory EVM.
+func (d *Devnet) EVM() *EVM {
+	return d.evm
+}
+
+// Median returns the Median contract for the given model.
+func (d *Devnet) Median(model string) *Median {
+	return d.medians[model]
+}
+
+// Scribe returns the Scribe contract for the given model.
+func (d *Devnet) Scribe(model string) *Scribe {
+	return d.scribes[model]
+}
+
+// OpScribe returns the OpScribe contract for the given model.
+func (d *Devnet) OpScribe(model string) *OpScribe {
+	return d.opScribes[model]
+}
+
+// Feeds returns the addresses of the feeds.
+func (d *Devnet) Feeds() []types.Address {
+	addrs := make([]types.Address, len(d.feedKeys))
+	for i, key := range d.feedKeys {
+		addrs[i] = key.Address()
+	}
+	return addrs
+}
+
+// RPCURL returns the URL of the EVM JSON-RPC endpoint. It returns an empty
+// string if the network is not started.
+func (d *Devnet) RPCURL() string {
+	if d.evmSrv == nil || d.evmSrv.Addr() == nil {
+		return ""
+	}
+	return fmt.Sprintf("http://%s", d.evmSrv.Addr())
+}
+
+// SpireAddr returns the address of the spire agent. It returns nil if the
+// network is not started.
+func (d *Devnet) SpireAddr() net.Addr {
+	if d.agent == nil {
+		return nil
+	}
+	return d.agent.Addr()
+}
+
+// MedianAddress returns the address at which the Median contract for the
+// given model is deployed.
+func MedianAddress(model string) types.Address {
+	return types.MustAddressFromBytes(crypto.Keccak256([]byte(model)).Bytes()[12:])
+}
+
+// ScribeAddress returns the address at which the Scribe contract for the
+// given model is deployed.
+func ScribeAddress(model string) types.Address {
+	return types.MustAddressFromBytes(crypto.Keccak256([]byte("Scribe"), []byte(model)).Bytes()[12:])
+}
+
+// OpScribeAddress returns the address at which the OpScribe contract for
+// the given model is deployed.
+func OpScribeAddress(model string) types.Address {
+	return types.MustAddressFromBytes(crypto.Keccak256([]byte("OpScribe"), []byte(model)).Bytes()[12:])
+}
+
+// newFeedKeys generates n random feed keys.
+//
+// Contracts identify feeds by the first byte of their addresses, so keys
+// must not share it. Keys are ordered by feed IDs, which is the order in
+// which Scribe contracts expect signers.
+func newFeedKeys(n int) []*wallet.PrivateKey {
+	var (
+		keys = make([]*wallet.PrivateKey, 0, n)
+		ids  = make(map[byte]bool)
+	)
+	for len(keys) < n {
+		key := wallet.NewRandomKey()
+		if ids[key.Address()[0]] {
+			continue
+		}
+		ids[key.Address()[0]] = true
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Address()[0] < keys[j].Address()[0]
+	})
+	return keys
+}
+
+func (d *Devnet) buildNodes() error {
+	topics := map[string]pkgTransport.Message{
+		messages.DataPointV1MessageName:      (*messages.DataPoint)(nil),
+		messages.MuSigSignatureV1MessageName: (*messages.MuSigSignature)(nil),
+	}
+	base := local.New([]byte("devnet"), localQueue, topics)
+	d.supervisor.Watch(base)
+
+	// Feeds.
+	nodes := make(map[string]graph.Node, len(d.models))
+	for _, model := range d.models {
+		pair, _ := value.PairFromString(model)
+		nodes[model] = graph.NewOriginNode(OriginName, pair, 0, time.Minute)
+	}
+	provider := graph.NewProvider(nodes, graph.NewUpdater(
+		map[string]origin.Origin{OriginName: d.origin},
+		d.baseLog,
+	))
+	for _, key := range d.feedKeys {
+		f, err := feed.New(feed.Config{
+			DataModels:   d.models,
+			DataProvider: provider,
+			Signers:      []datapoint.Signer{signer.NewTickSigner(key)},
+			Transport:    base.WithAuthor(key.Address().Bytes()),
+			Interval:     timeutil.NewTicker(d.interval),
+			Logger:       d.baseLog.WithField("feed", key.Address().String()),
+		})
+		if err != nil {
+			return err
+		}
+		d.supervisor.Watch(f)
+	}
+
+	// MuSig.
+	signerStore, err := d.newStore(base)
+	if err != nil {
+		return err
+	}
+	d.supervisor.Watch(signerStore, &muSigSigner{
+		waitCh:    make(chan error),
+		keys:      d.feedKeys,
+		bar:       d.bar,
+		models:    d.models,
+		store:     signerStore,
+		transport: base.WithAuthor(d.feedKeys[0].Address().Bytes()),
+		interval:  timeutil.NewTicker(d.interval),
+		log:       d.log,
+	})
+
+	// Spire.
+	spireStore, err := d.newStore(base)
+	if err != nil {
+		return err
+	}
+	d.agent, err = spire.NewAgent(spire.AgentConfig{
+		PriceStore: spireStore,
+		Transport:  base,
+		Address:    "127.0.0.1:0",
+		Logger:     d.baseLog,
+	})
+	if err != nil {
+		return err
+	}
+	d.supervisor.Watch(spireStore, d.agent)
+
+	// Spectre.
+	client, err := d.newClient()
+	if err != nil {
+		return err
+	}
+	relayStore, err := d.newStore(base)
+	if err != nil {
+		return err
+	}
+	muSigStore := musigStore.New(musigStore.Config{
+		Transport:  base,
+		DataModels: d.models,
+		Logger:     d.baseLog,
+	})
+	var (
+		medians   []relay.ConfigMedian
+		scribes   []relay.ConfigScribe
+		opScribes []relay.ConfigOptimisticScribe
+	)
+	for _, model := range d.models {
+		medians = append(medians, relay.ConfigMedian{
+			Client:          client,
+			DataPointStore:  relayStore,
+			DataModel:       model,
+			ContractAddress: MedianAddress(model),
+			FeedAddresses:   d.Feeds(),
+			Spread:          d.spread,
+			Expiration:      d.expiration,
+		})
+		scribes = append(scribes, relay.ConfigScribe{
+			Client:          client,
+			MuSigStore:      muSigStore,
+			DataPointStore:  relayStore,
+			DataModel:       model,
+			ContractAddress: ScribeAddress(model),
+			Spread:          d.spread,
+			Expiration:      d.expiration,
+		})
+		opScribes = append(opScribes, relay.ConfigOptimisticScribe{
+			Client:               client,
+			MuSigStore:           muSigStore,
+			DataPointStore:       relayStore,
+			DataModel:            model,
+			ContractAddress:      OpScribeAddress(model),
+			Spread:               d.spread,
+			Expiration:           d.expiration,
+			OptimisticSpread:     d.spread,
+			OptimisticExpiration: d.expiration,
+		})
+	}
+	r, err := relay.New(relay.Config{
+		Medians:           medians,
+		Scribes:           scribes,
+		OptimisticScribes: opScribes,
+		Ticker:            timeutil.NewTicker(d.interval),
+		Logger:            d.baseLog,
+	})
+	if err != nil {
+		return err
+	}
+	d.supervisor.Watch(relayStore, muSigStore, r)
+	return nil
+}
+
+func (d *Devnet) newStore(t *local.Local) (*store.Store, error) {
+	return store.New(store.Config{
+		Storage:    store.NewMemoryStorage(),
+		Transport:  t,
+		Models:     d.models,
+		Recoverers: []datapoint.Recoverer{signer.NewTickRecoverer(crypto.ECRecoverer)},
+		Logger:     d.baseLog,
+	})
+}
+
+func (d *Devnet) newClient() (*rpc.Client, error) {
+	t, err := transport.NewHTTP(transport.HTTPOptions{URL: d.RPCURL()})
+	if err != nil {
+		return nil, err
+	}
+	return rpc.NewClient(
+		rpc.WithTransport(t),
+		rpc.WithKeys(d.relayKey),
+		rpc.WithDefaultAddress(d.relayKey.Address()),
+		rpc.WithChainID(ChainID),
+		rpc.WithTXModifiers(
+			txmodifier.NewGasLimitEstimator(txmodifier.GasLimitEstimatorOptions{Multiplier: 1}),
+			txmodifier.NewNonceProvider(txmodifier.NonceProviderOptions{}),
+			txmodifier.NewLegacyGasFeeEstimator(txmodifier.LegacyGasFeeEstimatorOptions{Multiplier: 1}),
+		),
+	)
+}
+
+func (d *Devnet) contextCancelHandler() {
+	defer func() { close(d.waitCh) }()
+	defer d.log.Debug("Stopped")
+	<-d.ctx.Done()
+	<-d.supervisor.Wait()
+	<-d.evmSrv.Wait()
+}
@@ -0,0 +1,82 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package devnet
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDevnet(t *testing.T) {
+	ctx, ctxCancel := context.WithCancel(context.Background())
+	defer ctxCancel()
+
+	d, err := New(Config{
+		Feeds:    3,
+		Models:   map[string]float64{"BTC/USD": 40000, "ETH/USD": 2000},
+		Interval: 100 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	require.NoError(t, d.Start(ctx))
+	assert.NotEmpty(t, d.RPCURL())
+	assert.NotNil(t, d.SpireAddr())
+
+	// Initial prices must be relayed to all contracts. OpScribe contracts
+	// are updated optimistically.
+	require.Eventually(t, func() bool {
+		return d.Median("BTC/USD").Val().String() == "40000" &&
+			d.Median("ETH/USD").Val().String() == "2000" &&
+			d.Scribe("BTC/USD").Val().String() == "40000" &&
+			d.Scribe("ETH/USD").Val().String() == "2000" &&
+			d.OpScribe("BTC/USD").OpPokes() == 1 &&
+			d.OpScribe("ETH/USD").OpPokes() == 1
+	}, 10*time.Second, 50*time.Millisecond)
+	assert.Equal(t, 0, d.OpScribe("BTC/USD").Pokes())
+
+	// A price change above the spread must result in a new poke. The
+	// optimistic price is still in the challenge period, so the OpScribe
+	// contract must be poked regularly.
+	pokes := d.Median("BTC/USD").Pokes()
+	require.NoError(t, d.SetPrice("BTC/USD", 42000))
+	require.Eventually(t, func() bool {
+		return d.Median("BTC/USD").Val().String() == "42000" &&
+			d.Scribe("BTC/USD").Val().String() == "42000" &&
+			d.OpScribe("BTC/USD").Val().String() == "42000"
+	}, 20*time.Second, 50*time.Millisecond)
+	assert.Equal(t, pokes+1, d.Median("BTC/USD").Pokes())
+	assert.Equal(t, 1, d.Median("ETH/USD").Pokes())
+	assert.Equal(t, 1, d.OpScribe("BTC/USD").OpPokes())
+
+	ctxCancel()
+	select {
+	case <-d.Wait():
+	case <-time.After(5 * time.Second):
+		t.Fatal("devnet did not stop")
+	}
+}
+
+func TestDevnet_InvalidConfig(t *testing.T) {
+	_, err := New(Config{})
+	assert.Error(t, err)
+	_, err = New(Config{Models: map[string]float64{"BTCUSD": 1}})
+	assert.Error(t, err)
+	_, err = New(Config{Models: map[string]float64{"BTC/USD": 1}, Feeds: 2, Bar: 3})
+	assert.Error(t, err)
+}
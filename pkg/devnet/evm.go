@@ -0,0 +1,408 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package devnet
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/defiweb/go-eth/abi"
+	"github.com/defiweb/go-eth/crypto"
+	"github.com/defiweb/go-eth/hexutil"
+	"github.com/defiweb/go-eth/types"
+	gethRPC "github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/orcfax/oracle-suite/pkg/contract/multicall"
+)
+
+const (
+	// defaultGasPrice is the gas price returned by the EVM.
+	defaultGasPrice = 1_000_000_000
+
+	// baseGasUsage is the gas used by every transaction.
+	baseGasUsage = 21_000
+
+	// callGasUsage is the gas used by every contract call.
+	callGasUsage = 50_000
+
+	// revertErrorCode is the JSON-RPC error code used for reverted calls.
+	revertErrorCode = 3
+)
+
+var (
+	// defaultBalance is the balance of every account.
+	defaultBalance = new(big.Int).Mul(big.NewInt(1000), big.NewInt(1e18))
+
+	revertErrorMethod = abi.MustParseMethod("Error(string)")
+)
+
+// Contract is a contract deployed on the EVM.
+type Contract interface {
+	// Call executes a call to the contract. If commit is false, the call
+	// must not modify the state of the contract. Returned error reverts
+	// the call.
+	Call(env Env, input []byte, commit bool) ([]byte, error)
+
+	// Storage returns the value stored in the given storage slot.
+	Storage(slot types.Hash) types.Hash
+}
+
+// Env describes the environment in which a contract call is executed.
+type Env struct {
+	// From is the address of the caller.
+	From types.Address
+
+	// Time is the block timestamp.
+	Time time.Time
+}
+
+// RevertError is returned by contracts to revert a call.
+type RevertError struct {
+	Reason string
+}
+
+// Revert returns a new RevertError with the given reason.
+func Revert(reason string) error {
+	return &RevertError{Reason: reason}
+}
+
+// Error implements the error interface.
+func (e *RevertError) Error() string {
+	return "execution reverted: " + e.Reason
+}
+
+// ErrorCode implements the rpc.Error interface from the go-ethereum package.
+func (e *RevertError) ErrorCode() int {
+	return revertErrorCode
+}
+
+// ErrorData implements the rpc.DataError interface from the go-ethereum
+// package.
+func (e *RevertError) ErrorData() any {
+	data, err := revertErrorMethod.EncodeArgs(e.Reason)
+	if err != nil {
+		return nil
+	}
+	return hexutil.BytesToHex(data)
+}
+
+// EVM is an in-memory stand-in for an Ethereum node.
+//
+// It does not execute EVM bytecode. Instead, contracts are implemented in Go
+// and deployed using the Deploy method. The EVM exposes a subset of the
+// Ethereum JSON-RPC API that is sufficient to read contract state and send
+// transactions using the go-eth RPC client, both with local keys
+// (eth_sendRawTransaction) and without them (eth_sendTransaction).
+//
+// Every transaction is mined immediately in a new block. The Multicall3
+// contract is always available at its canonical address.
+type EVM struct {
+	mu sync.Mutex
+
+	chainID   uint64
+	block     uint64
+	contracts map[types.Address]Contract
+	nonces    map[types.Address]uint64
+	receipts  map[types.Hash]*types.TransactionReceipt
+	txs       []types.Transaction
+	recover   crypto.Recoverer
+	rpc       *gethRPC.Server
+}
+
+// NewEVM creates a new EVM instance with the given chain ID.
+func NewEVM(chainID uint64) (*EVM, error) {
+	e := &EVM{
+		chainID:   chainID,
+		contracts: make(map[types.Address]Contract),
+		nonces:    make(map[types.Address]uint64),
+		receipts:  make(map[types.Hash]*types.TransactionReceipt),
+		recover:   crypto.ECRecoverer,
+		rpc:       gethRPC.NewServer(),
+	}
+	if err := e.rpc.RegisterName("eth", &ethAPI{evm: e}); err != nil {
+		return nil, err
+	}
+	if err := e.rpc.RegisterName("net", &netAPI{evm: e}); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// ChainID returns the chain ID of the EVM.
+func (e *EVM) ChainID() uint64 {
+	return e.chainID
+}
+
+// Deploy deploys a contract at the given address.
+func (e *EVM) Deploy(address types.Address, contract Contract) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.contracts[address] = contract
+}
+
+// Transactions returns all transactions mined by the EVM, including
+// reverted ones.
+func (e *EVM) Transactions() []types.Transaction {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]types.Transaction(nil), e.txs...)
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (e *EVM) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	e.rpc.ServeHTTP(rw, req)
+}
+
+// call executes a call to the contract at the given address. The mutex must
+// be locked by the caller.
+func (e *EVM) call(env Env, to types.Address, input []byte, commit bool) ([]byte, error) {
+	if to == multicall.Address() {
+		return e.multicall(env, input, commit)
+	}
+	contract, ok := e.contracts[to]
+	if !ok {
+		// Calls to addresses without a contract always succeed, as they
+		// do on a real chain.
+		return nil, nil
+	}
+	return contract.Call(env, input, commit)
+}
+
+// multicall executes the aggregate3 call of the Multicall3 contract.
+func (e *EVM) multicall(env Env, input []byte, commit bool) ([]byte, error) {
+	calls, err := multicall.DecodeAggregate3(input)
+	if err != nil {
+		return nil, Revert(err.Error())
+	}
+	results := make([]multicall.Result, len(calls))
+	for i, c := range calls {
+		data, err := e.call(env, c.Target, c.CallData, commit)
+		if err != nil {
+			if !c.AllowFail {
+				return nil, Revert("Multicall3: call failed")
+			}
+			results[i] = multicall.Result{Success: false, Data: []byte{}}
+			continue
+		}
+		if data == nil {
+			data = []byte{}
+		}
+		results[i] = multicall.Result{Success: true, Data: data}
+	}
+	return multicall.EncodeAggregate3Results(results)
+}
+
+// sendTransaction mines the transaction in a new block.
+func (e *EVM) sendTransaction(tx types.Transaction, hash types.Hash) (types.Hash, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if tx.Call.From == nil {
+		return types.Hash{}, errors.New("missing transaction sender")
+	}
+	if tx.Call.To == nil {
+		return types.Hash{}, errors.New("contract creation is not supported")
+	}
+	if tx.ChainID != nil && *tx.ChainID != e.chainID {
+		return types.Hash{}, fmt.Errorf("invalid chain id: %d", *tx.ChainID)
+	}
+	from := *tx.Call.From
+	if tx.Nonce != nil {
+		switch {
+		case *tx.Nonce < e.nonces[from]:
+			return types.Hash{}, errors.New("nonce too low")
+		case *tx.Nonce > e.nonces[from]:
+			return types.Hash{}, errors.New("nonce too high")
+		}
+	}
+	e.block++
+	e.nonces[from]++
+	status := uint64(1)
+	env := Env{From: from, Time: time.Now()}
+	if _, err := e.call(env, *tx.Call.To, tx.Call.Input, true); err != nil {
+		status = 0
+	}
+	e.txs = append(e.txs, tx)
+	e.receipts[hash] = &types.TransactionReceipt{
+		TransactionHash:   hash,
+		BlockHash:         blockHash(e.block),
+		BlockNumber:       new(big.Int).SetUint64(e.block),
+		From:              from,
+		To:                *tx.Call.To,
+		CumulativeGasUsed: gasUsage(tx.Call.Input),
+		EffectiveGasPrice: big.NewInt(defaultGasPrice),
+		GasUsed:           gasUsage(tx.Call.Input),
+		Status:            &status,
+	}
+	return hash, nil
+}
+
+// ethAPI implements procedures with the "eth_" prefix.
+type ethAPI struct {
+	evm *EVM
+}
+
+// netAPI implements procedures with the "net_" prefix.
+type netAPI struct {
+	evm *EVM
+}
+
+// Version implements the "net_version" call.
+func (n *netAPI) Version() string {
+	return strconv.FormatUint(n.evm.chainID, 10)
+}
+
+// ChainId implements the "eth_chainId" call.
+func (a *ethAPI) ChainId() types.Number { //nolint:revive,stylecheck
+	return types.NumberFromUint64(a.evm.chainID)
+}
+
+// BlockNumber implements the "eth_blockNumber" call.
+func (a *ethAPI) BlockNumber() types.Number {
+	a.evm.mu.Lock()
+	defer a.evm.mu.Unlock()
+	return types.NumberFromUint64(a.evm.block)
+}
+
+// GasPrice implements the "eth_gasPrice" call.
+func (a *ethAPI) GasPrice() types.Number {
+	return types.NumberFromUint64(defaultGasPrice)
+}
+
+// MaxPriorityFeePerGas implements the "eth_maxPriorityFeePerGas" call.
+func (a *ethAPI) MaxPriorityFeePerGas() types.Number {
+	return types.NumberFromUint64(defaultGasPrice)
+}
+
+// GetBalance implements the "eth_getBalance" call.
+func (a *ethAPI) GetBalance(_ types.Address, _ *types.BlockNumber) types.Number {
+	return types.NumberFromBigInt(defaultBalance)
+}
+
+// GetTransactionCount implements the "eth_getTransactionCount" call.
+func (a *ethAPI) GetTransactionCount(address types.Address, _ *types.BlockNumber) types.Number {
+	a.evm.mu.Lock()
+	defer a.evm.mu.Unlock()
+	return types.NumberFromUint64(a.evm.nonces[address])
+}
+
+// GetCode implements the "eth_getCode" call. Because contracts are not
+// implemented in bytecode, it returns a single INVALID opcode for deployed
+// contracts.
+func (a *ethAPI) GetCode(address types.Address, _ *types.BlockNumber) types.Bytes {
+	a.evm.mu.Lock()
+	defer a.evm.mu.Unlock()
+	if _, ok := a.evm.contracts[address]; ok || address == multicall.Address() {
+		return types.Bytes{0xfe}
+	}
+	return types.Bytes{}
+}
+
+// GetStorageAt implements the "eth_getStorageAt" call.
+func (a *ethAPI) GetStorageAt(address types.Address, slot types.Number, _ *types.BlockNumber) (types.Hash, error) {
+	a.evm.mu.Lock()
+	defer a.evm.mu.Unlock()
+	key, err := types.HashFromBigInt(slot.Big())
+	if err != nil {
+		return types.Hash{}, err
+	}
+	if contract, ok := a.evm.contracts[address]; ok {
+		return contract.Storage(key), nil
+	}
+	return types.Hash{}, nil
+}
+
+// Call implements the "eth_call" call.
+func (a *ethAPI) Call(call types.Call, _ *types.BlockNumber) (types.Bytes, error) {
+	a.evm.mu.Lock()
+	defer a.evm.mu.Unlock()
+	if call.To == nil {
+		return nil, errors.New("missing call recipient")
+	}
+	env := Env{Time: time.Now()}
+	if call.From != nil {
+		env.From = *call.From
+	}
+	data, err := a.evm.call(env, *call.To, call.Input, false)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// EstimateGas implements the "eth_estimateGas" call.
+func (a *ethAPI) EstimateGas(call types.Call, block *types.BlockNumber) (types.Number, error) {
+	if _, err := a.Call(call, block); err != nil {
+		return types.Number{}, err
+	}
+	return types.NumberFromUint64(gasUsage(call.Input)), nil
+}
+
+// SendTransaction implements the "eth_sendTransaction" call.
+func (a *ethAPI) SendTransaction(tx types.Transaction) (types.Hash, error) {
+	b, err := json.Marshal(tx)
+	if err != nil {
+		return types.Hash{}, err
+	}
+	a.evm.mu.Lock()
+	b = append(b, blockHash(a.evm.block).Bytes()...)
+	a.evm.mu.Unlock()
+	return a.evm.sendTransaction(tx, crypto.Keccak256(b))
+}
+
+// SendRawTransaction implements the "eth_sendRawTransaction" call.
+func (a *ethAPI) SendRawTransaction(raw types.Bytes) (types.Hash, error) {
+	tx := types.NewTransaction()
+	if _, err := tx.DecodeRLP(raw); err != nil {
+		return types.Hash{}, fmt.Errorf("invalid transaction: %w", err)
+	}
+	if tx.Type == types.LegacyTxType {
+		// The chain ID of legacy transactions is not decoded, it must be
+		// derived from the V value as described in EIP-155.
+		tx.ChainID = nil
+		if tx.Signature != nil && tx.Signature.V.Cmp(big.NewInt(35)) >= 0 {
+			tx.SetChainID(new(big.Int).Div(new(big.Int).Sub(tx.Signature.V, big.NewInt(35)), big.NewInt(2)).Uint64())
+		}
+	}
+	from, err := a.evm.recover.RecoverTransaction(tx)
+	if err != nil {
+		return types.Hash{}, fmt.Errorf("invalid transaction signature: %w", err)
+	}
+	tx.Call.From = from
+	return a.evm.sendTransaction(*tx, crypto.Keccak256(raw))
+}
+
+// GetTransactionReceipt implements the "eth_getTransactionReceipt" call.
+func (a *ethAPI) GetTransactionReceipt(hash types.Hash) *types.TransactionReceipt {
+	a.evm.mu.Lock()
+	defer a.evm.mu.Unlock()
+	return a.evm.receipts[hash]
+}
+
+// gasUsage returns the gas used by a transaction with the given input.
+func gasUsage(input []byte) uint64 {
+	return baseGasUsage + callGasUsage + 16*uint64(len(input))
+}
+
+// blockHash returns a deterministic hash of the block with the given number.
+func blockHash(block uint64) types.Hash {
+	return crypto.Keccak256(new(big.Int).SetUint64(block).Bytes())
+}
@@ -0,0 +1,226 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package devnet
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/defiweb/go-eth/rpc"
+	"github.com/defiweb/go-eth/rpc/transport"
+	"github.com/defiweb/go-eth/txmodifier"
+	"github.com/defiweb/go-eth/types"
+	"github.com/defiweb/go-eth/wallet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/orcfax/oracle-suite/pkg/contract/chronicle"
+	"github.com/orcfax/oracle-suite/pkg/contract/multicall"
+	"github.com/orcfax/oracle-suite/pkg/util/bn"
+)
+
+func newTestClient(t *testing.T, evm *EVM, key wallet.Key) *rpc.Client {
+	srv := httptest.NewServer(evm)
+	t.Cleanup(srv.Close)
+	tr, err := transport.NewHTTP(transport.HTTPOptions{URL: srv.URL})
+	require.NoError(t, err)
+	client, err := rpc.NewClient(
+		rpc.WithTransport(tr),
+		rpc.WithKeys(key),
+		rpc.WithDefaultAddress(key.Address()),
+		rpc.WithChainID(evm.ChainID()),
+		rpc.WithTXModifiers(
+			txmodifier.NewGasLimitEstimator(txmodifier.GasLimitEstimatorOptions{Multiplier: 1}),
+			txmodifier.NewNonceProvider(txmodifier.NonceProviderOptions{}),
+			txmodifier.NewLegacyGasFeeEstimator(txmodifier.LegacyGasFeeEstimatorOptions{Multiplier: 1}),
+		),
+	)
+	require.NoError(t, err)
+	return client
+}
+
+func signedVal(t *testing.T, key wallet.Key, wat string, val float64, age time.Time) chronicle.MedianVal {
+	price := bn.DecFloatPoint(val)
+	sig, err := key.SignMessage(chronicle.ConstructMedianPokeMessage(wat, price, age))
+	require.NoError(t, err)
+	return chronicle.MedianVal{
+		Val: price.DecFixedPoint(chronicle.MedianPricePrecision),
+		Age: age,
+		V:   uint8(sig.V.Uint64()),
+		R:   sig.R,
+		S:   sig.S,
+	}
+}
+
+func TestEVM_Median(t *testing.T) {
+	ctx := context.Background()
+	feeds := newFeedKeys(3)
+	address := MedianAddress("ETH/USD")
+
+	evm, err := NewEVM(ChainID)
+	require.NoError(t, err)
+	median := NewMedian("ETH/USD", 3, feeds[0].Address(), feeds[1].Address(), feeds[2].Address())
+	evm.Deploy(address, median)
+
+	client := newTestClient(t, evm, wallet.NewRandomKey())
+	contract := chronicle.NewMedian(client, address)
+
+	// Read the initial state using a multicall.
+	var (
+		wat string
+		age time.Time
+		bar int
+	)
+	require.NoError(t, multicall.AggregateCallables(
+		client,
+		contract.Wat(),
+		contract.Age(),
+		contract.Bar(),
+	).Call(ctx, types.LatestBlockNumber, []any{&wat, &age, &bar}))
+	assert.Equal(t, "ETH/USD", wat)
+	assert.Equal(t, int64(0), age.Unix())
+	assert.Equal(t, 3, bar)
+
+	// Poke the contract.
+	now := time.Now().Add(-time.Minute).Truncate(time.Second)
+	_, _, err = contract.Poke([]chronicle.MedianVal{
+		signedVal(t, feeds[0], "ETH/USD", 1000, now),
+		signedVal(t, feeds[1], "ETH/USD", 1020, now),
+		signedVal(t, feeds[2], "ETH/USD", 1010, now),
+	}).SendTransaction(ctx)
+	require.NoError(t, err)
+
+	val, err := contract.Val(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "1010", val.String())
+	assert.Equal(t, 1, median.Pokes())
+	assert.Len(t, evm.Transactions(), 1)
+
+	// Messages older than the current age must be rejected.
+	_, err = contract.Poke([]chronicle.MedianVal{
+		signedVal(t, feeds[0], "ETH/USD", 1000, now),
+		signedVal(t, feeds[1], "ETH/USD", 1020, now),
+		signedVal(t, feeds[2], "ETH/USD", 1010, now),
+	}).Gas(ctx, types.LatestBlockNumber)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "stale-message")
+
+	// Messages signed by unknown feeds must be rejected.
+	later := time.Now().Add(time.Minute).Truncate(time.Second)
+	_, err = contract.Poke([]chronicle.MedianVal{
+		signedVal(t, feeds[0], "ETH/USD", 1000, later),
+		signedVal(t, feeds[1], "ETH/USD", 1020, later),
+		signedVal(t, wallet.NewRandomKey(), "ETH/USD", 1010, later),
+	}).Gas(ctx, types.LatestBlockNumber)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid-oracle")
+
+	// Not enough signatures.
+	_, err = contract.Poke([]chronicle.MedianVal{
+		signedVal(t, feeds[0], "ETH/USD", 1000, later),
+	}).Gas(ctx, types.LatestBlockNumber)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "bar-too-low")
+	assert.Equal(t, 1, median.Pokes())
+}
+
+func signedPokeData(t *testing.T, keys []*wallet.PrivateKey, wat string, val float64, age time.Time) (chronicle.PokeData, chronicle.SchnorrData) {
+	pokeData := chronicle.PokeData{
+		Val: bn.DecFloatPoint(val).DecFixedPoint(chronicle.ScribePricePrecision),
+		Age: age,
+	}
+	message := types.MustHashFromBytes(chronicle.ConstructScribePokeMessage(wat, pokeData), types.PadNone)
+	sig, commitment, err := schnorrSign(keys, message)
+	require.NoError(t, err)
+	var addrs []types.Address
+	for _, key := range keys {
+		addrs = append(addrs, key.Address())
+	}
+	return pokeData, chronicle.SchnorrData{
+		Signature:  sig,
+		Commitment: commitment,
+		FeedIDs:    chronicle.FeedIDsFromAddresses(addrs),
+	}
+}
+
+func TestEVM_Scribe(t *testing.T) {
+	ctx := context.Background()
+	feeds := newFeedKeys(3)
+	address := ScribeAddress("ETH/USD")
+
+	evm, err := NewEVM(ChainID)
+	require.NoError(t, err)
+	scribe := NewScribe("ETH/USD", 2, feeds[0].PublicKey(), feeds[1].PublicKey(), feeds[2].PublicKey())
+	evm.Deploy(address, scribe)
+
+	client := newTestClient(t, evm, wallet.NewRandomKey())
+	contract := chronicle.NewScribe(client, address)
+
+	// Read the initial state using a multicall.
+	var (
+		wat   string
+		bar   int
+		addrs []types.Address
+	)
+	require.NoError(t, multicall.AggregateCallables(
+		client,
+		contract.Wat(),
+		contract.Bar(),
+		contract.Feeds(),
+	).Call(ctx, types.LatestBlockNumber, []any{&wat, &bar, &addrs}))
+	assert.Equal(t, "ETH/USD", wat)
+	assert.Equal(t, 2, bar)
+	assert.ElementsMatch(t, []types.Address{feeds[0].Address(), feeds[1].Address(), feeds[2].Address()}, addrs)
+
+	// Signatures of unknown feeds must be rejected.
+	now := time.Now().Add(-time.Minute).Truncate(time.Second)
+	_, err = contract.Poke(signedPokeData(t, newFeedKeys(2), "ETH/USD", 1010, now)).Gas(ctx, types.LatestBlockNumber)
+	require.Error(t, err)
+
+	// Signature must match the poked data.
+	pd, sd := signedPokeData(t, feeds[1:3], "ETH/USD", 1010, now)
+	pd.Val = bn.DecFloatPoint(1020).DecFixedPoint(chronicle.ScribePricePrecision)
+	_, err = contract.Poke(pd, sd).Gas(ctx, types.LatestBlockNumber)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "schnorr-signature-invalid")
+
+	// Not enough signatures.
+	_, err = contract.Poke(signedPokeData(t, feeds[0:1], "ETH/USD", 1010, now)).Gas(ctx, types.LatestBlockNumber)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "bar-not-reached")
+
+	// Messages from the future must be rejected.
+	_, err = contract.Poke(signedPokeData(t, feeds[0:2], "ETH/USD", 1010, now.Add(time.Hour))).Gas(ctx, types.LatestBlockNumber)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "future-message")
+
+	// Poke the contract.
+	_, _, err = contract.Poke(signedPokeData(t, feeds[0:2], "ETH/USD", 1010, now)).SendTransaction(ctx)
+	require.NoError(t, err)
+
+	pokeData, err := contract.Read(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "1010", pokeData.Val.String())
+	assert.Equal(t, 1, scribe.Pokes())
+
+	// Messages older than the current age must be rejected.
+	_, err = contract.Poke(signedPokeData(t, feeds[1:3], "ETH/USD", 1020, now)).Gas(ctx, types.LatestBlockNumber)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "stale-message")
+	assert.Equal(t, 1, scribe.Pokes())
+}
@@ -0,0 +1,198 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package devnet
+
+import (
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/defiweb/go-eth/abi"
+	"github.com/defiweb/go-eth/crypto"
+	"github.com/defiweb/go-eth/types"
+
+	"github.com/orcfax/oracle-suite/pkg/contract/chronicle"
+	"github.com/orcfax/oracle-suite/pkg/util/bn"
+)
+
+var medianABI = abi.MustParseSignatures(
+	`age()(uint256 age)`,
+	`wat()(bytes32 wat)`,
+	`bar()(uint8 bar)`,
+	`poke(uint256[] val_, uint256[] age_, uint8[] v, bytes32[] r, bytes32[] s)`,
+)
+
+// medianValSlot is the storage slot in which the Median contract stores the
+// price value and its age.
+var medianValSlot = types.MustHashFromBigInt(big.NewInt(1))
+
+// Median is a stand-in for the Chronicle Median contract.
+//
+// It verifies poke calls the same way the Solidity implementation does:
+// the number of prices must be equal to the quorum, prices must be sorted,
+// signed by distinct authorized feeds and newer than the current price.
+type Median struct {
+	mu sync.Mutex
+
+	wat     string
+	bar     int
+	val     *big.Int
+	age     uint64
+	orcl    map[types.Address]bool
+	pokes   int
+	recover crypto.Recoverer
+}
+
+// NewMedian creates a new Median contract for the given asset name and
+// quorum. Feeds are authorized to sign prices.
+func NewMedian(wat string, bar int, feeds ...types.Address) *Median {
+	m := &Median{
+		wat:     wat,
+		bar:     bar,
+		val:     new(big.Int),
+		orcl:    make(map[types.Address]bool),
+		recover: crypto.ECRecoverer,
+	}
+	m.Lift(feeds...)
+	return m
+}
+
+// Lift authorizes feeds to sign prices.
+func (m *Median) Lift(feeds ...types.Address) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, f := range feeds {
+		m.orcl[f] = true
+	}
+}
+
+// Val returns the current price.
+func (m *Median) Val() *bn.DecFixedPointNumber {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return bn.DecFixedPointFromRawBigInt(new(big.Int).Set(m.val), chronicle.MedianPricePrecision)
+}
+
+// Age returns the time of the last update.
+func (m *Median) Age() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return time.Unix(int64(m.age), 0)
+}
+
+// Pokes returns the number of successful poke calls.
+func (m *Median) Pokes() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.pokes
+}
+
+// Call implements the Contract interface.
+func (m *Median) Call(env Env, input []byte, commit bool) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(input) < 4 {
+		return nil, Revert("Median/invalid-call")
+	}
+	switch {
+	case medianABI.Methods["age"].FourBytes().Match(input):
+		return abi.EncodeValues(medianABI.Methods["age"].Outputs(), m.age)
+	case medianABI.Methods["wat"].FourBytes().Match(input):
+		var wat [32]byte
+		copy(wat[:], m.wat)
+		return abi.EncodeValues(medianABI.Methods["wat"].Outputs(), wat)
+	case medianABI.Methods["bar"].FourBytes().Match(input):
+		return abi.EncodeValues(medianABI.Methods["bar"].Outputs(), uint8(m.bar))
+	case medianABI.Methods["poke"].FourBytes().Match(input):
+		return nil, m.poke(env, input, commit)
+	}
+	return nil, Revert("Median/invalid-call")
+}
+
+// Storage implements the Contract interface.
+func (m *Median) Storage(slot types.Hash) types.Hash {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var h types.Hash
+	if slot == medianValSlot {
+		// The val (uint128) and age (uint32) variables are packed into
+		// a single slot.
+		new(big.Int).SetUint64(m.age).FillBytes(h[12:16])
+		m.val.FillBytes(h[16:32])
+	}
+	return h
+}
+
+func (m *Median) poke(env Env, input []byte, commit bool) error {
+	var (
+		vals []*big.Int
+		ages []*big.Int
+		v    []uint8
+		r    []types.Hash
+		s    []types.Hash
+	)
+	if err := medianABI.Methods["poke"].DecodeArgs(input, &vals, &ages, &v, &r, &s); err != nil {
+		return Revert("Median/invalid-call")
+	}
+	if len(vals) != m.bar || len(ages) != m.bar || len(v) != m.bar || len(r) != m.bar || len(s) != m.bar {
+		return Revert("Median/bar-too-low")
+	}
+	var (
+		last   = new(big.Int)
+		signed = make(map[byte]bool)
+	)
+	for i := range vals {
+		sig := types.SignatureFromVRS(
+			big.NewInt(int64(v[i])),
+			new(big.Int).SetBytes(r[i].Bytes()),
+			new(big.Int).SetBytes(s[i].Bytes()),
+		)
+		signer, err := m.recover.RecoverMessage(m.pokeMessage(vals[i], ages[i]), sig)
+		if err != nil || !m.orcl[*signer] {
+			return Revert("Median/invalid-oracle")
+		}
+		if !ages[i].IsUint64() || ages[i].Uint64() <= m.age {
+			return Revert("Median/stale-message")
+		}
+		if vals[i].BitLen() > 128 {
+			return Revert("Median/val-overflow")
+		}
+		if vals[i].Cmp(last) < 0 {
+			return Revert("Median/messages-not-in-order")
+		}
+		last = vals[i]
+		if signed[signer[0]] {
+			return Revert("Median/oracle-already-signed")
+		}
+		signed[signer[0]] = true
+	}
+	if commit {
+		m.val = new(big.Int).Set(vals[len(vals)>>1])
+		m.age = uint64(env.Time.Unix())
+		m.pokes++
+	}
+	return nil
+}
+
+// pokeMessage returns the message signed by feeds, which is
+// keccak256(val ‖ age ‖ wat).
+func (m *Median) pokeMessage(val, age *big.Int) []byte {
+	data := make([]byte, 96)
+	val.FillBytes(data[0:32])
+	age.FillBytes(data[32:64])
+	copy(data[64:96], m.wat)
+	return crypto.Keccak256(data).Bytes()
+}
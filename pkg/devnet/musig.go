@@ -0,0 +1,179 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package devnet
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
+	"github.com/defiweb/go-eth/wallet"
+
+	"github.com/orcfax/oracle-suite/pkg/contract/chronicle"
+	"github.com/orcfax/oracle-suite/pkg/datapoint/store"
+	"github.com/orcfax/oracle-suite/pkg/datapoint/value"
+	"github.com/orcfax/oracle-suite/pkg/log"
+	"github.com/orcfax/oracle-suite/pkg/transport/local"
+	"github.com/orcfax/oracle-suite/pkg/transport/messages"
+	"github.com/orcfax/oracle-suite/pkg/util/bn"
+	"github.com/orcfax/oracle-suite/pkg/util/timeutil"
+)
+
+// muSigSigner is a stand-in for the MuSig sessions run by feeds to sign
+// prices for Scribe contracts.
+//
+// Because the devnet holds private keys of all feeds, it does not run the
+// multi-round protocol. Instead, it periodically calculates the median of
+// the latest feed prices, signs it with the aggregated Schnorr signature of
+// a bar of feeds and broadcasts the MuSig signature message. Every message
+// also includes the ECDSA signature required for optimistic pokes.
+type muSigSigner struct {
+	ctx    context.Context
+	waitCh chan error
+
+	keys      []*wallet.PrivateKey
+	bar       int
+	models    []string
+	store     *store.Store
+	transport *local.Local
+	interval  *timeutil.Ticker
+	log       log.Logger
+}
+
+func (s *muSigSigner) Start(ctx context.Context) error {
+	if s.ctx != nil {
+		return errors.New("service can be started only once")
+	}
+	if ctx == nil {
+		return errors.New("context must not be nil")
+	}
+	s.ctx = ctx
+	s.interval.Start(ctx)
+	go s.signerRoutine()
+	return nil
+}
+
+func (s *muSigSigner) Wait() <-chan error {
+	return s.waitCh
+}
+
+func (s *muSigSigner) signerRoutine() {
+	defer close(s.waitCh)
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-s.interval.TickCh():
+			for _, model := range s.models {
+				if err := s.sign(model); err != nil {
+					s.log.
+						WithError(err).
+						WithField("dataModel", model).
+						Warn("Unable to sign the price")
+				}
+			}
+		}
+	}
+}
+
+// sign signs the median of the latest prices of the given model. Prices are
+// taken from the first bar of feeds, ordered by feed IDs, that have
+// a price in the store.
+func (s *muSigSigner) sign(model string) error {
+	points, err := s.store.Latest(s.ctx, model)
+	if err != nil {
+		return err
+	}
+	var (
+		keys   []*wallet.PrivateKey
+		ticks  []messages.MuSigMetaFeedTick
+		prices []*bn.DecFloatPointNumber
+		age    time.Time
+	)
+	for _, key := range s.keys {
+		sdp, ok := points[key.Address()]
+		if !ok {
+			continue
+		}
+		tick, ok := sdp.DataPoint.Value.(value.Tick)
+		if !ok || tick.Price == nil {
+			continue
+		}
+		if age.IsZero() || sdp.DataPoint.Time.Before(age) {
+			age = sdp.DataPoint.Time
+		}
+		keys = append(keys, key)
+		prices = append(prices, tick.Price)
+		ticks = append(ticks, messages.MuSigMetaFeedTick{
+			Val: tick.Price.DecFixedPoint(chronicle.ScribePricePrecision),
+			Age: sdp.DataPoint.Time,
+			VRS: sdp.Signature,
+		})
+		if len(keys) == s.bar {
+			break
+		}
+	}
+	if len(keys) < s.bar {
+		return errors.New("not enough prices")
+	}
+	pokeData := chronicle.PokeData{
+		Val: bn.Median(prices).DecFixedPoint(chronicle.ScribePricePrecision),
+		Age: age.Truncate(time.Second),
+	}
+	message := types.MustHashFromBytes(chronicle.ConstructScribePokeMessage(model, pokeData), types.PadNone)
+	sig, commitment, err := schnorrSign(keys, message)
+	if err != nil {
+		return err
+	}
+	signers := make([]types.Address, len(keys))
+	for i, key := range keys {
+		signers[i] = key.Address()
+	}
+	feedIDs := chronicle.FeedIDsFromAddresses(signers)
+	ecdsaData, err := keys[0].SignMessage(chronicle.ConstructScribeOpPokeMessage(
+		model,
+		pokeData,
+		chronicle.SchnorrData{Signature: sig, Commitment: commitment, FeedIDs: feedIDs},
+		feedIDs,
+	))
+	if err != nil {
+		return err
+	}
+	var sessionID types.Hash
+	if _, err := rand.Read(sessionID[:]); err != nil {
+		return err
+	}
+	return s.transport.Broadcast(messages.MuSigSignatureV1MessageName, &messages.MuSigSignature{
+		MuSigMessage: &messages.MuSigMessage{
+			MsgType: messages.MuSigTickV1DataType,
+			MsgBody: message,
+			MsgMeta: messages.MuSigMeta{Meta: messages.MuSigMetaTickV1{
+				Wat:       model,
+				Val:       pokeData.Val,
+				Age:       pokeData.Age,
+				ECDSAData: ecdsaData,
+				FeedTicks: ticks,
+			}},
+			Signers: signers,
+		},
+		SessionID:        sessionID,
+		ComputedAt:       time.Now(),
+		Commitment:       commitment,
+		SchnorrSignature: sig,
+	})
+}
@@ -0,0 +1,70 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package devnet
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/orcfax/oracle-suite/pkg/datapoint"
+	"github.com/orcfax/oracle-suite/pkg/datapoint/value"
+	"github.com/orcfax/oracle-suite/pkg/util/bn"
+)
+
+// Origin is a price origin whose prices are set manually. It is queried
+// with asset pairs given as value.Pair and returns value.Tick data points.
+type Origin struct {
+	mu     sync.Mutex
+	prices map[value.Pair]*bn.DecFloatPointNumber
+}
+
+// NewOrigin creates a new Origin without any prices.
+func NewOrigin() *Origin {
+	return &Origin{prices: make(map[value.Pair]*bn.DecFloatPointNumber)}
+}
+
+// SetPrice sets the price for the given asset pair. The price may be given
+// in any type supported by the bn.DecFloatPoint function.
+func (o *Origin) SetPrice(pair value.Pair, price any) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.prices[pair] = bn.DecFloatPoint(price)
+}
+
+// FetchDataPoints implements the origin.Origin interface.
+func (o *Origin) FetchDataPoints(_ context.Context, query []any) (map[any]datapoint.Point, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	points := make(map[any]datapoint.Point, len(query))
+	for _, q := range query {
+		pair, ok := q.(value.Pair)
+		if !ok {
+			return nil, fmt.Errorf("invalid query: %T", q)
+		}
+		price, ok := o.prices[pair]
+		if !ok {
+			points[q] = datapoint.Point{Error: fmt.Errorf("price for %s is not set", pair)}
+			continue
+		}
+		points[q] = datapoint.Point{
+			Value: value.Tick{Pair: pair, Price: price},
+			Time:  time.Now(),
+		}
+	}
+	return points, nil
+}
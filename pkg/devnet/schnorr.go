@@ -0,0 +1,111 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package devnet
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"math/big"
+
+	"github.com/defiweb/go-eth/crypto"
+	"github.com/defiweb/go-eth/types"
+	"github.com/defiweb/go-eth/wallet"
+	gethCrypto "github.com/ethereum/go-ethereum/crypto"
+)
+
+// The functions below implement the Schnorr signature scheme used by the
+// Chronicle Scribe contracts:
+//
+//	e = H(Px ‖ Pparity ‖ message ‖ commitment)
+//	signature = k + e * x (mod N)
+//	commitment = address(k * G)
+//
+// Where P is the sum of public keys of the signers and x is the sum of
+// their private keys. A signature is valid if:
+//
+//	address(signature * G - e * P) == commitment
+//
+// The devnet holds the private keys of all feeds, so the signature is
+// computed directly instead of running a MuSig session.
+
+// schnorrSign signs the message using aggregated private keys of the given
+// signers.
+func schnorrSign(keys []*wallet.PrivateKey, message types.Hash) (*big.Int, types.Address, error) {
+	curve := gethCrypto.S256()
+	n := curve.Params().N
+	x := new(big.Int)
+	pubKeys := make([]*ecdsa.PublicKey, len(keys))
+	for i, key := range keys {
+		x.Add(x, key.PrivateKey().D)
+		pubKeys[i] = key.PublicKey()
+	}
+	x.Mod(x, n)
+	k, err := rand.Int(rand.Reader, n)
+	if err != nil {
+		return nil, types.ZeroAddress, err
+	}
+	commitment := pointToAddress(curve.ScalarBaseMult(scalarBytes(k)))
+	e := schnorrChallenge(aggregatePublicKeys(pubKeys), message, commitment)
+	sig := new(big.Int).Mul(e, x)
+	sig.Add(sig, k)
+	sig.Mod(sig, n)
+	return sig, commitment, nil
+}
+
+// schnorrVerify verifies the signature of the message created by signers
+// with the given public keys.
+func schnorrVerify(pubKeys []*ecdsa.PublicKey, message types.Hash, sig *big.Int, commitment types.Address) bool {
+	curve := gethCrypto.S256()
+	n := curve.Params().N
+	if len(pubKeys) == 0 || sig == nil || sig.Sign() <= 0 || sig.Cmp(n) >= 0 || commitment.IsZero() {
+		return false
+	}
+	pub := aggregatePublicKeys(pubKeys)
+	e := schnorrChallenge(pub, message, commitment)
+	sx, sy := curve.ScalarBaseMult(scalarBytes(sig))
+	ex, ey := curve.ScalarMult(pub.X, pub.Y, scalarBytes(e))
+	rx, ry := curve.Add(sx, sy, ex, new(big.Int).Sub(curve.Params().P, ey))
+	return pointToAddress(rx, ry) == commitment
+}
+
+// schnorrChallenge returns the challenge e = H(Px ‖ Pparity ‖ message ‖ commitment).
+func schnorrChallenge(pub *ecdsa.PublicKey, message types.Hash, commitment types.Address) *big.Int {
+	data := make([]byte, 32+1+32+20)
+	pub.X.FillBytes(data[0:32])
+	data[32] = byte(pub.Y.Bit(0))
+	copy(data[33:65], message.Bytes())
+	copy(data[65:85], commitment.Bytes())
+	e := new(big.Int).SetBytes(crypto.Keccak256(data).Bytes())
+	return e.Mod(e, gethCrypto.S256().Params().N)
+}
+
+// aggregatePublicKeys returns the sum of the given public keys.
+func aggregatePublicKeys(pubKeys []*ecdsa.PublicKey) *ecdsa.PublicKey {
+	curve := gethCrypto.S256()
+	x, y := pubKeys[0].X, pubKeys[0].Y
+	for _, pub := range pubKeys[1:] {
+		x, y = curve.Add(x, y, pub.X, pub.Y)
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+}
+
+func pointToAddress(x, y *big.Int) types.Address {
+	return crypto.ECPublicKeyToAddress(&ecdsa.PublicKey{Curve: gethCrypto.S256(), X: x, Y: y})
+}
+
+func scalarBytes(k *big.Int) []byte {
+	return k.FillBytes(make([]byte, 32))
+}
@@ -0,0 +1,375 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package devnet
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/defiweb/go-eth/abi"
+	"github.com/defiweb/go-eth/crypto"
+	"github.com/defiweb/go-eth/types"
+
+	"github.com/orcfax/oracle-suite/pkg/contract/chronicle"
+	"github.com/orcfax/oracle-suite/pkg/util/bn"
+)
+
+var scribeABI = abi.MustParseSignatures(
+	`wat()(bytes32 wat)`,
+	`bar()(uint8 bar)`,
+	`feeds()(address[] feeds)`,
+	`opChallengePeriod()(uint16 opChallengePeriod)`,
+	`poke_optimized_7136211(
+		(uint128 val, uint32 age) pokeData,
+		(bytes32 signature, address commitment, bytes feedIDs) schnorrData
+	)`,
+	`opPoke_optimized_397084999(
+		(uint128 val, uint32 age) pokeData,
+		(bytes32 signature, address commitment, bytes feedIDs) schnorrData,
+		(uint8 v, bytes32 r, bytes32 s) ecdsaData
+	)`,
+)
+
+var (
+	// scribePokeSlot is the storage slot in which the Scribe contract stores
+	// the price value and its age.
+	scribePokeSlot = types.MustHashFromBigInt(big.NewInt(4))
+
+	// scribeOpPokeSlot is the storage slot in which the OpScribe contract
+	// stores the optimistically poked price value and its age.
+	scribeOpPokeSlot = types.MustHashFromBigInt(big.NewInt(8))
+)
+
+// scribePokeData is the price value and its age stored by Scribe contracts.
+type scribePokeData struct {
+	val *big.Int
+	age uint64
+}
+
+// Scribe is a stand-in for the Chronicle Scribe contract.
+//
+// It verifies poke calls the same way the Solidity implementation does:
+// the price must be signed with an aggregated Schnorr signature of a bar
+// of distinct authorized feeds, ordered by their feed IDs, and it must be
+// newer than the current price.
+type Scribe struct {
+	mu sync.Mutex
+
+	wat      string
+	bar      int
+	pokeData scribePokeData
+	feeds    map[byte]*ecdsa.PublicKey
+	pokes    int
+}
+
+// NewScribe creates a new Scribe contract for the given asset name and
+// quorum. Feeds are authorized to sign prices.
+func NewScribe(wat string, bar int, feeds ...*ecdsa.PublicKey) *Scribe {
+	s := &Scribe{
+		wat:      wat,
+		bar:      bar,
+		pokeData: scribePokeData{val: new(big.Int)},
+		feeds:    make(map[byte]*ecdsa.PublicKey),
+	}
+	s.Lift(feeds...)
+	return s
+}
+
+// Lift authorizes feeds to sign prices. Feeds are identified by the first
+// byte of their addresses.
+func (s *Scribe) Lift(feeds ...*ecdsa.PublicKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, f := range feeds {
+		s.feeds[crypto.ECPublicKeyToAddress(f)[0]] = f
+	}
+}
+
+// Val returns the current price.
+func (s *Scribe) Val() *bn.DecFixedPointNumber {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return scribeVal(s.pokeData)
+}
+
+// Age returns the time of the last update.
+func (s *Scribe) Age() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return time.Unix(int64(s.pokeData.age), 0)
+}
+
+// Pokes returns the number of successful poke calls.
+func (s *Scribe) Pokes() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pokes
+}
+
+// Call implements the Contract interface.
+func (s *Scribe) Call(env Env, input []byte, commit bool) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.call(env, input, commit)
+}
+
+// Storage implements the Contract interface.
+func (s *Scribe) Storage(slot types.Hash) types.Hash {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if slot == scribePokeSlot {
+		return scribeStorage(s.pokeData)
+	}
+	return types.Hash{}
+}
+
+func (s *Scribe) call(env Env, input []byte, commit bool) ([]byte, error) {
+	if len(input) < 4 {
+		return nil, Revert("Scribe/invalid-call")
+	}
+	switch {
+	case scribeABI.Methods["wat"].FourBytes().Match(input):
+		var wat [32]byte
+		copy(wat[:], s.wat)
+		return abi.EncodeValues(scribeABI.Methods["wat"].Outputs(), wat)
+	case scribeABI.Methods["bar"].FourBytes().Match(input):
+		return abi.EncodeValues(scribeABI.Methods["bar"].Outputs(), uint8(s.bar))
+	case scribeABI.Methods["feeds"].FourBytes().Match(input):
+		var feeds []types.Address
+		for _, f := range s.feeds {
+			feeds = append(feeds, crypto.ECPublicKeyToAddress(f))
+		}
+		return abi.EncodeValues(scribeABI.Methods["feeds"].Outputs(), feeds)
+	case scribeABI.Methods["poke_optimized_7136211"].FourBytes().Match(input):
+		return nil, s.poke(env, input, s.pokeData, commit)
+	}
+	return nil, Revert("Scribe/invalid-call")
+}
+
+// poke verifies that the poke data is newer than the current one and
+// signed by a bar of authorized feeds.
+func (s *Scribe) poke(env Env, input []byte, current scribePokeData, commit bool) error {
+	var (
+		pokeData    chronicle.PokeDataStruct
+		schnorrData chronicle.SchnorrDataStruct
+	)
+	if err := scribeABI.Methods["poke_optimized_7136211"].DecodeArgs(input, &pokeData, &schnorrData); err != nil {
+		return Revert("Scribe/invalid-call")
+	}
+	if uint64(pokeData.Age) <= current.age {
+		return Revert("Scribe/stale-message")
+	}
+	if int64(pokeData.Age) > env.Time.Unix() {
+		return Revert("Scribe/future-message")
+	}
+	if pokeData.Val.BitLen() > 128 {
+		return Revert("Scribe/val-overflow")
+	}
+	if len(schnorrData.FeedIDs) != s.bar {
+		return Revert("Scribe/bar-not-reached")
+	}
+	pubKeys := make([]*ecdsa.PublicKey, len(schnorrData.FeedIDs))
+	for i, id := range schnorrData.FeedIDs {
+		if i > 0 && id <= schnorrData.FeedIDs[i-1] {
+			return Revert("Scribe/signers-not-ordered")
+		}
+		if pubKeys[i] = s.feeds[id]; pubKeys[i] == nil {
+			return Revert("Scribe/signer-not-feed")
+		}
+	}
+	message := chronicle.ConstructScribePokeMessage(s.wat, chronicle.PokeData{
+		Val: bn.DecFixedPointFromRawBigInt(pokeData.Val, chronicle.ScribePricePrecision),
+		Age: time.Unix(int64(pokeData.Age), 0),
+	})
+	if !schnorrVerify(pubKeys, types.MustHashFromBytes(message, types.PadNone), schnorrData.Signature, schnorrData.Commitment) {
+		return Revert("Scribe/schnorr-signature-invalid")
+	}
+	if commit {
+		s.pokeData = scribePokeData{val: pokeData.Val, age: uint64(env.Time.Unix())}
+		s.pokes++
+	}
+	return nil
+}
+
+// OpScribe is a stand-in for the Chronicle ScribeOptimistic contract.
+//
+// In addition to regular pokes, it accepts optimistic pokes signed with
+// ECDSA by a single authorized feed. The Schnorr signature of an optimistic
+// poke is not verified. The optimistically poked price becomes the current
+// price after the challenge period, unless it is overwritten by a regular
+// poke.
+type OpScribe struct {
+	Scribe
+
+	opChallengePeriod time.Duration
+	opPokeData        scribePokeData
+	opPokes           int
+	recover           crypto.Recoverer
+}
+
+// NewOpScribe creates a new OpScribe contract for the given asset name,
+// quorum and challenge period. Feeds are authorized to sign prices.
+func NewOpScribe(wat string, bar int, opChallengePeriod time.Duration, feeds ...*ecdsa.PublicKey) *OpScribe {
+	return &OpScribe{
+		Scribe:            *NewScribe(wat, bar, feeds...),
+		opChallengePeriod: opChallengePeriod,
+		opPokeData:        scribePokeData{val: new(big.Int)},
+		recover:           crypto.ECRecoverer,
+	}
+}
+
+// Val returns the current price, including the finalized optimistic price.
+func (s *OpScribe) Val() *bn.DecFixedPointNumber {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return scribeVal(s.currentPokeData(time.Now()))
+}
+
+// Age returns the time of the last update, including the finalized
+// optimistic update.
+func (s *OpScribe) Age() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return time.Unix(int64(s.currentPokeData(time.Now()).age), 0)
+}
+
+// OpPokes returns the number of successful opPoke calls.
+func (s *OpScribe) OpPokes() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.opPokes
+}
+
+// Call implements the Contract interface.
+func (s *OpScribe) Call(env Env, input []byte, commit bool) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(input) < 4 {
+		return nil, Revert("OpScribe/invalid-call")
+	}
+	switch {
+	case scribeABI.Methods["opChallengePeriod"].FourBytes().Match(input):
+		return abi.EncodeValues(
+			scribeABI.Methods["opChallengePeriod"].Outputs(),
+			uint16(s.opChallengePeriod/time.Second),
+		)
+	case scribeABI.Methods["poke_optimized_7136211"].FourBytes().Match(input):
+		// A regular poke is verified against the current price, including
+		// the finalized optimistic one. The non-finalized optimistic price
+		// is dropped.
+		if err := s.poke(env, input, s.currentPokeData(env.Time), commit); err != nil {
+			return nil, err
+		}
+		if commit {
+			s.opPokeData = scribePokeData{val: new(big.Int)}
+		}
+		return nil, nil
+	case scribeABI.Methods["opPoke_optimized_397084999"].FourBytes().Match(input):
+		return nil, s.opPoke(env, input, commit)
+	}
+	return s.call(env, input, commit)
+}
+
+// Storage implements the Contract interface.
+func (s *OpScribe) Storage(slot types.Hash) types.Hash {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch slot {
+	case scribePokeSlot:
+		return scribeStorage(s.pokeData)
+	case scribeOpPokeSlot:
+		return scribeStorage(s.opPokeData)
+	}
+	return types.Hash{}
+}
+
+func (s *OpScribe) opPoke(env Env, input []byte, commit bool) error {
+	var (
+		pokeData    chronicle.PokeDataStruct
+		schnorrData chronicle.SchnorrDataStruct
+		ecdsaData   chronicle.ECDSADataStruct
+	)
+	if err := scribeABI.Methods["opPoke_optimized_397084999"].DecodeArgs(input, &pokeData, &schnorrData, &ecdsaData); err != nil {
+		return Revert("OpScribe/invalid-call")
+	}
+	if !s.finalized(env.Time) {
+		return Revert("OpScribe/in-challenge-period")
+	}
+	current := s.currentPokeData(env.Time)
+	if uint64(pokeData.Age) <= current.age {
+		return Revert("OpScribe/stale-message")
+	}
+	if int64(pokeData.Age) > env.Time.Unix() {
+		return Revert("OpScribe/future-message")
+	}
+	if len(schnorrData.FeedIDs) != s.bar {
+		return Revert("OpScribe/bar-not-reached")
+	}
+	pd := chronicle.PokeData{
+		Val: bn.DecFixedPointFromRawBigInt(pokeData.Val, chronicle.ScribePricePrecision),
+		Age: time.Unix(int64(pokeData.Age), 0),
+	}
+	sd := chronicle.SchnorrData{
+		Signature:  schnorrData.Signature,
+		Commitment: schnorrData.Commitment,
+		FeedIDs:    chronicle.FeedIDsFromIDs(schnorrData.FeedIDs),
+	}
+	sig := types.SignatureFromVRS(
+		big.NewInt(int64(ecdsaData.V)),
+		ecdsaData.R,
+		ecdsaData.S,
+	)
+	signer, err := s.recover.RecoverMessage(chronicle.ConstructScribeOpPokeMessage(s.wat, pd, sd, sd.FeedIDs), sig)
+	if err != nil || s.feeds[signer[0]] == nil || crypto.ECPublicKeyToAddress(s.feeds[signer[0]]) != *signer {
+		return Revert("OpScribe/signer-not-feed")
+	}
+	if commit {
+		s.pokeData = current
+		s.opPokeData = scribePokeData{val: pokeData.Val, age: uint64(env.Time.Unix())}
+		s.opPokes++
+	}
+	return nil
+}
+
+// finalized returns true if the challenge period of the last optimistic
+// poke is over.
+func (s *OpScribe) finalized(t time.Time) bool {
+	return time.Unix(int64(s.opPokeData.age), 0).Add(s.opChallengePeriod).Before(t)
+}
+
+// currentPokeData returns the newer of the regular and the finalized
+// optimistic poke data.
+func (s *OpScribe) currentPokeData(t time.Time) scribePokeData {
+	if s.finalized(t) && s.opPokeData.age > s.pokeData.age {
+		return s.opPokeData
+	}
+	return s.pokeData
+}
+
+func scribeVal(pd scribePokeData) *bn.DecFixedPointNumber {
+	return bn.DecFixedPointFromRawBigInt(new(big.Int).Set(pd.val), chronicle.ScribePricePrecision)
+}
+
+func scribeStorage(pd scribePokeData) types.Hash {
+	// The val (uint128) and age (uint32) variables are packed into a single
+	// slot.
+	var h types.Hash
+	new(big.Int).SetUint64(pd.age).FillBytes(h[0:16])
+	pd.val.FillBytes(h[16:32])
+	return h
+}
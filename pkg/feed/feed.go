@@ -22,7 +22,9 @@ import (
 
 	"github.com/orcfax/oracle-suite/pkg/datapoint"
 	"github.com/orcfax/oracle-suite/pkg/log/null"
+	"github.com/orcfax/oracle-suite/pkg/tracing"
 	"github.com/orcfax/oracle-suite/pkg/transport/messages"
+	"github.com/orcfax/oracle-suite/pkg/util/maputil"
 	"github.com/orcfax/oracle-suite/pkg/util/sliceutil"
 	"github.com/orcfax/oracle-suite/pkg/util/timeutil"
 
@@ -142,7 +144,10 @@ func (f *Feed) Wait() <-chan error {
 }
 
 // broadcast sends data point to the network.
-func (f *Feed) broadcast(model string, point datapoint.Point) {
+func (f *Feed) broadcast(ctx context.Context, model string, point datapoint.Point) {
+	ctx, span := tracing.StartSpan(ctx, "feed.broadcast")
+	defer span.End()
+	span.SetAttribute("model", model)
 	found := false
 	for _, signer := range f.signers {
 		if !signer.Supports(f.ctx, point) {
@@ -187,13 +192,25 @@ func (f *Feed) broadcast(model string, point datapoint.Point) {
 			}
 		}
 
-		// Broadcast data point.
+		// Broadcast data point. The trace context is added to the metadata
+		// after signing, so it does not affect the signature. The metadata
+		// map is shared with the data provider, so it is copied first.
+		_, sendSpan := tracing.StartSpan(ctx, "transport.send", tracing.WithKind(tracing.SpanKindProducer))
+		sendSpan.SetAttribute("topic", messages.DataPointV1MessageName)
+		if sendSpan != nil {
+			point.Meta = maputil.Copy(point.Meta)
+			tracing.Inject(point.Meta, sendSpan.SpanContext())
+		}
 		msg := &messages.DataPoint{
 			Model:          model,
 			Point:          point,
 			ECDSASignature: *sig,
 		}
-		if err := f.transport.Broadcast(messages.DataPointV1MessageName, msg); err != nil {
+		err = f.transport.Broadcast(messages.DataPointV1MessageName, msg)
+		sendSpan.SetError(err)
+		sendSpan.End()
+		if err != nil {
+			span.SetError(err)
 			f.log.
 				WithError(err).
 				WithFields(messages.DataPointMessageLogFields(*msg)).
@@ -220,45 +237,58 @@ func (f *Feed) broadcasterRoutine() {
 		case <-f.ctx.Done():
 			return
 		case <-f.interval.TickCh():
-			// Fetch data points from the data provider.
-			models := sliceutil.Intersect(
-				f.dataProvider.ModelNames(f.ctx),
-				f.dataModels,
-			)
-			points, err := f.dataProvider.DataPoints(f.ctx, models...)
-			if err != nil {
+			f.tick()
+		}
+	}
+}
+
+// tick fetches data points from the data provider and broadcasts them.
+//
+// Every tick starts a new trace, its ID is added to the metadata of
+// broadcast data points.
+func (f *Feed) tick() {
+	ctx, span := tracing.StartSpan(f.ctx, "feed.tick")
+	defer span.End()
+
+	// Fetch data points from the data provider.
+	models := sliceutil.Intersect(
+		f.dataProvider.ModelNames(ctx),
+		f.dataModels,
+	)
+	span.SetAttribute("models", len(models))
+	points, err := f.dataProvider.DataPoints(ctx, models...)
+	if err != nil {
+		span.SetError(err)
+		f.log.
+			WithError(err).
+			WithField("models", models).
+			Error("Failed to fetch data points from provider")
+		return
+	}
+
+	// Send data points to the network.
+	for model, point := range points {
+		if err := point.Validate(); err != nil {
+			if log.IsLevel(f.log, log.Debug) {
+				trace, _ := json.Marshal(point)
 				f.log.
 					WithError(err).
-					WithField("models", models).
-					Error("Failed to fetch data points from provider")
-				continue
-			}
-
-			// Send data points to the network.
-			for model, point := range points {
-				if err := point.Validate(); err != nil {
-					if log.IsLevel(f.log, log.Debug) {
-						trace, _ := json.Marshal(point)
-						f.log.
-							WithError(err).
-							WithField("model", model).
-							WithField("trace", string(trace)).
-							WithFields(datapoint.PointLogFields(point)).
-							WithAdvice("Ignore if this occurs occasionally").
-							Warn("Data point is invalid; it will be skipped")
-					} else {
-						f.log.
-							WithError(err).
-							WithField("model", model).
-							WithFields(datapoint.PointLogFields(point)).
-							WithAdvice("Ignore if this occurs occasionally").
-							Warn("Data point is invalid; it will be skipped")
-					}
-					continue
-				}
-				f.broadcast(model, point)
+					WithField("model", model).
+					WithField("trace", string(trace)).
+					WithFields(datapoint.PointLogFields(point)).
+					WithAdvice("Ignore if this occurs occasionally").
+					Warn("Data point is invalid; it will be skipped")
+			} else {
+				f.log.
+					WithError(err).
+					WithField("model", model).
+					WithFields(datapoint.PointLogFields(point)).
+					WithAdvice("Ignore if this occurs occasionally").
+					Warn("Data point is invalid; it will be skipped")
 			}
+			continue
 		}
+		f.broadcast(ctx, model, point)
 	}
 }
 
@@ -18,6 +18,7 @@ package feed
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
@@ -29,6 +30,7 @@ import (
 	"github.com/orcfax/oracle-suite/pkg/datapoint"
 	dataMocks "github.com/orcfax/oracle-suite/pkg/datapoint/mocks"
 	"github.com/orcfax/oracle-suite/pkg/datapoint/value"
+	"github.com/orcfax/oracle-suite/pkg/tracing"
 	"github.com/orcfax/oracle-suite/pkg/transport"
 	"github.com/orcfax/oracle-suite/pkg/transport/local"
 	"github.com/orcfax/oracle-suite/pkg/transport/messages"
@@ -180,6 +182,91 @@ func TestFeed_Broadcast(t *testing.T) {
 	}
 }
 
+type exporterMock struct {
+	data [][]byte
+}
+
+func (e *exporterMock) Export(_ context.Context, data []byte) error {
+	e.data = append(e.data, data)
+	return nil
+}
+
+func TestFeed_Trace(t *testing.T) {
+	ctx, ctxCancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer ctxCancel()
+
+	exporter := &exporterMock{}
+	tracer, err := tracing.New(tracing.Config{Exporter: exporter})
+	require.NoError(t, err)
+	tracing.SetDefault(tracer)
+	defer tracing.SetDefault(nil)
+
+	// Setup test environment.
+	ticker := timeutil.NewTicker(0)
+	dataProvider := &dataMocks.Provider{}
+	localTransport := local.New([]byte("test"), 0, map[string]transport.Message{
+		messages.DataPointV1MessageName: (*messages.DataPoint)(nil),
+	})
+	meta := map[string]any{"type": "static"}
+	dataProvider.On("ModelNames", mock.Anything).Return([]string{"AAABBB"})
+	dataProvider.On("DataPoints", mock.Anything, []string{"AAABBB"}).Return(
+		map[string]datapoint.Point{"AAABBB": {
+			Value: value.StaticValue{Value: bn.DecFloatPoint(42)},
+			Time:  time.Unix(100, 0),
+			Meta:  meta,
+		}},
+		nil,
+	)
+
+	// Start feed.
+	feed, err := New(Config{
+		DataModels:   []string{"AAABBB"},
+		DataProvider: dataProvider,
+		Signers:      []datapoint.Signer{mockSigner{}},
+		Transport:    localTransport,
+		Interval:     ticker,
+	})
+	require.NoError(t, err)
+	require.NoError(t, localTransport.Start(ctx))
+	require.NoError(t, feed.Start(ctx))
+	defer func() {
+		ctxCancel()
+		<-feed.Wait()
+		<-localTransport.Wait()
+	}()
+
+	// Wait for services to start.
+	time.Sleep(time.Millisecond * 100)
+
+	ticker.Tick()
+	msg := <-localTransport.Messages(messages.DataPointV1MessageName)
+	dp := msg.Message.(*messages.DataPoint)
+
+	// The data point must carry the context of the transport.send span.
+	sc, ok := tracing.Extract(dp.Point.Meta)
+	require.True(t, ok)
+	assert.Equal(t, "static", dp.Point.Meta["type"])
+
+	// The metadata returned by the data provider must not be modified.
+	_, ok = tracing.Extract(meta)
+	assert.False(t, ok)
+
+	// The feed.tick span ends after the data point is broadcast.
+	var spans string
+	assert.Eventually(t, func() bool {
+		require.NoError(t, tracer.Flush(ctx))
+		for _, data := range exporter.data {
+			spans += string(data)
+		}
+		exporter.data = nil
+		return strings.Contains(spans, `"name":"feed.tick"`)
+	}, time.Second, time.Millisecond*10)
+	for _, name := range []string{"feed.tick", "feed.broadcast", "transport.send"} {
+		assert.Contains(t, spans, `"name":"`+name+`"`)
+	}
+	assert.Contains(t, spans, `"traceId":"`+sc.TraceID.String()+`","spanId":"`+sc.SpanID.String()+`"`)
+}
+
 func TestFeed_Start(t *testing.T) {
 	ctx, ctxCancel := context.WithTimeout(context.Background(), time.Second*10)
 	defer ctxCancel()
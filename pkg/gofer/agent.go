@@ -0,0 +1,411 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package gofer provides an HTTP API for data providers.
+package gofer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/orcfax/oracle-suite/pkg/datapoint"
+	"github.com/orcfax/oracle-suite/pkg/datapoint/graph"
+	"github.com/orcfax/oracle-suite/pkg/httpserver"
+	"github.com/orcfax/oracle-suite/pkg/httpserver/middleware"
+	"github.com/orcfax/oracle-suite/pkg/log"
+	"github.com/orcfax/oracle-suite/pkg/log/null"
+)
+
+const AgentLoggerTag = "GOFER_AGENT"
+
+const (
+	// defaultHTTPTimeout is the default timeout for the HTTP server.
+	defaultHTTPTimeout = 10 * time.Second
+
+	// defaultStreamInterval is the default interval at which data points
+	// are checked for changes by the stream endpoint.
+	defaultStreamInterval = 5 * time.Second
+
+	// defaultMaxStreams is the default maximum number of concurrent
+	// stream connections.
+	defaultMaxStreams = 100
+
+	// HealthCheckPath is the path of the health check endpoint.
+	HealthCheckPath = "/healthcheck"
+)
+
+// Agent exposes a data provider over an HTTP API.
+//
+// The following endpoints are available, all of them accept an optional
+// "model" query parameter, which may be repeated or contain a comma
+// separated list of model names. If omitted, all models are used:
+//
+//	GET /v1/models - list of model names
+//	GET /v1/graphs - model graphs, formats: json (default), trace, plain
+//	GET /v1/data   - data points, formats: json (default), plain, trace, orcfax
+//	GET /v1/stream - server-sent events with data points, sent every time
+//	                 they change, formats are the same as for /v1/data
+//
+// The format can be selected using the "format" query parameter.
+//
+// Streams requesting the same models share a single poller, so data points
+// are fetched once per interval regardless of the number of clients.
+type Agent struct {
+	ctx    context.Context
+	waitCh chan error
+
+	provider datapoint.Provider
+	streams  *streams
+	srv      *httpserver.HTTPServer
+	log      log.Logger
+}
+
+// AgentConfig is the configuration for the Agent.
+type AgentConfig struct {
+	// DataProvider is the data provider exposed by the API.
+	DataProvider datapoint.Provider
+
+	// Address is the address on which the HTTP server listens.
+	Address string
+
+	// StreamInterval is the interval at which data points are checked for
+	// changes by the stream endpoint. If zero, five seconds is used.
+	StreamInterval time.Duration
+
+	// MaxStreams is the maximum number of concurrent stream connections.
+	// If zero, 100 is used.
+	MaxStreams int
+
+	// CORSOrigin is the value of the Access-Control-Allow-Origin header.
+	// If empty, "*" is used.
+	CORSOrigin string
+
+	// Logger is a current logger interface used by the Agent.
+	// If nil, null logger will be used.
+	Logger log.Logger
+}
+
+// NewAgent creates a new Agent instance.
+func NewAgent(cfg AgentConfig) (*Agent, error) {
+	if cfg.DataProvider == nil {
+		return nil, errors.New("data provider must not be nil")
+	}
+	if cfg.StreamInterval == 0 {
+		cfg.StreamInterval = defaultStreamInterval
+	}
+	if cfg.MaxStreams < 0 {
+		return nil, errors.New("max streams must not be negative")
+	}
+	if cfg.MaxStreams == 0 {
+		cfg.MaxStreams = defaultMaxStreams
+	}
+	if cfg.CORSOrigin == "" {
+		cfg.CORSOrigin = "*"
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = null.New()
+	}
+	logger := cfg.Logger.WithField("tag", AgentLoggerTag)
+	a := &Agent{
+		waitCh:   make(chan error),
+		provider: cfg.DataProvider,
+		streams:  newStreams(cfg.DataProvider, cfg.StreamInterval, cfg.MaxStreams, logger),
+		log:      logger,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/models", a.handleModels)
+	mux.HandleFunc("/v1/graphs", a.handleGraphs)
+	mux.HandleFunc("/v1/data", a.handleData)
+	mux.HandleFunc("/v1/stream", a.handleStream)
+	a.srv = httpserver.New(&http.Server{
+		Addr:              cfg.Address,
+		Handler:           mux,
+		IdleTimeout:       defaultHTTPTimeout,
+		ReadTimeout:       defaultHTTPTimeout,
+		ReadHeaderTimeout: defaultHTTPTimeout,
+		// WriteTimeout is not set, because it would terminate
+		// long-lived stream connections.
+	})
+	a.srv.Use(
+		&middleware.HealthCheck{Path: HealthCheckPath},
+		&middleware.CORS{
+			Origin:  func(*http.Request) string { return cfg.CORSOrigin },
+			Headers: func(*http.Request) string { return "Content-Type" },
+			Methods: func(*http.Request) string { return "GET, OPTIONS" },
+		},
+		&middleware.Logger{Log: a.log},
+		&middleware.Recover{Recover: func(err any) {
+			a.log.WithField("panic", err).Error("Panic while handling HTTP request")
+		}},
+	)
+	return a, nil
+}
+
+// Start implements the supervisor.Service interface.
+func (a *Agent) Start(ctx context.Context) error {
+	if a.ctx != nil {
+		return errors.New("service can be started only once")
+	}
+	if ctx == nil {
+		return errors.New("context must not be nil")
+	}
+	a.log.Debug("Starting")
+	a.ctx = ctx
+	if err := a.srv.Start(ctx); err != nil {
+		return fmt.Errorf("unable to start the HTTP server: %w", err)
+	}
+	go a.contextCancelHandler()
+	return nil
+}
+
+// Wait implements the supervisor.Service interface.
+func (a *Agent) Wait() <-chan error {
+	return a.waitCh
+}
+
+// Addr returns the address on which the agent listens for connections.
+// It returns nil if the agent is not started.
+func (a *Agent) Addr() net.Addr {
+	return a.srv.Addr()
+}
+
+func (a *Agent) handleModels(rw http.ResponseWriter, r *http.Request) {
+	if !allowGet(rw, r) {
+		return
+	}
+	models, ok := a.modelNames(rw, r)
+	if !ok {
+		return
+	}
+	switch format(r, FormatJSON) {
+	case FormatJSON:
+		writeResponse(rw, http.StatusOK, FormatJSON, mustMarshalJSON(models))
+	case FormatPlain:
+		writeResponse(rw, http.StatusOK, FormatPlain, []byte(strings.Join(models, "\n")))
+	default:
+		writeError(rw, http.StatusBadRequest, fmt.Errorf("unsupported format: %s", format(r, "")))
+	}
+}
+
+func (a *Agent) handleGraphs(rw http.ResponseWriter, r *http.Request) {
+	if !allowGet(rw, r) {
+		return
+	}
+	names, ok := a.modelNames(rw, r)
+	if !ok {
+		return
+	}
+	models, err := a.provider.Models(r.Context(), names...)
+	if err != nil {
+		writeProviderError(rw, err)
+		return
+	}
+	f := format(r, FormatJSON)
+	b, err := MarshalModels(models, f)
+	if err != nil {
+		writeError(rw, http.StatusBadRequest, err)
+		return
+	}
+	writeResponse(rw, http.StatusOK, f, b)
+}
+
+func (a *Agent) handleData(rw http.ResponseWriter, r *http.Request) {
+	if !allowGet(rw, r) {
+		return
+	}
+	names, ok := a.modelNames(rw, r)
+	if !ok {
+		return
+	}
+	f := format(r, FormatJSON)
+	b, err := a.dataPoints(r.Context(), names, f)
+	if err != nil {
+		writeProviderError(rw, err)
+		return
+	}
+	writeResponse(rw, http.StatusOK, f, b)
+}
+
+func (a *Agent) handleStream(rw http.ResponseWriter, r *http.Request) {
+	if !allowGet(rw, r) {
+		return
+	}
+	flusher, ok := rw.(http.Flusher)
+	if !ok {
+		writeError(rw, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+	names, ok := a.modelNames(rw, r)
+	if !ok {
+		return
+	}
+	f := format(r, FormatJSON)
+	if _, err := MarshalDataPoints(nil, f); err != nil {
+		writeError(rw, http.StatusBadRequest, err)
+		return
+	}
+	updates, unsubscribe, err := a.streams.subscribe(a.ctx, names)
+	if err != nil {
+		writeError(rw, http.StatusServiceUnavailable, err)
+		return
+	}
+	defer unsubscribe()
+	rw.Header().Set("Content-Type", "text/event-stream")
+	rw.Header().Set("Cache-Control", "no-cache")
+	rw.Header().Set("Connection", "keep-alive")
+	rw.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	var (
+		id   int
+		last []byte
+	)
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-a.ctx.Done():
+			return
+		case u := <-updates:
+			data, err := u.marshal(f)
+			switch {
+			case err != nil:
+				writeEvent(rw, id, "error", []byte(err.Error()))
+				id++
+			case !bytes.Equal(data, last):
+				writeEvent(rw, id, "data", data)
+				last = data
+				id++
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// modelNames returns the model names given in the request or all
+// models if none were given. If the request contains unknown models,
+// it writes an error response and returns false.
+func (a *Agent) modelNames(rw http.ResponseWriter, r *http.Request) ([]string, bool) {
+	var models []string
+	for _, m := range r.URL.Query()["model"] {
+		for _, m := range strings.Split(m, ",") {
+			if m = strings.TrimSpace(m); m != "" {
+				models = append(models, m)
+			}
+		}
+	}
+	all := a.provider.ModelNames(r.Context())
+	if len(models) == 0 {
+		return all, true
+	}
+	known := make(map[string]bool, len(all))
+	for _, m := range all {
+		known[m] = true
+	}
+	for _, m := range models {
+		if !known[m] {
+			writeError(rw, http.StatusNotFound, fmt.Errorf("model %s not found", m))
+			return nil, false
+		}
+	}
+	return models, true
+}
+
+func (a *Agent) dataPoints(ctx context.Context, models []string, format string) ([]byte, error) {
+	points, err := a.provider.DataPoints(ctx, models...)
+	if err != nil {
+		return nil, err
+	}
+	return MarshalDataPoints(points, format)
+}
+
+func (a *Agent) contextCancelHandler() {
+	defer func() { close(a.waitCh) }()
+	defer a.log.Debug("Stopped")
+	<-a.ctx.Done()
+	<-a.srv.Wait()
+}
+
+func allowGet(rw http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		rw.WriteHeader(http.StatusMethodNotAllowed)
+		return false
+	}
+	return true
+}
+
+func format(r *http.Request, def string) string {
+	if f := r.URL.Query().Get("format"); f != "" {
+		return strings.ToLower(f)
+	}
+	return def
+}
+
+func contentType(format string) string {
+	switch format {
+	case FormatJSON, FormatOrcfax:
+		return "application/json"
+	default:
+		return "text/plain; charset=utf-8"
+	}
+}
+
+func writeResponse(rw http.ResponseWriter, status int, format string, b []byte) {
+	rw.Header().Set("Content-Type", contentType(format))
+	rw.WriteHeader(status)
+	_, _ = rw.Write(b)
+}
+
+func writeError(rw http.ResponseWriter, status int, err error) {
+	writeResponse(rw, status, FormatJSON, mustMarshalJSON(map[string]string{"error": err.Error()}))
+}
+
+func writeProviderError(rw http.ResponseWriter, err error) {
+	var notFound graph.ErrModelNotFound
+	if errors.As(err, &notFound) {
+		writeError(rw, http.StatusNotFound, err)
+		return
+	}
+	writeError(rw, http.StatusInternalServerError, err)
+}
+
+// writeEvent writes a server-sent event. Multi-line data is split into
+// multiple data fields, as required by the specification.
+func writeEvent(rw http.ResponseWriter, id int, event string, data []byte) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "id: %d\nevent: %s\n", id, event)
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		buf.WriteString("data: ")
+		buf.Write(line)
+		buf.WriteString("\n")
+	}
+	buf.WriteString("\n")
+	_, _ = rw.Write(buf.Bytes())
+}
+
+func mustMarshalJSON(v any) []byte {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
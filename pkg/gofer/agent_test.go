@@ -0,0 +1,271 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gofer
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/orcfax/oracle-suite/pkg/datapoint"
+	"github.com/orcfax/oracle-suite/pkg/datapoint/value"
+	"github.com/orcfax/oracle-suite/pkg/util/bn"
+)
+
+type testProvider struct {
+	mu     sync.Mutex
+	prices map[string]float64
+}
+
+func (p *testProvider) setPrice(model string, price float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.prices[model] = price
+}
+
+func (p *testProvider) ModelNames(_ context.Context) []string {
+	return []string{"AAA/BBB", "CCC/DDD"}
+}
+
+func (p *testProvider) DataPoint(ctx context.Context, model string) (datapoint.Point, error) {
+	points, err := p.DataPoints(ctx, model)
+	if err != nil {
+		return datapoint.Point{}, err
+	}
+	return points[model], nil
+}
+
+func (p *testProvider) DataPoints(_ context.Context, models ...string) (map[string]datapoint.Point, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	points := make(map[string]datapoint.Point)
+	for _, m := range models {
+		price, ok := p.prices[m]
+		if !ok {
+			return nil, fmt.Errorf("unknown model %s", m)
+		}
+		pair, _ := value.PairFromString(m)
+		points[m] = datapoint.Point{
+			Value: value.Tick{Pair: pair, Price: bn.DecFloatPoint(price)},
+			Time:  time.Unix(1700000000, 0),
+		}
+	}
+	return points, nil
+}
+
+func (p *testProvider) Model(ctx context.Context, model string) (datapoint.Model, error) {
+	models, err := p.Models(ctx, model)
+	if err != nil {
+		return datapoint.Model{}, err
+	}
+	return models[model], nil
+}
+
+func (p *testProvider) Models(_ context.Context, models ...string) (map[string]datapoint.Model, error) {
+	res := make(map[string]datapoint.Model)
+	for _, m := range models {
+		res[m] = datapoint.Model{Meta: map[string]any{"type": "origin", "origin": "test"}}
+	}
+	return res, nil
+}
+
+func startTestAgent(t *testing.T) (*testProvider, string) {
+	ctx, ctxCancel := context.WithCancel(context.Background())
+	t.Cleanup(ctxCancel)
+	provider := &testProvider{prices: map[string]float64{"AAA/BBB": 1.5, "CCC/DDD": 2}}
+	agent, err := NewAgent(AgentConfig{
+		DataProvider:   provider,
+		Address:        "127.0.0.1:0",
+		StreamInterval: 10 * time.Millisecond,
+		MaxStreams:     2,
+	})
+	require.NoError(t, err)
+	require.NoError(t, agent.Start(ctx))
+	return provider, "http://" + agent.Addr().String()
+}
+
+func get(t *testing.T, url string) (int, http.Header, string) {
+	res, err := http.Get(url) //nolint:gosec
+	require.NoError(t, err)
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	return res.StatusCode, res.Header, string(body)
+}
+
+func TestAgent(t *testing.T) {
+	_, url := startTestAgent(t)
+
+	tests := []struct {
+		name        string
+		path        string
+		status      int
+		contentType string
+		body        string
+	}{
+		{
+			name:        "models",
+			path:        "/v1/models",
+			status:      http.StatusOK,
+			contentType: "application/json",
+			body:        `["AAA/BBB","CCC/DDD"]`,
+		},
+		{
+			name:        "models-plain",
+			path:        "/v1/models?format=plain",
+			status:      http.StatusOK,
+			contentType: "text/plain; charset=utf-8",
+			body:        "AAA/BBB\nCCC/DDD",
+		},
+		{
+			name:        "graphs",
+			path:        "/v1/graphs?model=AAA/BBB",
+			status:      http.StatusOK,
+			contentType: "application/json",
+			body:        `{"AAA/BBB":{"models":null,"origin":"test","type":"origin"}}`,
+		},
+		{
+			name:        "data-plain",
+			path:        "/v1/data?model=AAA/BBB,CCC/DDD&format=plain",
+			status:      http.StatusOK,
+			contentType: "text/plain; charset=utf-8",
+			body:        "AAA/BBB: Pair=AAA/BBB, Price=1.5, Volume24h=<nil>\nCCC/DDD: Pair=CCC/DDD, Price=2, Volume24h=<nil>",
+		},
+		{
+			name:   "unknown-model",
+			path:   "/v1/data?model=XXX/YYY",
+			status: http.StatusNotFound,
+			body:   `{"error":"model XXX/YYY not found"}`,
+		},
+		{
+			name:   "unsupported-format",
+			path:   "/v1/graphs?format=orcfax",
+			status: http.StatusBadRequest,
+			body:   `{"error":"unsupported format: orcfax"}`,
+		},
+		{
+			name:   "healthcheck",
+			path:   HealthCheckPath,
+			status: http.StatusOK,
+		},
+		{
+			name:   "not-found",
+			path:   "/v1/unknown",
+			status: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, headers, body := get(t, url+tt.path)
+			assert.Equal(t, tt.status, status)
+			if tt.contentType != "" {
+				assert.Equal(t, tt.contentType, headers.Get("Content-Type"))
+			}
+			if tt.body != "" {
+				assert.Equal(t, tt.body, body)
+			}
+		})
+	}
+}
+
+func TestAgent_Data(t *testing.T) {
+	_, url := startTestAgent(t)
+
+	status, headers, body := get(t, url+"/v1/data")
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "*", headers.Get("Access-Control-Allow-Origin"))
+
+	var points map[string]map[string]any
+	require.NoError(t, json.Unmarshal([]byte(body), &points))
+	require.Len(t, points, 2)
+	assert.Equal(t, "2023-11-14T22:13:20Z", points["AAA/BBB"]["time"])
+}
+
+func TestAgent_MethodNotAllowed(t *testing.T) {
+	_, url := startTestAgent(t)
+
+	res, err := http.Post(url+"/v1/data", "application/json", nil) //nolint:gosec
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, res.StatusCode)
+}
+
+func TestAgent_Stream(t *testing.T) {
+	provider, url := startTestAgent(t)
+
+	res, err := http.Get(url + "/v1/stream?model=AAA/BBB&format=plain") //nolint:gosec
+	require.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+
+	r := bufio.NewReader(res.Body)
+	readEvent := func() []string {
+		var lines []string
+		for {
+			line, err := r.ReadString('\n')
+			require.NoError(t, err)
+			line = strings.TrimSuffix(line, "\n")
+			if line == "" {
+				return lines
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	assert.Equal(t, []string{
+		"id: 0",
+		"event: data",
+		"data: AAA/BBB: Pair=AAA/BBB, Price=1.5, Volume24h=<nil>",
+	}, readEvent())
+
+	// The next event must be sent only after the data point changes.
+	provider.setPrice("AAA/BBB", 3)
+	assert.Equal(t, []string{
+		"id: 1",
+		"event: data",
+		"data: AAA/BBB: Pair=AAA/BBB, Price=3, Volume24h=<nil>",
+	}, readEvent())
+}
+
+func TestAgent_StreamLimit(t *testing.T) {
+	_, url := startTestAgent(t)
+
+	for i := 0; i < 2; i++ {
+		res, err := http.Get(url + "/v1/stream?model=AAA/BBB") //nolint:gosec
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+	}
+
+	status, _, body := get(t, url+"/v1/stream?model=AAA/BBB")
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Equal(t, `{"error":"too many concurrent streams"}`, body)
+}
+
+func TestNewAgent_Errors(t *testing.T) {
+	_, err := NewAgent(AgentConfig{})
+	assert.Error(t, err)
+}
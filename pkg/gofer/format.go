@@ -0,0 +1,137 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gofer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/orcfax/oracle-suite/pkg/datapoint"
+	"github.com/orcfax/oracle-suite/pkg/datapoint/value"
+	"github.com/orcfax/oracle-suite/pkg/util/maputil"
+)
+
+// Output formats supported by the MarshalDataPoints and MarshalModels
+// functions.
+const (
+	FormatPlain  = "plain"
+	FormatTrace  = "trace"
+	FormatJSON   = "json"
+	FormatOrcfax = "orcfax"
+)
+
+// MarshalDataPoints marshals data points using the given format.
+func MarshalDataPoints(points map[string]datapoint.Point, format string) ([]byte, error) {
+	switch format {
+	case FormatPlain:
+		return marshalDataPointsPlain(points)
+	case FormatTrace:
+		return marshalDataPointsTrace(points)
+	case FormatJSON:
+		return marshalDataPointsJSON(points)
+	case FormatOrcfax:
+		return marshalDataPointsOrcfax(points)
+	default:
+		return nil, fmt.Errorf("unsupported format: %s", format)
+	}
+}
+
+// MarshalModels marshals models using the given format. The Orcfax format
+// is not supported for models.
+func MarshalModels(models map[string]datapoint.Model, format string) ([]byte, error) {
+	switch format {
+	case FormatPlain:
+		return marshalModelsPlain(models)
+	case FormatTrace:
+		return marshalModelsTrace(models)
+	case FormatJSON:
+		return marshalModelsJSON(models)
+	default:
+		return nil, fmt.Errorf("unsupported format: %s", format)
+	}
+}
+
+func marshalDataPointsPlain(points map[string]datapoint.Point) ([]byte, error) {
+	var buf bytes.Buffer
+	for i, name := range maputil.SortKeys(points, sort.Strings) {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		if err := points[name].Validate(); err != nil {
+			buf.WriteString(fmt.Sprintf("%s: %s", name, err))
+		} else {
+			buf.WriteString(fmt.Sprintf("%s: %s", name, points[name].Value.Print()))
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func marshalDataPointsTrace(points map[string]datapoint.Point) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, name := range maputil.SortKeys(points, sort.Strings) {
+		bts, err := points[name].MarshalTrace()
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteString(fmt.Sprintf("Data point for %s:\n", name))
+		buf.Write(bts)
+	}
+	return buf.Bytes(), nil
+}
+
+func marshalDataPointsJSON(points map[string]datapoint.Point) ([]byte, error) {
+	return json.Marshal(points)
+}
+
+func marshalDataPointsOrcfax(points map[string]datapoint.Point) ([]byte, error) {
+	ret := make(map[string]value.OrcfaxMessage)
+	for _, name := range maputil.SortKeys(points, sort.Strings) {
+		bts, _ := points[name].MarshalOrcfax()
+		ret[name] = bts
+	}
+	orcfaxData, _ := json.Marshal(ret)
+	return orcfaxData, nil
+}
+
+func marshalModelsPlain(models map[string]datapoint.Model) ([]byte, error) {
+	var buf bytes.Buffer
+	for i, name := range maputil.SortKeys(models, sort.Strings) {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		buf.WriteString(name)
+	}
+	return buf.Bytes(), nil
+}
+
+func marshalModelsTrace(models map[string]datapoint.Model) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, name := range maputil.SortKeys(models, sort.Strings) {
+		bts, err := models[name].MarshalTrace()
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteString(fmt.Sprintf("Model for %s:\n", name))
+		buf.Write(bts)
+	}
+	return buf.Bytes(), nil
+}
+
+func marshalModelsJSON(models map[string]datapoint.Model) ([]byte, error) {
+	return json.Marshal(models)
+}
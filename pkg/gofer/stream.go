@@ -0,0 +1,167 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gofer
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/orcfax/oracle-suite/pkg/datapoint"
+	"github.com/orcfax/oracle-suite/pkg/log"
+	"github.com/orcfax/oracle-suite/pkg/util/sliceutil"
+)
+
+// errTooManyStreams is returned when the maximum number of concurrent
+// streams is reached.
+var errTooManyStreams = errors.New("too many concurrent streams")
+
+// streamUpdate is a result of fetching data points for a stream.
+type streamUpdate struct {
+	points map[string]datapoint.Point
+	err    error
+}
+
+// marshal returns the data points encoded in the given format.
+func (u streamUpdate) marshal(format string) ([]byte, error) {
+	if u.err != nil {
+		return nil, u.err
+	}
+	return MarshalDataPoints(u.points, format)
+}
+
+// streams fetches data points for stream subscribers. Streams that
+// request the same set of models share a single poller, so the number of
+// connected clients does not affect the number of requests sent to the
+// data provider.
+type streams struct {
+	mu sync.Mutex
+
+	provider datapoint.Provider
+	interval time.Duration
+	limit    int
+	count    int
+	pollers  map[string]*streamPoller
+	log      log.Logger
+}
+
+// streamPoller periodically fetches data points for a set of models and
+// sends them to all subscribers.
+type streamPoller struct {
+	models []string
+	subs   map[chan streamUpdate]struct{}
+	last   *streamUpdate
+	cancel context.CancelFunc
+}
+
+func newStreams(provider datapoint.Provider, interval time.Duration, limit int, logger log.Logger) *streams {
+	return &streams{
+		provider: provider,
+		interval: interval,
+		limit:    limit,
+		pollers:  make(map[string]*streamPoller),
+		log:      logger,
+	}
+}
+
+// subscribe returns a channel with data point updates for the given
+// models and a function that must be called to unsubscribe. Only the
+// latest update is kept in the channel if the subscriber is too slow.
+func (s *streams) subscribe(ctx context.Context, models []string) (<-chan streamUpdate, func(), error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.count >= s.limit {
+		return nil, nil, errTooManyStreams
+	}
+	key := streamKey(models)
+	p, ok := s.pollers[key]
+	if !ok {
+		pollerCtx, cancel := context.WithCancel(ctx)
+		p = &streamPoller{
+			models: models,
+			subs:   make(map[chan streamUpdate]struct{}),
+			cancel: cancel,
+		}
+		s.pollers[key] = p
+		go s.pollRoutine(pollerCtx, p)
+	}
+	ch := make(chan streamUpdate, 1)
+	if p.last != nil {
+		ch <- *p.last
+	}
+	p.subs[ch] = struct{}{}
+	s.count++
+	return ch, func() { s.unsubscribe(key, p, ch) }, nil
+}
+
+func (s *streams) unsubscribe(key string, p *streamPoller, ch chan streamUpdate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := p.subs[ch]; !ok {
+		return
+	}
+	delete(p.subs, ch)
+	s.count--
+	if len(p.subs) == 0 {
+		p.cancel()
+		delete(s.pollers, key)
+	}
+}
+
+func (s *streams) pollRoutine(ctx context.Context, p *streamPoller) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		points, err := s.provider.DataPoints(ctx, p.models...)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			s.log.WithError(err).Warn("Unable to fetch data points for stream")
+		}
+		s.publish(p, streamUpdate{points: points, err: err})
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// publish sends the update to all subscribers of the poller, replacing
+// updates that were not received yet.
+func (s *streams) publish(p *streamPoller, u streamUpdate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p.last = &u
+	for ch := range p.subs {
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- u
+	}
+}
+
+// streamKey returns a key that identifies the set of models.
+func streamKey(models []string) string {
+	sorted := sliceutil.Copy(models)
+	sort.Strings(sorted)
+	return strings.Join(sorted, ",")
+}
@@ -0,0 +1,62 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gofer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/orcfax/oracle-suite/pkg/log/null"
+)
+
+func TestStreams_SharedPoller(t *testing.T) {
+	ctx, ctxCancel := context.WithCancel(context.Background())
+	defer ctxCancel()
+
+	provider := &testProvider{prices: map[string]float64{"AAA/BBB": 1.5, "CCC/DDD": 2}}
+	s := newStreams(provider, time.Hour, 3, null.New())
+
+	ch1, unsubscribe1, err := s.subscribe(ctx, []string{"AAA/BBB", "CCC/DDD"})
+	require.NoError(t, err)
+	u := <-ch1
+	require.NoError(t, u.err)
+	assert.Len(t, u.points, 2)
+
+	// Subscribers of the same models share a poller and receive the last
+	// update immediately.
+	ch2, unsubscribe2, err := s.subscribe(ctx, []string{"CCC/DDD", "AAA/BBB"})
+	require.NoError(t, err)
+	assert.Equal(t, u, <-ch2)
+	_, unsubscribe3, err := s.subscribe(ctx, []string{"AAA/BBB"})
+	require.NoError(t, err)
+	assert.Len(t, s.pollers, 2)
+
+	// The number of subscribers is limited.
+	_, _, err = s.subscribe(ctx, []string{"AAA/BBB"})
+	assert.ErrorIs(t, err, errTooManyStreams)
+
+	// Pollers are stopped when the last subscriber leaves.
+	unsubscribe1()
+	assert.Len(t, s.pollers, 2)
+	unsubscribe2()
+	unsubscribe3()
+	assert.Empty(t, s.pollers)
+	assert.Equal(t, 0, s.count)
+}
@@ -0,0 +1,46 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Metrics is a middleware that exposes metrics, it may be used with
+// Prometheus or any other monitoring system that scrapes metrics over HTTP.
+type Metrics struct {
+	// Path is the path where the metrics will be available.
+	Path string
+	// Handler is a handler that writes metrics, e.g. metrics.Memory.
+	Handler http.Handler
+}
+
+// Handle implements the httpserver.Middleware interface.
+func (m *Metrics) Handle(next http.Handler) http.Handler {
+	path := "/" + strings.Trim(m.Path, "/")
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if path == strings.TrimRight(r.URL.Path, "/") {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				rw.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			m.Handler.ServeHTTP(rw, r)
+			return
+		}
+		next.ServeHTTP(rw, r)
+	})
+}
@@ -0,0 +1,74 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	tests := []struct {
+		method         string
+		requestPath    string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			method:         http.MethodGet,
+			requestPath:    "/metrics",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "metrics",
+		},
+		{
+			method:         http.MethodGet,
+			requestPath:    "/metrics/",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "metrics",
+		},
+		{
+			method:         http.MethodPost,
+			requestPath:    "/metrics",
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			method:         http.MethodGet,
+			requestPath:    "/foo",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+	for n, test := range tests {
+		t.Run(fmt.Sprintf("case-%d", n+1), func(t *testing.T) {
+			h := (&Metrics{
+				Path: "metrics",
+				Handler: http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+					_, _ = writer.Write([]byte("metrics"))
+				}),
+			}).Handle(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				writer.WriteHeader(http.StatusBadRequest)
+			}))
+			r := httptest.NewRequest(test.method, test.requestPath, nil)
+			rw := httptest.NewRecorder()
+			h.ServeHTTP(rw, r)
+			assert.Equal(t, test.expectedStatus, rw.Code)
+			assert.Equal(t, test.expectedBody, rw.Body.String())
+		})
+	}
+}
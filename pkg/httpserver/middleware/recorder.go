@@ -16,8 +16,11 @@
 package middleware
 
 import (
+	"bufio"
 	"bytes"
+	"fmt"
 	"io"
+	"net"
 	"net/http"
 )
 
@@ -54,6 +57,23 @@ func (r *recorder) WriteHeader(code int) {
 	r.rw.WriteHeader(code)
 }
 
+// Flush implements the http.Flusher interface, if the underlying
+// ResponseWriter supports it.
+func (r *recorder) Flush() {
+	if f, ok := r.rw.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements the http.Hijacker interface, if the underlying
+// ResponseWriter supports it. It is required for websocket connections.
+func (r *recorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := r.rw.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, fmt.Errorf("hijacking is not supported")
+}
+
 func readRequest(r *http.Request) []byte {
 	b, _ := io.ReadAll(r.Body)
 	r.Body = io.NopCloser(bytes.NewReader(b))
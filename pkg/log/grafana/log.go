@@ -24,13 +24,11 @@ import (
 	"net/http"
 	"reflect"
 	"regexp"
-	"strconv"
-	"strings"
 	"sync"
 	"time"
 
+	"github.com/orcfax/oracle-suite/pkg/log/internal/logmetric"
 	"github.com/orcfax/oracle-suite/pkg/log/null"
-	"github.com/orcfax/oracle-suite/pkg/util/dump"
 	"github.com/orcfax/oracle-suite/pkg/util/interpolate"
 
 	"github.com/orcfax/oracle-suite/pkg/log"
@@ -268,7 +266,7 @@ func (c *logger) collect(msg string, fields log.Fields) {
 	defer c.mu.Unlock()
 	rfields := reflect.ValueOf(fields)
 	for _, metric := range c.metrics {
-		if !match(metric, msg, rfields) {
+		if !logmetric.Match(metric.MatchMessage, metric.MatchFields, msg, rfields) {
 			continue
 		}
 		var ok bool
@@ -276,7 +274,7 @@ func (c *logger) collect(msg string, fields log.Fields) {
 		var mv metricValue
 		mk.time = roundTime(time.Now().Unix(), c.interval)
 		mv.value = 1
-		mk.name, ok = replaceVars(metric.parsedName, rfields)
+		mk.name, ok = logmetric.ReplaceVars(metric.parsedName, rfields)
 		if !ok {
 			c.logger.
 				WithField("path", metric.Name).
@@ -285,7 +283,7 @@ func (c *logger) collect(msg string, fields log.Fields) {
 		}
 		for t, vs := range metric.parsedTags {
 			for _, v := range vs {
-				rt, ok := replaceVars(v, rfields)
+				rt, ok := logmetric.ReplaceVars(v, rfields)
 				if !ok {
 					c.logger.
 						WithField("path", v).
@@ -296,11 +294,11 @@ func (c *logger) collect(msg string, fields log.Fields) {
 			}
 		}
 		if len(metric.Value) > 0 {
-			value := byPath(rfields, metric.Value)
+			value := logmetric.ByPath(rfields, metric.Value)
 			if metric.ParserFunc != nil {
 				mv.value, ok = metric.ParserFunc(value)
 			} else {
-				mv.value, ok = toFloat(value)
+				mv.value, ok = logmetric.ToFloat(value)
 			}
 			if !ok {
 				c.logger.
@@ -440,113 +438,6 @@ func (c *logger) Wait() <-chan error {
 	return c.waitCh
 }
 
-// match checks if log message and log fields matches metric definition.
-func match(metric Metric, msg string, fields reflect.Value) bool {
-	for path, rx := range metric.MatchFields {
-		field, ok := toString(byPath(fields, path))
-		if !ok || !rx.MatchString(field) {
-			return false
-		}
-	}
-	return metric.MatchMessage == nil || metric.MatchMessage.MatchString(msg)
-}
-
-// replaceVars replaces vars provided as ${field} with values from log fields.
-func replaceVars(s interpolate.Parsed, fields reflect.Value) (string, bool) {
-	valid := true
-	return s.Interpolate(func(v interpolate.Variable) string {
-		name, ok := toString(byPath(fields, v.Name))
-		if !ok {
-			if v.HasDefault {
-				return v.Default
-			}
-			valid = false
-			return ""
-		}
-		return name
-	}), valid
-}
-
-func byPath(value reflect.Value, path string) reflect.Value {
-	if value.Kind() == reflect.Interface || value.Kind() == reflect.Ptr {
-		return byPath(value.Elem(), path)
-	}
-	if len(path) == 0 {
-		return value
-	}
-	switch value.Kind() {
-	case reflect.Slice:
-		elem, path := splitPath(path)
-		i, err := strconv.Atoi(elem)
-		if err != nil {
-			return reflect.Value{}
-		}
-		f := value.Index(i)
-		if !f.IsValid() {
-			return reflect.Value{}
-		}
-		return byPath(f, path)
-	case reflect.Map:
-		elem, path := splitPath(path)
-		if value.Type().Key().Kind() != reflect.String {
-			return reflect.Value{}
-		}
-		f := value.MapIndex(reflect.ValueOf(elem))
-		if !f.IsValid() {
-			return reflect.Value{}
-		}
-		return byPath(f, path)
-	case reflect.Struct:
-		elem, path := splitPath(path)
-		f := value.FieldByName(elem)
-		if !f.IsValid() {
-			return reflect.Value{}
-		}
-		return byPath(f, path)
-	}
-	return reflect.Value{}
-}
-
-func splitPath(path string) (a, b string) {
-	p := strings.SplitN(path, ".", 2)
-	switch len(p) {
-	case 1:
-		return p[0], ""
-	case 2:
-		return p[0], p[1]
-	default:
-		return "", ""
-	}
-}
-
-func toFloat(value reflect.Value) (float64, bool) {
-	if !value.IsValid() {
-		return 0, false
-	}
-	if t, ok := value.Interface().(time.Duration); ok {
-		return t.Seconds(), true
-	}
-	switch value.Type().Kind() {
-	case reflect.String:
-		f, err := strconv.ParseFloat(value.String(), 64)
-		return f, err == nil
-	case reflect.Float32, reflect.Float64:
-		return value.Float(), true
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		return float64(value.Int()), true
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		return float64(value.Uint()), true
-	}
-	return 0, false
-}
-
-func toString(value reflect.Value) (string, bool) {
-	if !value.IsValid() {
-		return "", false
-	}
-	return fmt.Sprint(dump.Dump(value.Interface())), true
-}
-
 func roundTime(time int64, interval uint) int64 {
 	return time - (time % int64(interval))
 }
@@ -0,0 +1,142 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package logmetric contains helpers shared by loggers that derive metrics
+// from log messages.
+package logmetric
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/orcfax/oracle-suite/pkg/util/dump"
+	"github.com/orcfax/oracle-suite/pkg/util/interpolate"
+)
+
+// Match checks if log message and log fields matches metric definition.
+func Match(matchMessage *regexp.Regexp, matchFields map[string]*regexp.Regexp, msg string, fields reflect.Value) bool {
+	for path, rx := range matchFields {
+		field, ok := ToString(ByPath(fields, path))
+		if !ok || !rx.MatchString(field) {
+			return false
+		}
+	}
+	return matchMessage == nil || matchMessage.MatchString(msg)
+}
+
+// ReplaceVars replaces vars provided as %{field} with values from log fields.
+func ReplaceVars(s interpolate.Parsed, fields reflect.Value) (string, bool) {
+	valid := true
+	return s.Interpolate(func(v interpolate.Variable) string {
+		name, ok := ToString(ByPath(fields, v.Name))
+		if !ok {
+			if v.HasDefault {
+				return v.Default
+			}
+			valid = false
+			return ""
+		}
+		return name
+	}), valid
+}
+
+// ByPath returns the value under the dot-separated path. If the path does
+// not exist, an invalid reflect.Value is returned.
+func ByPath(value reflect.Value, path string) reflect.Value {
+	if value.Kind() == reflect.Interface || value.Kind() == reflect.Ptr {
+		return ByPath(value.Elem(), path)
+	}
+	if len(path) == 0 {
+		return value
+	}
+	switch value.Kind() {
+	case reflect.Slice:
+		elem, path := splitPath(path)
+		i, err := strconv.Atoi(elem)
+		if err != nil {
+			return reflect.Value{}
+		}
+		f := value.Index(i)
+		if !f.IsValid() {
+			return reflect.Value{}
+		}
+		return ByPath(f, path)
+	case reflect.Map:
+		elem, path := splitPath(path)
+		if value.Type().Key().Kind() != reflect.String {
+			return reflect.Value{}
+		}
+		f := value.MapIndex(reflect.ValueOf(elem))
+		if !f.IsValid() {
+			return reflect.Value{}
+		}
+		return ByPath(f, path)
+	case reflect.Struct:
+		elem, path := splitPath(path)
+		f := value.FieldByName(elem)
+		if !f.IsValid() {
+			return reflect.Value{}
+		}
+		return ByPath(f, path)
+	}
+	return reflect.Value{}
+}
+
+// ToFloat converts a numeric value, a numeric string or a duration to
+// float64. Durations are converted to seconds.
+func ToFloat(value reflect.Value) (float64, bool) {
+	if !value.IsValid() {
+		return 0, false
+	}
+	if t, ok := value.Interface().(time.Duration); ok {
+		return t.Seconds(), true
+	}
+	switch value.Type().Kind() {
+	case reflect.String:
+		f, err := strconv.ParseFloat(value.String(), 64)
+		return f, err == nil
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), true
+	}
+	return 0, false
+}
+
+// ToString converts a value to its string representation.
+func ToString(value reflect.Value) (string, bool) {
+	if !value.IsValid() {
+		return "", false
+	}
+	return fmt.Sprint(dump.Dump(value.Interface())), true
+}
+
+func splitPath(path string) (a, b string) {
+	p := strings.SplitN(path, ".", 2)
+	switch len(p) {
+	case 1:
+		return p[0], ""
+	case 2:
+		return p[0], p[1]
+	default:
+		return "", ""
+	}
+}
@@ -0,0 +1,87 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package logmetric
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestByPath(t *testing.T) {
+	tests := []struct {
+		value   any
+		path    string
+		want    any
+		invalid bool
+	}{
+		{
+			value: "test",
+			path:  "",
+			want:  "test",
+		},
+		{
+			value:   "test",
+			path:    "abc",
+			invalid: true,
+		},
+		{
+			value: struct {
+				Field string
+			}{
+				Field: "test",
+			},
+			path: "Field",
+			want: "test",
+		},
+		{
+			value: map[string]string{"Key": "test"},
+			path:  "Key",
+			want:  "test",
+		},
+		{
+			value:   map[int]string{42: "test"},
+			path:    "42",
+			invalid: true,
+		},
+		{
+			value: []string{"test"},
+			path:  "0",
+			want:  "test",
+		},
+		{
+			value: struct {
+				Field map[string][]int
+			}{
+				Field: map[string][]int{"Field2": {42}},
+			},
+			path: "Field.Field2.0",
+			want: 42,
+		},
+	}
+	for n, tt := range tests {
+		t.Run(fmt.Sprintf("case-%d", n+1), func(t *testing.T) {
+			v := ByPath(reflect.ValueOf(tt.value), tt.path)
+			if tt.invalid {
+				assert.False(t, v.IsValid())
+			} else {
+				assert.Equal(t, tt.want, v.Interface())
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package formatter
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+// RedactFormatter passes formatted log entries through the Redact function,
+// so that sensitive values, like secrets, can be removed from logs
+// regardless of whether they appear in the message or in the fields.
+type RedactFormatter struct {
+	Formatter logrus.Formatter
+	Redact    func(string) string
+}
+
+func (f *RedactFormatter) Format(e *logrus.Entry) ([]byte, error) {
+	b, err := f.Formatter.Format(e)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(f.Redact(string(b))), nil
+}
@@ -0,0 +1,333 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/orcfax/oracle-suite/pkg/httpserver"
+	"github.com/orcfax/oracle-suite/pkg/log"
+	"github.com/orcfax/oracle-suite/pkg/log/internal/logmetric"
+	"github.com/orcfax/oracle-suite/pkg/log/null"
+	"github.com/orcfax/oracle-suite/pkg/metrics"
+	"github.com/orcfax/oracle-suite/pkg/util/interpolate"
+)
+
+const LoggerTag = "PROMETHEUS"
+
+// Config is the configuration for the Prometheus logger.
+type Config struct {
+	// Metrics is a list of metric definitions.
+	Metrics []Metric
+
+	// Registry is a registry in which metric values are stored.
+	Registry metrics.Registry
+
+	// Server is an optional HTTP server that exposes the registry. It is
+	// started and stopped together with the logger.
+	Server httpserver.Service
+
+	// Logger used to log errors related to this logger, such as invalid
+	// metric definitions.
+	Logger log.Logger
+}
+
+// Metric describes one Prometheus metric.
+type Metric struct {
+	// MatchMessage is a regexp that must match the log message.
+	MatchMessage *regexp.Regexp
+
+	// MatchFields is a list of regexp's that must match the values of the
+	// fields defined in the map keys.
+	MatchFields map[string]*regexp.Regexp
+
+	// Value is the dot-separated path of the field with the metric value.
+	// If empty, the value 1 will be used as the metric value.
+	Value string
+
+	// Name is the name of the metric. It can contain references to log fields
+	// in the format %{path}, where path is the dot-separated path to the field.
+	// Characters that are not allowed in Prometheus metric names are replaced
+	// with underscores.
+	Name string
+
+	// Labels is a list of metric labels. Values can contain references to
+	// log fields in the format %{path}, where path is the dot-separated path
+	// to the field.
+	Labels map[string]string
+
+	// Type is the type of the metric.
+	Type Type
+
+	// TransformFunc defines the function applied to the value before setting it.
+	TransformFunc func(float64) float64
+
+	// ParserFunc is going to be applied to transform the value reflection to an actual float64 value
+	ParserFunc func(reflect.Value) (float64, bool)
+
+	parsedName   interpolate.Parsed
+	parsedLabels map[string]interpolate.Parsed
+}
+
+// Type is a type of Prometheus metric.
+type Type int
+
+const (
+	Gauge   Type = iota // Set the metric to the value.
+	Counter             // Add the value to the metric.
+)
+
+// New creates a new logger that can extract parameters from log messages and
+// store them as gauges or counters in the metrics registry. If the server is
+// provided, it will be started together with the logger.
+func New(level log.Level, cfg Config) (log.Logger, error) {
+	if cfg.Registry == nil {
+		return nil, fmt.Errorf("registry must not be nil")
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = null.New()
+	}
+	// Parse names and labels in advance to improve performance.
+	for n := range cfg.Metrics {
+		m := &cfg.Metrics[n]
+		m.parsedName = interpolate.ParsePercent(m.Name)
+		m.parsedLabels = make(map[string]interpolate.Parsed, len(m.Labels))
+		for k, v := range m.Labels {
+			m.parsedLabels[sanitize(k)] = interpolate.ParsePercent(v)
+		}
+	}
+	l := &logger{
+		shared: &shared{
+			waitCh:   make(chan error),
+			logger:   cfg.Logger.WithField("tag", LoggerTag),
+			metrics:  cfg.Metrics,
+			registry: cfg.Registry,
+			server:   cfg.Server,
+		},
+		level:  level,
+		fields: log.Fields{},
+	}
+	return l, nil
+}
+
+type logger struct {
+	*shared
+	level  log.Level
+	fields log.Fields
+}
+
+type shared struct {
+	ctx    context.Context
+	waitCh chan error
+
+	logger   log.Logger
+	metrics  []Metric
+	registry metrics.Registry
+	server   httpserver.Service
+}
+
+// Level implements the log.Logger interface.
+func (c *logger) Level() log.Level {
+	return c.level
+}
+
+// WithField implements the log.Logger interface.
+func (c *logger) WithField(key string, value any) log.Logger {
+	f := log.Fields{}
+	for k, v := range c.fields {
+		f[k] = v
+	}
+	f[key] = value
+	return &logger{
+		shared: c.shared,
+		level:  c.level,
+		fields: f,
+	}
+}
+
+// WithFields implements the log.Logger interface.
+func (c *logger) WithFields(fields log.Fields) log.Logger {
+	f := log.Fields{}
+	for k, v := range c.fields {
+		f[k] = v
+	}
+	for k, v := range fields {
+		f[k] = v
+	}
+	return &logger{
+		shared: c.shared,
+		level:  c.level,
+		fields: f,
+	}
+}
+
+// WithError implements the log.Logger interface.
+func (c *logger) WithError(err error) log.Logger {
+	return c.WithField("err", err.Error())
+}
+
+// WithAdvice implements the log.Logger interface.
+func (c *logger) WithAdvice(advice string) log.Logger {
+	return c.WithField("advice", advice)
+}
+
+// Debug implements the log.Logger interface.
+func (c *logger) Debug(args ...any) {
+	if c.level >= log.Debug {
+		c.collect(fmt.Sprint(args...), c.fields)
+	}
+}
+
+// Info implements the log.Logger interface.
+func (c *logger) Info(args ...any) {
+	if c.level >= log.Info {
+		c.collect(fmt.Sprint(args...), c.fields)
+	}
+}
+
+// Warn implements the log.Logger interface.
+func (c *logger) Warn(args ...any) {
+	if c.level >= log.Warn {
+		c.collect(fmt.Sprint(args...), c.fields)
+	}
+}
+
+// Error implements the log.Logger interface.
+func (c *logger) Error(args ...any) {
+	if c.level >= log.Error {
+		c.collect(fmt.Sprint(args...), c.fields)
+	}
+}
+
+// Panic implements the log.Logger interface.
+func (c *logger) Panic(args ...any) {
+	msg := fmt.Sprint(args...)
+	c.collect(msg, c.fields)
+	panic(msg)
+}
+
+// collect checks if a log matches any of predefined metrics and if so,
+// updates the metric in the registry.
+func (c *logger) collect(msg string, fields log.Fields) {
+	rfields := reflect.ValueOf(fields)
+	for _, metric := range c.metrics {
+		if !logmetric.Match(metric.MatchMessage, metric.MatchFields, msg, rfields) {
+			continue
+		}
+		name, ok := logmetric.ReplaceVars(metric.parsedName, rfields)
+		if !ok {
+			c.logger.
+				WithField("path", metric.Name).
+				Warn("Invalid path in the name field")
+			continue
+		}
+		labels := make(metrics.Labels, len(metric.parsedLabels))
+		for k, v := range metric.parsedLabels {
+			lv, ok := logmetric.ReplaceVars(v, rfields)
+			if !ok {
+				c.logger.
+					WithField("label", k).
+					Warn("Invalid path in the label definition")
+				continue
+			}
+			labels[k] = lv
+		}
+		value := 1.0
+		if len(metric.Value) > 0 {
+			rv := logmetric.ByPath(rfields, metric.Value)
+			if metric.ParserFunc != nil {
+				value, ok = metric.ParserFunc(rv)
+			} else {
+				value, ok = logmetric.ToFloat(rv)
+			}
+			if !ok {
+				c.logger.
+					WithField("path", metric.Value).
+					Warn("Invalid path")
+				continue
+			}
+		}
+		if metric.TransformFunc != nil {
+			value = metric.TransformFunc(value)
+		}
+		switch metric.Type {
+		case Counter:
+			c.registry.Add(sanitize(name), labels, value)
+		default:
+			c.registry.Set(sanitize(name), labels, value)
+		}
+	}
+}
+
+// Start implements the supervisor.Service interface.
+func (c *logger) Start(ctx context.Context) error {
+	c.logger.Debug("Starting")
+	if c.ctx != nil {
+		return fmt.Errorf("service can be started only once")
+	}
+	if ctx == nil {
+		return fmt.Errorf("context is nil")
+	}
+	c.ctx = ctx
+	if c.server != nil {
+		if err := c.server.Start(ctx); err != nil {
+			return err
+		}
+	}
+	go c.contextCancelHandler()
+	return nil
+}
+
+// Wait implements the supervisor.Service interface.
+func (c *logger) Wait() <-chan error {
+	return c.waitCh
+}
+
+func (c *logger) contextCancelHandler() {
+	defer func() { close(c.waitCh) }()
+	defer c.logger.Debug("Stopped")
+	<-c.ctx.Done()
+	if c.server != nil {
+		if err := <-c.server.Wait(); err != nil {
+			c.waitCh <- err
+		}
+	}
+}
+
+// sanitize replaces characters that are not allowed in Prometheus metric
+// and label names with underscores.
+func sanitize(name string) string {
+	var b strings.Builder
+	b.Grow(len(name))
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_', r == ':':
+			b.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				b.WriteRune('_')
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,189 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package prometheus
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/orcfax/oracle-suite/pkg/log"
+	"github.com/orcfax/oracle-suite/pkg/metrics"
+)
+
+func TestLogger(t *testing.T) {
+	type want struct {
+		name   string
+		labels metrics.Labels
+		value  float64
+	}
+	tests := []struct {
+		metrics []Metric
+		want    []want
+		logs    func(l log.Logger)
+	}{
+		// MatchMessage test:
+		{
+			metrics: []Metric{{MatchMessage: regexp.MustCompile("foo"), Name: "test"}},
+			want: []want{
+				{name: "test", value: 1},
+			},
+			logs: func(l log.Logger) {
+				l.Info("foo")
+				l.Info("bar")
+			},
+		},
+		// MatchFields test:
+		{
+			metrics: []Metric{{MatchFields: map[string]*regexp.Regexp{"key": regexp.MustCompile("foo")}, Name: "test"}},
+			want: []want{
+				{name: "test", value: 1},
+			},
+			logs: func(l log.Logger) {
+				l.WithField("key", "foo").Info("test")
+				l.WithField("key", "bar").Info("test")
+			},
+		},
+		// Gauge keeps the last value:
+		{
+			metrics: []Metric{{MatchMessage: regexp.MustCompile("foo"), Name: "test", Value: "key"}},
+			want: []want{
+				{name: "test", value: 2.5},
+			},
+			logs: func(l log.Logger) {
+				l.WithField("key", 1.5).Info("foo")
+				l.WithField("key", "2.5").Info("foo")
+			},
+		},
+		// Counter sums values:
+		{
+			metrics: []Metric{{MatchMessage: regexp.MustCompile("foo"), Name: "test", Type: Counter}},
+			want: []want{
+				{name: "test", value: 3},
+			},
+			logs: func(l log.Logger) {
+				l.Info("foo")
+				l.Info("foo")
+				l.Info("foo")
+			},
+		},
+		// Value from duration:
+		{
+			metrics: []Metric{{MatchMessage: regexp.MustCompile("foo"), Name: "test", Value: "key"}},
+			want: []want{
+				{name: "test", value: 1.5},
+			},
+			logs: func(l log.Logger) {
+				l.WithField("key", 1500*time.Millisecond).Info("foo")
+			},
+		},
+		// Invalid value path:
+		{
+			metrics: []Metric{{MatchMessage: regexp.MustCompile("foo"), Name: "test", Value: "key"}},
+			want:    nil,
+			logs: func(l log.Logger) {
+				l.Info("foo")
+			},
+		},
+		// Transform function:
+		{
+			metrics: []Metric{{
+				MatchMessage:  regexp.MustCompile("foo"),
+				Name:          "test",
+				Value:         "key",
+				TransformFunc: func(v float64) float64 { return v * 10 },
+			}},
+			want: []want{
+				{name: "test", value: 15},
+			},
+			logs: func(l log.Logger) {
+				l.WithField("key", 1.5).Info("foo")
+			},
+		},
+		// Name and labels interpolation:
+		{
+			metrics: []Metric{{
+				MatchMessage: regexp.MustCompile("foo"),
+				Name:         "test.%{key}",
+				Labels:       map[string]string{"pair": "%{pair}", "origin": "%{origin-unknown}"},
+			}},
+			want: []want{
+				{name: "test_a", labels: metrics.Labels{"pair": "BTC/USD", "origin": "unknown"}, value: 1},
+				{name: "test_b", labels: metrics.Labels{"pair": "ETH/USD", "origin": "foo"}, value: 1},
+			},
+			logs: func(l log.Logger) {
+				l.WithFields(log.Fields{"key": "a", "pair": "BTC/USD"}).Info("foo")
+				l.WithFields(log.Fields{"key": "b", "pair": "ETH/USD", "origin": "foo"}).Info("foo")
+			},
+		},
+	}
+	for n, tt := range tests {
+		t.Run(fmt.Sprintf("case-%d", n+1), func(t *testing.T) {
+			reg := metrics.NewMemory(nil)
+			l, err := New(log.Debug, Config{Metrics: tt.metrics, Registry: reg})
+			require.NoError(t, err)
+			tt.logs(l)
+
+			for _, w := range tt.want {
+				v, ok := reg.Value(w.name, w.labels)
+				require.True(t, ok, "metric %s not found", w.name)
+				assert.Equal(t, w.value, v)
+			}
+			buf := &bytes.Buffer{}
+			_, err = reg.WriteTo(buf)
+			require.NoError(t, err)
+			if len(tt.want) == 0 {
+				assert.Empty(t, buf.String())
+			}
+		})
+	}
+}
+
+func TestLogger_Service(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	l, err := New(log.Debug, Config{Registry: metrics.NewMemory(nil)})
+	require.NoError(t, err)
+
+	srv := l.(log.LoggerService)
+	require.NoError(t, srv.Start(ctx))
+	require.Error(t, srv.Start(ctx))
+
+	cancel()
+	select {
+	case <-srv.Wait():
+	case <-time.After(time.Second):
+		t.Fatal("logger did not stop")
+	}
+}
+
+func Test_sanitize(t *testing.T) {
+	tests := map[string]string{
+		"test":           "test",
+		"gofer.price":    "gofer_price",
+		"BTC/USD-median": "BTC_USD_median",
+		"0abc":           "_0abc",
+		"a:b_c1":         "a:b_c1",
+	}
+	for in, want := range tests {
+		assert.Equal(t, want, sanitize(in), in)
+	}
+}
@@ -0,0 +1,283 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the default upper bounds of histogram buckets.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+type metricType int
+
+const (
+	counterType metricType = iota
+	gaugeType
+	histogramType
+)
+
+func (t metricType) String() string {
+	switch t {
+	case counterType:
+		return "counter"
+	case gaugeType:
+		return "gauge"
+	case histogramType:
+		return "histogram"
+	default:
+		return "untyped"
+	}
+}
+
+// Memory is a registry that keeps metrics in memory. It implements the
+// http.Handler interface which exposes metrics in the Prometheus text
+// format.
+//
+// If a metric is updated using a method that does not match the type of
+// the metric, the update is ignored.
+type Memory struct {
+	mu      sync.RWMutex
+	buckets []float64
+	metrics map[string]*metric
+}
+
+type metric struct {
+	typ    metricType
+	series map[string]*series
+}
+
+type series struct {
+	labels  string    // Labels formatted in the Prometheus text format.
+	value   float64   // Value of a counter or gauge, or a sum of observations.
+	count   uint64    // Number of observations.
+	buckets []uint64  // Number of observations in each bucket.
+	bounds  []float64 // Upper bounds of buckets.
+}
+
+// NewMemory returns a new instance of the Memory registry. Histograms use
+// the given bucket bounds. If empty, DefaultBuckets are used.
+func NewMemory(buckets []float64) *Memory {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	return &Memory{
+		buckets: buckets,
+		metrics: make(map[string]*metric),
+	}
+}
+
+// Add implements the Registry interface.
+func (m *Memory) Add(name string, labels Labels, delta float64) {
+	if delta < 0 {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s := m.series(name, labels, counterType); s != nil {
+		s.value += delta
+	}
+}
+
+// Set implements the Registry interface.
+func (m *Memory) Set(name string, labels Labels, value float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s := m.series(name, labels, gaugeType); s != nil {
+		s.value = value
+	}
+}
+
+// Observe implements the Registry interface.
+func (m *Memory) Observe(name string, labels Labels, value float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.series(name, labels, histogramType)
+	if s == nil {
+		return
+	}
+	s.value += value
+	s.count++
+	for i, b := range s.bounds {
+		if value <= b {
+			s.buckets[i]++
+		}
+	}
+}
+
+// Value returns the value of a counter or gauge, or the sum of observations
+// of a histogram. The second return value is false if the metric does not
+// exist.
+func (m *Memory) Value(name string, labels Labels) (float64, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	mt, ok := m.metrics[name]
+	if !ok {
+		return 0, false
+	}
+	s, ok := mt.series[formatLabels(labels)]
+	if !ok {
+		return 0, false
+	}
+	return s.value, true
+}
+
+// WriteTo writes all metrics in the Prometheus text format.
+func (m *Memory) WriteTo(w io.Writer) (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+	names := make([]string, 0, len(m.metrics))
+	for name := range m.metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		mt := m.metrics[name]
+		fmt.Fprintf(cw, "# TYPE %s %s\n", name, mt.typ)
+		keys := make([]string, 0, len(mt.series))
+		for key := range mt.series {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			s := mt.series[key]
+			if mt.typ != histogramType {
+				fmt.Fprintf(cw, "%s%s %s\n", name, braces(s.labels), formatFloat(s.value))
+				continue
+			}
+			for i, b := range s.bounds {
+				fmt.Fprintf(cw, "%s_bucket%s %d\n", name, braces(joinLabels(s.labels, `le="`+formatFloat(b)+`"`)), s.buckets[i])
+			}
+			fmt.Fprintf(cw, "%s_bucket%s %d\n", name, braces(joinLabels(s.labels, `le="+Inf"`)), s.count)
+			fmt.Fprintf(cw, "%s_sum%s %s\n", name, braces(s.labels), formatFloat(s.value))
+			fmt.Fprintf(cw, "%s_count%s %d\n", name, braces(s.labels), s.count)
+		}
+	}
+	if cw.err != nil {
+		return cw.n, cw.err
+	}
+	return cw.n, cw.w.Flush()
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (m *Memory) ServeHTTP(rw http.ResponseWriter, _ *http.Request) {
+	rw.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = m.WriteTo(rw)
+}
+
+// series returns a series for the given metric name and labels. If the
+// metric exists but has a different type, nil is returned.
+func (m *Memory) series(name string, labels Labels, typ metricType) *series {
+	mt, ok := m.metrics[name]
+	if !ok {
+		mt = &metric{typ: typ, series: make(map[string]*series)}
+		m.metrics[name] = mt
+	}
+	if mt.typ != typ {
+		return nil
+	}
+	key := formatLabels(labels)
+	s, ok := mt.series[key]
+	if !ok {
+		s = &series{labels: key}
+		if typ == histogramType {
+			s.bounds = m.buckets
+			s.buckets = make([]uint64, len(m.buckets))
+		}
+		mt.series[key] = s
+	}
+	return s
+}
+
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
+
+// formatLabels formats labels in the Prometheus text format, without
+// surrounding braces. Labels are sorted by name.
+func formatLabels(labels Labels) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var sb strings.Builder
+	for i, name := range names {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(name)
+		sb.WriteString(`="`)
+		sb.WriteString(labelValueReplacer.Replace(labels[name]))
+		sb.WriteByte('"')
+	}
+	return sb.String()
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func joinLabels(labels, label string) string {
+	if labels == "" {
+		return label
+	}
+	return labels + "," + label
+}
+
+func braces(labels string) string {
+	if labels == "" {
+		return ""
+	}
+	return "{" + labels + "}"
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case math.IsNaN(f):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+}
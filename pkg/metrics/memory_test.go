@@ -0,0 +1,90 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemory(t *testing.T) {
+	m := NewMemory([]float64{1, 5})
+
+	m.Add("messages_total", Labels{"topic": "a"}, 1)
+	m.Add("messages_total", Labels{"topic": "a"}, 2)
+	m.Add("messages_total", Labels{"topic": "b"}, 1)
+	m.Add("messages_total", Labels{"topic": "b"}, -1) // ignored, counters cannot decrease
+	m.Set("peers", nil, 3)
+	m.Set("peers", nil, 2)
+	m.Set("messages_total", Labels{"topic": "a"}, 10) // ignored, type mismatch
+	m.Observe("latency_seconds", Labels{"topic": "a"}, 0.5)
+	m.Observe("latency_seconds", Labels{"topic": "a"}, 2)
+	m.Observe("latency_seconds", Labels{"topic": "a"}, 10)
+
+	v, ok := m.Value("messages_total", Labels{"topic": "a"})
+	require.True(t, ok)
+	assert.Equal(t, float64(3), v)
+	v, ok = m.Value("messages_total", Labels{"topic": "b"})
+	require.True(t, ok)
+	assert.Equal(t, float64(1), v)
+	v, ok = m.Value("peers", nil)
+	require.True(t, ok)
+	assert.Equal(t, float64(2), v)
+	_, ok = m.Value("peers", Labels{"topic": "a"})
+	assert.False(t, ok)
+
+	var sb strings.Builder
+	_, err := m.WriteTo(&sb)
+	require.NoError(t, err)
+	assert.Equal(t, `# TYPE latency_seconds histogram
+latency_seconds_bucket{topic="a",le="1"} 1
+latency_seconds_bucket{topic="a",le="5"} 2
+latency_seconds_bucket{topic="a",le="+Inf"} 3
+latency_seconds_sum{topic="a"} 12.5
+latency_seconds_count{topic="a"} 3
+# TYPE messages_total counter
+messages_total{topic="a"} 3
+messages_total{topic="b"} 1
+# TYPE peers gauge
+peers 2
+`, sb.String())
+}
+
+func TestMemory_LabelEscaping(t *testing.T) {
+	m := NewMemory(nil)
+	m.Set("gauge", Labels{"b": "x\"y", "a": "x\\y\nz"}, 1)
+
+	var sb strings.Builder
+	_, err := m.WriteTo(&sb)
+	require.NoError(t, err)
+	assert.Equal(t, "# TYPE gauge gauge\ngauge{a=\"x\\\\y\\nz\",b=\"x\\\"y\"} 1\n", sb.String())
+}
+
+func TestMemory_ServeHTTP(t *testing.T) {
+	m := NewMemory(nil)
+	m.Add("counter", nil, 1)
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get("Content-Type"), "text/plain")
+	assert.Equal(t, "# TYPE counter counter\ncounter 1\n", rec.Body.String())
+}
@@ -0,0 +1,51 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package metrics
+
+// Labels is a set of label names and values that identify a metric series.
+type Labels map[string]string
+
+// Registry collects metrics. Metrics are identified by their names and
+// labels. The type of metric is determined by the method used to update it.
+//
+// Implementations must be safe for concurrent use.
+type Registry interface {
+	// Add adds the given delta to a counter. Delta must not be negative.
+	Add(name string, labels Labels, delta float64)
+
+	// Set sets the value of a gauge.
+	Set(name string, labels Labels, value float64)
+
+	// Observe adds an observation to a histogram.
+	Observe(name string, labels Labels, value float64)
+}
+
+// Null is a registry that discards all metrics.
+type Null struct{}
+
+// NewNull returns a new instance of the Null registry.
+func NewNull() *Null {
+	return &Null{}
+}
+
+// Add implements the Registry interface.
+func (*Null) Add(string, Labels, float64) {}
+
+// Set implements the Registry interface.
+func (*Null) Set(string, Labels, float64) {}
+
+// Observe implements the Registry interface.
+func (*Null) Observe(string, Labels, float64) {}
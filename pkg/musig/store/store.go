@@ -118,6 +118,14 @@ func (m *Store) SignaturesByDataModel(model string) []*messages.MuSigSignature {
 	return signatures
 }
 
+// SetDataModels replaces the list of models for which signatures are
+// collected.
+func (m *Store) SetDataModels(models []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dataModels = models
+}
+
 func (m *Store) collectSignature(feed types.Address, sig *messages.MuSigSignature) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -137,6 +145,8 @@ func (m *Store) shouldCollectSignature(sig *messages.MuSigSignature) bool {
 	if model == "" {
 		return false
 	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	for _, a := range m.dataModels {
 		if a == model {
 			return true
@@ -161,6 +171,13 @@ func (m *Store) handleSignatureMessage(msg transport.ReceivedMessage) {
 			Error("Unexpected value returned from the transport layer")
 		return
 	}
+	if len(msg.Author) == 0 {
+		m.log.
+			WithField("transport", msg.Meta.Transport).
+			WithAdvice("The transport does not authenticate message authors, check the configuration").
+			Warn("Signature message without an author, message dropped")
+		return
+	}
 	if !m.shouldCollectSignature(sig) {
 		return
 	}
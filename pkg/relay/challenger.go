@@ -0,0 +1,368 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package relay
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/defiweb/go-eth/rpc"
+	"github.com/defiweb/go-eth/types"
+
+	"github.com/orcfax/oracle-suite/pkg/contract"
+	"github.com/orcfax/oracle-suite/pkg/contract/chronicle"
+	datapointStore "github.com/orcfax/oracle-suite/pkg/datapoint/store"
+	"github.com/orcfax/oracle-suite/pkg/log"
+	"github.com/orcfax/oracle-suite/pkg/log/null"
+	"github.com/orcfax/oracle-suite/pkg/util/bn"
+	"github.com/orcfax/oracle-suite/pkg/util/timeutil"
+)
+
+const ChallengerLoggerTag = "RELAY_CHALLENGER"
+
+// defaultChallengerBlockRange is the default maximum number of blocks
+// scanned for OpPoked events in a single query.
+const defaultChallengerBlockRange = 1000
+
+// OpScribeChallengeContract is an OpScribe contract whose optimistic pokes
+// can be verified and challenged.
+type OpScribeChallengeContract interface {
+	OpScribeContract
+	OpChallengePeriod() contract.TypedSelfCaller[time.Duration]
+	ReadOpPokeData(ctx context.Context) (chronicle.PokeData, error)
+	ConstructPokeMessage(pokeData chronicle.PokeData) contract.TypedSelfCaller[types.Hash]
+	IsAcceptableSchnorrSignatureNow(message types.Hash, schnorrData chronicle.SchnorrData) contract.TypedSelfCaller[bool]
+	OpPokes(ctx context.Context, fromBlock, toBlock types.BlockNumber) ([]chronicle.OpPokeEvent, error)
+	OpChallenge(schnorrData chronicle.SchnorrData) contract.SelfTransactableCaller
+}
+
+var _ OpScribeChallengeContract = (*chronicle.OpScribe)(nil)
+
+// ChallengerConfig is the configuration for the Challenger.
+type ChallengerConfig struct {
+	// Relay is the relay whose optimistic scribe contracts are monitored.
+	Relay *Relay
+
+	// DataPointStore is the store used to verify values of optimistic
+	// pokes.
+	DataPointStore datapointStore.DataPointProvider
+
+	// BlockRange is the maximum number of blocks scanned for OpPoked events
+	// in a single query. It is also the number of past blocks scanned after
+	// the start. If zero, 1000 is used.
+	BlockRange uint64
+
+	// Ticker notifies the challenger to check for new optimistic pokes.
+	Ticker *timeutil.Ticker
+
+	// Logger is a current logger interface used by the Challenger.
+	// If nil, null logger will be used.
+	Logger log.Logger
+}
+
+// Challenger is a service that monitors optimistic pokes sent to OpScribe
+// contracts and challenges them if their Schnorr signatures are invalid.
+//
+// Optimistic pokes with a valid signature cannot be challenged, even if
+// their values differ from data points collected by the Challenger. Such
+// pokes are only reported in logs.
+type Challenger struct {
+	ctx            context.Context
+	waitCh         chan error
+	relay          *Relay
+	dataPointStore datapointStore.DataPointProvider
+	blockRange     uint64
+	ticker         *timeutil.Ticker
+	lastBlocks     map[challengeKey]uint64
+	log            log.Logger
+}
+
+type challengeKey struct {
+	client  rpc.RPC
+	address types.Address
+}
+
+// NewChallenger creates a new Challenger instance.
+func NewChallenger(cfg ChallengerConfig) (*Challenger, error) {
+	if cfg.Relay == nil {
+		return nil, errors.New("relay must not be nil")
+	}
+	if cfg.DataPointStore == nil {
+		return nil, errors.New("data point store must not be nil")
+	}
+	if cfg.Ticker == nil {
+		return nil, errors.New("ticker must not be nil")
+	}
+	if cfg.BlockRange == 0 {
+		cfg.BlockRange = defaultChallengerBlockRange
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = null.New()
+	}
+	return &Challenger{
+		waitCh:         make(chan error),
+		relay:          cfg.Relay,
+		dataPointStore: cfg.DataPointStore,
+		blockRange:     cfg.BlockRange,
+		ticker:         cfg.Ticker,
+		lastBlocks:     make(map[challengeKey]uint64),
+		log:            cfg.Logger.WithField("tag", ChallengerLoggerTag),
+	}, nil
+}
+
+// Start implements the supervisor.Service interface.
+func (c *Challenger) Start(ctx context.Context) error {
+	if c.ctx != nil {
+		return errors.New("service can be started only once")
+	}
+	if ctx == nil {
+		return errors.New("context must not be nil")
+	}
+	c.log.Info("Starting")
+	c.ctx = ctx
+	go c.challengeRoutine()
+	go c.contextCancelHandler()
+	return nil
+}
+
+// Wait implements the supervisor.Service interface.
+func (c *Challenger) Wait() <-chan error {
+	return c.waitCh
+}
+
+// check looks for new optimistic pokes in all OpScribe contracts handled
+// by the Relay.
+func (c *Challenger) check() {
+	for _, s := range c.relay.opScribes() {
+		opContract, ok := s.opContract.(OpScribeChallengeContract)
+		if !ok {
+			continue
+		}
+		c.checkContract(s, opContract)
+	}
+}
+
+// checkContract looks for new optimistic pokes in the given contract and
+// challenges the latest one if it is invalid.
+func (c *Challenger) checkContract(s *opScribe, opContract OpScribeChallengeContract) {
+	key := challengeKey{client: opContract.Client(), address: opContract.Address()}
+	latest, err := opContract.Client().BlockNumber(c.ctx)
+	if err != nil {
+		c.log.
+			WithError(err).
+			WithFields(s.logFields()).
+			WithAdvice("Ignore if it is related to temporary network issues").
+			Error("Failed to get the latest block number")
+		return
+	}
+	from := uint64(0)
+	if last, ok := c.lastBlocks[key]; ok {
+		from = last + 1
+	} else if latest.Uint64() >= c.blockRange {
+		from = latest.Uint64() - c.blockRange + 1
+	}
+	if from > latest.Uint64() {
+		return
+	}
+	to := latest.Uint64()
+	if to-from >= c.blockRange {
+		to = from + c.blockRange - 1
+	}
+	events, err := opContract.OpPokes(c.ctx, types.BlockNumberFromUint64(from), types.BlockNumberFromUint64(to))
+	if err != nil {
+		c.log.
+			WithError(err).
+			WithFields(s.logFields()).
+			WithAdvice("Ignore if it is related to temporary network issues").
+			Error("Failed to fetch OpPoked events")
+		return
+	}
+	c.lastBlocks[key] = to
+	if len(events) == 0 {
+		return
+	}
+
+	// Only the latest optimistic poke can be challenged, previous ones are
+	// either finalized or already overwritten.
+	event := events[len(events)-1]
+	challenge, err := c.shouldChallenge(s, opContract, event)
+	if err != nil {
+		c.log.
+			WithError(err).
+			WithFields(s.logFields()).
+			WithFields(opPokeEventLogFields(event)).
+			WithAdvice("Ignore if it is related to temporary network issues").
+			Error("Failed to verify the optimistic poke")
+		c.retryFrom(key, event.BlockNumber)
+		return
+	}
+	if !challenge {
+		return
+	}
+	c.log.
+		WithFields(s.logFields()).
+		WithFields(opPokeEventLogFields(event)).
+		Warn("Invalid optimistic poke found, sending challenge")
+	txHash, tx, err := opContract.OpChallenge(event.SchnorrData).SendTransaction(c.ctx)
+	if err != nil {
+		c.log.
+			WithError(err).
+			WithFields(s.logFields()).
+			WithFields(opPokeEventLogFields(event)).
+			WithAdvice("Ignore if it is related to temporary network issues").
+			Error("Failed to send challenge transaction")
+		c.retryFrom(key, event.BlockNumber)
+		return
+	}
+	c.log.
+		WithFields(s.logFields()).
+		WithFields(log.Fields{
+			"txHash":     txHash,
+			"txFrom":     tx.From,
+			"txTo":       tx.To,
+			"txChainId":  tx.ChainID,
+			"txNonce":    tx.Nonce,
+			"txGasLimit": tx.GasLimit,
+		}).
+		Info("Challenge transaction sent")
+}
+
+// shouldChallenge verifies the optimistic poke and returns true if it is
+// still challengeable and its Schnorr signature is invalid.
+func (c *Challenger) shouldChallenge(s *opScribe, opContract OpScribeChallengeContract, event chronicle.OpPokeEvent) (bool, error) {
+	// Verify that the poke from the event is still the current optimistic
+	// poke. Successfully challenged pokes, and pokes overwritten by regular
+	// pokes, are removed from the contract. The age stored in the contract
+	// may be set to the time of the opPoke, so it can only be greater than
+	// or equal to the age from the event.
+	opPokeData, err := opContract.ReadOpPokeData(c.ctx)
+	if err != nil {
+		return false, err
+	}
+	if opPokeData.Val == nil || opPokeData.Val.Cmp(event.PokeData.Val) != 0 || opPokeData.Age.Before(event.PokeData.Age) {
+		return false, nil
+	}
+
+	// Finalized pokes cannot be challenged.
+	challengePeriod, err := opContract.OpChallengePeriod().Call(c.ctx, types.LatestBlockNumber)
+	if err != nil {
+		return false, err
+	}
+	if !opPokeData.Age.Add(challengePeriod).After(time.Now()) {
+		return false, nil
+	}
+
+	// Verify the Schnorr signature using the contract itself, so the result
+	// is exactly the same as the one of the opChallenge call.
+	message, err := opContract.ConstructPokeMessage(event.PokeData).Call(c.ctx, types.LatestBlockNumber)
+	if err != nil {
+		return false, err
+	}
+	valid, err := opContract.IsAcceptableSchnorrSignatureNow(message, event.SchnorrData).Call(c.ctx, types.LatestBlockNumber)
+	if err != nil {
+		return false, err
+	}
+	if !valid {
+		return true, nil
+	}
+
+	c.verifyValue(s, event)
+	return false, nil
+}
+
+// verifyValue compares the value of the optimistic poke with the median of
+// data points from feeds that signed the poke.
+func (c *Challenger) verifyValue(s *opScribe, event chronicle.OpPokeEvent) {
+	points, err := c.dataPointStore.Latest(c.ctx, s.dataModel)
+	if err != nil {
+		c.log.
+			WithError(err).
+			WithFields(s.logFields()).
+			WithAdvice("This is a bug and needs to be investigated").
+			Error("Failed to get data points")
+		return
+	}
+	var signers []types.Address
+	for addr := range points {
+		if event.SchnorrData.FeedIDs.Has(addr) {
+			signers = append(signers, addr)
+		}
+	}
+	prices := tickPrices(points, signers, time.Time{})
+	if len(prices) == 0 {
+		c.log.
+			WithFields(s.logFields()).
+			WithFields(opPokeEventLogFields(event)).
+			Debug("No data points to verify the optimistic poke value")
+		return
+	}
+	median := bn.Median(prices)
+	spread := calculateSpread(event.PokeData.Val.DecFloatPoint(), median)
+	if math.IsInf(spread, 0) || spread > s.spread {
+		c.log.
+			WithFields(s.logFields()).
+			WithFields(opPokeEventLogFields(event)).
+			WithFields(log.Fields{
+				"median":        median,
+				"dataPoints":    len(prices),
+				"spread":        s.spread,
+				"currentSpread": spread,
+			}).
+			WithAdvice("The optimistic poke has a valid signature and cannot be challenged, verify data sources used by feeds").
+			Error("Optimistic poke value diverges from data points")
+	}
+}
+
+// retryFrom makes the Challenger scan events again starting from the given
+// block in the next check.
+func (c *Challenger) retryFrom(key challengeKey, block uint64) {
+	if block == 0 {
+		delete(c.lastBlocks, key)
+		return
+	}
+	c.lastBlocks[key] = block - 1
+}
+
+func (c *Challenger) challengeRoutine() {
+	c.ticker.Start(c.ctx)
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-c.ticker.TickCh():
+			c.check()
+		}
+	}
+}
+
+func (c *Challenger) contextCancelHandler() {
+	defer func() { close(c.waitCh) }()
+	defer c.log.Info("Stopped")
+	<-c.ctx.Done()
+}
+
+func opPokeEventLogFields(e chronicle.OpPokeEvent) log.Fields {
+	return log.Fields{
+		"opPokeBlock":  e.BlockNumber,
+		"opPokeTxHash": e.TxHash,
+		"opPokeCaller": e.Caller,
+		"opPokeFeed":   e.OpFeed,
+		"opPokeVal":    e.PokeData.Val,
+		"opPokeAge":    e.PokeData.Age,
+	}
+}
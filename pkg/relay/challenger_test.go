@@ -0,0 +1,227 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package relay
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/defiweb/go-eth/rpc"
+	"github.com/defiweb/go-eth/types"
+	"github.com/stretchr/testify/assert"
+	testifyMock "github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/orcfax/oracle-suite/pkg/contract"
+	"github.com/orcfax/oracle-suite/pkg/contract/chronicle"
+	"github.com/orcfax/oracle-suite/pkg/contract/mock"
+	"github.com/orcfax/oracle-suite/pkg/datapoint"
+	"github.com/orcfax/oracle-suite/pkg/datapoint/store"
+	"github.com/orcfax/oracle-suite/pkg/datapoint/value"
+	"github.com/orcfax/oracle-suite/pkg/ethereum/mocks"
+	"github.com/orcfax/oracle-suite/pkg/util/bn"
+	"github.com/orcfax/oracle-suite/pkg/util/timeutil"
+)
+
+func TestChallenger(t *testing.T) {
+	testFeed1 := types.MustAddressFromHex("0x1111111111111111111111111111111111111111")
+	testFeed2 := types.MustAddressFromHex("0x2222222222222222222222222222222222222222")
+	testContract := types.MustAddressFromHex("0x3333333333333333333333333333333333333333")
+	testMessage := types.MustHashFromHex("0x00112233445566778899aabbccddeeff00112233445566778899aabbccddeeff", types.PadNone)
+	mockLogger := newMockLogger(t)
+	mockContract := newMockOpScribeContract(t)
+	mockStore := newMockDataPointProvider(t)
+
+	relay, err := New(Config{Ticker: timeutil.NewTicker(0)})
+	require.NoError(t, err)
+	relay.providers = []callProvider{&opScribe{
+		scribe: scribe{
+			contract:   mockContract,
+			dataModel:  "ETH/USD",
+			spread:     1,
+			expiration: time.Hour,
+			log:        mockLogger,
+		},
+		opContract: mockContract,
+	}}
+
+	event := chronicle.OpPokeEvent{
+		BlockNumber: 90,
+		PokeData: chronicle.PokeData{
+			Val: bn.DecFixedPoint(100, chronicle.ScribePricePrecision),
+			Age: time.Now().Add(-time.Minute),
+		},
+		SchnorrData: chronicle.SchnorrData{
+			Signature:  big.NewInt(42),
+			Commitment: types.MustAddressFromHex("0x4444444444444444444444444444444444444444"),
+			FeedIDs:    chronicle.FeedIDsFromAddresses([]types.Address{testFeed1, testFeed2}),
+		},
+	}
+
+	// mockOpPoke mocks the contract calls used to verify the event.
+	mockOpPoke := func(t *testing.T, opPokeData chronicle.PokeData, validSignature bool) {
+		mockContract.OpPokesFn = func(ctx context.Context, fromBlock, toBlock types.BlockNumber) ([]chronicle.OpPokeEvent, error) {
+			return []chronicle.OpPokeEvent{event}, nil
+		}
+		mockContract.ReadOpPokeDataFn = func(ctx context.Context) (chronicle.PokeData, error) {
+			return opPokeData, nil
+		}
+		mockContract.OpChallengePeriodFn = func() contract.TypedSelfCaller[time.Duration] {
+			return mock.NewTypedCaller[time.Duration](t).MockResult(time.Hour, nil)
+		}
+		mockContract.ConstructPokeMessageFn = func(pokeData chronicle.PokeData) contract.TypedSelfCaller[types.Hash] {
+			assert.Equal(t, event.PokeData, pokeData)
+			return mock.NewTypedCaller[types.Hash](t).MockResult(testMessage, nil)
+		}
+		mockContract.IsAcceptableSchnorrSignatureNowFn = func(message types.Hash, schnorrData chronicle.SchnorrData) contract.TypedSelfCaller[bool] {
+			assert.Equal(t, testMessage, message)
+			assert.Equal(t, event.SchnorrData, schnorrData)
+			return mock.NewTypedCaller[bool](t).MockResult(validSignature, nil)
+		}
+	}
+	mockDataPoints := func(prices ...float64) {
+		mockStore.LatestFn = func(ctx context.Context, model string) (map[types.Address]store.StoredDataPoint, error) {
+			assert.Equal(t, "ETH/USD", model)
+			points := make(map[types.Address]store.StoredDataPoint)
+			for i, feed := range []types.Address{testFeed1, testFeed2}[:len(prices)] {
+				points[feed] = store.StoredDataPoint{
+					Model: model,
+					DataPoint: datapoint.Point{
+						Time:  time.Now(),
+						Value: value.Tick{Price: bn.DecFloatPoint(prices[i])},
+					},
+					From: feed,
+				}
+			}
+			return points, nil
+		}
+	}
+	newChallenger := func(t *testing.T, blockNumber uint64) *Challenger {
+		client := &mocks.RPC{}
+		client.On("BlockNumber", testifyMock.Anything).Return(new(big.Int).SetUint64(blockNumber), nil)
+		mockContract.ClientFn = func() rpc.RPC { return client }
+		mockContract.AddressFn = func() types.Address { return testContract }
+		c, err := NewChallenger(ChallengerConfig{
+			Relay:          relay,
+			DataPointStore: mockStore,
+			Ticker:         timeutil.NewTicker(0),
+			Logger:         mockLogger,
+		})
+		require.NoError(t, err)
+		c.ctx = context.Background()
+		return c
+	}
+	reset := func(t *testing.T) {
+		mockLogger.reset(t)
+		mockContract.reset(t)
+		mockStore.reset(t)
+	}
+
+	t.Run("invalid signature", func(t *testing.T) {
+		reset(t)
+		c := newChallenger(t, 100)
+		mockOpPoke(t, event.PokeData, false)
+
+		challenged := false
+		mockContract.OpChallengeFn = func(schnorrData chronicle.SchnorrData) contract.SelfTransactableCaller {
+			challenged = true
+			assert.Equal(t, event.SchnorrData, schnorrData)
+			caller := mock.NewCaller(t).MockAllowAllCalls()
+			caller.SendTransactionFn = func(ctx context.Context) (*types.Hash, *types.Transaction, error) {
+				return &types.Hash{}, &types.Transaction{}, nil
+			}
+			return caller
+		}
+		mockLogger.WarnFn = func(args ...any) {}
+		mockLogger.InfoFn = func(args ...any) {}
+
+		c.check()
+		assert.True(t, challenged, "opChallenge should have been called")
+	})
+
+	t.Run("valid signature", func(t *testing.T) {
+		reset(t)
+		c := newChallenger(t, 100)
+		mockOpPoke(t, event.PokeData, true)
+		mockDataPoints(100, 100.5)
+
+		c.check()
+	})
+
+	t.Run("valid signature, diverged value", func(t *testing.T) {
+		reset(t)
+		c := newChallenger(t, 100)
+		mockOpPoke(t, event.PokeData, true)
+		mockDataPoints(110, 112)
+
+		// Poke cannot be challenged, but an error is logged.
+		errorLogged := false
+		mockLogger.ErrorFn = func(args ...any) {
+			errorLogged = true
+			assert.Equal(t, "Optimistic poke value diverges from data points", args[0])
+		}
+
+		c.check()
+		assert.True(t, errorLogged, "error should have been logged")
+	})
+
+	t.Run("finalized", func(t *testing.T) {
+		reset(t)
+		c := newChallenger(t, 100)
+		mockOpPoke(t, chronicle.PokeData{Val: event.PokeData.Val, Age: time.Now().Add(-2 * time.Hour)}, false)
+		mockContract.ConstructPokeMessageFn = nil
+		mockContract.IsAcceptableSchnorrSignatureNowFn = nil
+
+		c.check()
+	})
+
+	t.Run("overwritten", func(t *testing.T) {
+		reset(t)
+		c := newChallenger(t, 100)
+		mockOpPoke(t, chronicle.PokeData{Val: bn.DecFixedPoint(0, chronicle.ScribePricePrecision)}, false)
+
+		c.check()
+	})
+
+	t.Run("block range", func(t *testing.T) {
+		reset(t)
+		c := newChallenger(t, 5000)
+		c.blockRange = 100
+
+		var ranges [][2]types.BlockNumber
+		mockContract.OpPokesFn = func(ctx context.Context, fromBlock, toBlock types.BlockNumber) ([]chronicle.OpPokeEvent, error) {
+			ranges = append(ranges, [2]types.BlockNumber{fromBlock, toBlock})
+			return []chronicle.OpPokeEvent{{BlockNumber: 4950}}, nil
+		}
+		mockContract.ReadOpPokeDataFn = func(ctx context.Context) (chronicle.PokeData, error) {
+			return chronicle.PokeData{}, errors.New("error")
+		}
+		mockLogger.ErrorFn = func(args ...any) {}
+
+		// The first check scans the last 100 blocks. Verification of the
+		// event fails, so the second check scans blocks again, starting from
+		// the block with the event.
+		c.check()
+		c.check()
+
+		require.Len(t, ranges, 2)
+		assert.Equal(t, [2]types.BlockNumber{types.BlockNumberFromUint64(4901), types.BlockNumberFromUint64(5000)}, ranges[0])
+		assert.Equal(t, [2]types.BlockNumber{types.BlockNumberFromUint64(4950), types.BlockNumberFromUint64(5000)}, ranges[1])
+	})
+}
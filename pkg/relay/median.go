@@ -28,6 +28,7 @@ import (
 	"github.com/orcfax/oracle-suite/pkg/datapoint/store"
 	"github.com/orcfax/oracle-suite/pkg/datapoint/value"
 	"github.com/orcfax/oracle-suite/pkg/log"
+	"github.com/orcfax/oracle-suite/pkg/tracing"
 	"github.com/orcfax/oracle-suite/pkg/util/bn"
 )
 
@@ -38,6 +39,7 @@ type median struct {
 	dataModel      string
 	spread         float64
 	expiration     time.Duration
+	reputation     Reputation
 	log            log.Logger
 }
 
@@ -72,9 +74,10 @@ func (w *median) createRelayCall(ctx context.Context) []relayCall {
 	if !ok {
 		return nil
 	}
+	linkDataPoints(tracing.SpanFromContext(ctx), dataPoints)
 
 	prices := dataPointsToPrices(dataPoints)
-	median := calculateMedian(prices)
+	median := bn.Median(prices)
 	spread := calculateSpread(median, state.val.DecFloatPoint())
 
 	// Check if price on the Median contract needs to be updated.
@@ -175,6 +178,13 @@ func (w *median) findDataPoints(ctx context.Context, after time.Time, quorum int
 	var dataPoints []datapoint.Point
 	var signatures []types.Signature
 	for _, i := range randIndices {
+		if w.reputation != nil && w.reputation.Excluded(w.feedAddresses[i]) {
+			w.log.
+				WithFields(w.logFields()).
+				WithField("feedAddress", w.feedAddresses[i]).
+				Debug("Feed excluded from the quorum due to poor data quality")
+			continue
+		}
 		sdp, ok, err := w.dataPointStore.LatestFrom(ctx, w.feedAddresses[i], w.dataModel)
 		if err != nil {
 			w.log.
@@ -223,6 +233,29 @@ func (w *median) findDataPoints(ctx context.Context, after time.Time, quorum int
 	return dataPoints, signatures, true
 }
 
+func (w *median) watchState(ctx context.Context) (watchState, error) {
+	s := watchState{
+		contractType: ContractTypeMedian,
+		client:       w.contract.Client(),
+		address:      w.contract.Address(),
+		dataModel:    w.dataModel,
+		feeds:        w.feedAddresses,
+		spread:       w.spread,
+		expiration:   w.expiration,
+	}
+	state, err := w.currentState(ctx)
+	if err != nil {
+		return s, err
+	}
+	if state.wat != w.dataModel {
+		return s, errWatMismatch
+	}
+	s.val = state.val.DecFloatPoint()
+	s.age = state.age
+	s.bar = state.bar
+	return s, nil
+}
+
 func (w *median) logFields() log.Fields {
 	return log.Fields{
 		"address":   w.contract.Address(),
@@ -530,4 +530,60 @@ func TestMedian(t *testing.T) {
 		median.createRelayCall(ctx)
 		assert.True(t, errLogCalled)
 	})
+
+	t.Run("excluded feed", func(t *testing.T) {
+		mockLogger.reset(t)
+		mockContract.reset(t)
+		mockTransport.reset(t)
+
+		median.reputation = reputationFunc(func(feed types.Address) bool { return feed == testFeed1 })
+		defer func() { median.reputation = nil }()
+
+		ctx := context.Background()
+		mockContract.ClientFn = func() rpc.RPC { return nil }
+		mockContract.AddressFn = func() types.Address { return types.Address{} }
+		mockContract.WatFn = func() contract.TypedSelfCaller[string] {
+			return mock.NewTypedCaller[string](t).MockResult("ETH/USD", nil)
+		}
+		mockContract.ValFn = func(ctx context.Context) (*bn.DecFixedPointNumber, error) {
+			return bn.DecFixedPoint(100, chronicle.MedianPricePrecision), nil
+		}
+		mockContract.AgeFn = func() contract.TypedSelfCaller[time.Time] {
+			return mock.NewTypedCaller[time.Time](t).MockResult(time.Now().Add(-1*time.Minute), nil)
+		}
+		mockContract.BarFn = func() contract.TypedSelfCaller[int] {
+			return mock.NewTypedCaller[int](t).MockResult(1, nil)
+		}
+		mockLogger.InfoFn = func(args ...any) {}
+		mockLogger.DebugFn = func(args ...any) {}
+		mockStore.LatestFromFn = func(ctx context.Context, from types.Address, model string) (store.StoredDataPoint, bool, error) {
+			assert.NotEqual(t, testFeed1, from, "excluded feed should not be queried")
+			price := map[types.Address]float64{testFeed2: 110, testFeed3: 120}[from]
+			return store.StoredDataPoint{
+				Model: "ETH/USD",
+				DataPoint: datapoint.Point{
+					Time:  time.Now(),
+					Value: value.Tick{Price: bn.DecFloatPoint(price)},
+				},
+				From:      from,
+				Signature: types.SignatureFromVRS(big.NewInt(27), big.NewInt(1), big.NewInt(2)),
+			}, true, nil
+		}
+
+		pokeCalled := false
+		mockContract.PokeFn = func(vals []chronicle.MedianVal) contract.SelfTransactableCaller {
+			pokeCalled = true
+			assert.Equal(t, 1, len(vals))
+			return mock.NewCaller(t).MockAllowAllCalls()
+		}
+
+		median.createRelayCall(ctx)
+		assert.True(t, pokeCalled, "poke should have been called")
+	})
+}
+
+type reputationFunc func(feed types.Address) bool
+
+func (f reputationFunc) Excluded(feed types.Address) bool {
+	return f(feed)
 }
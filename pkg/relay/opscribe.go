@@ -26,6 +26,7 @@ import (
 
 	"github.com/orcfax/oracle-suite/pkg/contract/chronicle"
 	"github.com/orcfax/oracle-suite/pkg/log"
+	"github.com/orcfax/oracle-suite/pkg/tracing"
 	"github.com/orcfax/oracle-suite/pkg/util/sliceutil"
 )
 
@@ -128,6 +129,7 @@ func (w *opScribe) createRelayCall(ctx context.Context) []relayCall {
 
 		// If price is stale or expired, return an optimistic poke transaction.
 		if isExpired || isStale {
+			linkDataPoints(tracing.SpanFromContext(ctx), w.signatureDataPoints(ctx, s, meta))
 			poke := w.opContract.OpPoke(
 				chronicle.PokeData{
 					Val: meta.Val,
@@ -170,6 +172,12 @@ func (w *opScribe) createRelayCall(ctx context.Context) []relayCall {
 	return w.scribe.createRelayCall(ctx)
 }
 
+func (w *opScribe) watchState(ctx context.Context) (watchState, error) {
+	s, err := w.scribe.watchState(ctx)
+	s.contractType = ContractTypeOptimisticScribe
+	return s, err
+}
+
 func (w *opScribe) handlePokeErr(err error) {
 	var customError abi.CustomError
 	if errors.As(err, &customError) && customError.Type.Name() == "InChallengePeriod" {
@@ -0,0 +1,461 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package relay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	goethABI "github.com/defiweb/go-eth/abi"
+	"github.com/defiweb/go-eth/rpc"
+	"github.com/defiweb/go-eth/rpc/transport"
+	"github.com/defiweb/go-eth/types"
+
+	"github.com/orcfax/oracle-suite/pkg/contract/chronicle"
+	datapointStore "github.com/orcfax/oracle-suite/pkg/datapoint/store"
+	"github.com/orcfax/oracle-suite/pkg/log"
+	"github.com/orcfax/oracle-suite/pkg/log/null"
+	musigStore "github.com/orcfax/oracle-suite/pkg/musig/store"
+	"github.com/orcfax/oracle-suite/pkg/util/sliceutil"
+	"github.com/orcfax/oracle-suite/pkg/util/timeutil"
+)
+
+const RegistryLoggerTag = "RELAY_REGISTRY"
+
+// ContractType is a type of oracle contract.
+type ContractType string
+
+const (
+	ContractTypeMedian           ContractType = "median"
+	ContractTypeScribe           ContractType = "scribe"
+	ContractTypeOptimisticScribe ContractType = "optimistic_scribe"
+)
+
+// DeploymentProvider provides a list of oracle contract deployments.
+//
+// It is implemented by the chronicle.Registry.
+type DeploymentProvider interface {
+	Deployments(ctx context.Context) ([]chronicle.Deployment, error)
+}
+
+// PokePolicy defines when contracts of a given type are updated.
+type PokePolicy struct {
+	// Spread is the minimum spread between the oracle price and new
+	// price required to send update.
+	Spread float64
+
+	// Expiration is the minimum time difference between the last oracle
+	// update and current time required to send update.
+	Expiration time.Duration
+
+	// OptimisticSpread is the minimum spread required to send an optimistic
+	// update. Used only for optimistic scribe contracts.
+	OptimisticSpread float64
+
+	// OptimisticExpiration is the minimum time difference required to send
+	// an optimistic update. Used only for optimistic scribe contracts.
+	OptimisticExpiration time.Duration
+}
+
+// Registry is a service that periodically builds the list of contracts
+// handled by the Relay from the on-chain registries.
+//
+// Contracts that are configured statically always take precedence over the
+// ones found in the registries.
+type Registry struct {
+	mu     sync.Mutex
+	ctx    context.Context
+	waitCh chan error
+
+	registry        DeploymentProvider
+	clients         []rpc.RPC
+	dataModels      []string
+	policies        map[ContractType]PokePolicy
+	staticContracts Contracts
+	dataPointStore  *datapointStore.Store
+	muSigStore      *musigStore.Store
+	reputation      Reputation
+	relay           *Relay
+	ticker          *timeutil.Ticker
+	log             log.Logger
+
+	chainClients  map[uint64]rpc.RPC
+	contractTypes map[contractKey]ContractType
+	contracts     Contracts
+}
+
+// contractKey identifies a contract deployed on a specific chain.
+type contractKey struct {
+	chainID uint64
+	address types.Address
+}
+
+// RegistryConfig is the configuration for the Registry.
+type RegistryConfig struct {
+	// Registry provides the list of deployed contracts.
+	Registry DeploymentProvider
+
+	// Clients is the list of RPC clients used to interact with contracts.
+	// Each client is matched with deployments by its chain ID. Deployments
+	// on chains without a client are ignored.
+	Clients []rpc.RPC
+
+	// DataModels is an optional list of data models to relay. If empty,
+	// all data models found in the registry are relayed.
+	DataModels []string
+
+	// Policies defines poke policies for each contract type. Contracts of
+	// a type without a policy are ignored.
+	Policies map[ContractType]PokePolicy
+
+	// StaticContracts is the list of statically configured contracts that
+	// are always relayed.
+	StaticContracts Contracts
+
+	// DataPointStore is the store used by median contracts. The list of
+	// collected models is updated after each refresh.
+	DataPointStore *datapointStore.Store
+
+	// MuSigStore is the store used by scribe contracts. The list of
+	// collected models is updated after each refresh.
+	MuSigStore *musigStore.Store
+
+	// Reputation is an optional source of feed reputation used by median
+	// contracts.
+	Reputation Reputation
+
+	// Relay is the relay service to update. If nil, the contracts are only
+	// resolved.
+	Relay *Relay
+
+	// Ticker notifies the registry to refresh the list of contracts.
+	Ticker *timeutil.Ticker
+
+	// Logger is a current logger interface used by the Registry.
+	// If nil, null logger will be used.
+	Logger log.Logger
+}
+
+// NewRegistry creates a new Registry instance.
+func NewRegistry(cfg RegistryConfig) (*Registry, error) {
+	if cfg.Registry == nil {
+		return nil, errors.New("registry must not be nil")
+	}
+	if cfg.Ticker == nil {
+		return nil, errors.New("ticker must not be nil")
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = null.New()
+	}
+	return &Registry{
+		waitCh:          make(chan error),
+		registry:        cfg.Registry,
+		clients:         cfg.Clients,
+		dataModels:      cfg.DataModels,
+		policies:        cfg.Policies,
+		staticContracts: cfg.StaticContracts,
+		dataPointStore:  cfg.DataPointStore,
+		muSigStore:      cfg.MuSigStore,
+		reputation:      cfg.Reputation,
+		relay:           cfg.Relay,
+		ticker:          cfg.Ticker,
+		log:             cfg.Logger.WithField("tag", RegistryLoggerTag),
+		contractTypes:   make(map[contractKey]ContractType),
+	}, nil
+}
+
+// Start implements the supervisor.Service interface.
+func (r *Registry) Start(ctx context.Context) error {
+	if r.ctx != nil {
+		return errors.New("service can be started only once")
+	}
+	if ctx == nil {
+		return errors.New("context must not be nil")
+	}
+	r.log.Info("Starting")
+	r.ctx = ctx
+	go r.refreshRoutine()
+	go r.contextCancelHandler()
+	return nil
+}
+
+// Wait implements the supervisor.Service interface.
+func (r *Registry) Wait() <-chan error {
+	return r.waitCh
+}
+
+// Contracts resolves the list of contracts from the on-chain registries.
+// The returned list does not include statically configured contracts.
+func (r *Registry) Contracts(ctx context.Context) (Contracts, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.resolveChainClients(ctx); err != nil {
+		return Contracts{}, err
+	}
+	deployments, err := r.registry.Deployments(ctx)
+	if err != nil {
+		return Contracts{}, err
+	}
+	// Avoid storing typed nil pointers in interfaces if stores are not set.
+	var (
+		dpStore datapointStore.DataPointProvider
+		msStore musigStore.SignatureProvider
+	)
+	if r.dataPointStore != nil {
+		dpStore = r.dataPointStore
+	}
+	if r.muSigStore != nil {
+		msStore = r.muSigStore
+	}
+	var c Contracts
+	for _, d := range deployments {
+		if len(r.dataModels) > 0 && !sliceutil.Contains(r.dataModels, d.Wat) {
+			continue
+		}
+		client, ok := r.chainClients[d.ChainID]
+		if !ok {
+			continue
+		}
+		typ, err := r.contractType(ctx, client, d.ChainID, d.Address)
+		if err != nil {
+			r.log.
+				WithError(err).
+				WithFields(deploymentLogFields(d)).
+				WithAdvice("Ignore if it is related to temporary network issues").
+				Warn("Unable to determine contract type")
+			continue
+		}
+		policy, ok := r.policies[typ]
+		if !ok {
+			continue
+		}
+		switch typ {
+		case ContractTypeMedian:
+			c.Medians = append(c.Medians, ConfigMedian{
+				Client:          client,
+				DataPointStore:  dpStore,
+				DataModel:       medianDataModel(d.Wat),
+				ContractAddress: d.Address,
+				FeedAddresses:   d.Feeds,
+				Spread:          policy.Spread,
+				Expiration:      policy.Expiration,
+				Reputation:      r.reputation,
+			})
+		case ContractTypeScribe:
+			c.Scribes = append(c.Scribes, ConfigScribe{
+				Client:          client,
+				MuSigStore:      msStore,
+				DataModel:       d.Wat,
+				ContractAddress: d.Address,
+				Spread:          policy.Spread,
+				Expiration:      policy.Expiration,
+			})
+		case ContractTypeOptimisticScribe:
+			c.OptimisticScribes = append(c.OptimisticScribes, ConfigOptimisticScribe{
+				Client:               client,
+				MuSigStore:           msStore,
+				DataModel:            d.Wat,
+				ContractAddress:      d.Address,
+				Spread:               policy.Spread,
+				Expiration:           policy.Expiration,
+				OptimisticSpread:     policy.OptimisticSpread,
+				OptimisticExpiration: policy.OptimisticExpiration,
+			})
+		}
+	}
+	return c, nil
+}
+
+// refresh resolves the list of contracts and updates the relay and stores.
+func (r *Registry) refresh() {
+	discovered, err := r.Contracts(r.ctx)
+	if err != nil {
+		r.log.
+			WithError(err).
+			WithAdvice("Ignore if it is related to temporary network issues; previously resolved contracts are still used").
+			Error("Failed to resolve contracts from the registry")
+		return
+	}
+	contracts := r.staticContracts.Merge(discovered)
+	r.logChanges(r.contracts, contracts)
+	r.contracts = contracts
+
+	medianModels, scribeModels := contracts.DataModels()
+	if r.dataPointStore != nil {
+		// Data points for scribe models are used by the Watcher to verify
+		// values stored in Scribe contracts.
+		r.dataPointStore.SetModels(append(medianModels, scribeModels...))
+	}
+	if r.muSigStore != nil {
+		r.muSigStore.SetDataModels(scribeModels)
+	}
+	if r.relay != nil {
+		r.relay.SetContracts(contracts)
+	}
+}
+
+// resolveChainClients maps configured clients to their chain IDs.
+func (r *Registry) resolveChainClients(ctx context.Context) error {
+	if r.chainClients != nil {
+		return nil
+	}
+	chainClients := make(map[uint64]rpc.RPC)
+	for _, client := range r.clients {
+		chainID, err := client.ChainID(ctx)
+		if err != nil {
+			return fmt.Errorf("unable to fetch chain ID: %w", err)
+		}
+		if _, ok := chainClients[chainID]; ok {
+			return fmt.Errorf("multiple clients configured for chain ID %d", chainID)
+		}
+		chainClients[chainID] = client
+	}
+	r.chainClients = chainClients
+	return nil
+}
+
+// contractType returns the type of the contract at the given address on
+// the given chain. Results are cached, because the type of contract never
+// changes.
+func (r *Registry) contractType(ctx context.Context, client rpc.RPC, chainID uint64, address types.Address) (ContractType, error) {
+	key := contractKey{chainID: chainID, address: address}
+	if typ, ok := r.contractTypes[key]; ok {
+		return typ, nil
+	}
+	typ, err := DetectContractType(ctx, client, address)
+	if err != nil {
+		return "", err
+	}
+	r.contractTypes[key] = typ
+	return typ, nil
+}
+
+func (r *Registry) logChanges(prev, next Contracts) {
+	prevAddrs := contractAddresses(prev)
+	nextAddrs := contractAddresses(next)
+	for addr, typ := range nextAddrs {
+		if _, ok := prevAddrs[addr]; !ok {
+			r.log.
+				WithFields(log.Fields{"address": addr, "type": typ}).
+				Info("Contract added")
+		}
+	}
+	for addr, typ := range prevAddrs {
+		if _, ok := nextAddrs[addr]; !ok {
+			r.log.
+				WithFields(log.Fields{"address": addr, "type": typ}).
+				Info("Contract removed")
+		}
+	}
+}
+
+func (r *Registry) refreshRoutine() {
+	r.ticker.Start(r.ctx)
+	r.refresh()
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-r.ticker.TickCh():
+			r.refresh()
+		}
+	}
+}
+
+func (r *Registry) contextCancelHandler() {
+	defer func() { close(r.waitCh) }()
+	defer r.log.Info("Stopped")
+	<-r.ctx.Done()
+}
+
+// DetectContractType detects the type of oracle contract by calling
+// methods specific to each contract type. A contract is not of the given
+// type only if the call reverts, other errors are returned, so that
+// temporary failures of the client do not cause misdetection.
+func DetectContractType(ctx context.Context, client rpc.RPC, address types.Address) (ContractType, error) {
+	// Every oracle contract implements the wat method. If this call fails,
+	// the contract is either not an oracle or the client is not available,
+	// in which case the remaining checks would be unreliable.
+	if _, err := chronicle.NewMedian(client, address).Wat().Call(ctx, types.LatestBlockNumber); err != nil {
+		return "", fmt.Errorf("unable to detect contract type: %w", err)
+	}
+	_, err := chronicle.NewOpScribe(client, address).OpChallengePeriod().Call(ctx, types.LatestBlockNumber)
+	switch {
+	case err == nil:
+		return ContractTypeOptimisticScribe, nil
+	case !isRevert(err):
+		return "", fmt.Errorf("unable to detect contract type: %w", err)
+	}
+	_, err = chronicle.NewScribe(client, address).Feeds().Call(ctx, types.LatestBlockNumber)
+	switch {
+	case err == nil:
+		return ContractTypeScribe, nil
+	case !isRevert(err):
+		return "", fmt.Errorf("unable to detect contract type: %w", err)
+	}
+	return ContractTypeMedian, nil
+}
+
+// isRevert returns true if the error indicates that the contract reverted
+// the call, as opposed to a failure of the client.
+func isRevert(err error) bool {
+	var customErr goethABI.CustomError
+	if errors.As(err, &customErr) {
+		return true
+	}
+	var codeErr interface{ RPCErrorCode() int }
+	if errors.As(err, &codeErr) {
+		switch codeErr.RPCErrorCode() {
+		case transport.ErrCodeExecutionError, transport.NethermindErrCodeExecutionError:
+			return true
+		}
+	}
+	// Some nodes use a generic error code for reverts without data.
+	return strings.Contains(err.Error(), "execution reverted")
+}
+
+// medianDataModel returns the data model name used by Median contracts,
+// which use asset names without a slash, e.g. "BTCUSD".
+func medianDataModel(wat string) string {
+	return strings.ReplaceAll(wat, "/", "")
+}
+
+func contractAddresses(c Contracts) map[types.Address]ContractType {
+	addrs := make(map[types.Address]ContractType)
+	for _, m := range c.Medians {
+		addrs[m.ContractAddress] = ContractTypeMedian
+	}
+	for _, s := range c.Scribes {
+		addrs[s.ContractAddress] = ContractTypeScribe
+	}
+	for _, s := range c.OptimisticScribes {
+		addrs[s.ContractAddress] = ContractTypeOptimisticScribe
+	}
+	return addrs
+}
+
+func deploymentLogFields(d chronicle.Deployment) log.Fields {
+	return log.Fields{
+		"address": d.Address,
+		"chainID": d.ChainID,
+		"wat":     d.Wat,
+		"bar":     d.Bar,
+	}
+}
@@ -0,0 +1,311 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package relay
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	goethABI "github.com/defiweb/go-eth/abi"
+	"github.com/defiweb/go-eth/rpc"
+	"github.com/defiweb/go-eth/rpc/transport"
+	"github.com/defiweb/go-eth/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/orcfax/oracle-suite/pkg/contract/chronicle"
+	datapointStore "github.com/orcfax/oracle-suite/pkg/datapoint/store"
+	"github.com/orcfax/oracle-suite/pkg/ethereum/mocks"
+	"github.com/orcfax/oracle-suite/pkg/log/null"
+	musigStore "github.com/orcfax/oracle-suite/pkg/musig/store"
+	"github.com/orcfax/oracle-suite/pkg/util/timeutil"
+)
+
+type mockDeploymentProvider struct {
+	deployments []chronicle.Deployment
+	err         error
+}
+
+func (m *mockDeploymentProvider) Deployments(_ context.Context) ([]chronicle.Deployment, error) {
+	return m.deployments, m.err
+}
+
+var (
+	testOpScribeAddr = types.MustAddressFromHex("0x1111111111111111111111111111111111111111")
+	testScribeAddr   = types.MustAddressFromHex("0x2222222222222222222222222222222222222222")
+	testMedianAddr   = types.MustAddressFromHex("0x3333333333333333333333333333333333333333")
+	testOtherAddr    = types.MustAddressFromHex("0x4444444444444444444444444444444444444444")
+	testFeedAddr     = types.MustAddressFromHex("0x5555555555555555555555555555555555555555")
+)
+
+// mockOracleCalls sets up the mock RPC client to respond to calls used to
+// detect contract types.
+func mockOracleCalls(client *mocks.RPC) {
+	var (
+		watSelector               = goethABI.MustParseMethod("wat()").FourBytes()
+		opChallengePeriodSelector = goethABI.MustParseMethod("opChallengePeriod()").FourBytes()
+		feedsSelector             = goethABI.MustParseMethod("feeds()").FourBytes()
+	)
+	callTo := func(addr types.Address, selector goethABI.FourBytes) any {
+		return mock.MatchedBy(func(call types.Call) bool {
+			return call.To != nil && *call.To == addr && bytes.HasPrefix(call.Input, selector.Bytes())
+		})
+	}
+	word := make([]byte, 32)
+	word[31] = 1
+	emptyArray := append(append(make([]byte, 31), 0x20), make([]byte, 32)...)
+	revert := errors.New("execution reverted")
+
+	for _, addr := range []types.Address{testOpScribeAddr, testScribeAddr, testMedianAddr} {
+		client.On("Call", mock.Anything, callTo(addr, watSelector), mock.Anything).Return(word, &types.Call{}, nil)
+	}
+	client.On("Call", mock.Anything, callTo(testOpScribeAddr, opChallengePeriodSelector), mock.Anything).Return(word, &types.Call{}, nil)
+	client.On("Call", mock.Anything, callTo(testScribeAddr, opChallengePeriodSelector), mock.Anything).Return([]byte{}, &types.Call{}, revert)
+	client.On("Call", mock.Anything, callTo(testMedianAddr, opChallengePeriodSelector), mock.Anything).Return([]byte{}, &types.Call{}, revert)
+	client.On("Call", mock.Anything, callTo(testScribeAddr, feedsSelector), mock.Anything).Return(emptyArray, &types.Call{}, nil)
+	client.On("Call", mock.Anything, callTo(testMedianAddr, feedsSelector), mock.Anything).Return([]byte{}, &types.Call{}, revert)
+}
+
+func TestRegistry_Contracts(t *testing.T) {
+	ctx := context.Background()
+	client := &mocks.RPC{}
+	client.On("ChainID", mock.Anything).Return(uint64(1), nil)
+	mockOracleCalls(client)
+
+	registry, err := NewRegistry(RegistryConfig{
+		Registry: &mockDeploymentProvider{
+			deployments: []chronicle.Deployment{
+				{Address: testOpScribeAddr, ChainID: 1, Wat: "ETH/USD", Bar: 13},
+				{Address: testScribeAddr, ChainID: 1, Wat: "BTC/USD", Bar: 13},
+				{Address: testMedianAddr, ChainID: 1, Wat: "BTC/USD", Bar: 13, Feeds: []types.Address{testFeedAddr}},
+				{Address: testOtherAddr, ChainID: 2, Wat: "BTC/USD", Bar: 13},
+			},
+		},
+		Clients: []rpc.RPC{client},
+		Policies: map[ContractType]PokePolicy{
+			ContractTypeMedian:           {Spread: 1, Expiration: time.Hour},
+			ContractTypeScribe:           {Spread: 2, Expiration: 2 * time.Hour},
+			ContractTypeOptimisticScribe: {Spread: 3, Expiration: 3 * time.Hour, OptimisticSpread: 0.5, OptimisticExpiration: time.Minute},
+		},
+		Ticker: timeutil.NewTicker(0),
+	})
+	require.NoError(t, err)
+
+	contracts, err := registry.Contracts(ctx)
+	require.NoError(t, err)
+
+	require.Len(t, contracts.Medians, 1)
+	assert.Equal(t, testMedianAddr, contracts.Medians[0].ContractAddress)
+	assert.Equal(t, "BTCUSD", contracts.Medians[0].DataModel)
+	assert.Equal(t, []types.Address{testFeedAddr}, contracts.Medians[0].FeedAddresses)
+	assert.Equal(t, 1.0, contracts.Medians[0].Spread)
+	assert.Equal(t, time.Hour, contracts.Medians[0].Expiration)
+	assert.Nil(t, contracts.Medians[0].DataPointStore)
+
+	require.Len(t, contracts.Scribes, 1)
+	assert.Equal(t, testScribeAddr, contracts.Scribes[0].ContractAddress)
+	assert.Equal(t, "BTC/USD", contracts.Scribes[0].DataModel)
+	assert.Equal(t, 2.0, contracts.Scribes[0].Spread)
+
+	require.Len(t, contracts.OptimisticScribes, 1)
+	assert.Equal(t, testOpScribeAddr, contracts.OptimisticScribes[0].ContractAddress)
+	assert.Equal(t, "ETH/USD", contracts.OptimisticScribes[0].DataModel)
+	assert.Equal(t, 0.5, contracts.OptimisticScribes[0].OptimisticSpread)
+	assert.Equal(t, time.Minute, contracts.OptimisticScribes[0].OptimisticExpiration)
+}
+
+func TestRegistry_ContractsFilteredByDataModel(t *testing.T) {
+	ctx := context.Background()
+	client := &mocks.RPC{}
+	client.On("ChainID", mock.Anything).Return(uint64(1), nil)
+	mockOracleCalls(client)
+
+	registry, err := NewRegistry(RegistryConfig{
+		Registry: &mockDeploymentProvider{
+			deployments: []chronicle.Deployment{
+				{Address: testOpScribeAddr, ChainID: 1, Wat: "ETH/USD", Bar: 13},
+				{Address: testScribeAddr, ChainID: 1, Wat: "BTC/USD", Bar: 13},
+			},
+		},
+		Clients:    []rpc.RPC{client},
+		DataModels: []string{"ETH/USD"},
+		Policies: map[ContractType]PokePolicy{
+			ContractTypeScribe:           {Spread: 2, Expiration: 2 * time.Hour},
+			ContractTypeOptimisticScribe: {Spread: 3, Expiration: 3 * time.Hour},
+		},
+		Ticker: timeutil.NewTicker(0),
+	})
+	require.NoError(t, err)
+
+	contracts, err := registry.Contracts(ctx)
+	require.NoError(t, err)
+	assert.Len(t, contracts.Scribes, 0)
+	assert.Len(t, contracts.OptimisticScribes, 1)
+}
+
+func TestRegistry_ContractTypeCachedPerChain(t *testing.T) {
+	ctx := context.Background()
+	client1 := &mocks.RPC{}
+	client1.On("ChainID", mock.Anything).Return(uint64(1), nil)
+	mockOracleCalls(client1)
+
+	// On the second chain, the same address is used by a Median contract.
+	client2 := &mocks.RPC{}
+	client2.On("ChainID", mock.Anything).Return(uint64(2), nil)
+	revert := &transport.RPCError{Code: transport.ErrCodeExecutionError, Message: "execution reverted"}
+	word := make([]byte, 32)
+	client2.On("Call", mock.Anything, mock.MatchedBy(func(call types.Call) bool {
+		return bytes.HasPrefix(call.Input, goethABI.MustParseMethod("wat()").FourBytes().Bytes())
+	}), mock.Anything).Return(word, &types.Call{}, nil)
+	client2.On("Call", mock.Anything, mock.Anything, mock.Anything).Return([]byte{}, &types.Call{}, revert)
+
+	registry, err := NewRegistry(RegistryConfig{
+		Registry: &mockDeploymentProvider{
+			deployments: []chronicle.Deployment{
+				{Address: testScribeAddr, ChainID: 1, Wat: "BTC/USD", Bar: 13},
+				{Address: testScribeAddr, ChainID: 2, Wat: "BTC/USD", Bar: 13},
+			},
+		},
+		Clients: []rpc.RPC{client1, client2},
+		Policies: map[ContractType]PokePolicy{
+			ContractTypeMedian: {Spread: 1, Expiration: time.Hour},
+			ContractTypeScribe: {Spread: 2, Expiration: 2 * time.Hour},
+		},
+		Ticker: timeutil.NewTicker(0),
+	})
+	require.NoError(t, err)
+
+	contracts, err := registry.Contracts(ctx)
+	require.NoError(t, err)
+	require.Len(t, contracts.Scribes, 1)
+	assert.Equal(t, client1, contracts.Scribes[0].Client)
+	require.Len(t, contracts.Medians, 1)
+	assert.Equal(t, client2, contracts.Medians[0].Client)
+}
+
+func TestDetectContractType_ClientError(t *testing.T) {
+	ctx := context.Background()
+	word := make([]byte, 32)
+	watSelector := goethABI.MustParseMethod("wat()").FourBytes()
+	isWat := mock.MatchedBy(func(call types.Call) bool {
+		return bytes.HasPrefix(call.Input, watSelector.Bytes())
+	})
+
+	tests := []struct {
+		name    string
+		err     error
+		want    ContractType
+		wantErr bool
+	}{
+		{name: "revert", err: errors.New("execution reverted"), want: ContractTypeMedian},
+		{name: "execution error", err: &transport.RPCError{Code: transport.ErrCodeExecutionError}, want: ContractTypeMedian},
+		{name: "timeout", err: context.DeadlineExceeded, wantErr: true},
+		{name: "rpc error", err: &transport.RPCError{Code: transport.ErrCodeLimitExceeded, Message: "limit exceeded"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &mocks.RPC{}
+			client.On("Call", mock.Anything, isWat, mock.Anything).Return(word, &types.Call{}, nil)
+			client.On("Call", mock.Anything, mock.Anything, mock.Anything).Return([]byte{}, &types.Call{}, tt.err)
+
+			typ, err := DetectContractType(ctx, client, testMedianAddr)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, typ)
+		})
+	}
+}
+
+func TestRegistry_Refresh(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := &mocks.RPC{}
+	client.On("ChainID", mock.Anything).Return(uint64(1), nil)
+	mockOracleCalls(client)
+
+	dpStore, err := datapointStore.New(datapointStore.Config{
+		Storage:   datapointStore.NewMemoryStorage(),
+		Transport: newMockTransport(t),
+		Models:    []string{"ETHUSD"},
+	})
+	require.NoError(t, err)
+	msStore := musigStore.New(musigStore.Config{
+		Transport: newMockTransport(t),
+		Logger:    null.New(),
+	})
+	relay, err := New(Config{
+		Medians: []ConfigMedian{{
+			Client:          client,
+			ContractAddress: testOtherAddr,
+			DataModel:       "ETHUSD",
+			DataPointStore:  dpStore,
+		}},
+		Ticker: timeutil.NewTicker(0),
+	})
+	require.NoError(t, err)
+
+	provider := &mockDeploymentProvider{
+		deployments: []chronicle.Deployment{
+			{Address: testScribeAddr, ChainID: 1, Wat: "BTC/USD", Bar: 13},
+		},
+	}
+	registry, err := NewRegistry(RegistryConfig{
+		Registry: provider,
+		Clients:  []rpc.RPC{client},
+		Policies: map[ContractType]PokePolicy{
+			ContractTypeMedian: {Spread: 1, Expiration: time.Hour},
+			ContractTypeScribe: {Spread: 2, Expiration: 2 * time.Hour},
+		},
+		StaticContracts: relay.Contracts(),
+		DataPointStore:  dpStore,
+		MuSigStore:      msStore,
+		Relay:           relay,
+		Ticker:          timeutil.NewTicker(0),
+	})
+	require.NoError(t, err)
+	registry.ctx = ctx
+
+	// Static contracts are kept, discovered ones are added.
+	registry.refresh()
+	contracts := relay.Contracts()
+	require.Len(t, contracts.Medians, 1)
+	require.Len(t, contracts.Scribes, 1)
+	assert.Equal(t, testScribeAddr, contracts.Scribes[0].ContractAddress)
+	assert.Equal(t, []string{"ETHUSD", "BTC/USD"}, dpStore.Models())
+
+	// Deployment removed from the registry.
+	provider.deployments = []chronicle.Deployment{
+		{Address: testMedianAddr, ChainID: 1, Wat: "BTC/USD", Bar: 13},
+	}
+	registry.refresh()
+	contracts = relay.Contracts()
+	require.Len(t, contracts.Medians, 2)
+	assert.Len(t, contracts.Scribes, 0)
+	assert.ElementsMatch(t, []string{"ETHUSD", "BTCUSD"}, dpStore.Models())
+
+	// Registry errors do not affect the current list of contracts.
+	provider.err = errors.New("error")
+	registry.refresh()
+	assert.Len(t, relay.Contracts().Medians, 2)
+}
@@ -18,29 +18,35 @@ package relay
 import (
 	"context"
 	"errors"
-	"strings"
+	"fmt"
+	"sort"
 	"sync"
 	"time"
 
-	"github.com/defiweb/go-eth/hexutil"
 	"github.com/defiweb/go-eth/rpc"
 	"github.com/defiweb/go-eth/types"
 
 	"github.com/orcfax/oracle-suite/pkg/contract"
 	"github.com/orcfax/oracle-suite/pkg/contract/chronicle"
-	"github.com/orcfax/oracle-suite/pkg/contract/multicall"
 	datapointStore "github.com/orcfax/oracle-suite/pkg/datapoint/store"
 	"github.com/orcfax/oracle-suite/pkg/log"
 	"github.com/orcfax/oracle-suite/pkg/log/null"
+	"github.com/orcfax/oracle-suite/pkg/metrics"
 	musigStore "github.com/orcfax/oracle-suite/pkg/musig/store"
 	"github.com/orcfax/oracle-suite/pkg/util/bn"
-	"github.com/orcfax/oracle-suite/pkg/util/errutil"
 	"github.com/orcfax/oracle-suite/pkg/util/sliceutil"
 	"github.com/orcfax/oracle-suite/pkg/util/timeutil"
 )
 
 const LoggerTag = "RELAY"
 
+// Names of metrics reported by the Relay.
+const (
+	WorkerHealthyMetric     = "relay_worker_healthy"
+	WorkerFailuresMetric    = "relay_worker_failures"
+	WorkerLastSuccessMetric = "relay_worker_last_success_timestamp_seconds"
+)
+
 const (
 	// baseGasUsage is the base gas usage for an Ethereum transaction.
 	baseGasUsage = 21_000
@@ -81,6 +87,12 @@ type OpScribeContract interface {
 	OpPoke(pokeData chronicle.PokeData, schnorrData chronicle.SchnorrData, ecdsaData types.Signature) contract.SelfTransactableCaller
 }
 
+// Reputation decides whether a feed should be excluded from quorum
+// selection because of poor data quality.
+type Reputation interface {
+	Excluded(feed types.Address) bool
+}
+
 // callProvider provides a contract call that can be used to relay data to the
 // contract.
 type callProvider interface {
@@ -98,14 +110,91 @@ type relayCall struct {
 }
 
 // Relay is a service that relays data to the blockchain.
+//
+// Contracts are grouped by their RPC clients and every group is handled by
+// a separate worker, so a slow or failing chain does not delay updates on
+// other chains.
 type Relay struct {
+	mu        sync.RWMutex
 	ctx       context.Context
 	waitCh    chan error
 	ticker    *timeutil.Ticker
+	contracts Contracts
 	providers []callProvider
+	workers   map[rpc.RPC]*worker
+	metrics   metrics.Registry
 	log       log.Logger
 }
 
+// Contracts is the list of contracts handled by the Relay.
+type Contracts struct {
+	// Medians is the list of median contracts configuration.
+	Medians []ConfigMedian
+
+	// Scribes is the list of scribe contracts configuration.
+	Scribes []ConfigScribe
+
+	// OptimisticScribes is the list of scribe optimistic contracts configuration.
+	OptimisticScribes []ConfigOptimisticScribe
+}
+
+// Merge returns a new list of contracts that contains contracts from both
+// lists. If a contract with the same address and client exists in both
+// lists, the one from c is used.
+func (c Contracts) Merge(o Contracts) Contracts {
+	type key struct {
+		client  rpc.RPC
+		address types.Address
+	}
+	var (
+		r    Contracts
+		seen = make(map[key]bool)
+	)
+	for _, l := range []Contracts{c, o} {
+		for _, m := range l.Medians {
+			if k := (key{m.Client, m.ContractAddress}); !seen[k] {
+				seen[k] = true
+				r.Medians = append(r.Medians, m)
+			}
+		}
+		for _, s := range l.Scribes {
+			if k := (key{s.Client, s.ContractAddress}); !seen[k] {
+				seen[k] = true
+				r.Scribes = append(r.Scribes, s)
+			}
+		}
+		for _, s := range l.OptimisticScribes {
+			if k := (key{s.Client, s.ContractAddress}); !seen[k] {
+				seen[k] = true
+				r.OptimisticScribes = append(r.OptimisticScribes, s)
+			}
+		}
+	}
+	return r
+}
+
+// DataModels returns the list of data models used by the median contracts
+// and the list of data models used by the scribe contracts, including
+// the optimistic ones.
+func (c Contracts) DataModels() (medianModels []string, scribeModels []string) {
+	for _, m := range c.Medians {
+		if !sliceutil.Contains(medianModels, m.DataModel) {
+			medianModels = append(medianModels, m.DataModel)
+		}
+	}
+	for _, s := range c.Scribes {
+		if !sliceutil.Contains(scribeModels, s.DataModel) {
+			scribeModels = append(scribeModels, s.DataModel)
+		}
+	}
+	for _, s := range c.OptimisticScribes {
+		if !sliceutil.Contains(scribeModels, s.DataModel) {
+			scribeModels = append(scribeModels, s.DataModel)
+		}
+	}
+	return medianModels, scribeModels
+}
+
 // Config is the configuration for the Relay.
 type Config struct {
 	// Medians is the list of median contracts configuration.
@@ -117,9 +206,19 @@ type Config struct {
 	// OptimisticScribes is the list of scribe optimistic contracts configuration.
 	OptimisticScribes []ConfigOptimisticScribe
 
-	// Ticker notifies the relay to check if an update is required.
+	// Workers is the list of relay workers configuration. Contracts that
+	// use an RPC client without a configured worker are handled by a worker
+	// with default settings.
+	Workers []ConfigWorker
+
+	// Ticker notifies the relay to check if an update is required. It is
+	// used by workers without their own ticker.
 	Ticker *timeutil.Ticker
 
+	// Metrics is an optional metrics registry to which the health of relay
+	// workers is reported after every relay attempt.
+	Metrics metrics.Registry
+
 	// Logger is a current logger interface used by the Relay.
 	// If nil, null logger will be used.
 	Logger log.Logger
@@ -151,6 +250,10 @@ type ConfigMedian struct {
 	// update on the Median contract and current time required to send
 	// update.
 	Expiration time.Duration
+
+	// Reputation is an optional source of feed reputation. Excluded feeds
+	// are not used to reach the quorum.
+	Reputation Reputation
 }
 
 type ConfigScribe struct {
@@ -160,6 +263,10 @@ type ConfigScribe struct {
 	// MuSigStore is the store used to retrieve MuSig signatures.
 	MuSigStore musigStore.SignatureProvider
 
+	// DataPointStore is an optional store used to find data points included
+	// in MuSig signatures, to link their traces.
+	DataPointStore datapointStore.DataPointProvider
+
 	// DataModel is the name of the data model that is used to update
 	// the Scribe contract.
 	DataModel string
@@ -184,6 +291,10 @@ type ConfigOptimisticScribe struct {
 	// MuSigStore is the store used to retrieve MuSig signatures.
 	MuSigStore musigStore.SignatureProvider
 
+	// DataPointStore is an optional store used to find data points included
+	// in MuSig signatures, to link their traces.
+	DataPointStore datapointStore.DataPointProvider
+
 	// DataModel is the name of the data model that is used to update
 	// the OptimisticScribe contract.
 	DataModel string
@@ -216,50 +327,163 @@ func New(cfg Config) (*Relay, error) {
 	if cfg.Logger == nil {
 		cfg.Logger = null.New()
 	}
-	logger := cfg.Logger.WithField("tag", LoggerTag)
+	if cfg.Metrics == nil {
+		cfg.Metrics = metrics.NewNull()
+	}
 	r := &Relay{
-		waitCh: make(chan error),
-		ticker: cfg.Ticker,
-		log:    logger,
+		waitCh:  make(chan error),
+		ticker:  cfg.Ticker,
+		workers: make(map[rpc.RPC]*worker),
+		metrics: cfg.Metrics,
+		log:     cfg.Logger.WithField("tag", LoggerTag),
+	}
+	for _, w := range cfg.Workers {
+		if w.Client == nil {
+			return nil, errors.New("worker client must not be nil")
+		}
+		if _, ok := r.workers[w.Client]; ok {
+			return nil, fmt.Errorf("duplicate worker for client %q", w.Name)
+		}
+		r.workers[w.Client] = newWorker(w, r.metrics, r.log)
 	}
-	for _, s := range cfg.OptimisticScribes {
+	r.SetContracts(Contracts{
+		Medians:           cfg.Medians,
+		Scribes:           cfg.Scribes,
+		OptimisticScribes: cfg.OptimisticScribes,
+	})
+	return r, nil
+}
+
+// Contracts returns the list of contracts currently handled by the Relay.
+func (m *Relay) Contracts() Contracts {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.contracts
+}
+
+// SetContracts replaces the list of contracts handled by the Relay.
+//
+// It is safe to call this method while the Relay is running. The new list
+// of contracts is used starting from the next tick.
+func (m *Relay) SetContracts(c Contracts) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var (
+		providers       []callProvider
+		clientProviders = make(map[rpc.RPC][]callProvider)
+	)
+	for _, s := range c.OptimisticScribes {
 		contract := chronicle.NewOpScribe(s.Client, s.ContractAddress)
-		r.providers = append(r.providers, &opScribe{
+		provider := &opScribe{
 			scribe: scribe{
-				contract:   contract,
-				muSigStore: s.MuSigStore,
-				dataModel:  s.DataModel,
-				spread:     s.Spread,
-				expiration: s.Expiration,
-				log:        logger,
+				contract:       contract,
+				muSigStore:     s.MuSigStore,
+				dataPointStore: s.DataPointStore,
+				dataModel:      s.DataModel,
+				spread:         s.Spread,
+				expiration:     s.Expiration,
+				log:            m.worker(s.Client).log,
 			},
 			opContract:   contract,
 			opSpread:     s.OptimisticSpread,
 			opExpiration: s.OptimisticExpiration,
-		})
+		}
+		providers = append(providers, provider)
+		clientProviders[s.Client] = append(clientProviders[s.Client], provider)
 	}
-	for _, s := range cfg.Scribes {
-		r.providers = append(r.providers, &scribe{
-			contract:   chronicle.NewScribe(s.Client, s.ContractAddress),
-			muSigStore: s.MuSigStore,
-			dataModel:  s.DataModel,
-			spread:     s.Spread,
-			expiration: s.Expiration,
-			log:        logger,
-		})
+	for _, s := range c.Scribes {
+		provider := &scribe{
+			contract:       chronicle.NewScribe(s.Client, s.ContractAddress),
+			muSigStore:     s.MuSigStore,
+			dataPointStore: s.DataPointStore,
+			dataModel:      s.DataModel,
+			spread:         s.Spread,
+			expiration:     s.Expiration,
+			log:            m.worker(s.Client).log,
+		}
+		providers = append(providers, provider)
+		clientProviders[s.Client] = append(clientProviders[s.Client], provider)
 	}
-	for _, m := range cfg.Medians {
-		r.providers = append(r.providers, &median{
-			contract:       chronicle.NewMedian(m.Client, m.ContractAddress),
-			dataPointStore: m.DataPointStore,
-			feedAddresses:  m.FeedAddresses,
-			dataModel:      m.DataModel,
-			spread:         m.Spread,
-			expiration:     m.Expiration,
-			log:            logger,
-		})
+	for _, md := range c.Medians {
+		provider := &median{
+			contract:       chronicle.NewMedian(md.Client, md.ContractAddress),
+			dataPointStore: md.DataPointStore,
+			feedAddresses:  md.FeedAddresses,
+			dataModel:      md.DataModel,
+			spread:         md.Spread,
+			expiration:     md.Expiration,
+			reputation:     md.Reputation,
+			log:            m.worker(md.Client).log,
+		}
+		providers = append(providers, provider)
+		clientProviders[md.Client] = append(clientProviders[md.Client], provider)
 	}
-	return r, nil
+	for client, w := range m.workers {
+		w.setProviders(clientProviders[client])
+	}
+	m.contracts = c
+	m.providers = providers
+}
+
+// Health returns the health of the relay workers, sorted by their names.
+func (m *Relay) Health() []WorkerHealth {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	health := make([]WorkerHealth, 0, len(m.workers))
+	for _, w := range m.workers {
+		health = append(health, w.healthStatus())
+	}
+	sort.Slice(health, func(i, j int) bool {
+		return health[i].Name < health[j].Name
+	})
+	return health
+}
+
+// worker returns the worker for the given client. If there is no worker for
+// the client, a new one with default settings is created and, if the Relay
+// is already running, started.
+//
+// The caller must hold the write lock.
+func (m *Relay) worker(client rpc.RPC) *worker {
+	if w, ok := m.workers[client]; ok {
+		return w
+	}
+	w := newWorker(ConfigWorker{
+		Name:   fmt.Sprintf("client%d", len(m.workers)+1),
+		Client: client,
+	}, m.metrics, m.log)
+	m.workers[client] = w
+	if m.ctx != nil {
+		go w.relayRoutine(m.ctx)
+	}
+	return w
+}
+
+// watchTargets returns the call providers that can be verified by the
+// Watcher.
+func (m *Relay) watchTargets() []watchTarget {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var targets []watchTarget
+	for _, p := range m.providers {
+		if t, ok := p.(watchTarget); ok {
+			targets = append(targets, t)
+		}
+	}
+	return targets
+}
+
+// opScribes returns the call providers of OpScribe contracts.
+func (m *Relay) opScribes() []*opScribe {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var scribes []*opScribe
+	for _, p := range m.providers {
+		if s, ok := p.(*opScribe); ok {
+			scribes = append(scribes, s)
+		}
+	}
+	return scribes
 }
 
 // Start implements the supervisor.Service interface.
@@ -271,7 +495,12 @@ func (m *Relay) Start(ctx context.Context) error {
 		return errors.New("context must not be nil")
 	}
 	m.log.Info("Starting")
+	m.mu.Lock()
 	m.ctx = ctx
+	for _, w := range m.workers {
+		go w.relayRoutine(ctx)
+	}
+	m.mu.Unlock()
 	go m.relayRoutine()
 	go m.contextCancelHandler()
 	return nil
@@ -282,105 +511,20 @@ func (m *Relay) Wait() <-chan error {
 	return m.waitCh
 }
 
-func (m *Relay) sendRelayTransactions() {
-	for client, calls := range m.relayCalls() {
-		// Note, that there is not need to create a separate branch for
-		// a single call because MultiCall internally handles this case.
-		call := multicall.AggregateCallables(client, calls...).AllowFail()
-		txHash, tx, err := call.SendTransaction(m.ctx)
-		if err != nil {
-			if strings.Contains(err.Error(), "nonce too low") || strings.Contains(err.Error(), "replacement transaction underpriced") {
-				m.log.
-					WithError(err).
-					WithFields(log.Fields{
-						"txTo":              call.Address(),
-						"txInput":           errutil.Ignore(call.CallData()),
-						"contractAddresses": addressesFromCalls(calls),
-					}).
-					Info("Unable to send transaction, previous transaction is still pending")
-				continue
-			}
-			m.log.
-				WithError(err).
-				WithFields(log.Fields{
-					"txTo":              call.Address(),
-					"txInput":           errutil.Ignore(call.CallData()),
-					"contractAddresses": addressesFromCalls(calls),
-				}).
-				WithAdvice("Ignore if it is related to temporary network issues").
-				Error("Failed to send transaction")
-			continue
-		}
-		m.log.
-			WithFields(log.Fields{
-				"txHash":                 txHash,
-				"txType":                 tx.Type,
-				"txFrom":                 tx.From,
-				"txTo":                   tx.To,
-				"txChainId":              tx.ChainID,
-				"txNonce":                tx.Nonce,
-				"txGasPrice":             tx.GasPrice,
-				"txGasLimit":             tx.GasLimit,
-				"txMaxFeePerGas":         tx.MaxFeePerGas,
-				"txMaxPriorityFeePerGas": tx.MaxPriorityFeePerGas,
-				"contractAddresses":      addressesFromCalls(calls),
-				"txInput":                hexutil.BytesToHex(tx.Input),
-			}).
-			Info("Relay transaction sent")
-	}
-}
-
-func (m *Relay) relayCalls() map[rpc.RPC][]contract.Callable {
-	var (
-		mu        = sync.Mutex{}
-		wg        = sync.WaitGroup{}
-		limiter   = make(chan struct{}, maxParallelCallProviders)
-		gasUsage  = make(map[rpc.RPC]uint64)
-		contracts = make(map[rpc.RPC][]types.Address)
-		calls     = make(map[rpc.RPC][]contract.Callable)
-	)
-	for _, u := range m.providers {
-		go func(u callProvider) {
-			defer wg.Done()
-			defer func() { <-limiter }()
-			limiter <- struct{}{}
-			for _, c := range u.createRelayCall(m.ctx) {
-				mu.Lock()
-				if gasUsage[c.client] >= gasUsageSoftCap {
-					// If the gas usage is above the soft cap, then do not
-					// add any more transactions to the aggregate transaction.
-					mu.Unlock()
-					continue
-				}
-				if sliceutil.Contains(contracts[c.client], c.address) {
-					// If there is already a transaction for the contract,
-					// then do not add another one.
-					mu.Unlock()
-					continue
-				}
-				gasEstimate := c.gasEstimate + 700 //nolint:gomnd // 700 is the minimum gas usage for a call
-				if gasEstimate > baseGasUsage {
-					gasEstimate -= baseGasUsage
-				}
-				gasUsage[c.client] += gasEstimate
-				contracts[c.client] = append(contracts[c.client], c.address)
-				calls[c.client] = append(calls[c.client], c.callable)
-				mu.Unlock()
-			}
-		}(u)
-	}
-	wg.Wait()
-	return calls
-}
-
 func (m *Relay) relayRoutine() {
 	m.ticker.Start(m.ctx)
 	for {
 		select {
 		case <-m.ctx.Done():
 			return
-		case <-m.ticker.TickCh():
-			m.sendRelayTransactions()
+		case t := <-m.ticker.TickCh():
+			m.mu.RLock()
+			for _, w := range m.workers {
+				if w.ticker == nil {
+					w.tick(t)
+				}
+			}
+			m.mu.RUnlock()
 		}
 	}
 }
@@ -300,11 +300,18 @@ func (m *mockScribeContract) Poke(pokeData chronicle.PokeData, schnorrData chron
 
 type mockOpScribeContract struct {
 	mockScribeContract
-	ReadNextFn func(ctx context.Context) (chronicle.PokeData, bool, error)
-	OpPokeFn   func(pokeData chronicle.PokeData, schnorrData chronicle.SchnorrData, ecdsaData types.Signature) contract.SelfTransactableCaller
+	ReadNextFn                        func(ctx context.Context) (chronicle.PokeData, bool, error)
+	OpPokeFn                          func(pokeData chronicle.PokeData, schnorrData chronicle.SchnorrData, ecdsaData types.Signature) contract.SelfTransactableCaller
+	OpChallengePeriodFn               func() contract.TypedSelfCaller[time.Duration]
+	ReadOpPokeDataFn                  func(ctx context.Context) (chronicle.PokeData, error)
+	ConstructPokeMessageFn            func(pokeData chronicle.PokeData) contract.TypedSelfCaller[types.Hash]
+	IsAcceptableSchnorrSignatureNowFn func(message types.Hash, schnorrData chronicle.SchnorrData) contract.TypedSelfCaller[bool]
+	OpPokesFn                         func(ctx context.Context, fromBlock, toBlock types.BlockNumber) ([]chronicle.OpPokeEvent, error)
+	OpChallengeFn                     func(schnorrData chronicle.SchnorrData) contract.SelfTransactableCaller
 }
 
 var _ OpScribeContract = (*mockOpScribeContract)(nil)
+var _ OpScribeChallengeContract = (*mockOpScribeContract)(nil)
 
 func newMockOpScribeContract(t *testing.T) *mockOpScribeContract {
 	sc := &mockOpScribeContract{}
@@ -322,6 +329,30 @@ func (m *mockOpScribeContract) reset(t *testing.T) {
 		assert.FailNow(t, "unexpected call to OpPoke")
 		return nil
 	}
+	m.OpChallengePeriodFn = func() contract.TypedSelfCaller[time.Duration] {
+		assert.FailNow(t, "unexpected call to OpChallengePeriod")
+		return nil
+	}
+	m.ReadOpPokeDataFn = func(ctx context.Context) (chronicle.PokeData, error) {
+		assert.FailNow(t, "unexpected call to ReadOpPokeData")
+		return chronicle.PokeData{}, nil
+	}
+	m.ConstructPokeMessageFn = func(pokeData chronicle.PokeData) contract.TypedSelfCaller[types.Hash] {
+		assert.FailNow(t, "unexpected call to ConstructPokeMessage")
+		return nil
+	}
+	m.IsAcceptableSchnorrSignatureNowFn = func(message types.Hash, schnorrData chronicle.SchnorrData) contract.TypedSelfCaller[bool] {
+		assert.FailNow(t, "unexpected call to IsAcceptableSchnorrSignatureNow")
+		return nil
+	}
+	m.OpPokesFn = func(ctx context.Context, fromBlock, toBlock types.BlockNumber) ([]chronicle.OpPokeEvent, error) {
+		assert.FailNow(t, "unexpected call to OpPokes")
+		return nil, nil
+	}
+	m.OpChallengeFn = func(schnorrData chronicle.SchnorrData) contract.SelfTransactableCaller {
+		assert.FailNow(t, "unexpected call to OpChallenge")
+		return nil
+	}
 }
 
 func (m *mockOpScribeContract) ReadNext(ctx context.Context) (chronicle.PokeData, bool, error) {
@@ -332,6 +363,30 @@ func (m *mockOpScribeContract) OpPoke(pokeData chronicle.PokeData, schnorrData c
 	return m.OpPokeFn(pokeData, schnorrData, ecdsaData)
 }
 
+func (m *mockOpScribeContract) OpChallengePeriod() contract.TypedSelfCaller[time.Duration] {
+	return m.OpChallengePeriodFn()
+}
+
+func (m *mockOpScribeContract) ReadOpPokeData(ctx context.Context) (chronicle.PokeData, error) {
+	return m.ReadOpPokeDataFn(ctx)
+}
+
+func (m *mockOpScribeContract) ConstructPokeMessage(pokeData chronicle.PokeData) contract.TypedSelfCaller[types.Hash] {
+	return m.ConstructPokeMessageFn(pokeData)
+}
+
+func (m *mockOpScribeContract) IsAcceptableSchnorrSignatureNow(message types.Hash, schnorrData chronicle.SchnorrData) contract.TypedSelfCaller[bool] {
+	return m.IsAcceptableSchnorrSignatureNowFn(message, schnorrData)
+}
+
+func (m *mockOpScribeContract) OpPokes(ctx context.Context, fromBlock, toBlock types.BlockNumber) ([]chronicle.OpPokeEvent, error) {
+	return m.OpPokesFn(ctx, fromBlock, toBlock)
+}
+
+func (m *mockOpScribeContract) OpChallenge(schnorrData chronicle.SchnorrData) contract.SelfTransactableCaller {
+	return m.OpChallengeFn(schnorrData)
+}
+
 type mockDataPointProvider struct {
 	LatestFromFn func(ctx context.Context, from types.Address, model string) (store.StoredDataPoint, bool, error)
 	LatestFn     func(ctx context.Context, model string) (map[types.Address]store.StoredDataPoint, error)
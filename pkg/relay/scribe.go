@@ -18,14 +18,19 @@ package relay
 import (
 	"context"
 	"math"
+	"sync"
 	"time"
 
 	"github.com/defiweb/go-eth/types"
 
 	"github.com/orcfax/oracle-suite/pkg/contract/chronicle"
 	"github.com/orcfax/oracle-suite/pkg/contract/multicall"
+	"github.com/orcfax/oracle-suite/pkg/datapoint"
+	datapointStore "github.com/orcfax/oracle-suite/pkg/datapoint/store"
 	"github.com/orcfax/oracle-suite/pkg/log"
 	"github.com/orcfax/oracle-suite/pkg/musig/store"
+	"github.com/orcfax/oracle-suite/pkg/tracing"
+	"github.com/orcfax/oracle-suite/pkg/transport/messages"
 	"github.com/orcfax/oracle-suite/pkg/util/sliceutil"
 )
 
@@ -34,13 +39,15 @@ import (
 const stateCacheExpiration = 10 * time.Second
 
 type scribe struct {
-	contract   ScribeContract
-	muSigStore store.SignatureProvider
-	dataModel  string
-	spread     float64
-	expiration time.Duration
-	log        log.Logger
+	contract       ScribeContract
+	muSigStore     store.SignatureProvider
+	dataPointStore datapointStore.DataPointProvider
+	dataModel      string
+	spread         float64
+	expiration     time.Duration
+	log            log.Logger
 
+	mu          sync.Mutex // Guards cachedState, it is accessed by both Relay and Watcher.
 	cachedState scribeState
 }
 
@@ -136,6 +143,7 @@ func (w *scribe) createRelayCall(ctx context.Context) []relayCall {
 
 		// If price is stale or expired, return a poke transaction.
 		if isExpired || isStale {
+			linkDataPoints(tracing.SpanFromContext(ctx), w.signatureDataPoints(ctx, s, meta))
 			poke := w.contract.Poke(
 				chronicle.PokeData{
 					Val: meta.Val,
@@ -179,7 +187,36 @@ func (w *scribe) createRelayCall(ctx context.Context) []relayCall {
 	return nil
 }
 
+// signatureDataPoints returns data points from the data point store that
+// were included in the given signature. Only the latest data point of every
+// signer is checked, so data points that were already replaced in the store
+// are omitted.
+func (w *scribe) signatureDataPoints(
+	ctx context.Context,
+	s *messages.MuSigSignature,
+	meta *messages.MuSigMetaTickV1,
+) []datapoint.Point {
+	if w.dataPointStore == nil {
+		return nil
+	}
+	ages := make(map[int64]bool, len(meta.FeedTicks))
+	for _, tick := range meta.FeedTicks {
+		ages[tick.Age.Unix()] = true
+	}
+	var dps []datapoint.Point
+	for _, feed := range s.Signers {
+		sdp, ok, err := w.dataPointStore.LatestFrom(ctx, feed, w.dataModel)
+		if err != nil || !ok || !ages[sdp.DataPoint.Time.Unix()] {
+			continue
+		}
+		dps = append(dps, sdp.DataPoint)
+	}
+	return dps
+}
+
 func (w *scribe) currentState(ctx context.Context) (state scribeState, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 	if time.Since(w.cachedState.time) <= stateCacheExpiration {
 		return w.cachedState, nil
 	}
@@ -218,6 +255,29 @@ func (w *scribe) currentState(ctx context.Context) (state scribeState, err error
 	return state, nil
 }
 
+func (w *scribe) watchState(ctx context.Context) (watchState, error) {
+	s := watchState{
+		contractType: ContractTypeScribe,
+		client:       w.contract.Client(),
+		address:      w.contract.Address(),
+		dataModel:    w.dataModel,
+		spread:       w.spread,
+		expiration:   w.expiration,
+	}
+	state, err := w.currentState(ctx)
+	if err != nil {
+		return s, err
+	}
+	if state.wat != w.dataModel {
+		return s, errWatMismatch
+	}
+	s.val = state.pokeData.Val.DecFloatPoint()
+	s.age = state.pokeData.Age
+	s.bar = state.bar
+	s.feeds = state.feeds
+	return s, nil
+}
+
 func (w *scribe) logFields() log.Fields {
 	return log.Fields{
 		"address":   w.contract.Address(),
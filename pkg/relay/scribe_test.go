@@ -30,6 +30,8 @@ import (
 	"github.com/orcfax/oracle-suite/pkg/contract"
 	"github.com/orcfax/oracle-suite/pkg/contract/chronicle"
 	"github.com/orcfax/oracle-suite/pkg/contract/mock"
+	"github.com/orcfax/oracle-suite/pkg/datapoint"
+	"github.com/orcfax/oracle-suite/pkg/datapoint/store"
 	"github.com/orcfax/oracle-suite/pkg/transport/messages"
 	"github.com/orcfax/oracle-suite/pkg/util/bn"
 )
@@ -468,3 +470,36 @@ func TestScribe(t *testing.T) {
 		assert.True(t, errLogCalled)
 	})
 }
+
+func TestScribe_signatureDataPoints(t *testing.T) {
+	testFeed := types.MustAddressFromHex("0x1111111111111111111111111111111111111111")
+	testFeed2 := types.MustAddressFromHex("0x2222222222222222222222222222222222222222")
+	tickTime := time.Unix(100, 0)
+	mockStore := newMockDataPointProvider(t)
+	mockStore.LatestFromFn = func(ctx context.Context, from types.Address, model string) (store.StoredDataPoint, bool, error) {
+		assert.Equal(t, "ETH/USD", model)
+		switch from {
+		case testFeed:
+			return store.StoredDataPoint{DataPoint: datapoint.Point{Time: tickTime}}, true, nil
+		case testFeed2:
+			// The data point was already replaced by a newer one.
+			return store.StoredDataPoint{DataPoint: datapoint.Point{Time: tickTime.Add(time.Minute)}}, true, nil
+		}
+		return store.StoredDataPoint{}, false, nil
+	}
+
+	scribe := &scribe{dataPointStore: mockStore, dataModel: "ETH/USD"}
+	meta := &messages.MuSigMetaTickV1{
+		FeedTicks: []messages.MuSigMetaFeedTick{{Age: tickTime}, {Age: tickTime}},
+	}
+	dps := scribe.signatureDataPoints(context.Background(), &messages.MuSigSignature{
+		MuSigMessage: &messages.MuSigMessage{Signers: []types.Address{testFeed, testFeed2}},
+	}, meta)
+	assert.Equal(t, []datapoint.Point{{Time: tickTime}}, dps)
+
+	// Without the data point store, no data points are returned.
+	scribe.dataPointStore = nil
+	assert.Empty(t, scribe.signatureDataPoints(context.Background(), &messages.MuSigSignature{
+		MuSigMessage: &messages.MuSigMessage{Signers: []types.Address{testFeed}},
+	}, meta))
+}
@@ -19,10 +19,14 @@ import (
 	"crypto/rand"
 	"math"
 	"math/big"
-	"sort"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
 
 	"github.com/orcfax/oracle-suite/pkg/datapoint"
+	"github.com/orcfax/oracle-suite/pkg/datapoint/store"
 	"github.com/orcfax/oracle-suite/pkg/datapoint/value"
+	"github.com/orcfax/oracle-suite/pkg/tracing"
 	"github.com/orcfax/oracle-suite/pkg/util/bn"
 )
 
@@ -35,6 +39,40 @@ func dataPointsToPrices(dps []datapoint.Point) []*bn.DecFloatPointNumber {
 	return p
 }
 
+// linkDataPoints links the span with the traces of the given data points.
+// Data points from many feeds, each with its own trace, are relayed in
+// a single transaction, so they are linked instead of being parents of
+// the span.
+func linkDataPoints(span *tracing.Span, dps []datapoint.Point) {
+	for _, dp := range dps {
+		if sc, ok := tracing.Extract(dp.Meta); ok {
+			span.AddLink(sc)
+		}
+	}
+}
+
+// tickPrices returns prices from the given data points that were sent by
+// one of the given feeds and are newer than the given time. Data points that
+// are not ticks are ignored.
+func tickPrices(points map[types.Address]store.StoredDataPoint, feeds []types.Address, after time.Time) []*bn.DecFloatPointNumber {
+	var prices []*bn.DecFloatPointNumber
+	for _, feed := range feeds {
+		sdp, ok := points[feed]
+		if !ok {
+			continue
+		}
+		tick, ok := sdp.DataPoint.Value.(value.Tick)
+		if !ok || tick.Price == nil {
+			continue
+		}
+		if !sdp.DataPoint.Time.After(after) {
+			continue
+		}
+		prices = append(prices, tick.Price)
+	}
+	return prices
+}
+
 // calculateSpread calculates the spread between given price and a median
 // price. The spread is returned as percentage points.
 //
@@ -47,26 +85,7 @@ func calculateSpread(new, old *bn.DecFloatPointNumber) float64 {
 	if old.Sign() == 0 {
 		return math.Inf(1)
 	}
-	spread, _ := new.Sub(old).Div(old).Mul(bn.DecFloatPoint(100)).Abs().BigFloat().Float64()
-	return spread
-}
-
-// calculateMedian calculates the median price.
-func calculateMedian(prices []*bn.DecFloatPointNumber) *bn.DecFloatPointNumber {
-	count := len(prices)
-	if count == 0 {
-		return bn.DecFloatPoint(0)
-	}
-	sort.Slice(prices, func(i, j int) bool {
-		return prices[i].Cmp(prices[j]) < 0
-	})
-	if count%2 == 0 {
-		m := count / 2
-		a := prices[m-1]
-		b := prices[m]
-		return a.Add(b).Div(bn.DecFloatPoint(2))
-	}
-	return prices[(count-1)/2]
+	return bn.Deviation(new, old)
 }
 
 // randomInts generates a slice of integers from 0 to n (exclusive), shuffled
@@ -44,42 +44,3 @@ func TestCalculateSpread(t *testing.T) {
 		})
 	}
 }
-
-func TestCalculateMedian(t *testing.T) {
-	tests := []struct {
-		name     string
-		prices   []*bn.DecFloatPointNumber
-		expected *bn.DecFloatPointNumber
-	}{
-		{
-			"calculateMedian([])",
-			[]*bn.DecFloatPointNumber{},
-			bn.DecFloatPoint(0),
-		},
-		{
-			"calculateMedian([1, 3, 2])",
-			[]*bn.DecFloatPointNumber{
-				bn.DecFloatPoint(1),
-				bn.DecFloatPoint(3),
-				bn.DecFloatPoint(2),
-			},
-			bn.DecFloatPoint(2),
-		},
-		{
-			"calculateMedian([1, 4, 3, 2])",
-			[]*bn.DecFloatPointNumber{
-				bn.DecFloatPoint(1),
-				bn.DecFloatPoint(4),
-				bn.DecFloatPoint(3),
-				bn.DecFloatPoint(2),
-			},
-			bn.DecFloatPoint(2.5),
-		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got := calculateMedian(tt.prices)
-			assert.Equal(t, tt.expected, got)
-		})
-	}
-}
@@ -0,0 +1,417 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package relay
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/defiweb/go-eth/rpc"
+	"github.com/defiweb/go-eth/types"
+
+	datapointStore "github.com/orcfax/oracle-suite/pkg/datapoint/store"
+	"github.com/orcfax/oracle-suite/pkg/log"
+	"github.com/orcfax/oracle-suite/pkg/log/null"
+	"github.com/orcfax/oracle-suite/pkg/metrics"
+	"github.com/orcfax/oracle-suite/pkg/util/bn"
+	"github.com/orcfax/oracle-suite/pkg/util/timeutil"
+)
+
+const WatcherLoggerTag = "RELAY_WATCHER"
+
+// WatcherAlarmMetric is the name of the metric reported by the hook
+// returned by MetricsAlarmHook.
+const WatcherAlarmMetric = "relay_watcher_alarm"
+
+var errWatMismatch = errors.New("contract asset name does not match the configured asset name")
+
+// AlarmType is the type of condition detected by the Watcher.
+type AlarmType string
+
+const (
+	// AlarmTypeDivergence is raised when the value stored in a contract
+	// differs from the median of the latest data points by more than
+	// the spread configured for the contract.
+	AlarmTypeDivergence AlarmType = "divergence"
+
+	// AlarmTypeExpired is raised when the value stored in a contract is older
+	// than the expiration configured for the contract, even though enough
+	// newer data points are available to update it.
+	AlarmTypeExpired AlarmType = "expired"
+)
+
+// Alarm describes a discrepancy between a contract and the data points
+// collected from feeds.
+type Alarm struct {
+	// Type is the type of the alarm.
+	Type AlarmType
+
+	// Active is true while the condition persists and false once it
+	// is resolved.
+	Active bool
+
+	// Since is the time when the condition was first detected.
+	Since time.Time
+
+	// ContractType is the type of the contract.
+	ContractType ContractType
+
+	// ContractAddress is the address of the contract.
+	ContractAddress types.Address
+
+	// DataModel is the name of the data model used by the contract.
+	DataModel string
+
+	// Value is the value currently stored in the contract.
+	Value *bn.DecFloatPointNumber
+
+	// Age is the age of the value stored in the contract.
+	Age time.Time
+
+	// QuorumValue is the median of the latest data points.
+	QuorumValue *bn.DecFloatPointNumber
+
+	// QuorumSize is the number of data points used to calculate
+	// QuorumValue.
+	QuorumSize int
+
+	// Spread is the spread between Value and QuorumValue, in percentage
+	// points.
+	Spread float64
+
+	// MaxSpread is the spread configured for the contract.
+	MaxSpread float64
+
+	// Expiration is the expiration configured for the contract.
+	Expiration time.Duration
+}
+
+// AlarmHook is called every time the Watcher checks a contract for which
+// an alarm is active, and once more when the alarm is resolved. It can be
+// used to export alarms as metrics.
+type AlarmHook func(Alarm)
+
+// MetricsAlarmHook returns an AlarmHook that exports alarms as metrics. The
+// metric is set to 1 while an alarm is active and to 0 once it is resolved.
+func MetricsAlarmHook(registry metrics.Registry) AlarmHook {
+	return func(a Alarm) {
+		active := 0.0
+		if a.Active {
+			active = 1
+		}
+		registry.Set(WatcherAlarmMetric, metrics.Labels{
+			"type":          string(a.Type),
+			"contract_type": string(a.ContractType),
+			"contract":      a.ContractAddress.String(),
+			"data_model":    a.DataModel,
+		}, active)
+	}
+}
+
+// watchTarget is implemented by call providers whose contracts can be
+// verified by the Watcher.
+type watchTarget interface {
+	// watchState returns the current state of the contract. The contract
+	// identity fields are set even if an error is returned.
+	watchState(ctx context.Context) (watchState, error)
+}
+
+type watchState struct {
+	contractType ContractType
+	client       rpc.RPC
+	address      types.Address
+	dataModel    string
+	val          *bn.DecFloatPointNumber
+	age          time.Time
+	bar          int
+	feeds        []types.Address
+	spread       float64
+	expiration   time.Duration
+}
+
+type alarmKey struct {
+	client    rpc.RPC
+	address   types.Address
+	alarmType AlarmType
+}
+
+type alarmState struct {
+	since  time.Time
+	raised bool
+}
+
+// WatcherConfig is the configuration for the Watcher.
+type WatcherConfig struct {
+	// Relay is the relay whose contracts are verified.
+	Relay *Relay
+
+	// DataPointStore is the store used to retrieve the latest data points.
+	DataPointStore datapointStore.DataPointProvider
+
+	// GracePeriod is the time for which a condition must persist before an
+	// alarm is raised. It gives the relay time to update the contract.
+	GracePeriod time.Duration
+
+	// AlarmHook is an optional function called for active and resolved
+	// alarms.
+	AlarmHook AlarmHook
+
+	// Ticker notifies the watcher to verify the contracts.
+	Ticker *timeutil.Ticker
+
+	// Logger is a current logger interface used by the Watcher.
+	// If nil, null logger will be used.
+	Logger log.Logger
+}
+
+// Watcher is a service that periodically verifies that the values stored
+// in contracts handled by the Relay match data points collected from feeds.
+type Watcher struct {
+	ctx            context.Context
+	waitCh         chan error
+	relay          *Relay
+	dataPointStore datapointStore.DataPointProvider
+	gracePeriod    time.Duration
+	alarmHook      AlarmHook
+	ticker         *timeutil.Ticker
+	alarms         map[alarmKey]*alarmState
+	log            log.Logger
+}
+
+// NewWatcher creates a new Watcher instance.
+func NewWatcher(cfg WatcherConfig) (*Watcher, error) {
+	if cfg.Relay == nil {
+		return nil, errors.New("relay must not be nil")
+	}
+	if cfg.DataPointStore == nil {
+		return nil, errors.New("data point store must not be nil")
+	}
+	if cfg.Ticker == nil {
+		return nil, errors.New("ticker must not be nil")
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = null.New()
+	}
+	return &Watcher{
+		waitCh:         make(chan error),
+		relay:          cfg.Relay,
+		dataPointStore: cfg.DataPointStore,
+		gracePeriod:    cfg.GracePeriod,
+		alarmHook:      cfg.AlarmHook,
+		ticker:         cfg.Ticker,
+		alarms:         make(map[alarmKey]*alarmState),
+		log:            cfg.Logger.WithField("tag", WatcherLoggerTag),
+	}, nil
+}
+
+// Start implements the supervisor.Service interface.
+func (w *Watcher) Start(ctx context.Context) error {
+	if w.ctx != nil {
+		return errors.New("service can be started only once")
+	}
+	if ctx == nil {
+		return errors.New("context must not be nil")
+	}
+	w.log.Info("Starting")
+	w.ctx = ctx
+	go w.watchRoutine()
+	go w.contextCancelHandler()
+	return nil
+}
+
+// Wait implements the supervisor.Service interface.
+func (w *Watcher) Wait() <-chan error {
+	return w.waitCh
+}
+
+// check verifies all contracts handled by the Relay.
+func (w *Watcher) check() {
+	checked := make(map[alarmKey]bool)
+	for _, t := range w.relay.watchTargets() {
+		state, err := t.watchState(w.ctx)
+		for _, typ := range []AlarmType{AlarmTypeDivergence, AlarmTypeExpired} {
+			checked[alarmKey{client: state.client, address: state.address, alarmType: typ}] = true
+		}
+		if err != nil {
+			w.log.
+				WithError(err).
+				WithFields(state.logFields()).
+				WithAdvice("Ignore if it is related to temporary network issues").
+				Error("Failed to read contract state")
+			continue
+		}
+		w.checkContract(state)
+	}
+
+	// Forget about contracts that are no longer handled by the Relay.
+	for k := range w.alarms {
+		if !checked[k] {
+			delete(w.alarms, k)
+		}
+	}
+}
+
+// checkContract compares the contract state with the latest data points.
+func (w *Watcher) checkContract(state watchState) {
+	points, err := w.dataPointStore.Latest(w.ctx, state.dataModel)
+	if err != nil {
+		w.log.
+			WithError(err).
+			WithFields(state.logFields()).
+			WithAdvice("This is a bug and needs to be investigated").
+			Error("Failed to get data points")
+		return
+	}
+
+	// Only data points from feeds allowed to update the contract that are
+	// newer than the current contract value are taken into account.
+	prices := tickPrices(points, state.feeds, state.age)
+	if len(prices) == 0 || len(prices) < state.bar {
+		// Without a quorum, it is not possible to tell whether the contract
+		// should have been updated. Current alarms are left unchanged.
+		w.log.
+			WithFields(state.logFields()).
+			WithFields(log.Fields{
+				"quorum": state.bar,
+				"found":  len(prices),
+			}).
+			Debug("Not enough data points to verify the contract")
+		return
+	}
+
+	quorumVal := bn.Median(prices)
+	spread := calculateSpread(quorumVal, state.val)
+	alarm := Alarm{
+		ContractType:    state.contractType,
+		ContractAddress: state.address,
+		DataModel:       state.dataModel,
+		Value:           state.val,
+		Age:             state.age,
+		QuorumValue:     quorumVal,
+		QuorumSize:      len(prices),
+		Spread:          spread,
+		MaxSpread:       state.spread,
+		Expiration:      state.expiration,
+	}
+
+	isDiverged := math.IsInf(spread, 0) || spread > state.spread
+	isExpired := time.Since(state.age) >= state.expiration
+
+	w.log.
+		WithFields(alarm.logFields()).
+		WithFields(log.Fields{
+			"diverged": isDiverged,
+			"expired":  isExpired,
+		}).
+		Debug("Contract verified")
+
+	w.updateAlarm(state, AlarmTypeDivergence, isDiverged, alarm)
+	w.updateAlarm(state, AlarmTypeExpired, isExpired, alarm)
+}
+
+// updateAlarm updates the state of an alarm of the given type. An alarm is
+// raised only if the condition persists for longer than the grace period.
+func (w *Watcher) updateAlarm(state watchState, typ AlarmType, active bool, alarm Alarm) {
+	key := alarmKey{client: state.client, address: state.address, alarmType: typ}
+	alarm.Type = typ
+	as, ok := w.alarms[key]
+	if !active {
+		if !ok {
+			return
+		}
+		delete(w.alarms, key)
+		if as.raised {
+			alarm.Since = as.since
+			w.log.
+				WithFields(alarm.logFields()).
+				Info("Relay alarm resolved")
+			if w.alarmHook != nil {
+				w.alarmHook(alarm)
+			}
+		}
+		return
+	}
+	if !ok {
+		as = &alarmState{since: time.Now()}
+		w.alarms[key] = as
+	}
+	alarm.Since = as.since
+	if time.Since(as.since) < w.gracePeriod {
+		return
+	}
+	as.raised = true
+	alarm.Active = true
+	switch typ {
+	case AlarmTypeDivergence:
+		w.log.
+			WithFields(alarm.logFields()).
+			WithAdvice("Check if the relay is able to send transactions and if the contract configuration is correct").
+			Warn("Contract value diverges from the data points")
+	case AlarmTypeExpired:
+		w.log.
+			WithFields(alarm.logFields()).
+			WithAdvice("Check if the relay is able to send transactions and if the contract configuration is correct").
+			Warn("Contract value expired despite available data points")
+	}
+	if w.alarmHook != nil {
+		w.alarmHook(alarm)
+	}
+}
+
+func (w *Watcher) watchRoutine() {
+	w.ticker.Start(w.ctx)
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-w.ticker.TickCh():
+			w.check()
+		}
+	}
+}
+
+func (w *Watcher) contextCancelHandler() {
+	defer func() { close(w.waitCh) }()
+	defer w.log.Info("Stopped")
+	<-w.ctx.Done()
+}
+
+func (a Alarm) logFields() log.Fields {
+	return log.Fields{
+		"alarm":         a.Type,
+		"contractType":  a.ContractType,
+		"address":       a.ContractAddress,
+		"dataModel":     a.DataModel,
+		"val":           a.Value,
+		"age":           a.Age,
+		"quorumVal":     a.QuorumValue,
+		"quorumSize":    a.QuorumSize,
+		"currentSpread": a.Spread,
+		"spread":        a.MaxSpread,
+		"expiration":    a.Expiration,
+		"since":         a.Since,
+	}
+}
+
+func (s watchState) logFields() log.Fields {
+	return log.Fields{
+		"contractType": s.contractType,
+		"address":      s.address,
+		"dataModel":    s.dataModel,
+	}
+}
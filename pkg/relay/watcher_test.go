@@ -0,0 +1,217 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package relay
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/defiweb/go-eth/rpc"
+	"github.com/defiweb/go-eth/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/orcfax/oracle-suite/pkg/contract"
+	"github.com/orcfax/oracle-suite/pkg/contract/chronicle"
+	"github.com/orcfax/oracle-suite/pkg/contract/mock"
+	"github.com/orcfax/oracle-suite/pkg/datapoint"
+	"github.com/orcfax/oracle-suite/pkg/datapoint/store"
+	"github.com/orcfax/oracle-suite/pkg/datapoint/value"
+	"github.com/orcfax/oracle-suite/pkg/metrics"
+	"github.com/orcfax/oracle-suite/pkg/util/bn"
+	"github.com/orcfax/oracle-suite/pkg/util/timeutil"
+)
+
+func TestWatcher(t *testing.T) {
+	testFeed1 := types.MustAddressFromHex("0x1111111111111111111111111111111111111111")
+	testFeed2 := types.MustAddressFromHex("0x2222222222222222222222222222222222222222")
+	testFeed3 := types.MustAddressFromHex("0x3333333333333333333333333333333333333333")
+	testContract := types.MustAddressFromHex("0x4444444444444444444444444444444444444444")
+	mockContract := newMockMedianContract(t)
+	mockStore := newMockDataPointProvider(t)
+
+	relay, err := New(Config{Ticker: timeutil.NewTicker(0)})
+	require.NoError(t, err)
+	relay.providers = []callProvider{&median{
+		contract:      mockContract,
+		feedAddresses: []types.Address{testFeed1, testFeed2, testFeed3},
+		dataModel:     "ETH/USD",
+		spread:        5,
+		expiration:    time.Hour,
+	}}
+
+	mockState := func(val float64, age time.Time) {
+		mockContract.ClientFn = func() rpc.RPC { return nil }
+		mockContract.AddressFn = func() types.Address { return testContract }
+		mockContract.WatFn = func() contract.TypedSelfCaller[string] {
+			return mock.NewTypedCaller[string](t).MockResult("ETH/USD", nil)
+		}
+		mockContract.ValFn = func(ctx context.Context) (*bn.DecFixedPointNumber, error) {
+			return bn.DecFixedPoint(val, chronicle.MedianPricePrecision), nil
+		}
+		mockContract.AgeFn = func() contract.TypedSelfCaller[time.Time] {
+			return mock.NewTypedCaller[time.Time](t).MockResult(age, nil)
+		}
+		mockContract.BarFn = func() contract.TypedSelfCaller[int] {
+			return mock.NewTypedCaller[int](t).MockResult(2, nil)
+		}
+	}
+	mockDataPoints := func(t time.Time, prices ...float64) {
+		mockStore.LatestFn = func(ctx context.Context, model string) (map[types.Address]store.StoredDataPoint, error) {
+			points := make(map[types.Address]store.StoredDataPoint)
+			for i, feed := range []types.Address{testFeed1, testFeed2, testFeed3}[:len(prices)] {
+				points[feed] = store.StoredDataPoint{
+					Model: model,
+					DataPoint: datapoint.Point{
+						Time:  t,
+						Value: value.Tick{Price: bn.DecFloatPoint(prices[i])},
+					},
+					From: feed,
+				}
+			}
+			return points, nil
+		}
+	}
+	newWatcher := func(gracePeriod time.Duration, alarms *[]Alarm) *Watcher {
+		w, err := NewWatcher(WatcherConfig{
+			Relay:          relay,
+			DataPointStore: mockStore,
+			GracePeriod:    gracePeriod,
+			AlarmHook:      func(a Alarm) { *alarms = append(*alarms, a) },
+			Ticker:         timeutil.NewTicker(0),
+		})
+		require.NoError(t, err)
+		w.ctx = context.Background()
+		return w
+	}
+
+	t.Run("divergence", func(t *testing.T) {
+		mockContract.reset(t)
+		mockStore.reset(t)
+
+		var alarms []Alarm
+		w := newWatcher(0, &alarms)
+
+		// Value diverges from the data points.
+		mockState(100, time.Now().Add(-time.Minute))
+		mockDataPoints(time.Now(), 110, 112, 120)
+		w.check()
+		require.Len(t, alarms, 1)
+		assert.Equal(t, AlarmTypeDivergence, alarms[0].Type)
+		assert.True(t, alarms[0].Active)
+		assert.Equal(t, ContractTypeMedian, alarms[0].ContractType)
+		assert.Equal(t, testContract, alarms[0].ContractAddress)
+		assert.Equal(t, "ETH/USD", alarms[0].DataModel)
+		assert.Equal(t, "112", alarms[0].QuorumValue.String())
+		assert.Equal(t, 3, alarms[0].QuorumSize)
+		assert.InDelta(t, 12, alarms[0].Spread, 0.0001)
+
+		// Contract is updated, the alarm is resolved.
+		mockState(112, time.Now().Add(-time.Minute))
+		w.check()
+		require.Len(t, alarms, 2)
+		assert.Equal(t, AlarmTypeDivergence, alarms[1].Type)
+		assert.False(t, alarms[1].Active)
+		assert.Equal(t, alarms[0].Since, alarms[1].Since)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		mockContract.reset(t)
+		mockStore.reset(t)
+
+		var alarms []Alarm
+		w := newWatcher(0, &alarms)
+
+		mockState(100, time.Now().Add(-2*time.Hour))
+		mockDataPoints(time.Now(), 100, 101)
+		w.check()
+		require.Len(t, alarms, 1)
+		assert.Equal(t, AlarmTypeExpired, alarms[0].Type)
+		assert.True(t, alarms[0].Active)
+		assert.Equal(t, 2, alarms[0].QuorumSize)
+	})
+
+	t.Run("not enough data points", func(t *testing.T) {
+		mockContract.reset(t)
+		mockStore.reset(t)
+
+		var alarms []Alarm
+		w := newWatcher(0, &alarms)
+
+		// Only one data point is available, but the quorum is 2.
+		mockState(100, time.Now().Add(-2*time.Hour))
+		mockDataPoints(time.Now(), 200)
+		w.check()
+		assert.Len(t, alarms, 0)
+
+		// Data points are older than the contract value.
+		mockDataPoints(time.Now().Add(-3*time.Hour), 200, 200, 200)
+		w.check()
+		assert.Len(t, alarms, 0)
+	})
+
+	t.Run("grace period", func(t *testing.T) {
+		mockContract.reset(t)
+		mockStore.reset(t)
+
+		var alarms []Alarm
+		w := newWatcher(time.Hour, &alarms)
+
+		mockState(100, time.Now().Add(-time.Minute))
+		mockDataPoints(time.Now(), 110, 112, 120)
+		w.check()
+		assert.Len(t, alarms, 0)
+		assert.Len(t, w.alarms, 1)
+
+		// Condition is resolved before the grace period has passed, so no
+		// alarm is reported.
+		mockState(112, time.Now().Add(-time.Minute))
+		w.check()
+		assert.Len(t, alarms, 0)
+		assert.Len(t, w.alarms, 0)
+	})
+}
+
+func TestMetricsAlarmHook(t *testing.T) {
+	testContract := types.MustAddressFromHex("0x4444444444444444444444444444444444444444")
+	registry := metrics.NewMemory(nil)
+	hook := MetricsAlarmHook(registry)
+	labels := metrics.Labels{
+		"type":          string(AlarmTypeExpired),
+		"contract_type": string(ContractTypeScribe),
+		"contract":      testContract.String(),
+		"data_model":    "ETH/USD",
+	}
+	alarm := Alarm{
+		Type:            AlarmTypeExpired,
+		Active:          true,
+		ContractType:    ContractTypeScribe,
+		ContractAddress: testContract,
+		DataModel:       "ETH/USD",
+	}
+
+	hook(alarm)
+	v, ok := registry.Value(WatcherAlarmMetric, labels)
+	require.True(t, ok)
+	assert.Equal(t, 1.0, v)
+
+	alarm.Active = false
+	hook(alarm)
+	v, ok = registry.Value(WatcherAlarmMetric, labels)
+	require.True(t, ok)
+	assert.Equal(t, 0.0, v)
+}
@@ -0,0 +1,321 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package relay
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/defiweb/go-eth/hexutil"
+	"github.com/defiweb/go-eth/rpc"
+	"github.com/defiweb/go-eth/types"
+
+	"github.com/orcfax/oracle-suite/pkg/contract"
+	"github.com/orcfax/oracle-suite/pkg/contract/multicall"
+	"github.com/orcfax/oracle-suite/pkg/log"
+	"github.com/orcfax/oracle-suite/pkg/metrics"
+	"github.com/orcfax/oracle-suite/pkg/tracing"
+	"github.com/orcfax/oracle-suite/pkg/util/errutil"
+	"github.com/orcfax/oracle-suite/pkg/util/sliceutil"
+	"github.com/orcfax/oracle-suite/pkg/util/timeutil"
+)
+
+// maxWorkerFailures is the number of consecutive failed relay attempts after
+// which a worker is considered unhealthy.
+const maxWorkerFailures = 3
+
+// ConfigWorker is the configuration of a relay worker.
+//
+// A worker relays data to all contracts that use the same RPC client,
+// independently of other workers. Because the RPC client holds the signer
+// key and the gas fee settings, every chain is typically handled by its own
+// worker.
+type ConfigWorker struct {
+	// Name is the name of the worker used in logs and health reports,
+	// typically the name of the Ethereum client.
+	Name string
+
+	// Client is the RPC client used to send relay transactions.
+	Client rpc.RPC
+
+	// Ticker notifies the worker to check if an update is required.
+	// If nil, the Relay ticker is used.
+	Ticker *timeutil.Ticker
+
+	// MaxConcurrency is the maximum number of contracts that can be checked
+	// in parallel. If zero, the default value is used.
+	MaxConcurrency int
+
+	// GasUsageSoftCap is the soft cap for gas usage of a single relay
+	// transaction. If zero, the default value is used.
+	GasUsageSoftCap uint64
+}
+
+// WorkerHealth describes the health of a relay worker.
+type WorkerHealth struct {
+	// Name is the name of the worker.
+	Name string
+
+	// Healthy is false if several consecutive relay attempts failed.
+	Healthy bool
+
+	// LastRun is the time of the last relay attempt.
+	LastRun time.Time
+
+	// LastSuccess is the time of the last successful relay attempt.
+	LastSuccess time.Time
+
+	// LastError is the error of the last failed relay attempt.
+	LastError error
+
+	// Failures is the number of consecutive failed relay attempts.
+	Failures int
+}
+
+// worker relays data to contracts that use the same RPC client.
+type worker struct {
+	mu        sync.RWMutex
+	providers []callProvider
+	health    WorkerHealth
+
+	client          rpc.RPC
+	ticker          *timeutil.Ticker // If nil, ticks are sent to tickCh by the Relay.
+	tickCh          chan time.Time
+	maxConcurrency  int
+	gasUsageSoftCap uint64
+	metrics         metrics.Registry
+	log             log.Logger
+}
+
+func newWorker(cfg ConfigWorker, registry metrics.Registry, logger log.Logger) *worker {
+	if cfg.MaxConcurrency <= 0 {
+		cfg.MaxConcurrency = maxParallelCallProviders
+	}
+	if cfg.GasUsageSoftCap == 0 {
+		cfg.GasUsageSoftCap = gasUsageSoftCap
+	}
+	return &worker{
+		health:          WorkerHealth{Name: cfg.Name, Healthy: true},
+		client:          cfg.Client,
+		ticker:          cfg.Ticker,
+		tickCh:          make(chan time.Time, 1),
+		maxConcurrency:  cfg.MaxConcurrency,
+		gasUsageSoftCap: cfg.GasUsageSoftCap,
+		metrics:         registry,
+		log:             logger.WithField("chain", cfg.Name),
+	}
+}
+
+// setProviders replaces the list of call providers handled by the worker.
+func (w *worker) setProviders(providers []callProvider) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.providers = providers
+}
+
+// healthStatus returns the current health of the worker.
+func (w *worker) healthStatus() WorkerHealth {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.health
+}
+
+// tick notifies the worker to check if an update is required. If the worker
+// is still busy with the previous tick, the ticks are coalesced, so a slow
+// worker never blocks the caller.
+func (w *worker) tick(t time.Time) {
+	select {
+	case w.tickCh <- t:
+	default:
+	}
+}
+
+func (w *worker) relayRoutine(ctx context.Context) {
+	tickCh := (<-chan time.Time)(w.tickCh)
+	if w.ticker != nil {
+		w.ticker.Start(ctx)
+		tickCh = w.ticker.TickCh()
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tickCh:
+			w.sendRelayTransaction(ctx)
+		}
+	}
+}
+
+func (w *worker) sendRelayTransaction(ctx context.Context) {
+	ctx, span := tracing.StartSpan(ctx, "relay.sendRelayTransaction")
+	defer span.End()
+	span.SetAttribute("chain", w.health.Name)
+	calls := w.relayCalls(ctx)
+	span.SetAttribute("calls", len(calls))
+	if len(calls) == 0 {
+		w.updateHealth(nil)
+		return
+	}
+
+	// Note, that there is not need to create a separate branch for
+	// a single call because MultiCall internally handles this case.
+	call := multicall.AggregateCallables(w.client, calls...).AllowFail()
+	txHash, tx, err := call.SendTransaction(ctx)
+	span.SetError(err)
+	if err != nil {
+		if strings.Contains(err.Error(), "nonce too low") || strings.Contains(err.Error(), "replacement transaction underpriced") {
+			w.log.
+				WithError(err).
+				WithFields(log.Fields{
+					"txTo":              call.Address(),
+					"txInput":           errutil.Ignore(call.CallData()),
+					"contractAddresses": addressesFromCalls(calls),
+				}).
+				Info("Unable to send transaction, previous transaction is still pending")
+			w.updateHealth(nil)
+			return
+		}
+		w.log.
+			WithError(err).
+			WithFields(log.Fields{
+				"txTo":              call.Address(),
+				"txInput":           errutil.Ignore(call.CallData()),
+				"contractAddresses": addressesFromCalls(calls),
+			}).
+			WithAdvice("Ignore if it is related to temporary network issues").
+			Error("Failed to send transaction")
+		w.updateHealth(err)
+		return
+	}
+	w.log.
+		WithFields(log.Fields{
+			"txHash":                 txHash,
+			"txType":                 tx.Type,
+			"txFrom":                 tx.From,
+			"txTo":                   tx.To,
+			"txChainId":              tx.ChainID,
+			"txNonce":                tx.Nonce,
+			"txGasPrice":             tx.GasPrice,
+			"txGasLimit":             tx.GasLimit,
+			"txMaxFeePerGas":         tx.MaxFeePerGas,
+			"txMaxPriorityFeePerGas": tx.MaxPriorityFeePerGas,
+			"contractAddresses":      addressesFromCalls(calls),
+			"txInput":                hexutil.BytesToHex(tx.Input),
+		}).
+		Info("Relay transaction sent")
+	span.SetAttribute("txHash", txHash.String())
+	w.updateHealth(nil)
+}
+
+func (w *worker) relayCalls(ctx context.Context) []contract.Callable {
+	var (
+		mu        = sync.Mutex{}
+		wg        = sync.WaitGroup{}
+		limiter   = make(chan struct{}, w.maxConcurrency)
+		gasUsage  uint64
+		contracts []types.Address
+		calls     []contract.Callable
+	)
+	w.mu.RLock()
+	providers := w.providers
+	w.mu.RUnlock()
+	for _, u := range providers {
+		wg.Add(1)
+		go func(u callProvider) {
+			defer wg.Done()
+			defer func() { <-limiter }()
+			limiter <- struct{}{}
+			ctx, span := tracing.StartSpan(ctx, "relay.createRelayCall")
+			defer span.End()
+			relayCalls := u.createRelayCall(ctx)
+			span.SetAttribute("calls", len(relayCalls))
+			for _, c := range relayCalls {
+				mu.Lock()
+				if gasUsage >= w.gasUsageSoftCap {
+					// If the gas usage is above the soft cap, then do not
+					// add any more transactions to the aggregate transaction.
+					mu.Unlock()
+					continue
+				}
+				if sliceutil.Contains(contracts, c.address) {
+					// If there is already a transaction for the contract,
+					// then do not add another one.
+					mu.Unlock()
+					continue
+				}
+				gasEstimate := c.gasEstimate + 700 //nolint:gomnd // 700 is the minimum gas usage for a call
+				if gasEstimate > baseGasUsage {
+					gasEstimate -= baseGasUsage
+				}
+				gasUsage += gasEstimate
+				contracts = append(contracts, c.address)
+				calls = append(calls, c.callable)
+				mu.Unlock()
+			}
+		}(u)
+	}
+	wg.Wait()
+	return calls
+}
+
+// updateHealth updates the health of the worker after a relay attempt.
+func (w *worker) updateHealth(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	defer w.reportHealth()
+	now := time.Now()
+	w.health.LastRun = now
+	if err != nil {
+		w.health.LastError = err
+		w.health.Failures++
+		if w.health.Healthy && w.health.Failures >= maxWorkerFailures {
+			w.health.Healthy = false
+			w.log.
+				WithError(err).
+				WithField("failures", w.health.Failures).
+				WithAdvice("Check the RPC endpoints and the balance of the signer account").
+				Warn("Relay worker is unhealthy")
+		}
+		return
+	}
+	if !w.health.Healthy {
+		w.log.
+			WithField("failures", w.health.Failures).
+			Info("Relay worker recovered")
+	}
+	w.health.Healthy = true
+	w.health.LastSuccess = now
+	w.health.LastError = nil
+	w.health.Failures = 0
+}
+
+// reportHealth reports the health of the worker to the metrics registry.
+//
+// The caller must hold the lock.
+func (w *worker) reportHealth() {
+	labels := metrics.Labels{"worker": w.health.Name}
+	healthy := 0.0
+	if w.health.Healthy {
+		healthy = 1
+	}
+	w.metrics.Set(WorkerHealthyMetric, labels, healthy)
+	w.metrics.Set(WorkerFailuresMetric, labels, float64(w.health.Failures))
+	if !w.health.LastSuccess.IsZero() {
+		w.metrics.Set(WorkerLastSuccessMetric, labels, float64(w.health.LastSuccess.Unix()))
+	}
+}
@@ -0,0 +1,191 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package relay
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
+	"github.com/stretchr/testify/assert"
+	testifyMock "github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/orcfax/oracle-suite/pkg/contract"
+	"github.com/orcfax/oracle-suite/pkg/ethereum/mocks"
+	"github.com/orcfax/oracle-suite/pkg/metrics"
+	"github.com/orcfax/oracle-suite/pkg/util/timeutil"
+)
+
+type mockCallProvider struct {
+	calls []relayCall
+}
+
+func (m *mockCallProvider) createRelayCall(_ context.Context) []relayCall {
+	return m.calls
+}
+
+func testRelayCall(addr types.Address, gasEstimate uint64) relayCall {
+	return relayCall{
+		address: addr,
+		callable: contract.NewCall(contract.CallOpts{
+			Address: addr,
+			Encoder: func() ([]byte, error) { return addr.Bytes(), nil },
+		}),
+		gasEstimate: gasEstimate,
+	}
+}
+
+func TestWorker_RelayCalls(t *testing.T) {
+	addr1 := types.MustAddressFromHex("0x1111111111111111111111111111111111111111")
+	addr2 := types.MustAddressFromHex("0x2222222222222222222222222222222222222222")
+	addr3 := types.MustAddressFromHex("0x3333333333333333333333333333333333333333")
+
+	t.Run("all calls", func(t *testing.T) {
+		w := newWorker(ConfigWorker{Name: "test"}, metrics.NewNull(), newMockLogger(t))
+		w.setProviders([]callProvider{
+			&mockCallProvider{calls: []relayCall{testRelayCall(addr1, 100_000)}},
+			&mockCallProvider{calls: []relayCall{testRelayCall(addr2, 100_000)}},
+			&mockCallProvider{calls: []relayCall{testRelayCall(addr3, 100_000)}},
+		})
+		assert.ElementsMatch(t, []types.Address{addr1, addr2, addr3}, addressesFromCalls(w.relayCalls(context.Background())))
+	})
+	t.Run("duplicated contract", func(t *testing.T) {
+		w := newWorker(ConfigWorker{Name: "test"}, metrics.NewNull(), newMockLogger(t))
+		w.setProviders([]callProvider{
+			&mockCallProvider{calls: []relayCall{testRelayCall(addr1, 100_000), testRelayCall(addr1, 100_000)}},
+		})
+		assert.Equal(t, []types.Address{addr1}, addressesFromCalls(w.relayCalls(context.Background())))
+	})
+	t.Run("gas usage soft cap", func(t *testing.T) {
+		w := newWorker(ConfigWorker{Name: "test", GasUsageSoftCap: 150_000, MaxConcurrency: 1}, metrics.NewNull(), newMockLogger(t))
+		w.setProviders([]callProvider{
+			&mockCallProvider{calls: []relayCall{
+				testRelayCall(addr1, 100_000),
+				testRelayCall(addr2, 100_000),
+				testRelayCall(addr3, 100_000),
+			}},
+		})
+		assert.Equal(t, []types.Address{addr1, addr2}, addressesFromCalls(w.relayCalls(context.Background())))
+	})
+}
+
+func TestWorker_Health(t *testing.T) {
+	addr := types.MustAddressFromHex("0x1111111111111111111111111111111111111111")
+	client := &mocks.RPC{}
+	logger := newMockLogger(t)
+	registry := metrics.NewMemory(nil)
+	w := newWorker(ConfigWorker{Name: "arb1", Client: client}, registry, logger)
+	w.setProviders([]callProvider{&mockCallProvider{calls: []relayCall{testRelayCall(addr, 100_000)}}})
+
+	// Failed transactions.
+	client.On("SendTransaction", testifyMock.Anything, testifyMock.Anything).
+		Return((*types.Hash)(nil), (*types.Transaction)(nil), errors.New("rpc error")).
+		Times(maxWorkerFailures)
+	errorCalls, warnCalls := 0, 0
+	logger.ErrorFn = func(args ...any) { errorCalls++ }
+	logger.WarnFn = func(args ...any) { warnCalls++ }
+	for i := 0; i < maxWorkerFailures; i++ {
+		assert.True(t, w.healthStatus().Healthy)
+		w.sendRelayTransaction(context.Background())
+	}
+	health := w.healthStatus()
+	assert.Equal(t, "arb1", health.Name)
+	assert.False(t, health.Healthy)
+	assert.Equal(t, maxWorkerFailures, health.Failures)
+	assert.EqualError(t, health.LastError, "rpc error")
+	assert.True(t, health.LastSuccess.IsZero())
+	assert.Equal(t, maxWorkerFailures, errorCalls)
+	assert.Equal(t, 1, warnCalls)
+	healthy, _ := registry.Value(WorkerHealthyMetric, metrics.Labels{"worker": "arb1"})
+	assert.Equal(t, 0.0, healthy)
+	failures, _ := registry.Value(WorkerFailuresMetric, metrics.Labels{"worker": "arb1"})
+	assert.Equal(t, float64(maxWorkerFailures), failures)
+
+	// Successful transaction.
+	client.On("SendTransaction", testifyMock.Anything, testifyMock.Anything).
+		Return(&types.Hash{}, &types.Transaction{}, nil).
+		Once()
+	infoCalls := 0
+	logger.InfoFn = func(args ...any) { infoCalls++ }
+	w.sendRelayTransaction(context.Background())
+	health = w.healthStatus()
+	assert.True(t, health.Healthy)
+	assert.Zero(t, health.Failures)
+	assert.NoError(t, health.LastError)
+	assert.False(t, health.LastSuccess.IsZero())
+	assert.Equal(t, 2, infoCalls) // "Relay worker recovered" and "Relay transaction sent"
+	healthy, _ = registry.Value(WorkerHealthyMetric, metrics.Labels{"worker": "arb1"})
+	assert.Equal(t, 1.0, healthy)
+	lastSuccess, ok := registry.Value(WorkerLastSuccessMetric, metrics.Labels{"worker": "arb1"})
+	assert.True(t, ok)
+	assert.Equal(t, float64(health.LastSuccess.Unix()), lastSuccess)
+}
+
+func TestRelay_Workers(t *testing.T) {
+	client1 := &mocks.RPC{}
+	client2 := &mocks.RPC{}
+	addr1 := types.MustAddressFromHex("0x1111111111111111111111111111111111111111")
+	addr2 := types.MustAddressFromHex("0x2222222222222222222222222222222222222222")
+
+	_, err := New(Config{
+		Workers: []ConfigWorker{{Name: "eth"}},
+		Ticker:  timeutil.NewTicker(0),
+	})
+	require.Error(t, err)
+
+	relay, err := New(Config{
+		Medians: []ConfigMedian{
+			{Client: client1, ContractAddress: addr1, DataModel: "ETHUSD"},
+			{Client: client2, ContractAddress: addr2, DataModel: "ETHUSD"},
+		},
+		Workers: []ConfigWorker{{
+			Name:           "eth",
+			Client:         client1,
+			Ticker:         timeutil.NewTicker(time.Minute),
+			MaxConcurrency: 2,
+		}},
+		Ticker: timeutil.NewTicker(0),
+	})
+	require.NoError(t, err)
+
+	// Contracts are handled by the worker of their client.
+	require.Len(t, relay.workers, 2)
+	require.Len(t, relay.workers[client1].providers, 1)
+	require.Len(t, relay.workers[client2].providers, 1)
+	assert.Equal(t, addr1, relay.workers[client1].providers[0].(*median).contract.Address())
+	assert.Equal(t, addr2, relay.workers[client2].providers[0].(*median).contract.Address())
+	assert.Equal(t, 2, relay.workers[client1].maxConcurrency)
+	assert.NotNil(t, relay.workers[client1].ticker)
+	assert.Equal(t, maxParallelCallProviders, relay.workers[client2].maxConcurrency)
+	assert.Nil(t, relay.workers[client2].ticker)
+
+	// Removing contracts removes them from the workers.
+	relay.SetContracts(Contracts{Medians: []ConfigMedian{
+		{Client: client2, ContractAddress: addr2, DataModel: "ETHUSD"},
+	}})
+	assert.Len(t, relay.workers[client1].providers, 0)
+	assert.Len(t, relay.workers[client2].providers, 1)
+
+	health := relay.Health()
+	require.Len(t, health, 2)
+	assert.Equal(t, "client2", health[0].Name)
+	assert.Equal(t, "eth", health[1].Name)
+	assert.True(t, health[0].Healthy)
+	assert.True(t, health[1].Healthy)
+}
@@ -0,0 +1,229 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package rpcsplitter
+
+import (
+	"encoding/json"
+	"reflect"
+	"sync/atomic"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+
+	"github.com/orcfax/oracle-suite/pkg/rpcsplitter/types"
+)
+
+// cacheHeadMaxAge is the maximum age of the latest block number used to
+// decide whether a block is confirmed. Older block numbers are refreshed.
+const cacheHeadMaxAge = time.Minute
+
+// cachePolicy decides whether the result of a call is immutable and
+// therefore can be cached.
+type cachePolicy struct {
+	// args reports whether results for the given arguments are immutable.
+	// If nil, all arguments are accepted.
+	args func(args []any) bool
+
+	// result reports whether the given result is immutable. If nil, all
+	// results are accepted.
+	result func(res any) bool
+
+	// block returns the number of the block on which the result depends.
+	// If the second return value is false, the result does not depend on
+	// any block that can be reorganized. If nil, the result does not
+	// depend on any block.
+	block func(args []any, res any) (uint64, bool)
+}
+
+// cachePolicies lists methods whose results may be cached. Methods that
+// are not listed here are never cached.
+//
+// Results for block-dependent methods are cached only if the block number
+// is known, that is, when tags were resolved by the taggedBlockToNumber
+// method, and the block is confirmed, that is, it is at least the configured
+// number of blocks below the latest block. Otherwise, the result could
+// change after a chain reorganization. Results for blocks given by their
+// hashes are cached without confirmations.
+var cachePolicies = map[string]cachePolicy{
+	"eth_chainId":               {},
+	"net_version":               {},
+	"eth_getBlockByHash":        {result: isNotEmpty},
+	"eth_getBlockByNumber":      {result: isNotEmpty, block: argBlock},
+	"eth_getTransactionByHash":  {result: isMinedTransaction, block: transactionBlock},
+	"eth_getTransactionReceipt": {result: isMinedReceipt, block: receiptBlock},
+	"eth_getTransactionCount":   {args: hasResolvedBlock, block: argBlock},
+	"eth_getBalance":            {args: hasResolvedBlock, block: argBlock},
+	"eth_getCode":               {args: hasResolvedBlock, block: argBlock},
+	"eth_getStorageAt":          {args: hasResolvedBlock, block: argBlock},
+	"eth_call":                  {args: hasResolvedBlock, block: argBlock},
+	"eth_feeHistory":            {args: hasResolvedBlock, block: argBlock},
+	"eth_getLogs":               {args: hasResolvedLogsQuery, block: logsQueryBlock},
+	"eth_getBlockReceipts":      {args: hasResolvedBlock, result: isNotEmpty, block: argBlock},
+	"eth_getProof":              {args: hasResolvedBlock, block: argBlock},
+	"eth_createAccessList":      {args: hasResolvedBlock, block: argBlock},
+	"debug_traceCall":           {args: hasResolvedBlock, block: argBlock},
+}
+
+func isNotEmpty(res any) bool {
+	return !reflect.ValueOf(res).Elem().IsZero()
+}
+
+func isMinedTransaction(res any) bool {
+	tx, ok := res.(*types.Transaction)
+	return ok && tx.BlockHash != (types.Hash{})
+}
+
+func isMinedReceipt(res any) bool {
+	r, ok := res.(*types.TransactionReceiptType)
+	return ok && r.BlockHash != (types.Hash{})
+}
+
+func hasResolvedBlock(args []any) bool {
+	for _, arg := range args {
+		if b, ok := arg.(types.BlockNumber); ok {
+			return !b.IsTag()
+		}
+	}
+	return false
+}
+
+func argBlock(args []any, _ any) (uint64, bool) {
+	for _, arg := range args {
+		switch b := arg.(type) {
+		case types.BlockNumber:
+			return b.Big().Uint64(), true
+		case types.Number:
+			return b.Big().Uint64(), true
+		}
+	}
+	return 0, false
+}
+
+func transactionBlock(_ []any, res any) (uint64, bool) {
+	return res.(*types.Transaction).BlockNumber.Big().Uint64(), true
+}
+
+func receiptBlock(_ []any, res any) (uint64, bool) {
+	return res.(*types.TransactionReceiptType).BlockNumber.Big().Uint64(), true
+}
+
+func logsQueryBlock(args []any, _ any) (uint64, bool) {
+	q := args[0].(types.FilterLogsQuery)
+	if q.BlockHash != nil {
+		return 0, false
+	}
+	return q.ToBlock.Big().Uint64(), true
+}
+
+func hasResolvedLogsQuery(args []any) bool {
+	if len(args) == 0 {
+		return false
+	}
+	q, ok := args[0].(types.FilterLogsQuery)
+	if !ok {
+		return false
+	}
+	if q.BlockHash != nil {
+		return true
+	}
+	return q.FromBlock != nil && !q.FromBlock.IsTag() && q.ToBlock != nil && !q.ToBlock.IsTag()
+}
+
+// cache is an LRU cache for immutable call results.
+type cache struct {
+	lru           *lru.Cache[string, any]
+	confirmations uint64
+	hits          atomic.Uint64
+	misses        atomic.Uint64
+
+	// Latest known block number and the time when it was updated.
+	head     atomic.Uint64
+	headTime atomic.Int64
+}
+
+func newCache(size int, confirmations uint64) (*cache, error) {
+	l, err := lru.New[string, any](size)
+	if err != nil {
+		return nil, err
+	}
+	return &cache{lru: l, confirmations: confirmations}, nil
+}
+
+// setHead updates the latest known block number.
+func (c *cache) setHead(n uint64) {
+	c.head.Store(n)
+	c.headTime.Store(time.Now().UnixNano())
+}
+
+// latestHead returns the latest known block number. It returns false if the
+// block number is unknown or outdated.
+func (c *cache) latestHead() (uint64, bool) {
+	t := c.headTime.Load()
+	if t == 0 || time.Since(time.Unix(0, t)) > cacheHeadMaxAge {
+		return 0, false
+	}
+	return c.head.Load(), true
+}
+
+// get copies the cached result for the given key into res. It returns
+// false if there is no cached result.
+func (c *cache) get(key string, res any) bool {
+	v, ok := c.lru.Get(key)
+	if !ok {
+		c.misses.Add(1)
+		return false
+	}
+	c.hits.Add(1)
+	reflect.ValueOf(res).Elem().Set(reflect.ValueOf(v).Elem())
+	return true
+}
+
+// add adds the result to the cache if it is immutable. The head function
+// returns the latest block number, it is called only for results that depend
+// on a block that can be reorganized.
+func (c *cache) add(key string, method string, args []any, res any, head func() (uint64, bool)) {
+	p, ok := cachePolicies[method]
+	if !ok || (p.result != nil && !p.result(res)) {
+		return
+	}
+	if p.block != nil {
+		if n, ok := p.block(args, res); ok {
+			h, ok := head()
+			if !ok || n+c.confirmations > h {
+				return
+			}
+		}
+	}
+	c.lru.Add(key, res)
+}
+
+// isCacheable returns true if results of the method called with the given
+// arguments may be cached. Whether a particular result is cached is decided
+// by the add method.
+func isCacheable(method string, args []any) bool {
+	p, ok := cachePolicies[method]
+	return ok && (p.args == nil || p.args(args))
+}
+
+// callKey returns a key that identifies a call with the given method and
+// arguments. It is used as a cache key and to coalesce identical calls.
+func callKey(method string, args []any) (string, error) {
+	b, err := json.Marshal(removeTrailingNilArgs(args))
+	if err != nil {
+		return "", err
+	}
+	return method + string(b), nil
+}
@@ -0,0 +1,137 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package rpcsplitter
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/orcfax/oracle-suite/pkg/rpcsplitter/types"
+)
+
+func Test_RPC_Cache(t *testing.T) {
+	t.Run("immutable", func(t *testing.T) {
+		// Every client expects only one call, the second request must be
+		// served from the cache.
+		ht := prepareHandlerTest(t, 2, "eth_chainId").
+			setOptions(WithRequirements(2, 10), WithCache(10, 5)).
+			mockClientCall(0, `0x1`, "eth_chainId").
+			mockClientCall(1, `0x1`, "eth_chainId").
+			expectedResult(`0x1`)
+		h := ht.server()
+		ht.testServer(h)
+		ht.testServer(h)
+	})
+	t.Run("resolved-block", func(t *testing.T) {
+		blockNumber := types.StringToBlockNumber("0x10")
+		code := types.HexToBytes("0x01")
+		addr := types.HexToAddress("0xd46e8dd67c5d32be8058bb8eb970870f07244567")
+		// The block is confirmed, so the result is cached. The latest block
+		// number is fetched to check the number of confirmations.
+		ht := prepareHandlerTest(t, 2, "eth_getCode", addr, blockNumber).
+			setOptions(WithRequirements(2, 10), WithCache(10, 5)).
+			mockClientCall(0, code, "eth_getCode", addr, blockNumber).
+			mockClientCall(1, code, "eth_getCode", addr, blockNumber).
+			mockClientCall(0, `0x15`, "eth_blockNumber").
+			mockClientCall(1, `0x15`, "eth_blockNumber").
+			expectedResult(code)
+		h := ht.server()
+		ht.testServer(h)
+		ht.testServer(h)
+	})
+	t.Run("unconfirmed-block", func(t *testing.T) {
+		// The block is not confirmed yet, so the result may change after
+		// a reorganization and must not be cached.
+		blockNumber := types.StringToBlockNumber("0x10")
+		code := types.HexToBytes("0x01")
+		addr := types.HexToAddress("0xd46e8dd67c5d32be8058bb8eb970870f07244567")
+		ht := prepareHandlerTest(t, 1, "eth_getCode", addr, blockNumber).
+			setOptions(WithRequirements(1, 10), WithCache(10, 5)).
+			mockClientCall(0, code, "eth_getCode", addr, blockNumber).
+			mockClientCall(0, `0x14`, "eth_blockNumber").
+			mockClientCall(0, code, "eth_getCode", addr, blockNumber).
+			expectedResult(code)
+		h := ht.server()
+		ht.testServer(h)
+		ht.testServer(h)
+	})
+	t.Run("tagged-block", func(t *testing.T) {
+		// With a single endpoint, tags are not resolved, so the result
+		// must not be cached.
+		code := types.HexToBytes("0x01")
+		addr := types.HexToAddress("0xd46e8dd67c5d32be8058bb8eb970870f07244567")
+		ht := prepareHandlerTest(t, 1, "eth_getCode", addr, types.LatestBlockNumber).
+			setOptions(WithRequirements(1, 10), WithCache(10, 5)).
+			mockClientCall(0, code, "eth_getCode", addr, types.LatestBlockNumber).
+			mockClientCall(0, code, "eth_getCode", addr, types.LatestBlockNumber).
+			expectedResult(code)
+		h := ht.server()
+		ht.testServer(h)
+		ht.testServer(h)
+	})
+	t.Run("pending-receipt", func(t *testing.T) {
+		// Receipts of transactions that are not mined yet must not be
+		// cached.
+		txHash := types.HexToHash("0xab059a62e22e230fe0f56d8555340a29b2e9532360368f810595453f6fdd213b")
+		ht := prepareHandlerTest(t, 1, "eth_getTransactionReceipt", txHash).
+			setOptions(WithRequirements(1, 10), WithCache(10, 5)).
+			mockClientCall(0, nil, "eth_getTransactionReceipt", txHash).
+			mockClientCall(0, transactionReceipt1Resp, "eth_getTransactionReceipt", txHash).
+			mockClientCall(0, `0x429d3c`, "eth_blockNumber").
+			mockClientCall(0, transactionReceipt1Resp, "eth_getTransactionReceipt", txHash).
+			mockClientCall(0, `0x429d40`, "eth_blockNumber")
+		h := ht.server()
+		ht.expectedResult(types.TransactionReceiptType{}).testServer(h)
+		// The receipt is not cached until the block is confirmed, because
+		// the transaction may be included in a different block after a
+		// reorganization.
+		ht.expectedResult(transactionReceipt1Resp).testServer(h)
+		h.(*server).cache.headTime.Store(0)
+		ht.testServer(h)
+		ht.testServer(h)
+	})
+	t.Run("disabled", func(t *testing.T) {
+		ht := prepareHandlerTest(t, 1, "eth_chainId").
+			setOptions(WithRequirements(1, 10)).
+			mockClientCall(0, `0x1`, "eth_chainId").
+			mockClientCall(0, `0x1`, "eth_chainId").
+			expectedResult(`0x1`)
+		h := ht.server()
+		ht.testServer(h)
+		ht.testServer(h)
+	})
+}
+
+func Test_RPC_Coalescing(t *testing.T) {
+	// Every client expects only one call, concurrent requests must be
+	// coalesced into a single call.
+	ht := prepareHandlerTest(t, 2, "eth_gasPrice").
+		setOptions(WithRequirements(2, 10)).
+		mockClientSlowCall(100*time.Millisecond, 0, `0x1`, "eth_gasPrice").
+		mockClientSlowCall(100*time.Millisecond, 1, `0x1`, "eth_gasPrice").
+		expectedResult(`0x1`)
+	h := ht.server()
+	wg := sync.WaitGroup{}
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ht.testServer(h)
+		}()
+	}
+	wg.Wait()
+}
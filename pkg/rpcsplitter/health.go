@@ -0,0 +1,445 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package rpcsplitter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/orcfax/oracle-suite/pkg/log"
+	"github.com/orcfax/oracle-suite/pkg/rpcsplitter/types"
+)
+
+// StatusPath is the path under which the status of endpoints is served.
+const StatusPath = "/status"
+
+const (
+	// healthAlpha is the smoothing factor for the moving averages of the
+	// error rate and latency.
+	healthAlpha = 0.1
+
+	defaultMaxErrorRate     = 0.5
+	defaultMinSamples       = 10
+	defaultEjectionTime     = 30 * time.Second
+	defaultMaxEjectionTime  = 10 * time.Minute
+	endpointStateHealthy    = "healthy"
+	endpointStateEjected    = "ejected"
+	endpointStateProbing    = "probing"
+	endpointStateUnobserved = "unobserved"
+)
+
+// HealthConfig configures the endpoint health tracking.
+//
+// Endpoints whose error rate, average latency or block lag exceeds the
+// configured limits are temporarily ejected. After the ejection time, the
+// endpoint is probed with an eth_blockNumber request sent along with the
+// next request. If the probe succeeds and the endpoint is not behind the
+// other endpoints, it is restored, otherwise it is ejected again for twice
+// as long.
+//
+// The block lag is measured against the median block number of healthy
+// endpoints, so a single endpoint reporting a wrong block number cannot
+// eject the others.
+type HealthConfig struct {
+	// MaxErrorRate is the error rate, between 0 and 1, above which an
+	// endpoint is ejected. If zero, 0.5 is used.
+	MaxErrorRate float64
+
+	// MaxLatency is the average latency above which an endpoint is ejected.
+	// If zero, the graceful timeout is used.
+	MaxLatency time.Duration
+
+	// MaxBlockLag is the number of blocks an endpoint can be behind the
+	// median block number of healthy endpoints before it is ejected. If zero,
+	// the maxBlockBehind value from the WithRequirements option is used.
+	MaxBlockLag uint64
+
+	// MinSamples is the minimum number of responses from an endpoint before
+	// its error rate and latency are taken into account. If zero, 10 is used.
+	MinSamples int
+
+	// EjectionTime is the initial time for which an endpoint is ejected.
+	// If zero, 30 seconds is used.
+	EjectionTime time.Duration
+
+	// MaxEjectionTime is the maximum time for which an endpoint is ejected.
+	// If zero, 10 minutes is used.
+	MaxEjectionTime time.Duration
+
+	// MaxEndpoints is the maximum number of healthy endpoints queried per
+	// request. If more endpoints are healthy, they are picked at random with
+	// a probability proportional to their scores, so more reliable endpoints
+	// are queried more often. It is never lower than the minimum number of
+	// responses. If zero, all healthy endpoints are queried.
+	MaxEndpoints int
+}
+
+// EndpointStatus describes the health of an endpoint.
+type EndpointStatus struct {
+	Name         string     `json:"name"`
+	State        string     `json:"state"`
+	Score        float64    `json:"score"`
+	ErrorRate    float64    `json:"errorRate"`
+	Latency      string     `json:"latency"`
+	BlockNumber  uint64     `json:"blockNumber"`
+	BlockLag     uint64     `json:"blockLag"`
+	Requests     uint64     `json:"requests"`
+	Errors       uint64     `json:"errors"`
+	Ejections    uint64     `json:"ejections"`
+	EjectedUntil *time.Time `json:"ejectedUntil,omitempty"`
+}
+
+// health tracks the health of endpoints.
+type health struct {
+	mu        sync.Mutex
+	log       log.Logger
+	cfg       HealthConfig
+	endpoints map[string]*endpointHealth
+	rand      *rand.Rand
+}
+
+type endpointHealth struct {
+	errorRate    float64
+	latency      time.Duration
+	blockNumber  uint64
+	requests     uint64
+	errors       uint64
+	ejections    uint64
+	ejectionTime time.Duration
+	ejectedUntil time.Time
+	probing      bool
+}
+
+func newHealth(cfg HealthConfig, callers map[string]caller, logger log.Logger) *health {
+	h := &health{
+		cfg:       cfg,
+		log:       logger,
+		endpoints: make(map[string]*endpointHealth, len(callers)),
+		rand:      rand.New(rand.NewSource(time.Now().UnixNano())), //nolint:gosec
+	}
+	for n := range callers {
+		h.endpoints[n] = &endpointHealth{}
+	}
+	return h
+}
+
+// healthConfigWithDefaults returns the health configuration with default
+// values for unset fields.
+func (s *server) healthConfigWithDefaults() HealthConfig {
+	cfg := s.healthConfig
+	if cfg.MaxErrorRate == 0 {
+		cfg.MaxErrorRate = defaultMaxErrorRate
+	}
+	if cfg.MaxLatency == 0 {
+		cfg.MaxLatency = s.gracefulTimeout
+	}
+	if cfg.MaxBlockLag == 0 && s.blockNumberResolver.maxBlocksBehind > 0 {
+		cfg.MaxBlockLag = uint64(s.blockNumberResolver.maxBlocksBehind)
+	}
+	if cfg.MinSamples == 0 {
+		cfg.MinSamples = defaultMinSamples
+	}
+	if cfg.EjectionTime == 0 {
+		cfg.EjectionTime = defaultEjectionTime
+	}
+	if cfg.MaxEjectionTime == 0 {
+		cfg.MaxEjectionTime = defaultMaxEjectionTime
+	}
+	return cfg
+}
+
+// serveStatus writes the status of all endpoints as JSON.
+func (s *server) serveStatus(rw http.ResponseWriter) {
+	rw.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(rw).Encode(s.health.status()); err != nil {
+		s.log.WithError(err).Error("Unable to write status")
+	}
+}
+
+// score returns the reliability score of the endpoint. The score is
+// between 0 and 1, where 1 means the endpoint always responds without
+// errors and without delay.
+func (e *endpointHealth) score() float64 {
+	return (1 - e.errorRate) / (1 + e.latency.Seconds())
+}
+
+// available returns the names of endpoints that should be queried and the
+// names of ejected endpoints that should be probed.
+//
+// Ejected endpoints are skipped, unless their ejection time has passed, in
+// which case they are probed. If fewer than minResponses endpoints are
+// available, the ejected endpoints with the highest scores are added. If
+// there are more healthy endpoints than MaxEndpoints, they are picked by
+// their scores.
+func (h *health) available(minResponses int) (names []string, probes []string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := time.Now()
+	var ejected []string
+	for n, e := range h.endpoints {
+		switch {
+		case e.ejectedUntil.IsZero():
+			names = append(names, n)
+		case now.After(e.ejectedUntil) && !e.probing:
+			e.probing = true
+			probes = append(probes, n)
+			ejected = append(ejected, n)
+		default:
+			ejected = append(ejected, n)
+		}
+	}
+	if maxEndpoints := h.cfg.MaxEndpoints; maxEndpoints > 0 {
+		if maxEndpoints < minResponses {
+			maxEndpoints = minResponses
+		}
+		if len(names) > maxEndpoints {
+			names = h.pick(names, maxEndpoints)
+		}
+	}
+	if len(names) < minResponses {
+		sort.Slice(ejected, func(i, j int) bool {
+			return h.endpoints[ejected[i]].score() > h.endpoints[ejected[j]].score()
+		})
+		for _, n := range ejected {
+			if len(names) >= minResponses {
+				break
+			}
+			names = append(names, n)
+		}
+	}
+	return names, probes
+}
+
+// pick picks n endpoints at random, with a probability proportional to
+// their scores.
+func (h *health) pick(names []string, n int) []string {
+	names = append([]string(nil), names...)
+	picked := make([]string, 0, n)
+	for len(picked) < n {
+		var total float64
+		for _, name := range names {
+			total += h.weight(name)
+		}
+		r := h.rand.Float64() * total
+		i := 0
+		for ; i < len(names)-1; i++ {
+			r -= h.weight(names[i])
+			if r < 0 {
+				break
+			}
+		}
+		picked = append(picked, names[i])
+		names = append(names[:i], names[i+1:]...)
+	}
+	return picked
+}
+
+// weight returns the weight of the endpoint used to pick endpoints. The
+// minimum weight is non-zero, so every endpoint is queried from time to
+// time, and its score can improve.
+func (h *health) weight(name string) float64 {
+	const minWeight = 0.01
+	if w := h.endpoints[name].score(); w > minWeight {
+		return w
+	}
+	return minWeight
+}
+
+// record records the result of a call to the endpoint.
+//
+// Canceled calls are not counted as errors, because calls to slower
+// endpoints are canceled once enough responses are received. Their
+// duration is still included in the latency.
+func (h *health) record(name string, method string, d time.Duration, res any, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	e, ok := h.endpoints[name]
+	if !ok {
+		return
+	}
+	failed := err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	e.requests++
+	if e.requests == 1 {
+		e.latency = d
+	} else {
+		e.latency = time.Duration((1-healthAlpha)*float64(e.latency) + healthAlpha*float64(d))
+	}
+	if failed {
+		e.errors++
+		e.errorRate = (1-healthAlpha)*e.errorRate + healthAlpha
+	} else {
+		e.errorRate = (1 - healthAlpha) * e.errorRate
+	}
+	if err == nil && method == "eth_blockNumber" {
+		if n, ok := res.(*types.Number); ok && n.Big().IsUint64() {
+			e.blockNumber = n.Big().Uint64()
+		}
+	}
+	if e.ejectedUntil.IsZero() && h.isUnhealthy(e) {
+		h.eject(name, e)
+	}
+}
+
+// recordProbe records the result of an eth_blockNumber probe of an ejected
+// endpoint.
+//
+// The endpoint is restored only if the probe returns a block number that is
+// not behind the other endpoints. Canceled probes leave the endpoint
+// ejected, so it is probed again with the next request.
+func (h *health) recordProbe(name string, d time.Duration, res any, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	e, ok := h.endpoints[name]
+	if !ok || !e.probing {
+		return
+	}
+	e.probing = false
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	n, ok := res.(*types.Number)
+	if err != nil || !ok || !n.Big().IsUint64() {
+		h.eject(name, e)
+		return
+	}
+	e.blockNumber = n.Big().Uint64()
+	if h.isLagging(e) {
+		h.eject(name, e)
+		return
+	}
+	h.log.WithField("name", redactEndpoint(name)).Info("Endpoint restored")
+	e.ejectedUntil = time.Time{}
+	e.ejectionTime = 0
+	e.errorRate = 0
+	e.latency = d
+}
+
+func (h *health) isUnhealthy(e *endpointHealth) bool {
+	if h.isLagging(e) {
+		return true
+	}
+	if e.requests < uint64(h.cfg.MinSamples) {
+		return false
+	}
+	return e.errorRate > h.cfg.MaxErrorRate || (h.cfg.MaxLatency > 0 && e.latency > h.cfg.MaxLatency)
+}
+
+// isLagging reports whether the endpoint is more than MaxBlockLag blocks
+// behind the other endpoints.
+func (h *health) isLagging(e *endpointHealth) bool {
+	return h.cfg.MaxBlockLag > 0 && h.blockLag(e) > h.cfg.MaxBlockLag
+}
+
+// blockLag returns the number of blocks the endpoint is behind the median
+// block number of healthy endpoints.
+func (h *health) blockLag(e *endpointHealth) uint64 {
+	if e.blockNumber == 0 {
+		return 0
+	}
+	if ref := h.referenceBlock(); ref > e.blockNumber {
+		return ref - e.blockNumber
+	}
+	return 0
+}
+
+// referenceBlock returns the median block number of healthy endpoints. For
+// an even number of endpoints, the higher of the two middle values is used.
+// It returns zero if no block numbers are known.
+func (h *health) referenceBlock() uint64 {
+	var bs []uint64
+	for _, e := range h.endpoints {
+		if e.ejectedUntil.IsZero() && e.blockNumber > 0 {
+			bs = append(bs, e.blockNumber)
+		}
+	}
+	if len(bs) == 0 {
+		return 0
+	}
+	sort.Slice(bs, func(i, j int) bool { return bs[i] < bs[j] })
+	return bs[len(bs)/2]
+}
+
+func (h *health) eject(name string, e *endpointHealth) {
+	switch {
+	case e.ejectionTime == 0:
+		e.ejectionTime = h.cfg.EjectionTime
+	case e.ejectionTime*2 > h.cfg.MaxEjectionTime:
+		e.ejectionTime = h.cfg.MaxEjectionTime
+	default:
+		e.ejectionTime *= 2
+	}
+	e.ejections++
+	e.ejectedUntil = time.Now().Add(e.ejectionTime)
+	h.log.
+		WithField("name", redactEndpoint(name)).
+		WithField("errorRate", e.errorRate).
+		WithField("latency", e.latency).
+		WithField("blockNumber", e.blockNumber).
+		WithField("ejectionTime", e.ejectionTime).
+		Warn("Endpoint ejected")
+}
+
+// status returns the status of all endpoints, sorted by name.
+func (h *health) status() []EndpointStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := time.Now()
+	var s []EndpointStatus
+	for n, e := range h.endpoints {
+		st := EndpointStatus{
+			Name:        redactEndpoint(n),
+			State:       endpointStateHealthy,
+			Score:       e.score(),
+			ErrorRate:   e.errorRate,
+			Latency:     e.latency.String(),
+			BlockNumber: e.blockNumber,
+			Requests:    e.requests,
+			Errors:      e.errors,
+			Ejections:   e.ejections,
+		}
+		st.BlockLag = h.blockLag(e)
+		switch {
+		case e.probing || (!e.ejectedUntil.IsZero() && now.After(e.ejectedUntil)):
+			st.State = endpointStateProbing
+		case !e.ejectedUntil.IsZero():
+			st.State = endpointStateEjected
+			t := e.ejectedUntil
+			st.EjectedUntil = &t
+		case e.requests == 0:
+			st.State = endpointStateUnobserved
+		}
+		s = append(s, st)
+	}
+	sort.Slice(s, func(i, j int) bool { return s[i].Name < s[j].Name })
+	return s
+}
+
+// redactEndpoint removes the path, query and user information from the
+// endpoint URL, because they often contain API keys.
+func redactEndpoint(name string) string {
+	u, err := url.Parse(name)
+	if err != nil || u.Host == "" {
+		return name
+	}
+	return u.Scheme + "://" + u.Host
+}
@@ -162,14 +162,23 @@ func (t *handlerTester) expectedError(msg string) *handlerTester {
 }
 
 func (t *handlerTester) test() {
-	// Prepare server.
+	t.testServer(t.server())
+}
+
+// server creates a new server using mocked clients.
+func (t *handlerTester) server() http.Handler {
 	callers := map[string]caller{}
 	for n, c := range t.clients {
 		callers[fmt.Sprintf("%d", n)] = c
 	}
 	h, err := NewServer(append([]Option{withCallers(callers)}, t.options...)...)
 	require.NoError(t.t, err, "failed to create server")
+	return h
+}
 
+// testServer sends the request to the given server and verifies the
+// response.
+func (t *handlerTester) testServer(h http.Handler) {
 	// Prepare request.
 	id := rand.Int()
 	msg := jsonMarshal(t.t, rpcReq{
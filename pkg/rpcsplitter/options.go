@@ -82,6 +82,27 @@ func WithGracefulTimeout(t time.Duration) Option {
 	}
 }
 
+// WithCache enables an in-memory LRU cache for immutable results, such as
+// the chain ID, mined transactions or calls at a specific block number.
+// The size is the maximum number of cached results. Results that depend on
+// a block are cached only if the block is at least confirmations blocks
+// below the latest block, so that they are not affected by reorganizations.
+func WithCache(size int, confirmations uint64) Option {
+	return func(s *server) (err error) {
+		s.cache, err = newCache(size, confirmations)
+		return err
+	}
+}
+
+// WithHealth configures the endpoint health tracking. Health tracking is
+// always enabled, this option only changes the default limits.
+func WithHealth(cfg HealthConfig) Option {
+	return func(s *server) error {
+		s.healthConfig = cfg
+		return nil
+	}
+}
+
 // WithLogger sets logger.
 func WithLogger(logger log.Logger) Option {
 	return func(s *server) error {
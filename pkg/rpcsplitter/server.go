@@ -16,15 +16,20 @@
 package rpcsplitter
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"reflect"
+	"strings"
+	"sync"
 	"time"
 
 	gethRPC "github.com/ethereum/go-ethereum/rpc"
+	"golang.org/x/sync/singleflight"
 
 	"github.com/orcfax/oracle-suite/pkg/log"
 	"github.com/orcfax/oracle-suite/pkg/log/null"
@@ -36,16 +41,22 @@ const LoggerTag = "RPCSPLITTER"
 const defaultTotalTimeout = 10 * time.Second
 const defaultGracefulTimeout = 1 * time.Second
 
+// maxRequestSize is the maximum size of a request body, it is the same as
+// the limit used by the go-ethereum RPC server.
+const maxRequestSize = 5 * 1024 * 1024
+
 type caller interface {
 	CallContext(ctx context.Context, result any, method string, args ...any) error
 }
 
 // server is an RPC proxy server. It merges multiple RPC endpoints into one.
 type server struct {
-	rpc *gethRPC.Server // rpc is an RPC server.
-	eth *rpcETHAPI      // eth implements procedures with the "eth_" prefix.
-	net *rpcNETAPI      // net implements procedures with the "net_" prefix.
-	log log.Logger
+	rpc   *gethRPC.Server // rpc is an RPC server.
+	ws    http.Handler    // ws is a websocket handler for the RPC server.
+	eth   *rpcETHAPI      // eth implements procedures with the "eth_" prefix.
+	net   *rpcNETAPI      // net implements procedures with the "net_" prefix.
+	debug *rpcDEBUGAPI    // debug implements procedures with the "debug_" prefix.
+	log   log.Logger
 
 	// List of endpoint callers.
 	callers map[string]caller
@@ -55,6 +66,15 @@ type server struct {
 	// if there is enough responses.
 	gracefulTimeout time.Duration
 
+	// Health of endpoints, used to skip unhealthy endpoints.
+	health       *health
+	healthConfig HealthConfig
+
+	// Cache for immutable results, nil if caching is disabled.
+	cache *cache
+	// Group used to coalesce identical in-flight calls.
+	group singleflight.Group
+
 	// Resolvers used to convert multiple responses into a single response:
 	defaultResolver     *defaultResolver
 	gasValueResolver    *gasValueResolver
@@ -69,6 +89,22 @@ type rpcNETAPI struct {
 	handler *server
 }
 
+type rpcDEBUGAPI struct {
+	handler *server
+}
+
+// batchKey is the context key under which the batchState is stored.
+type batchKey struct{}
+
+// batchState is shared by all calls in a single JSON-RPC batch. It is
+// used to resolve block tags only once per batch, so that all calls in
+// the batch use the same block number.
+type batchState struct {
+	once        sync.Once
+	blockNumber types.BlockNumber
+	err         error
+}
+
 func NewServer(opts ...Option) (http.Handler, error) {
 	h := &server{
 		rpc:     gethRPC.NewServer(),
@@ -76,14 +112,20 @@ func NewServer(opts ...Option) (http.Handler, error) {
 	}
 	eth := &rpcETHAPI{handler: h}
 	net := &rpcNETAPI{handler: h}
+	debug := &rpcDEBUGAPI{handler: h}
 	h.eth = eth
 	h.net = net
+	h.debug = debug
+	h.ws = h.rpc.WebsocketHandler([]string{"*"})
 	if err := h.rpc.RegisterName("eth", eth); err != nil {
 		return nil, err
 	}
 	if err := h.rpc.RegisterName("net", net); err != nil {
 		return nil, err
 	}
+	if err := h.rpc.RegisterName("debug", debug); err != nil {
+		return nil, err
+	}
 	for _, opt := range opts {
 		err := opt(h)
 		if err != nil {
@@ -106,19 +148,54 @@ func NewServer(opts ...Option) (http.Handler, error) {
 		h.gracefulTimeout = defaultGracefulTimeout
 	}
 	h.log = h.log.WithField("tag", LoggerTag)
+	h.health = newHealth(h.healthConfigWithDefaults(), h.callers, h.log)
 	return h, nil
 }
 
+// ServeHTTP implements the http.Handler interface. Websocket upgrade
+// requests are handled by the websocket handler, which supports
+// subscriptions.
 func (s *server) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if req.Method == http.MethodGet && req.URL.Path == StatusPath {
+		s.serveStatus(rw)
+		return
+	}
+	if strings.EqualFold(req.Header.Get("Upgrade"), "websocket") {
+		s.ws.ServeHTTP(rw, req)
+		return
+	}
+	if req.Body != nil {
+		body, err := io.ReadAll(http.MaxBytesReader(rw, req.Body, maxRequestSize))
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				http.Error(rw, err.Error(), http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		if isBatch(body) {
+			req = req.WithContext(context.WithValue(req.Context(), batchKey{}, &batchState{}))
+		}
+	}
 	s.rpc.ServeHTTP(rw, req)
 }
 
+// callContext returns a context for a call to endpoints. The context is
+// not canceled when the client request is canceled, because the call
+// may be shared with other requests.
+func (s *server) callContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), s.totalTimeout)
+}
+
 // BlockNumber implements the "eth_blockNumber" call.
 //
 // It returns the most common response that occurred at least as many times as
 // specified in the minRes method.
-func (r *rpcETHAPI) BlockNumber() (any, error) {
-	ctx, ctxCancel := context.WithTimeout(context.Background(), r.handler.totalTimeout)
+func (r *rpcETHAPI) BlockNumber(ctx context.Context) (any, error) {
+	ctx, ctxCancel := r.handler.callContext(ctx)
 	defer ctxCancel()
 
 	res := &types.Number{}
@@ -131,8 +208,8 @@ func (r *rpcETHAPI) BlockNumber() (any, error) {
 //
 // The number returned by this method is the median of all numbers returned
 // by the endpoints.
-func (r *rpcETHAPI) GetBlockByHash(blockHash types.Hash, obj bool) (any, error) {
-	ctx, ctxCancel := context.WithTimeout(context.Background(), r.handler.totalTimeout)
+func (r *rpcETHAPI) GetBlockByHash(ctx context.Context, blockHash types.Hash, obj bool) (any, error) {
+	ctx, ctxCancel := r.handler.callContext(ctx)
 	defer ctxCancel()
 
 	var res any
@@ -151,8 +228,8 @@ func (r *rpcETHAPI) GetBlockByHash(blockHash types.Hash, obj bool) (any, error)
 //
 // It returns the most common response that occurred at least as many times as
 // specified in the minRes method.
-func (r *rpcETHAPI) GetBlockByNumber(blockNumber types.Number, obj bool) (any, error) {
-	ctx, ctxCancel := context.WithTimeout(context.Background(), r.handler.totalTimeout)
+func (r *rpcETHAPI) GetBlockByNumber(ctx context.Context, blockNumber types.Number, obj bool) (any, error) {
+	ctx, ctxCancel := r.handler.callContext(ctx)
 	defer ctxCancel()
 
 	var res any
@@ -171,8 +248,8 @@ func (r *rpcETHAPI) GetBlockByNumber(blockNumber types.Number, obj bool) (any, e
 //
 // It returns the most common response that occurred at least as many times as
 // specified in the minRes method.
-func (r *rpcETHAPI) GetTransactionByHash(txHash types.Hash) (any, error) {
-	ctx, ctxCancel := context.WithTimeout(context.Background(), r.handler.totalTimeout)
+func (r *rpcETHAPI) GetTransactionByHash(ctx context.Context, txHash types.Hash) (any, error) {
+	ctx, ctxCancel := r.handler.callContext(ctx)
 	defer ctxCancel()
 
 	res := &types.Transaction{}
@@ -189,8 +266,8 @@ func (r *rpcETHAPI) GetTransactionByHash(txHash types.Hash) (any, error) {
 // If the block number is set to "latest" or "pending", it will be replaced by
 // the block number returned by the BlockNumber method. The "earliest" tag is
 // not supported.
-func (r *rpcETHAPI) GetTransactionCount(addr types.Address, blockID types.BlockNumber) (any, error) {
-	ctx, ctxCancel := context.WithTimeout(context.Background(), r.handler.totalTimeout)
+func (r *rpcETHAPI) GetTransactionCount(ctx context.Context, addr types.Address, blockID types.BlockNumber) (any, error) {
+	ctx, ctxCancel := r.handler.callContext(ctx)
 	defer ctxCancel()
 
 	blockNumber, err := r.handler.taggedBlockToNumber(ctx, blockID)
@@ -207,8 +284,8 @@ func (r *rpcETHAPI) GetTransactionCount(addr types.Address, blockID types.BlockN
 //
 // It returns the most common response that occurred at least as many times as
 // specified in the minRes method.
-func (r *rpcETHAPI) GetTransactionReceipt(txHash types.Hash) (any, error) {
-	ctx, ctxCancel := context.WithTimeout(context.Background(), r.handler.totalTimeout)
+func (r *rpcETHAPI) GetTransactionReceipt(ctx context.Context, txHash types.Hash) (any, error) {
+	ctx, ctxCancel := r.handler.callContext(ctx)
 	defer ctxCancel()
 
 	res := &types.TransactionReceiptType{}
@@ -217,6 +294,28 @@ func (r *rpcETHAPI) GetTransactionReceipt(txHash types.Hash) (any, error) {
 	return res, err
 }
 
+// GetBlockReceipts implements the "eth_getBlockReceipts" call.
+//
+// It returns the most common response that occurred at least as many times as
+// specified in the minRes method.
+//
+// If the block number is set to "latest" or "pending", it will be replaced by
+// the block number returned by the BlockNumber method. The "earliest" tag is
+// not supported.
+func (r *rpcETHAPI) GetBlockReceipts(ctx context.Context, blockID types.BlockNumber) (any, error) {
+	ctx, ctxCancel := r.handler.callContext(ctx)
+	defer ctxCancel()
+
+	blockNumber, err := r.handler.taggedBlockToNumber(ctx, blockID)
+	if err != nil {
+		return nil, err
+	}
+	res := &[]types.TransactionReceiptType{}
+	err = r.handler.call(ctx, r.handler.defaultResolver, res, "eth_getBlockReceipts", blockNumber)
+
+	return res, err
+}
+
 // TODO: eth_getBlockTransactionCountByHash
 // TODO: eth_getBlockTransactionCountByNumber
 // TODO: eth_getTransactionByBlockHashAndIndex
@@ -225,8 +324,8 @@ func (r *rpcETHAPI) GetTransactionReceipt(txHash types.Hash) (any, error) {
 // SendRawTransaction implements the "eth_sendRawTransaction" call.
 //
 // It returns the most common response.
-func (r *rpcETHAPI) SendRawTransaction(data types.Bytes) (any, error) {
-	ctx, ctxCancel := context.WithTimeout(context.Background(), r.handler.totalTimeout)
+func (r *rpcETHAPI) SendRawTransaction(ctx context.Context, data types.Bytes) (any, error) {
+	ctx, ctxCancel := r.handler.callContext(ctx)
 	defer ctxCancel()
 
 	res := &types.Hash{}
@@ -243,8 +342,8 @@ func (r *rpcETHAPI) SendRawTransaction(data types.Bytes) (any, error) {
 // If the block number is set to "latest" or "pending", it will be replaced by
 // the block number returned by the BlockNumber method. The "earliest" tag is
 // not supported.
-func (r *rpcETHAPI) GetBalance(addr types.Address, blockID types.BlockNumber) (any, error) {
-	ctx, ctxCancel := context.WithTimeout(context.Background(), r.handler.totalTimeout)
+func (r *rpcETHAPI) GetBalance(ctx context.Context, addr types.Address, blockID types.BlockNumber) (any, error) {
+	ctx, ctxCancel := r.handler.callContext(ctx)
 	defer ctxCancel()
 
 	blockNumber, err := r.handler.taggedBlockToNumber(ctx, blockID)
@@ -265,8 +364,8 @@ func (r *rpcETHAPI) GetBalance(addr types.Address, blockID types.BlockNumber) (a
 // If the block number is set to "latest" or "pending", it will be replaced by
 // the block number returned by the BlockNumber method. The "earliest" tag is
 // not supported.
-func (r *rpcETHAPI) GetCode(addr types.Address, blockID types.BlockNumber) (any, error) {
-	ctx, ctxCancel := context.WithTimeout(context.Background(), r.handler.totalTimeout)
+func (r *rpcETHAPI) GetCode(ctx context.Context, addr types.Address, blockID types.BlockNumber) (any, error) {
+	ctx, ctxCancel := r.handler.callContext(ctx)
 	defer ctxCancel()
 
 	blockNumber, err := r.handler.taggedBlockToNumber(ctx, blockID)
@@ -287,8 +386,8 @@ func (r *rpcETHAPI) GetCode(addr types.Address, blockID types.BlockNumber) (any,
 // If the block number is set to "latest" or "pending", it will be replaced by
 // the block number returned by the BlockNumber method. The "earliest" tag is
 // not supported.
-func (r *rpcETHAPI) GetStorageAt(data types.Address, pos types.Number, blockID types.BlockNumber) (any, error) {
-	ctx, ctxCancel := context.WithTimeout(context.Background(), r.handler.totalTimeout)
+func (r *rpcETHAPI) GetStorageAt(ctx context.Context, data types.Address, pos types.Number, blockID types.BlockNumber) (any, error) {
+	ctx, ctxCancel := r.handler.callContext(ctx)
 	defer ctxCancel()
 
 	blockNumber, err := r.handler.taggedBlockToNumber(ctx, blockID)
@@ -302,7 +401,50 @@ func (r *rpcETHAPI) GetStorageAt(data types.Address, pos types.Number, blockID t
 }
 
 // TODO: eth_accounts
-// TODO: eth_getProof
+
+// GetProof implements the "eth_getProof" call.
+//
+// It returns the most common response that occurred at least as many times as
+// specified in the minRes method.
+//
+// If the block number is set to "latest" or "pending", it will be replaced by
+// the block number returned by the BlockNumber method. The "earliest" tag is
+// not supported.
+func (r *rpcETHAPI) GetProof(ctx context.Context, addr types.Address, keys []types.Hash, blockID types.BlockNumber) (any, error) {
+	ctx, ctxCancel := r.handler.callContext(ctx)
+	defer ctxCancel()
+
+	blockNumber, err := r.handler.taggedBlockToNumber(ctx, blockID)
+	if err != nil {
+		return nil, err
+	}
+	res := &Any{}
+	err = r.handler.call(ctx, r.handler.defaultResolver, res, "eth_getProof", addr, keys, blockNumber)
+
+	return res, err
+}
+
+// CreateAccessList implements the "eth_createAccessList" call.
+//
+// It returns the most common response that occurred at least as many times as
+// specified in the minRes method.
+//
+// If the block number is set to "latest" or "pending", it will be replaced by
+// the block number returned by the BlockNumber method. The "earliest" tag is
+// not supported.
+func (r *rpcETHAPI) CreateAccessList(ctx context.Context, args Any, blockID types.BlockNumber) (any, error) {
+	ctx, ctxCancel := r.handler.callContext(ctx)
+	defer ctxCancel()
+
+	blockNumber, err := r.handler.taggedBlockToNumber(ctx, blockID)
+	if err != nil {
+		return nil, err
+	}
+	res := &Any{}
+	err = r.handler.call(ctx, r.handler.defaultResolver, res, "eth_createAccessList", args, blockNumber)
+
+	return res, err
+}
 
 // Call implements the "eth_call" call.
 //
@@ -312,8 +454,8 @@ func (r *rpcETHAPI) GetStorageAt(data types.Address, pos types.Number, blockID t
 // If the block number is set to "latest" or "pending", it will be replaced by
 // the block number returned by the BlockNumber method. The "earliest" tag is
 // not supported.
-func (r *rpcETHAPI) Call(args Any, blockID types.BlockNumber, overrides *Any) (any, error) {
-	ctx, ctxCancel := context.WithTimeout(context.Background(), r.handler.totalTimeout)
+func (r *rpcETHAPI) Call(ctx context.Context, args Any, blockID types.BlockNumber, overrides *Any) (any, error) {
+	ctx, ctxCancel := r.handler.callContext(ctx)
 	defer ctxCancel()
 
 	blockNumber, err := r.handler.taggedBlockToNumber(ctx, blockID)
@@ -334,8 +476,8 @@ func (r *rpcETHAPI) Call(args Any, blockID types.BlockNumber, overrides *Any) (a
 // If the block number is set to "latest" or "pending", it will be replaced by
 // the block number returned by the BlockNumber method. The "earliest" tag is
 // not supported.
-func (r *rpcETHAPI) GetLogs(logFilter types.FilterLogsQuery) (any, error) {
-	ctx, ctxCancel := context.WithTimeout(context.Background(), r.handler.totalTimeout)
+func (r *rpcETHAPI) GetLogs(ctx context.Context, logFilter types.FilterLogsQuery) (any, error) {
+	ctx, ctxCancel := r.handler.callContext(ctx)
 	defer ctxCancel()
 
 	if logFilter.FromBlock != nil {
@@ -364,8 +506,8 @@ func (r *rpcETHAPI) GetLogs(logFilter types.FilterLogsQuery) (any, error) {
 //
 // The number returned by this method is the median of all numbers returned
 // by the endpoints.
-func (r *rpcETHAPI) GasPrice() (any, error) {
-	ctx, ctxCancel := context.WithTimeout(context.Background(), r.handler.totalTimeout)
+func (r *rpcETHAPI) GasPrice(ctx context.Context) (any, error) {
+	ctx, ctxCancel := r.handler.callContext(ctx)
 	defer ctxCancel()
 
 	res := &types.Number{}
@@ -382,8 +524,8 @@ func (r *rpcETHAPI) GasPrice() (any, error) {
 // If the block number is set to "latest" or "pending", it will be replaced by
 // the block number returned by the BlockNumber method. The "earliest" tag is
 // not supported.
-func (r *rpcETHAPI) EstimateGas(args Any, blockID types.BlockNumber) (any, error) {
-	ctx, ctxCancel := context.WithTimeout(context.Background(), r.handler.totalTimeout)
+func (r *rpcETHAPI) EstimateGas(ctx context.Context, args Any, blockID types.BlockNumber) (any, error) {
+	ctx, ctxCancel := r.handler.callContext(ctx)
 	defer ctxCancel()
 
 	blockNumber, err := r.handler.taggedBlockToNumber(ctx, blockID)
@@ -400,8 +542,8 @@ func (r *rpcETHAPI) EstimateGas(args Any, blockID types.BlockNumber) (any, error
 //
 // It returns the most common response that occurred at least as many times as
 // specified in the minRes method.
-func (r *rpcETHAPI) FeeHistory(count types.Number, newestBlockID types.BlockNumber, percentiles Any) (any, error) {
-	ctx, ctxCancel := context.WithTimeout(context.Background(), r.handler.totalTimeout)
+func (r *rpcETHAPI) FeeHistory(ctx context.Context, count types.Number, newestBlockID types.BlockNumber, percentiles Any) (any, error) {
+	ctx, ctxCancel := r.handler.callContext(ctx)
 	defer ctxCancel()
 
 	blockNumber, err := r.handler.taggedBlockToNumber(ctx, newestBlockID)
@@ -418,8 +560,8 @@ func (r *rpcETHAPI) FeeHistory(count types.Number, newestBlockID types.BlockNumb
 //
 // The number returned by this method is the median of all numbers returned
 // by the endpoints.
-func (r *rpcETHAPI) MaxPriorityFeePerGas() (any, error) {
-	ctx, ctxCancel := context.WithTimeout(context.Background(), r.handler.totalTimeout)
+func (r *rpcETHAPI) MaxPriorityFeePerGas(ctx context.Context) (any, error) {
+	ctx, ctxCancel := r.handler.callContext(ctx)
 	defer ctxCancel()
 
 	res := &types.Number{}
@@ -432,8 +574,8 @@ func (r *rpcETHAPI) MaxPriorityFeePerGas() (any, error) {
 //
 // It returns the most common response that occurred at least as many times as
 // specified in the minRes method.
-func (r *rpcETHAPI) ChainId() (any, error) { //nolint:revive,stylecheck
-	ctx, ctxCancel := context.WithTimeout(context.Background(), r.handler.totalTimeout)
+func (r *rpcETHAPI) ChainId(ctx context.Context) (any, error) { //nolint:revive,stylecheck
+	ctx, ctxCancel := r.handler.callContext(ctx)
 	defer ctxCancel()
 
 	res := &types.Number{}
@@ -457,8 +599,8 @@ func (r *rpcETHAPI) ChainId() (any, error) { //nolint:revive,stylecheck
 //
 // It returns the most common response that occurred at least as many times as
 // specified in the minRes method.
-func (r *rpcNETAPI) Version() (any, error) {
-	ctx, ctxCancel := context.WithTimeout(context.Background(), r.handler.totalTimeout)
+func (r *rpcNETAPI) Version(ctx context.Context) (any, error) {
+	ctx, ctxCancel := r.handler.callContext(ctx)
 	defer ctxCancel()
 
 	res := &Any{}
@@ -467,6 +609,28 @@ func (r *rpcNETAPI) Version() (any, error) {
 	return res, err
 }
 
+// TraceCall implements the "debug_traceCall" call.
+//
+// It returns the most common response that occurred at least as many times as
+// specified in the minRes method.
+//
+// If the block number is set to "latest" or "pending", it will be replaced by
+// the block number returned by the BlockNumber method. The "earliest" tag is
+// not supported.
+func (r *rpcDEBUGAPI) TraceCall(ctx context.Context, args Any, blockID types.BlockNumber, config *Any) (any, error) {
+	ctx, ctxCancel := r.handler.callContext(ctx)
+	defer ctxCancel()
+
+	blockNumber, err := r.handler.taggedBlockToNumber(ctx, blockID)
+	if err != nil {
+		return nil, err
+	}
+	res := &Any{}
+	err = r.handler.call(ctx, r.handler.defaultResolver, res, "debug_traceCall", args, blockNumber, config)
+
+	return res, err
+}
+
 // taggedBlockToNumber returns a block number for tagged blocks. This is
 // necessary because different RPC endpoints may convert tags to different
 // block numbers.
@@ -482,22 +646,107 @@ func (s *server) taggedBlockToNumber(ctx context.Context, blockID types.BlockNum
 		// endpoints. It is impossible to reliably support it.
 		return types.BlockNumber{}, errors.New("earliest tag is not supported")
 	}
-	// The latest and pending blocks are handled in the same way.
+	// Calls in a batch must use the same block number.
+	if b, ok := ctx.Value(batchKey{}).(*batchState); ok {
+		b.once.Do(func() {
+			b.blockNumber, b.err = s.latestBlockNumber(ctx)
+		})
+		return b.blockNumber, b.err
+	}
+	return s.latestBlockNumber(ctx)
+}
+
+// latestBlockNumber returns the latest block number. The latest and pending
+// blocks are handled in the same way.
+func (s *server) latestBlockNumber(ctx context.Context) (types.BlockNumber, error) {
 	res := &types.Number{}
 	err := s.call(ctx, s.blockNumberResolver, res, "eth_blockNumber")
 	if err != nil {
 		return types.BlockNumber{}, err
 	}
+	if s.cache != nil {
+		s.cache.setHead(res.Big().Uint64())
+	}
 	return types.BlockNumber(*res), nil
 }
 
-// call executes RPC on all endpoints with the given arguments. If the context is
-// canceled before the call has successfully returned, call returns immediately.
+// cacheHead returns the latest block number used to decide whether results
+// can be cached. If the known block number is outdated, it is fetched from
+// the endpoints.
+func (s *server) cacheHead(ctx context.Context) (uint64, bool) {
+	if n, ok := s.cache.latestHead(); ok {
+		return n, true
+	}
+	n, err := s.latestBlockNumber(ctx)
+	if err != nil {
+		return 0, false
+	}
+	return n.Big().Uint64(), true
+}
+
+// call executes RPC on all endpoints with the given arguments, unless the
+// result is already cached. Identical calls that are executed concurrently
+// are coalesced into a single call.
+//
+// The result must be a pointer with a proper type.
+func (s *server) call(
+	ctx context.Context,
+	resolver resolver,
+	result any,
+	method string,
+	args ...any,
+) error {
+
+	if reflect.TypeOf(result).Kind() != reflect.Ptr {
+		return fmt.Errorf("call result parameter must be pointer")
+	}
+	key, err := callKey(method, args)
+	if err != nil {
+		return err
+	}
+	cacheable := s.cache != nil && isCacheable(method, args)
+	if cacheable {
+		hit := s.cache.get(key, result)
+		s.log.
+			WithField("method", method).
+			WithField("hit", hit).
+			WithField("hits", s.cache.hits.Load()).
+			WithField("misses", s.cache.misses.Load()).
+			Debug("Cache lookup")
+		if hit {
+			return nil
+		}
+	}
+	res, err, shared := s.group.Do(key, func() (any, error) {
+		res := reflect.New(reflect.TypeOf(result).Elem()).Interface()
+		if err := s.callEndpoints(ctx, resolver, res, method, args...); err != nil {
+			return nil, err
+		}
+		if cacheable {
+			s.cache.add(key, method, args, res, func() (uint64, bool) {
+				return s.cacheHead(ctx)
+			})
+		}
+		return res, nil
+	})
+	if shared {
+		s.log.WithField("method", method).Debug("Call coalesced")
+	}
+	if err != nil {
+		return err
+	}
+	reflect.ValueOf(result).Elem().Set(reflect.ValueOf(res).Elem())
+	return nil
+}
+
+// callEndpoints executes RPC on all endpoints with the given arguments. If
+// the context is canceled before the call has successfully returned,
+// callEndpoints returns immediately.
 //
 // The result must be a pointer with a proper type.
 //
 //nolint:funlen
-func (s *server) call(
+func (s *server) callEndpoints(
 	ctx context.Context,
 	resolver resolver,
 	result any,
@@ -520,11 +769,15 @@ func (s *server) call(
 		}
 	}()
 
-	// Send request to all endpoints.
-	ch := make(chan any, len(s.callers))
+	// Send request to all available endpoints and probe the ejected ones.
+	names, probes := s.health.available(s.defaultResolver.minResponses)
+	for _, n := range probes {
+		go s.probe(n)
+	}
+	ch := make(chan any, len(names))
 	rt := reflect.TypeOf(result).Elem()
-	for n, c := range s.callers {
-		n, c := n, c
+	for _, n := range names {
+		n, c := n, s.callers[n]
 		go func() {
 			t := time.Now()
 			var res any
@@ -533,6 +786,7 @@ func (s *server) call(
 				if r := recover(); r != nil {
 					err = fmt.Errorf("panic: %s", r)
 				}
+				s.health.record(n, method, time.Since(t), res, err)
 				switch {
 				case err != nil:
 					s.log.
@@ -572,7 +826,7 @@ func (s *server) call(
 		case <-t.C:
 			wait = false
 		}
-		if len(rs) == len(s.callers) {
+		if len(rs) == len(names) {
 			wait = false
 		}
 		if !wait {
@@ -581,13 +835,30 @@ func (s *server) call(
 			case err == nil:
 				reflect.ValueOf(result).Elem().Set(reflect.ValueOf(res).Elem())
 				return nil
-			case len(rs) >= len(s.callers):
+			case len(rs) >= len(names):
 				return err
 			}
 		}
 	}
 }
 
+// probe requests the latest block number from an ejected endpoint to check
+// whether it has recovered.
+func (s *server) probe(name string) {
+	ctx, ctxCancel := context.WithTimeout(context.Background(), s.gracefulTimeout)
+	defer ctxCancel()
+	t := time.Now()
+	res := new(types.Number)
+	err := s.callers[name].CallContext(ctx, res, "eth_blockNumber")
+	s.health.recordProbe(name, time.Since(t), res, err)
+}
+
+// isBatch reports whether the request body contains a JSON-RPC batch.
+func isBatch(body []byte) bool {
+	body = bytes.TrimLeft(body, " \t\r\n")
+	return len(body) > 0 && body[0] == '['
+}
+
 // removeTrailingNilArgs removes trailing nil parameters from the params
 // slice. Some RPC servers do not like null parameters and will return a
 // "bad request" error if they occur.
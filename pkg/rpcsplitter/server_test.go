@@ -16,11 +16,17 @@
 package rpcsplitter
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
 	"github.com/orcfax/oracle-suite/pkg/rpcsplitter/types"
 )
 
@@ -838,14 +844,155 @@ func Test_RPC_Accounts(t *testing.T) {
 }
 
 func Test_RPC_GetProof(t *testing.T) {
+	address := types.HexToAddress("0xb59f67a8bff5d8cd03f6ac17265c550ed8f33907")
+	keys := []types.Hash{types.HexToHash("0x01")}
+	proof1 := newAny(`{"address":"0xb59f67a8bff5d8cd03f6ac17265c550ed8f33907","balance":"0x1","storageProof":[]}`)
+	proof2 := newAny(`{"address":"0xb59f67a8bff5d8cd03f6ac17265c550ed8f33907","balance":"0x2","storageProof":[]}`)
+	blockNumber := types.StringToBlockNumber("0x10")
 	t.Run("simple", func(t *testing.T) {
-		prepareHandlerTest(t, 3, "eth_getProof").
-			setOptions(WithRequirements(1, 10)).
-			expectedError("the method eth_getProof does not exist").
+		prepareHandlerTest(t, 3, "eth_getProof", address, keys, blockNumber).
+			setOptions(WithRequirements(2, 10)).
+			mockClientCall(0, proof1, "eth_getProof", address, keys, blockNumber).
+			mockClientCall(1, proof1, "eth_getProof", address, keys, blockNumber).
+			mockClientCall(2, proof2, "eth_getProof", address, keys, blockNumber).
+			expectedResult(proof1).
+			test()
+	})
+	t.Run("different-responses", func(t *testing.T) {
+		prepareHandlerTest(t, 2, "eth_getProof", address, keys, blockNumber).
+			setOptions(WithRequirements(2, 10)).
+			mockClientCall(0, proof1, "eth_getProof", address, keys, blockNumber).
+			mockClientCall(1, proof2, "eth_getProof", address, keys, blockNumber).
+			expectedError("").
+			test()
+	})
+	t.Run("latest-block", func(t *testing.T) {
+		prepareHandlerTest(t, 2, "eth_getProof", address, keys, types.StringToBlockNumber("latest")).
+			setOptions(WithRequirements(2, 10)).
+			mockClientCall(0, blockNumber, "eth_blockNumber").
+			mockClientCall(1, blockNumber, "eth_blockNumber").
+			mockClientCall(0, proof1, "eth_getProof", address, keys, blockNumber).
+			mockClientCall(1, proof1, "eth_getProof", address, keys, blockNumber).
+			expectedResult(proof1).
+			test()
+	})
+}
+
+func Test_RPC_GetBlockReceipts(t *testing.T) {
+	blockNumber := types.StringToBlockNumber("0x10")
+	receipts := json.RawMessage(`[` + string(transactionReceipt1Resp) + `]`)
+	t.Run("simple", func(t *testing.T) {
+		prepareHandlerTest(t, 3, "eth_getBlockReceipts", blockNumber).
+			setOptions(WithRequirements(2, 10)).
+			mockClientCall(0, receipts, "eth_getBlockReceipts", blockNumber).
+			mockClientCall(1, receipts, "eth_getBlockReceipts", blockNumber).
+			mockClientCall(2, errors.New("error#1"), "eth_getBlockReceipts", blockNumber).
+			expectedResult(receipts).
+			test()
+	})
+	t.Run("latest-block", func(t *testing.T) {
+		prepareHandlerTest(t, 2, "eth_getBlockReceipts", types.StringToBlockNumber("latest")).
+			setOptions(WithRequirements(2, 10)).
+			mockClientCall(0, blockNumber, "eth_blockNumber").
+			mockClientCall(1, blockNumber, "eth_blockNumber").
+			mockClientCall(0, receipts, "eth_getBlockReceipts", blockNumber).
+			mockClientCall(1, receipts, "eth_getBlockReceipts", blockNumber).
+			expectedResult(receipts).
+			test()
+	})
+}
+
+func Test_RPC_CreateAccessList(t *testing.T) {
+	call := newAny(`{"from":"0xb60e8dd61c5d32be8058bb8eb970870f07233155","to":"0xd46e8dd67c5d32be8058bb8eb970870f07244567"}`)
+	accessList := newAny(`{"accessList":[],"gasUsed":"0x5208"}`)
+	blockNumber := types.StringToBlockNumber("0x10")
+	t.Run("simple", func(t *testing.T) {
+		prepareHandlerTest(t, 3, "eth_createAccessList", call, blockNumber).
+			setOptions(WithRequirements(2, 10)).
+			mockClientCall(0, accessList, "eth_createAccessList", call, blockNumber).
+			mockClientCall(1, accessList, "eth_createAccessList", call, blockNumber).
+			mockClientCall(2, accessList, "eth_createAccessList", call, blockNumber).
+			expectedResult(accessList).
+			test()
+	})
+	t.Run("two-failed", func(t *testing.T) {
+		prepareHandlerTest(t, 3, "eth_createAccessList", call, blockNumber).
+			setOptions(WithRequirements(2, 10)).
+			mockClientCall(0, accessList, "eth_createAccessList", call, blockNumber).
+			mockClientCall(1, errors.New("error#1"), "eth_createAccessList", call, blockNumber).
+			mockClientCall(2, errors.New("error#2"), "eth_createAccessList", call, blockNumber).
+			expectedError("error#1").
+			expectedError("error#2").
 			test()
 	})
 }
 
+func Test_RPC_TraceCall(t *testing.T) {
+	call := newAny(`{"from":"0xb60e8dd61c5d32be8058bb8eb970870f07233155","to":"0xd46e8dd67c5d32be8058bb8eb970870f07244567"}`)
+	config := newAny(`{"tracer":"callTracer"}`)
+	trace := newAny(`{"type":"CALL","gasUsed":"0x0","output":"0x"}`)
+	blockNumber := types.StringToBlockNumber("0x10")
+	t.Run("simple", func(t *testing.T) {
+		prepareHandlerTest(t, 2, "debug_traceCall", call, blockNumber, config).
+			setOptions(WithRequirements(2, 10)).
+			mockClientCall(0, trace, "debug_traceCall", call, blockNumber, config).
+			mockClientCall(1, trace, "debug_traceCall", call, blockNumber, config).
+			expectedResult(trace).
+			test()
+	})
+	t.Run("without-config", func(t *testing.T) {
+		prepareHandlerTest(t, 2, "debug_traceCall", call, blockNumber).
+			setOptions(WithRequirements(2, 10)).
+			mockClientCall(0, trace, "debug_traceCall", call, blockNumber).
+			mockClientCall(1, trace, "debug_traceCall", call, blockNumber).
+			expectedResult(trace).
+			test()
+	})
+}
+
+func Test_RPC_Batch(t *testing.T) {
+	address := types.HexToAddress("0xb59f67a8bff5d8cd03f6ac17265c550ed8f33907")
+	balance := types.HexToNumber("0x100000000000")
+	code := types.HexToBytes("0x6060")
+	blockNumber := types.StringToBlockNumber("0x10")
+
+	// The latest block must be resolved only once for the whole batch.
+	ht := prepareHandlerTest(t, 2, "").
+		setOptions(WithRequirements(2, 10)).
+		mockClientCall(0, blockNumber, "eth_blockNumber").
+		mockClientCall(1, blockNumber, "eth_blockNumber").
+		mockClientCall(0, balance, "eth_getBalance", address, blockNumber).
+		mockClientCall(1, balance, "eth_getBalance", address, blockNumber).
+		mockClientCall(0, code, "eth_getCode", address, blockNumber).
+		mockClientCall(1, code, "eth_getCode", address, blockNumber)
+
+	msg := jsonMarshal(t, []rpcReq{
+		{ID: 1, JSONRPC: "2.0", Method: "eth_getBalance", Params: []any{address, "latest"}},
+		{ID: 2, JSONRPC: "2.0", Method: "eth_getCode", Params: []any{address, "latest"}},
+	})
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(msg))
+	r.Header.Set("Content-Type", "application/json")
+	rw := httptest.NewRecorder()
+	ht.server().ServeHTTP(rw, r)
+
+	var res []rpcRes
+	jsonUnmarshal(t, rw.Body.Bytes(), &res)
+	require.Len(t, res, 2)
+	assert.Equal(t, 1, res[0].ID)
+	assert.JSONEq(t, string(jsonMarshal(t, balance)), string(jsonMarshal(t, res[0].Result)))
+	assert.Equal(t, 2, res[1].ID)
+	assert.JSONEq(t, string(jsonMarshal(t, code)), string(jsonMarshal(t, res[1].Result)))
+}
+
+func Test_RPC_RequestTooLarge(t *testing.T) {
+	ht := prepareHandlerTest(t, 1, "").setOptions(WithRequirements(1, 10))
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(make([]byte, maxRequestSize+1)))
+	r.Header.Set("Content-Type", "application/json")
+	rw := httptest.NewRecorder()
+	ht.server().ServeHTTP(rw, r)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rw.Code)
+}
+
 func Test_RPC_Call(t *testing.T) {
 	call := newAny(`
 		{
@@ -0,0 +1,241 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package rpcsplitter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	gethRPC "github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/orcfax/oracle-suite/pkg/rpcsplitter/types"
+)
+
+// maxTrackedNotifications is the maximum number of notifications for which
+// the agreement between endpoints is tracked.
+const maxTrackedNotifications = 1024
+
+// subscriber is implemented by endpoints that support subscriptions, such
+// as endpoints connected over websocket.
+type subscriber interface {
+	Subscribe(ctx context.Context, namespace string, channel any, args ...any) (*gethRPC.ClientSubscription, error)
+}
+
+// notification is a message received from an endpoint subscription.
+type notification struct {
+	endpoint string
+	msg      json.RawMessage
+}
+
+// notificationKey returns a key that identifies a notification. The same
+// notifications received from different endpoints must have the same key.
+type notificationKey func(msg json.RawMessage) (string, error)
+
+// NewHeads implements the "newHeads" subscription.
+//
+// A header is sent to the client only after the same header is received
+// from at least as many endpoints as specified in the minRes method.
+func (r *rpcETHAPI) NewHeads(ctx context.Context) (*gethRPC.Subscription, error) {
+	return r.handler.subscribe(ctx, headerKey, "newHeads")
+}
+
+// Logs implements the "logs" subscription.
+//
+// A log is sent to the client only after the same log is received from at
+// least as many endpoints as specified in the minRes method.
+func (r *rpcETHAPI) Logs(ctx context.Context, logFilter types.FilterLogsQuery) (*gethRPC.Subscription, error) {
+	return r.handler.subscribe(ctx, logKey, "logs", logFilter)
+}
+
+// subscribe subscribes to all endpoints that support subscriptions and
+// creates a subscription for the client. Notifications are forwarded to
+// the client once enough endpoints agree on them.
+func (s *server) subscribe(ctx context.Context, key notificationKey, args ...any) (*gethRPC.Subscription, error) {
+	notifier, ok := gethRPC.NotifierFromContext(ctx)
+	if !ok {
+		return nil, gethRPC.ErrNotificationsUnsupported
+	}
+	subCtx, subCtxCancel := s.callContext(ctx)
+	defer subCtxCancel()
+
+	var (
+		subs    []*gethRPC.ClientSubscription
+		errs    []error
+		notifCh = make(chan notification)
+		doneCh  = make(chan struct{})
+		wg      sync.WaitGroup
+	)
+	for n, c := range s.callers {
+		sc, ok := c.(subscriber)
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: subscriptions are not supported", n))
+			continue
+		}
+		msgCh := make(chan json.RawMessage)
+		sub, err := sc.Subscribe(subCtx, "eth", msgCh, args...)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		subs = append(subs, sub)
+		wg.Add(1)
+		go s.forwardNotifications(n, sub, msgCh, notifCh, doneCh, &wg)
+	}
+	if len(subs) < s.defaultResolver.minResponses {
+		close(doneCh)
+		for _, sub := range subs {
+			sub.Unsubscribe()
+		}
+		wg.Wait()
+		if len(errs) == 0 {
+			return nil, errNotEnoughResponses
+		}
+		return nil, addError(errNotEnoughResponses, errs...)
+	}
+	rpcSub := notifier.CreateSubscription()
+	go func() {
+		defer func() {
+			close(doneCh)
+			for _, sub := range subs {
+				sub.Unsubscribe()
+			}
+			wg.Wait()
+		}()
+		agreement := newAgreement(s.defaultResolver.minResponses)
+		for {
+			select {
+			case <-rpcSub.Err():
+				return
+			case n := <-notifCh:
+				k, err := key(n.msg)
+				if err != nil {
+					s.log.
+						WithField("name", n.endpoint).
+						WithError(err).
+						Warn("Invalid notification")
+					continue
+				}
+				if agreement.add(k, n.endpoint) {
+					if err := notifier.Notify(rpcSub.ID, n.msg); err != nil {
+						s.log.WithError(err).Warn("Unable to send notification")
+					}
+				}
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
+// forwardNotifications forwards notifications from the endpoint
+// subscription to the notifCh channel until the doneCh channel is closed
+// or the subscription fails.
+func (s *server) forwardNotifications(
+	endpoint string,
+	sub *gethRPC.ClientSubscription,
+	msgCh chan json.RawMessage,
+	notifCh chan notification,
+	doneCh chan struct{},
+	wg *sync.WaitGroup,
+) {
+	defer wg.Done()
+	for {
+		select {
+		case <-doneCh:
+			return
+		case err := <-sub.Err():
+			if err != nil {
+				s.log.
+					WithField("name", endpoint).
+					WithError(err).
+					Warn("Subscription failed")
+			}
+			return
+		case msg := <-msgCh:
+			select {
+			case notifCh <- notification{endpoint: endpoint, msg: msg}:
+			case <-doneCh:
+				return
+			}
+		}
+	}
+}
+
+// agreement tracks which endpoints sent a notification. It reports a
+// notification only once, when it is received from the required number of
+// endpoints.
+type agreement struct {
+	minResponses int
+	seen         map[string]map[string]struct{}
+	queue        []string
+}
+
+func newAgreement(minResponses int) *agreement {
+	return &agreement{
+		minResponses: minResponses,
+		seen:         make(map[string]map[string]struct{}),
+	}
+}
+
+// add records that the notification with the given key was received from
+// the endpoint. It returns true if the required number of endpoints was
+// reached with this call.
+func (a *agreement) add(key, endpoint string) bool {
+	endpoints, ok := a.seen[key]
+	if !ok {
+		endpoints = make(map[string]struct{})
+		a.seen[key] = endpoints
+		a.queue = append(a.queue, key)
+		if len(a.queue) > maxTrackedNotifications {
+			delete(a.seen, a.queue[0])
+			a.queue = a.queue[1:]
+		}
+	}
+	if _, ok := endpoints[endpoint]; ok {
+		return false
+	}
+	endpoints[endpoint] = struct{}{}
+	return len(endpoints) == a.minResponses
+}
+
+// headerKey returns a key for a block header notification.
+func headerKey(msg json.RawMessage) (string, error) {
+	var h struct {
+		Hash types.Hash `json:"hash"`
+	}
+	if err := json.Unmarshal(msg, &h); err != nil {
+		return "", err
+	}
+	if h.Hash == (types.Hash{}) {
+		return "", errors.New("header hash is missing")
+	}
+	return h.Hash.String(), nil
+}
+
+// logKey returns a key for a log notification. Removed logs have different
+// keys than the logs they revert.
+func logKey(msg json.RawMessage) (string, error) {
+	var l types.Log
+	if err := json.Unmarshal(msg, &l); err != nil {
+		return "", err
+	}
+	if l.BlockHash == (types.Hash{}) {
+		return "", errors.New("log block hash is missing")
+	}
+	return fmt.Sprintf("%s:%s:%s:%t", l.BlockHash.String(), l.TxHash.String(), l.LogIndex.String(), l.Removed), nil
+}
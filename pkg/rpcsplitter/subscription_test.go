@@ -0,0 +1,165 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package rpcsplitter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	gethRPC "github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// headsService is an upstream service that sends headers received on the
+// heads channel to subscribers.
+type headsService struct {
+	heads      chan json.RawMessage
+	subscribed chan struct{}
+}
+
+func (h *headsService) NewHeads(ctx context.Context) (*gethRPC.Subscription, error) {
+	notifier, _ := gethRPC.NotifierFromContext(ctx)
+	sub := notifier.CreateSubscription()
+	go func() {
+		for {
+			select {
+			case <-sub.Err():
+				return
+			case head := <-h.heads:
+				_ = notifier.Notify(sub.ID, head)
+			}
+		}
+	}()
+	h.subscribed <- struct{}{}
+	return sub, nil
+}
+
+func newUpstream(t *testing.T) (*headsService, *gethRPC.Client) {
+	svc := &headsService{heads: make(chan json.RawMessage), subscribed: make(chan struct{}, 1)}
+	srv := gethRPC.NewServer()
+	require.NoError(t, srv.RegisterName("eth", svc))
+	ts := httptest.NewServer(srv.WebsocketHandler([]string{"*"}))
+	t.Cleanup(ts.Close)
+	client, err := gethRPC.Dial("ws://" + strings.TrimPrefix(ts.URL, "http://"))
+	require.NoError(t, err)
+	t.Cleanup(client.Close)
+	return svc, client
+}
+
+func header(hash string) json.RawMessage {
+	return json.RawMessage(fmt.Sprintf(`{"hash":"%s","number":"0x1"}`, hash))
+}
+
+func Test_RPC_SubscribeNewHeads(t *testing.T) {
+	const (
+		hash1 = "0x1111111111111111111111111111111111111111111111111111111111111111"
+		hash2 = "0x2222222222222222222222222222222222222222222222222222222222222222"
+		hash3 = "0x3333333333333333333333333333333333333333333333333333333333333333"
+	)
+	callers := map[string]caller{}
+	var upstreams []*headsService
+	for i := 0; i < 3; i++ {
+		svc, client := newUpstream(t)
+		upstreams = append(upstreams, svc)
+		callers[fmt.Sprintf("%d", i)] = client
+	}
+	h, err := NewServer(withCallers(callers), WithRequirements(2, 10))
+	require.NoError(t, err)
+	ts := httptest.NewServer(h)
+	defer ts.Close()
+
+	client, err := gethRPC.Dial("ws://" + strings.TrimPrefix(ts.URL, "http://"))
+	require.NoError(t, err)
+	defer client.Close()
+
+	ctx, ctxCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer ctxCancel()
+	ch := make(chan json.RawMessage)
+	sub, err := client.EthSubscribe(ctx, ch, "newHeads")
+	require.NoError(t, err)
+	defer sub.Unsubscribe()
+	for _, u := range upstreams {
+		<-u.subscribed
+	}
+
+	receive := func() string {
+		select {
+		case msg := <-ch:
+			var h struct {
+				Hash string `json:"hash"`
+			}
+			require.NoError(t, json.Unmarshal(msg, &h))
+			return h.Hash
+		case <-ctx.Done():
+			require.Fail(t, "timeout")
+			return ""
+		}
+	}
+
+	// Header sent by only one endpoint must not be forwarded.
+	upstreams[2].heads <- header(hash2)
+
+	// Header sent by two endpoints must be forwarded once.
+	upstreams[0].heads <- header(hash1)
+	upstreams[1].heads <- header(hash1)
+	assert.Equal(t, hash1, receive())
+
+	// The third endpoint sends the same header, which must be ignored, and
+	// then another header that reaches the agreement.
+	upstreams[2].heads <- header(hash1)
+	upstreams[1].heads <- header(hash3)
+	upstreams[2].heads <- header(hash3)
+	assert.Equal(t, hash3, receive())
+}
+
+func Test_RPC_SubscribeNotSupported(t *testing.T) {
+	// Mocked clients do not support subscriptions.
+	h, err := NewServer(
+		withCallers(map[string]caller{"0": &mockClient{t: t}, "1": &mockClient{t: t}}),
+		WithRequirements(2, 10),
+	)
+	require.NoError(t, err)
+	ts := httptest.NewServer(h)
+	defer ts.Close()
+
+	client, err := gethRPC.Dial("ws://" + strings.TrimPrefix(ts.URL, "http://"))
+	require.NoError(t, err)
+	defer client.Close()
+
+	_, err = client.EthSubscribe(context.Background(), make(chan json.RawMessage), "newHeads")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "subscriptions are not supported")
+}
+
+func Test_agreement(t *testing.T) {
+	a := newAgreement(2)
+	assert.False(t, a.add("a", "e1"))
+	assert.False(t, a.add("a", "e1"))
+	assert.True(t, a.add("a", "e2"))
+	assert.False(t, a.add("a", "e3"))
+	assert.False(t, a.add("b", "e3"))
+	for i := 0; i < maxTrackedNotifications; i++ {
+		a.add(fmt.Sprintf("key%d", i), "e1")
+	}
+	assert.Len(t, a.seen, maxTrackedNotifications)
+	assert.Len(t, a.queue, maxTrackedNotifications)
+}
@@ -0,0 +1,57 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package rpcsplitter
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// VirtualHosts is an HTTP handler that routes requests to multiple
+// RPC-Splitter servers, for example one per chain.
+//
+// A server is selected by the first label of the host name, so that the
+// "ethereum" server handles requests to "ethereum" and
+// "ethereum.rpc.example.com". If the host name does not match any server,
+// the first path segment is used instead, so that the "ethereum" server
+// handles requests to "/ethereum" and "/ethereum/status" with the prefix
+// removed.
+type VirtualHosts map[string]http.Handler
+
+// ServeHTTP implements the http.Handler interface.
+func (v VirtualHosts) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	host := req.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if n := strings.IndexByte(host, '.'); n >= 0 {
+		host = host[:n]
+	}
+	if h, ok := v[host]; ok {
+		h.ServeHTTP(rw, req)
+		return
+	}
+	name, path, _ := strings.Cut(strings.TrimPrefix(req.URL.Path, "/"), "/")
+	if h, ok := v[name]; ok {
+		req = req.Clone(req.Context())
+		req.URL.Path = "/" + path
+		req.URL.RawPath = ""
+		h.ServeHTTP(rw, req)
+		return
+	}
+	http.Error(rw, "unknown virtual host", http.StatusNotFound)
+}
@@ -0,0 +1,62 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package rpcsplitter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVirtualHosts(t *testing.T) {
+	handler := func(name string) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			_, _ = rw.Write([]byte(name + " " + req.URL.Path))
+		})
+	}
+	v := VirtualHosts{
+		"ethereum": handler("ethereum"),
+		"arbitrum": handler("arbitrum"),
+	}
+	tests := []struct {
+		host string
+		path string
+		code int
+		body string
+	}{
+		{host: "ethereum", path: "/", code: http.StatusOK, body: "ethereum /"},
+		{host: "arbitrum:8545", path: "/status", code: http.StatusOK, body: "arbitrum /status"},
+		{host: "ethereum.rpc.example.com", path: "/", code: http.StatusOK, body: "ethereum /"},
+		{host: "localhost:8545", path: "/arbitrum", code: http.StatusOK, body: "arbitrum /"},
+		{host: "localhost:8545", path: "/ethereum/status", code: http.StatusOK, body: "ethereum /status"},
+		{host: "localhost:8545", path: "/", code: http.StatusNotFound},
+		{host: "optimism", path: "/optimism", code: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host+tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, nil)
+			req.Host = tt.host
+			rw := httptest.NewRecorder()
+			v.ServeHTTP(rw, req)
+			assert.Equal(t, tt.code, rw.Code)
+			if tt.body != "" {
+				assert.Equal(t, tt.body, rw.Body.String())
+			}
+		})
+	}
+}
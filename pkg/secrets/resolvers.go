@@ -0,0 +1,180 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultVaultTimeout is the timeout of requests to the Vault server used
+// if neither VaultResolver.Timeout nor VAULT_CLIENT_TIMEOUT is set.
+const defaultVaultTimeout = 10 * time.Second
+
+// FileResolver reads secrets from files. References have the form
+// "///path/to/file" or "path/to/file", so both "file:///run/secrets/key"
+// and "file:secrets/key" URIs are supported. Trailing new lines are removed
+// from the file contents.
+type FileResolver struct{}
+
+// Resolve implements the Resolver interface.
+func (r *FileResolver) Resolve(_ context.Context, ref string) (string, error) {
+	path := strings.TrimPrefix(ref, "//")
+	if path == "" {
+		return "", errors.New("empty path")
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+// EnvResolver reads secrets from environment variables. The reference is
+// the name of the variable, e.g. "env:API_KEY". Unlike the env function,
+// it returns an error if the variable is not set.
+type EnvResolver struct{}
+
+// Resolve implements the Resolver interface.
+func (r *EnvResolver) Resolve(_ context.Context, ref string) (string, error) {
+	value, ok := os.LookupEnv(ref)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", ref)
+	}
+	return value, nil
+}
+
+// VaultResolver reads secrets from the HashiCorp Vault KV secrets engine,
+// version 2, or from any server compatible with its HTTP API. References
+// have the form "<mount>/<path>#<key>", e.g. "vault:secret/oracle#api_key"
+// reads the "api_key" key of the "oracle" secret in the "secret" mount.
+type VaultResolver struct {
+	// Address is the address of the Vault server. If empty, the VAULT_ADDR
+	// environment variable is used.
+	Address string
+
+	// Token is the token used to authenticate requests. If empty,
+	// the VAULT_TOKEN environment variable is used.
+	Token string
+
+	// HTTPClient is the HTTP client used to send requests. If nil,
+	// http.DefaultClient is used.
+	HTTPClient *http.Client
+
+	// Timeout is the timeout of a single request. If zero,
+	// the VAULT_CLIENT_TIMEOUT environment variable is used, either as
+	// a duration, e.g. "30s", or as a number of seconds. If it is not set,
+	// the timeout is 10 seconds.
+	Timeout time.Duration
+}
+
+// Resolve implements the Resolver interface.
+func (r *VaultResolver) Resolve(ctx context.Context, ref string) (string, error) {
+	path, key, ok := strings.Cut(strings.TrimPrefix(ref, "//"), "#")
+	if !ok || key == "" {
+		return "", errors.New("missing key, the reference must have the form <mount>/<path>#<key>")
+	}
+	mount, path, ok := strings.Cut(path, "/")
+	if !ok || mount == "" || path == "" {
+		return "", errors.New("missing path, the reference must have the form <mount>/<path>#<key>")
+	}
+	addr := r.Address
+	if addr == "" {
+		addr = os.Getenv("VAULT_ADDR")
+	}
+	if addr == "" {
+		return "", errors.New("vault address is not set")
+	}
+	token := r.Token
+	if token == "" {
+		token = os.Getenv("VAULT_TOKEN")
+	}
+	timeout, err := r.timeout()
+	if err != nil {
+		return "", err
+	}
+	ctx, ctxCancel := context.WithTimeout(ctx, timeout)
+	defer ctxCancel()
+	client := r.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	u, err := url.JoinPath(addr, "v1", mount, "data", path)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return "", err
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	var body struct {
+		Data struct {
+			Data map[string]any `json:"data"`
+		} `json:"data"`
+		Errors []string `json:"errors"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil && res.StatusCode == http.StatusOK {
+		return "", fmt.Errorf("invalid response: %w", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		if len(body.Errors) > 0 {
+			return "", fmt.Errorf("vault responded with status %d: %s", res.StatusCode, strings.Join(body.Errors, ", "))
+		}
+		return "", fmt.Errorf("vault responded with status %d", res.StatusCode)
+	}
+	value, ok := body.Data.Data[key]
+	if !ok {
+		return "", fmt.Errorf("key %s does not exist", key)
+	}
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("key %s is not a string", key)
+	}
+	return s, nil
+}
+
+func (r *VaultResolver) timeout() (time.Duration, error) {
+	if r.Timeout > 0 {
+		return r.Timeout, nil
+	}
+	env := os.Getenv("VAULT_CLIENT_TIMEOUT")
+	if env == "" {
+		return defaultVaultTimeout, nil
+	}
+	if sec, err := strconv.Atoi(env); err == nil && sec > 0 {
+		return time.Duration(sec) * time.Second, nil
+	}
+	if d, err := time.ParseDuration(env); err == nil && d > 0 {
+		return d, nil
+	}
+	return 0, fmt.Errorf("invalid VAULT_CLIENT_TIMEOUT value %q", env)
+}
@@ -0,0 +1,148 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package secrets resolves secrets referenced in configuration files, like
+// passphrases and API keys, from pluggable backends. Resolved values are
+// remembered, so that they can be redacted from logs and rendered
+// configuration.
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Redacted replaces secret values in redacted text.
+const Redacted = "[REDACTED]"
+
+// MinRedactLength is the minimum length of a secret value that is
+// redacted. Shorter values, like "1" or "true", would otherwise be
+// replaced everywhere in the redacted text.
+const MinRedactLength = 6
+
+// Resolver resolves secrets from a single backend.
+type Resolver interface {
+	// Resolve returns the secret value for the given reference. The
+	// reference is a URI without the scheme and the colon that follows it,
+	// e.g. "///run/secrets/key" for "file:///run/secrets/key".
+	Resolve(ctx context.Context, ref string) (string, error)
+}
+
+// ResolverFunc is an adapter to allow the use of ordinary functions as
+// resolvers.
+type ResolverFunc func(ctx context.Context, ref string) (string, error)
+
+// Resolve implements the Resolver interface.
+func (f ResolverFunc) Resolve(ctx context.Context, ref string) (string, error) {
+	return f(ctx, ref)
+}
+
+var (
+	mu        sync.RWMutex
+	resolvers = map[string]Resolver{
+		"file":  &FileResolver{},
+		"env":   &EnvResolver{},
+		"vault": &VaultResolver{},
+	}
+	values   = map[string]struct{}{}
+	replacer *strings.Replacer // Replacer for values, nil if there are no values.
+)
+
+// Register registers a resolver for the given URI scheme. It replaces the
+// resolver that was previously registered for the scheme, which allows
+// replacing the default backends, e.g. with a mock in tests.
+func Register(scheme string, r Resolver) {
+	mu.Lock()
+	defer mu.Unlock()
+	resolvers[scheme] = r
+}
+
+// Resolve returns the secret value referenced by the given URI, e.g.
+// "file:///run/secrets/key", "env:API_KEY" or "vault:secret/oracle#key".
+// The scheme selects the resolver. The returned value is remembered and
+// redacted by the Redact function, unless it is shorter than
+// MinRedactLength.
+func Resolve(ctx context.Context, uri string) (string, error) {
+	scheme, ref, ok := strings.Cut(uri, ":")
+	if !ok {
+		return "", fmt.Errorf("secrets: invalid secret reference %q: missing scheme", uri)
+	}
+	mu.RLock()
+	r, ok := resolvers[scheme]
+	mu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("secrets: unsupported scheme %q", scheme)
+	}
+	value, err := r.Resolve(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("secrets: unable to resolve %q: %w", uri, err)
+	}
+	if len(value) >= MinRedactLength {
+		mu.Lock()
+		if _, ok := values[value]; !ok {
+			values[value] = struct{}{}
+			replacer = newReplacer()
+		}
+		mu.Unlock()
+	}
+	return value, nil
+}
+
+// Redact replaces all resolved secret values in the given text with
+// the Redacted placeholder. Values are also replaced in their JSON-escaped
+// form, so that secrets are redacted from JSON logs.
+func Redact(s string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+	if replacer == nil {
+		return s
+	}
+	return replacer.Replace(s)
+}
+
+// newReplacer returns a replacer for the remembered secret values. It must
+// be called with the write lock held, every time the values change.
+func newReplacer() *strings.Replacer {
+	// Longer values are replaced first, so that a value that contains
+	// another one is redacted completely.
+	sorted := make([]string, 0, len(values))
+	for v := range values {
+		sorted = append(sorted, v)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return len(sorted[i]) > len(sorted[j])
+	})
+	var pairs []string
+	for _, v := range sorted {
+		pairs = append(pairs, v, Redacted)
+		if escaped := jsonEscape(v); escaped != v {
+			pairs = append(pairs, escaped, Redacted)
+		}
+	}
+	return strings.NewReplacer(pairs...)
+}
+
+// jsonEscape returns the string as it appears inside a JSON string.
+func jsonEscape(s string) string {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return s
+	}
+	return string(b[1 : len(b)-1])
+}
@@ -0,0 +1,161 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolve(t *testing.T) {
+	ctx := context.Background()
+
+	// Vault mock.
+	vault := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "token" {
+			rw.WriteHeader(http.StatusForbidden)
+			_, _ = rw.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		if r.URL.Path != "/v1/secret/data/oracle/feed" {
+			rw.WriteHeader(http.StatusNotFound)
+			_, _ = rw.Write([]byte(`{"errors":[]}`))
+			return
+		}
+		_ = json.NewEncoder(rw).Encode(map[string]any{
+			"data": map[string]any{
+				"data":     map[string]any{"api_key": "vault-secret", "port": 8080},
+				"metadata": map[string]any{"version": 1},
+			},
+		})
+	}))
+	defer vault.Close()
+	t.Setenv("VAULT_ADDR", vault.URL)
+	t.Setenv("VAULT_TOKEN", "token")
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "passphrase")
+	require.NoError(t, os.WriteFile(path, []byte("file-secret\n"), 0600))
+	t.Setenv("SECRET_TEST_VAR", "env-secret")
+
+	tests := []struct {
+		uri     string
+		want    string
+		wantErr string
+	}{
+		{uri: "file://" + path, want: "file-secret"},
+		{uri: "file:" + path, want: "file-secret"},
+		{uri: "file://" + filepath.Join(dir, "missing"), wantErr: "no such file"},
+		{uri: "env:SECRET_TEST_VAR", want: "env-secret"},
+		{uri: "env:SECRET_TEST_MISSING", wantErr: "is not set"},
+		{uri: "vault:secret/oracle/feed#api_key", want: "vault-secret"},
+		{uri: "vault://secret/oracle/feed#api_key", want: "vault-secret"},
+		{uri: "vault:secret/oracle/feed#missing", wantErr: "key missing does not exist"},
+		{uri: "vault:secret/oracle/feed#port", wantErr: "is not a string"},
+		{uri: "vault:secret/oracle/other#api_key", wantErr: "status 404"},
+		{uri: "vault:secret/oracle/feed", wantErr: "missing key"},
+		{uri: "unknown:foo", wantErr: "unsupported scheme"},
+		{uri: "foo", wantErr: "missing scheme"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.uri, func(t *testing.T) {
+			got, err := Resolve(ctx, tt.uri)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("vault permission denied", func(t *testing.T) {
+		t.Setenv("VAULT_TOKEN", "invalid")
+		_, err := Resolve(ctx, "vault:secret/oracle/feed#api_key")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "permission denied")
+	})
+}
+
+func TestVaultResolver_Timeout(t *testing.T) {
+	ctx := context.Background()
+
+	// Vault mock that does not respond until the test ends.
+	done := make(chan struct{})
+	vault := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		select {
+		case <-done:
+		case <-r.Context().Done():
+		}
+	}))
+	defer vault.Close()
+	defer close(done)
+	t.Setenv("VAULT_ADDR", vault.URL)
+
+	t.Run("config", func(t *testing.T) {
+		r := &VaultResolver{Timeout: 50 * time.Millisecond}
+		_, err := r.Resolve(ctx, "secret/oracle/feed#api_key")
+		require.Error(t, err)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+	t.Run("env", func(t *testing.T) {
+		t.Setenv("VAULT_CLIENT_TIMEOUT", "50ms")
+		_, err := (&VaultResolver{}).Resolve(ctx, "secret/oracle/feed#api_key")
+		require.Error(t, err)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+	t.Run("invalid env", func(t *testing.T) {
+		t.Setenv("VAULT_CLIENT_TIMEOUT", "soon")
+		_, err := (&VaultResolver{}).Resolve(ctx, "secret/oracle/feed#api_key")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid VAULT_CLIENT_TIMEOUT")
+	})
+}
+
+func TestRegister(t *testing.T) {
+	Register("test", ResolverFunc(func(_ context.Context, ref string) (string, error) {
+		return "test-" + ref, nil
+	}))
+	got, err := Resolve(context.Background(), "test:secret")
+	require.NoError(t, err)
+	assert.Equal(t, "test-secret", got)
+}
+
+func TestRedact(t *testing.T) {
+	Register("test", ResolverFunc(func(_ context.Context, ref string) (string, error) {
+		return ref, nil
+	}))
+	for _, ref := range []string{"redact-me", "redact-me-too", `quoted"secret`, "true", "1"} {
+		_, err := Resolve(context.Background(), "test:"+ref)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, "key=[REDACTED] other=[REDACTED]", Redact("key=redact-me other=redact-me-too"))
+	assert.Equal(t, `{"key":"[REDACTED]"}`, Redact(`{"key":"quoted\"secret"}`))
+	assert.Equal(t, "nothing to redact", Redact("nothing to redact"))
+
+	// Values shorter than MinRedactLength are not redacted.
+	assert.Equal(t, "enabled=true count=1", Redact("enabled=true count=1"))
+}
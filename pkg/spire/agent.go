@@ -19,6 +19,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"net/rpc"
 	"time"
@@ -97,6 +98,12 @@ func (s *Agent) Wait() <-chan error {
 	return s.srv.Wait()
 }
 
+// Addr returns the address on which the agent listens for connections.
+// It returns nil if the agent is not started.
+func (s *Agent) Addr() net.Addr {
+	return s.srv.Addr()
+}
+
 func (s *Agent) contextCancelHandler() {
 	defer s.log.Debug("Stopped")
 	<-s.ctx.Done()
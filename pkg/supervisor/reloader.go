@@ -20,6 +20,7 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/orcfax/oracle-suite/pkg/log"
 	"github.com/orcfax/oracle-suite/pkg/log/null"
@@ -28,6 +29,11 @@ import (
 
 const ReloaderLoggerTag = "RELOADER"
 
+// failedServiceStopTimeout is the maximum time to wait for a service that
+// failed to start to stop before it is rolled back. Services that fail
+// before starting any goroutines may never close their wait channel.
+const failedServiceStopTimeout = 10 * time.Second
+
 // Reloader is a service that can reload another wrapped service.
 type Reloader struct {
 	mu     sync.Mutex
@@ -42,6 +48,7 @@ type Reloader struct {
 	serviceErr    error
 	service       Service
 	factory       func(ctx context.Context, service chan Service) error
+	rollback      func() (Service, error)
 	factoryCh     chan Service
 	serviceWaitCh <-chan error
 }
@@ -56,6 +63,15 @@ type ReloaderConfig struct {
 	// factory function returns an error.
 	Factory func(ctx context.Context, service chan Service) error
 
+	// Rollback is an optional function called when a service sent by the
+	// factory, other than the first one, fails to start. It returns a service
+	// that is started instead, usually created from the previous
+	// configuration.
+	//
+	// If Rollback is nil, or the returned service also fails to start, the
+	// Reloader stops with an error.
+	Rollback func() (Service, error)
+
 	// Logger is a logger instance.
 	Logger log.Logger
 }
@@ -69,6 +85,7 @@ func NewReloader(cfg ReloaderConfig) *Reloader {
 		waitCh:        make(chan error),
 		log:           cfg.Logger.WithField("tag", ReloaderLoggerTag),
 		factory:       cfg.Factory,
+		rollback:      cfg.Rollback,
 		factoryCh:     make(chan Service),
 		serviceWaitCh: make(chan error),
 	}
@@ -105,7 +122,8 @@ func (r *Reloader) ServiceName() string {
 }
 
 func (r *Reloader) reloadService(service Service) (err error) {
-	if r.serviceCancel != nil {
+	reloaded := r.serviceCancel != nil
+	if reloaded {
 		r.log.
 			WithField("service", ServiceName(r.service)).
 			Info("Reloading service")
@@ -117,6 +135,64 @@ func (r *Reloader) reloadService(service Service) (err error) {
 		}
 	}
 
+	if err := r.startService(service); err != nil {
+		if !reloaded || r.rollback == nil {
+			return err
+		}
+		r.log.
+			WithError(err).
+			WithField("service", ServiceName(service)).
+			WithAdvice("The previous service will be restored, check the new configuration").
+			Error("Unable to start reloaded service")
+
+		// Stop services that might have been started before the failure
+		// and wait for them to release their resources, e.g. listeners.
+		r.serviceCancel()
+		r.waitForStop(service)
+		prev, rollbackErr := r.rollback()
+		if rollbackErr != nil {
+			return fmt.Errorf("service reloader: failed to roll back service: %w", errutil.Append(err, rollbackErr))
+		}
+		if err := r.startService(prev); err != nil {
+			return err
+		}
+		r.log.
+			WithField("service", ServiceName(prev)).
+			Info("Service rolled back")
+		return nil
+	}
+
+	r.log.
+		WithField("service", ServiceName(r.service)).
+		Info("Service reloaded")
+
+	return nil
+}
+
+// waitForStop waits until the service that failed to start closes its
+// wait channel, or until the failedServiceStopTimeout passes.
+func (r *Reloader) waitForStop(service Service) {
+	timer := time.NewTimer(failedServiceStopTimeout)
+	defer timer.Stop()
+	for {
+		select {
+		case _, ok := <-service.Wait():
+			if !ok {
+				return
+			}
+		case <-timer.C:
+			r.log.
+				WithField("service", ServiceName(service)).
+				Warn("Timeout while waiting for the service that failed to start to stop")
+			return
+		case <-r.ctx.Done():
+			return
+		}
+	}
+}
+
+// startService replaces the current service instance and starts it.
+func (r *Reloader) startService(service Service) error {
 	// Update the service instance.
 	r.mu.Lock()
 	r.serviceCtx, r.serviceCancel = context.WithCancel(r.ctx)
@@ -128,11 +204,6 @@ func (r *Reloader) reloadService(service Service) (err error) {
 	if err := r.service.Start(r.serviceCtx); err != nil {
 		return fmt.Errorf("service reloader: failed to start service: %w", err)
 	}
-
-	r.log.
-		WithField("service", ServiceName(r.service)).
-		Info("Service reloaded")
-
 	return nil
 }
 
@@ -109,6 +109,85 @@ func TestReloader(t *testing.T) {
 		require.Error(t, <-r.Wait())
 	})
 
+	t.Run("reloaded service failed to start", func(t *testing.T) {
+		s1 := &service{waitCh: make(chan error)}
+		s2 := &service{waitCh: make(chan error), failOnStart: true}
+		s3 := &service{waitCh: make(chan error)}
+		close(s2.waitCh)
+		r := NewReloader(ReloaderConfig{
+			Factory: func(ctx context.Context, serviceCh chan Service) error {
+				serviceCh <- s1
+				serviceCh <- s2
+				<-ctx.Done()
+				return nil
+			},
+			Rollback: func() (Service, error) {
+				return s3, nil
+			},
+		})
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		require.NoError(t, r.Start(ctx))
+		assert.Eventually(t, func() bool {
+			return !s1.Started() && s3.Started()
+		}, 100*time.Millisecond, 10*time.Millisecond)
+		select {
+		case err := <-r.Wait():
+			require.Fail(t, "reloader stopped", err)
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+
+	t.Run("rollback waits for failed service", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		// The service that failed to start stops its goroutines only after
+		// its context is canceled.
+		s1 := &service{waitCh: make(chan error)}
+		s2 := New(nil)
+		s2s := &service{waitCh: make(chan error)}
+		s2.Watch(s2s, &service{waitCh: make(chan error), failOnStart: true})
+		s3 := &service{waitCh: make(chan error)}
+		var s2Started bool
+		r := NewReloader(ReloaderConfig{
+			Factory: func(ctx context.Context, serviceCh chan Service) error {
+				serviceCh <- s1
+				serviceCh <- s2
+				<-ctx.Done()
+				return nil
+			},
+			Rollback: func() (Service, error) {
+				s2Started = s2s.Started()
+				return s3, nil
+			},
+		})
+		require.NoError(t, r.Start(ctx))
+		assert.Eventually(t, func() bool {
+			return s3.Started()
+		}, 100*time.Millisecond, 10*time.Millisecond)
+		assert.False(t, s2Started)
+	})
+
+	t.Run("rollback failed", func(t *testing.T) {
+		s1 := &service{waitCh: make(chan error)}
+		s2 := &service{waitCh: make(chan error), failOnStart: true}
+		r := NewReloader(ReloaderConfig{
+			Factory: func(ctx context.Context, serviceCh chan Service) error {
+				serviceCh <- s1
+				serviceCh <- s2
+				return nil
+			},
+			Rollback: func() (Service, error) {
+				return nil, fmt.Errorf("rollback failed")
+			},
+		})
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		require.NoError(t, r.Start(ctx))
+		require.Error(t, <-r.Wait())
+	})
+
 	t.Run("service stopped", func(t *testing.T) {
 		s := &service{waitCh: make(chan error)}
 		r := NewReloader(ReloaderConfig{
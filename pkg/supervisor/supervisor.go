@@ -44,6 +44,18 @@ type Service interface {
 	Wait() <-chan error
 }
 
+// Reloadable is an optional interface that can be implemented by a service
+// returned from Config.Services to apply a new configuration without
+// restarting all services.
+type Reloadable interface {
+	Service
+
+	// Reload applies the given configuration to the running services. The
+	// configuration must be of the same type as the one used to create
+	// the service.
+	Reload(cfg Config) error
+}
+
 // WithName is an optional interface that can be implemented by a service
 // to provide a name. The name is used in logs and metrics.
 type WithName interface {
@@ -94,13 +106,15 @@ func (s *Supervisor) Start(ctx context.Context) error {
 		return errors.New("context must not be nil")
 	}
 	s.ctx, s.ctxCancel = context.WithCancel(ctx)
-	for _, srv := range s.services {
+	for i, srv := range s.services {
 		s.log.
 			WithField("service", ServiceName(srv)).
 			Debug("Starting service")
 		if err := srv.Start(s.ctx); err != nil {
 			s.ctxCancel()
-			close(s.waitCh)
+			// The wait channel is closed after already started services
+			// stop, so callers can wait for them to release resources.
+			go s.waitForStarted(s.services[:i])
 			return err
 		}
 	}
@@ -117,6 +131,22 @@ func (s *Supervisor) Wait() <-chan error {
 	return s.waitCh
 }
 
+// waitForStarted waits for the given services to stop and closes the
+// wait channel.
+func (s *Supervisor) waitForStarted(services []Service) {
+	for _, srv := range services {
+		for err := range srv.Wait() {
+			if err != nil {
+				s.log.
+					WithError(err).
+					WithField("service", ServiceName(srv)).
+					Warn("Service stopped with an error after a failed start")
+			}
+		}
+	}
+	close(s.waitCh)
+}
+
 func (s *Supervisor) serviceMonitor() {
 	var err error
 	// In this loop, a select is created (using reflection) that waits until
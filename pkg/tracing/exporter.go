@@ -0,0 +1,98 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// FileExporter appends exported spans to a file. Every export is written as
+// a single line, following the format used by the OpenTelemetry Collector
+// file exporter.
+type FileExporter struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileExporter returns a new FileExporter that writes to the file at
+// the given path. The file is created if it does not exist.
+func NewFileExporter(path string) *FileExporter {
+	return &FileExporter{path: path}
+}
+
+// Export implements the Exporter interface.
+func (e *FileExporter) Export(_ context.Context, data []byte) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	f, err := os.OpenFile(e.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644) //nolint:gomnd
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// HTTPExporter sends exported spans to an OTLP/HTTP collector endpoint,
+// e.g. http://localhost:4318/v1/traces.
+type HTTPExporter struct {
+	endpoint string
+	headers  map[string]string
+	client   *http.Client
+}
+
+// NewHTTPExporter returns a new HTTPExporter. Headers are added to every
+// request. If client is nil, http.DefaultClient is used.
+func NewHTTPExporter(endpoint string, headers map[string]string, client *http.Client) *HTTPExporter {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &HTTPExporter{
+		endpoint: endpoint,
+		headers:  headers,
+		client:   client,
+	}
+}
+
+// Export implements the Exporter interface.
+func (e *HTTPExporter) Export(ctx context.Context, data []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+	res, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, res.Body)
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("collector responded with status %s", res.Status)
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package tracing
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// scopeName is the instrumentation scope reported in exported spans.
+const scopeName = "github.com/orcfax/oracle-suite"
+
+// OTLP status codes.
+const (
+	statusCodeUnset = 0
+	statusCodeError = 2
+)
+
+// MarshalOTLP encodes spans as an ExportTraceServiceRequest in the OTLP/JSON
+// format. Resource attributes describe the process that produced the spans.
+func MarshalOTLP(resource map[string]any, spans []*Span) ([]byte, error) {
+	req := otlpRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{Attributes: otlpAttributes(resource)},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: scopeName},
+				Spans: make([]otlpSpan, 0, len(spans)),
+			}},
+		}},
+	}
+	for _, s := range spans {
+		req.ResourceSpans[0].ScopeSpans[0].Spans = append(
+			req.ResourceSpans[0].ScopeSpans[0].Spans,
+			s.otlp(),
+		)
+	}
+	return json.Marshal(req)
+}
+
+func (s *Span) otlp() otlpSpan {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	span := otlpSpan{
+		TraceID:           s.sc.TraceID.String(),
+		SpanID:            s.sc.SpanID.String(),
+		Name:              s.name,
+		Kind:              int(s.kind),
+		StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+		Attributes:        otlpAttributes(s.attrs),
+		Status:            otlpStatus{Code: statusCodeUnset},
+	}
+	if s.parent.IsValid() {
+		span.ParentSpanID = s.parent.String()
+	}
+	for _, l := range s.links {
+		span.Links = append(span.Links, otlpLink{
+			TraceID: l.TraceID.String(),
+			SpanID:  l.SpanID.String(),
+		})
+	}
+	if s.err != nil {
+		span.Status = otlpStatus{Code: statusCodeError, Message: s.err.Error()}
+	}
+	return span
+}
+
+func otlpAttributes(attrs map[string]any) []otlpKeyValue {
+	if len(attrs) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	kvs := make([]otlpKeyValue, 0, len(attrs))
+	for _, k := range keys {
+		kvs = append(kvs, otlpKeyValue{Key: k, Value: otlpAnyValue(attrs[k])})
+	}
+	return kvs
+}
+
+func otlpAnyValue(v any) map[string]any {
+	switch v := v.(type) {
+	case string:
+		return map[string]any{"stringValue": v}
+	case bool:
+		return map[string]any{"boolValue": v}
+	case int:
+		return map[string]any{"intValue": strconv.FormatInt(int64(v), 10)}
+	case int8:
+		return map[string]any{"intValue": strconv.FormatInt(int64(v), 10)}
+	case int16:
+		return map[string]any{"intValue": strconv.FormatInt(int64(v), 10)}
+	case int32:
+		return map[string]any{"intValue": strconv.FormatInt(int64(v), 10)}
+	case int64:
+		return map[string]any{"intValue": strconv.FormatInt(v, 10)}
+	case uint:
+		return map[string]any{"intValue": strconv.FormatUint(uint64(v), 10)}
+	case uint8:
+		return map[string]any{"intValue": strconv.FormatUint(uint64(v), 10)}
+	case uint16:
+		return map[string]any{"intValue": strconv.FormatUint(uint64(v), 10)}
+	case uint32:
+		return map[string]any{"intValue": strconv.FormatUint(uint64(v), 10)}
+	case uint64:
+		return map[string]any{"intValue": strconv.FormatUint(v, 10)}
+	case float32:
+		return map[string]any{"doubleValue": float64(v)}
+	case float64:
+		return map[string]any{"doubleValue": v}
+	case fmt.Stringer:
+		return map[string]any{"stringValue": v.String()}
+	default:
+		return map[string]any{"stringValue": fmt.Sprint(v)}
+	}
+}
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Links             []otlpLink     `json:"links,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpKeyValue struct {
+	Key   string         `json:"key"`
+	Value map[string]any `json:"value"`
+}
+
+type otlpLink struct {
+	TraceID string `json:"traceId"`
+	SpanID  string `json:"spanId"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
@@ -0,0 +1,229 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/orcfax/oracle-suite/pkg/log"
+	"github.com/orcfax/oracle-suite/pkg/log/null"
+)
+
+const LoggerTag = "TRACING"
+
+const (
+	defaultInterval     = 5 * time.Second
+	defaultMaxQueueSize = 2048
+	exportTimeout       = 10 * time.Second
+)
+
+// Exporter exports spans encoded as an OTLP/JSON ExportTraceServiceRequest.
+type Exporter interface {
+	Export(ctx context.Context, data []byte) error
+}
+
+// Config is the configuration for the Tracer.
+type Config struct {
+	// Exporter is used to export finished spans.
+	Exporter Exporter
+
+	// ServiceName is the name of the service reported in the
+	// "service.name" resource attribute.
+	ServiceName string
+
+	// ServiceVersion is the version of the service reported in the
+	// "service.version" resource attribute.
+	ServiceVersion string
+
+	// Interval specifies how often finished spans are exported.
+	// If zero, spans are exported every 5 seconds.
+	Interval time.Duration
+
+	// MaxQueueSize is the maximum number of finished spans waiting to be
+	// exported. Spans above this limit are dropped. If zero, 2048 is used.
+	MaxQueueSize int
+
+	// Logger is a current logger interface used by the Tracer.
+	// If nil, null logger will be used.
+	Logger log.Logger
+}
+
+// Tracer creates spans and periodically exports finished spans in
+// batches.
+type Tracer struct {
+	mu     sync.Mutex
+	ctx    context.Context
+	waitCh chan error
+
+	exporter     Exporter
+	resource     map[string]any
+	interval     time.Duration
+	maxQueueSize int
+	queue        []*Span
+	dropped      int
+	log          log.Logger
+}
+
+// New creates a new Tracer.
+func New(cfg Config) (*Tracer, error) {
+	if cfg.Exporter == nil {
+		return nil, errors.New("exporter must not be nil")
+	}
+	if cfg.Interval == 0 {
+		cfg.Interval = defaultInterval
+	}
+	if cfg.MaxQueueSize == 0 {
+		cfg.MaxQueueSize = defaultMaxQueueSize
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = null.New()
+	}
+	resource := map[string]any{}
+	if cfg.ServiceName != "" {
+		resource["service.name"] = cfg.ServiceName
+	}
+	if cfg.ServiceVersion != "" {
+		resource["service.version"] = cfg.ServiceVersion
+	}
+	return &Tracer{
+		waitCh:       make(chan error),
+		exporter:     cfg.Exporter,
+		resource:     resource,
+		interval:     cfg.Interval,
+		maxQueueSize: cfg.MaxQueueSize,
+		log:          cfg.Logger.WithField("tag", LoggerTag),
+	}, nil
+}
+
+// Start implements the supervisor.Service interface.
+func (t *Tracer) Start(ctx context.Context) error {
+	if t.ctx != nil {
+		return errors.New("service can be started only once")
+	}
+	if ctx == nil {
+		return errors.New("context must not be nil")
+	}
+	t.log.
+		WithField("interval", t.interval).
+		Debug("Starting")
+	t.ctx = ctx
+	go t.exportRoutine()
+	return nil
+}
+
+// Wait implements the supervisor.Service interface.
+func (t *Tracer) Wait() <-chan error {
+	return t.waitCh
+}
+
+// StartSpan starts a new span. If the context contains a span or a remote
+// span context, the new span becomes its child, otherwise a new trace is
+// started.
+//
+// If the tracer is nil, the context is returned unchanged along with a nil
+// span.
+func (t *Tracer) StartSpan(ctx context.Context, name string, opts ...SpanOption) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+	s := &Span{
+		tracer: t,
+		name:   name,
+		kind:   SpanKindInternal,
+		attrs:  make(map[string]any),
+		start:  time.Now(),
+	}
+	parent := SpanContextFromContext(ctx)
+	if parent.IsValid() {
+		s.sc.TraceID = parent.TraceID
+		s.parent = parent.SpanID
+	} else {
+		randomBytes(s.sc.TraceID[:])
+	}
+	randomBytes(s.sc.SpanID[:])
+	for _, opt := range opts {
+		opt(s)
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return ContextWithSpan(ctx, s), s
+}
+
+// Flush exports all finished spans.
+func (t *Tracer) Flush(ctx context.Context) error {
+	t.mu.Lock()
+	spans, dropped := t.queue, t.dropped
+	t.queue, t.dropped = nil, 0
+	t.mu.Unlock()
+	if dropped > 0 {
+		t.log.
+			WithField("dropped", dropped).
+			WithAdvice("Decrease the export interval or increase the queue size").
+			Warn("Spans were dropped because the export queue is full")
+	}
+	if len(spans) == 0 {
+		return nil
+	}
+	data, err := MarshalOTLP(t.resource, spans)
+	if err != nil {
+		return err
+	}
+	return t.exporter.Export(ctx, data)
+}
+
+func (t *Tracer) enqueue(s *Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.queue) >= t.maxQueueSize {
+		t.dropped++
+		return
+	}
+	t.queue = append(t.queue, s)
+}
+
+func (t *Tracer) exportRoutine() {
+	defer func() { close(t.waitCh) }()
+	defer t.log.Debug("Stopped")
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-t.ctx.Done():
+			// Export the remaining spans before exiting.
+			ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+			t.export(ctx)
+			cancel()
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(t.ctx, exportTimeout)
+			t.export(ctx)
+			cancel()
+		}
+	}
+}
+
+func (t *Tracer) export(ctx context.Context) {
+	if err := t.Flush(ctx); err != nil {
+		t.log.
+			WithError(err).
+			WithAdvice("Ignore if it is related to temporary network issues").
+			Warn("Unable to export spans")
+	}
+}
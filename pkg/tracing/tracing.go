@@ -0,0 +1,330 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package tracing provides a minimal implementation of distributed tracing
+// compatible with the OpenTelemetry data model.
+//
+// Spans are started using the StartSpan function, which uses the default
+// tracer set by SetDefault. If the default tracer is not set, tracing is
+// disabled, StartSpan returns a nil span and all Span methods are no-ops.
+//
+// The trace context is propagated between processes in the metadata of
+// data points, see Inject and Extract.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Meta keys used to propagate the trace context in data point metadata.
+const (
+	MetaTraceID = "trace_id"
+	MetaSpanID  = "span_id"
+)
+
+// TraceID is a unique identifier of a trace.
+type TraceID [16]byte
+
+// String returns the hex representation of the trace ID.
+func (t TraceID) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+// IsValid returns true if the trace ID is not zero.
+func (t TraceID) IsValid() bool {
+	return t != TraceID{}
+}
+
+// SpanID is a unique identifier of a span within a trace.
+type SpanID [8]byte
+
+// String returns the hex representation of the span ID.
+func (s SpanID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+// IsValid returns true if the span ID is not zero.
+func (s SpanID) IsValid() bool {
+	return s != SpanID{}
+}
+
+// ParseTraceID parses a hex representation of a trace ID.
+func ParseTraceID(s string) (TraceID, error) {
+	var t TraceID
+	if err := parseHex(t[:], s); err != nil {
+		return TraceID{}, err
+	}
+	return t, nil
+}
+
+// ParseSpanID parses a hex representation of a span ID.
+func ParseSpanID(s string) (SpanID, error) {
+	var id SpanID
+	if err := parseHex(id[:], s); err != nil {
+		return SpanID{}, err
+	}
+	return id, nil
+}
+
+// SpanContext identifies a span.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+}
+
+// IsValid returns true if both the trace ID and the span ID are valid.
+func (s SpanContext) IsValid() bool {
+	return s.TraceID.IsValid() && s.SpanID.IsValid()
+}
+
+// SpanKind describes the relationship between the span, its parents and
+// its children.
+type SpanKind int
+
+// Span kinds as defined by OpenTelemetry.
+const (
+	SpanKindInternal SpanKind = iota + 1
+	SpanKindServer
+	SpanKindClient
+	SpanKindProducer
+	SpanKindConsumer
+)
+
+// Span represents a single operation within a trace.
+//
+// All methods are safe to call on a nil span.
+type Span struct {
+	mu     sync.Mutex
+	tracer *Tracer
+
+	name   string
+	kind   SpanKind
+	sc     SpanContext
+	parent SpanID
+	links  []SpanContext
+	attrs  map[string]any
+	start  time.Time
+	end    time.Time
+	err    error
+	ended  bool
+}
+
+// SpanContext returns the span context of the span.
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.sc
+}
+
+// SetAttribute sets an attribute of the span. Values of type string, bool,
+// integer and float are exported as is, other values are exported as
+// strings.
+func (s *Span) SetAttribute(key string, value any) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attrs[key] = value
+}
+
+// SetError marks the span as failed. Nil errors are ignored.
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+// AddLink links the span with a span from another trace. Invalid span
+// contexts are ignored.
+func (s *Span) AddLink(sc SpanContext) {
+	if s == nil || !sc.IsValid() {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.links = append(s.links, sc)
+}
+
+// End ends the span and passes it to the tracer to be exported. Calling End
+// more than once has no effect.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mu.Unlock()
+	s.tracer.enqueue(s)
+}
+
+// SpanOption configures a span started by StartSpan.
+type SpanOption func(*Span)
+
+// WithKind sets the kind of the span. The default kind is SpanKindInternal.
+func WithKind(kind SpanKind) SpanOption {
+	return func(s *Span) {
+		s.kind = kind
+	}
+}
+
+// WithLinks links the span with spans from other traces.
+func WithLinks(links ...SpanContext) SpanOption {
+	return func(s *Span) {
+		for _, l := range links {
+			if l.IsValid() {
+				s.links = append(s.links, l)
+			}
+		}
+	}
+}
+
+// WithAttributes sets attributes of the span.
+func WithAttributes(attrs map[string]any) SpanOption {
+	return func(s *Span) {
+		for k, v := range attrs {
+			s.attrs[k] = v
+		}
+	}
+}
+
+var defaultTracer atomic.Pointer[Tracer]
+
+// SetDefault sets the tracer used by StartSpan. If nil, tracing is disabled.
+func SetDefault(t *Tracer) {
+	defaultTracer.Store(t)
+}
+
+// Default returns the tracer used by StartSpan, or nil if tracing is
+// disabled.
+func Default() *Tracer {
+	return defaultTracer.Load()
+}
+
+// StartSpan starts a new span using the default tracer. If the context
+// contains a span or a remote span context, the new span becomes its child,
+// otherwise a new trace is started.
+//
+// The returned context contains the new span. If tracing is disabled, the
+// context is returned unchanged along with a nil span.
+func StartSpan(ctx context.Context, name string, opts ...SpanOption) (context.Context, *Span) {
+	return Default().StartSpan(ctx, name, opts...)
+}
+
+type spanKey struct{}
+
+// ContextWithSpan returns a copy of the context with the span.
+func ContextWithSpan(ctx context.Context, s *Span) context.Context {
+	if s == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, spanKey{}, s)
+}
+
+// ContextWithRemoteSpanContext returns a copy of the context with a span
+// context received from another process. Spans started from the returned
+// context become children of the remote span. Invalid span contexts are
+// ignored.
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	if !sc.IsValid() {
+		return ctx
+	}
+	return context.WithValue(ctx, spanKey{}, sc)
+}
+
+// SpanFromContext returns the span stored in the context. It returns nil if
+// the context does not contain a span or contains only a remote span
+// context.
+func SpanFromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+// SpanContextFromContext returns the context of the span, or the remote
+// span context, stored in the context.
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if ctx == nil {
+		return SpanContext{}
+	}
+	switch v := ctx.Value(spanKey{}).(type) {
+	case *Span:
+		return v.SpanContext()
+	case SpanContext:
+		return v
+	}
+	return SpanContext{}
+}
+
+// Inject stores the span context in the data point metadata. If the span
+// context is invalid or the metadata is nil, it does nothing.
+func Inject(meta map[string]any, sc SpanContext) {
+	if meta == nil || !sc.IsValid() {
+		return
+	}
+	meta[MetaTraceID] = sc.TraceID.String()
+	meta[MetaSpanID] = sc.SpanID.String()
+}
+
+// Extract reads the span context from the data point metadata. It returns
+// false if the metadata does not contain a valid span context.
+func Extract(meta map[string]any) (SpanContext, bool) {
+	traceID, _ := meta[MetaTraceID].(string)
+	spanID, _ := meta[MetaSpanID].(string)
+	if traceID == "" || spanID == "" {
+		return SpanContext{}, false
+	}
+	var (
+		sc  SpanContext
+		err error
+	)
+	if sc.TraceID, err = ParseTraceID(traceID); err != nil {
+		return SpanContext{}, false
+	}
+	if sc.SpanID, err = ParseSpanID(spanID); err != nil {
+		return SpanContext{}, false
+	}
+	return sc, sc.IsValid()
+}
+
+func parseHex(dst []byte, s string) error {
+	if hex.DecodedLen(len(s)) != len(dst) {
+		return errors.New("invalid length")
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err
+}
+
+func randomBytes(b []byte) {
+	// The crypto/rand reader never returns an error on supported platforms.
+	_, _ = rand.Read(b)
+}
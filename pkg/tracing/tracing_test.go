@@ -0,0 +1,230 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type exporterMock struct {
+	mu    sync.Mutex
+	calls [][]byte
+	err   error
+}
+
+func (e *exporterMock) Export(_ context.Context, data []byte) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.calls = append(e.calls, data)
+	return e.err
+}
+
+func (e *exporterMock) spans(t *testing.T) []map[string]any {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var spans []map[string]any
+	for _, data := range e.calls {
+		var req struct {
+			ResourceSpans []struct {
+				ScopeSpans []struct {
+					Spans []map[string]any `json:"spans"`
+				} `json:"scopeSpans"`
+			} `json:"resourceSpans"`
+		}
+		require.NoError(t, json.Unmarshal(data, &req))
+		for _, rs := range req.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				spans = append(spans, ss.Spans...)
+			}
+		}
+	}
+	return spans
+}
+
+func TestStartSpan_Disabled(t *testing.T) {
+	SetDefault(nil)
+	ctx := context.Background()
+	spanCtx, span := StartSpan(ctx, "test")
+	assert.Nil(t, span)
+	assert.Equal(t, ctx, spanCtx)
+
+	// Methods of a nil span must not panic.
+	span.SetAttribute("key", "value")
+	span.SetError(errors.New("error"))
+	span.AddLink(SpanContext{})
+	span.End()
+	assert.False(t, span.SpanContext().IsValid())
+}
+
+func TestTracer_StartSpan(t *testing.T) {
+	exp := &exporterMock{}
+	tracer, err := New(Config{Exporter: exp})
+	require.NoError(t, err)
+
+	ctx, root := tracer.StartSpan(context.Background(), "root")
+	_, child := tracer.StartSpan(ctx, "child", WithKind(SpanKindProducer))
+
+	require.True(t, root.SpanContext().IsValid())
+	require.True(t, child.SpanContext().IsValid())
+	assert.Equal(t, root.SpanContext().TraceID, child.SpanContext().TraceID)
+	assert.NotEqual(t, root.SpanContext().SpanID, child.SpanContext().SpanID)
+	assert.Equal(t, root.SpanContext(), SpanContextFromContext(ctx))
+	assert.Same(t, root, SpanFromContext(ctx))
+
+	// A remote span context becomes the parent of the new span.
+	remote := SpanContext{TraceID: TraceID{1}, SpanID: SpanID{2}}
+	_, remoteChild := tracer.StartSpan(ContextWithRemoteSpanContext(context.Background(), remote), "remote")
+	assert.Equal(t, remote.TraceID, remoteChild.SpanContext().TraceID)
+	assert.Nil(t, SpanFromContext(ContextWithRemoteSpanContext(context.Background(), remote)))
+
+	child.SetAttribute("model", "BTC/USD")
+	child.SetError(errors.New("failed"))
+	child.AddLink(remote)
+	child.End()
+	child.End() // Ending a span twice must not export it twice.
+	root.End()
+
+	require.NoError(t, tracer.Flush(context.Background()))
+	spans := exp.spans(t)
+	require.Len(t, spans, 2)
+
+	assert.Equal(t, "child", spans[0]["name"])
+	assert.Equal(t, float64(SpanKindProducer), spans[0]["kind"])
+	assert.Equal(t, root.SpanContext().SpanID.String(), spans[0]["parentSpanId"])
+	assert.Equal(t, root.SpanContext().TraceID.String(), spans[0]["traceId"])
+	assert.Equal(t, []any{map[string]any{"key": "model", "value": map[string]any{"stringValue": "BTC/USD"}}}, spans[0]["attributes"])
+	assert.Equal(t, []any{map[string]any{"traceId": remote.TraceID.String(), "spanId": remote.SpanID.String()}}, spans[0]["links"])
+	assert.Equal(t, map[string]any{"code": float64(2), "message": "failed"}, spans[0]["status"])
+
+	assert.Equal(t, "root", spans[1]["name"])
+	assert.NotContains(t, spans[1], "parentSpanId")
+	assert.Equal(t, map[string]any{"code": float64(0)}, spans[1]["status"])
+
+	// Queue is empty after flush.
+	require.NoError(t, tracer.Flush(context.Background()))
+	assert.Len(t, exp.calls, 1)
+}
+
+func TestTracer_MaxQueueSize(t *testing.T) {
+	exp := &exporterMock{}
+	tracer, err := New(Config{Exporter: exp, MaxQueueSize: 2})
+	require.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		_, span := tracer.StartSpan(context.Background(), "span")
+		span.End()
+	}
+	require.NoError(t, tracer.Flush(context.Background()))
+	assert.Len(t, exp.spans(t), 2)
+}
+
+func TestTracer_Service(t *testing.T) {
+	exp := &exporterMock{}
+	tracer, err := New(Config{Exporter: exp, Interval: time.Hour, ServiceName: "ghost", ServiceVersion: "1.0.0"})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, tracer.Start(ctx))
+	require.Error(t, tracer.Start(ctx))
+
+	_, span := tracer.StartSpan(ctx, "span")
+	span.End()
+
+	// Remaining spans must be exported on shutdown.
+	cancel()
+	select {
+	case <-tracer.Wait():
+	case <-time.After(time.Second):
+		t.Fatal("tracer did not stop")
+	}
+	require.Len(t, exp.calls, 1)
+	assert.Contains(t, string(exp.calls[0]), `{"key":"service.name","value":{"stringValue":"ghost"}}`)
+	assert.Contains(t, string(exp.calls[0]), `{"key":"service.version","value":{"stringValue":"1.0.0"}}`)
+}
+
+func TestInjectExtract(t *testing.T) {
+	sc := SpanContext{
+		TraceID: TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:  SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+	}
+	meta := map[string]any{}
+	Inject(meta, sc)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", meta[MetaTraceID])
+	assert.Equal(t, "00f067aa0ba902b7", meta[MetaSpanID])
+
+	got, ok := Extract(meta)
+	require.True(t, ok)
+	assert.Equal(t, sc, got)
+
+	// Invalid span contexts are not injected.
+	empty := map[string]any{}
+	Inject(empty, SpanContext{})
+	assert.Empty(t, empty)
+
+	// Invalid metadata.
+	_, ok = Extract(map[string]any{MetaTraceID: "abc", MetaSpanID: "00f067aa0ba902b7"})
+	assert.False(t, ok)
+	_, ok = Extract(nil)
+	assert.False(t, ok)
+}
+
+func TestFileExporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.json")
+	exp := NewFileExporter(path)
+	require.NoError(t, exp.Export(context.Background(), []byte(`{"a":1}`)))
+	require.NoError(t, exp.Export(context.Background(), []byte(`{"b":2}`)))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "{\"a\":1}\n{\"b\":2}\n", string(data))
+}
+
+func TestHTTPExporter(t *testing.T) {
+	var (
+		body    string
+		headers http.Header
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		headers = r.Header
+		if strings.Contains(body, "fail") {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	exp := NewHTTPExporter(srv.URL+"/v1/traces", map[string]string{"Authorization": "Bearer token"}, nil)
+	require.NoError(t, exp.Export(context.Background(), []byte(`{"a":1}`)))
+	assert.Equal(t, `{"a":1}`, body)
+	assert.Equal(t, "application/json", headers.Get("Content-Type"))
+	assert.Equal(t, "Bearer token", headers.Get("Authorization"))
+
+	assert.Error(t, exp.Export(context.Background(), []byte(`"fail"`)))
+}
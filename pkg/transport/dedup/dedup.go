@@ -0,0 +1,256 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dedup
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/orcfax/oracle-suite/pkg/log"
+	"github.com/orcfax/oracle-suite/pkg/log/null"
+	"github.com/orcfax/oracle-suite/pkg/supervisor"
+	"github.com/orcfax/oracle-suite/pkg/transport"
+	"github.com/orcfax/oracle-suite/pkg/transport/messages"
+	"github.com/orcfax/oracle-suite/pkg/util/chanutil"
+)
+
+const LoggerTag = "DEDUP"
+
+// Dedup is a transport decorator that drops duplicated messages and,
+// optionally, data points that are older than previously received ones.
+//
+// Messages are considered duplicates if they have the same author, topic
+// and content. This happens when the same message is delivered by more
+// than one transport, e.g. when transports are combined using the
+// chain.Chain. Duplicates are dropped only within the configured window,
+// counted from the time the first copy was received.
+//
+// If the monotonic option is enabled, data points received from an author
+// must have strictly increasing timestamps for every data model. Data points
+// that are not newer than the last accepted one are dropped, which protects
+// the receivers from stale and replayed data points.
+//
+// Only messages read from channels returned by the Messages method are
+// filtered.
+type Dedup struct {
+	mu     sync.Mutex
+	ctx    context.Context
+	waitCh chan error
+
+	// State fields:
+	seen     map[messageKey]struct{} // Messages received within the window.
+	queue    []seenMessage           // Received messages in order of receipt.
+	lastTime map[orderKey]time.Time  // Time of the last data point per author and model.
+	msgFO    map[string]*chanutil.FanOut[transport.ReceivedMessage]
+
+	// Configuration fields:
+	transport transport.Service
+	window    time.Duration
+	monotonic bool
+	log       log.Logger
+}
+
+// Config is the configuration for the Dedup.
+type Config struct {
+	// Transport is the decorated transport.
+	Transport transport.Service
+
+	// Window is the time during which duplicates of a message are dropped.
+	Window time.Duration
+
+	// Monotonic enables dropping data points that are not newer than the
+	// last data point received from the same author for the same model.
+	Monotonic bool
+
+	// Logger is a current logger interface used by the Dedup.
+	// If nil, null logger will be used.
+	Logger log.Logger
+}
+
+type messageKey struct {
+	author string
+	topic  string
+	hash   [sha256.Size]byte
+}
+
+type orderKey struct {
+	author string
+	topic  string
+	model  string
+}
+
+type seenMessage struct {
+	key  messageKey
+	time time.Time
+}
+
+// New creates a new Dedup.
+func New(cfg Config) (*Dedup, error) {
+	if cfg.Transport == nil {
+		return nil, errors.New("transport must not be nil")
+	}
+	if cfg.Window <= 0 {
+		return nil, errors.New("window must be greater than zero")
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = null.New()
+	}
+	return &Dedup{
+		waitCh:    make(chan error),
+		seen:      make(map[messageKey]struct{}),
+		lastTime:  make(map[orderKey]time.Time),
+		msgFO:     make(map[string]*chanutil.FanOut[transport.ReceivedMessage]),
+		transport: cfg.Transport,
+		window:    cfg.Window,
+		monotonic: cfg.Monotonic,
+		log:       cfg.Logger.WithField("tag", LoggerTag),
+	}, nil
+}
+
+// Start implements the supervisor.Service interface.
+func (d *Dedup) Start(ctx context.Context) error {
+	if d.ctx != nil {
+		return errors.New("service can be started only once")
+	}
+	if ctx == nil {
+		return errors.New("context must not be nil")
+	}
+	d.log.Debug("Starting")
+	if err := d.transport.Start(ctx); err != nil {
+		return err
+	}
+	d.ctx = ctx
+	go d.contextCancelHandler()
+	return nil
+}
+
+// Wait implements the supervisor.Service interface.
+func (d *Dedup) Wait() <-chan error {
+	return d.waitCh
+}
+
+// Broadcast implements the transport.Transport interface.
+func (d *Dedup) Broadcast(topic string, message transport.Message) error {
+	return d.transport.Broadcast(topic, message)
+}
+
+// Messages implements the transport.Transport interface.
+func (d *Dedup) Messages(topic string) <-chan transport.ReceivedMessage {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if fo, ok := d.msgFO[topic]; ok {
+		return fo.Chan()
+	}
+	in := d.transport.Messages(topic)
+	if in == nil {
+		// It is possible that the underlying transport does not support
+		// given topic. In such case, it will return nil.
+		return nil
+	}
+	out := make(chan transport.ReceivedMessage)
+	go d.filterRoutine(topic, in, out)
+	fo := chanutil.NewFanOut[transport.ReceivedMessage](out)
+	d.msgFO[topic] = fo
+	return fo.Chan()
+}
+
+// ServiceName implements the supervisor.WithName interface.
+func (d *Dedup) ServiceName() string {
+	return fmt.Sprintf("Dedup(%s)", supervisor.ServiceName(d.transport))
+}
+
+func (d *Dedup) filterRoutine(topic string, in <-chan transport.ReceivedMessage, out chan<- transport.ReceivedMessage) {
+	defer close(out)
+	for msg := range in {
+		if msg.Error == nil && !d.accept(topic, msg) {
+			continue
+		}
+		out <- msg
+	}
+}
+
+// accept returns true if the message should be passed to the receivers.
+func (d *Dedup) accept(topic string, msg transport.ReceivedMessage) bool {
+	bin, err := msg.Message.MarshallBinary()
+	if err != nil {
+		// If the message cannot be hashed, it is not possible to tell
+		// whether it is a duplicate, so it is passed through.
+		return true
+	}
+	now := time.Now()
+	key := messageKey{
+		author: string(msg.Author),
+		topic:  topic,
+		hash:   sha256.Sum256(bin),
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.expire(now)
+	if _, ok := d.seen[key]; ok {
+		d.log.
+			WithFields(transport.ReceivedMessageFields(msg)).
+			Debug("Duplicated message dropped")
+		return false
+	}
+	if dp, ok := msg.Message.(*messages.DataPoint); ok && d.monotonic {
+		ord := orderKey{
+			author: string(msg.Author),
+			topic:  topic,
+			model:  dp.Model,
+		}
+		if last, found := d.lastTime[ord]; found && !dp.Point.Time.After(last) {
+			d.log.
+				WithFields(transport.ReceivedMessageFields(msg)).
+				WithFields(log.Fields{
+					"model":    dp.Model,
+					"time":     dp.Point.Time,
+					"lastTime": last,
+				}).
+				Debug("Stale data point dropped")
+			return false
+		}
+		d.lastTime[ord] = dp.Point.Time
+	}
+	d.seen[key] = struct{}{}
+	d.queue = append(d.queue, seenMessage{key: key, time: now})
+	return true
+}
+
+// expire removes messages received before the window from the state.
+func (d *Dedup) expire(now time.Time) {
+	n := 0
+	for n < len(d.queue) && now.Sub(d.queue[n].time) > d.window {
+		delete(d.seen, d.queue[n].key)
+		n++
+	}
+	if n > 0 {
+		d.queue = append(d.queue[:0], d.queue[n:]...)
+	}
+}
+
+// contextCancelHandler handles context cancellation.
+func (d *Dedup) contextCancelHandler() {
+	defer func() { close(d.waitCh) }()
+	defer d.log.Debug("Stopped")
+	if err := <-d.transport.Wait(); err != nil {
+		d.waitCh <- err
+	}
+}
@@ -0,0 +1,155 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dedup
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/orcfax/oracle-suite/pkg/datapoint"
+	"github.com/orcfax/oracle-suite/pkg/datapoint/value"
+	"github.com/orcfax/oracle-suite/pkg/transport"
+	"github.com/orcfax/oracle-suite/pkg/transport/local"
+	"github.com/orcfax/oracle-suite/pkg/transport/messages"
+	"github.com/orcfax/oracle-suite/pkg/util/bn"
+)
+
+func testDataPoint(model string, price int64, tm time.Time) *messages.DataPoint {
+	return &messages.DataPoint{
+		Model: model,
+		Point: datapoint.Point{
+			Value: value.StaticValue{Value: bn.DecFloatPoint(price)},
+			Time:  tm,
+		},
+	}
+}
+
+func receive(t *testing.T, ch <-chan transport.ReceivedMessage) []*messages.DataPoint {
+	var dps []*messages.DataPoint
+	for {
+		select {
+		case msg := <-ch:
+			require.NoError(t, msg.Error)
+			dps = append(dps, msg.Message.(*messages.DataPoint))
+		case <-time.After(100 * time.Millisecond):
+			return dps
+		}
+	}
+}
+
+func TestDedup(t *testing.T) {
+	tm := time.Unix(1700000000, 0)
+	tests := []struct {
+		name      string
+		monotonic bool
+		send      []*messages.DataPoint
+		want      []int64
+	}{
+		{
+			name: "drop duplicates",
+			send: []*messages.DataPoint{
+				testDataPoint("ETH/USD", 1, tm),
+				testDataPoint("ETH/USD", 1, tm),
+				testDataPoint("ETH/USD", 2, tm),
+				testDataPoint("BTC/USD", 1, tm),
+			},
+			want: []int64{1, 2, 1},
+		},
+		{
+			name: "accept stale data points",
+			send: []*messages.DataPoint{
+				testDataPoint("ETH/USD", 1, tm),
+				testDataPoint("ETH/USD", 2, tm.Add(-time.Second)),
+			},
+			want: []int64{1, 2},
+		},
+		{
+			name:      "drop stale data points",
+			monotonic: true,
+			send: []*messages.DataPoint{
+				testDataPoint("ETH/USD", 1, tm),
+				testDataPoint("ETH/USD", 2, tm.Add(-time.Second)),
+				testDataPoint("ETH/USD", 3, tm),
+				testDataPoint("BTC/USD", 4, tm.Add(-time.Second)),
+				testDataPoint("ETH/USD", 5, tm.Add(time.Second)),
+			},
+			want: []int64{1, 4, 5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, ctxCancel := context.WithCancel(context.Background())
+			defer ctxCancel()
+
+			d, err := New(Config{
+				Transport: local.New([]byte("test"), 10, map[string]transport.Message{
+					"dp": (*messages.DataPoint)(nil),
+				}),
+				Window:    time.Minute,
+				Monotonic: tt.monotonic,
+			})
+			require.NoError(t, err)
+			require.NoError(t, d.Start(ctx))
+
+			ch := d.Messages("dp")
+			for _, dp := range tt.send {
+				require.NoError(t, d.Broadcast("dp", dp))
+			}
+			var got []int64
+			for _, dp := range receive(t, ch) {
+				got = append(got, dp.Point.Value.(value.StaticValue).Value.Int().BigInt().Int64())
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDedup_Window(t *testing.T) {
+	ctx, ctxCancel := context.WithCancel(context.Background())
+	defer ctxCancel()
+
+	d, err := New(Config{
+		Transport: local.New([]byte("test"), 10, map[string]transport.Message{
+			"dp": (*messages.DataPoint)(nil),
+		}),
+		Window: 200 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	require.NoError(t, d.Start(ctx))
+
+	ch := d.Messages("dp")
+	dp := testDataPoint("ETH/USD", 1, time.Unix(1700000000, 0))
+	require.NoError(t, d.Broadcast("dp", dp))
+	require.NoError(t, d.Broadcast("dp", dp))
+	assert.Len(t, receive(t, ch), 1)
+
+	// After the window, the same message is not a duplicate anymore.
+	time.Sleep(200 * time.Millisecond)
+	require.NoError(t, d.Broadcast("dp", dp))
+	assert.Len(t, receive(t, ch), 1)
+	assert.Len(t, d.queue, 1)
+}
+
+func TestDedup_Config(t *testing.T) {
+	_, err := New(Config{Window: time.Minute})
+	require.Error(t, err)
+	_, err = New(Config{Transport: local.New([]byte("test"), 10, nil)})
+	require.Error(t, err)
+}
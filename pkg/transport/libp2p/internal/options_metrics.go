@@ -0,0 +1,157 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package internal
+
+import (
+	"sync"
+	"time"
+
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+
+	"github.com/orcfax/oracle-suite/pkg/metrics"
+	"github.com/orcfax/oracle-suite/pkg/transport/libp2p/internal/sets"
+)
+
+// Names of metrics collected by the Metrics option.
+const (
+	PeersMetric            = "libp2p_peers"
+	TopicPeersMetric       = "libp2p_topic_peers"
+	MeshPeersMetric        = "libp2p_mesh_peers"
+	MessagesRejectedMetric = "libp2p_messages_rejected_total"
+)
+
+// metricsTracer is a pubsub.RawTracer that reports rejected messages and
+// the mesh size of each topic.
+type metricsTracer struct {
+	mu       sync.Mutex
+	registry metrics.Registry
+	mesh     map[string]map[peer.ID]struct{}
+}
+
+func newMetricsTracer(registry metrics.Registry) *metricsTracer {
+	return &metricsTracer{
+		registry: registry,
+		mesh:     make(map[string]map[peer.ID]struct{}),
+	}
+}
+
+// Graft implements the pubsub.RawTracer interface.
+func (t *metricsTracer) Graft(p peer.ID, topic string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.mesh[topic] == nil {
+		t.mesh[topic] = make(map[peer.ID]struct{})
+	}
+	t.mesh[topic][p] = struct{}{}
+	t.registry.Set(MeshPeersMetric, metrics.Labels{"topic": topic}, float64(len(t.mesh[topic])))
+}
+
+// Prune implements the pubsub.RawTracer interface.
+func (t *metricsTracer) Prune(p peer.ID, topic string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.mesh[topic], p)
+	t.registry.Set(MeshPeersMetric, metrics.Labels{"topic": topic}, float64(len(t.mesh[topic])))
+}
+
+// RemovePeer implements the pubsub.RawTracer interface.
+func (t *metricsTracer) RemovePeer(p peer.ID) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	// Gossipsub removes disconnected peers from the mesh without pruning.
+	for topic, peers := range t.mesh {
+		if _, ok := peers[p]; ok {
+			delete(peers, p)
+			t.registry.Set(MeshPeersMetric, metrics.Labels{"topic": topic}, float64(len(peers)))
+		}
+	}
+}
+
+// Leave implements the pubsub.RawTracer interface.
+func (t *metricsTracer) Leave(topic string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.mesh, topic)
+	t.registry.Set(MeshPeersMetric, metrics.Labels{"topic": topic}, 0)
+}
+
+// RejectMessage implements the pubsub.RawTracer interface.
+func (t *metricsTracer) RejectMessage(msg *pubsub.Message, reason string) {
+	t.registry.Add(MessagesRejectedMetric, metrics.Labels{"topic": msg.GetTopic(), "reason": reason}, 1)
+}
+
+// AddPeer implements the pubsub.RawTracer interface.
+func (t *metricsTracer) AddPeer(peer.ID, protocol.ID) {}
+
+// Join implements the pubsub.RawTracer interface.
+func (t *metricsTracer) Join(string) {}
+
+// ValidateMessage implements the pubsub.RawTracer interface.
+func (t *metricsTracer) ValidateMessage(*pubsub.Message) {}
+
+// DeliverMessage implements the pubsub.RawTracer interface.
+func (t *metricsTracer) DeliverMessage(*pubsub.Message) {}
+
+// DuplicateMessage implements the pubsub.RawTracer interface.
+func (t *metricsTracer) DuplicateMessage(*pubsub.Message) {}
+
+// ThrottlePeer implements the pubsub.RawTracer interface.
+func (t *metricsTracer) ThrottlePeer(peer.ID) {}
+
+// RecvRPC implements the pubsub.RawTracer interface.
+func (t *metricsTracer) RecvRPC(*pubsub.RPC) {}
+
+// SendRPC implements the pubsub.RawTracer interface.
+func (t *metricsTracer) SendRPC(*pubsub.RPC, peer.ID) {}
+
+// DropRPC implements the pubsub.RawTracer interface.
+func (t *metricsTracer) DropRPC(*pubsub.RPC, peer.ID) {}
+
+// UndeliverableMessage implements the pubsub.RawTracer interface.
+func (t *metricsTracer) UndeliverableMessage(*pubsub.Message) {}
+
+// Metrics reports the number of connected peers, the number of peers and
+// the mesh size of each topic, and the number of rejected messages by
+// reason to the given registry. Gauges are updated every interval.
+func Metrics(registry metrics.Registry, interval time.Duration) Options {
+	return func(n *Node) error {
+		n.pubsubOpts = append(n.pubsubOpts, pubsub.WithRawTracer(newMetricsTracer(registry)))
+		n.AddNodeEventHandler(sets.NodeEventHandlerFunc(func(event interface{}) {
+			if _, ok := event.(sets.NodeStartedEvent); ok {
+				go func() {
+					t := time.NewTicker(interval)
+					defer t.Stop()
+					for {
+						registry.Set(PeersMetric, nil, float64(len(n.host.Network().Peers())))
+						if n.pubSub != nil {
+							for _, topic := range n.pubSub.GetTopics() {
+								registry.Set(TopicPeersMetric, metrics.Labels{"topic": topic}, float64(len(n.pubSub.ListPeers(topic))))
+							}
+						}
+						select {
+						case <-n.ctx.Done():
+							return
+						case <-t.C:
+						}
+					}
+				}()
+			}
+		}))
+		return nil
+	}
+}
@@ -33,6 +33,7 @@ import (
 
 	"github.com/orcfax/oracle-suite/pkg/log"
 	"github.com/orcfax/oracle-suite/pkg/log/null"
+	"github.com/orcfax/oracle-suite/pkg/metrics"
 	"github.com/orcfax/oracle-suite/pkg/transport"
 	"github.com/orcfax/oracle-suite/pkg/transport/libp2p/crypto/ethkey"
 	"github.com/orcfax/oracle-suite/pkg/transport/libp2p/internal"
@@ -70,6 +71,9 @@ const maxInvalidMsgsPerHour float64 = 60 // per topic
 // the Ethereum wallet requires more time.
 const connectionTimeout = 120 * time.Second
 
+// metricsInterval is the interval between updates of peer metrics.
+const metricsInterval = 15 * time.Second
+
 // defaultListenAddrs is the list of default multiaddresses on which node will
 // be listening on.
 var defaultListenAddrs = []string{"/ip4/0.0.0.0/tcp/0"}
@@ -83,6 +87,7 @@ type P2P struct {
 	topics     map[string]transport.Message
 	msgCh      map[string]chan transport.ReceivedMessage
 	msgFanOut  map[string]*chanutil.FanOut[transport.ReceivedMessage]
+	feedPeers  *feedPeers
 	appName    string
 	appVersion string
 }
@@ -139,6 +144,14 @@ type Config struct {
 	// Signer used to verify price messages. Ignored in bootstrap mode.
 	Signer wallet.Key
 
+	// Reputation is an optional source of data quality penalties of feeds.
+	// Penalties are included in the application-specific peer score.
+	Reputation Reputation
+
+	// Metrics is an optional registry to which peer and topic metrics are
+	// reported.
+	Metrics metrics.Registry
+
 	// Logger is a custom logger instance. If not provided then null
 	// logger is used.
 	Logger log.Logger
@@ -189,6 +202,7 @@ func New(cfg Config) (*P2P, error) {
 	}
 
 	logger := cfg.Logger.WithField("tag", LoggerTag)
+	peers := newFeedPeers()
 	opts := []internal.Options{
 		internal.DialTimeout(connectionTimeout),
 		internal.Logger(logger),
@@ -214,6 +228,9 @@ func New(cfg Config) (*P2P, error) {
 	if cfg.PeerPrivKey != nil {
 		opts = append(opts, internal.PeerPrivKey(cfg.PeerPrivKey))
 	}
+	if cfg.Metrics != nil {
+		opts = append(opts, internal.Metrics(cfg.Metrics, metricsInterval))
+	}
 
 	switch cfg.Mode {
 	case ClientMode:
@@ -223,7 +240,7 @@ func New(cfg Config) (*P2P, error) {
 		}
 		opts = append(opts,
 			internal.RateLimiter(rateLimiterConfig(cfg)),
-			internal.PeerScoring(peerScoreParams, thresholds, func(topic string) *pubsub.TopicScoreParams {
+			internal.PeerScoring(newPeerScoreParams(cfg.Reputation, peers), thresholds, func(topic string) *pubsub.TopicScoreParams {
 				if topic == messages.PriceV0MessageName || topic == messages.PriceV1MessageName { //nolint:staticcheck
 					return priceTopicScoreParams
 				}
@@ -265,6 +282,7 @@ func New(cfg Config) (*P2P, error) {
 		topics:     cfg.Topics,
 		msgCh:      map[string]chan transport.ReceivedMessage{},
 		msgFanOut:  map[string]*chanutil.FanOut[transport.ReceivedMessage]{},
+		feedPeers:  peers,
 		appName:    cfg.AppName,
 		appVersion: cfg.AppVersion,
 	}, nil
@@ -336,6 +354,7 @@ func (p *P2P) messagesLoop(topic string, sub *internal.Subscription) {
 			return
 		}
 		id := nodeMsg.GetFrom()
+		p.feedPeers.record(nodeMsg)
 		if msg, ok := nodeMsg.ValidatorData.(transport.Message); ok {
 			userAgent := ""
 			if appInfo, ok := msg.(transport.WithAppInfo); ok {
@@ -18,10 +18,14 @@ package libp2p
 import (
 	"errors"
 	"math"
+	"sync"
 	"time"
 
+	"github.com/defiweb/go-eth/types"
 	pubsub "github.com/libp2p/go-libp2p-pubsub"
 	"github.com/libp2p/go-libp2p/core/peer"
+
+	"github.com/orcfax/oracle-suite/pkg/transport/libp2p/crypto/ethkey"
 )
 
 // Peer scoring:
@@ -30,6 +34,18 @@ import (
 const decayInterval = time.Minute
 const decayToZero = 0.01
 
+// maxReputationPenalty is the application-specific score of a peer with
+// the highest data quality penalty. It is lower than the graylist threshold,
+// so that messages from such peers are ignored.
+const maxReputationPenalty = -5000
+
+// Reputation provides data quality penalties of feeds.
+type Reputation interface {
+	// Penalty returns a penalty of the feed in the range [0, 1], where 0
+	// means no penalty.
+	Penalty(feed types.Address) float64
+}
+
 var thresholds = &pubsub.PeerScoreThresholds{
 	// -4000 is sum of P₃ and P₃b for the "price" and "event" topics. It should
 	// be equal to the lowest score a silent peer can get without receiving any
@@ -56,6 +72,67 @@ var peerScoreParams = &pubsub.PeerScoreParams{
 	Topics:                      make(map[string]*pubsub.TopicScoreParams),
 }
 
+// newPeerScoreParams returns peer score parameters that include penalties
+// provided by the reputation in the application-specific score. A peer gets
+// the highest penalty of the feeds whose messages it forwarded, see the
+// feedPeers type.
+func newPeerScoreParams(reputation Reputation, peers *feedPeers) *pubsub.PeerScoreParams {
+	if reputation == nil {
+		return peerScoreParams
+	}
+	params := *peerScoreParams
+	params.AppSpecificScore = func(id peer.ID) float64 {
+		var penalty float64
+		for _, feed := range peers.feeds(id) {
+			penalty = math.Max(penalty, reputation.Penalty(feed))
+		}
+		return maxReputationPenalty * penalty
+	}
+	return &params
+}
+
+// feedPeers keeps track of peers that forward messages of feeds.
+//
+// Messages are signed with feed keys, but peers use separate host keys, so
+// peer IDs cannot be derived from feed addresses. Instead, every feed is
+// linked to the peer that most recently forwarded a message authored by it.
+type feedPeers struct {
+	mu    sync.RWMutex
+	peers map[types.Address]peer.ID
+}
+
+func newFeedPeers() *feedPeers {
+	return &feedPeers{peers: make(map[types.Address]peer.ID)}
+}
+
+// record links the author of the message with the peer from which the
+// message was received.
+func (f *feedPeers) record(msg *pubsub.Message) {
+	if msg.Local || msg.ReceivedFrom == "" {
+		return
+	}
+	feed := ethkey.PeerIDToAddress(msg.GetFrom())
+	if feed == types.ZeroAddress {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.peers[feed] = msg.ReceivedFrom
+}
+
+// feeds returns the feeds linked with the peer.
+func (f *feedPeers) feeds(id peer.ID) []types.Address {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	var feeds []types.Address
+	for feed, p := range f.peers {
+		if p == id {
+			feeds = append(feeds, feed)
+		}
+	}
+	return feeds
+}
+
 func calculatePriceTopicScoreParams(cfg Config) (*pubsub.TopicScoreParams, error) {
 	var maxPeers = float64(pubsub.GossipSubDhi)
 	// Minimum and maximum expected number of feeds connected to the network:
@@ -16,12 +16,21 @@
 package libp2p
 
 import (
+	"crypto/rand"
 	"math"
 	"testing"
 	"time"
 
+	"github.com/defiweb/go-eth/types"
+	"github.com/defiweb/go-eth/wallet"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	pb "github.com/libp2p/go-libp2p-pubsub/pb"
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/orcfax/oracle-suite/pkg/transport/libp2p/crypto/ethkey"
 )
 
 func TestScoreParams_calculate(t *testing.T) {
@@ -63,3 +72,62 @@ func TestScoreParams_calculate(t *testing.T) {
 	assert.InDelta(t, p.maxMessagesPerSecond, pc.MeshMessageDeliveriesCap/p.p3Length.Seconds(), 0.01)
 	assert.InDelta(t, p.maxInvalidMessages, decayToZero*math.Pow(pc.InvalidMessageDeliveriesDecay, p.p4Length.Seconds()/decayInterval.Seconds()*-1), 0.01)
 }
+
+type testReputation map[types.Address]float64
+
+func (r testReputation) Penalty(feed types.Address) float64 {
+	return r[feed]
+}
+
+func TestNewPeerScoreParams(t *testing.T) {
+	feed := wallet.NewRandomKey().Address()
+	other := wallet.NewRandomKey().Address()
+
+	// Peers use host keys generated in the same way as in the config
+	// package, so their IDs are not derived from feed addresses.
+	hostKey, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	require.NoError(t, err)
+	host, err := peer.IDFromPrivateKey(hostKey)
+	require.NoError(t, err)
+	otherHostKey, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	require.NoError(t, err)
+	otherHost, err := peer.IDFromPrivateKey(otherHostKey)
+	require.NoError(t, err)
+
+	// Without reputation, default parameters are used:
+	assert.Same(t, peerScoreParams, newPeerScoreParams(nil, newFeedPeers()))
+
+	peers := newFeedPeers()
+	params := newPeerScoreParams(testReputation{feed: 0.5, other: 0.25}, peers)
+	assert.NotSame(t, peerScoreParams, params)
+	assert.Less(t, float64(maxReputationPenalty), thresholds.GraylistThreshold)
+
+	// Before the peer forwards any message of the feed, it is not penalized:
+	assert.Equal(t, 0.0, params.AppSpecificScore(host))
+
+	peers.record(testPubsubMessage(feed, host))
+	peers.record(testPubsubMessage(other, host))
+	assert.Equal(t, 0.5*maxReputationPenalty, params.AppSpecificScore(host))
+	assert.Equal(t, 0.0, params.AppSpecificScore(otherHost))
+
+	// The feed is linked with the peer that most recently forwarded its
+	// message:
+	peers.record(testPubsubMessage(feed, otherHost))
+	assert.Equal(t, 0.25*maxReputationPenalty, params.AppSpecificScore(host))
+	assert.Equal(t, 0.5*maxReputationPenalty, params.AppSpecificScore(otherHost))
+
+	// Local messages are not recorded:
+	msg := testPubsubMessage(other, otherHost)
+	msg.Local = true
+	peers.record(msg)
+	assert.Equal(t, 0.25*maxReputationPenalty, params.AppSpecificScore(host))
+}
+
+// testPubsubMessage returns a message authored by the feed and received from
+// the peer.
+func testPubsubMessage(feed types.Address, from peer.ID) *pubsub.Message {
+	return &pubsub.Message{
+		Message:      &pb.Message{From: []byte(ethkey.AddressToPeerID(feed))},
+		ReceivedFrom: from,
+	}
+}
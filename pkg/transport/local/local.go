@@ -40,6 +40,7 @@ type base struct {
 	mu     sync.RWMutex
 	ctx    context.Context
 	waitCh chan error
+	doneCh chan struct{} // Closed when the context is canceled, before subscriptions are closed.
 	subs   map[string]*subscription
 }
 
@@ -71,6 +72,7 @@ func New(author []byte, queue int, topics map[string]transport.Message) *Local {
 	l := &Local{
 		base: &base{
 			waitCh: make(chan error),
+			doneCh: make(chan struct{}),
 			subs:   make(map[string]*subscription),
 		},
 		author: author,
@@ -120,8 +122,12 @@ func (l *Local) Broadcast(topic string, message transport.Message) error {
 		if err != nil {
 			return err
 		}
-		sub.rawMsgCh <- rawMsg{author: l.author, data: data}
-		return nil
+		select {
+		case sub.rawMsgCh <- rawMsg{author: l.author, data: data}:
+			return nil
+		case <-l.doneCh:
+			return l.ctx.Err()
+		}
 	}
 	return ErrNotSubscribed
 }
@@ -142,16 +148,36 @@ func (l *Local) unmarshallRoutine(sub *subscription) {
 		if !ok {
 			return
 		}
-		l.mu.RLock()
 		msg := reflect.New(sub.typ).Interface().(transport.Message)
 		err := msg.UnmarshallBinary(rawMsg.data)
-		sub.msgCh <- transport.ReceivedMessage{
+		if !l.deliver(sub, transport.ReceivedMessage{
 			Message: msg,
 			Author:  rawMsg.author,
 			Error:   err,
 			Meta:    transport.Meta{Transport: TransportName},
+		}) {
+			return
 		}
-		l.mu.RUnlock()
+	}
+}
+
+// deliver sends the message to the subscription. It returns false if the
+// transport is closed.
+//
+// The read lock prevents the subscription from being closed while the
+// message is sent, and the doneCh channel allows the contextCancelHandler
+// to acquire the write lock if no one is reading the message.
+func (l *Local) deliver(sub *subscription, msg transport.ReceivedMessage) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.subs == nil {
+		return false
+	}
+	select {
+	case sub.msgCh <- msg:
+		return true
+	case <-l.doneCh:
+		return false
 	}
 }
 
@@ -159,6 +185,7 @@ func (l *Local) unmarshallRoutine(sub *subscription) {
 func (l *Local) contextCancelHandler() {
 	defer func() { close(l.waitCh) }()
 	<-l.ctx.Done()
+	close(l.doneCh)
 	l.mu.Lock()
 	defer l.mu.Unlock()
 	for _, sub := range l.subs {
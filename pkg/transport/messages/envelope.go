@@ -0,0 +1,86 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package messages
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/orcfax/oracle-suite/pkg/transport"
+	"github.com/orcfax/oracle-suite/pkg/transport/messages/pb"
+	"github.com/orcfax/oracle-suite/pkg/util/maputil"
+)
+
+// Envelope wraps a message encoded in one or more versions. It allows
+// nodes to publish a message in multiple versions during migrations, so
+// that every consumer can pick the newest version it understands.
+type Envelope struct {
+	transport.AppInfo
+
+	// Payloads maps a message version to the binary encoded message.
+	Payloads map[uint32][]byte
+}
+
+// Versions returns a sorted list of versions included in the envelope.
+func (e Envelope) Versions() []uint32 {
+	return maputil.SortKeys(e.Payloads, func(v []uint32) {
+		sort.Slice(v, func(i, j int) bool { return v[i] < v[j] })
+	})
+}
+
+func (e Envelope) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"versions": e.Versions(),
+	})
+}
+
+// MarshallBinary implements the transport.Message interface.
+func (e Envelope) MarshallBinary() ([]byte, error) {
+	msg := &pb.Envelope{AppInfo: appInfoToProtobuf(e.AppInfo)}
+	for _, v := range e.Versions() {
+		msg.Payloads = append(msg.Payloads, &pb.Envelope_Payload{
+			Version: v,
+			Data:    e.Payloads[v],
+		})
+	}
+	return proto.Marshal(msg)
+}
+
+// UnmarshallBinary implements the transport.Message interface.
+func (e *Envelope) UnmarshallBinary(data []byte) error {
+	if len(data) == 0 {
+		return fmt.Errorf("empty message")
+	}
+	msg := pb.Envelope{}
+	if err := proto.Unmarshal(data, &msg); err != nil {
+		return err
+	}
+	if len(msg.Payloads) == 0 {
+		return fmt.Errorf("envelope does not contain any payload")
+	}
+	e.Payloads = make(map[uint32][]byte, len(msg.Payloads))
+	for _, p := range msg.Payloads {
+		if _, ok := e.Payloads[p.Version]; ok {
+			return fmt.Errorf("duplicated payload version: %d", p.Version)
+		}
+		e.Payloads[p.Version] = p.Data
+	}
+	e.AppInfo = appInfoFromProtobuf(msg.AppInfo)
+	return nil
+}
@@ -0,0 +1,79 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package messages
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/orcfax/oracle-suite/pkg/transport"
+)
+
+func TestEnvelope_Marshalling(t *testing.T) {
+	tests := []struct {
+		name     string
+		envelope Envelope
+		wantErr  bool
+	}{
+		{
+			name: "single version",
+			envelope: Envelope{
+				AppInfo:  transport.AppInfo{Name: "test", Version: "1.0.0"},
+				Payloads: map[uint32][]byte{1: []byte("foo")},
+			},
+		},
+		{
+			name: "multiple versions",
+			envelope: Envelope{
+				AppInfo:  transport.AppInfo{Name: "test", Version: "1.0.0"},
+				Payloads: map[uint32][]byte{1: []byte("foo"), 2: []byte("bar"), 5: []byte("baz")},
+			},
+		},
+		{
+			name:     "no payloads",
+			envelope: Envelope{},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := tt.envelope.MarshallBinary()
+			require.NoError(t, err)
+
+			var envelope Envelope
+			err = envelope.UnmarshallBinary(b)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.envelope, envelope)
+		})
+	}
+}
+
+func TestEnvelope_Versions(t *testing.T) {
+	envelope := Envelope{Payloads: map[uint32][]byte{3: nil, 1: nil, 2: nil}}
+	assert.Equal(t, []uint32{1, 2, 3}, envelope.Versions())
+}
+
+func FuzzEnvelope_UnmarshallBinary(f *testing.F) {
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_ = (&Envelope{}).UnmarshallBinary(data)
+	})
+}
@@ -19,6 +19,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"math/big"
+	"sort"
 
 	"github.com/defiweb/go-eth/hexutil"
 	"github.com/defiweb/go-eth/types"
@@ -27,6 +28,7 @@ import (
 
 	"github.com/orcfax/oracle-suite/pkg/transport"
 	"github.com/orcfax/oracle-suite/pkg/transport/messages/pb"
+	"github.com/orcfax/oracle-suite/pkg/util/maputil"
 )
 
 const GreetV1MessageName = "greet/v1"
@@ -38,6 +40,11 @@ type Greet struct {
 	PublicKeyX *big.Int
 	PublicKeyY *big.Int
 	WebURL     string
+
+	// Capabilities maps topic names to message versions supported by the
+	// node. It is used to negotiate versions of messages published in
+	// envelopes.
+	Capabilities map[string][]uint32
 }
 
 func (e Greet) MarshalJSON() ([]byte, error) {
@@ -46,6 +53,7 @@ func (e Greet) MarshalJSON() ([]byte, error) {
 		"public_key_x": hexutil.BigIntToHex(e.PublicKeyX),
 		"public_key_y": hexutil.BigIntToHex(e.PublicKeyY),
 		"web_url":      e.WebURL,
+		"capabilities": e.Capabilities,
 	})
 }
 
@@ -61,12 +69,20 @@ func (e Greet) MarshallBinary() ([]byte, error) {
 	if e.PublicKeyY != nil {
 		pubKeyY = e.PublicKeyY.Bytes()
 	}
+	var capabilities []*pb.Capability
+	for _, topic := range maputil.SortKeys(e.Capabilities, sort.Strings) {
+		capabilities = append(capabilities, &pb.Capability{
+			Topic:    topic,
+			Versions: e.Capabilities[topic],
+		})
+	}
 	return proto.Marshal(&pb.Greet{
-		Signature: e.Signature.Bytes(),
-		PubKeyX:   pubKeyX,
-		PubKeyY:   pubKeyY,
-		WebURL:    e.WebURL,
-		AppInfo:   appInfoToProtobuf(e.AppInfo),
+		Signature:    e.Signature.Bytes(),
+		PubKeyX:      pubKeyX,
+		PubKeyY:      pubKeyY,
+		WebURL:       e.WebURL,
+		Capabilities: capabilities,
+		AppInfo:      appInfoToProtobuf(e.AppInfo),
 	})
 }
 
@@ -86,6 +102,13 @@ func (e *Greet) UnmarshallBinary(data []byte) (err error) {
 	e.PublicKeyX = new(big.Int).SetBytes(msg.PubKeyX)
 	e.PublicKeyY = new(big.Int).SetBytes(msg.PubKeyY)
 	e.WebURL = msg.WebURL
+	e.Capabilities = nil
+	if len(msg.Capabilities) > 0 {
+		e.Capabilities = make(map[string][]uint32, len(msg.Capabilities))
+		for _, c := range msg.Capabilities {
+			e.Capabilities[c.Topic] = c.Versions
+		}
+	}
 	e.AppInfo = appInfoFromProtobuf(msg.AppInfo)
 	return nil
 }
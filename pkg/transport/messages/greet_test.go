@@ -21,6 +21,7 @@ import (
 
 	"github.com/defiweb/go-eth/types"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGreet_MarshallBinary(t *testing.T) {
@@ -100,6 +101,24 @@ func TestGreet_UnmarshallBinary(t *testing.T) {
 	}
 }
 
+func TestGreet_Capabilities(t *testing.T) {
+	greet := Greet{
+		Signature:  types.MustSignatureFromHex("0x00112233445566778899aabbccddeeff00112233445566778899aabbccddeeff00112233445566778899aabbccddeeff00112233445566778899aabbccddeeff00"),
+		PublicKeyX: big.NewInt(1234567890),
+		PublicKeyY: big.NewInt(1234567890),
+		Capabilities: map[string][]uint32{
+			"data_point": {1, 2},
+			"price":      {1},
+		},
+	}
+	b, err := greet.MarshallBinary()
+	require.NoError(t, err)
+
+	var got Greet
+	require.NoError(t, got.UnmarshallBinary(b))
+	assert.Equal(t, greet.Capabilities, got.Capabilities)
+}
+
 func FuzzGreet_UnmarshallBinary(f *testing.F) {
 	f.Fuzz(func(t *testing.T, data []byte) {
 		_ = (&Greet{}).UnmarshallBinary(data)
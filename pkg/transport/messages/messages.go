@@ -58,8 +58,8 @@ func appInfoToProtobuf(a transport.AppInfo) *pb.AppInfo {
 
 func appInfoFromProtobuf(a *pb.AppInfo) transport.AppInfo {
 	return transport.AppInfo{
-		Name:    a.Name,
-		Version: a.Version,
+		Name:    a.GetName(),
+		Version: a.GetVersion(),
 	}
 }
 
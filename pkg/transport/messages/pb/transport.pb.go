@@ -1,6 +1,6 @@
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // versions:
-// 	protoc-gen-go v1.28.1
+// 	protoc-gen-go v1.32.0
 // 	protoc        v3.21.12
 // source: transport.proto
 
@@ -1019,11 +1019,12 @@ type Greet struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Signature []byte   `protobuf:"bytes,1,opt,name=signature,proto3" json:"signature,omitempty"`
-	PubKeyX   []byte   `protobuf:"bytes,2,opt,name=pubKeyX,proto3" json:"pubKeyX,omitempty"`
-	PubKeyY   []byte   `protobuf:"bytes,3,opt,name=pubKeyY,proto3" json:"pubKeyY,omitempty"`
-	WebURL    string   `protobuf:"bytes,4,opt,name=webURL,proto3" json:"webURL,omitempty"`
-	AppInfo   *AppInfo `protobuf:"bytes,1000,opt,name=appInfo,proto3" json:"appInfo,omitempty"` // Application info.
+	Signature    []byte        `protobuf:"bytes,1,opt,name=signature,proto3" json:"signature,omitempty"`
+	PubKeyX      []byte        `protobuf:"bytes,2,opt,name=pubKeyX,proto3" json:"pubKeyX,omitempty"`
+	PubKeyY      []byte        `protobuf:"bytes,3,opt,name=pubKeyY,proto3" json:"pubKeyY,omitempty"`
+	WebURL       string        `protobuf:"bytes,4,opt,name=webURL,proto3" json:"webURL,omitempty"`
+	Capabilities []*Capability `protobuf:"bytes,5,rep,name=capabilities,proto3" json:"capabilities,omitempty"` // Topics and message versions supported by the node.
+	AppInfo      *AppInfo      `protobuf:"bytes,1000,opt,name=appInfo,proto3" json:"appInfo,omitempty"`        // Application info.
 }
 
 func (x *Greet) Reset() {
@@ -1086,6 +1087,13 @@ func (x *Greet) GetWebURL() string {
 	return ""
 }
 
+func (x *Greet) GetCapabilities() []*Capability {
+	if x != nil {
+		return x.Capabilities
+	}
+	return nil
+}
+
 func (x *Greet) GetAppInfo() *AppInfo {
 	if x != nil {
 		return x.AppInfo
@@ -1093,6 +1101,116 @@ func (x *Greet) GetAppInfo() *AppInfo {
 	return nil
 }
 
+type Capability struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Topic    string   `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"`               // Topic name without a version suffix.
+	Versions []uint32 `protobuf:"varint,2,rep,packed,name=versions,proto3" json:"versions,omitempty"` // Supported message versions.
+}
+
+func (x *Capability) Reset() {
+	*x = Capability{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_transport_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Capability) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Capability) ProtoMessage() {}
+
+func (x *Capability) ProtoReflect() protoreflect.Message {
+	mi := &file_transport_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Capability.ProtoReflect.Descriptor instead.
+func (*Capability) Descriptor() ([]byte, []int) {
+	return file_transport_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *Capability) GetTopic() string {
+	if x != nil {
+		return x.Topic
+	}
+	return ""
+}
+
+func (x *Capability) GetVersions() []uint32 {
+	if x != nil {
+		return x.Versions
+	}
+	return nil
+}
+
+type Envelope struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Payloads []*Envelope_Payload `protobuf:"bytes,1,rep,name=payloads,proto3" json:"payloads,omitempty"`  // Message encoded in one or more versions.
+	AppInfo  *AppInfo            `protobuf:"bytes,1000,opt,name=appInfo,proto3" json:"appInfo,omitempty"` // Application info.
+}
+
+func (x *Envelope) Reset() {
+	*x = Envelope{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_transport_proto_msgTypes[15]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Envelope) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Envelope) ProtoMessage() {}
+
+func (x *Envelope) ProtoReflect() protoreflect.Message {
+	mi := &file_transport_proto_msgTypes[15]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Envelope.ProtoReflect.Descriptor instead.
+func (*Envelope) Descriptor() ([]byte, []int) {
+	return file_transport_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *Envelope) GetPayloads() []*Envelope_Payload {
+	if x != nil {
+		return x.Payloads
+	}
+	return nil
+}
+
+func (x *Envelope) GetAppInfo() *AppInfo {
+	if x != nil {
+		return x.AppInfo
+	}
+	return nil
+}
+
 type MuSigMetaTickV1_FeedTick struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -1106,7 +1224,7 @@ type MuSigMetaTickV1_FeedTick struct {
 func (x *MuSigMetaTickV1_FeedTick) Reset() {
 	*x = MuSigMetaTickV1_FeedTick{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_transport_proto_msgTypes[15]
+		mi := &file_transport_proto_msgTypes[17]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1119,7 +1237,7 @@ func (x *MuSigMetaTickV1_FeedTick) String() string {
 func (*MuSigMetaTickV1_FeedTick) ProtoMessage() {}
 
 func (x *MuSigMetaTickV1_FeedTick) ProtoReflect() protoreflect.Message {
-	mi := &file_transport_proto_msgTypes[15]
+	mi := &file_transport_proto_msgTypes[17]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1156,6 +1274,61 @@ func (x *MuSigMetaTickV1_FeedTick) GetVrs() []byte {
 	return nil
 }
 
+type Envelope_Payload struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Version uint32 `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"` // Message version.
+	Data    []byte `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`        // Binary encoded message.
+}
+
+func (x *Envelope_Payload) Reset() {
+	*x = Envelope_Payload{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_transport_proto_msgTypes[18]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Envelope_Payload) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Envelope_Payload) ProtoMessage() {}
+
+func (x *Envelope_Payload) ProtoReflect() protoreflect.Message {
+	mi := &file_transport_proto_msgTypes[18]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Envelope_Payload.ProtoReflect.Descriptor instead.
+func (*Envelope_Payload) Descriptor() ([]byte, []int) {
+	return file_transport_proto_rawDescGZIP(), []int{15, 0}
+}
+
+func (x *Envelope_Payload) GetVersion() uint32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *Envelope_Payload) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
 var File_transport_proto protoreflect.FileDescriptor
 
 var file_transport_proto_rawDesc = []byte{
@@ -1296,21 +1469,38 @@ var file_transport_proto_rawDesc = []byte{
 	0x75, 0x72, 0x65, 0x12, 0x23, 0x0a, 0x07, 0x61, 0x70, 0x70, 0x49, 0x6e, 0x66, 0x6f, 0x18, 0xe8,
 	0x07, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x08, 0x2e, 0x41, 0x70, 0x70, 0x49, 0x6e, 0x66, 0x6f, 0x52,
 	0x07, 0x61, 0x70, 0x70, 0x49, 0x6e, 0x66, 0x6f, 0x42, 0x0a, 0x0a, 0x08, 0x5f, 0x6d, 0x73, 0x67,
-	0x4d, 0x65, 0x74, 0x61, 0x22, 0x96, 0x01, 0x0a, 0x05, 0x47, 0x72, 0x65, 0x65, 0x74, 0x12, 0x1c,
+	0x4d, 0x65, 0x74, 0x61, 0x22, 0xc7, 0x01, 0x0a, 0x05, 0x47, 0x72, 0x65, 0x65, 0x74, 0x12, 0x1c,
 	0x0a, 0x09, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28,
 	0x0c, 0x52, 0x09, 0x73, 0x69, 0x67, 0x6e, 0x61, 0x74, 0x75, 0x72, 0x65, 0x12, 0x18, 0x0a, 0x07,
 	0x70, 0x75, 0x62, 0x4b, 0x65, 0x79, 0x58, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x07, 0x70,
 	0x75, 0x62, 0x4b, 0x65, 0x79, 0x58, 0x12, 0x18, 0x0a, 0x07, 0x70, 0x75, 0x62, 0x4b, 0x65, 0x79,
 	0x59, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x07, 0x70, 0x75, 0x62, 0x4b, 0x65, 0x79, 0x59,
 	0x12, 0x16, 0x0a, 0x06, 0x77, 0x65, 0x62, 0x55, 0x52, 0x4c, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09,
-	0x52, 0x06, 0x77, 0x65, 0x62, 0x55, 0x52, 0x4c, 0x12, 0x23, 0x0a, 0x07, 0x61, 0x70, 0x70, 0x49,
-	0x6e, 0x66, 0x6f, 0x18, 0xe8, 0x07, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x08, 0x2e, 0x41, 0x70, 0x70,
-	0x49, 0x6e, 0x66, 0x6f, 0x52, 0x07, 0x61, 0x70, 0x70, 0x49, 0x6e, 0x66, 0x6f, 0x42, 0x45, 0x5a,
-	0x43, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x68, 0x72, 0x6f,
-	0x6e, 0x69, 0x63, 0x6c, 0x65, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x2f, 0x6f, 0x72,
-	0x61, 0x63, 0x6c, 0x65, 0x2d, 0x73, 0x75, 0x69, 0x74, 0x65, 0x2f, 0x70, 0x6b, 0x67, 0x2f, 0x74,
-	0x72, 0x61, 0x6e, 0x73, 0x70, 0x6f, 0x72, 0x74, 0x2f, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65,
-	0x73, 0x2f, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x52, 0x06, 0x77, 0x65, 0x62, 0x55, 0x52, 0x4c, 0x12, 0x2f, 0x0a, 0x0c, 0x63, 0x61, 0x70, 0x61,
+	0x62, 0x69, 0x6c, 0x69, 0x74, 0x69, 0x65, 0x73, 0x18, 0x05, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0b,
+	0x2e, 0x43, 0x61, 0x70, 0x61, 0x62, 0x69, 0x6c, 0x69, 0x74, 0x79, 0x52, 0x0c, 0x63, 0x61, 0x70,
+	0x61, 0x62, 0x69, 0x6c, 0x69, 0x74, 0x69, 0x65, 0x73, 0x12, 0x23, 0x0a, 0x07, 0x61, 0x70, 0x70,
+	0x49, 0x6e, 0x66, 0x6f, 0x18, 0xe8, 0x07, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x08, 0x2e, 0x41, 0x70,
+	0x70, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x07, 0x61, 0x70, 0x70, 0x49, 0x6e, 0x66, 0x6f, 0x22, 0x3e,
+	0x0a, 0x0a, 0x43, 0x61, 0x70, 0x61, 0x62, 0x69, 0x6c, 0x69, 0x74, 0x79, 0x12, 0x14, 0x0a, 0x05,
+	0x74, 0x6f, 0x70, 0x69, 0x63, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x74, 0x6f, 0x70,
+	0x69, 0x63, 0x12, 0x1a, 0x0a, 0x08, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x02,
+	0x20, 0x03, 0x28, 0x0d, 0x52, 0x08, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x22, 0x97,
+	0x01, 0x0a, 0x08, 0x45, 0x6e, 0x76, 0x65, 0x6c, 0x6f, 0x70, 0x65, 0x12, 0x2d, 0x0a, 0x08, 0x70,
+	0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x11, 0x2e,
+	0x45, 0x6e, 0x76, 0x65, 0x6c, 0x6f, 0x70, 0x65, 0x2e, 0x50, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64,
+	0x52, 0x08, 0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x73, 0x12, 0x23, 0x0a, 0x07, 0x61, 0x70,
+	0x70, 0x49, 0x6e, 0x66, 0x6f, 0x18, 0xe8, 0x07, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x08, 0x2e, 0x41,
+	0x70, 0x70, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x07, 0x61, 0x70, 0x70, 0x49, 0x6e, 0x66, 0x6f, 0x1a,
+	0x37, 0x0a, 0x07, 0x50, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65,
+	0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x07, 0x76, 0x65, 0x72,
+	0x73, 0x69, 0x6f, 0x6e, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x0c, 0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x42, 0x45, 0x5a, 0x43, 0x67, 0x69, 0x74, 0x68,
+	0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x68, 0x72, 0x6f, 0x6e, 0x69, 0x63, 0x6c, 0x65,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x2f, 0x6f, 0x72, 0x61, 0x63, 0x6c, 0x65, 0x2d,
+	0x73, 0x75, 0x69, 0x74, 0x65, 0x2f, 0x70, 0x6b, 0x67, 0x2f, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x70,
+	0x6f, 0x72, 0x74, 0x2f, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x73, 0x2f, 0x70, 0x62, 0x62,
+	0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -1325,7 +1515,7 @@ func file_transport_proto_rawDescGZIP() []byte {
 	return file_transport_proto_rawDescData
 }
 
-var file_transport_proto_msgTypes = make([]protoimpl.MessageInfo, 16)
+var file_transport_proto_msgTypes = make([]protoimpl.MessageInfo, 19)
 var file_transport_proto_goTypes = []interface{}{
 	(*AppInfo)(nil),                      // 0: AppInfo
 	(*Price)(nil),                        // 1: Price
@@ -1341,18 +1531,21 @@ var file_transport_proto_goTypes = []interface{}{
 	(*MuSigPartialSignatureMessage)(nil), // 11: MuSigPartialSignatureMessage
 	(*MuSigSignatureMessage)(nil),        // 12: MuSigSignatureMessage
 	(*Greet)(nil),                        // 13: Greet
-	nil,                                  // 14: DataPoint.MetaEntry
-	(*MuSigMetaTickV1_FeedTick)(nil),     // 15: MuSigMetaTickV1.FeedTick
+	(*Capability)(nil),                   // 14: Capability
+	(*Envelope)(nil),                     // 15: Envelope
+	nil,                                  // 16: DataPoint.MetaEntry
+	(*MuSigMetaTickV1_FeedTick)(nil),     // 17: MuSigMetaTickV1.FeedTick
+	(*Envelope_Payload)(nil),             // 18: Envelope.Payload
 }
 var file_transport_proto_depIdxs = []int32{
 	3,  // 0: DataPointValue.tick:type_name -> DataPointTickValue
 	2,  // 1: DataPoint.value:type_name -> DataPointValue
 	4,  // 2: DataPoint.subPoints:type_name -> DataPoint
-	14, // 3: DataPoint.meta:type_name -> DataPoint.MetaEntry
+	16, // 3: DataPoint.meta:type_name -> DataPoint.MetaEntry
 	4,  // 4: DataPointMessage.dataPoint:type_name -> DataPoint
 	0,  // 5: DataPointMessage.appInfo:type_name -> AppInfo
 	7,  // 6: MuSigMeta.ticks:type_name -> MuSigMetaTickV1
-	15, // 7: MuSigMetaTickV1.ticks:type_name -> MuSigMetaTickV1.FeedTick
+	17, // 7: MuSigMetaTickV1.ticks:type_name -> MuSigMetaTickV1.FeedTick
 	6,  // 8: MuSigInitializeMessage.msgMeta:type_name -> MuSigMeta
 	0,  // 9: MuSigInitializeMessage.appInfo:type_name -> AppInfo
 	0,  // 10: MuSigTerminateMessage.appInfo:type_name -> AppInfo
@@ -1360,12 +1553,15 @@ var file_transport_proto_depIdxs = []int32{
 	0,  // 12: MuSigPartialSignatureMessage.appInfo:type_name -> AppInfo
 	6,  // 13: MuSigSignatureMessage.msgMeta:type_name -> MuSigMeta
 	0,  // 14: MuSigSignatureMessage.appInfo:type_name -> AppInfo
-	0,  // 15: Greet.appInfo:type_name -> AppInfo
-	16, // [16:16] is the sub-list for method output_type
-	16, // [16:16] is the sub-list for method input_type
-	16, // [16:16] is the sub-list for extension type_name
-	16, // [16:16] is the sub-list for extension extendee
-	0,  // [0:16] is the sub-list for field type_name
+	14, // 15: Greet.capabilities:type_name -> Capability
+	0,  // 16: Greet.appInfo:type_name -> AppInfo
+	18, // 17: Envelope.payloads:type_name -> Envelope.Payload
+	0,  // 18: Envelope.appInfo:type_name -> AppInfo
+	19, // [19:19] is the sub-list for method output_type
+	19, // [19:19] is the sub-list for method input_type
+	19, // [19:19] is the sub-list for extension type_name
+	19, // [19:19] is the sub-list for extension extendee
+	0,  // [0:19] is the sub-list for field type_name
 }
 
 func init() { file_transport_proto_init() }
@@ -1542,7 +1738,31 @@ func file_transport_proto_init() {
 				return nil
 			}
 		}
+		file_transport_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Capability); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 		file_transport_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Envelope); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_transport_proto_msgTypes[17].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*MuSigMetaTickV1_FeedTick); i {
 			case 0:
 				return &v.state
@@ -1554,6 +1774,18 @@ func file_transport_proto_init() {
 				return nil
 			}
 		}
+		file_transport_proto_msgTypes[18].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Envelope_Payload); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	file_transport_proto_msgTypes[2].OneofWrappers = []interface{}{
 		(*DataPointValue_Static)(nil),
@@ -1571,7 +1803,7 @@ func file_transport_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_transport_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   16,
+			NumMessages:   19,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
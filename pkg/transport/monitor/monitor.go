@@ -0,0 +1,205 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/orcfax/oracle-suite/pkg/httpserver"
+	"github.com/orcfax/oracle-suite/pkg/log"
+	"github.com/orcfax/oracle-suite/pkg/log/null"
+	"github.com/orcfax/oracle-suite/pkg/metrics"
+	"github.com/orcfax/oracle-suite/pkg/supervisor"
+	"github.com/orcfax/oracle-suite/pkg/transport"
+	"github.com/orcfax/oracle-suite/pkg/transport/messages"
+	"github.com/orcfax/oracle-suite/pkg/util/chanutil"
+)
+
+const LoggerTag = "TRANSPORT_MONITOR"
+
+// Names of metrics collected by the Monitor.
+const (
+	MessagesSentMetric     = "transport_messages_sent_total"
+	BroadcastErrorsMetric  = "transport_broadcast_errors_total"
+	MessagesReceivedMetric = "transport_messages_received_total"
+	MessagesRejectedMetric = "transport_messages_rejected_total"
+	MessageLatencyMetric   = "transport_message_latency_seconds"
+)
+
+// Monitor collects metrics of messages sent and received by the transport.
+//
+// The following metrics are collected:
+//   - number of broadcast messages and broadcast errors per topic,
+//   - number of received messages per topic and transport,
+//   - number of received messages that could not be decoded per topic,
+//   - end-to-end latency of data point messages, calculated as the
+//     difference between the time of receipt and the data point time.
+//
+// Only messages read from channels returned by the Messages method are
+// counted.
+type Monitor struct {
+	mu     sync.Mutex
+	ctx    context.Context
+	waitCh chan error
+
+	transport transport.Service
+	registry  metrics.Registry
+	server    httpserver.Service
+	msgFO     map[string]*chanutil.FanOut[transport.ReceivedMessage]
+	log       log.Logger
+}
+
+// Config is the configuration for the Monitor.
+type Config struct {
+	// Transport is the transport to monitor.
+	Transport transport.Service
+
+	// Registry is the registry to which metrics are reported.
+	Registry metrics.Registry
+
+	// Server is an optional HTTP server used to expose metrics. It is
+	// started and stopped together with the transport.
+	Server httpserver.Service
+
+	// Logger is a current logger interface used by the Monitor.
+	// If nil, null logger will be used.
+	Logger log.Logger
+}
+
+// New creates a new Monitor.
+func New(cfg Config) (*Monitor, error) {
+	if cfg.Transport == nil {
+		return nil, errors.New("transport must not be nil")
+	}
+	if cfg.Registry == nil {
+		return nil, errors.New("registry must not be nil")
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = null.New()
+	}
+	return &Monitor{
+		waitCh:    make(chan error),
+		transport: cfg.Transport,
+		registry:  cfg.Registry,
+		server:    cfg.Server,
+		msgFO:     make(map[string]*chanutil.FanOut[transport.ReceivedMessage]),
+		log:       cfg.Logger.WithField("tag", LoggerTag),
+	}, nil
+}
+
+// Start implements the supervisor.Service interface.
+func (m *Monitor) Start(ctx context.Context) error {
+	if m.ctx != nil {
+		return errors.New("service can be started only once")
+	}
+	if ctx == nil {
+		return errors.New("context must not be nil")
+	}
+	m.log.Debug("Starting")
+	if err := m.transport.Start(ctx); err != nil {
+		return err
+	}
+	if m.server != nil {
+		if err := m.server.Start(ctx); err != nil {
+			return err
+		}
+		m.log.
+			WithField("addr", m.server.Addr()).
+			Info("Metrics server started")
+	}
+	m.ctx = ctx
+	go m.contextCancelHandler()
+	return nil
+}
+
+// Wait implements the supervisor.Service interface.
+func (m *Monitor) Wait() <-chan error {
+	return m.waitCh
+}
+
+// Broadcast implements the transport.Transport interface.
+func (m *Monitor) Broadcast(topic string, message transport.Message) error {
+	err := m.transport.Broadcast(topic, message)
+	if err != nil {
+		m.registry.Add(BroadcastErrorsMetric, metrics.Labels{"topic": topic}, 1)
+		return err
+	}
+	m.registry.Add(MessagesSentMetric, metrics.Labels{"topic": topic}, 1)
+	return nil
+}
+
+// Messages implements the transport.Transport interface.
+func (m *Monitor) Messages(topic string) <-chan transport.ReceivedMessage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if fo, ok := m.msgFO[topic]; ok {
+		return fo.Chan()
+	}
+	in := m.transport.Messages(topic)
+	if in == nil {
+		// It is possible that the underlying transport does not support
+		// given topic. In such case, it will return nil.
+		return nil
+	}
+	out := make(chan transport.ReceivedMessage)
+	go m.collectRoutine(topic, in, out)
+	fo := chanutil.NewFanOut[transport.ReceivedMessage](out)
+	m.msgFO[topic] = fo
+	return fo.Chan()
+}
+
+// ServiceName implements the supervisor.WithName interface.
+func (m *Monitor) ServiceName() string {
+	return fmt.Sprintf("Monitor(%s)", supervisor.ServiceName(m.transport))
+}
+
+func (m *Monitor) collectRoutine(topic string, in <-chan transport.ReceivedMessage, out chan<- transport.ReceivedMessage) {
+	defer close(out)
+	for msg := range in {
+		m.collect(topic, msg)
+		out <- msg
+	}
+}
+
+func (m *Monitor) collect(topic string, msg transport.ReceivedMessage) {
+	if msg.Error != nil {
+		m.registry.Add(MessagesRejectedMetric, metrics.Labels{"topic": topic, "reason": "invalid message"}, 1)
+		return
+	}
+	m.registry.Add(MessagesReceivedMetric, metrics.Labels{"topic": topic, "transport": msg.Meta.Transport}, 1)
+	if dp, ok := msg.Message.(*messages.DataPoint); ok && !dp.Point.Time.IsZero() {
+		m.registry.Observe(MessageLatencyMetric, metrics.Labels{"topic": topic}, time.Since(dp.Point.Time).Seconds())
+	}
+}
+
+// contextCancelHandler handles context cancellation.
+func (m *Monitor) contextCancelHandler() {
+	defer func() { close(m.waitCh) }()
+	defer m.log.Debug("Stopped")
+	err := <-m.transport.Wait()
+	if m.server != nil {
+		if srvErr := <-m.server.Wait(); err == nil {
+			err = srvErr
+		}
+	}
+	if err != nil {
+		m.waitCh <- err
+	}
+}
@@ -0,0 +1,99 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package monitor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/orcfax/oracle-suite/pkg/datapoint"
+	"github.com/orcfax/oracle-suite/pkg/datapoint/value"
+	"github.com/orcfax/oracle-suite/pkg/metrics"
+	"github.com/orcfax/oracle-suite/pkg/transport"
+	"github.com/orcfax/oracle-suite/pkg/transport/local"
+	"github.com/orcfax/oracle-suite/pkg/transport/messages"
+	"github.com/orcfax/oracle-suite/pkg/util/bn"
+)
+
+type invalidMsg struct{}
+
+func (invalidMsg) MarshallBinary() ([]byte, error) {
+	return []byte{}, nil
+}
+
+func (*invalidMsg) UnmarshallBinary([]byte) error {
+	return errors.New("invalid message")
+}
+
+func TestMonitor(t *testing.T) {
+	ctx, ctxCancel := context.WithCancel(context.Background())
+	defer ctxCancel()
+
+	registry := metrics.NewMemory(nil)
+	m, err := New(Config{
+		Transport: local.New([]byte("test"), 1, map[string]transport.Message{
+			"dp":      (*messages.DataPoint)(nil),
+			"invalid": (*invalidMsg)(nil),
+		}),
+		Registry: registry,
+	})
+	require.NoError(t, err)
+	require.NoError(t, m.Start(ctx))
+
+	// Channels returned for the same topic must share counters.
+	dpCh1 := m.Messages("dp")
+	dpCh2 := m.Messages("dp")
+	invalidCh := m.Messages("invalid")
+	assert.Nil(t, m.Messages("unknown"))
+
+	require.NoError(t, m.Broadcast("dp", &messages.DataPoint{
+		Model: "ETH/USD",
+		Point: datapoint.Point{
+			Value: value.StaticValue{Value: bn.DecFloatPoint(1)},
+			Time:  time.Now().Add(-10 * time.Second),
+		},
+	}))
+	require.NoError(t, m.Broadcast("invalid", &invalidMsg{}))
+	require.Error(t, m.Broadcast("unknown", &invalidMsg{}))
+
+	msg := <-dpCh1
+	require.NoError(t, msg.Error)
+	msg = <-dpCh2
+	require.NoError(t, msg.Error)
+	msg = <-invalidCh
+	require.Error(t, msg.Error)
+
+	v, _ := registry.Value(MessagesSentMetric, metrics.Labels{"topic": "dp"})
+	assert.Equal(t, float64(1), v)
+	v, _ = registry.Value(MessagesSentMetric, metrics.Labels{"topic": "invalid"})
+	assert.Equal(t, float64(1), v)
+	v, _ = registry.Value(BroadcastErrorsMetric, metrics.Labels{"topic": "unknown"})
+	assert.Equal(t, float64(1), v)
+	v, _ = registry.Value(MessagesReceivedMetric, metrics.Labels{"topic": "dp", "transport": local.TransportName})
+	assert.Equal(t, float64(1), v)
+	v, _ = registry.Value(MessagesRejectedMetric, metrics.Labels{"topic": "invalid", "reason": "invalid message"})
+	assert.Equal(t, float64(1), v)
+	v, _ = registry.Value(MessageLatencyMetric, metrics.Labels{"topic": "dp"})
+	assert.GreaterOrEqual(t, v, float64(9))
+
+	ctxCancel()
+	assert.NoError(t, <-m.Wait())
+}
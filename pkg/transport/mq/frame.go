@@ -0,0 +1,195 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package mq
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/defiweb/go-eth/crypto"
+	"github.com/defiweb/go-eth/types"
+	"github.com/defiweb/go-eth/wallet"
+)
+
+// Frame types of the protocol.
+const (
+	frameHello   byte = 1 // Sent by the producer after connecting to the consumer.
+	frameWelcome byte = 2 // Response to the hello frame sent by the consumer.
+	frameBatch   byte = 3 // Batch of records sent by the producer.
+	frameAck     byte = 4 // Acknowledgement of the batch sent by the consumer.
+)
+
+const (
+	// maxFrameSize is the maximum size of a single frame.
+	maxFrameSize = 16 << 20 // 16 MiB
+
+	// helloRandSize is the size of the random value in the hello frame.
+	helloRandSize = 16
+)
+
+var errInvalidFrame = errors.New("invalid frame")
+
+// hello is the first frame sent by the producer. It is used to authenticate
+// the producer.
+type hello struct {
+	Time      time.Time       // Time of the connection.
+	Rand      []byte          // Random value.
+	Last      uint64          // Offset of the last record in the producer journal.
+	Signature types.Signature // Signature of the above fields.
+}
+
+func (h hello) signingData() []byte {
+	var b []byte
+	b = append(b, "mq"...)
+	b = binary.BigEndian.AppendUint64(b, uint64(h.Time.UnixNano()))
+	b = append(b, h.Rand...)
+	b = binary.BigEndian.AppendUint64(b, h.Last)
+	return b
+}
+
+// newHello creates a new hello frame signed by the signer.
+func newHello(signer wallet.Key, t time.Time, last uint64, rand io.Reader) (hello, error) {
+	h := hello{Time: time.Unix(0, t.UnixNano()), Rand: make([]byte, helloRandSize), Last: last}
+	if _, err := io.ReadFull(rand, h.Rand); err != nil {
+		return hello{}, err
+	}
+	sig, err := signer.SignMessage(h.signingData())
+	if err != nil {
+		return hello{}, err
+	}
+	h.Signature = *sig
+	return h, nil
+}
+
+// verify returns the address of the hello frame signer.
+func (h hello) verify(recover crypto.Recoverer) (*types.Address, error) {
+	return recover.RecoverMessage(h.signingData(), h.Signature)
+}
+
+func (h hello) encode() []byte {
+	sig := h.Signature.Bytes()
+	b := make([]byte, 0, 8+helloRandSize+8+len(sig))
+	b = binary.BigEndian.AppendUint64(b, uint64(h.Time.UnixNano()))
+	b = append(b, h.Rand...)
+	b = binary.BigEndian.AppendUint64(b, h.Last)
+	b = append(b, sig...)
+	return b
+}
+
+func decodeHello(b []byte) (hello, error) {
+	if len(b) <= 8+helloRandSize+8 {
+		return hello{}, errInvalidFrame
+	}
+	sig, err := types.SignatureFromBytes(b[8+helloRandSize+8:])
+	if err != nil {
+		return hello{}, errInvalidFrame
+	}
+	return hello{
+		Time:      time.Unix(0, int64(binary.BigEndian.Uint64(b[:8]))),
+		Rand:      append([]byte{}, b[8:8+helloRandSize]...),
+		Last:      binary.BigEndian.Uint64(b[8+helloRandSize:]),
+		Signature: sig,
+	}, nil
+}
+
+// encodeOffset encodes the payload of the welcome and ack frames.
+func encodeOffset(offset uint64) []byte {
+	return binary.BigEndian.AppendUint64(nil, offset)
+}
+
+// decodeOffset decodes the payload of the welcome and ack frames.
+func decodeOffset(b []byte) (uint64, error) {
+	if len(b) != 8 {
+		return 0, errInvalidFrame
+	}
+	return binary.BigEndian.Uint64(b), nil
+}
+
+// encodeBatch encodes the payload of the batch frame.
+func encodeBatch(records []Record) []byte {
+	var b []byte
+	b = binary.BigEndian.AppendUint32(b, uint32(len(records)))
+	for _, r := range records {
+		rec := encodeRecord(r)
+		b = binary.BigEndian.AppendUint32(b, uint32(len(rec)))
+		b = append(b, rec...)
+	}
+	return b
+}
+
+// decodeBatch decodes the payload of the batch frame.
+func decodeBatch(b []byte) ([]Record, error) {
+	if len(b) < 4 {
+		return nil, errInvalidFrame
+	}
+	n := binary.BigEndian.Uint32(b)
+	b = b[4:]
+	records := make([]Record, 0, min(n, 1024))
+	for i := uint32(0); i < n; i++ {
+		if len(b) < 4 {
+			return nil, errInvalidFrame
+		}
+		size := int(binary.BigEndian.Uint32(b))
+		b = b[4:]
+		if len(b) < size {
+			return nil, errInvalidFrame
+		}
+		r, err := decodeRecord(b[:size])
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+		b = b[size:]
+	}
+	if len(b) != 0 {
+		return nil, errInvalidFrame
+	}
+	return records, nil
+}
+
+// writeFrame writes a frame as its length, type and payload.
+func writeFrame(w io.Writer, typ byte, payload []byte) error {
+	b := make([]byte, 0, 5+len(payload))
+	b = binary.BigEndian.AppendUint32(b, uint32(len(payload)+1))
+	b = append(b, typ)
+	b = append(b, payload...)
+	_, err := w.Write(b)
+	return err
+}
+
+// readFrame reads a frame written by writeFrame. It returns an error if
+// the frame type is different from the expected one.
+func readFrame(r io.Reader, typ byte) ([]byte, error) {
+	var head [4]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return nil, err
+	}
+	size := binary.BigEndian.Uint32(head[:])
+	if size == 0 || size > maxFrameSize {
+		return nil, errInvalidFrame
+	}
+	b := make([]byte, size)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+	if b[0] != typ {
+		return nil, fmt.Errorf("unexpected frame type %d, expected %d", b[0], typ)
+	}
+	return b[1:], nil
+}
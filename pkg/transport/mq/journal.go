@@ -0,0 +1,425 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package mq
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
+)
+
+const (
+	// journalLogExt is the extension of the file with journal records.
+	journalLogExt = ".log"
+
+	// journalOffsetsExt is the extension of the file with consumer offsets.
+	journalOffsetsExt = ".offsets"
+
+	// maxRecordSize is the maximum size of an encoded record.
+	maxRecordSize = 1 << 20 // 1 MiB
+)
+
+var errCorruptedRecord = errors.New("corrupted record")
+
+// Record is a single entry in the journal.
+type Record struct {
+	// Offset is the position of the record in the journal. It is assigned
+	// by the journal when the record is appended. Offsets start from 1.
+	Offset uint64
+
+	// Origin is the offset of the record in the journal of the producer.
+	// It is zero for records appended by the producer itself.
+	Origin uint64
+
+	// Time is the time when the record was created by the producer.
+	Time time.Time
+
+	// Topic is the topic of the message.
+	Topic string
+
+	// Author is the address of the message producer.
+	Author types.Address
+
+	// Signature is the signature of the record created by the producer.
+	Signature types.Signature
+
+	// Data is the binary representation of the message.
+	Data []byte
+}
+
+// signingData returns the data signed by the producer.
+func (r Record) signingData() []byte {
+	var b []byte
+	b = binary.BigEndian.AppendUint64(b, uint64(r.Time.UnixNano()))
+	b = append(b, r.Topic...)
+	b = append(b, 0)
+	b = append(b, r.Data...)
+	return b
+}
+
+// Journal is an append-only log of records persisted in a file.
+//
+// Besides the records, the journal stores offsets of its consumers. The
+// offsets are used to track which records were already processed, so
+// processing can be resumed after a restart.
+//
+// Each record is stored as its length, the encoded record and the CRC32
+// checksum. If the application is terminated during writing, the incomplete
+// record at the end of the file is discarded when the journal is opened.
+type Journal struct {
+	mu         sync.RWMutex
+	path       string
+	file       *os.File
+	size       int64
+	positions  []int64 // Positions of records in the file, starting from the first one.
+	first      uint64  // Offset of the first record in the journal.
+	offsets    map[string]uint64
+	maxRecords int
+	notifyCh   chan struct{}
+}
+
+// OpenJournal opens or creates a journal at the given path. The path is
+// used as a prefix for the journal files.
+//
+// If maxRecords is greater than zero, the journal keeps at least the last
+// maxRecords records and older ones are periodically removed.
+func OpenJournal(path string, maxRecords int) (*Journal, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("mq: unable to create journal directory: %w", err)
+	}
+	j := &Journal{
+		path:       path,
+		first:      1,
+		offsets:    make(map[string]uint64),
+		maxRecords: maxRecords,
+		notifyCh:   make(chan struct{}),
+	}
+	if err := j.loadOffsets(); err != nil {
+		return nil, err
+	}
+	if err := j.loadRecords(); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// Close closes the journal file.
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.file.Close()
+}
+
+// Append appends a record to the journal and returns its offset.
+func (j *Journal) Append(r Record) (uint64, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	r.Offset = j.first + uint64(len(j.positions))
+	n, err := writeRecord(j.file, r)
+	if err != nil {
+		return 0, fmt.Errorf("mq: unable to write journal record: %w", err)
+	}
+	if err := j.file.Sync(); err != nil {
+		return 0, fmt.Errorf("mq: unable to sync journal: %w", err)
+	}
+	j.positions = append(j.positions, j.size)
+	j.size += n
+	if j.maxRecords > 0 && len(j.positions) >= 2*j.maxRecords {
+		if err := j.compact(); err != nil {
+			return 0, err
+		}
+	}
+	close(j.notifyCh)
+	j.notifyCh = make(chan struct{})
+	return r.Offset, nil
+}
+
+// Read returns up to limit records starting from the given offset. If the
+// offset is older than the oldest record in the journal, records are
+// returned starting from the oldest one.
+func (j *Journal) Read(from uint64, limit int) ([]Record, error) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	if from < j.first {
+		from = j.first
+	}
+	var records []Record
+	for i := int(from - j.first); i < len(j.positions) && len(records) < limit; i++ {
+		r, _, err := readRecord(io.NewSectionReader(j.file, j.positions[i], j.size-j.positions[i]))
+		if err != nil {
+			return nil, fmt.Errorf("mq: unable to read journal record: %w", err)
+		}
+		records = append(records, r)
+	}
+	return records, nil
+}
+
+// Last returns the offset of the last appended record or zero if no records
+// were appended yet.
+func (j *Journal) Last() uint64 {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return j.first + uint64(len(j.positions)) - 1
+}
+
+// Notify returns a channel that is closed when a new record is appended.
+func (j *Journal) Notify() <-chan struct{} {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return j.notifyCh
+}
+
+// Offset returns the offset stored for the given consumer or zero if there
+// is no offset for the consumer.
+func (j *Journal) Offset(consumer string) uint64 {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return j.offsets[consumer]
+}
+
+// SetOffset stores the offset for the given consumer.
+func (j *Journal) SetOffset(consumer string, offset uint64) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.offsets[consumer] == offset {
+		return nil
+	}
+	j.offsets[consumer] = offset
+	return j.saveOffsets()
+}
+
+func (j *Journal) loadOffsets() error {
+	b, err := os.ReadFile(j.path + journalOffsetsExt)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("mq: unable to read journal offsets: %w", err)
+	}
+	if err := json.Unmarshal(b, &j.offsets); err != nil {
+		return fmt.Errorf("mq: unable to decode journal offsets: %w", err)
+	}
+	return nil
+}
+
+// saveOffsets writes the offsets to a temporary file which then replaces
+// the current one, so the offsets file is never partially written.
+func (j *Journal) saveOffsets() error {
+	b, err := json.Marshal(j.offsets)
+	if err != nil {
+		return fmt.Errorf("mq: unable to encode journal offsets: %w", err)
+	}
+	if err := writeFileAtomic(j.path+journalOffsetsExt, b); err != nil {
+		return fmt.Errorf("mq: unable to write journal offsets: %w", err)
+	}
+	return nil
+}
+
+// loadRecords opens the journal file and builds the index of records.
+// Incomplete or corrupted records at the end of the file are discarded.
+func (j *Journal) loadRecords() error {
+	f, err := os.OpenFile(j.path+journalLogExt, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("mq: unable to open journal: %w", err)
+	}
+	var (
+		pos int64
+		br  = bufio.NewReader(f)
+	)
+	for {
+		r, n, err := readRecord(br)
+		if err != nil {
+			break
+		}
+		if len(j.positions) == 0 {
+			j.first = r.Offset
+		}
+		if r.Offset != j.first+uint64(len(j.positions)) {
+			break
+		}
+		j.positions = append(j.positions, pos)
+		pos += n
+	}
+	if err := f.Truncate(pos); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("mq: unable to truncate journal: %w", err)
+	}
+	if _, err := f.Seek(pos, io.SeekStart); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("mq: unable to seek journal: %w", err)
+	}
+	// If all records were removed, the offsets must continue from the last
+	// offset known by consumers.
+	if len(j.positions) == 0 {
+		for _, o := range j.offsets {
+			if o >= j.first {
+				j.first = o + 1
+			}
+		}
+	}
+	j.file = f
+	j.size = pos
+	return nil
+}
+
+// compact removes the oldest records, keeping the last maxRecords records.
+// The caller must hold the write lock.
+func (j *Journal) compact() error {
+	keep := j.positions[len(j.positions)-j.maxRecords:]
+	tmpPath := j.path + journalLogExt + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("mq: unable to compact journal: %w", err)
+	}
+	if _, err := io.Copy(tmp, io.NewSectionReader(j.file, keep[0], j.size-keep[0])); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("mq: unable to compact journal: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("mq: unable to compact journal: %w", err)
+	}
+	if err := os.Rename(tmpPath, j.path+journalLogExt); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("mq: unable to compact journal: %w", err)
+	}
+	_ = j.file.Close()
+	shift := keep[0]
+	positions := make([]int64, len(keep))
+	for i, p := range keep {
+		positions[i] = p - shift
+	}
+	j.first += uint64(len(j.positions) - len(keep))
+	j.positions = positions
+	j.size -= shift
+	j.file = tmp
+	return nil
+}
+
+// writeRecord writes the record as its length, the encoded record and
+// the CRC32 checksum. It returns the number of written bytes.
+func writeRecord(w io.Writer, r Record) (int64, error) {
+	payload := encodeRecord(r)
+	b := make([]byte, 0, len(payload)+8)
+	b = binary.BigEndian.AppendUint32(b, uint32(len(payload)))
+	b = append(b, payload...)
+	b = binary.BigEndian.AppendUint32(b, crc32.ChecksumIEEE(payload))
+	n, err := w.Write(b)
+	return int64(n), err
+}
+
+// readRecord reads a record written by writeRecord. It returns the number
+// of read bytes.
+func readRecord(r io.Reader) (Record, int64, error) {
+	var head [4]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return Record{}, 0, err
+	}
+	size := binary.BigEndian.Uint32(head[:])
+	if size > maxRecordSize {
+		return Record{}, 0, errCorruptedRecord
+	}
+	b := make([]byte, size+4)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return Record{}, 0, err
+	}
+	payload := b[:size]
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(b[size:]) {
+		return Record{}, 0, errCorruptedRecord
+	}
+	rec, err := decodeRecord(payload)
+	if err != nil {
+		return Record{}, 0, err
+	}
+	return rec, int64(size) + 8, nil
+}
+
+// encodeRecord encodes the record into a binary form.
+func encodeRecord(r Record) []byte {
+	sig := r.Signature.Bytes()
+	b := make([]byte, 0, 8+8+8+types.AddressLength+1+len(sig)+2+len(r.Topic)+4+len(r.Data))
+	b = binary.BigEndian.AppendUint64(b, r.Offset)
+	b = binary.BigEndian.AppendUint64(b, r.Origin)
+	b = binary.BigEndian.AppendUint64(b, uint64(r.Time.UnixNano()))
+	b = append(b, r.Author.Bytes()...)
+	b = append(b, uint8(len(sig)))
+	b = append(b, sig...)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(r.Topic)))
+	b = append(b, r.Topic...)
+	b = binary.BigEndian.AppendUint32(b, uint32(len(r.Data)))
+	b = append(b, r.Data...)
+	return b
+}
+
+// decodeRecord decodes the record encoded by encodeRecord.
+func decodeRecord(b []byte) (Record, error) {
+	const fixedSize = 8 + 8 + 8 + types.AddressLength + 1
+	var r Record
+	if len(b) < fixedSize {
+		return r, errCorruptedRecord
+	}
+	r.Offset = binary.BigEndian.Uint64(b[0:8])
+	r.Origin = binary.BigEndian.Uint64(b[8:16])
+	r.Time = time.Unix(0, int64(binary.BigEndian.Uint64(b[16:24])))
+	b = b[24:]
+	r.Author = types.MustAddressFromBytes(b[:types.AddressLength])
+	b = b[types.AddressLength:]
+	sigLen := int(b[0])
+	b = b[1:]
+	if len(b) < sigLen+2 {
+		return r, errCorruptedRecord
+	}
+	sig, err := types.SignatureFromBytes(b[:sigLen])
+	if err != nil {
+		return r, errCorruptedRecord
+	}
+	r.Signature = sig
+	b = b[sigLen:]
+	topicLen := int(binary.BigEndian.Uint16(b[:2]))
+	b = b[2:]
+	if len(b) < topicLen+4 {
+		return r, errCorruptedRecord
+	}
+	r.Topic = string(b[:topicLen])
+	b = b[topicLen:]
+	dataLen := int(binary.BigEndian.Uint32(b[:4]))
+	b = b[4:]
+	if len(b) != dataLen {
+		return r, errCorruptedRecord
+	}
+	r.Data = append([]byte{}, b...)
+	return r, nil
+}
+
+// writeFileAtomic writes data to a temporary file and renames it to the
+// given path.
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,147 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package mq
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testRecord(topic, data string) Record {
+	return Record{
+		Time:      time.Unix(1700000000, 0),
+		Topic:     topic,
+		Author:    types.MustAddressFromHex("0x1234567890123456789012345678901234567890"),
+		Signature: types.MustSignatureFromBytes(append(make([]byte, 64), 27)),
+		Data:      []byte(data),
+	}
+}
+
+func TestJournal_AppendRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal")
+	j, err := OpenJournal(path, 0)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), j.Last())
+
+	for _, d := range []string{"a", "b", "c"} {
+		_, err := j.Append(testRecord("test", d))
+		require.NoError(t, err)
+	}
+	assert.Equal(t, uint64(3), j.Last())
+
+	records, err := j.Read(2, 10)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, uint64(2), records[0].Offset)
+	assert.Equal(t, []byte("b"), records[0].Data)
+	assert.Equal(t, "test", records[0].Topic)
+	assert.Equal(t, testRecord("", "").Author, records[0].Author)
+	assert.Equal(t, testRecord("", "").Signature, records[0].Signature)
+	assert.True(t, testRecord("", "").Time.Equal(records[0].Time))
+	assert.Equal(t, uint64(3), records[1].Offset)
+
+	records, err = j.Read(1, 1)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, []byte("a"), records[0].Data)
+
+	records, err = j.Read(4, 10)
+	require.NoError(t, err)
+	assert.Empty(t, records)
+}
+
+func TestJournal_Reopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal")
+	j, err := OpenJournal(path, 0)
+	require.NoError(t, err)
+	_, err = j.Append(testRecord("test", "a"))
+	require.NoError(t, err)
+	_, err = j.Append(testRecord("test", "b"))
+	require.NoError(t, err)
+	require.NoError(t, j.SetOffset("consumer", 1))
+	require.NoError(t, j.Close())
+
+	// Simulate a crash during writing a record.
+	f, err := os.OpenFile(path+journalLogExt, os.O_APPEND|os.O_WRONLY, 0o600)
+	require.NoError(t, err)
+	_, err = f.Write([]byte{0, 0, 0, 100, 1, 2, 3})
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	j, err = OpenJournal(path, 0)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), j.Last())
+	assert.Equal(t, uint64(1), j.Offset("consumer"))
+	assert.Equal(t, uint64(0), j.Offset("unknown"))
+
+	offset, err := j.Append(testRecord("test", "c"))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), offset)
+	records, err := j.Read(0, 10)
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, []byte("c"), records[2].Data)
+}
+
+func TestJournal_Compact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal")
+	j, err := OpenJournal(path, 2)
+	require.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		_, err := j.Append(testRecord("test", string(rune('a'+i))))
+		require.NoError(t, err)
+	}
+	assert.Equal(t, uint64(5), j.Last())
+
+	// After compaction, old records are removed but offsets are preserved.
+	records, err := j.Read(1, 10)
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, uint64(3), records[0].Offset)
+	assert.Equal(t, []byte("c"), records[0].Data)
+	require.NoError(t, j.Close())
+
+	j, err = OpenJournal(path, 2)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(5), j.Last())
+	offset, err := j.Append(testRecord("test", "f"))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(6), offset)
+}
+
+func TestJournal_Notify(t *testing.T) {
+	j, err := OpenJournal(filepath.Join(t.TempDir(), "journal"), 0)
+	require.NoError(t, err)
+	ch := j.Notify()
+	select {
+	case <-ch:
+		t.Fatal("unexpected notification")
+	default:
+	}
+	_, err = j.Append(testRecord("test", "a"))
+	require.NoError(t, err)
+	select {
+	case <-ch:
+	default:
+		t.Fatal("expected notification")
+	}
+}
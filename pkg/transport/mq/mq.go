@@ -0,0 +1,797 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package mq
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/defiweb/go-eth/crypto"
+	"github.com/defiweb/go-eth/types"
+	"github.com/defiweb/go-eth/wallet"
+
+	"github.com/orcfax/oracle-suite/pkg/log"
+	"github.com/orcfax/oracle-suite/pkg/log/null"
+	"github.com/orcfax/oracle-suite/pkg/transport"
+	"github.com/orcfax/oracle-suite/pkg/util/chanutil"
+	"github.com/orcfax/oracle-suite/pkg/util/maputil"
+	"github.com/orcfax/oracle-suite/pkg/util/sliceutil"
+)
+
+const (
+	LoggerTag     = "MQ"
+	TransportName = "mq"
+
+	// messageChanSize is the size of the message channel. It is used to buffer
+	// messages before they are consumed.
+	messageChanSize = 10000
+
+	// maxBatchRecords is the maximum number of records sent in a single batch.
+	maxBatchRecords = 100
+
+	// deliveredOffset is the name of the offset in the incoming journal that
+	// points to the last record delivered to the message channels.
+	deliveredOffset = "delivered"
+
+	// outgoingJournal and incomingJournal are the names of the journal files.
+	outgoingJournal = "outgoing"
+	incomingJournal = "incoming"
+
+	// defaultMaxJournalRecords is the default number of records kept in
+	// each journal.
+	defaultMaxJournalRecords = 100000
+
+	// defaultMaxClockSkew is the maximum allowed clock skew between the consumer
+	// and the producer.
+	defaultMaxClockSkew = 10 * time.Second
+
+	// defaultTimeout is the default timeout for network operations.
+	defaultTimeout = 60 * time.Second
+
+	// defaultRetryInterval is the default time between reconnection attempts.
+	defaultRetryInterval = 10 * time.Second
+)
+
+// Dialer is used by producers to connect to consumers.
+type Dialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// MQ is a durable transport that stores messages in local journals and
+// delivers them over a framed TCP protocol with acknowledgements. Like
+// WebAPI, it is designed to use over secure network, e.g. Tor, I2P or VPN.
+//
+// Transport involves two main actors: message producers and consumers.
+//
+// Broadcasted messages are signed and appended to the outgoing journal of
+// the producer. For every consumer, the producer stores the offset of the
+// last record acknowledged by the consumer and sends records after that
+// offset. If a consumer is unavailable, records wait in the journal and are
+// sent after the consumer becomes available again.
+//
+// Consumers append received records to the incoming journal before
+// acknowledging them, so acknowledged records survive a restart. Records
+// are delivered to the message channels from the incoming journal. The
+// offset of the last delivered record is stored, so after a restart the
+// records that were not delivered yet are replayed.
+//
+// The protocol uses frames encoded as the length of the frame, the frame
+// type and the payload. After connecting, the producer sends the hello frame
+// with a signed timestamp. The consumer verifies if the producer is on the
+// allowlist and responds with the welcome frame containing the offset of
+// the last record received from the producer. Then, the producer sends
+// batches of records and the consumer acknowledges every batch with the
+// offset of the last stored record. Empty batches are used as heartbeats.
+type MQ struct {
+	mu     sync.RWMutex
+	ctx    context.Context
+	waitCh chan error
+	wg     sync.WaitGroup
+
+	// State fields:
+	outgoing  *Journal                                               // Journal of broadcasted messages.
+	incoming  *Journal                                               // Journal of received messages.
+	listener  net.Listener                                           // Listener for producer connections.
+	conns     map[types.Address]net.Conn                             // Active connections from producers.
+	connsMu   map[types.Address]*sync.Mutex                          // Locks held by connections from producers.
+	lastHello map[types.Address]time.Time                            // Last hello timestamp received from each producer.
+	msgCh     map[string]chan transport.ReceivedMessage              // Channels for received messages.
+	msgChFO   map[string]*chanutil.FanOut[transport.ReceivedMessage] // Fan-out channels for received messages.
+
+	// Configuration fields:
+	listenAddr        string
+	consumers         []string
+	journalPath       string
+	maxJournalRecords int
+	topics            map[string]transport.Message
+	allowlist         []types.Address
+	signer            wallet.Key
+	dialer            Dialer
+	timeout           time.Duration
+	retryInterval     time.Duration
+	maxClockSkew      time.Duration
+	rand              io.Reader
+	log               log.Logger
+	appName           string
+	appVersion        string
+
+	// Internal fields:
+	recover crypto.Recoverer
+}
+
+// Config is a configuration of MQ.
+type Config struct {
+	// ListenAddr is the address on which the consumer listens for
+	// connections from producers. If empty, messages are not received.
+	ListenAddr string
+
+	// Consumers is a list of consumer addresses in the host:port format.
+	// All broadcasted messages are sent to every consumer.
+	Consumers []string
+
+	// JournalPath is the directory where journals are stored.
+	//
+	// Cannot be empty.
+	JournalPath string
+
+	// MaxJournalRecords is the number of the most recent records kept in
+	// each journal. If zero, default value will be used (100000).
+	MaxJournalRecords int
+
+	// Topics is a list of subscribed topics. A value of the map a type of
+	// message given as a nil pointer, e.g.: (*Message)(nil).
+	Topics map[string]transport.Message
+
+	// AuthorAllowlist is a list of allowed message authors. Only messages from
+	// these addresses will be accepted.
+	AuthorAllowlist []types.Address
+
+	// Signer used to sign messages.
+	// If not provided, message broadcast will not be available.
+	Signer wallet.Key
+
+	// Dialer is an optional dialer used to connect to consumers. If not
+	// provided, net.Dialer is used.
+	Dialer Dialer
+
+	// Timeout is a timeout for network operations. Heartbeats are sent
+	// every half of the timeout.
+	//
+	// If timeout is zero, default value will be used (60 seconds).
+	Timeout time.Duration
+
+	// RetryInterval is the time between reconnection attempts to a consumer.
+	//
+	// If zero, default value will be used (10 seconds).
+	RetryInterval time.Duration
+
+	// Rand is an optional random number generator. If not provided, Reader
+	// from crypto/rand package will be used.
+	Rand io.Reader
+
+	// MaxClockSkew is the maximum allowed clock skew between the consumer
+	// and the producer. If not provided, default value will be used (10 seconds).
+	MaxClockSkew time.Duration
+
+	// Logger is a custom logger instance. If not provided then null
+	// logger is used.
+	Logger log.Logger
+
+	// Application info:
+	AppName    string
+	AppVersion string
+}
+
+// New returns a new instance of MQ.
+func New(cfg Config) (*MQ, error) {
+	if cfg.JournalPath == "" {
+		return nil, errors.New("journal path must be provided")
+	}
+	if len(cfg.Consumers) > 0 && cfg.Signer == nil {
+		return nil, errors.New("signer must be provided to send messages to consumers")
+	}
+	if cfg.MaxJournalRecords == 0 {
+		cfg.MaxJournalRecords = defaultMaxJournalRecords
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	if cfg.RetryInterval == 0 {
+		cfg.RetryInterval = defaultRetryInterval
+	}
+	if cfg.MaxClockSkew == 0 {
+		cfg.MaxClockSkew = defaultMaxClockSkew
+	}
+	if cfg.Rand == nil {
+		cfg.Rand = rand.Reader
+	}
+	if cfg.Dialer == nil {
+		cfg.Dialer = &net.Dialer{Timeout: cfg.Timeout}
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = null.New()
+	}
+	return &MQ{
+		waitCh:            make(chan error),
+		conns:             make(map[types.Address]net.Conn),
+		connsMu:           make(map[types.Address]*sync.Mutex),
+		lastHello:         make(map[types.Address]time.Time),
+		msgCh:             make(map[string]chan transport.ReceivedMessage),
+		msgChFO:           make(map[string]*chanutil.FanOut[transport.ReceivedMessage]),
+		listenAddr:        cfg.ListenAddr,
+		consumers:         sliceutil.Copy(cfg.Consumers),
+		journalPath:       cfg.JournalPath,
+		maxJournalRecords: cfg.MaxJournalRecords,
+		topics:            maputil.Copy(cfg.Topics),
+		allowlist:         sliceutil.Copy(cfg.AuthorAllowlist),
+		signer:            cfg.Signer,
+		dialer:            cfg.Dialer,
+		timeout:           cfg.Timeout,
+		retryInterval:     cfg.RetryInterval,
+		maxClockSkew:      cfg.MaxClockSkew,
+		rand:              cfg.Rand,
+		log:               cfg.Logger.WithField("tag", LoggerTag),
+		appName:           cfg.AppName,
+		appVersion:        cfg.AppVersion,
+		recover:           crypto.ECRecoverer,
+	}, nil
+}
+
+// Start implements the supervisor.Supervisor interface.
+func (m *MQ) Start(ctx context.Context) error {
+	if m.ctx != nil {
+		return errors.New("service can be started only once")
+	}
+	if ctx == nil {
+		return errors.New("context must not be nil")
+	}
+	m.log.
+		WithFields(log.Fields{
+			"listenAddr":  m.listenAddr,
+			"journalPath": m.journalPath,
+		}).
+		Debug("Starting")
+	outgoing, err := OpenJournal(filepath.Join(m.journalPath, outgoingJournal), m.maxJournalRecords)
+	if err != nil {
+		return err
+	}
+	incoming, err := OpenJournal(filepath.Join(m.journalPath, incomingJournal), m.maxJournalRecords)
+	if err != nil {
+		_ = outgoing.Close()
+		return err
+	}
+	if m.listenAddr != "" {
+		m.listener, err = net.Listen("tcp", m.listenAddr)
+		if err != nil {
+			_ = outgoing.Close()
+			_ = incoming.Close()
+			return fmt.Errorf("mq: unable to listen on %s: %w", m.listenAddr, err)
+		}
+	}
+	m.mu.Lock()
+	m.ctx = ctx
+	m.outgoing = outgoing
+	m.incoming = incoming
+	for topic := range m.topics {
+		m.msgCh[topic] = make(chan transport.ReceivedMessage, messageChanSize)
+		m.msgChFO[topic] = chanutil.NewFanOut(m.msgCh[topic])
+	}
+	m.mu.Unlock()
+	m.wg.Add(1)
+	go m.deliverRoutine(ctx)
+	if m.listener != nil {
+		m.wg.Add(1)
+		go m.acceptRoutine(ctx)
+	}
+	for _, addr := range m.consumers {
+		m.wg.Add(1)
+		go m.produceRoutine(ctx, addr)
+	}
+	go m.contextCancelHandler()
+	return nil
+}
+
+// Wait implements the supervisor.Supervisor interface.
+func (m *MQ) Wait() <-chan error {
+	return m.waitCh
+}
+
+// Addr returns the address of the listener or nil if the transport does not
+// listen for connections or is not started.
+func (m *MQ) Addr() net.Addr {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.listener == nil {
+		return nil
+	}
+	return m.listener.Addr()
+}
+
+// Broadcast implements the transport.Transport interface.
+//
+// The message is appended to the outgoing journal and sent to consumers
+// asynchronously.
+func (m *MQ) Broadcast(topic string, message transport.Message) error {
+	if m.signer == nil {
+		return fmt.Errorf("unable to broadcast messages: signer is not set")
+	}
+	m.mu.RLock()
+	outgoing := m.outgoing
+	m.mu.RUnlock()
+	if outgoing == nil {
+		return fmt.Errorf("unable to broadcast messages: transport is not started")
+	}
+	if appInfo, ok := message.(transport.WithAppInfo); ok {
+		appInfo.SetAppInfo(transport.AppInfo{
+			Name:    m.appName,
+			Version: m.appVersion,
+		})
+	}
+	m.log.WithField("topic", topic).Debug("Broadcasting message")
+	bin, err := message.MarshallBinary()
+	if err != nil {
+		return err
+	}
+	rec := Record{
+		Time:   time.Now(),
+		Topic:  topic,
+		Author: m.signer.Address(),
+		Data:   bin,
+	}
+	sig, err := m.signer.SignMessage(rec.signingData())
+	if err != nil {
+		return err
+	}
+	rec.Signature = *sig
+	_, err = outgoing.Append(rec)
+	return err
+}
+
+// Messages implements the transport.Transport interface.
+func (m *MQ) Messages(topic string) <-chan transport.ReceivedMessage {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if ch, ok := m.msgChFO[topic]; ok {
+		return ch.Chan()
+	}
+	return nil
+}
+
+// produceRoutine sends records from the outgoing journal to the consumer.
+// If the connection fails, it reconnects after the retry interval.
+func (m *MQ) produceRoutine(ctx context.Context, addr string) {
+	defer m.wg.Done()
+	for {
+		err := m.produce(ctx, addr)
+		if ctx.Err() != nil {
+			return
+		}
+		m.log.
+			WithError(err).
+			WithField("consumer", addr).
+			WithAdvice("Ignore if occurs occasionally, especially if it is related to temporary network issues, messages will be sent after reconnecting"). //nolint:lll
+			Warn("Unable to send messages to consumer")
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(m.retryInterval):
+		}
+	}
+}
+
+// produce connects to the consumer and sends records from the outgoing
+// journal until an error occurs or the context is canceled.
+func (m *MQ) produce(ctx context.Context, addr string) error {
+	conn, err := m.dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	defer context.AfterFunc(ctx, func() { _ = conn.Close() })()
+
+	// Authenticate and read the offset of the last record received by
+	// the consumer.
+	h, err := newHello(m.signer, time.Now(), m.outgoing.Last(), m.rand)
+	if err != nil {
+		return err
+	}
+	if err := conn.SetDeadline(time.Now().Add(m.timeout)); err != nil {
+		return err
+	}
+	if err := writeFrame(conn, frameHello, h.encode()); err != nil {
+		return err
+	}
+	payload, err := readFrame(conn, frameWelcome)
+	if err != nil {
+		return err
+	}
+	received, err := decodeOffset(payload)
+	if err != nil {
+		return err
+	}
+	next := max(m.outgoing.Offset(addr), received) + 1
+	m.log.
+		WithFields(log.Fields{
+			"consumer": addr,
+			"offset":   next,
+			"last":     h.Last,
+		}).
+		Info("Connected to consumer")
+
+	heartbeat := time.NewTicker(m.timeout / 2)
+	defer heartbeat.Stop()
+	for {
+		notifyCh := m.outgoing.Notify()
+		records, err := m.outgoing.Read(next, maxBatchRecords)
+		if err != nil {
+			return err
+		}
+		if len(records) == 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-notifyCh:
+				continue
+			case <-heartbeat.C:
+			}
+		}
+		if err := conn.SetDeadline(time.Now().Add(m.timeout)); err != nil {
+			return err
+		}
+		if err := writeFrame(conn, frameBatch, encodeBatch(records)); err != nil {
+			return err
+		}
+		payload, err := readFrame(conn, frameAck)
+		if err != nil {
+			return err
+		}
+		ack, err := decodeOffset(payload)
+		if err != nil {
+			return err
+		}
+		if len(records) > 0 && ack < records[len(records)-1].Offset {
+			return fmt.Errorf("consumer acknowledged offset %d, expected %d", ack, records[len(records)-1].Offset)
+		}
+		if err := m.outgoing.SetOffset(addr, ack); err != nil {
+			return err
+		}
+		next = ack + 1
+	}
+}
+
+// acceptRoutine accepts connections from producers.
+func (m *MQ) acceptRoutine(ctx context.Context) {
+	defer m.wg.Done()
+	for {
+		conn, err := m.listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			m.log.
+				WithError(err).
+				WithAdvice("Ignore if occurs occasionally, especially if it is related to temporary network issues").
+				Warn("Unable to accept connection")
+			continue
+		}
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+			defer conn.Close()
+			m.consume(ctx, conn)
+		}()
+	}
+}
+
+// consume handles a connection from a producer. Received records are
+// appended to the incoming journal before they are acknowledged.
+func (m *MQ) consume(ctx context.Context, conn net.Conn) {
+	fields := log.Fields{"addr": conn.RemoteAddr().String()}
+
+	// Authenticate the producer.
+	if err := conn.SetDeadline(time.Now().Add(m.timeout)); err != nil {
+		return
+	}
+	payload, err := readFrame(conn, frameHello)
+	if err != nil {
+		m.log.
+			WithError(err).
+			WithFields(fields).
+			WithAdvice("This may happen if someone is trying to connect to the MQ server with incompatible software").
+			Warn("Invalid hello frame")
+		return
+	}
+	h, err := decodeHello(payload)
+	if err != nil {
+		m.log.
+			WithError(err).
+			WithFields(fields).
+			WithAdvice("This may happen if someone is trying to connect to the MQ server with incompatible software").
+			Warn("Invalid hello frame")
+		return
+	}
+	author, err := h.verify(m.recover)
+	if err != nil {
+		m.log.
+			WithError(err).
+			WithFields(fields).
+			WithAdvice("This may indicate a bug in the MQ transport or someone is trying to connect to the MQ server with incompatible software"). //nolint:lll
+			Warn("Invalid hello signature")
+		return
+	}
+	fields["author"] = author
+	if !sliceutil.Contains(m.allowlist, *author) {
+		m.log.
+			WithFields(fields).
+			Debug("Feed is not allowed to send messages")
+		return
+	}
+	if !m.acceptHello(*author, h.Time) {
+		m.log.
+			WithFields(fields).
+			WithField("timestamp", h.Time).
+			WithAdvice("This may be cased by setting incorrect system time on this server or server that send the message").
+			Warn("Invalid hello timestamp")
+		return
+	}
+
+	// Only one connection from the same producer is handled at a time.
+	// A new connection replaces the previous one.
+	unlock := m.lockProducer(*author, conn)
+	defer unlock()
+	defer context.AfterFunc(ctx, func() { _ = conn.Close() })()
+
+	// The producer journal may have been recreated. In that case, offsets
+	// start from the beginning.
+	producer := author.String()
+	last := m.incoming.Offset(producer)
+	if last > h.Last {
+		m.log.
+			WithFields(fields).
+			WithFields(log.Fields{"offset": last, "last": h.Last}).
+			WithAdvice("This may happen if the journal of the producer was removed").
+			Warn("Producer journal is behind the received offset, resetting offset")
+		last = 0
+		if err := m.incoming.SetOffset(producer, last); err != nil {
+			m.log.WithError(err).WithFields(fields).Error("Unable to store offset")
+			return
+		}
+	}
+	if err := writeFrame(conn, frameWelcome, encodeOffset(last)); err != nil {
+		return
+	}
+	m.log.WithFields(fields).WithField("offset", last).Info("Producer connected")
+
+	for {
+		if err := conn.SetDeadline(time.Now().Add(m.timeout)); err != nil {
+			return
+		}
+		payload, err := readFrame(conn, frameBatch)
+		if err != nil {
+			if ctx.Err() == nil {
+				m.log.
+					WithError(err).
+					WithFields(fields).
+					WithAdvice("Ignore if occurs occasionally, especially if it is related to temporary network issues").
+					Warn("Connection with producer closed")
+			}
+			return
+		}
+		records, err := decodeBatch(payload)
+		if err != nil {
+			m.log.
+				WithError(err).
+				WithFields(fields).
+				WithAdvice("This is likely a bug and must be investigated").
+				Error("Unable to decode batch")
+			return
+		}
+		for _, rec := range records {
+			if rec.Offset <= last {
+				continue // Already received.
+			}
+			signer, err := m.recover.RecoverMessage(rec.signingData(), rec.Signature)
+			if err == nil && (*signer != *author || rec.Author != *author) {
+				err = errors.New("record is not signed by the producer")
+			}
+			if err != nil {
+				m.log.
+					WithError(err).
+					WithFields(fields).
+					WithField("offset", rec.Offset).
+					WithAdvice("This is likely a bug and must be investigated").
+					Error("Invalid record signature")
+				return
+			}
+			rec.Origin = rec.Offset
+			if _, err := m.incoming.Append(rec); err != nil {
+				m.log.WithError(err).WithFields(fields).Error("Unable to store record")
+				return
+			}
+			last = rec.Origin
+		}
+		if err := m.incoming.SetOffset(producer, last); err != nil {
+			m.log.WithError(err).WithFields(fields).Error("Unable to store offset")
+			return
+		}
+		if err := writeFrame(conn, frameAck, encodeOffset(last)); err != nil {
+			return
+		}
+	}
+}
+
+// acceptHello verifies the timestamp of the hello frame. The timestamp must
+// be within the clock skew and newer than the previous one received from
+// the same producer.
+func (m *MQ) acceptHello(author types.Address, t time.Time) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	if t.After(now.Add(m.maxClockSkew)) || t.Before(now.Add(-m.maxClockSkew)) {
+		return false
+	}
+	if !t.After(m.lastHello[author]) {
+		return false
+	}
+	m.lastHello[author] = t
+	return true
+}
+
+// lockProducer closes the previous connection from the producer and waits
+// until it is handled. It returns a function that releases the lock.
+func (m *MQ) lockProducer(author types.Address, conn net.Conn) func() {
+	m.mu.Lock()
+	if prev, ok := m.conns[author]; ok {
+		_ = prev.Close()
+	}
+	m.conns[author] = conn
+	mu, ok := m.connsMu[author]
+	if !ok {
+		mu = &sync.Mutex{}
+		m.connsMu[author] = mu
+	}
+	m.mu.Unlock()
+	mu.Lock()
+	return func() {
+		m.mu.Lock()
+		if m.conns[author] == conn {
+			delete(m.conns, author)
+		}
+		m.mu.Unlock()
+		mu.Unlock()
+	}
+}
+
+// deliverRoutine delivers records from the incoming journal to the message
+// channels, starting from the first record that was not delivered yet.
+func (m *MQ) deliverRoutine(ctx context.Context) {
+	defer m.wg.Done()
+	defer func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		for _, ch := range m.msgCh {
+			close(ch)
+		}
+	}()
+	next := m.incoming.Offset(deliveredOffset) + 1
+	for {
+		notifyCh := m.incoming.Notify()
+		records, err := m.incoming.Read(next, maxBatchRecords)
+		if err != nil {
+			m.log.
+				WithError(err).
+				WithAdvice("This may indicate a corrupted journal or a problem with the disk").
+				Error("Unable to read incoming journal")
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(m.retryInterval):
+				continue
+			}
+		}
+		if len(records) == 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-notifyCh:
+				continue
+			}
+		}
+		for _, rec := range records {
+			if !m.deliver(ctx, rec) {
+				return
+			}
+			next = rec.Offset + 1
+		}
+		if err := m.incoming.SetOffset(deliveredOffset, next-1); err != nil {
+			m.log.
+				WithError(err).
+				WithAdvice("This may indicate a problem with the disk, messages may be delivered again after a restart").
+				Error("Unable to store offset")
+		}
+	}
+}
+
+// deliver sends the record to the message channel of its topic. It returns
+// false if the context was canceled.
+func (m *MQ) deliver(ctx context.Context, rec Record) bool {
+	typ, ok := m.topics[rec.Topic]
+	if !ok {
+		return true // Ignore messages for unknown topics.
+	}
+	msg := reflect.New(reflect.TypeOf(typ).Elem()).Interface().(transport.Message)
+	if err := msg.UnmarshallBinary(rec.Data); err != nil {
+		m.log.
+			WithError(err).
+			WithFields(log.Fields{
+				"topic":  rec.Topic,
+				"author": rec.Author,
+			}).
+			WithAdvice("This is likely a bug and must be investigated").
+			Error("Unable to unmarshal message")
+		return true
+	}
+	userAgent := ""
+	if appInfo, ok := msg.(transport.WithAppInfo); ok {
+		userAgent = fmt.Sprintf("%s/%s", appInfo.GetAppInfo().Name, appInfo.GetAppInfo().Version)
+	}
+	select {
+	case <-ctx.Done():
+		return false
+	case m.msgCh[rec.Topic] <- transport.ReceivedMessage{
+		Message: msg,
+		Author:  rec.Author.Bytes(),
+		Meta: transport.Meta{
+			Transport: TransportName,
+			Topic:     rec.Topic,
+			MessageID: fmt.Sprintf("%s:%d", rec.Author, rec.Origin),
+			UserAgent: userAgent,
+			PeerAddr:  rec.Author.String(),
+		},
+	}:
+		return true
+	}
+}
+
+// ServiceName implements the supervisor.WithName interface.
+func (m *MQ) ServiceName() string {
+	return "MQ"
+}
+
+// contextCancelHandler handles context cancellation.
+func (m *MQ) contextCancelHandler() {
+	defer func() { close(m.waitCh) }()
+	defer m.log.Debug("Stopped")
+	<-m.ctx.Done()
+	if m.listener != nil {
+		_ = m.listener.Close()
+	}
+	m.wg.Wait()
+	if err := m.outgoing.Close(); err != nil {
+		m.log.WithError(err).Error("Unable to close outgoing journal")
+	}
+	if err := m.incoming.Close(); err != nil {
+		m.log.WithError(err).Error("Unable to close incoming journal")
+	}
+}
@@ -0,0 +1,191 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package mq
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
+	"github.com/defiweb/go-eth/wallet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/orcfax/oracle-suite/pkg/transport"
+)
+
+type message struct {
+	data []byte
+}
+
+func (m *message) MarshallBinary() ([]byte, error) {
+	return m.data, nil
+}
+
+func (m *message) UnmarshallBinary(i []byte) error {
+	m.data = i
+	return nil
+}
+
+var testTopics = map[string]transport.Message{"test": (*message)(nil)}
+
+func startConsumer(t *testing.T, ctx context.Context, path, addr string, allowlist []types.Address) *MQ {
+	c, err := New(Config{
+		ListenAddr:      addr,
+		JournalPath:     path,
+		Topics:          testTopics,
+		AuthorAllowlist: allowlist,
+	})
+	require.NoError(t, err)
+	require.NoError(t, c.Start(ctx))
+	return c
+}
+
+func startProducer(t *testing.T, ctx context.Context, path string, key wallet.Key, consumers ...string) *MQ {
+	p, err := New(Config{
+		Consumers:     consumers,
+		JournalPath:   path,
+		Signer:        key,
+		RetryInterval: 50 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	require.NoError(t, p.Start(ctx))
+	return p
+}
+
+func receive(t *testing.T, ch <-chan transport.ReceivedMessage) transport.ReceivedMessage {
+	select {
+	case msg := <-ch:
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for message")
+		return transport.ReceivedMessage{}
+	}
+}
+
+func TestMQ(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	key := wallet.NewRandomKey()
+	c := startConsumer(t, ctx, t.TempDir(), "127.0.0.1:0", []types.Address{key.Address()})
+	p := startProducer(t, ctx, t.TempDir(), key, c.Addr().String())
+
+	ch := c.Messages("test")
+	require.NoError(t, p.Broadcast("test", &message{data: []byte("a")}))
+	require.NoError(t, p.Broadcast("test", &message{data: []byte("b")}))
+
+	msg := receive(t, ch)
+	assert.Equal(t, []byte("a"), msg.Message.(*message).data)
+	assert.Equal(t, key.Address().Bytes(), msg.Author)
+	assert.Equal(t, TransportName, msg.Meta.Transport)
+	assert.Equal(t, "test", msg.Meta.Topic)
+	msg = receive(t, ch)
+	assert.Equal(t, []byte("b"), msg.Message.(*message).data)
+
+	cancel()
+	<-p.Wait()
+	<-c.Wait()
+}
+
+func TestMQ_ConsumerRestart(t *testing.T) {
+	pCtx, pCancel := context.WithCancel(context.Background())
+	defer pCancel()
+	cCtx, cCancel := context.WithCancel(context.Background())
+
+	key := wallet.NewRandomKey()
+	consumerPath := t.TempDir()
+	c := startConsumer(t, cCtx, consumerPath, "127.0.0.1:0", []types.Address{key.Address()})
+	addr := c.Addr().String()
+	p := startProducer(t, pCtx, t.TempDir(), key, addr)
+
+	require.NoError(t, p.Broadcast("test", &message{data: []byte("a")}))
+	assert.Equal(t, []byte("a"), receive(t, c.Messages("test")).Message.(*message).data)
+
+	// Stop the consumer. Messages broadcast while it is down must be
+	// delivered after the restart.
+	cCancel()
+	<-c.Wait()
+	require.NoError(t, p.Broadcast("test", &message{data: []byte("b")}))
+	require.NoError(t, p.Broadcast("test", &message{data: []byte("c")}))
+
+	cCtx, cCancel = context.WithCancel(context.Background())
+	defer cCancel()
+	c = startConsumer(t, cCtx, consumerPath, addr, []types.Address{key.Address()})
+	ch := c.Messages("test")
+	assert.Equal(t, []byte("b"), receive(t, ch).Message.(*message).data)
+	assert.Equal(t, []byte("c"), receive(t, ch).Message.(*message).data)
+	select {
+	case msg := <-ch:
+		t.Fatalf("unexpected message: %s", msg.Message.(*message).data)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestMQ_ReplayUndelivered(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Records stored in the incoming journal but not delivered before
+	// a restart must be delivered after the start.
+	path := t.TempDir()
+	j, err := OpenJournal(filepath.Join(path, incomingJournal), 0)
+	require.NoError(t, err)
+	for _, d := range []string{"a", "b", "c"} {
+		_, err := j.Append(Record{Topic: "test", Data: []byte(d), Time: time.Now()})
+		require.NoError(t, err)
+	}
+	require.NoError(t, j.SetOffset(deliveredOffset, 1))
+	require.NoError(t, j.Close())
+
+	c := startConsumer(t, ctx, path, "", nil)
+	ch := c.Messages("test")
+	assert.Equal(t, []byte("b"), receive(t, ch).Message.(*message).data)
+	assert.Equal(t, []byte("c"), receive(t, ch).Message.(*message).data)
+}
+
+func TestMQ_NotAllowedAuthor(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	key := wallet.NewRandomKey()
+	c := startConsumer(t, ctx, t.TempDir(), "127.0.0.1:0", []types.Address{wallet.NewRandomKey().Address()})
+	p := startProducer(t, ctx, t.TempDir(), key, c.Addr().String())
+
+	ch := c.Messages("test")
+	require.NoError(t, p.Broadcast("test", &message{data: []byte("a")}))
+	select {
+	case <-ch:
+		t.Fatal("unexpected message")
+	case <-time.After(200 * time.Millisecond):
+	}
+	assert.Equal(t, uint64(0), p.outgoing.Offset(c.Addr().String()))
+}
+
+func TestMQ_Broadcast(t *testing.T) {
+	p, err := New(Config{JournalPath: t.TempDir(), Signer: wallet.NewRandomKey()})
+	require.NoError(t, err)
+	assert.Error(t, p.Broadcast("test", &message{}))
+
+	c, err := New(Config{JournalPath: t.TempDir()})
+	require.NoError(t, err)
+	assert.Error(t, c.Broadcast("test", &message{}))
+
+	_, err = New(Config{JournalPath: t.TempDir(), Consumers: []string{"localhost:1234"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,652 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package mqtt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/defiweb/go-eth/crypto"
+	"github.com/defiweb/go-eth/types"
+	"github.com/defiweb/go-eth/wallet"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+
+	"github.com/orcfax/oracle-suite/pkg/log"
+	"github.com/orcfax/oracle-suite/pkg/log/null"
+	"github.com/orcfax/oracle-suite/pkg/supervisor"
+	"github.com/orcfax/oracle-suite/pkg/transport"
+	"github.com/orcfax/oracle-suite/pkg/util/chanutil"
+	"github.com/orcfax/oracle-suite/pkg/util/maputil"
+	"github.com/orcfax/oracle-suite/pkg/util/sliceutil"
+)
+
+const (
+	LoggerTag     = "MQTT"
+	TransportName = "mqtt"
+
+	// messageChanSize is the size of the message channel. It is used to buffer
+	// messages before they are consumed.
+	messageChanSize = 10000
+
+	// publishQueueSize is the number of messages waiting to be published to
+	// the broker. If the queue is full, new messages are dropped.
+	publishQueueSize = 10000
+
+	// defaultKeepAlive is the default keep alive interval.
+	defaultKeepAlive = 30 * time.Second
+
+	// defaultTimeout is the default timeout for network operations.
+	defaultTimeout = 10 * time.Second
+
+	// defaultRetryInterval is the default time between reconnection attempts.
+	defaultRetryInterval = 10 * time.Second
+)
+
+// Format is the format of message payloads.
+type Format int
+
+const (
+	// FormatProtobuf uses the binary representation of messages, the same
+	// as used by other transports.
+	FormatProtobuf Format = iota
+
+	// FormatJSON uses the JSON representation of messages.
+	FormatJSON
+)
+
+// String implements the fmt.Stringer interface.
+func (f Format) String() string {
+	switch f {
+	case FormatProtobuf:
+		return "protobuf"
+	case FormatJSON:
+		return "json"
+	default:
+		return fmt.Sprintf("Format(%d)", int(f))
+	}
+}
+
+// ParseFormat parses the format name.
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(s) {
+	case "protobuf":
+		return FormatProtobuf, nil
+	case "json":
+		return FormatJSON, nil
+	default:
+		return 0, fmt.Errorf("unknown format %q, supported formats are protobuf and json", s)
+	}
+}
+
+// Topic describes how a transport topic is mapped to MQTT topics.
+type Topic struct {
+	// Publish is the MQTT topic to which messages received by the bridged
+	// transport are published. If empty, messages are not published.
+	Publish string
+
+	// Subscribe is the MQTT topic filter from which messages are received.
+	// If empty, messages are not received from the broker.
+	Subscribe string
+
+	// Format is the format of message payloads.
+	Format Format
+}
+
+// Bridge is a transport that bridges another transport with an MQTT 3.1.1
+// broker. It allows services outside the oracle suite to consume and
+// produce messages without joining the peer-to-peer network.
+//
+// Messages received by the bridged transport on mapped topics are published
+// to the broker. Messages received from the broker are returned by the
+// Messages method together with messages from the bridged transport. The
+// broadcast messages are sent only using the bridged transport.
+//
+// Messages received from the broker must be wrapped in a signed envelope,
+// see the envelope type. Messages from authors that are not on the
+// allowlist and messages that were already received are dropped. If the
+// signer is configured, published messages are also wrapped in envelopes,
+// otherwise they are published as they are and cannot be received by other
+// bridges. Envelopes always carry the signature of the message author:
+// messages received from the libp2p transport are published with their
+// original signature, messages authored by the signer are signed by it,
+// and other messages are not published.
+type Bridge struct {
+	mu     sync.RWMutex
+	ctx    context.Context
+	waitCh <-chan error
+	doneCh chan error
+	wg     sync.WaitGroup
+	seqno  uint64
+
+	// State fields:
+	publishCh chan publication                                       // Messages waiting to be published.
+	msgCh     map[string]chan transport.ReceivedMessage              // Channels for messages received from the broker.
+	msgChFO   map[string]*chanutil.FanOut[transport.ReceivedMessage] // Fan-out channels for messages received from the broker.
+
+	// Configuration fields:
+	transport     transport.Service
+	topics        map[string]Topic
+	messages      map[string]transport.Message
+	opts          clientOptions
+	allowlist     []types.Address
+	signer        wallet.Key
+	qos           byte
+	retryInterval time.Duration
+	log           log.Logger
+
+	// Internal fields:
+	recover crypto.Recoverer
+	replay  *replayGuard
+}
+
+// publication is a message waiting to be published to the broker.
+type publication struct {
+	topic   string
+	payload []byte
+}
+
+// Config is a configuration of Bridge.
+type Config struct {
+	// Transport is the bridged transport.
+	//
+	// Cannot be nil.
+	Transport transport.Service
+
+	// BrokerAddr is the address of the MQTT broker in the host:port format.
+	//
+	// Cannot be empty.
+	BrokerAddr string
+
+	// ClientID is the client identifier sent to the broker. If empty, the
+	// broker assigns a random identifier.
+	ClientID string
+
+	// Username and Password are optional credentials.
+	Username string
+	Password string
+
+	// Topics maps transport topics to MQTT topics.
+	Topics map[string]Topic
+
+	// Messages is a list of message types. A value of the map a type of
+	// message given as a nil pointer, e.g.: (*Message)(nil). The type must be
+	// provided for every topic with the Subscribe field set.
+	Messages map[string]transport.Message
+
+	// AuthorAllowlist is a list of allowed message authors. Only messages
+	// signed by these addresses are received from the broker.
+	//
+	// Cannot be empty if any topic has the Subscribe field set.
+	AuthorAllowlist []types.Address
+
+	// Signer is used to sign messages authored by it that are published to
+	// the broker. Messages authored by others are published only if their
+	// original signature is available. If not provided, messages are
+	// published without the envelope.
+	Signer wallet.Key
+
+	// QoS is the MQTT quality of service level used for publishing and
+	// subscribing. Only 0 and 1 are supported.
+	QoS byte
+
+	// KeepAlive is the keep alive interval. If zero, default value will be
+	// used (30 seconds).
+	KeepAlive time.Duration
+
+	// Timeout is a timeout for network operations. If zero, default value
+	// will be used (10 seconds).
+	Timeout time.Duration
+
+	// RetryInterval is the time between reconnection attempts. If zero,
+	// default value will be used (10 seconds).
+	RetryInterval time.Duration
+
+	// Dialer is an optional dialer used to connect to the broker. If not
+	// provided, net.Dialer is used.
+	Dialer Dialer
+
+	// Logger is a custom logger instance. If not provided then null
+	// logger is used.
+	Logger log.Logger
+}
+
+// New returns a new instance of Bridge.
+func New(cfg Config) (*Bridge, error) {
+	if cfg.Transport == nil {
+		return nil, errors.New("transport must not be nil")
+	}
+	if cfg.BrokerAddr == "" {
+		return nil, errors.New("broker address must be provided")
+	}
+	if cfg.QoS > 1 {
+		return nil, fmt.Errorf("unsupported QoS level %d", cfg.QoS)
+	}
+	for name, topic := range cfg.Topics {
+		if strings.ContainsAny(topic.Publish, "+#") {
+			return nil, fmt.Errorf("publish topic %q of %s must not contain wildcards", topic.Publish, name)
+		}
+		if topic.Subscribe == "" {
+			continue
+		}
+		if len(cfg.AuthorAllowlist) == 0 {
+			return nil, fmt.Errorf("author allowlist must be provided to subscribe to topic %s", name)
+		}
+		if _, ok := cfg.Messages[name]; !ok {
+			return nil, fmt.Errorf("unknown message type for topic %s", name)
+		}
+		for _, other := range cfg.Topics {
+			if other.Publish != "" && matchTopic(topic.Subscribe, other.Publish) {
+				return nil, fmt.Errorf("subscribe topic %q of %s matches publish topic %q", topic.Subscribe, name, other.Publish)
+			}
+		}
+	}
+	if cfg.KeepAlive == 0 {
+		cfg.KeepAlive = defaultKeepAlive
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	if cfg.RetryInterval == 0 {
+		cfg.RetryInterval = defaultRetryInterval
+	}
+	if cfg.Dialer == nil {
+		cfg.Dialer = &net.Dialer{Timeout: cfg.Timeout}
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = null.New()
+	}
+	doneCh := make(chan error)
+	fi := chanutil.NewFanIn[error](cfg.Transport.Wait(), doneCh)
+	fi.AutoClose()
+	b := &Bridge{
+		waitCh:    fi.Chan(),
+		doneCh:    doneCh,
+		seqno:     uint64(time.Now().UnixNano()),
+		publishCh: make(chan publication, publishQueueSize),
+		msgCh:     make(map[string]chan transport.ReceivedMessage),
+		msgChFO:   make(map[string]*chanutil.FanOut[transport.ReceivedMessage]),
+		transport: cfg.Transport,
+		topics:    maputil.Copy(cfg.Topics),
+		messages:  maputil.Copy(cfg.Messages),
+		opts: clientOptions{
+			addr:      cfg.BrokerAddr,
+			clientID:  cfg.ClientID,
+			username:  cfg.Username,
+			password:  cfg.Password,
+			keepAlive: cfg.KeepAlive,
+			timeout:   cfg.Timeout,
+			dialer:    cfg.Dialer,
+		},
+		allowlist:     sliceutil.Copy(cfg.AuthorAllowlist),
+		signer:        cfg.Signer,
+		qos:           cfg.QoS,
+		retryInterval: cfg.RetryInterval,
+		log:           cfg.Logger.WithField("tag", LoggerTag),
+		recover:       crypto.ECRecoverer,
+		replay:        newReplayGuard(),
+	}
+	b.opts.handler = b.handleMessage
+	return b, nil
+}
+
+// Start implements the supervisor.Supervisor interface.
+func (b *Bridge) Start(ctx context.Context) error {
+	if b.ctx != nil {
+		return errors.New("service can be started only once")
+	}
+	if ctx == nil {
+		return errors.New("context must not be nil")
+	}
+	b.log.
+		WithField("address", b.opts.addr).
+		Debug("Starting")
+	if err := b.transport.Start(ctx); err != nil {
+		return err
+	}
+	b.mu.Lock()
+	b.ctx = ctx
+	for name, topic := range b.topics {
+		if topic.Subscribe == "" {
+			continue
+		}
+		b.msgCh[name] = make(chan transport.ReceivedMessage, messageChanSize)
+		b.msgChFO[name] = chanutil.NewFanOut(b.msgCh[name])
+	}
+	b.mu.Unlock()
+	for name, topic := range b.topics {
+		if topic.Publish == "" {
+			continue
+		}
+		ch := b.transport.Messages(name)
+		if ch == nil {
+			b.log.
+				WithField("topic", name).
+				WithAdvice("The topic is not supported by the bridged transport, check the configuration").
+				Warn("Unable to bridge topic")
+			continue
+		}
+		b.wg.Add(1)
+		go b.forwardRoutine(ctx, name, topic, ch)
+	}
+	b.wg.Add(1)
+	go b.connectionRoutine(ctx)
+	go b.contextCancelHandler()
+	return nil
+}
+
+// Wait implements the supervisor.Supervisor interface.
+func (b *Bridge) Wait() <-chan error {
+	return b.waitCh
+}
+
+// Broadcast implements the transport.Transport interface.
+func (b *Bridge) Broadcast(topic string, message transport.Message) error {
+	return b.transport.Broadcast(topic, message)
+}
+
+// Messages implements the transport.Transport interface.
+func (b *Bridge) Messages(topic string) <-chan transport.ReceivedMessage {
+	ch := b.transport.Messages(topic)
+	b.mu.RLock()
+	fo, ok := b.msgChFO[topic]
+	b.mu.RUnlock()
+	if !ok {
+		return ch
+	}
+	fi := chanutil.NewFanIn[transport.ReceivedMessage](fo.Chan())
+	if ch != nil {
+		_ = fi.Add(ch)
+	}
+	fi.AutoClose()
+	return fi.Chan()
+}
+
+// ServiceName implements the supervisor.WithName interface.
+func (b *Bridge) ServiceName() string {
+	return fmt.Sprintf("MQTT(%s)", supervisor.ServiceName(b.transport))
+}
+
+// forwardRoutine queues messages received by the bridged transport to be
+// published to the broker.
+func (b *Bridge) forwardRoutine(ctx context.Context, name string, topic Topic, ch <-chan transport.ReceivedMessage) {
+	defer b.wg.Done()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			if msg.Error != nil || msg.Message == nil {
+				continue
+			}
+			payload, err := b.encodePayload(name, msg, topic.Format)
+			if errors.Is(err, errMissingSignature) {
+				b.log.
+					WithField("topic", name).
+					WithField("author", fmt.Sprintf("%x", msg.Author)).
+					Debug("Message not published, the signature of the author is not available")
+				continue
+			}
+			if err != nil {
+				b.log.
+					WithError(err).
+					WithField("topic", name).
+					WithAdvice("This is a bug and must be investigated").
+					Error("Unable to encode message")
+				continue
+			}
+			select {
+			case b.publishCh <- publication{topic: topic.Publish, payload: payload}:
+			default:
+				b.log.
+					WithField("topic", name).
+					WithAdvice("The MQTT broker is unavailable or too slow, check the connection to the broker").
+					Warn("Publish queue is full, message dropped")
+			}
+		}
+	}
+}
+
+// connectionRoutine maintains the connection to the broker and publishes
+// queued messages. If the connection is lost, it reconnects after the retry
+// interval.
+func (b *Bridge) connectionRoutine(ctx context.Context) {
+	defer b.wg.Done()
+	var pending *publication
+	for {
+		c, err := b.connect(ctx)
+		if err == nil {
+			b.log.WithField("address", b.opts.addr).Info("Connected to MQTT broker")
+			pending, err = b.publishLoop(ctx, c, pending)
+			if ctx.Err() != nil {
+				c.disconnect()
+				return
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		b.log.
+			WithError(err).
+			WithField("address", b.opts.addr).
+			WithAdvice("Ignore if occurs occasionally, especially if it is related to temporary network issues").
+			Warn("Connection to MQTT broker failed")
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(b.retryInterval):
+		}
+	}
+}
+
+// connect connects to the broker and subscribes to mapped topics.
+func (b *Bridge) connect(ctx context.Context) (*client, error) {
+	c, err := dial(ctx, b.opts)
+	if err != nil {
+		return nil, err
+	}
+	var subs []subscription
+	for _, name := range maputil.SortKeys(b.topics, sort.Strings) {
+		if f := b.topics[name].Subscribe; f != "" {
+			subs = append(subs, subscription{filter: f, qos: b.qos})
+		}
+	}
+	if len(subs) > 0 {
+		if err := c.subscribe(ctx, subs); err != nil {
+			c.disconnect()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// publishLoop publishes queued messages until the connection is closed.
+// It returns the message that could not be published.
+func (b *Bridge) publishLoop(ctx context.Context, c *client, pending *publication) (*publication, error) {
+	for {
+		if pending != nil {
+			if err := c.publish(ctx, pending.topic, pending.payload, b.qos); err != nil {
+				return pending, err
+			}
+			pending = nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-c.done():
+			return nil, c.closeErr()
+		case p := <-b.publishCh:
+			pending = &p
+		}
+	}
+}
+
+// handleMessage handles a message received from the broker.
+func (b *Bridge) handleMessage(mqttTopic string, payload []byte) {
+	// The lock prevents sending messages to closed channels, see the
+	// contextCancelHandler method.
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.ctx.Err() != nil {
+		return
+	}
+	for name, topic := range b.topics {
+		if topic.Subscribe == "" || !matchTopic(topic.Subscribe, mqttTopic) {
+			continue
+		}
+		fields := log.Fields{"topic": name, "mqttTopic": mqttTopic}
+		env, err := decodeEnvelope(payload, topic.Format)
+		if err != nil {
+			b.log.
+				WithError(err).
+				WithFields(fields).
+				WithAdvice("The message was published by an incompatible client or has an invalid format").
+				Warn("Unable to decode envelope")
+			continue
+		}
+		if err := env.verify(name, b.recover); err != nil {
+			b.log.
+				WithError(err).
+				WithFields(fields).
+				WithAdvice("The message was published by an incompatible client or has an invalid signature").
+				Warn("Unable to verify envelope signature")
+			continue
+		}
+		author := env.Author
+		if !sliceutil.Contains(b.allowlist, author) {
+			b.log.
+				WithFields(fields).
+				WithField("author", author.String()).
+				WithAdvice("Ignore if the author is not expected to publish messages, otherwise add it to the feeds list").
+				Debug("Author is not allowed to send messages")
+			continue
+		}
+		if !b.replay.accept(author, env.Seqno) {
+			b.log.
+				WithFields(fields).
+				WithField("author", author.String()).
+				WithField("seqno", env.Seqno).
+				WithAdvice("Ignore if occurs occasionally, otherwise someone may be replaying captured messages").
+				Warn("Envelope was already received")
+			continue
+		}
+		msg := reflect.New(reflect.TypeOf(b.messages[name]).Elem()).Interface().(transport.Message)
+		if err := msg.UnmarshallBinary(env.Data); err != nil {
+			b.log.
+				WithError(err).
+				WithFields(fields).
+				WithAdvice("The message was published by an incompatible client or has an invalid format").
+				Warn("Unable to decode message")
+			continue
+		}
+		userAgent := ""
+		if appInfo, ok := msg.(transport.WithAppInfo); ok {
+			userAgent = fmt.Sprintf("%s/%s", appInfo.GetAppInfo().Name, appInfo.GetAppInfo().Version)
+		}
+		select {
+		case <-b.ctx.Done():
+			return
+		case b.msgCh[name] <- transport.ReceivedMessage{
+			Message: msg,
+			Author:  author.Bytes(),
+			Meta: transport.Meta{
+				Transport: TransportName,
+				Topic:     name,
+				UserAgent: userAgent,
+				PeerAddr:  author.String(),
+			},
+		}:
+		}
+	}
+}
+
+// contextCancelHandler handles context cancellation.
+func (b *Bridge) contextCancelHandler() {
+	defer func() { close(b.doneCh) }()
+	defer b.log.Debug("Stopped")
+	<-b.ctx.Done()
+	b.wg.Wait()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, ch := range b.msgCh {
+		close(ch)
+	}
+}
+
+// errMissingSignature is returned by encodePayload if the message cannot be
+// wrapped in an envelope signed by its author.
+var errMissingSignature = errors.New("signature of the author is not available")
+
+// encodePayload encodes the message published to the broker. If the signer
+// is configured, the message is wrapped in an envelope signed by its author.
+func (b *Bridge) encodePayload(topic string, msg transport.ReceivedMessage, format Format) ([]byte, error) {
+	data, err := encodeMessage(msg.Message, format)
+	if err != nil {
+		return nil, err
+	}
+	if b.signer == nil {
+		return data, nil
+	}
+	var env envelope
+	if psMsg, ok := msg.Data.(*pubsub.Message); ok {
+		// Messages received from the libp2p transport are already signed
+		// by their authors.
+		env, err = envelopeFromPubsub(psMsg)
+		if err != nil || env.Topic != topic {
+			return nil, errMissingSignature
+		}
+	} else {
+		if !bytes.Equal(msg.Author, b.signer.Address().Bytes()) {
+			return nil, errMissingSignature
+		}
+		bin, err := msg.Message.MarshallBinary()
+		if err != nil {
+			return nil, err
+		}
+		env = envelope{Seqno: atomic.AddUint64(&b.seqno, 1), Topic: topic, Data: bin}
+		if err := env.sign(b.signer); err != nil {
+			return nil, err
+		}
+	}
+	if format == FormatJSON {
+		env.Message = data
+	}
+	return env.encode(format)
+}
+
+// encodeMessage encodes the message using the given format.
+func encodeMessage(msg transport.Message, format Format) ([]byte, error) {
+	switch format {
+	case FormatProtobuf:
+		return msg.MarshallBinary()
+	case FormatJSON:
+		return json.Marshal(msg)
+	default:
+		return nil, fmt.Errorf("unsupported format %s", format)
+	}
+}
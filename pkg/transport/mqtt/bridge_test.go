@@ -0,0 +1,446 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package mqtt
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
+	"github.com/defiweb/go-eth/wallet"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/orcfax/oracle-suite/pkg/transport"
+	"github.com/orcfax/oracle-suite/pkg/transport/libp2p/crypto/ethkey"
+	"github.com/orcfax/oracle-suite/pkg/transport/local"
+)
+
+type message struct {
+	Data string `json:"data"`
+}
+
+func (m *message) MarshallBinary() ([]byte, error) {
+	return []byte(m.Data), nil
+}
+
+func (m *message) UnmarshallBinary(b []byte) error {
+	m.Data = string(b)
+	return nil
+}
+
+var testMessages = map[string]transport.Message{"test": (*message)(nil)}
+
+// testSubscriber is an MQTT client that collects received messages.
+type testSubscriber struct {
+	mu   sync.Mutex
+	msgs map[string][][]byte
+	c    *client
+}
+
+func newTestSubscriber(t *testing.T, addr string, filters ...string) *testSubscriber {
+	s := &testSubscriber{msgs: make(map[string][][]byte)}
+	c, err := dial(context.Background(), clientOptions{
+		addr:      addr,
+		keepAlive: time.Minute,
+		timeout:   time.Second,
+		dialer:    &net.Dialer{},
+		handler: func(topic string, payload []byte) {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.msgs[topic] = append(s.msgs[topic], payload)
+		},
+	})
+	require.NoError(t, err)
+	t.Cleanup(c.disconnect)
+	var subs []subscription
+	for _, f := range filters {
+		subs = append(subs, subscription{filter: f, qos: 1})
+	}
+	if len(subs) > 0 {
+		require.NoError(t, c.subscribe(context.Background(), subs))
+	}
+	s.c = c
+	return s
+}
+
+func (s *testSubscriber) messages(topic string) [][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.msgs[topic]
+}
+
+func newTestBridge(t *testing.T, ctx context.Context, addr string, topics map[string]Topic, signer wallet.Key, allowlist ...types.Address) (*Bridge, *local.Local) {
+	author := []byte("author")
+	if signer != nil {
+		author = signer.Address().Bytes()
+	}
+	l := local.New(author, 10, testMessages)
+	b, err := New(Config{
+		Transport:       l,
+		BrokerAddr:      addr,
+		ClientID:        "bridge",
+		Username:        "user",
+		Password:        "pass",
+		Topics:          topics,
+		Messages:        testMessages,
+		AuthorAllowlist: allowlist,
+		Signer:          signer,
+		QoS:             1,
+		RetryInterval:   50 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	require.NoError(t, b.Start(ctx))
+	return b, l
+}
+
+func TestBridge_Publish(t *testing.T) {
+	tests := []struct {
+		format  Format
+		payload string
+	}{
+		{format: FormatProtobuf, payload: "foo"},
+		{format: FormatJSON, payload: `{"data":"foo"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format.String(), func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			broker := newTestBroker(t)
+			sub := newTestSubscriber(t, broker.addr(), "oracle/#")
+			b, _ := newTestBridge(t, ctx, broker.addr(), map[string]Topic{
+				"test": {Publish: "oracle/test", Format: tt.format},
+			}, nil)
+
+			require.NoError(t, b.Broadcast("test", &message{Data: "foo"}))
+			assert.Eventually(t, func() bool {
+				return len(sub.messages("oracle/test")) == 1
+			}, 5*time.Second, 10*time.Millisecond)
+			assert.Equal(t, tt.payload, string(sub.messages("oracle/test")[0]))
+
+			// The first connection is made by the subscriber.
+			connects := broker.connectPackets()
+			require.Len(t, connects, 2)
+			assert.Equal(t, "bridge", connects[1].clientID)
+			assert.Equal(t, "user", connects[1].username)
+			assert.Equal(t, "pass", connects[1].password)
+
+			cancel()
+			<-b.Wait()
+		})
+	}
+}
+
+func TestBridge_Subscribe(t *testing.T) {
+	for _, format := range []Format{FormatProtobuf, FormatJSON} {
+		t.Run(format.String(), func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			key := wallet.NewRandomKey()
+			broker := newTestBroker(t)
+			b, _ := newTestBridge(t, ctx, broker.addr(), map[string]Topic{
+				"test": {Subscribe: "external/test/+", Format: format},
+			}, nil, key.Address())
+			ch := b.Messages("test")
+
+			// Wait until the bridge subscribes to the topic.
+			require.Eventually(t, func() bool {
+				return broker.subscribers() == 1
+			}, 5*time.Second, 10*time.Millisecond)
+
+			env := envelope{Seqno: 1, Topic: "test", Data: []byte("bar")}
+			require.NoError(t, env.sign(key))
+			payload, err := env.encode(format)
+			require.NoError(t, err)
+
+			pub := newTestSubscriber(t, broker.addr())
+			require.NoError(t, pub.c.publish(ctx, "external/test/a", payload, 1))
+
+			select {
+			case msg := <-ch:
+				assert.Equal(t, "bar", msg.Message.(*message).Data)
+				assert.Equal(t, key.Address().Bytes(), msg.Author)
+				assert.Equal(t, TransportName, msg.Meta.Transport)
+				assert.Equal(t, "test", msg.Meta.Topic)
+			case <-time.After(5 * time.Second):
+				t.Fatal("timeout waiting for message")
+			}
+
+			// Messages from the bridged transport are also returned.
+			require.NoError(t, b.Broadcast("test", &message{Data: "baz"}))
+			select {
+			case msg := <-ch:
+				assert.Equal(t, "baz", msg.Message.(*message).Data)
+				assert.Equal(t, local.TransportName, msg.Meta.Transport)
+			case <-time.After(5 * time.Second):
+				t.Fatal("timeout waiting for message")
+			}
+		})
+	}
+}
+
+func TestBridge_SubscribeUnauthorized(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	key := wallet.NewRandomKey()
+	broker := newTestBroker(t)
+	b, _ := newTestBridge(t, ctx, broker.addr(), map[string]Topic{
+		"test": {Subscribe: "external/test", Format: FormatJSON},
+	}, nil, key.Address())
+	ch := b.Messages("test")
+
+	require.Eventually(t, func() bool {
+		return broker.subscribers() == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	unknown := envelope{Seqno: 2000, Topic: "test", Data: []byte("unknown")}
+	require.NoError(t, unknown.sign(wallet.NewRandomKey()))
+	otherTopic := envelope{Seqno: 2001, Topic: "other", Data: []byte("other")}
+	require.NoError(t, otherTopic.sign(key))
+	valid := envelope{Seqno: 2002, Topic: "test", Data: []byte("valid")}
+	require.NoError(t, valid.sign(key))
+	forged := valid
+	forged.Data = []byte("forged")
+	stale := envelope{Seqno: 2002 - replayWindow, Topic: "test", Data: []byte("stale")}
+	require.NoError(t, stale.sign(key))
+	last := envelope{Seqno: 2003, Topic: "test", Data: []byte("last")}
+	require.NoError(t, last.sign(key))
+
+	pub := newTestSubscriber(t, broker.addr())
+	for _, payload := range [][]byte{
+		[]byte(`{"data":"unsigned"}`),
+		mustEncode(t, unknown),
+		mustEncode(t, otherTopic),
+		mustEncode(t, valid),
+		mustEncode(t, forged),
+		mustEncode(t, valid), // Replayed.
+		mustEncode(t, stale),
+		mustEncode(t, last),
+	} {
+		require.NoError(t, pub.c.publish(ctx, "external/test", payload, 1))
+	}
+
+	// Only messages signed by the allowed author for the right topic are
+	// received, and only once.
+	for _, data := range []string{"valid", "last"} {
+		select {
+		case msg := <-ch:
+			assert.Equal(t, data, msg.Message.(*message).Data)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for message")
+		}
+	}
+}
+
+func TestBridge_PublishSigned(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	key := wallet.NewRandomKey()
+	broker := newTestBroker(t)
+	sub := newTestSubscriber(t, broker.addr(), "oracle/#")
+	b, _ := newTestBridge(t, ctx, broker.addr(), map[string]Topic{
+		"test": {Publish: "oracle/test", Format: FormatProtobuf},
+	}, key)
+
+	require.NoError(t, b.Broadcast("test", &message{Data: "foo"}))
+	require.Eventually(t, func() bool {
+		return len(sub.messages("oracle/test")) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	env, err := decodeEnvelope(sub.messages("oracle/test")[0], FormatProtobuf)
+	require.NoError(t, err)
+	assert.Equal(t, "foo", string(env.Data))
+	require.NoError(t, env.verify("test", b.recover))
+	assert.Equal(t, key.Address(), env.Author)
+}
+
+func TestBridge_encodePayload(t *testing.T) {
+	signer := wallet.NewRandomKey()
+	author := wallet.NewRandomKey()
+	b, err := New(Config{
+		Transport:  local.New(nil, 1, testMessages),
+		BrokerAddr: "localhost:1883",
+		Messages:   testMessages,
+		Signer:     signer,
+	})
+	require.NoError(t, err)
+
+	// A message received from libp2p, signed by its author.
+	orig := envelope{Seqno: 42, Topic: "test", Data: []byte("foo")}
+	require.NoError(t, orig.sign(author))
+	psMsg := &pubsub.Message{Message: orig.pb()}
+
+	for _, format := range []Format{FormatProtobuf, FormatJSON} {
+		t.Run(format.String(), func(t *testing.T) {
+			// The original signature of the author must be preserved.
+			payload, err := b.encodePayload("test", transport.ReceivedMessage{
+				Message: &message{Data: "foo"},
+				Author:  author.Address().Bytes(),
+				Data:    psMsg,
+			}, format)
+			require.NoError(t, err)
+			env, err := decodeEnvelope(payload, format)
+			require.NoError(t, err)
+			require.NoError(t, env.verify("test", b.recover))
+			assert.Equal(t, author.Address(), env.Author)
+			assert.Equal(t, uint64(42), env.Seqno)
+			assert.Equal(t, orig.Signature, env.Signature)
+
+			// The signature can be verified in the same way as libp2p does.
+			data, err := env.signingData()
+			require.NoError(t, err)
+			ok, err := ethkey.NewPubKey(author.Address()).Verify(data, env.Signature.Bytes())
+			require.NoError(t, err)
+			assert.True(t, ok)
+		})
+	}
+
+	// Messages from other authors without a signature are not published.
+	_, err = b.encodePayload("test", transport.ReceivedMessage{
+		Message: &message{Data: "foo"},
+		Author:  author.Address().Bytes(),
+	}, FormatProtobuf)
+	assert.ErrorIs(t, err, errMissingSignature)
+
+	// The signature is bound to the topic.
+	_, err = b.encodePayload("other", transport.ReceivedMessage{
+		Message: &message{Data: "foo"},
+		Author:  author.Address().Bytes(),
+		Data:    psMsg,
+	}, FormatProtobuf)
+	assert.ErrorIs(t, err, errMissingSignature)
+}
+
+func TestReplayGuard(t *testing.T) {
+	a := wallet.NewRandomKey().Address()
+	b := wallet.NewRandomKey().Address()
+	g := newReplayGuard()
+	assert.True(t, g.accept(a, 5000))
+	assert.False(t, g.accept(a, 5000))
+	assert.True(t, g.accept(b, 5000))
+	assert.True(t, g.accept(a, 5002))
+	assert.True(t, g.accept(a, 5001)) // Out of order.
+	assert.False(t, g.accept(a, 5001))
+	assert.False(t, g.accept(a, 5002-replayWindow))
+	assert.True(t, g.accept(a, 5003-replayWindow))
+}
+
+func mustEncode(t *testing.T, e envelope) []byte {
+	b, err := e.encode(FormatJSON)
+	require.NoError(t, err)
+	return b
+}
+
+func TestBridge_Reconnect(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Start the bridge before the broker is available, messages must be
+	// published after the connection is established.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	require.NoError(t, ln.Close())
+
+	b, _ := newTestBridge(t, ctx, addr, map[string]Topic{
+		"test": {Publish: "oracle/test"},
+	}, nil)
+	require.NoError(t, b.Broadcast("test", &message{Data: "foo"}))
+	time.Sleep(100 * time.Millisecond)
+
+	broker := &testBroker{sessions: make(map[*brokerSession]struct{})}
+	broker.ln, err = net.Listen("tcp", addr)
+	require.NoError(t, err)
+	go broker.acceptRoutine()
+	t.Cleanup(broker.close)
+
+	sub := newTestSubscriber(t, addr, "oracle/test")
+	require.NoError(t, b.Broadcast("test", &message{Data: "bar"}))
+	assert.Eventually(t, func() bool {
+		return len(sub.messages("oracle/test")) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestNew_Validation(t *testing.T) {
+	l := local.New(nil, 1, testMessages)
+	allowlist := []types.Address{wallet.NewRandomKey().Address()}
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{
+			name: "missing transport",
+			cfg:  Config{BrokerAddr: "localhost:1883"},
+		},
+		{
+			name: "missing broker",
+			cfg:  Config{Transport: l},
+		},
+		{
+			name: "unsupported qos",
+			cfg:  Config{Transport: l, BrokerAddr: "localhost:1883", QoS: 2},
+		},
+		{
+			name: "wildcard in publish topic",
+			cfg: Config{Transport: l, BrokerAddr: "localhost:1883", Topics: map[string]Topic{
+				"test": {Publish: "oracle/#"},
+			}},
+		},
+		{
+			name: "unknown message type",
+			cfg: Config{Transport: l, BrokerAddr: "localhost:1883", AuthorAllowlist: allowlist, Topics: map[string]Topic{
+				"unknown": {Subscribe: "oracle/unknown"},
+			}},
+		},
+		{
+			name: "subscribe matches publish",
+			cfg: Config{Transport: l, BrokerAddr: "localhost:1883", Messages: testMessages, AuthorAllowlist: allowlist, Topics: map[string]Topic{
+				"test": {Publish: "oracle/test", Subscribe: "oracle/+"},
+			}},
+		},
+		{
+			name: "missing author allowlist",
+			cfg: Config{Transport: l, BrokerAddr: "localhost:1883", Messages: testMessages, Topics: map[string]Topic{
+				"test": {Subscribe: "oracle/test"},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.cfg)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	f, err := ParseFormat("JSON")
+	require.NoError(t, err)
+	assert.Equal(t, FormatJSON, f)
+	f, err = ParseFormat("protobuf")
+	require.NoError(t, err)
+	assert.Equal(t, FormatProtobuf, f)
+	_, err = ParseFormat("xml")
+	assert.Error(t, err)
+}
@@ -0,0 +1,184 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package mqtt
+
+import (
+	"bufio"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testBroker is a minimal in-process MQTT broker used in tests. It supports
+// clean sessions, QoS 0 and 1 for incoming messages and delivers all
+// messages with QoS 0.
+type testBroker struct {
+	mu       sync.Mutex
+	ln       net.Listener
+	sessions map[*brokerSession]struct{}
+	connects []*packet
+}
+
+type brokerSession struct {
+	mu      sync.Mutex
+	conn    net.Conn
+	filters []string
+}
+
+func newTestBroker(t *testing.T) *testBroker {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	b := &testBroker{ln: ln, sessions: make(map[*brokerSession]struct{})}
+	go b.acceptRoutine()
+	t.Cleanup(b.close)
+	return b
+}
+
+func (b *testBroker) addr() string {
+	return b.ln.Addr().String()
+}
+
+func (b *testBroker) close() {
+	_ = b.ln.Close()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for s := range b.sessions {
+		_ = s.conn.Close()
+	}
+}
+
+// connectPackets returns CONNECT packets received by the broker.
+func (b *testBroker) connectPackets() []*packet {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]*packet{}, b.connects...)
+}
+
+// subscribers returns the number of sessions subscribed to any topic.
+func (b *testBroker) subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n := 0
+	for s := range b.sessions {
+		s.mu.Lock()
+		if len(s.filters) > 0 {
+			n++
+		}
+		s.mu.Unlock()
+	}
+	return n
+}
+
+func (b *testBroker) acceptRoutine() {
+	for {
+		conn, err := b.ln.Accept()
+		if err != nil {
+			return
+		}
+		go b.serve(conn)
+	}
+}
+
+func (b *testBroker) serve(conn net.Conn) {
+	s := &brokerSession{conn: conn}
+	defer func() {
+		b.mu.Lock()
+		delete(b.sessions, s)
+		b.mu.Unlock()
+		_ = conn.Close()
+	}()
+	br := bufio.NewReader(conn)
+	p, err := readPacket(br)
+	if err != nil || p.typ != packetConnect {
+		return
+	}
+	b.mu.Lock()
+	b.connects = append(b.connects, p)
+	b.sessions[s] = struct{}{}
+	b.mu.Unlock()
+	if s.write(&packet{typ: packetConnAck, returnCode: connAckAccepted}) != nil {
+		return
+	}
+	for {
+		p, err := readPacket(br)
+		if err != nil {
+			return
+		}
+		switch p.typ {
+		case packetSubscribe:
+			codes := make([]byte, len(p.subscriptions))
+			s.mu.Lock()
+			for i, sub := range p.subscriptions {
+				s.filters = append(s.filters, sub.filter)
+				codes[i] = sub.qos
+			}
+			s.mu.Unlock()
+			if s.write(&packet{typ: packetSubAck, packetID: p.packetID, returnCodes: codes}) != nil {
+				return
+			}
+		case packetPublish:
+			if p.qos == 1 {
+				if s.write(&packet{typ: packetPubAck, packetID: p.packetID}) != nil {
+					return
+				}
+			}
+			b.route(p.topic, p.payload)
+		case packetPingReq:
+			if s.write(&packet{typ: packetPingResp}) != nil {
+				return
+			}
+		case packetDisconnect:
+			return
+		}
+	}
+}
+
+// route delivers the message to all sessions subscribed to the topic.
+func (b *testBroker) route(topic string, payload []byte) {
+	b.mu.Lock()
+	var sessions []*brokerSession
+	for s := range b.sessions {
+		sessions = append(sessions, s)
+	}
+	b.mu.Unlock()
+	for _, s := range sessions {
+		s.mu.Lock()
+		match := false
+		for _, f := range s.filters {
+			if matchTopic(f, topic) {
+				match = true
+				break
+			}
+		}
+		s.mu.Unlock()
+		if match {
+			_ = s.write(&packet{typ: packetPublish, topic: topic, payload: payload})
+		}
+	}
+}
+
+func (s *brokerSession) write(p *packet) error {
+	b, err := p.encode()
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.conn.Write(b)
+	return err
+}
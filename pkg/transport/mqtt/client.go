@@ -0,0 +1,277 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+var errClientClosed = errors.New("MQTT connection closed")
+
+// Dialer is used to connect to the MQTT broker.
+type Dialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// clientOptions are options of the MQTT client.
+type clientOptions struct {
+	addr      string
+	clientID  string
+	username  string
+	password  string
+	keepAlive time.Duration
+	timeout   time.Duration
+	dialer    Dialer
+	handler   func(topic string, payload []byte)
+}
+
+// client is a minimal MQTT 3.1.1 client. It supports only clean sessions
+// and QoS levels 0 and 1.
+type client struct {
+	mu      sync.Mutex
+	writeMu sync.Mutex
+	conn    net.Conn
+	nextID  uint16
+	pending map[uint16]chan *packet
+	doneCh  chan struct{}
+	err     error
+	opts    clientOptions
+}
+
+// dial connects to the MQTT broker.
+func dial(ctx context.Context, opts clientOptions) (*client, error) {
+	conn, err := opts.dialer.DialContext(ctx, "tcp", opts.addr)
+	if err != nil {
+		return nil, err
+	}
+	c := &client{
+		conn:    conn,
+		pending: make(map[uint16]chan *packet),
+		doneCh:  make(chan struct{}),
+		opts:    opts,
+	}
+	br := bufio.NewReader(conn)
+	if err := conn.SetDeadline(time.Now().Add(opts.timeout)); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	err = c.write(&packet{
+		typ:       packetConnect,
+		clientID:  opts.clientID,
+		username:  opts.username,
+		password:  opts.password,
+		keepAlive: uint16(opts.keepAlive / time.Second),
+	})
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	p, err := readPacket(br)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	if p.typ != packetConnAck {
+		_ = conn.Close()
+		return nil, fmt.Errorf("unexpected MQTT packet type %d, expected CONNACK", p.typ)
+	}
+	if p.returnCode != connAckAccepted {
+		_ = conn.Close()
+		return nil, fmt.Errorf("MQTT connection refused with code %d", p.returnCode)
+	}
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	go c.readRoutine(br)
+	go c.pingRoutine()
+	return c, nil
+}
+
+// publish publishes a message. For QoS 1, it waits for the acknowledgment.
+func (c *client) publish(ctx context.Context, topic string, payload []byte, qos byte) error {
+	p := &packet{typ: packetPublish, topic: topic, payload: payload, qos: qos}
+	if qos == 0 {
+		return c.write(p)
+	}
+	_, err := c.request(ctx, p)
+	return err
+}
+
+// subscribe subscribes to the given topic filters.
+func (c *client) subscribe(ctx context.Context, subs []subscription) error {
+	res, err := c.request(ctx, &packet{typ: packetSubscribe, subscriptions: subs})
+	if err != nil {
+		return err
+	}
+	if len(res.returnCodes) != len(subs) {
+		return errMalformedPacket
+	}
+	for i, code := range res.returnCodes {
+		if code == subAckFailure {
+			return fmt.Errorf("MQTT subscription to %q rejected", subs[i].filter)
+		}
+	}
+	return nil
+}
+
+// disconnect sends the DISCONNECT packet and closes the connection.
+func (c *client) disconnect() {
+	_ = c.write(&packet{typ: packetDisconnect})
+	c.close(errClientClosed)
+}
+
+// done returns a channel that is closed when the connection is closed.
+func (c *client) done() <-chan struct{} {
+	return c.doneCh
+}
+
+// closeErr returns the reason why the connection was closed.
+func (c *client) closeErr() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// request sends a packet with a packet identifier and waits for the
+// response.
+func (c *client) request(ctx context.Context, p *packet) (*packet, error) {
+	ch := make(chan *packet, 1)
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return nil, c.err
+	}
+	c.nextID++
+	if c.nextID == 0 {
+		c.nextID = 1
+	}
+	p.packetID = c.nextID
+	c.pending[p.packetID] = ch
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, p.packetID)
+		c.mu.Unlock()
+	}()
+	if err := c.write(p); err != nil {
+		return nil, err
+	}
+	timer := time.NewTimer(c.opts.timeout)
+	defer timer.Stop()
+	select {
+	case res := <-ch:
+		return res, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-c.doneCh:
+		return nil, c.closeErr()
+	case <-timer.C:
+		return nil, errors.New("MQTT request timed out")
+	}
+}
+
+func (c *client) write(p *packet) error {
+	b, err := p.encode()
+	if err != nil {
+		return err
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if err := c.conn.SetWriteDeadline(time.Now().Add(c.opts.timeout)); err != nil {
+		return err
+	}
+	_, err = c.conn.Write(b)
+	return err
+}
+
+func (c *client) close(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return
+	}
+	c.err = err
+	_ = c.conn.Close()
+	close(c.doneCh)
+}
+
+// readRoutine reads packets from the broker. If no packet is received
+// within one and a half of the keep alive interval, the connection is
+// considered broken.
+func (c *client) readRoutine(br *bufio.Reader) {
+	for {
+		if err := c.conn.SetReadDeadline(time.Now().Add(c.opts.keepAlive * 3 / 2)); err != nil {
+			c.close(err)
+			return
+		}
+		p, err := readPacket(br)
+		if err != nil {
+			c.close(err)
+			return
+		}
+		switch p.typ {
+		case packetPublish:
+			if p.qos > 1 {
+				c.close(fmt.Errorf("unsupported MQTT QoS level %d", p.qos))
+				return
+			}
+			if p.qos == 1 {
+				if err := c.write(&packet{typ: packetPubAck, packetID: p.packetID}); err != nil {
+					c.close(err)
+					return
+				}
+			}
+			c.opts.handler(p.topic, p.payload)
+		case packetPubAck, packetSubAck:
+			c.mu.Lock()
+			if ch, ok := c.pending[p.packetID]; ok {
+				select {
+				case ch <- p:
+				default: // Duplicated acknowledgment.
+				}
+			}
+			c.mu.Unlock()
+		case packetPingResp:
+		default:
+			c.close(fmt.Errorf("unexpected MQTT packet type %d", p.typ))
+			return
+		}
+	}
+}
+
+// pingRoutine sends PINGREQ packets to keep the connection alive.
+func (c *client) pingRoutine() {
+	t := time.NewTicker(c.opts.keepAlive / 2)
+	defer t.Stop()
+	for {
+		select {
+		case <-c.doneCh:
+			return
+		case <-t.C:
+			if err := c.write(&packet{typ: packetPingReq}); err != nil {
+				c.close(err)
+				return
+			}
+		}
+	}
+}
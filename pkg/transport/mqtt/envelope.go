@@ -0,0 +1,260 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package mqtt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/defiweb/go-eth/crypto"
+	"github.com/defiweb/go-eth/types"
+	"github.com/defiweb/go-eth/wallet"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	pb "github.com/libp2p/go-libp2p-pubsub/pb"
+	"github.com/libp2p/go-libp2p/core/peer"
+
+	"github.com/orcfax/oracle-suite/pkg/transport/libp2p/crypto/ethkey"
+)
+
+// seqnoLength is the length of the sequence number in libp2p messages.
+const seqnoLength = 8
+
+// replayWindow is the number of sequence numbers below the highest one
+// received from an author for which envelopes are still accepted. Envelopes
+// may arrive out of order because the broker does not guarantee ordering
+// across topics.
+const replayWindow = 1024
+
+// envelope is a message signed by its author.
+//
+// The envelope has the same structure and signature as libp2p pubsub
+// messages signed with Ethereum keys, so messages received from the libp2p
+// transport are forwarded with the original signature of their author,
+// instead of being signed again by the bridge.
+//
+// Payloads in the protobuf format are encoded as the libp2p pubsub message.
+// Payloads in the JSON format are encoded as an object with the "author",
+// "seqno", "topic", "data" and "signature" fields, and an optional "message"
+// field with the JSON representation of the message. The "message" field is
+// not signed, receivers decode the message from the "data" field.
+//
+// The signature covers the transport topic name, so a message cannot be
+// replayed on a different topic, and the sequence number, so a message
+// cannot be replayed at all, see the replayGuard type.
+type envelope struct {
+	Author    types.Address
+	Seqno     uint64
+	Topic     string
+	Data      []byte
+	Signature types.Signature
+
+	// Message is the JSON representation of the message. It is not signed.
+	Message json.RawMessage
+}
+
+type jsonEnvelope struct {
+	Author    types.Address   `json:"author"`
+	Seqno     uint64          `json:"seqno,string"`
+	Topic     string          `json:"topic"`
+	Data      []byte          `json:"data"`
+	Signature types.Signature `json:"signature"`
+	Message   json.RawMessage `json:"message,omitempty"`
+}
+
+// envelopeFromPubsub returns the envelope with the original libp2p message
+// signed by its author. The message must be signed with an Ethereum key.
+func envelopeFromPubsub(msg *pubsub.Message) (envelope, error) {
+	if msg.Message == nil {
+		return envelope{}, errors.New("missing message")
+	}
+	return envelopeFromPB(msg.Message)
+}
+
+// signingData returns the data signed by the author. It is the same data
+// as signed by libp2p.
+func (e envelope) signingData() ([]byte, error) {
+	m := e.pb()
+	m.Signature = nil
+	b, err := m.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(pubsub.SignPrefix), b...), nil
+}
+
+// sign signs the envelope using the given key.
+func (e *envelope) sign(signer wallet.Key) error {
+	e.Author = signer.Address()
+	data, err := e.signingData()
+	if err != nil {
+		return err
+	}
+	sig, err := signer.SignMessage(data)
+	if err != nil {
+		return err
+	}
+	e.Signature = *sig
+	return nil
+}
+
+// verify verifies that the envelope is signed by its author for the given
+// topic.
+func (e envelope) verify(topic string, recover crypto.Recoverer) error {
+	if e.Topic != topic {
+		return fmt.Errorf("envelope is signed for topic %s", e.Topic)
+	}
+	data, err := e.signingData()
+	if err != nil {
+		return err
+	}
+	author, err := recover.RecoverMessage(data, e.Signature)
+	if err != nil {
+		return err
+	}
+	if *author != e.Author {
+		return fmt.Errorf("envelope is signed by %s instead of the author", author)
+	}
+	return nil
+}
+
+// encode returns the payload published to the broker.
+func (e envelope) encode(format Format) ([]byte, error) {
+	switch format {
+	case FormatProtobuf:
+		return e.pb().Marshal()
+	case FormatJSON:
+		return json.Marshal(jsonEnvelope(e))
+	default:
+		return nil, fmt.Errorf("unsupported format %s", format)
+	}
+}
+
+// pb returns the envelope as a libp2p pubsub message.
+func (e envelope) pb() *pb.Message {
+	seqno := make([]byte, seqnoLength)
+	binary.BigEndian.PutUint64(seqno, e.Seqno)
+	topic := e.Topic
+	return &pb.Message{
+		From:      []byte(ethkey.AddressToPeerID(e.Author)),
+		Data:      e.Data,
+		Seqno:     seqno,
+		Topic:     &topic,
+		Signature: e.Signature.Bytes(),
+	}
+}
+
+// decodeEnvelope decodes the payload received from the broker.
+func decodeEnvelope(payload []byte, format Format) (envelope, error) {
+	switch format {
+	case FormatProtobuf:
+		var m pb.Message
+		if err := m.Unmarshal(payload); err != nil {
+			return envelope{}, err
+		}
+		return envelopeFromPB(&m)
+	case FormatJSON:
+		var e jsonEnvelope
+		if err := json.Unmarshal(payload, &e); err != nil {
+			return envelope{}, err
+		}
+		if len(e.Data) == 0 {
+			return envelope{}, errors.New("missing data")
+		}
+		return envelope(e), nil
+	default:
+		return envelope{}, fmt.Errorf("unsupported format %s", format)
+	}
+}
+
+// envelopeFromPB converts a libp2p pubsub message to the envelope.
+func envelopeFromPB(m *pb.Message) (envelope, error) {
+	author := ethkey.PeerIDToAddress(peer.ID(m.From))
+	if !bytes.Equal(m.From, []byte(ethkey.AddressToPeerID(author))) {
+		return envelope{}, errors.New("author is not an Ethereum address")
+	}
+	if len(m.Seqno) != seqnoLength {
+		return envelope{}, errors.New("invalid sequence number")
+	}
+	if len(m.Data) == 0 {
+		return envelope{}, errors.New("missing data")
+	}
+	sig, err := types.SignatureFromBytes(m.Signature)
+	if err != nil {
+		return envelope{}, err
+	}
+	return envelope{
+		Author:    author,
+		Seqno:     binary.BigEndian.Uint64(m.Seqno),
+		Topic:     m.GetTopic(),
+		Data:      m.Data,
+		Signature: sig,
+	}, nil
+}
+
+// replayGuard rejects envelopes that were already received.
+//
+// Sequence numbers of libp2p messages are initialized with the current time
+// and incremented for every message, so they increase even if the author is
+// restarted. The guard remembers the highest sequence number received from
+// every author and rejects envelopes with the same or much lower sequence
+// numbers.
+type replayGuard struct {
+	mu      sync.Mutex
+	authors map[types.Address]*seqnoWindow
+}
+
+type seqnoWindow struct {
+	max  uint64
+	seen map[uint64]struct{}
+}
+
+func newReplayGuard() *replayGuard {
+	return &replayGuard{authors: make(map[types.Address]*seqnoWindow)}
+}
+
+// accept returns true if the envelope with the given sequence number was not
+// received from the author before.
+func (g *replayGuard) accept(author types.Address, seqno uint64) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	w, ok := g.authors[author]
+	if !ok {
+		g.authors[author] = &seqnoWindow{max: seqno, seen: map[uint64]struct{}{seqno: {}}}
+		return true
+	}
+	if seqno+replayWindow <= w.max {
+		return false
+	}
+	if _, ok := w.seen[seqno]; ok {
+		return false
+	}
+	w.seen[seqno] = struct{}{}
+	if seqno > w.max {
+		w.max = seqno
+	}
+	if len(w.seen) > replayWindow {
+		for s := range w.seen {
+			if s+replayWindow <= w.max {
+				delete(w.seen, s)
+			}
+		}
+	}
+	return true
+}
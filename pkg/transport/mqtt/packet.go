@@ -0,0 +1,322 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Control packet types defined in the MQTT 3.1.1 specification.
+const (
+	packetConnect    byte = 1
+	packetConnAck    byte = 2
+	packetPublish    byte = 3
+	packetPubAck     byte = 4
+	packetSubscribe  byte = 8
+	packetSubAck     byte = 9
+	packetPingReq    byte = 12
+	packetPingResp   byte = 13
+	packetDisconnect byte = 14
+)
+
+const (
+	protocolName         = "MQTT"
+	protocolLevel   byte = 4 // MQTT 3.1.1
+	maxRemainingLen      = 268435455
+
+	connAckAccepted byte = 0
+	subAckFailure   byte = 0x80
+
+	connectFlagClean    byte = 0x02
+	connectFlagPassword byte = 0x40
+	connectFlagUsername byte = 0x80
+)
+
+var errMalformedPacket = errors.New("malformed MQTT packet")
+
+// subscription is a topic filter with the requested QoS.
+type subscription struct {
+	filter string
+	qos    byte
+}
+
+// packet is an MQTT control packet. Only fields relevant to the packet type
+// are used.
+type packet struct {
+	typ byte
+
+	// CONNECT:
+	clientID  string
+	username  string
+	password  string
+	keepAlive uint16
+
+	// CONNACK:
+	returnCode byte
+
+	// PUBLISH, PUBACK, SUBSCRIBE, SUBACK:
+	packetID uint16
+
+	// PUBLISH:
+	topic   string
+	payload []byte
+	qos     byte
+	retain  bool
+	dup     bool
+
+	// SUBSCRIBE:
+	subscriptions []subscription
+
+	// SUBACK:
+	returnCodes []byte
+}
+
+// encode returns the binary representation of the packet.
+func (p *packet) encode() ([]byte, error) {
+	var (
+		flags byte
+		body  []byte
+	)
+	switch p.typ {
+	case packetConnect:
+		var cf byte = connectFlagClean
+		if p.username != "" {
+			cf |= connectFlagUsername
+		}
+		if p.password != "" {
+			cf |= connectFlagPassword
+		}
+		body = appendString(body, protocolName)
+		body = append(body, protocolLevel, cf)
+		body = binary.BigEndian.AppendUint16(body, p.keepAlive)
+		body = appendString(body, p.clientID)
+		if p.username != "" {
+			body = appendString(body, p.username)
+		}
+		if p.password != "" {
+			body = appendString(body, p.password)
+		}
+	case packetConnAck:
+		body = []byte{0, p.returnCode}
+	case packetPublish:
+		flags = p.qos << 1
+		if p.retain {
+			flags |= 0x01
+		}
+		if p.dup {
+			flags |= 0x08
+		}
+		body = appendString(body, p.topic)
+		if p.qos > 0 {
+			body = binary.BigEndian.AppendUint16(body, p.packetID)
+		}
+		body = append(body, p.payload...)
+	case packetPubAck:
+		body = binary.BigEndian.AppendUint16(body, p.packetID)
+	case packetSubscribe:
+		flags = 0x02
+		body = binary.BigEndian.AppendUint16(body, p.packetID)
+		for _, s := range p.subscriptions {
+			body = appendString(body, s.filter)
+			body = append(body, s.qos)
+		}
+	case packetSubAck:
+		body = binary.BigEndian.AppendUint16(body, p.packetID)
+		body = append(body, p.returnCodes...)
+	case packetPingReq, packetPingResp, packetDisconnect:
+	default:
+		return nil, fmt.Errorf("unsupported MQTT packet type %d", p.typ)
+	}
+	if len(body) > maxRemainingLen {
+		return nil, errors.New("MQTT packet too large")
+	}
+	b := []byte{p.typ<<4 | flags}
+	b = appendRemainingLength(b, len(body))
+	return append(b, body...), nil
+}
+
+// readPacket reads a single MQTT control packet.
+func readPacket(r *bufio.Reader) (*packet, error) {
+	head, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	size, err := readRemainingLength(r)
+	if err != nil {
+		return nil, err
+	}
+	body := make([]byte, size)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	p := &packet{typ: head >> 4}
+	flags := head & 0x0f
+	d := decoder{b: body}
+	switch p.typ {
+	case packetConnect:
+		if d.string() != protocolName {
+			return nil, errMalformedPacket
+		}
+		level, cf := d.byte(), d.byte()
+		if level != protocolLevel {
+			return nil, fmt.Errorf("unsupported MQTT protocol level %d", level)
+		}
+		p.keepAlive = d.uint16()
+		p.clientID = d.string()
+		if cf&connectFlagUsername != 0 {
+			p.username = d.string()
+		}
+		if cf&connectFlagPassword != 0 {
+			p.password = d.string()
+		}
+	case packetConnAck:
+		d.byte()
+		p.returnCode = d.byte()
+	case packetPublish:
+		p.qos = (flags >> 1) & 0x03
+		p.retain = flags&0x01 != 0
+		p.dup = flags&0x08 != 0
+		p.topic = d.string()
+		if p.qos > 0 {
+			p.packetID = d.uint16()
+		}
+		p.payload = d.rest()
+	case packetPubAck:
+		p.packetID = d.uint16()
+	case packetSubscribe:
+		p.packetID = d.uint16()
+		for !d.empty() {
+			p.subscriptions = append(p.subscriptions, subscription{filter: d.string(), qos: d.byte()})
+		}
+	case packetSubAck:
+		p.packetID = d.uint16()
+		p.returnCodes = d.rest()
+	case packetPingReq, packetPingResp, packetDisconnect:
+	default:
+		return nil, fmt.Errorf("unsupported MQTT packet type %d", p.typ)
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	return p, nil
+}
+
+// matchTopic reports whether the topic name matches the topic filter. The
+// filter may contain the single-level (+) and multi-level (#) wildcards.
+func matchTopic(filter, topic string) bool {
+	fs := strings.Split(filter, "/")
+	ts := strings.Split(topic, "/")
+	for i, f := range fs {
+		if f == "#" {
+			return true
+		}
+		if i >= len(ts) {
+			return false
+		}
+		if f != "+" && f != ts[i] {
+			return false
+		}
+	}
+	return len(fs) == len(ts)
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+func appendRemainingLength(b []byte, n int) []byte {
+	for {
+		c := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			c |= 0x80
+		}
+		b = append(b, c)
+		if n == 0 {
+			return b
+		}
+	}
+}
+
+func readRemainingLength(r io.ByteReader) (int, error) {
+	var n, mul int = 0, 1
+	for i := 0; i < 4; i++ {
+		c, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		n += int(c&0x7f) * mul
+		if c&0x80 == 0 {
+			return n, nil
+		}
+		mul *= 128
+	}
+	return 0, errMalformedPacket
+}
+
+// decoder reads MQTT data types from a packet body. After the first
+// error, all methods return zero values.
+type decoder struct {
+	b   []byte
+	err error
+}
+
+func (d *decoder) empty() bool {
+	return d.err != nil || len(d.b) == 0
+}
+
+func (d *decoder) byte() byte {
+	if d.err != nil || len(d.b) < 1 {
+		d.err = errMalformedPacket
+		return 0
+	}
+	v := d.b[0]
+	d.b = d.b[1:]
+	return v
+}
+
+func (d *decoder) uint16() uint16 {
+	if d.err != nil || len(d.b) < 2 {
+		d.err = errMalformedPacket
+		return 0
+	}
+	v := binary.BigEndian.Uint16(d.b)
+	d.b = d.b[2:]
+	return v
+}
+
+func (d *decoder) string() string {
+	n := int(d.uint16())
+	if d.err != nil || len(d.b) < n {
+		d.err = errMalformedPacket
+		return ""
+	}
+	v := string(d.b[:n])
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *decoder) rest() []byte {
+	v := append([]byte{}, d.b...)
+	d.b = nil
+	return v
+}
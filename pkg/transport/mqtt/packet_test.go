@@ -0,0 +1,120 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package mqtt
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPacket_EncodeDecode(t *testing.T) {
+	tests := []struct {
+		name   string
+		packet *packet
+	}{
+		{
+			name: "connect",
+			packet: &packet{
+				typ:       packetConnect,
+				clientID:  "client",
+				username:  "user",
+				password:  "pass",
+				keepAlive: 30,
+			},
+		},
+		{
+			name:   "connack",
+			packet: &packet{typ: packetConnAck, returnCode: 5},
+		},
+		{
+			name:   "publish qos 0",
+			packet: &packet{typ: packetPublish, topic: "a/b", payload: []byte("data")},
+		},
+		{
+			name:   "publish qos 1",
+			packet: &packet{typ: packetPublish, topic: "a/b", payload: []byte("data"), qos: 1, packetID: 42, retain: true},
+		},
+		{
+			name:   "publish large",
+			packet: &packet{typ: packetPublish, topic: "a", payload: bytes.Repeat([]byte{1}, 20000)},
+		},
+		{
+			name:   "puback",
+			packet: &packet{typ: packetPubAck, packetID: 7},
+		},
+		{
+			name: "subscribe",
+			packet: &packet{typ: packetSubscribe, packetID: 1, subscriptions: []subscription{
+				{filter: "a/+", qos: 1},
+				{filter: "b/#", qos: 0},
+			}},
+		},
+		{
+			name:   "suback",
+			packet: &packet{typ: packetSubAck, packetID: 1, returnCodes: []byte{1, subAckFailure}},
+		},
+		{
+			name:   "pingreq",
+			packet: &packet{typ: packetPingReq},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := tt.packet.encode()
+			require.NoError(t, err)
+			p, err := readPacket(bufio.NewReader(bytes.NewReader(b)))
+			require.NoError(t, err)
+			if len(tt.packet.payload) == 0 && p.payload != nil && len(p.payload) == 0 {
+				p.payload = tt.packet.payload
+			}
+			if tt.packet.returnCodes == nil && len(p.returnCodes) == 0 {
+				p.returnCodes = nil
+			}
+			assert.Equal(t, tt.packet, p)
+		})
+	}
+}
+
+func TestPacket_Malformed(t *testing.T) {
+	_, err := readPacket(bufio.NewReader(strings.NewReader("\x30\x01\x00")))
+	assert.Error(t, err)
+	_, err = readPacket(bufio.NewReader(strings.NewReader("\x30\xff\xff\xff\xff")))
+	assert.Error(t, err)
+}
+
+func TestMatchTopic(t *testing.T) {
+	tests := []struct {
+		filter string
+		topic  string
+		want   bool
+	}{
+		{filter: "a/b", topic: "a/b", want: true},
+		{filter: "a/b", topic: "a/c", want: false},
+		{filter: "a/+", topic: "a/b", want: true},
+		{filter: "a/+", topic: "a/b/c", want: false},
+		{filter: "a/#", topic: "a/b/c", want: true},
+		{filter: "#", topic: "a", want: true},
+		{filter: "a/b/c", topic: "a/b", want: false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, matchTopic(tt.filter, tt.topic), "%s %s", tt.filter, tt.topic)
+	}
+}
@@ -0,0 +1,227 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package private
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/defiweb/go-eth/crypto"
+	"github.com/defiweb/go-eth/types"
+	"github.com/defiweb/go-eth/wallet"
+)
+
+const (
+	// encryptionKeySize is the size of the X25519 public key.
+	encryptionKeySize = 32
+
+	// nonceSize is the size of the AES-GCM nonce.
+	nonceSize = 12
+)
+
+var errInvalidMessage = errors.New("invalid message")
+
+// Recipient is a group key encrypted for a single member.
+type Recipient struct {
+	Address types.Address // Address of the member.
+	Key     []byte        // Nonce and encrypted key.
+}
+
+// Announcement is a signed message used to distribute encryption keys
+// between members. It is published on the AnnouncementMessageName topic.
+//
+// Every member announces its X25519 public key, and the current group key
+// used to encrypt messages it sends, encrypted separately for every known
+// member. Announcements are relayed by non-members, but only members can
+// decrypt the group key.
+type Announcement struct {
+	Time          time.Time       // Time of the announcement.
+	Epoch         uint64          // Identifier of the announced group key.
+	Request       bool            // Asks other members to announce their keys.
+	EncryptionKey []byte          // X25519 public key of the author.
+	Recipients    []Recipient     // Group key encrypted for members.
+	Signature     types.Signature // Signature of the above fields.
+}
+
+func (a Announcement) MarshalJSON() ([]byte, error) {
+	recipients := make([]string, len(a.Recipients))
+	for i, r := range a.Recipients {
+		recipients[i] = r.Address.String()
+	}
+	return json.Marshal(map[string]any{
+		"time":       a.Time.Unix(),
+		"epoch":      a.Epoch,
+		"request":    a.Request,
+		"recipients": recipients,
+		"signature":  a.Signature.String(),
+	})
+}
+
+// MarshallBinary implements the transport.Message interface.
+func (a *Announcement) MarshallBinary() ([]byte, error) {
+	b := a.body()
+	sig := a.Signature.Bytes()
+	b = append(b, byte(len(sig)))
+	b = append(b, sig...)
+	return b, nil
+}
+
+// UnmarshallBinary implements the transport.Message interface.
+func (a *Announcement) UnmarshallBinary(data []byte) (err error) {
+	d := decoder{b: data}
+	a.Time = time.Unix(0, int64(d.uint64()))
+	a.Epoch = d.uint64()
+	a.Request = d.byte() == 1
+	a.EncryptionKey = d.bytes(encryptionKeySize)
+	n := int(d.uint16())
+	a.Recipients = nil
+	for i := 0; i < n && d.err == nil; i++ {
+		var r Recipient
+		r.Address, _ = types.AddressFromBytes(d.bytes(types.AddressLength))
+		r.Key = d.bytes(int(d.uint16()))
+		a.Recipients = append(a.Recipients, r)
+	}
+	sig := d.bytes(int(d.byte()))
+	if d.err != nil || len(d.b) != 0 {
+		return errInvalidMessage
+	}
+	a.Signature, err = types.SignatureFromBytes(sig)
+	return err
+}
+
+// sign signs the announcement using the given key.
+func (a *Announcement) sign(signer wallet.Key) error {
+	sig, err := signer.SignMessage(a.signingData())
+	if err != nil {
+		return err
+	}
+	a.Signature = *sig
+	return nil
+}
+
+// author returns the address of the announcement signer.
+func (a *Announcement) author(recoverer crypto.Recoverer) (types.Address, error) {
+	addr, err := recoverer.RecoverMessage(a.signingData(), a.Signature)
+	if err != nil {
+		return types.ZeroAddress, err
+	}
+	return *addr, nil
+}
+
+// signingData returns the data signed by the announcement author.
+func (a *Announcement) signingData() []byte {
+	return append([]byte("private"), a.body()...)
+}
+
+// body encodes the announcement without the signature.
+func (a *Announcement) body() []byte {
+	var b []byte
+	b = binary.BigEndian.AppendUint64(b, uint64(a.Time.UnixNano()))
+	b = binary.BigEndian.AppendUint64(b, a.Epoch)
+	if a.Request {
+		b = append(b, 1)
+	} else {
+		b = append(b, 0)
+	}
+	b = append(b, a.EncryptionKey...)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(a.Recipients)))
+	for _, r := range a.Recipients {
+		b = append(b, r.Address.Bytes()...)
+		b = binary.BigEndian.AppendUint16(b, uint16(len(r.Key)))
+		b = append(b, r.Key...)
+	}
+	return b
+}
+
+// Encrypted is a message encrypted using the group key of its author.
+// Encrypted messages are published on private topics in place of the
+// original messages.
+type Encrypted struct {
+	Epoch uint64 // Identifier of the group key.
+	Nonce []byte // AES-GCM nonce.
+	Data  []byte // Encrypted message.
+}
+
+func (e Encrypted) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"epoch": e.Epoch,
+		"size":  len(e.Data),
+	})
+}
+
+// MarshallBinary implements the transport.Message interface.
+func (e *Encrypted) MarshallBinary() ([]byte, error) {
+	if len(e.Nonce) != nonceSize {
+		return nil, errInvalidMessage
+	}
+	b := make([]byte, 0, 8+nonceSize+len(e.Data))
+	b = binary.BigEndian.AppendUint64(b, e.Epoch)
+	b = append(b, e.Nonce...)
+	b = append(b, e.Data...)
+	return b, nil
+}
+
+// UnmarshallBinary implements the transport.Message interface.
+func (e *Encrypted) UnmarshallBinary(data []byte) error {
+	d := decoder{b: data}
+	e.Epoch = d.uint64()
+	e.Nonce = d.bytes(nonceSize)
+	if d.err != nil || len(d.b) == 0 {
+		return errInvalidMessage
+	}
+	e.Data = d.b
+	return nil
+}
+
+// decoder reads values from a byte slice. After the first error, all
+// subsequent reads return zero values.
+type decoder struct {
+	b   []byte
+	err error
+}
+
+func (d *decoder) bytes(n int) []byte {
+	if d.err != nil || len(d.b) < n {
+		d.err = errInvalidMessage
+		return nil
+	}
+	v := d.b[:n:n]
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *decoder) byte() byte {
+	if b := d.bytes(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (d *decoder) uint16() uint16 {
+	if b := d.bytes(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (d *decoder) uint64() uint64 {
+	if b := d.bytes(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+	return 0
+}
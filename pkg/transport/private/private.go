@@ -0,0 +1,636 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package private
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/defiweb/go-eth/crypto"
+	"github.com/defiweb/go-eth/types"
+	"github.com/defiweb/go-eth/wallet"
+
+	"github.com/orcfax/oracle-suite/pkg/log"
+	"github.com/orcfax/oracle-suite/pkg/log/null"
+	"github.com/orcfax/oracle-suite/pkg/supervisor"
+	"github.com/orcfax/oracle-suite/pkg/transport"
+	"github.com/orcfax/oracle-suite/pkg/util/maputil"
+)
+
+const (
+	LoggerTag = "PRIVATE"
+
+	// AnnouncementMessageName is the name of the topic on which group keys
+	// are announced.
+	AnnouncementMessageName = "private_announcement/v1"
+
+	// groupKeySize is the size of the AES-256 group key.
+	groupKeySize = 32
+
+	// keptEpochs is the number of the most recent group keys kept for each
+	// member. Older keys are kept to decrypt messages sent shortly before
+	// the key rotation.
+	keptEpochs = 2
+
+	// defaultRotationInterval is the default interval between group key
+	// rotations.
+	defaultRotationInterval = time.Hour
+
+	// minRequestInterval is the minimum time between key requests sent after
+	// receiving a message encrypted with an unknown key.
+	minRequestInterval = time.Minute
+)
+
+// ErrUnknownKey is returned in the ReceivedMessage.Error field when a
+// message is encrypted with a group key that was not announced to the node.
+var ErrUnknownKey = errors.New("message is encrypted with an unknown group key")
+
+// ErrNotMember is returned when a node that is not a member of the group
+// tries to broadcast a message on a private topic.
+var ErrNotMember = errors.New("node is not a member of the private group")
+
+// Private is a transport decorator that encrypts messages on private topics
+// so that they can be read only by members of a group defined by a list of
+// Ethereum addresses.
+//
+// Each member encrypts messages it sends using its own group key. The group
+// key is distributed to other members in signed announcements, encrypted
+// separately for every member using the X25519 key exchange. Group keys are
+// rotated periodically, and a new key is announced on every rotation.
+//
+// Nodes that are not members of the group can relay encrypted messages
+// without being able to read them. For such nodes, the Messages method
+// returns nil for private topics.
+//
+// The decorated transport must use the Encrypted and Announcement types for
+// private topics and the announcement topic, see the Topics function.
+type Private struct {
+	mu     sync.RWMutex
+	ctx    context.Context
+	waitCh chan error
+
+	// State fields:
+	encryptionKey *ecdh.PrivateKey          // Key used to receive group keys.
+	groupKey      []byte                    // Current group key.
+	epoch         uint64                    // Identifier of the current group key.
+	members       map[types.Address]*member // Keys announced by members.
+	lastRequest   time.Time                 // Time of the last key request.
+
+	// Configuration fields:
+	transport        transport.Service
+	topics           map[string]transport.Message
+	allowlist        map[types.Address]struct{}
+	signer           wallet.Key
+	recoverer        crypto.Recoverer
+	rotationInterval time.Duration
+	rand             io.Reader
+	log              log.Logger
+}
+
+// member holds keys announced by a group member.
+type member struct {
+	time          time.Time         // Time of the last announcement.
+	encryptionKey *ecdh.PublicKey   // Key used to send group keys to the member.
+	groupKeys     map[uint64][]byte // Group keys used by the member, by epoch.
+}
+
+// Config is a configuration of Private.
+type Config struct {
+	// Transport is the decorated transport.
+	//
+	// Cannot be nil.
+	Transport transport.Service
+
+	// Topics is a list of private topics. A value of the map a type of
+	// message given as a nil pointer, e.g.: (*Message)(nil).
+	Topics map[string]transport.Message
+
+	// Members is a list of addresses of group members.
+	//
+	// Cannot be empty.
+	Members []types.Address
+
+	// Signer is a key used to sign announcements. If nil, or the address
+	// of the key is not on the Members list, the node only relays encrypted
+	// messages.
+	Signer wallet.Key
+
+	// Recoverer is used to recover addresses of announcement authors. If
+	// nil, the default recoverer is used.
+	Recoverer crypto.Recoverer
+
+	// RotationInterval is the interval between group key rotations. If
+	// zero, default value will be used (1 hour).
+	RotationInterval time.Duration
+
+	// Rand is a source of randomness. If nil, crypto/rand is used.
+	Rand io.Reader
+
+	// Logger is a custom logger instance. If not provided then null
+	// logger is used.
+	Logger log.Logger
+}
+
+// New returns a new instance of the Private transport.
+func New(cfg Config) (*Private, error) {
+	if cfg.Transport == nil {
+		return nil, errors.New("transport must not be nil")
+	}
+	if len(cfg.Members) == 0 {
+		return nil, errors.New("members list must not be empty")
+	}
+	for topic, typ := range cfg.Topics {
+		if topic == AnnouncementMessageName {
+			return nil, fmt.Errorf("topic %s is reserved for announcements", topic)
+		}
+		if typ == nil || reflect.TypeOf(typ).Kind() != reflect.Ptr {
+			return nil, fmt.Errorf("message type for topic %s must be a pointer", topic)
+		}
+	}
+	if cfg.Recoverer == nil {
+		cfg.Recoverer = crypto.ECRecoverer
+	}
+	if cfg.RotationInterval == 0 {
+		cfg.RotationInterval = defaultRotationInterval
+	}
+	if cfg.Rand == nil {
+		cfg.Rand = rand.Reader
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = null.New()
+	}
+	p := &Private{
+		waitCh:           make(chan error),
+		members:          make(map[types.Address]*member),
+		transport:        cfg.Transport,
+		topics:           cfg.Topics,
+		allowlist:        make(map[types.Address]struct{}),
+		recoverer:        cfg.Recoverer,
+		rotationInterval: cfg.RotationInterval,
+		rand:             cfg.Rand,
+		log:              cfg.Logger.WithField("tag", LoggerTag),
+	}
+	for _, addr := range cfg.Members {
+		p.allowlist[addr] = struct{}{}
+	}
+	if cfg.Signer != nil {
+		if _, ok := p.allowlist[cfg.Signer.Address()]; ok {
+			p.signer = cfg.Signer
+		}
+	}
+	if p.signer != nil {
+		var err error
+		if p.encryptionKey, err = ecdh.X25519().GenerateKey(p.rand); err != nil {
+			return nil, fmt.Errorf("unable to generate encryption key: %w", err)
+		}
+		if err := p.rotate(); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// Topics returns a copy of the given message map in which message types of
+// private topics are replaced with the Encrypted type. The announcement
+// topic is added to the map. The returned map should be used to configure
+// the decorated transport.
+func Topics(m map[string]transport.Message, topics []string) map[string]transport.Message {
+	r := make(map[string]transport.Message, len(m)+len(topics)+1)
+	for topic, typ := range m {
+		r[topic] = typ
+	}
+	for _, topic := range topics {
+		r[topic] = (*Encrypted)(nil)
+	}
+	r[AnnouncementMessageName] = (*Announcement)(nil)
+	return r
+}
+
+// IsMember returns true if the node is a member of the group.
+func (p *Private) IsMember() bool {
+	return p.signer != nil
+}
+
+// Start implements the supervisor.Service interface.
+func (p *Private) Start(ctx context.Context) error {
+	if p.ctx != nil {
+		return errors.New("service can be started only once")
+	}
+	if ctx == nil {
+		return errors.New("context must not be nil")
+	}
+	p.log.
+		WithField("member", p.IsMember()).
+		Debug("Starting")
+	if err := p.transport.Start(ctx); err != nil {
+		return err
+	}
+	p.ctx = ctx
+	if p.IsMember() {
+		ch := p.transport.Messages(AnnouncementMessageName)
+		if ch == nil {
+			return fmt.Errorf("topic %s is not supported by the decorated transport", AnnouncementMessageName)
+		}
+		go p.announcementRoutine(ctx, ch)
+		go p.rotationRoutine(ctx)
+		if err := p.announce(true); err != nil {
+			p.log.
+				WithError(err).
+				Warn("Unable to announce group key")
+		}
+	}
+	go p.contextCancelHandler()
+	return nil
+}
+
+// Wait implements the supervisor.Service interface.
+func (p *Private) Wait() <-chan error {
+	return p.waitCh
+}
+
+// Broadcast implements the transport.Transport interface.
+func (p *Private) Broadcast(topic string, message transport.Message) error {
+	if _, ok := p.topics[topic]; !ok {
+		return p.transport.Broadcast(topic, message)
+	}
+	if !p.IsMember() {
+		return ErrNotMember
+	}
+	data, err := message.MarshallBinary()
+	if err != nil {
+		return err
+	}
+	p.mu.RLock()
+	key, epoch := p.groupKey, p.epoch
+	p.mu.RUnlock()
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, nonceSize)
+	if _, err := io.ReadFull(p.rand, nonce); err != nil {
+		return err
+	}
+	return p.transport.Broadcast(topic, &Encrypted{
+		Epoch: epoch,
+		Nonce: nonce,
+		Data:  aead.Seal(nil, nonce, data, messageAD(topic, epoch)),
+	})
+}
+
+// Messages implements the transport.Transport interface.
+func (p *Private) Messages(topic string) <-chan transport.ReceivedMessage {
+	typ, ok := p.topics[topic]
+	if !ok {
+		return p.transport.Messages(topic)
+	}
+	if !p.IsMember() {
+		return nil
+	}
+	in := p.transport.Messages(topic)
+	if in == nil {
+		return nil
+	}
+	out := make(chan transport.ReceivedMessage)
+	go func() {
+		defer close(out)
+		for msg := range in {
+			if msg.Error == nil {
+				msg.Message, msg.Error = p.decrypt(topic, typ, msg)
+			}
+			out <- msg
+		}
+	}()
+	return out
+}
+
+// ServiceName implements the supervisor.WithName interface.
+func (p *Private) ServiceName() string {
+	return fmt.Sprintf("Private(%s)", supervisor.ServiceName(p.transport))
+}
+
+// decrypt decrypts a message received on a private topic.
+func (p *Private) decrypt(topic string, typ transport.Message, msg transport.ReceivedMessage) (transport.Message, error) {
+	encrypted, ok := msg.Message.(*Encrypted)
+	if !ok {
+		return nil, fmt.Errorf("unexpected message type %T, expected encrypted message", msg.Message)
+	}
+	author, err := types.AddressFromBytes(msg.Author)
+	if err != nil {
+		return nil, fmt.Errorf("invalid message author: %w", err)
+	}
+	p.mu.RLock()
+	var key []byte
+	if m, ok := p.members[author]; ok {
+		key = m.groupKeys[encrypted.Epoch]
+	}
+	p.mu.RUnlock()
+	if key == nil {
+		p.requestKeys()
+		return nil, ErrUnknownKey
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := aead.Open(nil, encrypted.Nonce, encrypted.Data, messageAD(topic, encrypted.Epoch))
+	if err != nil {
+		return nil, fmt.Errorf("unable to decrypt message: %w", err)
+	}
+	message := reflect.New(reflect.TypeOf(typ).Elem()).Interface().(transport.Message)
+	if err := message.UnmarshallBinary(data); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// rotate generates a new group key.
+func (p *Private) rotate() error {
+	key := make([]byte, groupKeySize)
+	if _, err := io.ReadFull(p.rand, key); err != nil {
+		return fmt.Errorf("unable to generate group key: %w", err)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	epoch := uint64(time.Now().UnixNano())
+	if epoch <= p.epoch {
+		epoch = p.epoch + 1
+	}
+	p.groupKey = key
+	p.epoch = epoch
+	// Own keys are stored as any other member keys to decrypt own messages
+	// delivered by the decorated transport.
+	p.member(p.signer.Address()).addGroupKey(epoch, key)
+	return nil
+}
+
+// announce broadcasts the current group key encrypted for all known
+// members. If request is true, other members are asked to announce their
+// keys.
+func (p *Private) announce(request bool) error {
+	p.mu.RLock()
+	a := &Announcement{
+		Time:          time.Now(),
+		Epoch:         p.epoch,
+		Request:       request,
+		EncryptionKey: p.encryptionKey.PublicKey().Bytes(),
+	}
+	self := p.signer.Address()
+	addrs := maputil.SortKeys(p.members, func(a []types.Address) {
+		sort.Slice(a, func(i, j int) bool { return a[i].String() < a[j].String() })
+	})
+	for _, addr := range addrs {
+		m := p.members[addr]
+		if addr == self || m.encryptionKey == nil {
+			continue
+		}
+		key, err := p.wrapKey(m.encryptionKey, self, addr, p.epoch, p.groupKey)
+		if err != nil {
+			p.mu.RUnlock()
+			return err
+		}
+		a.Recipients = append(a.Recipients, Recipient{Address: addr, Key: key})
+	}
+	p.mu.RUnlock()
+	if err := a.sign(p.signer); err != nil {
+		return err
+	}
+	return p.transport.Broadcast(AnnouncementMessageName, a)
+}
+
+// requestKeys asks other members to announce their keys. Requests are
+// sent not more often than minRequestInterval.
+func (p *Private) requestKeys() {
+	p.mu.Lock()
+	if time.Since(p.lastRequest) < minRequestInterval {
+		p.mu.Unlock()
+		return
+	}
+	p.lastRequest = time.Now()
+	p.mu.Unlock()
+	if err := p.announce(true); err != nil {
+		p.log.
+			WithError(err).
+			Warn("Unable to request group keys")
+	}
+}
+
+// handleAnnouncement stores keys announced by another member. It returns
+// true if the node should announce its own key in response.
+func (p *Private) handleAnnouncement(msg transport.ReceivedMessage) (bool, error) {
+	a, ok := msg.Message.(*Announcement)
+	if !ok {
+		return false, fmt.Errorf("unexpected message type %T, expected announcement", msg.Message)
+	}
+	author, err := a.author(p.recoverer)
+	if err != nil {
+		return false, fmt.Errorf("invalid announcement signature: %w", err)
+	}
+	self := p.signer.Address()
+	if author == self {
+		return false, nil
+	}
+	if _, ok := p.allowlist[author]; !ok {
+		return false, fmt.Errorf("announcement author %s is not a member", author)
+	}
+	pub, err := ecdh.X25519().NewPublicKey(a.EncryptionKey)
+	if err != nil {
+		return false, fmt.Errorf("invalid encryption key: %w", err)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	m := p.member(author)
+	if !a.Time.After(m.time) {
+		// Announcements older than the last one are ignored to prevent
+		// replaying old keys.
+		return false, nil
+	}
+	m.time = a.Time
+	newKey := m.encryptionKey == nil || !m.encryptionKey.Equal(pub)
+	m.encryptionKey = pub
+	for _, r := range a.Recipients {
+		if r.Address != self {
+			continue
+		}
+		key, err := p.unwrapKey(pub, author, self, a.Epoch, r.Key)
+		if err != nil {
+			return newKey || a.Request, fmt.Errorf("unable to decrypt group key: %w", err)
+		}
+		m.addGroupKey(a.Epoch, key)
+	}
+	return newKey || a.Request, nil
+}
+
+// member returns the member with the given address, creating it if
+// necessary. The caller must hold the lock.
+func (p *Private) member(addr types.Address) *member {
+	m, ok := p.members[addr]
+	if !ok {
+		m = &member{groupKeys: make(map[uint64][]byte)}
+		p.members[addr] = m
+	}
+	return m
+}
+
+// wrapKey encrypts the group key for the recipient.
+func (p *Private) wrapKey(pub *ecdh.PublicKey, from, to types.Address, epoch uint64, key []byte) ([]byte, error) {
+	aead, err := p.wrappingAEAD(pub, from, to, epoch)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, nonceSize)
+	if _, err := io.ReadFull(p.rand, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, key, nil), nil
+}
+
+// unwrapKey decrypts the group key encrypted by wrapKey.
+func (p *Private) unwrapKey(pub *ecdh.PublicKey, from, to types.Address, epoch uint64, data []byte) ([]byte, error) {
+	if len(data) < nonceSize {
+		return nil, errInvalidMessage
+	}
+	aead, err := p.wrappingAEAD(pub, from, to, epoch)
+	if err != nil {
+		return nil, err
+	}
+	key, err := aead.Open(nil, data[:nonceSize], data[nonceSize:], nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != groupKeySize {
+		return nil, errInvalidMessage
+	}
+	return key, nil
+}
+
+// wrappingAEAD returns a cipher used to encrypt group keys sent between
+// two members. The key is derived from the X25519 shared secret.
+func (p *Private) wrappingAEAD(pub *ecdh.PublicKey, from, to types.Address, epoch uint64) (cipher.AEAD, error) {
+	secret, err := p.encryptionKey.ECDH(pub)
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	h.Write([]byte("private"))
+	h.Write(secret)
+	h.Write(from.Bytes())
+	h.Write(to.Bytes())
+	h.Write(binary.BigEndian.AppendUint64(nil, epoch))
+	return newAEAD(h.Sum(nil))
+}
+
+func (p *Private) announcementRoutine(ctx context.Context, ch <-chan transport.ReceivedMessage) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			if msg.Error != nil {
+				continue
+			}
+			respond, err := p.handleAnnouncement(msg)
+			if err != nil {
+				p.log.
+					WithError(err).
+					WithFields(transport.ReceivedMessageFields(msg)).
+					Warn("Invalid announcement")
+			}
+			if respond {
+				if err := p.announce(false); err != nil {
+					p.log.
+						WithError(err).
+						Warn("Unable to announce group key")
+				}
+			}
+		}
+	}
+}
+
+func (p *Private) rotationRoutine(ctx context.Context) {
+	t := time.NewTicker(p.rotationInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if err := p.rotate(); err != nil {
+				p.log.
+					WithError(err).
+					Error("Unable to rotate group key")
+				continue
+			}
+			if err := p.announce(false); err != nil {
+				p.log.
+					WithError(err).
+					Warn("Unable to announce group key")
+			}
+		}
+	}
+}
+
+func (p *Private) contextCancelHandler() {
+	defer func() { close(p.waitCh) }()
+	defer p.log.Debug("Stopped")
+	if err := <-p.transport.Wait(); err != nil {
+		p.waitCh <- err
+	}
+}
+
+// addGroupKey adds the group key, removing the oldest keys if there are
+// more than keptEpochs keys.
+func (m *member) addGroupKey(epoch uint64, key []byte) {
+	m.groupKeys[epoch] = key
+	for len(m.groupKeys) > keptEpochs {
+		oldest := epoch
+		for e := range m.groupKeys {
+			if e < oldest {
+				oldest = e
+			}
+		}
+		delete(m.groupKeys, oldest)
+	}
+}
+
+// messageAD returns additional data authenticated with the encrypted
+// message. It binds the message to the topic and the group key.
+func messageAD(topic string, epoch uint64) []byte {
+	return binary.BigEndian.AppendUint64([]byte(topic), epoch)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,274 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package private
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/defiweb/go-eth/crypto"
+	"github.com/defiweb/go-eth/types"
+	"github.com/defiweb/go-eth/wallet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/orcfax/oracle-suite/pkg/transport"
+	"github.com/orcfax/oracle-suite/pkg/transport/local"
+)
+
+type testMsg struct {
+	Val string
+}
+
+func (t *testMsg) MarshallBinary() ([]byte, error) {
+	return []byte(t.Val), nil
+}
+
+func (t *testMsg) UnmarshallBinary(bytes []byte) error {
+	t.Val = string(bytes)
+	return nil
+}
+
+// testNode shares the local transport between multiple nodes. The local
+// transport is started only once, by the test.
+type testNode struct {
+	*local.Local
+}
+
+func (n testNode) Start(context.Context) error {
+	return nil
+}
+
+var testTopics = map[string]transport.Message{
+	"foo": (*testMsg)(nil),
+	"bar": (*testMsg)(nil),
+}
+
+func newNetwork(t *testing.T, ctx context.Context) *local.Local {
+	l := local.New(nil, 100, Topics(testTopics, []string{"foo"}))
+	require.NoError(t, l.Start(ctx))
+	return l
+}
+
+func newNode(t *testing.T, ctx context.Context, l *local.Local, key wallet.Key, members []types.Address) *Private {
+	author := []byte("relay")
+	if key != nil {
+		author = key.Address().Bytes()
+	}
+	p, err := New(Config{
+		Transport: testNode{Local: l.WithAuthor(author)},
+		Topics:    map[string]transport.Message{"foo": (*testMsg)(nil)},
+		Members:   members,
+		Signer:    key,
+	})
+	require.NoError(t, err)
+	require.NoError(t, p.Start(ctx))
+	return p
+}
+
+// drain reads all messages from the channel until the context is canceled.
+// Channels returned by the local transport must be read, otherwise the
+// transport is blocked.
+func drain(ctx context.Context, ch <-chan transport.ReceivedMessage) {
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ch:
+			}
+		}
+	}()
+}
+
+func hasKey(p *Private, addr types.Address) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	m, ok := p.members[addr]
+	return ok && len(m.groupKeys) > 0
+}
+
+func receive(t *testing.T, ch <-chan transport.ReceivedMessage) transport.ReceivedMessage {
+	select {
+	case msg := <-ch:
+		return msg
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "timeout waiting for a message")
+		return transport.ReceivedMessage{}
+	}
+}
+
+func TestPrivate(t *testing.T) {
+	ctx, ctxCancel := context.WithCancel(context.Background())
+	defer ctxCancel()
+
+	keyA := wallet.NewRandomKey()
+	keyB := wallet.NewRandomKey()
+	keyC := wallet.NewRandomKey()
+	members := []types.Address{keyA.Address(), keyB.Address()}
+
+	l := newNetwork(t, ctx)
+	raw := l.Messages("foo")
+	a := newNode(t, ctx, l, keyA, members)
+	b := newNode(t, ctx, l, keyB, members)
+	c := newNode(t, ctx, l, keyC, members) // Not a member.
+
+	assert.True(t, a.IsMember())
+	assert.True(t, b.IsMember())
+	assert.False(t, c.IsMember())
+	assert.Nil(t, c.Messages("foo"))
+	assert.ErrorIs(t, c.Broadcast("foo", &testMsg{Val: "baz"}), ErrNotMember)
+
+	// Wait for key exchange:
+	assert.Eventually(t, func() bool {
+		return hasKey(a, keyB.Address()) && hasKey(b, keyA.Address())
+	}, 5*time.Second, 10*time.Millisecond)
+
+	msgsA := a.Messages("foo")
+	msgsB := b.Messages("foo")
+	require.NoError(t, a.Broadcast("foo", &testMsg{Val: "secret"}))
+
+	// Ciphertext does not contain the message:
+	enc := receive(t, raw)
+	require.NoError(t, enc.Error)
+	assert.NotContains(t, string(enc.Message.(*Encrypted).Data), "secret")
+
+	// Members can decrypt messages, including their own:
+	msg := receive(t, msgsB)
+	require.NoError(t, msg.Error)
+	assert.Equal(t, "secret", msg.Message.(*testMsg).Val)
+	msg = receive(t, msgsA)
+	require.NoError(t, msg.Error)
+	assert.Equal(t, "secret", msg.Message.(*testMsg).Val)
+
+	// Non-private topics are not encrypted:
+	msgsBar := c.Messages("bar")
+	require.NoError(t, c.Broadcast("bar", &testMsg{Val: "public"}))
+	msg = receive(t, msgsBar)
+	require.NoError(t, msg.Error)
+	assert.Equal(t, "public", msg.Message.(*testMsg).Val)
+}
+
+func TestPrivate_Rotation(t *testing.T) {
+	ctx, ctxCancel := context.WithCancel(context.Background())
+	defer ctxCancel()
+
+	keyA := wallet.NewRandomKey()
+	keyB := wallet.NewRandomKey()
+	members := []types.Address{keyA.Address(), keyB.Address()}
+
+	l := newNetwork(t, ctx)
+	drain(ctx, l.Messages("foo"))
+	a := newNode(t, ctx, l, keyA, members)
+	b := newNode(t, ctx, l, keyB, members)
+	assert.Eventually(t, func() bool {
+		return hasKey(b, keyA.Address())
+	}, 5*time.Second, 10*time.Millisecond)
+
+	a.mu.RLock()
+	oldEpoch := a.epoch
+	a.mu.RUnlock()
+	require.NoError(t, a.rotate())
+	require.NoError(t, a.announce(false))
+	a.mu.RLock()
+	newEpoch := a.epoch
+	a.mu.RUnlock()
+	require.NotEqual(t, oldEpoch, newEpoch)
+
+	assert.Eventually(t, func() bool {
+		b.mu.RLock()
+		defer b.mu.RUnlock()
+		return b.members[keyA.Address()].groupKeys[newEpoch] != nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	msgsB := b.Messages("foo")
+	require.NoError(t, a.Broadcast("foo", &testMsg{Val: "rotated"}))
+	msg := receive(t, msgsB)
+	require.NoError(t, msg.Error)
+	assert.Equal(t, "rotated", msg.Message.(*testMsg).Val)
+}
+
+func TestPrivate_UnknownKey(t *testing.T) {
+	ctx, ctxCancel := context.WithCancel(context.Background())
+	defer ctxCancel()
+
+	keyA := wallet.NewRandomKey()
+	keyB := wallet.NewRandomKey()
+	members := []types.Address{keyA.Address(), keyB.Address()}
+
+	l := newNetwork(t, ctx)
+	drain(ctx, l.Messages("foo"))
+	a := newNode(t, ctx, l, keyA, members)
+	msgsA := a.Messages("foo")
+
+	// Message encrypted by a member that did not announce its key:
+	require.NoError(t, l.WithAuthor(keyB.Address().Bytes()).Broadcast("foo", &Encrypted{
+		Epoch: 1,
+		Nonce: make([]byte, nonceSize),
+		Data:  []byte("data"),
+	}))
+	msg := receive(t, msgsA)
+	assert.Nil(t, msg.Message)
+	assert.ErrorIs(t, msg.Error, ErrUnknownKey)
+}
+
+func TestAnnouncement_Marshalling(t *testing.T) {
+	key := wallet.NewRandomKey()
+	a := &Announcement{
+		Time:          time.Unix(0, time.Now().UnixNano()),
+		Epoch:         42,
+		Request:       true,
+		EncryptionKey: make([]byte, encryptionKeySize),
+		Recipients: []Recipient{
+			{Address: types.MustAddressFromHex("0x1234567890123456789012345678901234567890"), Key: []byte("key")},
+		},
+	}
+	require.NoError(t, a.sign(key))
+
+	b, err := a.MarshallBinary()
+	require.NoError(t, err)
+
+	var got Announcement
+	require.NoError(t, got.UnmarshallBinary(b))
+	assert.True(t, a.Time.Equal(got.Time))
+	assert.Equal(t, a.Epoch, got.Epoch)
+	assert.Equal(t, a.Request, got.Request)
+	assert.Equal(t, a.EncryptionKey, got.EncryptionKey)
+	assert.Equal(t, a.Recipients, got.Recipients)
+
+	author, err := got.author(crypto.ECRecoverer)
+	require.NoError(t, err)
+	assert.Equal(t, key.Address(), author)
+}
+
+func TestEncrypted_UnmarshallBinary(t *testing.T) {
+	var e Encrypted
+	assert.Error(t, e.UnmarshallBinary(nil))
+	assert.Error(t, e.UnmarshallBinary(make([]byte, 8+nonceSize)))
+	assert.NoError(t, e.UnmarshallBinary(make([]byte, 8+nonceSize+1)))
+}
+
+func TestNew_Validation(t *testing.T) {
+	l := local.New(nil, 1, nil)
+	members := []types.Address{types.MustAddressFromHex("0x1234567890123456789012345678901234567890")}
+	_, err := New(Config{Members: members})
+	assert.Error(t, err)
+	_, err = New(Config{Transport: l})
+	assert.Error(t, err)
+	_, err = New(Config{Transport: l, Members: members, Topics: map[string]transport.Message{AnnouncementMessageName: (*testMsg)(nil)}})
+	assert.Error(t, err)
+}
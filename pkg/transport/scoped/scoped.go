@@ -0,0 +1,168 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package scoped
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/orcfax/oracle-suite/pkg/supervisor"
+	"github.com/orcfax/oracle-suite/pkg/transport"
+	"github.com/orcfax/oracle-suite/pkg/util/chanutil"
+)
+
+// Subscriptions holds subscriptions to topics of a transport. Every topic is
+// subscribed only once and received messages are distributed to channels
+// returned by Scoped instances created from the Subscriptions.
+//
+// Subscriptions are meant to live as long as the transport, while Scoped
+// instances may be created and stopped many times, e.g. on every
+// configuration reload, without adding subscriptions to the transport.
+type Subscriptions struct {
+	mu        sync.Mutex
+	transport transport.Transport
+	topics    map[string]*chanutil.FanOut[transport.ReceivedMessage]
+}
+
+// NewSubscriptions returns a new instance of Subscriptions.
+func NewSubscriptions(t transport.Transport) *Subscriptions {
+	if t == nil {
+		panic("t cannot be nil")
+	}
+	return &Subscriptions{
+		transport: t,
+		topics:    make(map[string]*chanutil.FanOut[transport.ReceivedMessage]),
+	}
+}
+
+// fanOut returns the fan-out of messages for the given topic. The topic is
+// subscribed on the first call. It returns nil if the topic is not supported
+// by the transport.
+func (s *Subscriptions) fanOut(topic string) *chanutil.FanOut[transport.ReceivedMessage] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if fo, ok := s.topics[topic]; ok {
+		return fo
+	}
+	ch := s.transport.Messages(topic)
+	if ch == nil {
+		return nil
+	}
+	fo := chanutil.NewFanOut(ch)
+	s.topics[topic] = fo
+	return fo
+}
+
+// Scoped is a transport decorator for services that may be stopped while
+// the decorated transport keeps running, e.g. services restarted during
+// a configuration reload.
+//
+// Transports distribute received messages to all channels returned by
+// the Messages method and block until every channel is read. Channels
+// returned by Scoped are created from the shared Subscriptions, and once
+// the context passed to Start is canceled, they are removed from
+// Subscriptions and closed, so abandoned channels do not block the
+// transport.
+//
+// Starting Scoped does not start the decorated transport.
+type Scoped struct {
+	mu     sync.Mutex
+	ctx    context.Context
+	waitCh chan error
+
+	subs     *Subscriptions
+	chs      []scopedChan
+	released bool
+}
+
+// scopedChan is a channel returned by the Messages method.
+type scopedChan struct {
+	fo *chanutil.FanOut[transport.ReceivedMessage]
+	ch <-chan transport.ReceivedMessage
+}
+
+// New returns a new instance of Scoped that uses the given subscriptions.
+func New(subs *Subscriptions) *Scoped {
+	if subs == nil {
+		panic("subs cannot be nil")
+	}
+	return &Scoped{
+		waitCh: make(chan error),
+		subs:   subs,
+	}
+}
+
+// Start implements the supervisor.Service interface.
+func (s *Scoped) Start(ctx context.Context) error {
+	if s.ctx != nil {
+		return errors.New("service can be started only once")
+	}
+	if ctx == nil {
+		return errors.New("context must not be nil")
+	}
+	s.ctx = ctx
+	go s.contextCancelHandler()
+	return nil
+}
+
+// Wait implements the supervisor.Service interface.
+func (s *Scoped) Wait() <-chan error {
+	return s.waitCh
+}
+
+// Broadcast implements the transport.Transport interface.
+func (s *Scoped) Broadcast(topic string, message transport.Message) error {
+	return s.subs.transport.Broadcast(topic, message)
+}
+
+// Messages implements the transport.Transport interface.
+//
+// Channels requested after the context is canceled are closed immediately.
+func (s *Scoped) Messages(topic string) <-chan transport.ReceivedMessage {
+	fo := s.subs.fanOut(topic)
+	if fo == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.released {
+		ch := make(chan transport.ReceivedMessage)
+		close(ch)
+		return ch
+	}
+	ch := fo.Chan()
+	s.chs = append(s.chs, scopedChan{fo: fo, ch: ch})
+	return ch
+}
+
+// ServiceName implements the supervisor.WithName interface.
+func (s *Scoped) ServiceName() string {
+	return fmt.Sprintf("Scoped(%s)", supervisor.ServiceName(s.subs.transport))
+}
+
+func (s *Scoped) contextCancelHandler() {
+	defer func() { close(s.waitCh) }()
+	<-s.ctx.Done()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.released = true
+	for _, c := range s.chs {
+		c.fo.Remove(c.ch)
+	}
+	s.chs = nil
+}
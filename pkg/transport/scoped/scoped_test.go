@@ -0,0 +1,83 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package scoped
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/orcfax/oracle-suite/pkg/transport"
+	"github.com/orcfax/oracle-suite/pkg/transport/local"
+	"github.com/orcfax/oracle-suite/pkg/transport/messages"
+)
+
+func TestScoped(t *testing.T) {
+	ctx, ctxCancel := context.WithCancel(context.Background())
+	defer ctxCancel()
+
+	l := local.New([]byte("test"), 0, map[string]transport.Message{
+		messages.DataPointV1MessageName: (*messages.DataPoint)(nil),
+	})
+	require.NoError(t, l.Start(ctx))
+	subs := NewSubscriptions(l)
+
+	// The first scope subscribes to messages but never reads them.
+	scopeCtx, scopeCancel := context.WithCancel(ctx)
+	s1 := New(subs)
+	require.NoError(t, s1.Start(scopeCtx))
+	ch1 := s1.Messages(messages.DataPointV1MessageName)
+	require.NotNil(t, ch1)
+
+	// The second scope reads messages.
+	s2 := New(subs)
+	require.NoError(t, s2.Start(ctx))
+	ch2 := s2.Messages(messages.DataPointV1MessageName)
+
+	// Both scopes share a single subscription.
+	assert.Len(t, subs.topics, 1)
+
+	// After the first scope is stopped, its channel is closed and
+	// messages are delivered to the second scope.
+	scopeCancel()
+	<-s1.Wait()
+	_, ok := <-ch1
+	assert.False(t, ok)
+
+	// Channels obtained after stopping are closed too.
+	_, ok = <-s1.Messages(messages.DataPointV1MessageName)
+	assert.False(t, ok)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, s2.Broadcast(messages.DataPointV1MessageName, &messages.DataPoint{Model: "ETH/USD"}))
+		select {
+		case msg := <-ch2:
+			assert.Equal(t, "ETH/USD", msg.Message.(*messages.DataPoint).Model)
+		case <-time.After(time.Second):
+			require.Fail(t, "message not received")
+		}
+	}
+}
+
+func TestScoped_Start(t *testing.T) {
+	s := New(NewSubscriptions(local.New([]byte("test"), 0, nil)))
+	require.Error(t, s.Start(nil)) //nolint:staticcheck
+	require.NoError(t, s.Start(context.Background()))
+	require.Error(t, s.Start(context.Background()))
+}
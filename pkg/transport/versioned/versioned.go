@@ -0,0 +1,456 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package versioned
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/orcfax/oracle-suite/pkg/log"
+	"github.com/orcfax/oracle-suite/pkg/log/null"
+	"github.com/orcfax/oracle-suite/pkg/supervisor"
+	"github.com/orcfax/oracle-suite/pkg/transport"
+	"github.com/orcfax/oracle-suite/pkg/transport/messages"
+	"github.com/orcfax/oracle-suite/pkg/util/maputil"
+)
+
+const LoggerTag = "VERSIONED"
+
+// ErrUnsupportedVersion is returned in the ReceivedMessage.Error field when
+// an envelope does not contain any version of a message that is known to
+// the node.
+var ErrUnsupportedVersion = errors.New("envelope does not contain a supported message version")
+
+// Schema describes versions of a message used on a topic.
+type Schema struct {
+	// Versions maps a version number to a message type given as a nil
+	// pointer, e.g.: (*Message)(nil). Versions start from 1, messages
+	// that are not wrapped in envelopes are decoded as version 1.
+	//
+	// Cannot be empty.
+	Versions map[uint32]transport.Message
+
+	// Publish is an optional list of versions in which messages are
+	// published. If empty, versions are negotiated: a message is published
+	// in the newest version and in the newest version supported by each
+	// peer that advertised its capabilities in a greet message.
+	Publish []uint32
+
+	// Convert converts a message to a given version. It is used to publish
+	// a message in versions other than the version of the message type.
+	// If nil, messages are published only in the version that matches
+	// their type.
+	Convert func(msg transport.Message, version uint32) (transport.Message, error)
+}
+
+// newest returns the newest version defined in the schema.
+func (s Schema) newest() uint32 {
+	var v uint32
+	for ver := range s.Versions {
+		if ver > v {
+			v = ver
+		}
+	}
+	return v
+}
+
+// versions returns a sorted list of versions defined in the schema.
+func (s Schema) versions() []uint32 {
+	return maputil.SortKeys(s.Versions, sortVersions)
+}
+
+// Versioned is a transport decorator that publishes messages on selected
+// topics in versioned envelopes.
+//
+// A message broadcast on a versioned topic is encoded in one or more
+// versions and wrapped in the messages.Envelope. Consumers unwrap the
+// envelope and decode the newest version they understand, so nodes can be
+// migrated to a new message format without introducing new topics.
+//
+// Versions supported by the node are advertised in greet messages
+// broadcast through the transport. Capabilities advertised by other nodes
+// are used to negotiate versions of published messages.
+//
+// Messages published by nodes that do not support versioning are not
+// wrapped in envelopes. Such messages are treated as version 1, so nodes
+// can be upgraded one by one.
+//
+// The decorated transport must use the type returned by the Topics
+// function for versioned topics.
+type Versioned struct {
+	mu     sync.RWMutex
+	ctx    context.Context
+	waitCh chan error
+
+	// State fields:
+	peers map[string]map[string][]uint32 // Capabilities advertised by peers, keyed by the author.
+
+	// Configuration fields:
+	transport transport.Service
+	schemas   map[string]Schema
+	log       log.Logger
+}
+
+// Config is a configuration of Versioned.
+type Config struct {
+	// Transport is the decorated transport.
+	//
+	// Cannot be nil.
+	Transport transport.Service
+
+	// Schemas maps topic names to message schemas. Topics that are not
+	// listed are passed to the decorated transport without changes.
+	Schemas map[string]Schema
+
+	// Logger is a custom logger instance. If not provided then null
+	// logger is used.
+	Logger log.Logger
+}
+
+// New returns a new instance of the Versioned transport.
+func New(cfg Config) (*Versioned, error) {
+	if cfg.Transport == nil {
+		return nil, errors.New("transport must not be nil")
+	}
+	for topic, schema := range cfg.Schemas {
+		if len(schema.Versions) == 0 {
+			return nil, fmt.Errorf("schema for topic %s does not define any version", topic)
+		}
+		for ver, typ := range schema.Versions {
+			if ver == 0 {
+				return nil, fmt.Errorf("message versions for topic %s must start from 1", topic)
+			}
+			if typ == nil || reflect.TypeOf(typ).Kind() != reflect.Ptr {
+				return nil, fmt.Errorf("message type for topic %s version %d must be a pointer", topic, ver)
+			}
+		}
+		for _, ver := range schema.Publish {
+			if _, ok := schema.Versions[ver]; !ok {
+				return nil, fmt.Errorf("published version %d for topic %s is not defined", ver, topic)
+			}
+		}
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = null.New()
+	}
+	return &Versioned{
+		waitCh:    make(chan error),
+		peers:     make(map[string]map[string][]uint32),
+		transport: cfg.Transport,
+		schemas:   cfg.Schemas,
+		log:       cfg.Logger.WithField("tag", LoggerTag),
+	}, nil
+}
+
+// Topics returns a copy of the given message map in which message types
+// of versioned topics are replaced with a type that accepts both
+// envelopes and raw messages. The returned map should be used to configure
+// the decorated transport.
+func Topics(m messages.MessageMap, schemas map[string]Schema) messages.MessageMap {
+	r := make(messages.MessageMap, len(m)+len(schemas))
+	for topic, typ := range m {
+		r[topic] = typ
+	}
+	for topic := range schemas {
+		r[topic] = (*payload)(nil)
+	}
+	return r
+}
+
+// Capabilities returns message versions supported by the node, keyed by
+// the topic name.
+func (v *Versioned) Capabilities() map[string][]uint32 {
+	c := make(map[string][]uint32, len(v.schemas))
+	for topic, schema := range v.schemas {
+		c[topic] = schema.versions()
+	}
+	return c
+}
+
+// Start implements the supervisor.Service interface.
+func (v *Versioned) Start(ctx context.Context) error {
+	if v.ctx != nil {
+		return errors.New("service can be started only once")
+	}
+	if ctx == nil {
+		return errors.New("context must not be nil")
+	}
+	v.log.Debug("Starting")
+	if err := v.transport.Start(ctx); err != nil {
+		return err
+	}
+	v.ctx = ctx
+	if ch := v.transport.Messages(messages.GreetV1MessageName); ch != nil {
+		go v.greetRoutine(ctx, ch)
+	}
+	go v.contextCancelHandler()
+	return nil
+}
+
+// Wait implements the supervisor.Service interface.
+func (v *Versioned) Wait() <-chan error {
+	return v.waitCh
+}
+
+// Broadcast implements the transport.Transport interface.
+func (v *Versioned) Broadcast(topic string, message transport.Message) error {
+	if greet, ok := message.(*messages.Greet); ok && greet.Capabilities == nil {
+		cpy := *greet
+		cpy.Capabilities = v.Capabilities()
+		message = &cpy
+	}
+	schema, ok := v.schemas[topic]
+	if !ok {
+		return v.transport.Broadcast(topic, message)
+	}
+	envelope, err := v.envelope(topic, schema, message)
+	if err != nil {
+		return err
+	}
+	return v.transport.Broadcast(topic, envelope)
+}
+
+// Messages implements the transport.Transport interface.
+func (v *Versioned) Messages(topic string) <-chan transport.ReceivedMessage {
+	in := v.transport.Messages(topic)
+	schema, ok := v.schemas[topic]
+	if !ok || in == nil {
+		return in
+	}
+	out := make(chan transport.ReceivedMessage)
+	go func() {
+		defer close(out)
+		for msg := range in {
+			out <- unwrap(schema, msg)
+		}
+	}()
+	return out
+}
+
+// ServiceName implements the supervisor.WithName interface.
+func (v *Versioned) ServiceName() string {
+	return fmt.Sprintf("Versioned(%s)", supervisor.ServiceName(v.transport))
+}
+
+// envelope encodes the message in versions that should be published and
+// wraps them in an envelope.
+func (v *Versioned) envelope(topic string, schema Schema, message transport.Message) (*messages.Envelope, error) {
+	envelope := &messages.Envelope{Payloads: make(map[uint32][]byte)}
+	if appInfo, ok := message.(transport.WithAppInfo); ok {
+		envelope.AppInfo = appInfo.GetAppInfo()
+	}
+	versions := v.publishVersions(topic, schema)
+	for _, ver := range versions {
+		msg := message
+		if reflect.TypeOf(msg) != reflect.TypeOf(schema.Versions[ver]) {
+			if schema.Convert == nil {
+				continue
+			}
+			var err error
+			if msg, err = schema.Convert(message, ver); err != nil {
+				v.log.
+					WithError(err).
+					WithFields(log.Fields{
+						"topic":   topic,
+						"version": ver,
+					}).
+					Warn("Unable to convert message")
+				continue
+			}
+		}
+		data, err := msg.MarshallBinary()
+		if err != nil {
+			return nil, fmt.Errorf("unable to marshall message version %d: %w", ver, err)
+		}
+		envelope.Payloads[ver] = data
+	}
+	if len(envelope.Payloads) == 0 {
+		return nil, fmt.Errorf("unable to encode message for topic %s in any of versions %v", topic, versions)
+	}
+	return envelope, nil
+}
+
+// publishVersions returns a list of versions in which messages on the
+// given topic should be published.
+func (v *Versioned) publishVersions(topic string, schema Schema) []uint32 {
+	if len(schema.Publish) > 0 {
+		return schema.Publish
+	}
+	versions := map[uint32]struct{}{schema.newest(): {}}
+	v.mu.RLock()
+	for _, capabilities := range v.peers {
+		var newest uint32
+		var found bool
+		for _, ver := range capabilities[topic] {
+			if _, ok := schema.Versions[ver]; ok && (!found || ver > newest) {
+				newest, found = ver, true
+			}
+		}
+		if found {
+			versions[newest] = struct{}{}
+		}
+	}
+	v.mu.RUnlock()
+	return maputil.SortKeys(versions, sortVersions)
+}
+
+// greetRoutine collects capabilities advertised by other nodes.
+func (v *Versioned) greetRoutine(ctx context.Context, ch <-chan transport.ReceivedMessage) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			if msg.Error != nil {
+				continue
+			}
+			greet, ok := msg.Message.(*messages.Greet)
+			if !ok {
+				continue
+			}
+			author := hex.EncodeToString(msg.Author)
+			v.mu.Lock()
+			if len(greet.Capabilities) == 0 {
+				delete(v.peers, author)
+			} else {
+				v.peers[author] = greet.Capabilities
+			}
+			v.mu.Unlock()
+		}
+	}
+}
+
+func (v *Versioned) contextCancelHandler() {
+	defer func() { close(v.waitCh) }()
+	defer v.log.Debug("Stopped")
+	if err := <-v.transport.Wait(); err != nil {
+		v.waitCh <- err
+	}
+}
+
+// payload is a message received on a versioned topic. It holds either
+// an envelope or a raw message published by a node that does not support
+// versioning.
+type payload struct {
+	messages.Envelope
+
+	raw []byte // Set if the message is not an envelope.
+}
+
+// MarshallBinary implements the transport.Message interface.
+func (p *payload) MarshallBinary() ([]byte, error) {
+	if p.raw != nil {
+		return p.raw, nil
+	}
+	return p.Envelope.MarshallBinary()
+}
+
+// UnmarshallBinary implements the transport.Message interface.
+//
+// Raw protobuf messages may occasionally be parsed as envelopes, but
+// the version of a payload decoded that way is zero, which is never used
+// by schemas.
+func (p *payload) UnmarshallBinary(data []byte) error {
+	if err := p.Envelope.UnmarshallBinary(data); err != nil || p.hasVersion(0) {
+		p.Envelope = messages.Envelope{}
+		p.raw = data
+	}
+	return nil
+}
+
+func (p *payload) hasVersion(ver uint32) bool {
+	_, ok := p.Payloads[ver]
+	return ok
+}
+
+// unwrap replaces the envelope in the received message with the newest
+// message version supported by the schema.
+func unwrap(schema Schema, msg transport.ReceivedMessage) transport.ReceivedMessage {
+	if msg.Error != nil {
+		return msg
+	}
+	var (
+		message transport.Message
+		err     error
+	)
+	switch m := msg.Message.(type) {
+	case *payload:
+		if m.raw != nil {
+			message, err = decodeRaw(schema, m.raw)
+		} else {
+			message, err = decode(schema, &m.Envelope)
+		}
+	case *messages.Envelope:
+		message, err = decode(schema, m)
+	default:
+		err = fmt.Errorf("unexpected message type %T, expected envelope", msg.Message)
+	}
+	msg.Message = message
+	msg.Error = err
+	return msg
+}
+
+// decodeRaw decodes a message that is not wrapped in an envelope as
+// version 1.
+func decodeRaw(schema Schema, data []byte) (transport.Message, error) {
+	typ, ok := schema.Versions[1]
+	if !ok {
+		return nil, fmt.Errorf("%w: message is not wrapped in an envelope", ErrUnsupportedVersion)
+	}
+	msg := reflect.New(reflect.TypeOf(typ).Elem()).Interface().(transport.Message)
+	if err := msg.UnmarshallBinary(data); err != nil {
+		return nil, fmt.Errorf("unable to unmarshall message version 1: %w", err)
+	}
+	return msg, nil
+}
+
+// decode decodes the newest message version supported by the schema.
+// If the newest version cannot be decoded, older versions are tried.
+func decode(schema Schema, envelope *messages.Envelope) (transport.Message, error) {
+	versions := envelope.Versions()
+	var err error
+	for i := len(versions) - 1; i >= 0; i-- {
+		typ, ok := schema.Versions[versions[i]]
+		if !ok {
+			continue
+		}
+		msg := reflect.New(reflect.TypeOf(typ).Elem()).Interface().(transport.Message)
+		if err = msg.UnmarshallBinary(envelope.Payloads[versions[i]]); err != nil {
+			err = fmt.Errorf("unable to unmarshall message version %d: %w", versions[i], err)
+			continue
+		}
+		if appInfo, ok := msg.(transport.WithAppInfo); ok {
+			appInfo.SetAppInfo(envelope.AppInfo)
+		}
+		return msg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("%w: %v", ErrUnsupportedVersion, versions)
+}
+
+func sortVersions(v []uint32) {
+	sort.Slice(v, func(i, j int) bool { return v[i] < v[j] })
+}
@@ -0,0 +1,314 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package versioned
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/orcfax/oracle-suite/pkg/transport"
+	"github.com/orcfax/oracle-suite/pkg/transport/local"
+	"github.com/orcfax/oracle-suite/pkg/transport/messages"
+)
+
+type testMsgV1 struct {
+	transport.AppInfo
+	Val string
+}
+
+func (t *testMsgV1) MarshallBinary() ([]byte, error) {
+	return []byte(t.Val), nil
+}
+
+func (t *testMsgV1) UnmarshallBinary(bytes []byte) error {
+	t.Val = string(bytes)
+	return nil
+}
+
+type testMsgV2 struct {
+	transport.AppInfo
+	Val int
+}
+
+func (t *testMsgV2) MarshallBinary() ([]byte, error) {
+	return []byte(strconv.Itoa(t.Val)), nil
+}
+
+func (t *testMsgV2) UnmarshallBinary(bytes []byte) (err error) {
+	t.Val, err = strconv.Atoi(string(bytes))
+	return err
+}
+
+func testSchema() Schema {
+	return Schema{
+		Versions: map[uint32]transport.Message{
+			1: (*testMsgV1)(nil),
+			2: (*testMsgV2)(nil),
+		},
+		Convert: func(msg transport.Message, version uint32) (transport.Message, error) {
+			if m, ok := msg.(*testMsgV2); ok && version == 1 {
+				return &testMsgV1{Val: strconv.Itoa(m.Val)}, nil
+			}
+			return nil, errors.New("unsupported conversion")
+		},
+	}
+}
+
+func newLocal() *local.Local {
+	return local.New([]byte("test"), 10, Topics(
+		messages.MessageMap{messages.GreetV1MessageName: (*messages.Greet)(nil)},
+		map[string]Schema{"foo": {}},
+	))
+}
+
+func receive(t *testing.T, ch <-chan transport.ReceivedMessage) transport.ReceivedMessage {
+	select {
+	case msg := <-ch:
+		return msg
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "timeout waiting for a message")
+		return transport.ReceivedMessage{}
+	}
+}
+
+func TestVersioned_Newest(t *testing.T) {
+	ctx, ctxCancel := context.WithCancel(context.Background())
+	defer ctxCancel()
+
+	l := newLocal()
+	v, err := New(Config{Transport: l, Schemas: map[string]Schema{"foo": testSchema()}})
+	require.NoError(t, err)
+	require.NoError(t, v.Start(ctx))
+
+	ch := v.Messages("foo")
+	raw := l.Messages("foo")
+	require.NoError(t, v.Broadcast("foo", &testMsgV2{Val: 42}))
+
+	// Without known peers, only the newest version is published.
+	envelope := receive(t, raw).Message.(*payload)
+	assert.Equal(t, []uint32{2}, envelope.Versions())
+
+	msg := receive(t, ch)
+	require.NoError(t, msg.Error)
+	assert.Equal(t, 42, msg.Message.(*testMsgV2).Val)
+}
+
+func TestVersioned_OlderConsumer(t *testing.T) {
+	ctx, ctxCancel := context.WithCancel(context.Background())
+	defer ctxCancel()
+
+	l := newLocal()
+	schema := testSchema()
+	schema.Publish = []uint32{1, 2}
+	producer, err := New(Config{Transport: l, Schemas: map[string]Schema{"foo": schema}})
+	require.NoError(t, err)
+	consumer, err := New(Config{Transport: l, Schemas: map[string]Schema{"foo": {
+		Versions: map[uint32]transport.Message{1: (*testMsgV1)(nil)},
+	}}})
+	require.NoError(t, err)
+	require.NoError(t, producer.Start(ctx))
+
+	ch := consumer.Messages("foo")
+	require.NoError(t, producer.Broadcast("foo", &testMsgV2{Val: 42}))
+
+	msg := receive(t, ch)
+	require.NoError(t, msg.Error)
+	assert.Equal(t, "42", msg.Message.(*testMsgV1).Val)
+}
+
+func TestVersioned_UnsupportedVersion(t *testing.T) {
+	ctx, ctxCancel := context.WithCancel(context.Background())
+	defer ctxCancel()
+
+	l := newLocal()
+	v, err := New(Config{Transport: l, Schemas: map[string]Schema{"foo": {
+		Versions: map[uint32]transport.Message{1: (*testMsgV1)(nil)},
+	}}})
+	require.NoError(t, err)
+	require.NoError(t, v.Start(ctx))
+
+	ch := v.Messages("foo")
+	require.NoError(t, l.Broadcast("foo", &messages.Envelope{Payloads: map[uint32][]byte{3: []byte("bar")}}))
+
+	msg := receive(t, ch)
+	assert.Nil(t, msg.Message)
+	assert.ErrorIs(t, msg.Error, ErrUnsupportedVersion)
+}
+
+func TestVersioned_Negotiation(t *testing.T) {
+	ctx, ctxCancel := context.WithCancel(context.Background())
+	defer ctxCancel()
+
+	l := newLocal()
+	v, err := New(Config{Transport: l, Schemas: map[string]Schema{"foo": testSchema()}})
+	require.NoError(t, err)
+	require.NoError(t, v.Start(ctx))
+
+	// Peer that supports only the first version:
+	require.NoError(t, l.WithAuthor([]byte("peer")).Broadcast(messages.GreetV1MessageName, &messages.Greet{
+		Signature:    types.MustSignatureFromBytes(make([]byte, 65)),
+		Capabilities: map[string][]uint32{"foo": {1}},
+	}))
+	assert.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual([]uint32{1, 2}, v.publishVersions("foo", v.schemas["foo"]))
+	}, 5*time.Second, 10*time.Millisecond)
+
+	raw := l.Messages("foo")
+	require.NoError(t, v.Broadcast("foo", &testMsgV2{Val: 42}))
+
+	envelope := receive(t, raw).Message.(*payload)
+	assert.Equal(t, []uint32{1, 2}, envelope.Versions())
+	assert.Equal(t, []byte("42"), envelope.Payloads[1])
+	assert.Equal(t, []byte("42"), envelope.Payloads[2])
+}
+
+func TestVersioned_GreetCapabilities(t *testing.T) {
+	ctx, ctxCancel := context.WithCancel(context.Background())
+	defer ctxCancel()
+
+	l := newLocal()
+	v, err := New(Config{Transport: l, Schemas: map[string]Schema{"foo": testSchema()}})
+	require.NoError(t, err)
+	require.NoError(t, v.Start(ctx))
+
+	ch := v.Messages(messages.GreetV1MessageName)
+	greet := &messages.Greet{
+		Signature: types.MustSignatureFromBytes(make([]byte, 65)),
+	}
+	require.NoError(t, v.Broadcast(messages.GreetV1MessageName, greet))
+
+	msg := receive(t, ch)
+	require.NoError(t, msg.Error)
+	assert.Equal(t, map[string][]uint32{"foo": {1, 2}}, msg.Message.(*messages.Greet).Capabilities)
+
+	// The broadcast message must not be modified.
+	assert.Nil(t, greet.Capabilities)
+}
+
+func TestVersioned_RawMessage(t *testing.T) {
+	ctx, ctxCancel := context.WithCancel(context.Background())
+	defer ctxCancel()
+
+	l := newLocal()
+	v, err := New(Config{Transport: l, Schemas: map[string]Schema{"foo": testSchema()}})
+	require.NoError(t, err)
+	require.NoError(t, v.Start(ctx))
+
+	// Nodes that do not support versioning publish raw messages, they
+	// must be decoded as version 1.
+	ch := v.Messages("foo")
+	require.NoError(t, l.Broadcast("foo", &testMsgV1{Val: "bar"}))
+
+	msg := receive(t, ch)
+	require.NoError(t, msg.Error)
+	assert.Equal(t, "bar", msg.Message.(*testMsgV1).Val)
+}
+
+func TestVersioned_RawMessageUnsupported(t *testing.T) {
+	ctx, ctxCancel := context.WithCancel(context.Background())
+	defer ctxCancel()
+
+	l := newLocal()
+	v, err := New(Config{Transport: l, Schemas: map[string]Schema{"foo": {
+		Versions: map[uint32]transport.Message{2: (*testMsgV2)(nil)},
+	}}})
+	require.NoError(t, err)
+	require.NoError(t, v.Start(ctx))
+
+	ch := v.Messages("foo")
+	require.NoError(t, l.Broadcast("foo", &testMsgV1{Val: "bar"}))
+
+	msg := receive(t, ch)
+	assert.Nil(t, msg.Message)
+	assert.ErrorIs(t, msg.Error, ErrUnsupportedVersion)
+}
+
+func TestVersioned_Passthrough(t *testing.T) {
+	ctx, ctxCancel := context.WithCancel(context.Background())
+	defer ctxCancel()
+
+	l := local.New([]byte("test"), 10, map[string]transport.Message{"bar": (*testMsgV1)(nil)})
+	v, err := New(Config{Transport: l, Schemas: map[string]Schema{"foo": testSchema()}})
+	require.NoError(t, err)
+	require.NoError(t, v.Start(ctx))
+
+	ch := v.Messages("bar")
+	require.NoError(t, v.Broadcast("bar", &testMsgV1{Val: "baz"}))
+
+	msg := receive(t, ch)
+	require.NoError(t, msg.Error)
+	assert.Equal(t, "baz", msg.Message.(*testMsgV1).Val)
+}
+
+func TestNew_Validation(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{
+			name: "missing transport",
+			cfg:  Config{},
+		},
+		{
+			name: "empty schema",
+			cfg: Config{
+				Transport: newLocal(),
+				Schemas:   map[string]Schema{"foo": {}},
+			},
+		},
+		{
+			name: "non-pointer type",
+			cfg: Config{
+				Transport: newLocal(),
+				Schemas: map[string]Schema{"foo": {
+					Versions: map[uint32]transport.Message{1: nil},
+				}},
+			},
+		},
+		{
+			name: "zero version",
+			cfg: Config{
+				Transport: newLocal(),
+				Schemas: map[string]Schema{"foo": {
+					Versions: map[uint32]transport.Message{0: (*testMsgV1)(nil)},
+				}},
+			},
+		},
+		{
+			name: "undefined published version",
+			cfg: Config{
+				Transport: newLocal(),
+				Schemas: map[string]Schema{"foo": {
+					Versions: map[uint32]transport.Message{1: (*testMsgV1)(nil)},
+					Publish:  []uint32{2},
+				}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.cfg)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -17,12 +17,22 @@ package webapi
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/defiweb/go-eth/abi"
+	"github.com/defiweb/go-eth/crypto"
 	"github.com/defiweb/go-eth/rpc"
 	"github.com/defiweb/go-eth/types"
+
+	"github.com/orcfax/oracle-suite/pkg/util/sliceutil"
 )
 
 // AddressBook provides a list of addresses to which the messages should be
@@ -74,6 +84,36 @@ func (m *MultiAddressBook) Consumers(ctx context.Context) ([]string, error) {
 	return addresses, nil
 }
 
+// SwappableAddressBook is an implementation of AddressBook that delegates
+// to another AddressBook, which can be replaced at runtime. It allows
+// changing the list of consumers without restarting the transport.
+type SwappableAddressBook struct {
+	mu   sync.RWMutex
+	book AddressBook
+}
+
+// NewSwappableAddressBook creates a new instance of SwappableAddressBook.
+func NewSwappableAddressBook(book AddressBook) *SwappableAddressBook {
+	return &SwappableAddressBook{
+		book: book,
+	}
+}
+
+// Swap replaces the underlying address book.
+func (s *SwappableAddressBook) Swap(book AddressBook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.book = book
+}
+
+// Consumers implements the AddressBook interface.
+func (s *SwappableAddressBook) Consumers(ctx context.Context) ([]string, error) {
+	s.mu.RLock()
+	book := s.book
+	s.mu.RUnlock()
+	return book.Consumers(ctx)
+}
+
 // StaticAddressBook is an implementation of AddressBook that returns a static
 // list of addresses.
 type StaticAddressBook struct {
@@ -89,7 +129,7 @@ func NewStaticAddressBook(addresses []string) *StaticAddressBook {
 
 // Consumers implements the AddressBook interface.
 func (c *StaticAddressBook) Consumers(_ context.Context) ([]string, error) {
-	return c.addresses, nil
+	return sliceutil.Copy(c.addresses), nil
 }
 
 // EthereumAddressBook is an AddressBook implementation that uses an Ethereum
@@ -127,7 +167,7 @@ func (c *EthereumAddressBook) Consumers(ctx context.Context) ([]string, error) {
 		c.cache = addrs
 		c.cacheTime = time.Now()
 	}
-	return c.cache, nil
+	return sliceutil.Copy(c.cache), nil
 }
 
 func (c *EthereumAddressBook) fetchConsumers(ctx context.Context) ([]string, error) {
@@ -151,3 +191,293 @@ func (c *EthereumAddressBook) fetchConsumers(ctx context.Context) ([]string, err
 }
 
 var consumersMethod = abi.MustParseMethod("function list() returns (string[])")
+
+// DNSRecordPrefix is the prefix of the TXT records used by DNSAddressBook.
+const DNSRecordPrefix = "oracle-consumer="
+
+// DNSResolver resolves DNS TXT records. The net.Resolver type implements this
+// interface.
+type DNSResolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// DNSRecord is a consumer address stored in a DNS TXT record.
+//
+// The signature is the Ethereum signature of the IssuedAt and Expires
+// Unix timestamps followed by the address, in the same format as in the
+// WellKnownDocument. Expired records are rejected, so a record of a revoked
+// consumer cannot be published again after it expires.
+type DNSRecord struct {
+	Consumer  string
+	IssuedAt  int64
+	Expires   int64
+	Signature types.Signature
+}
+
+// SigningData returns the data that must be signed by the address book
+// signer.
+func (r DNSRecord) SigningData() []byte {
+	return []byte(fmt.Sprintf("%d\n%d\n%s", r.IssuedAt, r.Expires, consumersSigningData([]string{r.Consumer})))
+}
+
+// String returns the TXT record value.
+func (r DNSRecord) String() string {
+	return fmt.Sprintf("%s%s %d %d %s", DNSRecordPrefix, r.Consumer, r.IssuedAt, r.Expires, r.Signature.String())
+}
+
+// parseDNSRecord parses the TXT record value. It returns false if the
+// record has a different prefix or an invalid format.
+func parseDNSRecord(record string) (DNSRecord, bool) {
+	if !strings.HasPrefix(record, DNSRecordPrefix) {
+		return DNSRecord{}, false
+	}
+	parts := strings.Fields(strings.TrimPrefix(record, DNSRecordPrefix))
+	if len(parts) != 4 {
+		return DNSRecord{}, false
+	}
+	issuedAt, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return DNSRecord{}, false
+	}
+	expires, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return DNSRecord{}, false
+	}
+	sig, err := types.SignatureFromHex(parts[3])
+	if err != nil {
+		return DNSRecord{}, false
+	}
+	return DNSRecord{
+		Consumer:  parts[0],
+		IssuedAt:  issuedAt,
+		Expires:   expires,
+		Signature: sig,
+	}, true
+}
+
+// DNSAddressBook is an AddressBook implementation that reads the list of
+// addresses from DNS TXT records.
+//
+// Every address is stored in a separate TXT record in the following format:
+//
+//	oracle-consumer=<address> <issued_at> <expires> <signature>
+//
+// See DNSRecord for the description of the signature. Records with
+// a different prefix are ignored, and records with an invalid signature
+// or validity period are skipped, so that a compromised DNS zone cannot add
+// new consumers or restore revoked ones.
+type DNSAddressBook struct {
+	mu sync.Mutex
+
+	resolver  DNSResolver      // DNS resolver.
+	recover   crypto.Recoverer // Signature recoverer.
+	domain    string           // Domain name with TXT records.
+	signer    types.Address    // Address of the expected signer.
+	cache     []string         // Cached list of addresses.
+	cacheTime time.Time        // Time when the cache was last updated.
+	cacheTTL  time.Duration    // How long the cache should be valid.
+	expires   time.Time        // Expiration time of the earliest expiring cached record.
+}
+
+// NewDNSAddressBook creates a new instance of DNSAddressBook.
+// The cacheTTL parameter specifies how long the list of addresses should be
+// cached before the TXT records are resolved again.
+func NewDNSAddressBook(r DNSResolver, domain string, signer types.Address, cacheTTL time.Duration) *DNSAddressBook {
+	return &DNSAddressBook{
+		resolver: r,
+		recover:  crypto.ECRecoverer,
+		domain:   domain,
+		signer:   signer,
+		cacheTTL: cacheTTL,
+	}
+}
+
+// Consumers implements the AddressBook interface.
+//
+// The list of addresses is cached until the cache TTL passes or any of
+// the records expires, whichever comes first.
+func (c *DNSAddressBook) Consumers(ctx context.Context) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if c.cache == nil || c.cacheTime.Add(c.cacheTTL).Before(now) || (!c.expires.IsZero() && !now.Before(c.expires)) {
+		addrs, expires, err := c.fetchConsumers(ctx)
+		if err != nil {
+			return nil, err
+		}
+		c.cache = addrs
+		c.cacheTime = now
+		c.expires = expires
+	}
+	return sliceutil.Copy(c.cache), nil
+}
+
+// fetchConsumers returns the addresses from valid records and the
+// expiration time of the earliest expiring one.
+func (c *DNSAddressBook) fetchConsumers(ctx context.Context) ([]string, time.Time, error) {
+	records, err := c.resolver.LookupTXT(ctx, c.domain)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	var (
+		addrs   = []string{}
+		expires time.Time
+		now     = time.Now()
+	)
+	for _, txt := range records {
+		record, ok := parseDNSRecord(txt)
+		if !ok {
+			continue
+		}
+		if !verifySignature(c.recover, c.signer, record.SigningData(), record.Signature) {
+			continue
+		}
+		if !validityPeriodValid(record.IssuedAt, record.Expires, now) {
+			continue
+		}
+		if exp := time.Unix(record.Expires, 0); expires.IsZero() || exp.Before(expires) {
+			expires = exp
+		}
+		addrs = append(addrs, record.Consumer)
+	}
+	return addrs, expires, nil
+}
+
+// WellKnownPath is the path of the document used by WellKnownAddressBook.
+const WellKnownPath = "/.well-known/oracle-consumers.json"
+
+// wellKnownMaxSize is the maximum size of the well-known document.
+const wellKnownMaxSize = 1 << 20 // 1MiB
+
+// maxClockSkew is the maximum difference between the local time and
+// the issue time of a signed well-known document or DNS record.
+const maxClockSkew = 5 * time.Minute
+
+// WellKnownDocument is the document served by the well-known endpoint.
+//
+// The signature is the Ethereum signature of the IssuedAt and Expires
+// Unix timestamps followed by the consumer addresses, each on a separate
+// line. Expired documents are rejected, so a signed document cannot be
+// replayed after it expires.
+type WellKnownDocument struct {
+	Consumers []string        `json:"consumers"`
+	IssuedAt  int64           `json:"issued_at"`
+	Expires   int64           `json:"expires"`
+	Signature types.Signature `json:"signature"`
+}
+
+// SigningData returns the data that must be signed by the address book
+// signer.
+func (d WellKnownDocument) SigningData() []byte {
+	return []byte(fmt.Sprintf("%d\n%d\n%s", d.IssuedAt, d.Expires, consumersSigningData(d.Consumers)))
+}
+
+// WellKnownAddressBook is an AddressBook implementation that fetches the
+// list of addresses from the WellKnownPath document served by an HTTP server.
+// The document must be signed by the configured signer.
+type WellKnownAddressBook struct {
+	mu sync.Mutex
+
+	client    *http.Client     // HTTP client.
+	recover   crypto.Recoverer // Signature recoverer.
+	url       string           // URL of the well-known document.
+	signer    types.Address    // Address of the expected signer.
+	cache     []string         // Cached list of addresses.
+	cacheTime time.Time        // Time when the cache was last updated.
+	cacheTTL  time.Duration    // How long the cache should be valid.
+	issuedAt  int64            // Issue time of the cached document.
+	expires   time.Time        // Expiration time of the cached document.
+}
+
+// NewWellKnownAddressBook creates a new instance of WellKnownAddressBook.
+// The baseURL parameter is the URL of the server, without the WellKnownPath.
+// The cacheTTL parameter specifies how long the list of addresses should be
+// cached before the document is fetched again.
+func NewWellKnownAddressBook(client *http.Client, baseURL string, signer types.Address, cacheTTL time.Duration) *WellKnownAddressBook {
+	return &WellKnownAddressBook{
+		client:   client,
+		recover:  crypto.ECRecoverer,
+		url:      strings.TrimSuffix(baseURL, "/") + WellKnownPath,
+		signer:   signer,
+		cacheTTL: cacheTTL,
+	}
+}
+
+// Consumers implements the AddressBook interface.
+//
+// The list of addresses is cached until the cache TTL passes or the document
+// expires, whichever comes first.
+func (c *WellKnownAddressBook) Consumers(ctx context.Context) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if c.cache == nil || c.cacheTime.Add(c.cacheTTL).Before(now) || !now.Before(c.expires) {
+		doc, err := c.fetchDocument(ctx)
+		if err != nil {
+			return nil, err
+		}
+		c.cache = doc.Consumers
+		if c.cache == nil {
+			c.cache = []string{}
+		}
+		c.cacheTime = now
+		c.issuedAt = doc.IssuedAt
+		c.expires = time.Unix(doc.Expires, 0)
+	}
+	return sliceutil.Copy(c.cache), nil
+}
+
+func (c *WellKnownAddressBook) fetchDocument(ctx context.Context) (*WellKnownDocument, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d from %s", res.StatusCode, c.url)
+	}
+	var doc WellKnownDocument
+	if err := json.NewDecoder(io.LimitReader(res.Body, wellKnownMaxSize)).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid document from %s: %w", c.url, err)
+	}
+	if !verifySignature(c.recover, c.signer, doc.SigningData(), doc.Signature) {
+		return nil, errors.New("invalid document signature from " + c.url)
+	}
+	now := time.Now()
+	switch {
+	case doc.IssuedAt == 0 || doc.Expires <= doc.IssuedAt:
+		return nil, fmt.Errorf("invalid document validity period from %s", c.url)
+	case time.Unix(doc.IssuedAt, 0).After(now.Add(maxClockSkew)):
+		return nil, fmt.Errorf("document from %s is issued in the future", c.url)
+	case !now.Before(time.Unix(doc.Expires, 0)):
+		return nil, fmt.Errorf("document from %s has expired", c.url)
+	case doc.IssuedAt < c.issuedAt:
+		return nil, fmt.Errorf("document from %s is older than the previously fetched one", c.url)
+	}
+	return &doc, nil
+}
+
+// validityPeriodValid returns true if the validity period is well-formed,
+// was not issued in the future and has not expired at the given time.
+func validityPeriodValid(issuedAt, expires int64, now time.Time) bool {
+	return issuedAt != 0 &&
+		expires > issuedAt &&
+		!time.Unix(issuedAt, 0).After(now.Add(maxClockSkew)) &&
+		now.Before(time.Unix(expires, 0))
+}
+
+// consumersSigningData returns the data that is signed by the address book
+// signer.
+func consumersSigningData(addrs []string) []byte {
+	return []byte(strings.Join(addrs, "\n"))
+}
+
+// verifySignature verifies that the data is signed by the given signer.
+func verifySignature(recover crypto.Recoverer, signer types.Address, data []byte, sig types.Signature) bool {
+	addr, err := recover.RecoverMessage(data, sig)
+	return err == nil && *addr == signer
+}
@@ -2,13 +2,20 @@ package webapi
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/defiweb/go-eth/abi"
 	"github.com/defiweb/go-eth/hexutil"
 	"github.com/defiweb/go-eth/types"
+	"github.com/defiweb/go-eth/wallet"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -101,6 +108,19 @@ func TestMultiAddressBook_Consumers(t *testing.T) {
 	}
 }
 
+func TestSwappableAddressBook_Consumers(t *testing.T) {
+	ctx := context.Background()
+	book := NewSwappableAddressBook(NewStaticAddressBook([]string{"domain1.example"}))
+	consumers, err := book.Consumers(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"domain1.example"}, consumers)
+
+	book.Swap(NewStaticAddressBook([]string{"domain2.example"}))
+	consumers, err = book.Consumers(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"domain2.example"}, consumers)
+}
+
 func TestStaticAddressBook_Consumers(t *testing.T) {
 	tests := []struct{ addresses []string }{
 		{addresses: nil},
@@ -178,6 +198,315 @@ func TestEthereumAddressBook_Cache(t *testing.T) {
 	require.NoError(t, err)
 }
 
+type dnsResolverFunc func(ctx context.Context, name string) ([]string, error)
+
+func (f dnsResolverFunc) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	return f(ctx, name)
+}
+
+func TestDNSAddressBook_Consumers(t *testing.T) {
+	var (
+		signer = wallet.NewRandomKey()
+		other  = wallet.NewRandomKey()
+	)
+	now := time.Now().Unix()
+	record := func(key wallet.Key, addr string) string {
+		return dnsRecord(key, addr, now, now+3600)
+	}
+	tests := []struct {
+		records           []string
+		expectedAddresses []string
+	}{
+		{
+			records:           nil,
+			expectedAddresses: []string{},
+		},
+		{
+			records: []string{
+				record(signer, "domain1.example"),
+				record(signer, "domain2.example"),
+			},
+			expectedAddresses: []string{
+				"domain1.example",
+				"domain2.example",
+			},
+		},
+		{
+			// Records with invalid signatures or unknown format must be
+			// ignored.
+			records: []string{
+				"v=spf1 -all",
+				record(signer, "domain1.example"),
+				record(other, "domain2.example"),
+				DNSRecordPrefix + "domain3.example",
+				DNSRecordPrefix + "domain4.example 0x1234",
+			},
+			expectedAddresses: []string{
+				"domain1.example",
+			},
+		},
+		{
+			// Records without a valid validity period must be ignored.
+			records: []string{
+				record(signer, "domain1.example"),
+				dnsRecord(signer, "domain2.example", now-7200, now-3600),
+				dnsRecord(signer, "domain3.example", now+3600, now+7200),
+				dnsRecord(signer, "domain4.example", now, now),
+				dnsRecord(signer, "domain5.example", 0, now+3600),
+			},
+			expectedAddresses: []string{
+				"domain1.example",
+			},
+		},
+		{
+			// Signature does not cover a modified validity period.
+			records: []string{
+				strings.Replace(record(signer, "domain1.example"), fmt.Sprint(now+3600), fmt.Sprint(now+7200), 1),
+			},
+			expectedAddresses: []string{},
+		},
+	}
+	for n, tt := range tests {
+		t.Run(fmt.Sprintf("case-%d", n+1), func(t *testing.T) {
+			resolver := dnsResolverFunc(func(_ context.Context, name string) ([]string, error) {
+				assert.Equal(t, "consumers.example", name)
+				return tt.records, nil
+			})
+			book := NewDNSAddressBook(resolver, "consumers.example", signer.Address(), time.Minute)
+			consumers, err := book.Consumers(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedAddresses, consumers)
+		})
+	}
+}
+
+func TestDNSAddressBook_CacheExpiredRecord(t *testing.T) {
+	var (
+		signer = wallet.NewRandomKey()
+		calls  int32
+		now    = time.Now().Unix()
+	)
+	resolver := dnsResolverFunc(func(_ context.Context, _ string) ([]string, error) {
+		atomic.AddInt32(&calls, 1)
+		return []string{dnsRecord(signer, "domain1.example", now, now+1)}, nil
+	})
+	book := NewDNSAddressBook(resolver, "consumers.example", signer.Address(), time.Hour)
+	consumers, err := book.Consumers(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"domain1.example"}, consumers)
+
+	// The cache must not outlive the records.
+	time.Sleep(time.Until(time.Unix(now+1, 0)))
+	consumers, err = book.Consumers(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, consumers)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestDNSAddressBook_ConsumersCopy(t *testing.T) {
+	signer := wallet.NewRandomKey()
+	now := time.Now().Unix()
+	resolver := dnsResolverFunc(func(_ context.Context, _ string) ([]string, error) {
+		return []string{dnsRecord(signer, "domain1.example", now, now+3600)}, nil
+	})
+	book := NewDNSAddressBook(resolver, "consumers.example", signer.Address(), time.Hour)
+	consumers, err := book.Consumers(context.Background())
+	require.NoError(t, err)
+	consumers[0] = "modified"
+
+	consumers, err = book.Consumers(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"domain1.example"}, consumers)
+}
+
+func TestDNSAddressBook_Cache(t *testing.T) {
+	var calls int32
+	resolver := dnsResolverFunc(func(_ context.Context, _ string) ([]string, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, nil
+	})
+	book := NewDNSAddressBook(resolver, "consumers.example", types.ZeroAddress, time.Second)
+	_, err := book.Consumers(context.Background())
+	require.NoError(t, err)
+	_, err = book.Consumers(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// After one second, the cache is invalided.
+	time.Sleep(time.Second)
+	_, err = book.Consumers(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestWellKnownAddressBook_Consumers(t *testing.T) {
+	var (
+		signer = wallet.NewRandomKey()
+		other  = wallet.NewRandomKey()
+	)
+	now := time.Now().Unix()
+	document := func(key wallet.Key, addrs []string) []byte {
+		return wellKnownDocument(key, addrs, now, now+3600)
+	}
+	tests := []struct {
+		status            int
+		body              []byte
+		expectedAddresses []string
+		wantErr           bool
+	}{
+		{
+			status:            http.StatusOK,
+			body:              document(signer, []string{"domain1.example", "domain2.example"}),
+			expectedAddresses: []string{"domain1.example", "domain2.example"},
+		},
+		{
+			status:            http.StatusOK,
+			body:              document(signer, nil),
+			expectedAddresses: []string{},
+		},
+		{
+			status:  http.StatusOK,
+			body:    document(other, []string{"domain1.example"}),
+			wantErr: true,
+		},
+		{
+			status:  http.StatusOK,
+			body:    []byte("invalid"),
+			wantErr: true,
+		},
+		{
+			// Expired document.
+			status:  http.StatusOK,
+			body:    wellKnownDocument(signer, []string{"domain1.example"}, now-7200, now-3600),
+			wantErr: true,
+		},
+		{
+			// Document issued in the future.
+			status:  http.StatusOK,
+			body:    wellKnownDocument(signer, []string{"domain1.example"}, now+3600, now+7200),
+			wantErr: true,
+		},
+		{
+			// Document without a validity period.
+			status:  http.StatusOK,
+			body:    wellKnownDocument(signer, []string{"domain1.example"}, 0, 0),
+			wantErr: true,
+		},
+		{
+			// Validity period is not covered by the signature.
+			status: http.StatusOK,
+			body: func() []byte {
+				var doc WellKnownDocument
+				require.NoError(t, json.Unmarshal(wellKnownDocument(signer, []string{"domain1.example"}, now-7200, now-3600), &doc))
+				doc.Expires = now + 3600
+				return errutil.Must(json.Marshal(doc))
+			}(),
+			wantErr: true,
+		},
+		{
+			status:  http.StatusNotFound,
+			wantErr: true,
+		},
+	}
+	for n, tt := range tests {
+		t.Run(fmt.Sprintf("case-%d", n+1), func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+				assert.Equal(t, WellKnownPath, req.URL.Path)
+				res.WriteHeader(tt.status)
+				_, _ = res.Write(tt.body)
+			}))
+			defer srv.Close()
+
+			book := NewWellKnownAddressBook(srv.Client(), srv.URL+"/", signer.Address(), time.Minute)
+			consumers, err := book.Consumers(context.Background())
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedAddresses, consumers)
+		})
+	}
+}
+
+func TestWellKnownAddressBook_Cache(t *testing.T) {
+	var (
+		calls  int32
+		signer = wallet.NewRandomKey()
+		now    = time.Now().Unix()
+		body   = wellKnownDocument(signer, []string{"domain1.example"}, now, now+3600)
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, _ *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		_, _ = res.Write(body)
+	}))
+	defer srv.Close()
+
+	book := NewWellKnownAddressBook(srv.Client(), srv.URL, signer.Address(), time.Second)
+	_, err := book.Consumers(context.Background())
+	require.NoError(t, err)
+	_, err = book.Consumers(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// After one second, the cache is invalided.
+	time.Sleep(time.Second)
+	_, err = book.Consumers(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestWellKnownAddressBook_Expiration(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		calls  int
+		signer = wallet.NewRandomKey()
+		now    = time.Now().Unix()
+		bodies = [][]byte{
+			// The first document expires before the cache TTL.
+			wellKnownDocument(signer, []string{"domain1.example"}, now, now+2),
+			wellKnownDocument(signer, []string{"domain2.example"}, now+1, now+3600),
+			// An older document must not replace a newer one.
+			wellKnownDocument(signer, []string{"domain1.example"}, now, now+3600),
+		}
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		_, _ = res.Write(bodies[calls])
+		calls++
+	}))
+	defer srv.Close()
+
+	book := NewWellKnownAddressBook(srv.Client(), srv.URL, signer.Address(), time.Hour)
+	consumers, err := book.Consumers(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"domain1.example"}, consumers)
+
+	// After the document expires, it is fetched again.
+	time.Sleep(2 * time.Second)
+	consumers, err = book.Consumers(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"domain2.example"}, consumers)
+
+	// Force the cache to expire.
+	book.cacheTime = time.Time{}
+	_, err = book.Consumers(context.Background())
+	require.Error(t, err)
+}
+
+func wellKnownDocument(key wallet.Key, addrs []string, issuedAt, expires int64) []byte {
+	doc := WellKnownDocument{Consumers: addrs, IssuedAt: issuedAt, Expires: expires}
+	doc.Signature = *errutil.Must(key.SignMessage(doc.SigningData()))
+	return errutil.Must(json.Marshal(doc))
+}
+
+func dnsRecord(key wallet.Key, addr string, issuedAt, expires int64) string {
+	record := DNSRecord{Consumer: addr, IssuedAt: issuedAt, Expires: expires}
+	record.Signature = *errutil.Must(key.SignMessage(record.SigningData()))
+	return record.String()
+}
+
 func encodeAddresses(addresses []string) []byte {
 	return errutil.Must(abi.EncodeValues(consumersMethod.Outputs(), addresses))
 }
@@ -0,0 +1,468 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package webapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"golang.org/x/time/rate"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/orcfax/oracle-suite/pkg/log"
+	"github.com/orcfax/oracle-suite/pkg/transport/webapi/pb"
+	"github.com/orcfax/oracle-suite/pkg/util/sliceutil"
+)
+
+const (
+	// streamPath is the URL path for the stream endpoint.
+	streamPath = "/stream"
+
+	// streamQueueSize is the number of messages that can be queued for
+	// a single stream. If the queue is full, the stream is closed and
+	// the producer falls back to batch requests.
+	streamQueueSize = 1000
+
+	// streamMaxMessageSize is the maximum size of a single stream message.
+	streamMaxMessageSize = 1 << 20
+
+	// streamPingInterval is the interval between ping frames sent by
+	// producers. Consumers close streams that are idle for twice as long.
+	streamPingInterval = 30 * time.Second
+
+	// streamRetryInterval is the time after which the producer tries again
+	// to open a stream to a consumer that does not support streaming.
+	streamRetryInterval = 10 * time.Minute
+
+	// defaultStreamMessageRate is the default number of messages per second
+	// a producer can push over a stream. The burst size is streamQueueSize,
+	// so a producer can push its whole queue at once.
+	defaultStreamMessageRate = 10
+)
+
+// producerStream is a stream opened by a producer to a consumer.
+type producerStream struct {
+	conn        *websocket.Conn
+	connectedAt time.Time
+	queue       chan []byte
+	closed      chan struct{}
+	closeOnce   sync.Once
+}
+
+func newProducerStream(conn *websocket.Conn) *producerStream {
+	return &producerStream{
+		conn:        conn,
+		connectedAt: time.Now(),
+		queue:       make(chan []byte, streamQueueSize),
+		closed:      make(chan struct{}),
+	}
+}
+
+// send queues the data to be sent. It returns false if the stream is
+// closed or the queue is full.
+func (s *producerStream) send(data []byte) bool {
+	select {
+	case <-s.closed:
+		return false
+	case s.queue <- data:
+		return true
+	default:
+		return false
+	}
+}
+
+// close closes the stream. It is safe to call close multiple times.
+func (s *producerStream) close() {
+	s.closeOnce.Do(func() {
+		close(s.closed)
+		s.conn.Close()
+	})
+}
+
+// pushMessage sends the message immediately to all consumers with an open
+// stream. Every message is sent as a separately signed message pack.
+func (w *WebAPI) pushMessage(topic string, bin []byte) error {
+	w.streamMu.Lock()
+	defer w.streamMu.Unlock()
+	if len(w.streams) == 0 {
+		return nil
+	}
+	mp := &pb.MessagePack{
+		Messages: map[string]*pb.MessagePack_Messages{topic: {Data: [][]byte{bin}}},
+	}
+	if err := signMessage(mp, w.signer); err != nil {
+		return err
+	}
+	data, err := proto.Marshal(mp)
+	if err != nil {
+		return err
+	}
+	for addr, s := range w.streams {
+		if s == nil {
+			continue // Stream is being opened.
+		}
+		if !s.send(data) {
+			w.log.
+				WithField("address", addr).
+				WithAdvice("Ignore if occurs occasionally, especially if it is related to temporary network issues").
+				Warn("Unable to push message, falling back to batch requests")
+			s.close()
+		}
+	}
+	return nil
+}
+
+// streamed returns true if messages added to the current batch were pushed
+// to the consumer over a stream.
+//
+// Must be called with w.mu locked.
+func (w *WebAPI) streamed(addr string) bool {
+	w.streamMu.Lock()
+	defer w.streamMu.Unlock()
+	s := w.streams[addr]
+	return s != nil && !s.connectedAt.After(w.batchStart)
+}
+
+// connectStreams opens streams to consumers that do not have one.
+func (w *WebAPI) connectStreams(ctx context.Context) {
+	cons, err := w.consumers(ctx)
+	if err != nil {
+		w.log.
+			WithError(err).
+			WithAdvice("Ignore if occurs occasionally, especially if it is related to temporary network issues").
+			Warn("Unable to get the list of consumers")
+		return
+	}
+	w.streamMu.Lock()
+	defer w.streamMu.Unlock()
+	for _, addr := range cons {
+		if _, ok := w.streams[addr]; ok {
+			continue
+		}
+		if t, ok := w.noStream[addr]; ok && time.Now().Before(t) {
+			continue
+		}
+		w.streams[addr] = nil // Placeholder until the stream is opened.
+		go w.streamRoutine(ctx, addr)
+	}
+}
+
+// streamRoutine opens a stream to the consumer and sends queued messages
+// until the stream is closed or the context is canceled.
+func (w *WebAPI) streamRoutine(ctx context.Context, addr string) {
+	s, err := w.openStream(ctx, addr)
+	if err != nil {
+		w.streamMu.Lock()
+		delete(w.streams, addr)
+		if errors.Is(err, websocket.ErrBadHandshake) {
+			// The consumer does not support streaming, the producer will
+			// use batch requests for a while.
+			w.noStream[addr] = time.Now().Add(streamRetryInterval)
+		}
+		w.streamMu.Unlock()
+		w.log.
+			WithError(err).
+			WithField("address", addr).
+			WithAdvice("Messages will be sent in batches, ignore if the consumer does not support streaming").
+			Warn("Unable to open stream to consumer")
+		return
+	}
+	w.streamMu.Lock()
+	w.streams[addr] = s
+	delete(w.noStream, addr)
+	w.streamMu.Unlock()
+	w.log.WithField("address", addr).Info("Stream to consumer opened")
+	defer func() {
+		s.close()
+		w.streamMu.Lock()
+		if w.streams[addr] == s {
+			delete(w.streams, addr)
+		}
+		w.streamMu.Unlock()
+		w.log.WithField("address", addr).Info("Stream to consumer closed")
+	}()
+
+	// Reading is required to process control frames sent by the consumer.
+	go func() {
+		for {
+			if _, _, err := s.conn.NextReader(); err != nil {
+				s.close()
+				return
+			}
+		}
+	}()
+
+	t := time.NewTicker(streamPingInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			_ = s.conn.WriteControl(
+				websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseGoingAway, ""),
+				time.Now().Add(time.Second),
+			)
+			return
+		case <-s.closed:
+			return
+		case data := <-s.queue:
+			_ = s.conn.SetWriteDeadline(time.Now().Add(w.timeout))
+			if err := s.conn.WriteMessage(websocket.BinaryMessage, data); err != nil {
+				w.log.
+					WithError(err).
+					WithField("address", addr).
+					WithAdvice("Ignore if occurs occasionally, especially if it is related to temporary network issues").
+					Warn("Failed to push message to consumer")
+				return
+			}
+		case <-t.C:
+			if err := s.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(w.timeout)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// openStream opens a websocket connection to the consumer. The URL of
+// the connection is signed in the same way as batch requests.
+func (w *WebAPI) openStream(ctx context.Context, addr string) (*producerStream, error) {
+	url, err := signURL(streamURL(addr), time.Now(), w.signer, w.rand)
+	if err != nil {
+		return nil, err
+	}
+	conn, res, err := w.dialer.DialContext(ctx, url, nil)
+	if res != nil && res.Body != nil {
+		res.Body.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newProducerStream(conn), nil
+}
+
+// streamHandler handles streams opened by producers.
+//
+// Request must be a websocket handshake to the /stream path. The URL must be
+// signed by the producer. Each websocket message is a protobuf-encoded
+// MessagePack signed by the same producer.
+//
+//nolint:funlen
+func (w *WebAPI) streamHandler(res http.ResponseWriter, req *http.Request) {
+	// Skip if the context is canceled.
+	if w.ctx.Err() != nil {
+		res.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	fields := log.Fields{
+		"method": req.Method,
+		"addr":   req.RemoteAddr,
+		"url":    req.URL.String(),
+	}
+
+	w.log.
+		WithFields(fields).
+		Debug("Received stream request")
+
+	// Verify the request URL signature.
+	requestAuthor, timestamp, err := verifyURL(req.URL.String(), w.recover)
+	if err != nil {
+		w.log.
+			WithFields(fields).
+			WithError(err).
+			WithAdvice("This may indicate a bug in the WebAPI server or a bug in the consumer software, or someone is trying to connect to the WebAPI server with incompatible software"). //nolint:lll
+			Warn("Invalid request signature")
+		res.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	fields["author"] = requestAuthor
+	fields["timestamp"] = timestamp
+
+	// Verify if the feed is allowed to send messages.
+	if !sliceutil.Contains(w.allowlist, *requestAuthor) {
+		w.log.
+			WithFields(fields).
+			Debug("Feed is not allowed to send messages")
+		res.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// Stream timestamp must be within the allowed time window:
+	// [now - maxClockSkew, now + maxClockSkew].
+	currentTimestamp := time.Now()
+	if timestamp.After(currentTimestamp.Add(w.maxClockSkew)) {
+		w.log.
+			WithFields(fields).
+			WithAdvice("This may be cased by setting incorrect system time on this server or server that send the message").
+			Warn("Timestamp too far in the future")
+		res.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if timestamp.Before(currentTimestamp.Add(-w.maxClockSkew)) {
+		w.log.
+			WithFields(fields).
+			WithAdvice("This may be cased by setting incorrect system time on this server or server that send the message").
+			Warn("Timestamp too far in the past")
+		res.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	conn, err := w.upgrader.Upgrade(res, req, nil)
+	if err != nil {
+		// Upgrader responds with an error to the producer.
+		w.log.
+			WithError(err).
+			WithFields(fields).
+			WithAdvice("This may happen if someone is trying to connect to the WebAPI server with incompatible software").
+			Warn("Unable to upgrade connection")
+		return
+	}
+	conn.SetReadLimit(streamMaxMessageSize)
+
+	// Only one stream per producer is allowed, the newest one is used.
+	// The message budget is kept between streams, so it cannot be reset
+	// by reconnecting.
+	w.streamMu.Lock()
+	if prev, ok := w.consStreams[*requestAuthor]; ok {
+		prev.Close()
+	}
+	w.consStreams[*requestAuthor] = conn
+	limiter, ok := w.consLimits[*requestAuthor]
+	if !ok {
+		limiter = rate.NewLimiter(w.streamRate, streamQueueSize)
+		w.consLimits[*requestAuthor] = limiter
+	}
+	w.streamMu.Unlock()
+
+	doneCh := make(chan struct{})
+	defer func() {
+		close(doneCh)
+		conn.Close()
+		w.streamMu.Lock()
+		if w.consStreams[*requestAuthor] == conn {
+			delete(w.consStreams, *requestAuthor)
+		}
+		w.streamMu.Unlock()
+	}()
+
+	// Hijacked connections are not closed by the HTTP server on shutdown.
+	go func() {
+		select {
+		case <-w.ctx.Done():
+			conn.Close()
+		case <-doneCh:
+		}
+	}()
+
+	w.log.
+		WithFields(fields).
+		Info("Stream from producer opened")
+
+	extendDeadline := func() {
+		_ = conn.SetReadDeadline(time.Now().Add(2 * streamPingInterval))
+	}
+	conn.SetPingHandler(func(data string) error {
+		extendDeadline()
+		return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+	})
+	extendDeadline()
+	for {
+		typ, data, err := conn.ReadMessage()
+		if err != nil {
+			w.log.
+				WithError(err).
+				WithFields(fields).
+				Info("Stream from producer closed")
+			return
+		}
+		extendDeadline()
+		if typ != websocket.BinaryMessage {
+			continue
+		}
+
+		// Unmarshal protobuf-encoded MessagePack.
+		mp := &pb.MessagePack{}
+		if err := proto.Unmarshal(data, mp); err != nil {
+			w.log.
+				WithError(err).
+				WithFields(fields).
+				WithAdvice("This is likely a bug and must be investigated").
+				Error("Unable to decode protobuf message")
+			return
+		}
+
+		// Verify the message signature and verify that the message signer
+		// is same as the stream author.
+		messagePackSigner, err := verifyMessage(mp, w.recover)
+		if err != nil {
+			w.log.
+				WithError(err).
+				WithFields(fields).
+				WithAdvice("This is likely a bug and must be investigated").
+				Error("Invalid message pack signature")
+			return
+		}
+		if *messagePackSigner != *requestAuthor {
+			w.log.
+				WithFields(fields).
+				WithField("signer", messagePackSigner).
+				WithAdvice("This is likely a bug and must be investigated").
+				Error("Message signer does not match request author")
+			return
+		}
+
+		// Producers exceeding the message budget must fall back to batch
+		// requests, which are limited to one per flush interval.
+		if !limiter.AllowN(time.Now(), messageCount(mp)) {
+			w.log.
+				WithFields(fields).
+				WithAdvice("This may be causes by misconfiguration on this server or server that send the message").
+				Warn("Too many messages received in a short time, closing stream")
+			return
+		}
+
+		w.mu.RLock()
+		if w.ctx.Err() == nil {
+			w.deliverMessages(mp, *requestAuthor, fields)
+		}
+		w.mu.RUnlock()
+	}
+}
+
+// messageCount returns the number of messages in the message pack.
+func messageCount(mp *pb.MessagePack) int {
+	n := 0
+	for _, msgs := range mp.Messages {
+		n += len(msgs.Data)
+	}
+	return n
+}
+
+// streamURL returns the URL of the stream endpoint of the consumer.
+func streamURL(addr string) string {
+	switch {
+	case strings.HasPrefix(addr, "https://"):
+		addr = "wss://" + strings.TrimPrefix(addr, "https://")
+	case strings.HasPrefix(addr, "http://"):
+		addr = "ws://" + strings.TrimPrefix(addr, "http://")
+	}
+	return strings.TrimRight(addr, "/") + streamPath
+}
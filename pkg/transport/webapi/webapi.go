@@ -37,6 +37,8 @@ import (
 	"github.com/defiweb/go-eth/crypto"
 	"github.com/defiweb/go-eth/types"
 	"github.com/defiweb/go-eth/wallet"
+	"github.com/gorilla/websocket"
+	"golang.org/x/time/rate"
 	"google.golang.org/protobuf/proto"
 
 	"github.com/orcfax/oracle-suite/pkg/httpserver"
@@ -132,6 +134,14 @@ const (
 // The HTTP server returns HTTP 200 OK response if the request is valid.
 // Otherwise, it returns 429 Too Many Requests response if producer sends
 // messages too often or 400 Bad Request response for any other error.
+//
+// If streaming is enabled, producers additionally try to open a websocket
+// connection to the /stream path of every consumer. The URL of the
+// connection is signed in the same way as batch requests, but the timestamp
+// must be within the maxClockSkew range. Messages are pushed over the stream
+// immediately after they are broadcast, each one as a separate, signed
+// MessagePack. Consumers that do not support streaming, or whose stream was
+// closed, receive messages in batches.
 type WebAPI struct {
 	mu     sync.RWMutex
 	ctx    context.Context
@@ -142,6 +152,14 @@ type WebAPI struct {
 	lastReqs    map[types.Address]time.Time                            // Last timestamp received from each producer.
 	msgCh       map[string]chan transport.ReceivedMessage              // Channels for received messages.
 	msgChFO     map[string]*chanutil.FanOut[transport.ReceivedMessage] // Fan-out channels for received messages.
+	batchStart  time.Time                                              // Time when the first message was added to the message pack.
+
+	// Stream state fields:
+	streamMu    sync.Mutex
+	streams     map[string]*producerStream        // Streams opened to consumers, by consumer address.
+	noStream    map[string]time.Time              // Consumers that do not support streaming, with the retry time.
+	consStreams map[types.Address]*websocket.Conn // Streams opened by producers.
+	consLimits  map[types.Address]*rate.Limiter   // Message budgets of producers with streams.
 
 	// Configuration fields:
 	addressBook  AddressBook
@@ -151,7 +169,12 @@ type WebAPI struct {
 	signer       wallet.Key
 	client       *http.Client
 	server       httpserver.Service
+	streaming    bool
+	streamRate   rate.Limit
+	dialer       *websocket.Dialer
+	upgrader     *websocket.Upgrader
 	rand         io.Reader
+	timeout      time.Duration
 	maxClockSkew time.Duration
 	log          log.Logger
 	appName      string
@@ -209,6 +232,20 @@ type Config struct {
 	// messages. If provided, Timeout is ignored.
 	Client *http.Client
 
+	// Streaming enables pushing messages to consumers over websocket
+	// connections immediately after they are broadcast. Consumers that do
+	// not support streaming receive messages in batches. Consumers always
+	// accept streams, regardless of this option.
+	Streaming bool
+
+	// StreamMessageRate is the number of messages per second a single
+	// producer can push over a stream, with bursts of up to 1000 messages.
+	// Streams of producers exceeding the limit are closed, so they fall
+	// back to rate limited batch requests.
+	//
+	// If zero, default value will be used (10 messages per second).
+	StreamMessageRate float64
+
 	// Rand is an optional random number generator. If not provided, Reader
 	// from crypto/rand package will be used.
 	Rand io.Reader
@@ -243,6 +280,9 @@ func New(cfg Config) (*WebAPI, error) {
 	if cfg.MaxClockSkew == 0 {
 		cfg.MaxClockSkew = defaultMaxClockSkew
 	}
+	if cfg.StreamMessageRate == 0 {
+		cfg.StreamMessageRate = defaultStreamMessageRate
+	}
 	if cfg.Rand == nil {
 		cfg.Rand = rand.Reader
 	}
@@ -266,6 +306,19 @@ func New(cfg Config) (*WebAPI, error) {
 			})
 		}
 	}
+	dialer := &websocket.Dialer{HandshakeTimeout: cfg.Timeout}
+	if t, ok := cfg.Client.Transport.(*http.Transport); ok {
+		// Use the same proxy settings for streams as for batch requests.
+		dialer.NetDialContext = t.DialContext
+		dialer.Proxy = t.Proxy
+		dialer.TLSClientConfig = t.TLSClientConfig
+	}
+	upgrader := &websocket.Upgrader{
+		HandshakeTimeout: cfg.Timeout,
+		// Streams are opened by producers, not browsers. Requests are
+		// authenticated using the URL signature.
+		CheckOrigin: func(*http.Request) bool { return true },
+	}
 	w := &WebAPI{
 		waitCh:       make(chan error),
 		topics:       maputil.Copy(cfg.Topics),
@@ -278,6 +331,15 @@ func New(cfg Config) (*WebAPI, error) {
 		lastReqs:     make(map[types.Address]time.Time),
 		msgCh:        make(map[string]chan transport.ReceivedMessage),
 		msgChFO:      make(map[string]*chanutil.FanOut[transport.ReceivedMessage]),
+		streams:      make(map[string]*producerStream),
+		noStream:     make(map[string]time.Time),
+		consStreams:  make(map[types.Address]*websocket.Conn),
+		consLimits:   make(map[types.Address]*rate.Limiter),
+		streaming:    cfg.Streaming,
+		streamRate:   rate.Limit(cfg.StreamMessageRate),
+		dialer:       dialer,
+		upgrader:     upgrader,
+		timeout:      cfg.Timeout,
 		maxClockSkew: cfg.MaxClockSkew,
 		rand:         cfg.Rand,
 		log:          logger,
@@ -285,7 +347,7 @@ func New(cfg Config) (*WebAPI, error) {
 		appVersion:   cfg.AppVersion,
 		recover:      crypto.ECRecoverer,
 	}
-	w.server.SetHandler(http.HandlerFunc(w.consumeHandler))
+	w.server.SetHandler(http.HandlerFunc(w.handler))
 	return w, nil
 }
 
@@ -335,17 +397,12 @@ func (w *WebAPI) Broadcast(topic string, message transport.Message) error {
 	if err != nil {
 		return err
 	}
-	w.mu.Lock()
-	defer w.mu.Unlock()
-	// Add message to the next batch. The batch will be sent to the consumers
-	// on the next flush (see flushRoutine).
-	if w.messagePack == nil {
-		w.messagePack = &pb.MessagePack{Messages: make(map[string]*pb.MessagePack_Messages)}
-	}
-	if w.messagePack.Messages[topic] == nil {
-		w.messagePack.Messages[topic] = &pb.MessagePack_Messages{}
+	w.addToBatch(topic, bin)
+	if w.streaming {
+		// The message is also added to the batch for consumers without
+		// an open stream.
+		return w.pushMessage(topic, bin)
 	}
-	w.messagePack.Messages[topic].Data = append(w.messagePack.Messages[topic].Data, bin)
 	return nil
 }
 
@@ -359,6 +416,21 @@ func (w *WebAPI) Messages(topic string) <-chan transport.ReceivedMessage {
 	return nil
 }
 
+// addToBatch adds the message to the next batch. The batch will be sent to
+// the consumers on the next flush (see flushRoutine).
+func (w *WebAPI) addToBatch(topic string, bin []byte) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.messagePack == nil {
+		w.messagePack = &pb.MessagePack{Messages: make(map[string]*pb.MessagePack_Messages)}
+		w.batchStart = time.Now()
+	}
+	if w.messagePack.Messages[topic] == nil {
+		w.messagePack.Messages[topic] = &pb.MessagePack_Messages{}
+	}
+	w.messagePack.Messages[topic].Data = append(w.messagePack.Messages[topic].Data, bin)
+}
+
 // flushMessages sends the current batch of messages to the consumers.
 // The batch is cleared after the messages are sent.
 func (w *WebAPI) flushMessages(ctx context.Context, t time.Time) error {
@@ -381,10 +453,25 @@ func (w *WebAPI) flushMessages(ctx context.Context, t time.Time) error {
 	if err != nil {
 		return err
 	}
-	cons, err := w.addressBook.Consumers(ctx)
+	cons, err := w.consumers(ctx)
 	if err != nil {
 		return err
 	}
+	for _, addr := range cons {
+		if w.streaming && w.streamed(addr) {
+			continue // Messages were already pushed over the stream.
+		}
+		go w.doHTTPRequest(ctx, addr, bin, t)
+	}
+	return nil
+}
+
+// consumers returns the list of consumer addresses from the address book.
+func (w *WebAPI) consumers(ctx context.Context) ([]string, error) {
+	cons, err := w.addressBook.Consumers(ctx)
+	if err != nil {
+		return nil, err
+	}
 	// Consumer addresses may omit protocol scheme, so we add it here.
 	for n, addr := range cons {
 		if !strings.Contains(addr, "://") {
@@ -393,10 +480,7 @@ func (w *WebAPI) flushMessages(ctx context.Context, t time.Time) error {
 			cons[n] = "http://" + addr
 		}
 	}
-	for _, addr := range cons {
-		go w.doHTTPRequest(ctx, addr, bin, t)
-	}
-	return nil
+	return cons, nil
 }
 
 // doHTTPRequest sends a POST request to the given address with the given
@@ -447,6 +531,15 @@ func (w *WebAPI) doHTTPRequest(ctx context.Context, addr string, data []byte, t
 	res.Body.Close()
 }
 
+// handler routes requests to the consume and stream handlers.
+func (w *WebAPI) handler(res http.ResponseWriter, req *http.Request) {
+	if req.URL.Path == streamPath {
+		w.streamHandler(res, req)
+		return
+	}
+	w.consumeHandler(res, req)
+}
+
 // consumeHandler handles incoming messages from consumers.
 //
 // Request must be a POST request to the /consume path with a protobuf-encoded
@@ -638,7 +731,13 @@ func (w *WebAPI) consumeHandler(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	// Send messages from the MessagePack to the msgCh channel.
+	w.deliverMessages(mp, *requestAuthor, fields)
+}
+
+// deliverMessages sends messages from the MessagePack to the msgCh channels.
+//
+// Must be called with w.mu read-locked.
+func (w *WebAPI) deliverMessages(mp *pb.MessagePack, author types.Address, fields log.Fields) {
 	for topic, msgs := range mp.Messages {
 		typ, ok := w.topics[topic]
 		if !ok {
@@ -672,12 +771,12 @@ func (w *WebAPI) consumeHandler(res http.ResponseWriter, req *http.Request) {
 
 			w.msgCh[topic] <- transport.ReceivedMessage{
 				Message: msg,
-				Author:  requestAuthor.Bytes(),
+				Author:  author.Bytes(),
 				Meta: transport.Meta{
 					Transport: TransportName,
 					Topic:     topic,
 					UserAgent: userAgent,
-					PeerAddr:  requestAuthor.String(),
+					PeerAddr:  author.String(),
 				},
 			}
 		}
@@ -701,6 +800,9 @@ func (w *WebAPI) flushRoutine(ctx context.Context) {
 					WithAdvice("This is a bug and must be investigated").
 					Error("Failed to send messages")
 			}
+			if w.streaming {
+				w.connectStreams(ctx)
+			}
 		}
 	}
 }
@@ -26,9 +26,11 @@ import (
 	"time"
 
 	"github.com/defiweb/go-eth/types"
+	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
 
 	"github.com/orcfax/oracle-suite/pkg/ethereum/mocks"
 	"github.com/orcfax/oracle-suite/pkg/httpserver"
@@ -385,6 +387,167 @@ func Test_WebAPI(t *testing.T) {
 	}
 }
 
+func Test_WebAPI_Streaming(t *testing.T) {
+	address := types.MustAddressFromHex("0x1234567890123456789012345678901234567890")
+
+	tests := []struct {
+		name      string
+		streaming bool // whether the consumer supports streaming
+	}{
+		{name: "stream", streaming: true},
+		{name: "fallback", streaming: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, ctxCancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer ctxCancel()
+
+			// Dependencies:
+			consSrv := httpserver.New(&http.Server{Addr: "127.0.0.1:0"})
+			ab := &addressBook{addresses: []string{}}
+			signer := &mocks.Key{}
+			signer.On("SignMessage", mock.Anything).Return(&fakeSignature, nil)
+			recoverer := &mocks.Recoverer{}
+			recoverer.On("RecoverMessage", mock.Anything, fakeSignature).Return(&address, nil)
+
+			prod, err := New(Config{
+				ListenAddr:  "127.0.0.1:0",
+				Topics:      map[string]transport.Message{"test": (*message)(nil)},
+				AddressBook: ab,
+				Signer:      signer,
+				FlushTicker: timeutil.NewTicker(60 * time.Second),
+				Streaming:   true,
+			})
+			require.NoError(t, err)
+			cons, err := New(Config{
+				Topics:          map[string]transport.Message{"test": (*message)(nil)},
+				AuthorAllowlist: []types.Address{address},
+				AddressBook:     ab,
+				FlushTicker:     timeutil.NewTicker(60 * time.Second),
+				Server:          consSrv,
+			})
+			require.NoError(t, err)
+			if !tt.streaming {
+				// Simulate a consumer that supports only batch requests.
+				consSrv.SetHandler(http.HandlerFunc(cons.consumeHandler))
+			}
+			prod.recover = recoverer
+			cons.recover = recoverer
+
+			require.NoError(t, prod.Start(ctx))
+			require.NoError(t, cons.Start(ctx))
+			consAddr := "http://" + consSrv.Addr().String()
+			ab.addresses = []string{consAddr}
+			ch := cons.Messages("test")
+
+			// The first tick opens streams.
+			prod.flushTicker.TickAt(time.Now())
+			assert.Eventually(t, func() bool {
+				prod.streamMu.Lock()
+				defer prod.streamMu.Unlock()
+				if tt.streaming {
+					return prod.streams[consAddr] != nil
+				}
+				_, ok := prod.noStream[consAddr]
+				return ok
+			}, time.Second, 10*time.Millisecond)
+
+			require.NoError(t, prod.Broadcast("test", &message{data: []byte("data")}))
+			if !tt.streaming {
+				// Without a stream, messages are sent on the next flush.
+				prod.flushTicker.TickAt(time.Now())
+			}
+
+			select {
+			case msg := <-ch:
+				assert.Equal(t, []byte("data"), msg.Message.(*message).data)
+				assert.Equal(t, address.Bytes(), msg.Author)
+			case <-ctx.Done():
+				require.Fail(t, "message not received")
+			}
+
+			if tt.streaming {
+				// Messages pushed over the stream must not be sent again
+				// in a batch.
+				prod.flushTicker.TickAt(time.Now())
+				select {
+				case <-ch:
+					assert.Fail(t, "message received twice")
+				case <-time.After(200 * time.Millisecond):
+				}
+			}
+		})
+	}
+}
+
+func Test_WebAPI_StreamingRateLimit(t *testing.T) {
+	ctx, ctxCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer ctxCancel()
+
+	address := types.MustAddressFromHex("0x1234567890123456789012345678901234567890")
+	signer := &mocks.Key{}
+	signer.On("SignMessage", mock.Anything).Return(&fakeSignature, nil)
+	recoverer := &mocks.Recoverer{}
+	recoverer.On("RecoverMessage", mock.Anything, fakeSignature).Return(&address, nil)
+
+	consSrv := httpserver.New(&http.Server{Addr: "127.0.0.1:0"})
+	cons, err := New(Config{
+		Topics:          map[string]transport.Message{"test": (*message)(nil)},
+		AuthorAllowlist: []types.Address{address},
+		AddressBook:     &addressBook{},
+		FlushTicker:     timeutil.NewTicker(60 * time.Second),
+		Server:          consSrv,
+	})
+	require.NoError(t, err)
+	cons.recover = recoverer
+	require.NoError(t, cons.Start(ctx))
+	ch := cons.Messages("test")
+
+	url, err := signURL(streamURL("http://"+consSrv.Addr().String()), time.Now(), signer, strings.NewReader(strings.Repeat("x", 16)))
+	require.NoError(t, err)
+	conn, res, err := websocket.DefaultDialer.DialContext(ctx, url, nil)
+	require.NoError(t, err)
+	res.Body.Close()
+	defer conn.Close()
+
+	send := func(n int) {
+		data := make([][]byte, n)
+		for i := range data {
+			data[i] = []byte("data")
+		}
+		mp := &pb.MessagePack{Messages: map[string]*pb.MessagePack_Messages{"test": {Data: data}}}
+		require.NoError(t, signMessage(mp, signer))
+		bin, err := proto.Marshal(mp)
+		require.NoError(t, err)
+		require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, bin))
+	}
+
+	// Messages within the budget are delivered.
+	send(1)
+	select {
+	case msg := <-ch:
+		assert.Equal(t, []byte("data"), msg.Message.(*message).data)
+	case <-ctx.Done():
+		require.Fail(t, "message not received")
+	}
+
+	// Exceeding the budget closes the stream without delivering messages.
+	send(streamQueueSize + 1)
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, _, err = conn.ReadMessage()
+	assert.Error(t, err)
+	select {
+	case <-ch:
+		assert.Fail(t, "message over the budget received")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func Test_streamURL(t *testing.T) {
+	assert.Equal(t, "ws://localhost:8080/stream", streamURL("http://localhost:8080"))
+	assert.Equal(t, "wss://example.com/stream", streamURL("https://example.com/"))
+}
+
 func Test_signMessage(t *testing.T) {
 	var (
 		mp = &pb.MessagePack{
@@ -0,0 +1,56 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package bn
+
+import (
+	"math"
+	"sort"
+)
+
+// Median returns the median of the given numbers. If the number of elements
+// is even, the median is the mean of the two middle elements. It returns zero
+// if the slice is empty. The slice is sorted in place.
+func Median(xs []*DecFloatPointNumber) *DecFloatPointNumber {
+	count := len(xs)
+	if count == 0 {
+		return DecFloatPoint(0)
+	}
+	sort.Slice(xs, func(i, j int) bool {
+		return xs[i].Cmp(xs[j]) < 0
+	})
+	if count%2 == 0 {
+		return xs[count/2-1].Add(xs[count/2]).Div(DecFloatPoint(2))
+	}
+	return xs[count/2]
+}
+
+// Deviation returns the absolute difference between x and the reference
+// value ref, as a percentage of ref:
+//
+//	abs((x - ref) / ref * 100)
+//
+// If ref is zero, the result is positive infinity, or zero if x is also
+// zero.
+func Deviation(x, ref *DecFloatPointNumber) float64 {
+	if ref.Sign() == 0 {
+		if x.Sign() == 0 {
+			return 0
+		}
+		return math.Inf(1)
+	}
+	deviation, _ := x.Sub(ref).Div(ref).Mul(DecFloatPoint(100)).Abs().BigFloat().Float64()
+	return deviation
+}
@@ -0,0 +1,72 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package bn
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMedian(t *testing.T) {
+	tests := []struct {
+		name     string
+		xs       []*DecFloatPointNumber
+		expected *DecFloatPointNumber
+	}{
+		{
+			name:     "empty",
+			xs:       []*DecFloatPointNumber{},
+			expected: DecFloatPoint(0),
+		},
+		{
+			name:     "odd",
+			xs:       []*DecFloatPointNumber{DecFloatPoint(1), DecFloatPoint(3), DecFloatPoint(2)},
+			expected: DecFloatPoint(2),
+		},
+		{
+			name:     "even",
+			xs:       []*DecFloatPointNumber{DecFloatPoint(1), DecFloatPoint(4), DecFloatPoint(3), DecFloatPoint(2)},
+			expected: DecFloatPoint(2.5),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Median(tt.xs))
+		})
+	}
+}
+
+func TestDeviation(t *testing.T) {
+	tests := []struct {
+		name     string
+		x        *DecFloatPointNumber
+		ref      *DecFloatPointNumber
+		expected float64
+	}{
+		{name: "above", x: DecFloatPoint(110), ref: DecFloatPoint(100), expected: 10},
+		{name: "below", x: DecFloatPoint(90), ref: DecFloatPoint(100), expected: 10},
+		{name: "equal", x: DecFloatPoint(100), ref: DecFloatPoint(100), expected: 0},
+		{name: "zero ref", x: DecFloatPoint(1), ref: DecFloatPoint(0), expected: math.Inf(1)},
+		{name: "both zero", x: DecFloatPoint(0), ref: DecFloatPoint(0), expected: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Deviation(tt.x, tt.ref))
+		})
+	}
+}
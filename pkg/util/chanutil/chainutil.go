@@ -114,11 +114,19 @@ func (fi *FanIn[T]) Chan() <-chan T {
 // FanOut is a fan-out channel demultiplexer. It takes a single input channel
 // and distributes its values to multiple output channels. The input channel
 // is emptied even if there are no output channels. Output channels are closed
-// when the input channel is closed. The implementation is thread-safe.
+// when the input channel is closed or when they are removed using the Remove
+// method. The implementation is thread-safe.
 type FanOut[T any] struct {
-	mu  sync.Mutex
-	in  <-chan T
-	out []chan T
+	mu     sync.Mutex
+	sendMu sync.Mutex // Held while a value is sent to output channels.
+	in     <-chan T
+	out    []*fanOutChan[T]
+}
+
+// fanOutChan is an output channel of FanOut.
+type fanOutChan[T any] struct {
+	ch     chan T
+	doneCh chan struct{} // Closed when the channel is removed.
 }
 
 // NewFanOut creates a new FanOut instance.
@@ -139,20 +147,72 @@ func (fo *FanOut[T]) Chan() <-chan T {
 		close(ch)
 		return ch
 	}
-	fo.out = append(fo.out, ch)
+	fo.out = append(fo.out, &fanOutChan[T]{ch: ch, doneCh: make(chan struct{})})
 	return ch
 }
 
+// Remove removes the output channel returned by the Chan method and closes
+// it. Values that are not read from the removed channel are discarded.
+// It returns false if the channel was already removed or closed.
+func (fo *FanOut[T]) Remove(ch <-chan T) bool {
+	fo.mu.Lock()
+	var out *fanOutChan[T]
+	for _, o := range fo.out {
+		if o.ch == ch {
+			out = o
+			break
+		}
+	}
+	if out == nil {
+		fo.mu.Unlock()
+		return false
+	}
+	select {
+	case <-out.doneCh:
+		// Already being removed by another call.
+		fo.mu.Unlock()
+		return false
+	default:
+		// Unblock the worker if it is sending a value to the channel.
+		close(out.doneCh)
+	}
+	fo.mu.Unlock()
+
+	// The channel can be closed only when the worker is not sending a value
+	// to it.
+	fo.sendMu.Lock()
+	defer fo.sendMu.Unlock()
+	fo.mu.Lock()
+	defer fo.mu.Unlock()
+	for i, o := range fo.out {
+		if o == out {
+			// A new slice is created because the worker may iterate over
+			// the previous one.
+			fo.out = append(append(make([]*fanOutChan[T], 0, len(fo.out)-1), fo.out[:i]...), fo.out[i+1:]...)
+			close(o.ch)
+			return true
+		}
+	}
+	// The channel was closed by the worker because the input channel
+	// was closed.
+	return false
+}
+
 func (fo *FanOut[T]) worker() {
 	for v := range fo.in {
-		for _, ch := range fo.chs() {
-			ch <- v
+		fo.sendMu.Lock()
+		for _, o := range fo.chs() {
+			select {
+			case o.ch <- v:
+			case <-o.doneCh:
+			}
 		}
+		fo.sendMu.Unlock()
 	}
 	fo.mu.Lock()
 	defer fo.mu.Unlock()
-	for _, ch := range fo.out {
-		close(ch)
+	for _, o := range fo.out {
+		close(o.ch)
 	}
 	// Remove references to the output channels to help the garbage collector
 	// to free the memory. These channels are inaccessible at this point.
@@ -160,7 +220,7 @@ func (fo *FanOut[T]) worker() {
 	fo.out = nil
 }
 
-func (fo *FanOut[T]) chs() []chan T {
+func (fo *FanOut[T]) chs() []*fanOutChan[T] {
 	fo.mu.Lock()
 	defer fo.mu.Unlock()
 	return fo.out
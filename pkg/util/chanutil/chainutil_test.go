@@ -168,3 +168,34 @@ func TestFanOut(t *testing.T) {
 	_, ok := <-fo.Chan()
 	assert.False(t, ok)
 }
+
+func TestFanOut_Remove(t *testing.T) {
+	ch := make(chan int)
+
+	fo := NewFanOut(ch)
+
+	out1 := fo.Chan()
+	out2 := fo.Chan()
+
+	// The first value is never read from out1, it must not block
+	// distribution to other channels after out1 is removed.
+	ch <- 1
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		assert.True(t, fo.Remove(out1))
+	}()
+	assert.Equal(t, 1, <-out2)
+
+	// Removed channel is closed.
+	_, ok := <-out1
+	assert.False(t, ok)
+	assert.False(t, fo.Remove(out1))
+
+	ch <- 2
+	assert.Equal(t, 2, <-out2)
+
+	close(ch)
+	_, ok = <-out2
+	assert.False(t, ok)
+	assert.False(t, fo.Remove(out2))
+}